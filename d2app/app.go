@@ -85,10 +85,11 @@ type App struct {
 
 // Options is used to store all of the app options that can be set with arguments
 type Options struct {
-	Debug    *bool
-	profiler *string
-	Server   *d2networking.ServerOptions
-	LogLevel *d2util.LogLevel
+	Debug       *bool
+	profiler    *string
+	Server      *d2networking.ServerOptions
+	LogLevel    *d2util.LogLevel
+	JSONPackets *bool
 }
 
 const (
@@ -209,6 +210,7 @@ func (a *App) parseArguments() {
 	a.Options.Server.Dedicated = flag.Bool("dedicated", false, "Starts a dedicated server")
 	a.Options.Server.MaxPlayers = flag.Int("players", 0, descPlayers)
 	a.Options.LogLevel = flag.Int("l", d2util.LogLevelDefault, descLogging)
+	a.Options.JSONPackets = flag.Bool("jsonpackets", false, "Asks the server for JSON packets instead of binary ones")
 	showVersion := flag.Bool("v", false, "Show version")
 	showHelp := flag.Bool("h", false, "Show help")
 
@@ -914,6 +916,8 @@ func (a *App) ToCreateGame(filePath string, connType d2clientconnectiontype.Clie
 		a.Error(err.Error())
 	}
 
+	gameClient.SetJSONPackets(*a.Options.JSONPackets)
+
 	if err = gameClient.Open(host, filePath); err != nil {
 		errorMessage := fmt.Sprintf("can not connect to the host: %s", host)
 		a.Error(errorMessage)
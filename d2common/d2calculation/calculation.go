@@ -10,6 +10,13 @@ import (
 type Calculation interface {
 	fmt.Stringer
 	Eval() int
+	EvalWith(ctx Context) int
+}
+
+// Context resolves the property references of a calculation, such as the level of a skill or
+// the value of a stat of the unit the calculation is evaluated for.
+type Context interface {
+	Reference(propType, propName, qualifier string) int
 }
 
 // BinaryCalculation is a calculation with a binary function or operator.
@@ -26,7 +33,12 @@ type BinaryCalculation struct {
 
 // Eval evaluates the calculation.
 func (node *BinaryCalculation) Eval() int {
-	return node.Op(node.Left.Eval(), node.Right.Eval())
+	return node.EvalWith(nil)
+}
+
+// EvalWith evaluates the calculation in the given context.
+func (node *BinaryCalculation) EvalWith(ctx Context) int {
+	return node.Op(node.Left.EvalWith(ctx), node.Right.EvalWith(ctx))
 }
 
 func (node *BinaryCalculation) String() string {
@@ -44,7 +56,12 @@ type UnaryCalculation struct {
 
 // Eval evaluates the calculation.
 func (node *UnaryCalculation) Eval() int {
-	return node.Op(node.Child.Eval())
+	return node.EvalWith(nil)
+}
+
+// EvalWith evaluates the calculation in the given context.
+func (node *UnaryCalculation) EvalWith(ctx Context) int {
+	return node.Op(node.Child.EvalWith(ctx))
 }
 
 func (node *UnaryCalculation) String() string {
@@ -66,7 +83,12 @@ type TernaryCalculation struct {
 
 // Eval evaluates the calculation.
 func (node *TernaryCalculation) Eval() int {
-	return node.Op(node.Left.Eval(), node.Middle.Eval(), node.Right.Eval())
+	return node.EvalWith(nil)
+}
+
+// EvalWith evaluates the calculation in the given context.
+func (node *TernaryCalculation) EvalWith(ctx Context) int {
+	return node.Op(node.Left.EvalWith(ctx), node.Middle.EvalWith(ctx), node.Right.EvalWith(ctx))
 }
 
 func (node *TernaryCalculation) String() string {
@@ -80,9 +102,18 @@ type PropertyReferenceCalculation struct {
 	Qualifier string
 }
 
-// Eval evaluates the calculation.
+// Eval evaluates the calculation, a property cannot be resolved without a context and is 0.
 func (node *PropertyReferenceCalculation) Eval() int {
-	return 1
+	return node.EvalWith(nil)
+}
+
+// EvalWith evaluates the calculation by resolving the property in the given context.
+func (node *PropertyReferenceCalculation) EvalWith(ctx Context) int {
+	if ctx == nil {
+		return 0
+	}
+
+	return ctx.Reference(node.Type, node.Name, node.Qualifier)
 }
 
 func (node *PropertyReferenceCalculation) String() string {
@@ -100,6 +131,11 @@ func (node *ConstantCalculation) Eval() int {
 	return node.Value
 }
 
+// EvalWith evaluates the calculation, a constant does not depend on the context.
+func (node *ConstantCalculation) EvalWith(Context) int {
+	return node.Value
+}
+
 func (node *ConstantCalculation) String() string {
 	return strconv.Itoa(node.Value)
 }
@@ -264,6 +264,50 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+type testContext map[string]int
+
+func (ctx testContext) Reference(propType, propName, qualifier string) int {
+	return ctx[propType+"/"+propName+"/"+qualifier]
+}
+
+func TestPropertyReferences(t *testing.T) {
+	parser := New()
+	parser.SetCurrentReference("skill", "Fire Ball")
+
+	ctx := testContext{
+		"skill/Fire Ball/lvl":  5,
+		"skill/Fire Ball/ln12": 14,
+		"skill/Fire Bolt/blvl": 3,
+		"stat/strength/accr":   40,
+	}
+
+	table := []struct {
+		expr   string
+		result int
+	}{
+		{"lvl", 5},
+		{"ln12*2", 28},
+		{"skill('Fire Bolt'.blvl)*14", 42},
+		{"(skill('Fire Bolt'.blvl)+skill('Meteor'.blvl))*14", 42},
+		{"stat('strength'.accr)/4", 10},
+		{"lvl < 10 ? ln12 : 0", 14},
+		{"min(lvl, 3)", 3},
+	}
+
+	for _, row := range table {
+		c := parser.Parse(row.expr)
+		res := c.EvalWith(ctx)
+
+		if res != row.result {
+			t.Errorf("Expression %v gave wrong result, got %d, want %d", row.expr, res, row.result)
+		}
+
+		if res := c.Eval(); row.expr == "lvl" && res != 0 {
+			t.Errorf("Expression %v without a context gave %d, want 0", row.expr, res)
+		}
+	}
+}
+
 func TestRandFunction(t *testing.T) {
 	parser := New()
 	c := parser.Parse("rand(1,5)")
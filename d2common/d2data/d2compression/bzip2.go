@@ -0,0 +1,493 @@
+package d2compression
+
+import (
+	"bytes"
+	"container/heap"
+)
+
+// bzip2 format constants
+const (
+	bzip2BlockSizeLevel  = 9
+	bzip2MaxBlockRawSize = 700000 // keeps the run length encoded block below 900k bytes
+	bzip2BlockMagic      = 0x314159265359
+	bzip2EndMagic        = 0x177245385090
+	bzip2MagicBits       = 48
+	bzip2OrigPtrBits     = 24
+	bzip2NumGroupsBits   = 3
+	bzip2SelectorsBits   = 15
+	bzip2LengthBits      = 5
+	bzip2NumGroups       = 2
+	bzip2GroupSize       = 50
+	bzip2MaxCodeLength   = 17
+	bzip2MinRun          = 4
+	bzip2MaxRun          = 255
+	bzip2RunA            = 0
+	bzip2RunB            = 1
+	bzip2CRCPolynomial   = 0x04C11DB7
+	bzip2SymbolRanges    = 16
+	bzip2RangeSize       = 16
+	bzip2SymbolMapBits   = 16
+	bzip2LengthIncrease  = 2 // binary 10
+	bzip2LengthDecrease  = 3 // binary 11
+	bzip2LengthDeltaBits = 2
+)
+
+// msbBitWriter writes bits most significant bit first
+type msbBitWriter struct {
+	out   bytes.Buffer
+	acc   uint64
+	count uint
+}
+
+func (w *msbBitWriter) writeBits(value uint64, count uint) {
+	for count > 0 {
+		count--
+		w.acc = (w.acc << 1) | ((value >> count) & 1)
+		w.count++
+
+		if w.count == 8 { //nolint:gomnd // bits in a byte
+			w.out.WriteByte(byte(w.acc))
+			w.acc = 0
+			w.count = 0
+		}
+	}
+}
+
+func (w *msbBitWriter) flush() []byte {
+	if w.count > 0 {
+		w.out.WriteByte(byte(w.acc << (8 - w.count))) //nolint:gomnd // pad the last byte
+		w.acc = 0
+		w.count = 0
+	}
+
+	return w.out.Bytes()
+}
+
+// BZip2Compress compresses data into a bzip2 stream
+func BZip2Compress(data []byte) []byte {
+	w := &msbBitWriter{}
+
+	w.out.WriteString("BZh")
+	w.out.WriteByte('0' + bzip2BlockSizeLevel)
+
+	crcTable := bzip2CRCTable()
+	combinedCRC := uint32(0)
+
+	for start := 0; start < len(data); start += bzip2MaxBlockRawSize {
+		end := start + bzip2MaxBlockRawSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		blockCRC := bzip2CRC(crcTable, data[start:end])
+		combinedCRC = ((combinedCRC << 1) | (combinedCRC >> 31)) ^ blockCRC //nolint:gomnd // rotate left
+
+		bzip2WriteBlock(w, data[start:end], blockCRC)
+	}
+
+	w.writeBits(bzip2EndMagic, bzip2MagicBits)
+	w.writeBits(uint64(combinedCRC), 32) //nolint:gomnd // crc size
+
+	return w.flush()
+}
+
+func bzip2CRCTable() []uint32 {
+	table := make([]uint32, 256) //nolint:gomnd // one entry per byte value
+
+	for i := range table {
+		crc := uint32(i) << 24 //nolint:gomnd // crc of the byte in the top bits
+
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = (crc << 1) ^ bzip2CRCPolynomial
+			} else {
+				crc <<= 1
+			}
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}
+
+func bzip2CRC(table []uint32, data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+
+	for _, b := range data {
+		crc = (crc << 8) ^ table[byte(crc>>24)^b] //nolint:gomnd // byte wise crc
+	}
+
+	return ^crc
+}
+
+// bzip2RunLengthEncode applies the initial run length encoding, where runs of 4 to 255 equal
+// bytes are stored as 4 bytes followed by the number of additional repetitions
+func bzip2RunLengthEncode(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+
+	for i := 0; i < len(data); {
+		run := 1
+		for i+run < len(data) && data[i+run] == data[i] && run < bzip2MaxRun {
+			run++
+		}
+
+		if run < bzip2MinRun {
+			out = append(out, data[i:i+run]...)
+		} else {
+			out = append(out, data[i], data[i], data[i], data[i], byte(run-bzip2MinRun))
+		}
+
+		i += run
+	}
+
+	return out
+}
+
+// bzip2SortRotations returns the start indices of all rotations of data in sorted order
+func bzip2SortRotations(data []byte) []int {
+	n := len(data)
+	order := make([]int, n)
+	rank := make([]int, n)
+	counts := make([]int, 256) //nolint:gomnd // one entry per byte value
+
+	for _, b := range data {
+		counts[b]++
+	}
+
+	for i := 1; i < len(counts); i++ {
+		counts[i] += counts[i-1]
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		counts[data[i]]--
+		order[counts[data[i]]] = i
+	}
+
+	classes := 1
+
+	for i := 1; i < n; i++ {
+		if data[order[i]] != data[order[i-1]] {
+			classes++
+		}
+
+		rank[order[i]] = classes - 1
+	}
+
+	byFirst := make([]int, n)
+	newRank := make([]int, n)
+
+	for k := 1; k < n && classes < n; k <<= 1 {
+		// sorting by the second half is a shift of the current order
+		for i := range order {
+			byFirst[i] = (order[i] - k + n) % n
+		}
+
+		counts = make([]int, classes)
+		for i := range byFirst {
+			counts[rank[byFirst[i]]]++
+		}
+
+		for i := 1; i < classes; i++ {
+			counts[i] += counts[i-1]
+		}
+
+		for i := n - 1; i >= 0; i-- {
+			counts[rank[byFirst[i]]]--
+			order[counts[rank[byFirst[i]]]] = byFirst[i]
+		}
+
+		newRank[order[0]] = 0
+		classes = 1
+
+		for i := 1; i < n; i++ {
+			cur, prev := order[i], order[i-1]
+			if rank[cur] != rank[prev] || rank[(cur+k)%n] != rank[(prev+k)%n] {
+				classes++
+			}
+
+			newRank[cur] = classes - 1
+		}
+
+		rank, newRank = newRank, rank
+	}
+
+	return order
+}
+
+func bzip2WriteBlock(w *msbBitWriter, raw []byte, blockCRC uint32) {
+	block := bzip2RunLengthEncode(raw)
+	order := bzip2SortRotations(block)
+
+	origPtr := 0
+	last := make([]byte, len(block))
+
+	for i, start := range order {
+		if start == 0 {
+			origPtr = i
+		}
+
+		last[i] = block[(start+len(block)-1)%len(block)]
+	}
+
+	var inUse [256]bool
+
+	for _, b := range block {
+		inUse[b] = true
+	}
+
+	symbols, alphaSize := bzip2MoveToFront(last, &inUse)
+	lengths := bzip2CodeLengths(symbols, alphaSize)
+	codes := bzip2Codes(lengths)
+
+	w.writeBits(bzip2BlockMagic, bzip2MagicBits)
+	w.writeBits(uint64(blockCRC), 32) //nolint:gomnd // crc size
+	w.writeBits(0, 1)                 // not randomised
+	w.writeBits(uint64(origPtr), bzip2OrigPtrBits)
+
+	bzip2WriteSymbolMap(w, &inUse)
+
+	numSelectors := (len(symbols) + bzip2GroupSize - 1) / bzip2GroupSize
+
+	w.writeBits(bzip2NumGroups, bzip2NumGroupsBits)
+	w.writeBits(uint64(numSelectors), bzip2SelectorsBits)
+
+	// every group uses the first table, which is a move to front index of zero
+	for i := 0; i < numSelectors; i++ {
+		w.writeBits(0, 1)
+	}
+
+	for table := 0; table < bzip2NumGroups; table++ {
+		current := lengths[0]
+		w.writeBits(uint64(current), bzip2LengthBits)
+
+		for _, length := range lengths {
+			for current < length {
+				w.writeBits(bzip2LengthIncrease, bzip2LengthDeltaBits)
+				current++
+			}
+
+			for current > length {
+				w.writeBits(bzip2LengthDecrease, bzip2LengthDeltaBits)
+				current--
+			}
+
+			w.writeBits(0, 1)
+		}
+	}
+
+	for _, symbol := range symbols {
+		w.writeBits(uint64(codes[symbol]), uint(lengths[symbol]))
+	}
+}
+
+func bzip2WriteSymbolMap(w *msbBitWriter, inUse *[256]bool) {
+	ranges := uint64(0)
+
+	for r := 0; r < bzip2SymbolRanges; r++ {
+		for i := 0; i < bzip2RangeSize; i++ {
+			if inUse[r*bzip2RangeSize+i] {
+				ranges |= 1 << (bzip2SymbolRanges - 1 - r)
+				break
+			}
+		}
+	}
+
+	w.writeBits(ranges, bzip2SymbolMapBits)
+
+	for r := 0; r < bzip2SymbolRanges; r++ {
+		if ranges&(1<<(bzip2SymbolRanges-1-r)) == 0 {
+			continue
+		}
+
+		bits := uint64(0)
+
+		for i := 0; i < bzip2RangeSize; i++ {
+			if inUse[r*bzip2RangeSize+i] {
+				bits |= 1 << (bzip2RangeSize - 1 - i)
+			}
+		}
+
+		w.writeBits(bits, bzip2SymbolMapBits)
+	}
+}
+
+// bzip2MoveToFront applies the move to front transform and the zero run length encoding to
+// the last column of the sorted rotations, returning the symbols and the alphabet size
+func bzip2MoveToFront(last []byte, inUse *[256]bool) (symbols []uint16, alphaSize int) {
+	list := make([]byte, 0, len(inUse))
+
+	for b := range inUse {
+		if inUse[b] {
+			list = append(list, byte(b))
+		}
+	}
+
+	endOfBlock := uint16(len(list) + 1)
+	symbols = make([]uint16, 0, len(last)+1)
+	zeroes := 0
+
+	flushZeroes := func() {
+		if zeroes == 0 {
+			return
+		}
+
+		zeroes--
+
+		for {
+			if zeroes&1 != 0 {
+				symbols = append(symbols, bzip2RunB)
+			} else {
+				symbols = append(symbols, bzip2RunA)
+			}
+
+			if zeroes < 2 { //nolint:gomnd // bijective base 2
+				break
+			}
+
+			zeroes = (zeroes - 2) / 2 //nolint:gomnd // bijective base 2
+		}
+
+		zeroes = 0
+	}
+
+	for _, b := range last {
+		pos := bytes.IndexByte(list, b)
+
+		if pos == 0 {
+			zeroes++
+			continue
+		}
+
+		flushZeroes()
+
+		copy(list[1:pos+1], list[:pos])
+		list[0] = b
+
+		symbols = append(symbols, uint16(pos+1))
+	}
+
+	flushZeroes()
+
+	symbols = append(symbols, endOfBlock)
+
+	return symbols, int(endOfBlock) + 1
+}
+
+// bzip2CodeLengths builds huffman code lengths which do not exceed the maximum code length
+func bzip2CodeLengths(symbols []uint16, alphaSize int) []uint8 {
+	freqs := make([]int, alphaSize)
+
+	for _, symbol := range symbols {
+		freqs[symbol]++
+	}
+
+	for {
+		lengths, maxLength := huffmanCodeLengths(freqs)
+		if maxLength <= bzip2MaxCodeLength {
+			return lengths
+		}
+
+		for i := range freqs {
+			freqs[i] = freqs[i]/2 + 1 //nolint:gomnd // flatten the distribution
+		}
+	}
+}
+
+// bzip2Codes assigns canonical codes, ordered by length and then by symbol
+func bzip2Codes(lengths []uint8) []uint32 {
+	codes := make([]uint32, len(lengths))
+	code := uint32(0)
+
+	for length := uint8(1); length <= bzip2MaxCodeLength; length++ {
+		for symbol := range lengths {
+			if lengths[symbol] == length {
+				codes[symbol] = code
+				code++
+			}
+		}
+
+		code <<= 1
+	}
+
+	return codes
+}
+
+type huffmanHeapNode struct {
+	weight int
+	order  int
+	parent *huffmanHeapNode
+}
+
+type huffmanHeap []*huffmanHeapNode
+
+func (h huffmanHeap) Len() int { return len(h) }
+
+func (h huffmanHeap) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight < h[j].weight
+	}
+
+	return h[i].order < h[j].order
+}
+
+func (h huffmanHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *huffmanHeap) Push(x interface{}) { *h = append(*h, x.(*huffmanHeapNode)) }
+
+func (h *huffmanHeap) Pop() interface{} {
+	old := *h
+	node := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return node
+}
+
+// huffmanCodeLengths computes huffman code lengths for the given frequencies. Every symbol gets
+// a code, even if it does not occur.
+func huffmanCodeLengths(freqs []int) (lengths []uint8, maxLength int) {
+	leaves := make([]*huffmanHeapNode, len(freqs))
+	h := &huffmanHeap{}
+
+	for symbol, freq := range freqs {
+		if freq == 0 {
+			freq = 1
+		}
+
+		leaves[symbol] = &huffmanHeapNode{weight: freq, order: symbol}
+		heap.Push(h, leaves[symbol])
+	}
+
+	order := len(freqs)
+
+	for h.Len() > 1 {
+		a := heap.Pop(h).(*huffmanHeapNode)
+		b := heap.Pop(h).(*huffmanHeapNode)
+		parent := &huffmanHeapNode{weight: a.weight + b.weight, order: order}
+		a.parent, b.parent = parent, parent
+		order++
+
+		heap.Push(h, parent)
+	}
+
+	lengths = make([]uint8, len(freqs))
+
+	for symbol, leaf := range leaves {
+		depth := 0
+		for node := leaf; node.parent != nil; node = node.parent {
+			depth++
+		}
+
+		if depth == 0 {
+			depth = 1
+		}
+
+		lengths[symbol] = uint8(depth)
+
+		if depth > maxLength {
+			maxLength = depth
+		}
+	}
+
+	return lengths, maxLength
+}
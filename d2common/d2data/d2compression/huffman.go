@@ -205,7 +205,7 @@ func decode(input *d2datautils.BitStream, head *linkedNode) *linkedNode {
 	for node.child0 != nil {
 		bit := input.ReadBits(1)
 		if bit == -1 {
-			log.Fatal("unexpected end of file")
+			log.Panic("unexpected end of file")
 		}
 
 		if bit == 0 {
@@ -322,7 +322,7 @@ func adjustTree(newNode *linkedNode) {
 
 		// insert current after prev
 		if prev == nil {
-			log.Fatal("previous frame not defined!")
+			log.Panic("previous frame not defined!")
 		}
 
 		temp := prev.next
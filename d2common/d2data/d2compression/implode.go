@@ -0,0 +1,244 @@
+package d2compression
+
+import (
+	"bytes"
+)
+
+// PKWARE Data Compression Library (implode) format constants, see blast.c by Mark Adler
+const (
+	implodeBinaryLiterals = 0
+	implodeDictBits       = 6 // 4096 byte dictionary
+	implodeMinMatch       = 2
+	implodeMaxMatch       = 518
+	implodeEndOfStream    = 519
+	implodeShortDistBits  = 2
+	implodeMaxShortDist   = 1 << (implodeShortDistBits + 6)
+	implodeHashBits       = 12
+	implodeMaxChain       = 64
+	implodeMinHashMatch   = 3
+	implodeLiteralBits    = 8
+	implodeDictionarySize = 64 << implodeDictBits
+)
+
+// code lengths in the compacted blast.c representation, length bases and extra bits
+var ( //nolint:gochecknoglobals // constant lookup tables
+	implodeLenLengths  = []byte{2, 35, 36, 53, 38, 23}
+	implodeDistLengths = []byte{2, 20, 53, 230, 247, 151, 248}
+	implodeLenBase     = []int{3, 2, 4, 5, 6, 7, 8, 9, 10, 12, 16, 24, 40, 72, 136, 264}
+	implodeLenExtra    = []uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8}
+)
+
+// implodeCode is a huffman code, already inverted and bit reversed for an LSB first bit stream
+type implodeCode struct {
+	bits   uint32
+	length uint
+}
+
+// implodeCodes builds the codes for the compacted code lengths the same way blast.c decodes them
+func implodeCodes(compacted []byte) []implodeCode {
+	lengths := make([]uint, 0)
+
+	for _, rep := range compacted {
+		count := int(rep>>4) + 1 //nolint:gomnd // high nibble is the repeat count
+		for i := 0; i < count; i++ {
+			lengths = append(lengths, uint(rep&0x0F)) //nolint:gomnd // low nibble is the length
+		}
+	}
+
+	codes := make([]implodeCode, len(lengths))
+	code := uint32(0)
+
+	for length := uint(1); length <= 16; length++ { //nolint:gomnd // max code length
+		for symbol := range lengths {
+			if lengths[symbol] != length {
+				continue
+			}
+
+			// codes are stored inverted, most significant bit first
+			reversed := uint32(0)
+
+			for i := uint(0); i < length; i++ {
+				bit := (code >> (length - 1 - i)) & 1
+				reversed |= (bit ^ 1) << i
+			}
+
+			codes[symbol] = implodeCode{bits: reversed, length: length}
+			code++
+		}
+
+		code <<= 1
+	}
+
+	return codes
+}
+
+// lsbBitWriter writes bits least significant bit first
+type lsbBitWriter struct {
+	out   bytes.Buffer
+	acc   uint32
+	count uint
+}
+
+func (w *lsbBitWriter) writeBits(value uint32, count uint) {
+	for i := uint(0); i < count; i++ {
+		w.acc |= ((value >> i) & 1) << w.count
+		w.count++
+
+		if w.count == 8 { //nolint:gomnd // bits in a byte
+			w.out.WriteByte(byte(w.acc))
+			w.acc = 0
+			w.count = 0
+		}
+	}
+}
+
+func (w *lsbBitWriter) flush() []byte {
+	if w.count > 0 {
+		w.out.WriteByte(byte(w.acc))
+		w.acc = 0
+		w.count = 0
+	}
+
+	return w.out.Bytes()
+}
+
+type implodeEncoder struct {
+	w         lsbBitWriter
+	lenCodes  []implodeCode
+	distCodes []implodeCode
+}
+
+func (e *implodeEncoder) literal(b byte) {
+	e.w.writeBits(0, 1)
+	e.w.writeBits(uint32(b), implodeLiteralBits)
+}
+
+func (e *implodeEncoder) length(length int) {
+	e.w.writeBits(1, 1)
+
+	for symbol := len(implodeLenBase) - 1; symbol >= 0; symbol-- {
+		if length < implodeLenBase[symbol] || length >= implodeLenBase[symbol]+(1<<implodeLenExtra[symbol]) {
+			continue
+		}
+
+		e.w.writeBits(e.lenCodes[symbol].bits, e.lenCodes[symbol].length)
+		e.w.writeBits(uint32(length-implodeLenBase[symbol]), implodeLenExtra[symbol])
+
+		return
+	}
+}
+
+func (e *implodeEncoder) match(length, distance int) {
+	e.length(length)
+
+	lowBits := uint(implodeDictBits)
+	if length == implodeMinMatch {
+		lowBits = implodeShortDistBits
+	}
+
+	dist := uint32(distance - 1)
+	symbol := dist >> lowBits
+
+	e.w.writeBits(e.distCodes[symbol].bits, e.distCodes[symbol].length)
+	e.w.writeBits(dist&((1<<lowBits)-1), lowBits)
+}
+
+func implodeHash(data []byte, pos int) int {
+	h := int(data[pos])<<8 ^ int(data[pos+1])<<4 ^ int(data[pos+2]) //nolint:gomnd // hash mixing
+
+	return h & ((1 << implodeHashBits) - 1)
+}
+
+// PKCompress compresses data with the PKWARE Data Compression Library (implode) format, using
+// uncoded literals and a 4096 byte dictionary. It can be decompressed with blast.
+func PKCompress(data []byte) []byte {
+	enc := &implodeEncoder{
+		lenCodes:  implodeCodes(implodeLenLengths),
+		distCodes: implodeCodes(implodeDistLengths),
+	}
+
+	enc.w.out.WriteByte(implodeBinaryLiterals)
+	enc.w.out.WriteByte(implodeDictBits)
+
+	head := make([]int, 1<<implodeHashBits)
+	prev := make([]int, len(data))
+
+	for i := range head {
+		head[i] = -1
+	}
+
+	insert := func(pos int) {
+		if pos+implodeMinHashMatch > len(data) {
+			return
+		}
+
+		h := implodeHash(data, pos)
+		prev[pos] = head[h]
+		head[h] = pos
+	}
+
+	for pos := 0; pos < len(data); {
+		bestLen, bestDist := 0, 0
+
+		if pos+implodeMinHashMatch <= len(data) {
+			maxLen := len(data) - pos
+			if maxLen > implodeMaxMatch {
+				maxLen = implodeMaxMatch
+			}
+
+			chain := 0
+
+			for candidate := head[implodeHash(data, pos)]; candidate >= 0 && chain < implodeMaxChain; candidate = prev[candidate] {
+				distance := pos - candidate
+				if distance > implodeDictionarySize {
+					break
+				}
+
+				chain++
+
+				matchLen := 0
+				for matchLen < maxLen && data[candidate+matchLen] == data[pos+matchLen] {
+					matchLen++
+				}
+
+				if matchLen > bestLen {
+					bestLen, bestDist = matchLen, distance
+				}
+			}
+		}
+
+		if bestLen < implodeMinHashMatch {
+			bestLen = 0
+		}
+
+		if bestLen == 0 && pos > 0 && pos+1 < len(data) {
+			// try a short match with the previous bytes
+			for distance := 1; distance <= pos && distance <= implodeMaxShortDist; distance++ {
+				if data[pos-distance] == data[pos] && data[pos-distance+1] == data[pos+1] {
+					bestLen, bestDist = implodeMinMatch, distance
+					break
+				}
+			}
+		}
+
+		if bestLen < implodeMinMatch {
+			enc.literal(data[pos])
+			insert(pos)
+			pos++
+
+			continue
+		}
+
+		enc.match(bestLen, bestDist)
+
+		for i := 0; i < bestLen; i++ {
+			insert(pos + i)
+		}
+
+		pos += bestLen
+	}
+
+	enc.length(implodeEndOfStream)
+
+	return enc.w.flush()
+}
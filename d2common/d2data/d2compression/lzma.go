@@ -0,0 +1,455 @@
+package d2compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	lzmaPropsSize         = 5
+	lzmaMaxPropsValue     = 9 * 5 * 5
+	lzmaLiteralContexts   = 9
+	lzmaLiteralPositions  = 5
+	lzmaMinDictionarySize = 1 << 12
+
+	lzmaNumStates          = 12
+	lzmaNumPosBitsMax      = 4
+	lzmaNumLenToPosStates  = 4
+	lzmaNumAlignBits       = 4
+	lzmaStartPosModelIndex = 4
+	lzmaEndPosModelIndex   = 14
+	lzmaNumFullDistances   = 1 << (lzmaEndPosModelIndex >> 1)
+	lzmaMatchMinLen        = 2
+	lzmaNumPosSlotBits     = 6
+
+	lzmaLenLowBits   = 3
+	lzmaLenMidBits   = 3
+	lzmaLenHighBits  = 8
+	lzmaLenLowCount  = 1 << lzmaLenLowBits
+	lzmaLenMidCount  = 1 << lzmaLenMidBits
+	lzmaLiteralCoder = 0x300
+
+	lzmaNumBitModelTotalBits = 11
+	lzmaBitModelTotal        = 1 << lzmaNumBitModelTotalBits
+	lzmaNumMoveBits          = 5
+	lzmaProbInitValue        = lzmaBitModelTotal / 2
+	lzmaTopValue             = 1 << 24
+
+	lzmaEndMarkerDistance = 0xFFFFFFFF
+)
+
+var (
+	errLzmaCorrupted = errors.New("lzma: corrupted data")
+	errLzmaTruncated = errors.New("lzma: unexpected end of data")
+)
+
+// LzmaDecompress decompresses a raw LZMA stream, described by the given 5 properties bytes,
+// into a buffer of exactly outSize bytes.
+func LzmaDecompress(props, data []byte, outSize int) ([]byte, error) {
+	if len(props) < lzmaPropsSize {
+		return nil, errors.New("lzma: properties are too short")
+	}
+
+	d := int(props[0])
+	if d >= lzmaMaxPropsValue {
+		return nil, fmt.Errorf("lzma: invalid properties byte %#x", props[0])
+	}
+
+	dec := &lzmaDecoder{
+		lc:       uint(d % lzmaLiteralContexts),
+		lp:       uint((d / lzmaLiteralContexts) % lzmaLiteralPositions),
+		pb:       uint(d / (lzmaLiteralContexts * lzmaLiteralPositions)),
+		dictSize: binary.LittleEndian.Uint32(props[1:lzmaPropsSize]),
+		out:      make([]byte, 0, outSize),
+		outSize:  outSize,
+	}
+
+	if dec.dictSize < lzmaMinDictionarySize {
+		dec.dictSize = lzmaMinDictionarySize
+	}
+
+	if err := dec.rc.init(data); err != nil {
+		return nil, err
+	}
+
+	dec.initProbabilities()
+
+	if err := dec.decode(); err != nil {
+		return nil, err
+	}
+
+	return dec.out, nil
+}
+
+// lzmaRangeDecoder is the arithmetic decoder which all LZMA symbols are read with
+type lzmaRangeDecoder struct {
+	data  []byte
+	pos   int
+	rng   uint32
+	code  uint32
+	error error
+}
+
+func (rc *lzmaRangeDecoder) init(data []byte) error {
+	rc.data = data
+	rc.rng = 0xFFFFFFFF
+
+	first := rc.readByte()
+
+	for i := 0; i < 4; i++ {
+		rc.code = (rc.code << 8) | uint32(rc.readByte()) //nolint:gomnd // shift in one byte
+	}
+
+	if first != 0 || rc.code == rc.rng {
+		return errLzmaCorrupted
+	}
+
+	return rc.error
+}
+
+func (rc *lzmaRangeDecoder) readByte() byte {
+	if rc.pos >= len(rc.data) {
+		rc.error = errLzmaTruncated
+		return 0
+	}
+
+	b := rc.data[rc.pos]
+	rc.pos++
+
+	return b
+}
+
+func (rc *lzmaRangeDecoder) normalize() {
+	if rc.rng < lzmaTopValue {
+		rc.rng <<= 8
+		rc.code = (rc.code << 8) | uint32(rc.readByte()) //nolint:gomnd // shift in one byte
+	}
+}
+
+func (rc *lzmaRangeDecoder) decodeDirectBits(numBits uint) uint32 {
+	var result uint32
+
+	for ; numBits > 0; numBits-- {
+		rc.rng >>= 1
+		rc.code -= rc.rng
+		t := 0 - (rc.code >> 31) //nolint:gomnd // sign bit
+		rc.code += rc.rng & t
+
+		if rc.code == rc.rng {
+			rc.error = errLzmaCorrupted
+		}
+
+		rc.normalize()
+
+		result = (result << 1) + (t + 1)
+	}
+
+	return result
+}
+
+func (rc *lzmaRangeDecoder) decodeBit(prob *uint16) uint32 {
+	v := uint32(*prob)
+	bound := (rc.rng >> lzmaNumBitModelTotalBits) * v
+
+	var symbol uint32
+
+	if rc.code < bound {
+		v += (lzmaBitModelTotal - v) >> lzmaNumMoveBits
+		rc.rng = bound
+	} else {
+		v -= v >> lzmaNumMoveBits
+		rc.code -= bound
+		rc.rng -= bound
+		symbol = 1
+	}
+
+	*prob = uint16(v)
+
+	rc.normalize()
+
+	return symbol
+}
+
+func (rc *lzmaRangeDecoder) bitTreeDecode(probs []uint16, numBits uint) uint32 {
+	m := uint32(1)
+
+	for i := uint(0); i < numBits; i++ {
+		m = (m << 1) + rc.decodeBit(&probs[m])
+	}
+
+	return m - (1 << numBits)
+}
+
+func (rc *lzmaRangeDecoder) bitTreeReverseDecode(probs []uint16, numBits uint) uint32 {
+	m := uint32(1)
+	symbol := uint32(0)
+
+	for i := uint(0); i < numBits; i++ {
+		bit := rc.decodeBit(&probs[m])
+		m = (m << 1) + bit
+		symbol |= bit << i
+	}
+
+	return symbol
+}
+
+// lzmaLenDecoder decodes the length of a match
+type lzmaLenDecoder struct {
+	choice  uint16
+	choice2 uint16
+	low     [1 << lzmaNumPosBitsMax][lzmaLenLowCount]uint16
+	mid     [1 << lzmaNumPosBitsMax][lzmaLenMidCount]uint16
+	high    [1 << lzmaLenHighBits]uint16
+}
+
+func (ld *lzmaLenDecoder) init() {
+	ld.choice = lzmaProbInitValue
+	ld.choice2 = lzmaProbInitValue
+
+	initProbs(ld.high[:])
+
+	for i := range ld.low {
+		initProbs(ld.low[i][:])
+		initProbs(ld.mid[i][:])
+	}
+}
+
+func (ld *lzmaLenDecoder) decode(rc *lzmaRangeDecoder, posState uint32) uint32 {
+	if rc.decodeBit(&ld.choice) == 0 {
+		return rc.bitTreeDecode(ld.low[posState][:], lzmaLenLowBits)
+	}
+
+	if rc.decodeBit(&ld.choice2) == 0 {
+		return lzmaLenLowCount + rc.bitTreeDecode(ld.mid[posState][:], lzmaLenMidBits)
+	}
+
+	return lzmaLenLowCount + lzmaLenMidCount + rc.bitTreeDecode(ld.high[:], lzmaLenHighBits)
+}
+
+type lzmaDecoder struct {
+	rc lzmaRangeDecoder
+
+	lc, lp, pb uint
+	dictSize   uint32
+
+	literalProbs []uint16
+	posSlot      [lzmaNumLenToPosStates][1 << lzmaNumPosSlotBits]uint16
+	posDecoders  [1 + lzmaNumFullDistances - lzmaEndPosModelIndex]uint16
+	align        [1 << lzmaNumAlignBits]uint16
+
+	isMatch    [lzmaNumStates << lzmaNumPosBitsMax]uint16
+	isRep      [lzmaNumStates]uint16
+	isRepG0    [lzmaNumStates]uint16
+	isRepG1    [lzmaNumStates]uint16
+	isRepG2    [lzmaNumStates]uint16
+	isRep0Long [lzmaNumStates << lzmaNumPosBitsMax]uint16
+
+	lenDecoder    lzmaLenDecoder
+	repLenDecoder lzmaLenDecoder
+
+	out     []byte
+	outSize int
+}
+
+func initProbs(probs []uint16) {
+	for i := range probs {
+		probs[i] = lzmaProbInitValue
+	}
+}
+
+func (d *lzmaDecoder) initProbabilities() {
+	d.literalProbs = make([]uint16, lzmaLiteralCoder<<(d.lc+d.lp))
+	initProbs(d.literalProbs)
+
+	for i := range d.posSlot {
+		initProbs(d.posSlot[i][:])
+	}
+
+	initProbs(d.posDecoders[:])
+	initProbs(d.align[:])
+	initProbs(d.isMatch[:])
+	initProbs(d.isRep[:])
+	initProbs(d.isRepG0[:])
+	initProbs(d.isRepG1[:])
+	initProbs(d.isRepG2[:])
+	initProbs(d.isRep0Long[:])
+
+	d.lenDecoder.init()
+	d.repLenDecoder.init()
+}
+
+func (d *lzmaDecoder) decodeLiteral(state, rep0 uint32) {
+	prevByte := uint32(0)
+	if len(d.out) > 0 {
+		prevByte = uint32(d.out[len(d.out)-1])
+	}
+
+	litState := ((uint32(len(d.out)) & ((1 << d.lp) - 1)) << d.lc) + (prevByte >> (8 - d.lc)) //nolint:gomnd // bits in a byte
+	probs := d.literalProbs[lzmaLiteralCoder*litState:]
+	symbol := uint32(1)
+
+	//nolint:gomnd // bit twiddling over a single byte
+	if state >= 7 {
+		matchByte := uint32(d.out[len(d.out)-int(rep0)-1])
+
+		for symbol < 0x100 {
+			matchBit := (matchByte >> 7) & 1
+			matchByte <<= 1
+			bit := d.rc.decodeBit(&probs[((1+matchBit)<<8)+symbol])
+			symbol = (symbol << 1) | bit
+
+			if matchBit != bit {
+				break
+			}
+		}
+	}
+
+	for symbol < 0x100 {
+		symbol = (symbol << 1) | d.rc.decodeBit(&probs[symbol])
+	}
+
+	d.out = append(d.out, byte(symbol-0x100)) //nolint:gomnd // strip the marker bit
+}
+
+func (d *lzmaDecoder) decodeDistance(length uint32) uint32 {
+	lenState := length
+	if lenState > lzmaNumLenToPosStates-1 {
+		lenState = lzmaNumLenToPosStates - 1
+	}
+
+	posSlot := d.rc.bitTreeDecode(d.posSlot[lenState][:], lzmaNumPosSlotBits)
+	if posSlot < lzmaStartPosModelIndex {
+		return posSlot
+	}
+
+	numDirectBits := uint((posSlot >> 1) - 1)
+	dist := (2 | (posSlot & 1)) << numDirectBits //nolint:gomnd // top two bits of the distance
+
+	if posSlot < lzmaEndPosModelIndex {
+		return dist + d.rc.bitTreeReverseDecode(d.posDecoders[dist-posSlot:], numDirectBits)
+	}
+
+	dist += d.rc.decodeDirectBits(numDirectBits-lzmaNumAlignBits) << lzmaNumAlignBits
+
+	return dist + d.rc.bitTreeReverseDecode(d.align[:], lzmaNumAlignBits)
+}
+
+//nolint:gomnd,funlen,gocyclo,gocognit // state machine constants from the LZMA specification
+func (d *lzmaDecoder) decode() error {
+	var state, rep0, rep1, rep2, rep3 uint32
+
+	pbMask := uint32(1<<d.pb) - 1
+
+	for len(d.out) < d.outSize {
+		if d.rc.error != nil {
+			return d.rc.error
+		}
+
+		posState := uint32(len(d.out)) & pbMask
+
+		if d.rc.decodeBit(&d.isMatch[(state<<lzmaNumPosBitsMax)+posState]) == 0 {
+			d.decodeLiteral(state, rep0)
+
+			switch {
+			case state < 4:
+				state = 0
+			case state < 10:
+				state -= 3
+			default:
+				state -= 6
+			}
+
+			continue
+		}
+
+		var length uint32
+
+		if d.rc.decodeBit(&d.isRep[state]) != 0 {
+			if len(d.out) == 0 {
+				return errLzmaCorrupted
+			}
+
+			if d.rc.decodeBit(&d.isRepG0[state]) == 0 {
+				if d.rc.decodeBit(&d.isRep0Long[(state<<lzmaNumPosBitsMax)+posState]) == 0 {
+					if state < 7 {
+						state = 9
+					} else {
+						state = 11
+					}
+
+					d.out = append(d.out, d.out[len(d.out)-int(rep0)-1])
+
+					continue
+				}
+			} else {
+				var dist uint32
+
+				if d.rc.decodeBit(&d.isRepG1[state]) == 0 {
+					dist = rep1
+				} else {
+					if d.rc.decodeBit(&d.isRepG2[state]) == 0 {
+						dist = rep2
+					} else {
+						dist = rep3
+						rep3 = rep2
+					}
+
+					rep2 = rep1
+				}
+
+				rep1 = rep0
+				rep0 = dist
+			}
+
+			length = d.repLenDecoder.decode(&d.rc, posState)
+
+			if state < 7 {
+				state = 8
+			} else {
+				state = 11
+			}
+		} else {
+			rep3 = rep2
+			rep2 = rep1
+			rep1 = rep0
+			length = d.lenDecoder.decode(&d.rc, posState)
+
+			if state < 7 {
+				state = 7
+			} else {
+				state = 10
+			}
+
+			rep0 = d.decodeDistance(length)
+
+			if rep0 == lzmaEndMarkerDistance {
+				break
+			}
+
+			if rep0 >= d.dictSize || int(rep0) >= len(d.out) {
+				return errLzmaCorrupted
+			}
+		}
+
+		length += lzmaMatchMinLen
+
+		if remaining := uint32(d.outSize - len(d.out)); length > remaining {
+			length = remaining
+		}
+
+		for i := uint32(0); i < length; i++ {
+			d.out = append(d.out, d.out[len(d.out)-int(rep0)-1])
+		}
+	}
+
+	if d.rc.error != nil {
+		return d.rc.error
+	}
+
+	if len(d.out) != d.outSize {
+		return errLzmaTruncated
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package d2compression
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math"
+)
+
+const (
+	sparseHeaderSize    = 4
+	sparseLiteralFlag   = 0x80
+	sparseLengthMask    = 0x7F
+	sparseMinLiteralRun = 1
+	sparseMinZeroRun    = 3
+	sparseMaxLiteralRun = sparseLengthMask + sparseMinLiteralRun
+	sparseMaxZeroRun    = sparseLengthMask + sparseMinZeroRun
+)
+
+// SparseDecompress decompresses data which was run-length encoded with the
+// sparse compression used by StarCraft II era MPQ archives.
+func SparseDecompress(data []byte) ([]byte, error) {
+	if len(data) < sparseHeaderSize {
+		return nil, errors.New("sparse: data is too short")
+	}
+
+	outSize := int(binary.BigEndian.Uint32(data))
+	output := make([]byte, 0, outSize)
+	input := data[sparseHeaderSize:]
+
+	for len(input) > 0 && len(output) < outSize {
+		control := input[0]
+		input = input[1:]
+
+		if control&sparseLiteralFlag == 0 {
+			chunkSize := d2math.MinInt(int(control&sparseLengthMask)+sparseMinZeroRun, outSize-len(output))
+			output = append(output, make([]byte, chunkSize)...)
+
+			continue
+		}
+
+		chunkSize := d2math.MinInt(int(control&sparseLengthMask)+sparseMinLiteralRun, outSize-len(output))
+		if chunkSize > len(input) {
+			return nil, errors.New("sparse: unexpected end of data")
+		}
+
+		output = append(output, input[:chunkSize]...)
+		input = input[chunkSize:]
+	}
+
+	if len(output) != outSize {
+		return nil, errors.New("sparse: unexpected end of data")
+	}
+
+	return output, nil
+}
+
+// SparseCompress run-length encodes runs of zero bytes in the given data.
+func SparseCompress(data []byte) []byte {
+	output := make([]byte, sparseHeaderSize, len(data)+sparseHeaderSize)
+	binary.BigEndian.PutUint32(output, uint32(len(data)))
+
+	literalStart := 0
+
+	flushLiterals := func(end int) {
+		for literalStart < end {
+			chunkSize := d2math.MinInt(end-literalStart, sparseMaxLiteralRun)
+			output = append(output, sparseLiteralFlag|byte(chunkSize-sparseMinLiteralRun))
+			output = append(output, data[literalStart:literalStart+chunkSize]...)
+			literalStart += chunkSize
+		}
+	}
+
+	for idx := 0; idx < len(data); {
+		zeroes := 0
+		for idx+zeroes < len(data) && data[idx+zeroes] == 0 && zeroes < sparseMaxZeroRun {
+			zeroes++
+		}
+
+		if zeroes < sparseMinZeroRun {
+			idx++
+			continue
+		}
+
+		flushLiterals(idx)
+
+		output = append(output, byte(zeroes-sparseMinZeroRun))
+		idx += zeroes
+		literalStart = idx
+	}
+
+	flushLiterals(len(data))
+
+	return output
+}
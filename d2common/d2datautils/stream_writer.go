@@ -4,7 +4,8 @@ import "bytes"
 
 // StreamWriter allows you to create a byte array by streaming in writes of various sizes
 type StreamWriter struct {
-	data *bytes.Buffer
+	data      *bytes.Buffer
+	bitOffset int // offset of the next bit in the stream, when the last byte was written by PushBits
 }
 
 // CreateStreamWriter creates a new StreamWriter instance
@@ -26,6 +27,11 @@ func (v *StreamWriter) PushByte(val byte) {
 	v.data.WriteByte(val)
 }
 
+// PushBytes writes a bunch of bytes to the stream
+func (v *StreamWriter) PushBytes(b ...byte) {
+	v.data.Write(b)
+}
+
 // PushInt16 writes a int16 word to the stream
 func (v *StreamWriter) PushInt16(val int16) {
 	v.PushUint16(uint16(val))
@@ -69,3 +75,34 @@ func (v *StreamWriter) PushUint64(val uint64) {
 	v.data.WriteByte(byte(val >> 48))
 	v.data.WriteByte(byte(val >> 56))
 }
+
+// PushBit writes a single bit to the stream. Bits are packed from the least significant bit of
+// each byte, the same way BitMuncher reads them, and a byte written after them starts on the next
+// byte boundary.
+func (v *StreamWriter) PushBit(b bool) {
+	if v.bitOffset%byteLen == 0 || v.bitOffset/byteLen != v.data.Len()-1 {
+		v.data.WriteByte(0)
+		v.bitOffset = (v.data.Len() - 1) * byteLen
+	}
+
+	if b {
+		buf := v.data.Bytes()
+		buf[len(buf)-1] |= oneBit << uint(v.bitOffset%byteLen)
+	}
+
+	v.bitOffset++
+}
+
+// PushBits writes the given number of low bits of val to the stream, least significant bit first
+func (v *StreamWriter) PushBits(val uint32, bits int) {
+	for i := 0; i < bits; i++ {
+		v.PushBit(val>>uint(i)&oneBit == oneBit)
+	}
+}
+
+// PushBits64 writes the given number of low bits of a 64 bit value to the stream
+func (v *StreamWriter) PushBits64(val uint64, bits int) {
+	for i := 0; i < bits; i++ {
+		v.PushBit(val>>uint(i)&oneBit == oneBit)
+	}
+}
@@ -48,3 +48,45 @@ func TestStreamWriterDword(t *testing.T) {
 		}
 	}
 }
+
+func TestStreamWriterBits(t *testing.T) {
+	sw := CreateStreamWriter()
+
+	table := []struct {
+		value uint32
+		bits  int
+	}{
+		{0x1, 1},
+		{0x5, 3},
+		{0x1ff, 9},
+		{0x12345, 20},
+		{0x0, 2},
+		{0xdeadbeef, 32},
+	}
+
+	for _, row := range table {
+		sw.PushBits(row.value, row.bits)
+	}
+
+	sw.PushByte(0x7f)
+	sw.PushBits(0x3, 2)
+
+	bm := CreateBitMuncher(sw.GetBytes(), 0)
+
+	for _, row := range table {
+		if got := bm.GetBits(row.bits); got != row.value {
+			t.Fatalf("sw.PushBits() wrote %X in %d bits, but %X was read back", row.value, row.bits, got)
+		}
+	}
+
+	// the byte starts on the next byte boundary
+	bm.SetOffset((bm.Offset() + byteLen - 1) / byteLen * byteLen)
+
+	if got := bm.GetByte(); got != 0x7f {
+		t.Fatalf("sw.PushByte() after bits wrote %X, but 7F was expected", got)
+	}
+
+	if got := bm.GetBits(2); got != 0x3 {
+		t.Fatalf("sw.PushBits() after a byte wrote %X, but 3 was expected", got)
+	}
+}
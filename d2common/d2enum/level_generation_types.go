@@ -11,7 +11,8 @@ type LevelGenerationType int
 
 // Level generation types
 const (
-	LevelTypeRandomMaze LevelGenerationType = iota
+	LevelTypeNone LevelGenerationType = iota
+	LevelTypeRandomMaze
 	LevelTypePreset
 	LevelTypeWilderness
 )
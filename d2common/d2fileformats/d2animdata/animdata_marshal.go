@@ -0,0 +1,89 @@
+package d2animdata
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+)
+
+// New creates an AnimationData without records
+func New() *AnimationData {
+	animdata := &AnimationData{entries: make(map[string][]*AnimationDataRecord)}
+
+	for blockIdx := range animdata.blocks {
+		animdata.blocks[blockIdx] = &block{records: make([]*AnimationDataRecord, 0)}
+	}
+
+	return animdata
+}
+
+// AddRecord adds a record to the block of the hash of its name, the events are keyed by their
+// frame index
+func (ad *AnimationData) AddRecord(name string, framesPerDirection uint32, speed uint16,
+	events map[int]AnimationEvent) (*AnimationDataRecord, error) {
+	if name == "" || len(name) >= byteCountName {
+		return nil, fmt.Errorf("animdata record name %q is not 1 to %d characters long", name, byteCountName-1)
+	}
+
+	for frame := range events {
+		if frame < 0 || frame >= numEvents {
+			return nil, fmt.Errorf("animdata record %q has an event at frame %d", name, frame)
+		}
+	}
+
+	b := ad.blocks[hashName(name)]
+	if b.recordCount >= maxRecordsPerBlock {
+		return nil, errors.New("animdata record block is full")
+	}
+
+	r := &AnimationDataRecord{
+		name,
+		framesPerDirection,
+		speed,
+		make(map[int]AnimationEvent, len(events)),
+	}
+
+	for frame, event := range events {
+		if event != AnimationEventNone {
+			r.events[frame] = event
+		}
+	}
+
+	b.records = append(b.records, r)
+	b.recordCount++
+
+	ad.entries[name] = append(ad.entries[name], r)
+
+	return r, nil
+}
+
+// Marshal encodes the records to the bytes of an AnimData.d2 file, block by block
+func (ad *AnimationData) Marshal() []byte {
+	sw := d2datautils.CreateStreamWriter()
+
+	for _, b := range ad.blocks {
+		if b == nil {
+			sw.PushUint32(0)
+			continue
+		}
+
+		sw.PushUint32(uint32(len(b.records)))
+
+		for _, r := range b.records {
+			name := make([]byte, byteCountName)
+			copy(name, r.name)
+
+			sw.PushBytes(name...)
+			sw.PushUint32(r.framesPerDirection)
+			sw.PushUint16(r.speed)
+			sw.PushBytes(make([]byte, byteCountSpeedPadding)...)
+
+			for eventIdx := 0; eventIdx < numEvents; eventIdx++ {
+				sw.PushByte(byte(r.events[eventIdx]))
+			}
+		}
+	}
+
+	return sw.GetBytes()
+}
@@ -1,6 +1,8 @@
 package d2animdata
 
 import (
+	"bytes"
+	"io/ioutil"
 	"log"
 	"os"
 	"testing"
@@ -154,3 +156,61 @@ func TestAnimationDataRecord_FPS(t *testing.T) {
 		t.Error("incorrect fps")
 	}
 }
+
+func TestAnimationData_Marshal(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/AnimData.d2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	animdata, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(animdata.Marshal(), data) {
+		t.Error("the loaded animation data is not written back byte for byte")
+	}
+}
+
+func TestAnimationData_AddRecord(t *testing.T) {
+	animdata := New()
+
+	for _, name := range []string{"AAA", "aaa", "BAA"} {
+		if _, err := animdata.AddRecord(name, 8, 256, map[int]AnimationEvent{3: AnimationEventAttack}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := animdata.AddRecord("TOOLONGX", 1, 1, nil); err == nil {
+		t.Error("a record name of 8 characters was added")
+	}
+
+	if _, err := animdata.AddRecord("ZZZ", 1, 1, map[int]AnimationEvent{numEvents: AnimationEventSound}); err == nil {
+		t.Error("an event after the last frame was added")
+	}
+
+	loaded, err := Load(animdata.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.blocks[hashName("AAA")].records) != 2 || len(loaded.blocks[hashName("BAA")].records) != 1 {
+		t.Error("the records are not in the blocks of the hashes of their names")
+	}
+
+	record := loaded.GetRecord("aaa")
+	if record == nil || record.framesPerDirection != 8 || record.FPS() != speedBaseFPS || record.events[3] != AnimationEventAttack {
+		t.Errorf("loaded record %+v", record)
+	}
+
+	for idx := 0; idx < maxRecordsPerBlock-1; idx++ {
+		if _, err := animdata.AddRecord("ABA", 1, 1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := animdata.AddRecord("AAB", 1, 1, nil); err == nil {
+		t.Error("a record was added to a full block")
+	}
+}
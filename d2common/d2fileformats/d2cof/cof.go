@@ -13,6 +13,8 @@ type COF struct {
 	FramesPerDirection int
 	NumberOfLayers     int
 	Speed              int
+	Unknown1           [unknownHeaderBytes]byte // the bytes of the header before the speed
+	Unknown2           [unknownSpeedBytes]byte  // the bytes of the header after the speed
 	CofLayers          []CofLayer
 	CompositeLayers    map[d2enum.CompositeType]int
 	AnimationFrames    []d2enum.AnimationFrame
@@ -27,11 +29,11 @@ func Load(fileData []byte) (*COF, error) {
 	result.FramesPerDirection = int(streamReader.GetByte())
 	result.NumberOfDirections = int(streamReader.GetByte())
 
-	streamReader.SkipBytes(21) //nolint:gomnd // Unknown data
+	copy(result.Unknown1[:], streamReader.ReadBytes(unknownHeaderBytes))
 
 	result.Speed = int(streamReader.GetByte())
 
-	streamReader.SkipBytes(3) //nolint:gomnd // Unknown data
+	copy(result.Unknown2[:], streamReader.ReadBytes(unknownSpeedBytes))
 
 	result.CofLayers = make([]CofLayer, result.NumberOfLayers)
 	result.CompositeLayers = make(map[d2enum.CompositeType]int)
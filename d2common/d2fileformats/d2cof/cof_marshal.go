@@ -0,0 +1,57 @@
+package d2cof
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+)
+
+const (
+	unknownHeaderBytes = 21
+	unknownSpeedBytes  = 3
+	weaponClassBytes   = 4
+)
+
+// Marshal encodes the COF to the bytes of a COF file
+func (c *COF) Marshal() []byte {
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushByte(byte(c.NumberOfLayers))
+	sw.PushByte(byte(c.FramesPerDirection))
+	sw.PushByte(byte(c.NumberOfDirections))
+	sw.PushBytes(c.Unknown1[:]...)
+	sw.PushByte(byte(c.Speed))
+	sw.PushBytes(c.Unknown2[:]...)
+
+	for _, layer := range c.CofLayers {
+		sw.PushByte(byte(layer.Type))
+		sw.PushByte(layer.Shadow)
+		sw.PushByte(boolToByte(layer.Selectable))
+		sw.PushByte(boolToByte(layer.Transparent))
+		sw.PushByte(byte(layer.DrawEffect))
+
+		weaponClass := make([]byte, weaponClassBytes)
+		copy(weaponClass, layer.WeaponClass.String())
+		sw.PushBytes(weaponClass...)
+	}
+
+	for _, frame := range c.AnimationFrames {
+		sw.PushByte(byte(frame))
+	}
+
+	for direction := range c.Priority {
+		for frame := range c.Priority[direction] {
+			for _, layer := range c.Priority[direction][frame] {
+				sw.PushByte(byte(layer))
+			}
+		}
+	}
+
+	return sw.GetBytes()
+}
+
+func boolToByte(b bool) byte {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,61 @@
+package d2cof
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+)
+
+func TestCOFFilesAreWrittenAndLoadedBack(t *testing.T) {
+	layers := []CofLayer{
+		{Type: d2enum.CompositeTypeHead, Shadow: 1, Selectable: true, DrawEffect: d2enum.DrawEffectNone,
+			WeaponClass: d2enum.WeaponClassHandToHand},
+		{Type: d2enum.CompositeTypeTorso, Transparent: true, DrawEffect: d2enum.DrawEffectPctTransparency50,
+			WeaponClass: d2enum.WeaponClassOneHandSwing},
+		{Type: d2enum.CompositeTypeRightHand, WeaponClass: d2enum.WeaponClassNone},
+	}
+
+	want := &COF{
+		NumberOfDirections: 2,
+		FramesPerDirection: 3,
+		NumberOfLayers:     len(layers),
+		Speed:              128,
+		Unknown1:           [unknownHeaderBytes]byte{0: 1, 7: 0xff, 20: 3},
+		Unknown2:           [unknownSpeedBytes]byte{4, 5, 6},
+		CofLayers:          layers,
+		CompositeLayers:    map[d2enum.CompositeType]int{},
+		AnimationFrames:    []d2enum.AnimationFrame{d2enum.AnimationFrameNoEvent, d2enum.AnimationFrameAttack, 0},
+		Priority:           make([][][]d2enum.CompositeType, 2),
+	}
+
+	for idx, layer := range layers {
+		want.CompositeLayers[layer.Type] = idx
+	}
+
+	for direction := range want.Priority {
+		want.Priority[direction] = make([][]d2enum.CompositeType, want.FramesPerDirection)
+
+		for frame := range want.Priority[direction] {
+			want.Priority[direction][frame] = []d2enum.CompositeType{
+				layers[(direction+frame)%3].Type, layers[(direction+frame+1)%3].Type, layers[(direction+frame+2)%3].Type,
+			}
+		}
+	}
+
+	data := want.Marshal()
+
+	loaded, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("loaded %+v, want %+v", loaded, want)
+	}
+
+	if !bytes.Equal(loaded.Marshal(), data) {
+		t.Error("the loaded COF is not written back byte for byte")
+	}
+}
@@ -31,11 +31,6 @@ type DC6 struct {
 
 // Load uses restruct to read the binary dc6 data into structs then parses image data from the frame data.
 func Load(data []byte) (*DC6, error) {
-	const (
-		terminationSize = 4
-		terminatorSize  = 3
-	)
-
 	r := d2datautils.CreateStreamReader(data)
 
 	var dc DC6
@@ -0,0 +1,169 @@
+package d2dc6
+
+import (
+	"errors"
+	"image"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+)
+
+const (
+	dc6Version      = 6
+	dc6Flags        = 1
+	dc6Encoding     = 0
+	terminationByte = 0xee
+
+	headerSize       = 24 // up to the frame pointers
+	framePointerSize = 4
+	frameHeaderSize  = 32
+	terminationSize  = 4
+	terminatorSize   = 3
+)
+
+// Encode creates a DC6 from the frames of its directions, every direction has the same number of
+// frames. The pixels of the frames are quantized to the given palette, the transparent pixels are
+// left out. A frame is drawn at its offsets, the top left corner of the bounds of its image.
+func Encode(directions [][]image.Image, palette d2interface.Palette) (*DC6, error) {
+	if len(directions) == 0 || len(directions[0]) == 0 {
+		return nil, errors.New("a DC6 needs at least one frame")
+	}
+
+	framesPerDirection := len(directions[0])
+
+	dc := &DC6{
+		Version:            dc6Version,
+		Flags:              dc6Flags,
+		Encoding:           dc6Encoding,
+		Termination:        []byte{terminationByte, terminationByte, terminationByte, terminationByte},
+		Directions:         uint32(len(directions)),
+		FramesPerDirection: uint32(framesPerDirection),
+		Frames:             make([]*DC6Frame, 0, len(directions)*framesPerDirection),
+	}
+
+	for _, frames := range directions {
+		if len(frames) != framesPerDirection {
+			return nil, errors.New("the directions of a DC6 have different numbers of frames")
+		}
+
+		for _, img := range frames {
+			dc.Frames = append(dc.Frames, encodeFrame(img, palette))
+		}
+	}
+
+	dc.updatePointers()
+
+	return dc, nil
+}
+
+// encodeFrame creates the frame of the given image
+func encodeFrame(img image.Image, palette d2interface.Palette) *DC6Frame {
+	bounds := img.Bounds()
+	data := EncodeFrameData(d2util.RGBAToImgIndex(img, palette), bounds.Dx(), bounds.Dy())
+
+	return &DC6Frame{
+		Width:      uint32(bounds.Dx()),
+		Height:     uint32(bounds.Dy()),
+		OffsetX:    int32(bounds.Min.X),
+		OffsetY:    int32(bounds.Min.Y),
+		Length:     uint32(len(data)),
+		FrameData:  data,
+		Terminator: []byte{terminationByte, terminationByte, terminationByte},
+	}
+}
+
+// EncodeFrameData encodes the given indexed color texture the way DecodeFrame decodes it: the
+// scanlines from the bottom to the top, each made of runs of transparent pixels (index zero) and
+// runs of opaque pixels, up to the last opaque pixel of the scanline, and an end of the scanline
+func EncodeFrameData(indexData []byte, width, height int) []byte {
+	data := make([]byte, 0, len(indexData)+height)
+
+	for y := height - 1; y >= 0; y-- {
+		row := indexData[y*width : (y+1)*width]
+
+		end := len(row)
+		for end > 0 && row[end-1] == 0 {
+			end--
+		}
+
+		for x := 0; x < end; {
+			run := 1
+			for x+run < end && run < maxRunLength && (row[x+run] == 0) == (row[x] == 0) {
+				run++
+			}
+
+			if row[x] == 0 {
+				data = append(data, endOfScanLine|byte(run))
+			} else {
+				data = append(data, byte(run))
+				data = append(data, row[x:x+run]...)
+			}
+
+			x += run
+		}
+
+		data = append(data, endOfScanLine)
+	}
+
+	return data
+}
+
+// updatePointers sets the frame pointers of the DC6 and the next blocks and lengths of its frames
+// to where the frames are written
+func (d *DC6) updatePointers() {
+	d.FramePointers = make([]uint32, len(d.Frames))
+	offset := uint32(headerSize + framePointerSize*len(d.Frames))
+
+	for idx, frame := range d.Frames {
+		d.FramePointers[idx] = offset
+		frame.Length = uint32(len(frame.FrameData))
+		offset += frameHeaderSize + frame.Length + terminatorSize
+		frame.NextBlock = offset
+	}
+}
+
+// Marshal encodes the DC6 back to the bytes of a DC6 file
+func (d *DC6) Marshal() []byte {
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushInt32(d.Version)
+	sw.PushUint32(d.Flags)
+	sw.PushUint32(d.Encoding)
+	sw.PushBytes(fixedBytes(d.Termination, terminationSize)...)
+	sw.PushUint32(d.Directions)
+	sw.PushUint32(d.FramesPerDirection)
+
+	for _, pointer := range d.FramePointers {
+		sw.PushUint32(pointer)
+	}
+
+	for _, frame := range d.Frames {
+		sw.PushUint32(frame.Flipped)
+		sw.PushUint32(frame.Width)
+		sw.PushUint32(frame.Height)
+		sw.PushInt32(frame.OffsetX)
+		sw.PushInt32(frame.OffsetY)
+		sw.PushUint32(frame.Unknown)
+		sw.PushUint32(frame.NextBlock)
+		sw.PushUint32(uint32(len(frame.FrameData)))
+		sw.PushBytes(frame.FrameData...)
+		sw.PushBytes(fixedBytes(frame.Terminator, terminatorSize)...)
+	}
+
+	return sw.GetBytes()
+}
+
+// fixedBytes returns the given bytes cut or padded with termination bytes to the given size
+func fixedBytes(data []byte, size int) []byte {
+	fixed := make([]byte, size)
+
+	for idx := range fixed {
+		fixed[idx] = terminationByte
+		if idx < len(data) {
+			fixed[idx] = data[idx]
+		}
+	}
+
+	return fixed
+}
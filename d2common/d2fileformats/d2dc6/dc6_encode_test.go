@@ -0,0 +1,176 @@
+package d2dc6
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+)
+
+// testPalette returns a palette whose color i is (i, 255-i, i/2)
+func testPalette(t *testing.T) d2interface.Palette {
+	data := make([]byte, 256*3)
+
+	for idx := 0; idx < 256; idx++ {
+		data[idx*3], data[idx*3+1], data[idx*3+2] = byte(idx/2), byte(255-idx), byte(idx)
+	}
+
+	palette, err := d2dat.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return palette
+}
+
+// testImage returns an image with transparent borders, runs longer than a DC6 run and colors
+// which are not in the palette
+func testImage(bounds image.Rectangle, seed int) image.Image {
+	img := image.NewNRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			column := x - bounds.Min.X
+			if column < 3 || column > 140 && column < 290 || (column+y+seed)%7 == 0 || y == bounds.Min.Y+1 {
+				continue
+			}
+
+			value := byte(column*3 + y + seed)
+			img.SetNRGBA(x, y, color.NRGBA{R: value, G: 255 - value, B: value/2 + byte(column%3), A: 255})
+		}
+	}
+
+	return img
+}
+
+func TestEncodedFramesDecodeToTheirQuantizedPixels(t *testing.T) {
+	palette := testPalette(t)
+	directions := [][]image.Image{
+		{testImage(image.Rect(-150, -80, 150, -60), 0), testImage(image.Rect(0, 0, 1, 1), 1)},
+		{testImage(image.Rect(-20, -40, 300, -5), 2), testImage(image.Rect(3, -9, 8, 0), 3)},
+	}
+
+	dc, err := Encode(directions, palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dc.Directions != 2 || dc.FramesPerDirection != 2 || len(dc.Frames) != 4 {
+		t.Fatalf("%d directions of %d frames, %d frames", dc.Directions, dc.FramesPerDirection, len(dc.Frames))
+	}
+
+	for idx, frame := range dc.Frames {
+		img := directions[idx/2][idx%2]
+		bounds := img.Bounds()
+
+		if int(frame.Width) != bounds.Dx() || int(frame.Height) != bounds.Dy() ||
+			int(frame.OffsetX) != bounds.Min.X || int(frame.OffsetY) != bounds.Min.Y {
+			t.Errorf("frame %d is %dx%d at %d,%d, want %v", idx, frame.Width, frame.Height, frame.OffsetX, frame.OffsetY, bounds)
+		}
+
+		want := d2util.RGBAToImgIndex(img, palette)
+		if got := dc.DecodeFrame(idx); !bytes.Equal(got, want) {
+			t.Errorf("frame %d does not decode to its pixels", idx)
+		}
+	}
+}
+
+func TestQuantizedPixelsGetTheClosestOpaqueColor(t *testing.T) {
+	palette := testPalette(t)
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 40, G: 215, B: 20, A: 255}) // color 40
+	img.SetNRGBA(1, 0, color.NRGBA{R: 41, G: 214, B: 19, A: 200}) // closest to color 41
+	img.SetNRGBA(2, 0, color.NRGBA{R: 0, G: 255, B: 0, A: 255})   // color 0 is transparent
+	img.SetNRGBA(3, 0, color.NRGBA{R: 40, G: 215, B: 20, A: 10})
+
+	if got := d2util.RGBAToImgIndex(img, palette); !bytes.Equal(got, []byte{40, 41, 1, 0}) {
+		t.Errorf("quantized to %v", got)
+	}
+}
+
+func TestEncodedFramesAreWrittenAsScanlines(t *testing.T) {
+	row := make([]byte, 300)
+	for idx := 2; idx < 132; idx++ {
+		row[idx] = 9
+	}
+
+	row[140] = 7
+
+	indexData := append(append([]byte{}, row...), make([]byte, 300)...)
+
+	want := []byte{endOfScanLine}
+	want = append(want, endOfScanLine|2, maxRunLength)
+	want = append(want, bytes.Repeat([]byte{9}, maxRunLength)...)
+	want = append(want, 3, 9, 9, 9, endOfScanLine|8, 1, 7, endOfScanLine)
+
+	if got := EncodeFrameData(indexData, 300, 2); !bytes.Equal(got, want) {
+		t.Errorf("encoded to %v, want %v", got, want)
+	}
+}
+
+func TestEncodedDC6FilesAreLoadedByteForByte(t *testing.T) {
+	directions := [][]image.Image{
+		{testImage(image.Rect(-10, -30, 10, 0), 0), testImage(image.Rect(-12, -31, 9, 0), 1)},
+		{testImage(image.Rect(-300, -8, 0, 0), 2), testImage(image.Rect(0, 0, 2, 2), 3)},
+		{testImage(image.Rect(-1, -1, 1, 1), 4), testImage(image.Rect(5, 5, 6, 6), 5)},
+	}
+
+	dc, err := Encode(directions, testPalette(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := dc.Marshal()
+
+	loaded, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(loaded.Marshal(), data) {
+		t.Fatal("the loaded DC6 is not written back byte for byte")
+	}
+
+	if !bytes.Equal(loaded.Termination, []byte{0xee, 0xee, 0xee, 0xee}) {
+		t.Errorf("termination %v", loaded.Termination)
+	}
+
+	for idx, frame := range loaded.Frames {
+		if !bytes.Equal(data[loaded.FramePointers[idx]+frameHeaderSize-4:][:4], []byte{
+			byte(frame.Length), byte(frame.Length >> 8), byte(frame.Length >> 16), byte(frame.Length >> 24)}) {
+			t.Errorf("frame pointer %d does not point to frame %d", loaded.FramePointers[idx], idx)
+		}
+
+		if idx+1 < len(loaded.Frames) && frame.NextBlock != loaded.FramePointers[idx+1] {
+			t.Errorf("the next block of frame %d is %d", idx, frame.NextBlock)
+		}
+
+		if !bytes.Equal(frame.Terminator, []byte{0xee, 0xee, 0xee}) {
+			t.Errorf("frame %d terminator %v", idx, frame.Terminator)
+		}
+
+		if !bytes.Equal(loaded.DecodeFrame(idx), dc.DecodeFrame(idx)) {
+			t.Errorf("frame %d decodes differently once loaded", idx)
+		}
+	}
+
+	if frame := loaded.Frames[len(loaded.Frames)-1]; int(frame.NextBlock) != len(data) {
+		t.Errorf("the last frame ends at %d of %d bytes", frame.NextBlock, len(data))
+	}
+}
+
+func TestDirectionsWithDifferentFrameCountsAreNotEncoded(t *testing.T) {
+	img := testImage(image.Rect(0, 0, 4, 4), 0)
+
+	if _, err := Encode([][]image.Image{{img, img}, {img}}, testPalette(t)); err == nil {
+		t.Error("directions with different frame counts were encoded")
+	}
+
+	if _, err := Encode(nil, testPalette(t)); err == nil {
+		t.Error("a DC6 without frames was encoded")
+	}
+}
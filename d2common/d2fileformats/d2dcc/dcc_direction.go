@@ -11,6 +11,10 @@ import (
 
 const cellsPerRow = 4
 
+// crazyBitTable is the number of bits of the fields of the frame headers of a direction, by the
+// codes of the fields in the header of the direction
+var crazyBitTable = [...]byte{0, 1, 2, 4, 6, 8, 10, 12, 14, 16, 20, 24, 26, 28, 30, 32} //nolint:gochecknoglobals // lookup table
+
 // DCCDirection represents a DCCDirection file.
 type DCCDirection struct {
 	OutSizeCoded               int
@@ -38,8 +42,6 @@ type DCCDirection struct {
 
 // CreateDCCDirection creates an instance of a DCCDirection.
 func CreateDCCDirection(bm *d2datautils.BitMuncher, file *DCC) *DCCDirection {
-	var crazyBitTable = []byte{0, 1, 2, 4, 6, 8, 10, 12, 14, 16, 20, 24, 26, 28, 30, 32}
-
 	result := &DCCDirection{
 		OutSizeCoded:     int(bm.GetUInt32()),
 		CompressionFlags: int(bm.GetBits(2)),                //nolint:gomnd // binary data
@@ -256,6 +258,10 @@ func (v *DCCDirection) generateFrames(pcd *d2datautils.BitMuncher) {
 			bufferCell.LastYOffset = cell.YOffset
 		}
 
+		if frame.FrameIsBottomUp {
+			frame.flipRows(frame.PixelData, v)
+		}
+
 		// Free up the stuff we no longer need
 		frame.Cells = nil
 	}
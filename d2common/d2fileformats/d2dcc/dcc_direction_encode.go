@@ -0,0 +1,644 @@
+package d2dcc
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+const (
+	compressionRawPixels   = 0x1 // the direction has the encoding type and raw pixel streams
+	compressionEqualCells  = 0x2 // the direction has the equal cells stream
+	compressionFlagsBits   = 2
+	fieldCodeBits          = 4
+	streamSizeBits         = 20
+	paletteSize            = 256
+	pixelMaskBits          = 4
+	allPixelsMask          = 0xf
+	displacementBits       = 4
+	maxDisplacement        = 15 // a displacement of 15 is continued by the next displacement
+	rawPixelBits           = 8
+	maxCellColors          = 4
+	directionOutSizeOffset = 4 // the size of the direction is written before the rest of it
+)
+
+// bitStream collects the values of one of the bit streams of a direction
+type bitStream struct {
+	values []uint32
+	widths []int
+	size   int // in bits
+}
+
+func (b *bitStream) push(value uint32, width int) {
+	b.values = append(b.values, value)
+	b.widths = append(b.widths, width)
+	b.size += width
+}
+
+func (b *bitStream) writeTo(sw *d2datautils.StreamWriter) {
+	for idx, value := range b.values {
+		sw.PushBits(value, b.widths[idx])
+	}
+}
+
+// cellCoding is how the colors of a cell of a frame are coded: the new pixel buffer entry of the
+// cell, with the colors of the pixel mask coded from the lowest to the highest
+type cellCoding struct {
+	mask      uint32
+	values    [4]byte // palette entries
+	coded     []byte
+	raw       bool
+	pixelBits int
+	cost      int // in bits, -1 if the coding can not be used
+}
+
+// directionEncoder encodes the frames of a direction the way CreateDCCDirection decodes them: it
+// keeps the pixel buffer entries and the pixels of the cells of the direction as the decoder has
+// them after every cell, to code the next cells from them
+type directionEncoder struct {
+	direction *DCCDirection
+	palette   d2interface.Palette
+	targets   [][]byte // the pixels of each frame in the box of the direction, its rows in coded order
+	used      [paletteSize]bool
+	entries   [paletteSize]byte // the palette entry of each used color
+	cellEntry []*[4]byte
+	pixels    []byte
+
+	equalCells, pixelMasks, encodingTypes, rawPixels, displacements, pixelCodes bitStream
+}
+
+// encodeDirection encodes the given frames to the data of a direction
+func encodeDirection(frames []FrameImage, palette d2interface.Palette) ([]byte, error) {
+	direction := &DCCDirection{
+		CompressionFlags: compressionRawPixels | compressionEqualCells,
+		Frames:           make([]*DCCDirectionFrame, len(frames)),
+	}
+
+	minX, minY, maxX, maxY := 0, 0, 0, 0
+
+	for idx := range frames {
+		box := frames[idx].Box
+		if box.Width <= 0 || box.Height <= 0 {
+			return nil, fmt.Errorf("frame %d is empty", idx)
+		}
+
+		if len(frames[idx].PixelData) != box.Width*box.Height {
+			return nil, fmt.Errorf("frame %d has %d pixels for a box of %dx%d", idx, len(frames[idx].PixelData),
+				box.Width, box.Height)
+		}
+
+		frame := &DCCDirectionFrame{
+			Box:             box,
+			Width:           box.Width,
+			Height:          box.Height,
+			XOffset:         box.Left,
+			YOffset:         box.Bottom() - 1,
+			FrameIsBottomUp: frames[idx].BottomUp,
+		}
+
+		if frame.FrameIsBottomUp {
+			frame.YOffset = box.Top
+		}
+
+		if idx == 0 || box.Left < minX {
+			minX = box.Left
+		}
+
+		if idx == 0 || box.Top < minY {
+			minY = box.Top
+		}
+
+		if idx == 0 || box.Right() > maxX {
+			maxX = box.Right()
+		}
+
+		if idx == 0 || box.Bottom() > maxY {
+			maxY = box.Bottom()
+		}
+
+		direction.Frames[idx] = frame
+	}
+
+	direction.Box = d2geom.Rectangle{Left: minX, Top: minY, Width: maxX - minX, Height: maxY - minY}
+	direction.calculateCells()
+
+	for _, frame := range direction.Frames {
+		frame.recalculateCells(direction)
+	}
+
+	e := &directionEncoder{
+		direction: direction,
+		palette:   palette,
+		targets:   make([][]byte, len(frames)),
+		cellEntry: make([]*[4]byte, len(direction.Cells)),
+		pixels:    make([]byte, direction.Box.Width*direction.Box.Height),
+	}
+
+	for idx := range frames {
+		e.targets[idx] = e.target(direction.Frames[idx], frames[idx].PixelData)
+	}
+
+	e.setPaletteEntries()
+
+	for _, cell := range direction.Cells {
+		cell.LastWidth = -1
+		cell.LastHeight = -1
+	}
+
+	for idx, frame := range direction.Frames {
+		for _, cell := range frame.Cells {
+			e.encodeCell(e.targets[idx], cell)
+		}
+	}
+
+	return e.bytes()
+}
+
+// target returns the pixels of the given frame in the box of the direction, in the order its rows
+// are coded, with at most four colors in each of its cells
+func (e *directionEncoder) target(frame *DCCDirectionFrame, pixelData []byte) []byte {
+	target := make([]byte, len(e.pixels))
+	left, top := frame.Box.Left-e.direction.Box.Left, frame.Box.Top-e.direction.Box.Top
+
+	for y := 0; y < frame.Height; y++ {
+		copy(target[left+(top+y)*e.direction.Box.Width:], pixelData[y*frame.Width:][:frame.Width])
+	}
+
+	if frame.FrameIsBottomUp {
+		frame.flipRows(target, e.direction)
+	}
+
+	for _, cell := range frame.Cells {
+		e.reduceColors(target, cell)
+	}
+
+	return target
+}
+
+// eachPixel calls the given function with the index of every pixel of the given cell in the box of
+// the direction, row by row
+func (e *directionEncoder) eachPixel(cell DCCCell, fn func(idx int)) {
+	for y := 0; y < cell.Height; y++ {
+		for x := 0; x < cell.Width; x++ {
+			fn(cell.XOffset + x + (cell.YOffset+y)*e.direction.Box.Width)
+		}
+	}
+}
+
+// reduceColors keeps the four most used colors of the given cell, the other pixels of the cell get
+// the closest of them
+func (e *directionEncoder) reduceColors(target []byte, cell DCCCell) {
+	counts := make(map[byte]int)
+
+	e.eachPixel(cell, func(idx int) { counts[target[idx]]++ })
+
+	if len(counts) <= maxCellColors {
+		return
+	}
+
+	colors := make([]byte, 0, len(counts))
+	for color := range counts {
+		colors = append(colors, color)
+	}
+
+	sort.Slice(colors, func(i, j int) bool {
+		if counts[colors[i]] != counts[colors[j]] {
+			return counts[colors[i]] > counts[colors[j]]
+		}
+
+		return colors[i] < colors[j]
+	})
+
+	kept := colors[:maxCellColors]
+	closest := make(map[byte]byte)
+
+	for _, color := range colors[maxCellColors:] {
+		closest[color] = e.closestColor(color, kept)
+	}
+
+	e.eachPixel(cell, func(idx int) {
+		if color, found := closest[target[idx]]; found {
+			target[idx] = color
+		}
+	})
+}
+
+// closestColor returns the color of the given colors closest to the given color in the palette,
+// opaque colors stay opaque. Without a palette it is the first of the given colors it can be.
+func (e *directionEncoder) closestColor(color byte, colors []byte) byte {
+	var paletteColors []d2interface.Color
+	if e.palette != nil {
+		colors := e.palette.GetColors()
+		paletteColors = colors[:]
+	}
+
+	best, bestDistance := colors[0], -1
+
+	for _, candidate := range colors {
+		if candidate == 0 && color != 0 {
+			continue
+		}
+
+		distance := 0
+
+		if paletteColors != nil && paletteColors[color] != nil && paletteColors[candidate] != nil {
+			dr := int(paletteColors[color].R()) - int(paletteColors[candidate].R())
+			dg := int(paletteColors[color].G()) - int(paletteColors[candidate].G())
+			db := int(paletteColors[color].B()) - int(paletteColors[candidate].B())
+			distance = dr*dr + dg*dg + db*db
+		}
+
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	return best
+}
+
+// setPaletteEntries sets the palette entries of the direction to the colors of its frames, the
+// transparent color is the first entry as pixels default to it
+func (e *directionEncoder) setPaletteEntries() {
+	e.used[0] = true
+
+	for _, target := range e.targets {
+		for _, color := range target {
+			e.used[color] = true
+		}
+	}
+
+	count := 0
+
+	for color := range e.used {
+		if e.used[color] {
+			e.direction.PaletteEntries[count] = byte(color)
+			e.entries[color] = byte(count)
+			count++
+		}
+	}
+}
+
+// encodeCell codes the given cell of a frame, from the pixel buffer entry and pixels of the cell
+// of the direction when the cell has been coded in a previous frame
+func (e *directionEncoder) encodeCell(target []byte, cell DCCCell) {
+	cellIdx := cell.XOffset/cellsPerRow + cell.YOffset/cellsPerRow*e.direction.HorizontalCellCount
+	bufferCell := e.direction.Cells[cellIdx]
+	previous := e.cellEntry[cellIdx]
+
+	defer func() {
+		bufferCell.LastWidth, bufferCell.LastHeight = cell.Width, cell.Height
+		bufferCell.LastXOffset, bufferCell.LastYOffset = cell.XOffset, cell.YOffset
+	}()
+
+	if previous != nil {
+		if e.copyEqualCell(target, cell, bufferCell) {
+			e.equalCells.push(1, 1)
+			return
+		}
+
+		e.equalCells.push(0, 1)
+	}
+
+	colorSet := make(map[byte]bool)
+
+	e.eachPixel(cell, func(idx int) { colorSet[e.entries[target[idx]]] = true })
+
+	colors := make([]byte, 0, len(colorSet))
+	for color := range colorSet {
+		colors = append(colors, color)
+	}
+
+	sort.Slice(colors, func(i, j int) bool { return colors[i] < colors[j] })
+
+	coding := bestCellCoding(colors, previous, cell.Width*cell.Height)
+
+	if previous != nil {
+		e.pixelMasks.push(coding.mask, pixelMaskBits)
+	}
+
+	e.pushColors(coding)
+
+	e.eachPixel(cell, func(idx int) {
+		entry := e.entries[target[idx]]
+		code := 0
+
+		for code < len(coding.values)-1 && coding.values[code] != entry {
+			code++
+		}
+
+		e.pixelCodes.push(uint32(code), coding.pixelBits)
+		e.pixels[idx] = target[idx]
+	})
+
+	e.cellEntry[cellIdx] = &coding.values
+}
+
+// copyEqualCell returns true if the decoder copying the given cell from where it was in the
+// previous frame gives its pixels, and copies it. The decoder clears a cell whose size changed.
+func (e *directionEncoder) copyEqualCell(target []byte, cell DCCCell, bufferCell *DCCCell) bool {
+	copied := make([]byte, cell.Width*cell.Height)
+
+	if cell.Width == bufferCell.LastWidth && cell.Height == bufferCell.LastHeight {
+		// the decoder copies the pixels one by one, the pixels it copies may have been copied already
+		for y := 0; y < cell.Height; y++ {
+			for x := 0; x < cell.Width; x++ {
+				fromX, fromY := x+bufferCell.LastXOffset-cell.XOffset, y+bufferCell.LastYOffset-cell.YOffset
+				if fromX >= 0 && fromX < cell.Width && fromY >= 0 && fromY < cell.Height &&
+					fromX+fromY*cell.Width < x+y*cell.Width {
+					copied[x+y*cell.Width] = copied[fromX+fromY*cell.Width]
+					continue
+				}
+
+				copied[x+y*cell.Width] = e.pixels[x+bufferCell.LastXOffset+(y+bufferCell.LastYOffset)*e.direction.Box.Width]
+			}
+		}
+	}
+
+	pixel := 0
+	equal := true
+
+	e.eachPixel(cell, func(idx int) {
+		equal = equal && target[idx] == copied[pixel]
+		pixel++
+	})
+
+	if !equal {
+		return false
+	}
+
+	pixel = 0
+
+	e.eachPixel(cell, func(idx int) {
+		e.pixels[idx] = copied[pixel]
+		pixel++
+	})
+
+	return true
+}
+
+// bestCellCoding returns the cheapest coding of a cell with the given palette entries, sorted, and
+// the given number of pixels. A cell coded in a previous frame can keep colors of its previous
+// pixel buffer entry, the colors of a new cell are all coded.
+func bestCellCoding(colors []byte, previous *[4]byte, pixelCount int) cellCoding {
+	masks := []uint32{allPixelsMask}
+	if previous != nil {
+		masks = make([]uint32, 0, allPixelsMask+1)
+		for mask := uint32(0); mask <= allPixelsMask; mask++ {
+			masks = append(masks, mask)
+		}
+	}
+
+	// the transparent entry is left out of the coded colors, it is what the mask gets without them
+	opaque := colors
+	if len(opaque) > 0 && opaque[0] == 0 {
+		opaque = opaque[1:]
+	}
+
+	best := cellCoding{cost: -1}
+
+	for _, mask := range masks {
+		maskCount := bits.OnesCount32(mask)
+
+		for subset := 0; subset < 1<<len(opaque); subset++ {
+			coded := make([]byte, 0, len(opaque))
+
+			for idx, color := range opaque {
+				if subset&(1<<idx) != 0 {
+					coded = append(coded, color)
+				}
+			}
+
+			if len(coded) > maskCount {
+				continue
+			}
+
+			coding := cellCoding{mask: mask, coded: coded}
+			next := len(coded) - 1
+
+			for idx := range coding.values {
+				switch {
+				case mask&(1<<idx) == 0:
+					coding.values[idx] = previous[idx]
+				case next >= 0:
+					coding.values[idx] = coded[next]
+					next--
+				}
+			}
+
+			coding.pixelBits = pixelBits(coding.values, colors)
+			if coding.pixelBits < 0 {
+				continue
+			}
+
+			var cost int
+
+			coding.raw, cost = colorsCost(coded, maskCount)
+			coding.cost = cost + coding.pixelBits*pixelCount
+
+			if previous != nil {
+				coding.cost += pixelMaskBits
+			}
+
+			if best.cost < 0 || coding.cost < best.cost {
+				best = coding
+			}
+		}
+	}
+
+	return best
+}
+
+// pixelBits returns the number of bits of the pixel codes of a cell with the given pixel buffer
+// entry, -1 if some of the given colors can not be coded
+func pixelBits(values [4]byte, colors []byte) int {
+	has := func(values ...byte) bool {
+		for _, color := range colors {
+			found := false
+
+			for _, value := range values {
+				found = found || value == color
+			}
+
+			if !found {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	switch {
+	case values[0] == values[1]:
+		if has(values[0]) {
+			return 0
+		}
+	case values[1] == values[2]:
+		if has(values[0], values[1]) {
+			return 1
+		}
+	default:
+		if has(values[:]...) {
+			return 2 //nolint:gomnd // one of four colors
+		}
+	}
+
+	return -1
+}
+
+// colorsCost returns whether the given colors are cheaper coded raw than as displacements, and the
+// number of bits they are coded with, along with the encoding type. The colors end with a repeated
+// color when there are fewer of them than the given number of colors of the mask.
+func colorsCost(coded []byte, maskCount int) (raw bool, cost int) {
+	if maskCount == 0 {
+		return false, 0
+	}
+
+	displacementCost, rawCost, last := 0, 0, byte(0)
+
+	for _, color := range coded {
+		displacementCost += displacementBits * (int(color-last)/maxDisplacement + 1)
+		rawCost += rawPixelBits
+		last = color
+	}
+
+	if len(coded) < maskCount {
+		displacementCost += displacementBits
+		rawCost += rawPixelBits
+	}
+
+	if rawCost < displacementCost {
+		return true, 1 + rawCost
+	}
+
+	return false, 1 + displacementCost
+}
+
+// pushColors writes the encoding type and the coded colors of the given coding
+func (e *directionEncoder) pushColors(coding cellCoding) {
+	maskCount := bits.OnesCount32(coding.mask)
+	if maskCount == 0 {
+		return
+	}
+
+	last := byte(0)
+
+	if coding.raw {
+		e.encodingTypes.push(1, 1)
+
+		for _, color := range coding.coded {
+			e.rawPixels.push(uint32(color), rawPixelBits)
+			last = color
+		}
+
+		if len(coding.coded) < maskCount {
+			e.rawPixels.push(uint32(last), rawPixelBits)
+		}
+
+		return
+	}
+
+	e.encodingTypes.push(0, 1)
+
+	for _, color := range coding.coded {
+		displacement := int(color - last)
+
+		for ; displacement >= maxDisplacement; displacement -= maxDisplacement {
+			e.displacements.push(maxDisplacement, displacementBits)
+		}
+
+		e.displacements.push(uint32(displacement), displacementBits)
+		last = color
+	}
+
+	if len(coding.coded) < maskCount {
+		e.displacements.push(0, displacementBits)
+	}
+}
+
+// bytes returns the data of the direction: its header, the headers of its frames, its palette and
+// its streams, the displacements before the pixel codes as they share their stream
+func (e *directionEncoder) bytes() ([]byte, error) {
+	streams := []*bitStream{&e.equalCells, &e.pixelMasks, &e.encodingTypes, &e.rawPixels}
+	for _, stream := range streams {
+		if stream.size >= 1<<streamSizeBits {
+			return nil, errors.New("the direction is too large for a DCC")
+		}
+	}
+
+	var widths, heights, xOffsets, yOffsets []int
+
+	for _, frame := range e.direction.Frames {
+		widths = append(widths, frame.Width)
+		heights = append(heights, frame.Height)
+		xOffsets = append(xOffsets, frame.XOffset)
+		yOffsets = append(yOffsets, frame.YOffset)
+	}
+
+	widthCode, heightCode := fieldCode(widths, false), fieldCode(heights, false)
+	xOffsetCode, yOffsetCode := fieldCode(xOffsets, true), fieldCode(yOffsets, true)
+
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushBits(uint32(e.direction.CompressionFlags), compressionFlagsBits)
+
+	for _, code := range []int{0, widthCode, heightCode, xOffsetCode, yOffsetCode, 0, 0} {
+		sw.PushBits(uint32(code), fieldCodeBits)
+	}
+
+	for _, frame := range e.direction.Frames {
+		sw.PushBits(uint32(frame.Width), int(crazyBitTable[widthCode]))
+		sw.PushBits(uint32(frame.Height), int(crazyBitTable[heightCode]))
+		sw.PushBits(uint32(frame.XOffset), int(crazyBitTable[xOffsetCode]))
+		sw.PushBits(uint32(frame.YOffset), int(crazyBitTable[yOffsetCode]))
+		sw.PushBit(frame.FrameIsBottomUp)
+	}
+
+	for _, stream := range streams {
+		sw.PushBits(uint32(stream.size), streamSizeBits)
+	}
+
+	for _, used := range e.used {
+		sw.PushBit(used)
+	}
+
+	for _, stream := range append(streams, &e.displacements, &e.pixelCodes) {
+		stream.writeTo(sw)
+	}
+
+	data := sw.GetBytes()
+
+	out := d2datautils.CreateStreamWriter()
+	out.PushUint32(uint32(directionOutSizeOffset + len(data)))
+	out.PushBytes(data...)
+
+	return out.GetBytes(), nil
+}
+
+// fieldCode returns the code of the fewest bits the given values fit in
+func fieldCode(values []int, signed bool) int {
+	for code, fieldBits := range crazyBitTable {
+		fits := true
+
+		for _, value := range values {
+			switch {
+			case fieldBits == 0:
+				fits = fits && value == 0
+			case signed:
+				fits = fits && value >= -(1<<(fieldBits-1)) && value < 1<<(fieldBits-1)
+			default:
+				fits = fits && value >= 0 && value < 1<<fieldBits
+			}
+		}
+
+		if fits {
+			return code
+		}
+	}
+
+	return len(crazyBitTable) - 1
+}
@@ -1,8 +1,6 @@
 package d2dcc
 
 import (
-	"log"
-
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
 )
@@ -20,7 +18,7 @@ type DCCDirectionFrame struct {
 	NumberOfCodedBytes    int
 	HorizontalCellCount   int
 	VerticalCellCount     int
-	FrameIsBottomUp       bool
+	FrameIsBottomUp       bool // the rows of the frame are coded from its bottom to its top
 	valid                 bool
 }
 
@@ -38,15 +36,17 @@ func CreateDCCDirectionFrame(bits *d2datautils.BitMuncher, direction *DCCDirecti
 	result.NumberOfCodedBytes = int(bits.GetBits(direction.CodedBytesBits))
 	result.FrameIsBottomUp = bits.GetBit() == 1
 
+	// the offsets are the first coded row of the frame: its top row when it is coded from the
+	// bottom, its bottom row otherwise
+	result.Box = d2geom.Rectangle{
+		Left:   result.XOffset,
+		Top:    result.YOffset - result.Height + 1,
+		Width:  result.Width,
+		Height: result.Height,
+	}
+
 	if result.FrameIsBottomUp {
-		log.Panic("Bottom up frames are not implemented.")
-	} else {
-		result.Box = d2geom.Rectangle{
-			Left:   result.XOffset,
-			Top:    result.YOffset - result.Height + 1,
-			Width:  result.Width,
-			Height: result.Height,
-		}
+		result.Box.Top = result.YOffset
 	}
 
 	result.valid = true
@@ -122,3 +122,19 @@ func (v *DCCDirectionFrame) recalculateCells(direction *DCCDirection) {
 		offsetY += cellHeights[y]
 	}
 }
+
+// flipRows turns the rows of the box of the frame upside down in the given pixels of the box of its
+// direction, the rows of a bottom up frame are coded from the bottom
+func (v *DCCDirectionFrame) flipRows(pixels []byte, direction *DCCDirection) {
+	left := v.Box.Left - direction.Box.Left
+	top := v.Box.Top - direction.Box.Top
+
+	for y := 0; y < v.Height/2; y++ {
+		upper := pixels[left+(top+y)*direction.Box.Width:][:v.Width]
+		lower := pixels[left+(top+v.Height-1-y)*direction.Box.Width:][:v.Width]
+
+		for x := range upper {
+			upper[x], lower[x] = lower[x], upper[x]
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package d2dcc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+const (
+	dccVersion    = 6
+	dccHeaderSize = 15 // up to the direction offsets
+	maxDirections = 255
+)
+
+// FrameImage is an indexed color frame to encode in a DCC
+type FrameImage struct {
+	Box       d2geom.Rectangle // where the frame is drawn, relative to the origin of the animation
+	PixelData []byte           // the palette indices of the pixels of the box, row by row, zero is transparent
+	BottomUp  bool             // the rows of the frame are coded from its bottom to its top
+}
+
+// Encode encodes the given frames of each direction to a DCC file, every direction has the same
+// number of frames. A cell of a frame keeps at most four colors: the other pixels of a cell which
+// has more colors get the closest of its four most used colors, in the given palette if any.
+func Encode(directions [][]FrameImage, palette d2interface.Palette) ([]byte, error) {
+	if len(directions) == 0 || len(directions) > maxDirections || len(directions[0]) == 0 {
+		return nil, fmt.Errorf("a DCC has 1 to %d directions of at least one frame", maxDirections)
+	}
+
+	directionData := make([][]byte, len(directions))
+
+	for idx, frames := range directions {
+		if len(frames) != len(directions[0]) {
+			return nil, errors.New("the directions of a DCC have different numbers of frames")
+		}
+
+		data, err := encodeDirection(frames, palette)
+		if err != nil {
+			return nil, fmt.Errorf("direction %d: %w", idx, err)
+		}
+
+		directionData[idx] = data
+	}
+
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushByte(dccFileSignature)
+	sw.PushByte(dccVersion)
+	sw.PushByte(byte(len(directions)))
+	sw.PushInt32(int32(len(directions[0])))
+	sw.PushInt32(1)
+
+	offset := dccHeaderSize + 4*len(directions) //nolint:gomnd // the direction offsets
+	totalSize := 0
+
+	for _, data := range directionData {
+		totalSize += len(data)
+	}
+
+	sw.PushInt32(int32(totalSize))
+
+	for _, data := range directionData {
+		sw.PushInt32(int32(offset))
+		offset += len(data)
+	}
+
+	for _, data := range directionData {
+		sw.PushBytes(data...)
+	}
+
+	return sw.GetBytes(), nil
+}
+
+// Marshal encodes the decoded frames of the DCC back to a DCC file
+func (d *DCC) Marshal() ([]byte, error) {
+	directions := make([][]FrameImage, len(d.Directions))
+
+	for idx, direction := range d.Directions {
+		directions[idx] = make([]FrameImage, len(direction.Frames))
+
+		for frameIdx, frame := range direction.Frames {
+			frameImage := FrameImage{
+				Box:       frame.Box,
+				PixelData: make([]byte, 0, frame.Box.Width*frame.Box.Height),
+				BottomUp:  frame.FrameIsBottomUp,
+			}
+
+			left := frame.Box.Left - direction.Box.Left
+
+			for y := frame.Box.Top - direction.Box.Top; y < frame.Box.Bottom()-direction.Box.Top; y++ {
+				frameImage.PixelData = append(frameImage.PixelData, frame.PixelData[left+y*direction.Box.Width:][:frame.Box.Width]...)
+			}
+
+			directions[idx][frameIdx] = frameImage
+		}
+	}
+
+	return Encode(directions, nil)
+}
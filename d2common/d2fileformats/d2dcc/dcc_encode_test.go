@@ -0,0 +1,246 @@
+package d2dcc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+)
+
+// testFrame returns a frame of stripes of the transparent color and blocks of colors, at most four
+// colors in every cell
+func testFrame(box d2geom.Rectangle, seed int, bottomUp bool) FrameImage {
+	frame := FrameImage{Box: box, PixelData: make([]byte, box.Width*box.Height), BottomUp: bottomUp}
+
+	for y := 0; y < box.Height; y++ {
+		for x := 0; x < box.Width; x++ {
+			worldX, worldY := box.Left+x+100, box.Top+y+100
+			if (worldX+worldY+seed)%3 == 0 {
+				continue
+			}
+
+			frame.PixelData[x+y*box.Width] = byte(1 + (worldX/8+worldY/8+seed)%3*97 + seed%5)
+		}
+	}
+
+	return frame
+}
+
+// directionPixels returns the pixels of the given frame in the given box of its direction
+func directionPixels(frame FrameImage, box d2geom.Rectangle) []byte {
+	pixels := make([]byte, box.Width*box.Height)
+
+	for y := 0; y < frame.Box.Height; y++ {
+		copy(pixels[frame.Box.Left-box.Left+(frame.Box.Top-box.Top+y)*box.Width:], frame.PixelData[y*frame.Box.Width:][:frame.Box.Width])
+	}
+
+	return pixels
+}
+
+func testDirections() [][]FrameImage {
+	first := testFrame(d2geom.Rectangle{Left: -20, Top: -60, Width: 41, Height: 57}, 0, false)
+
+	return [][]FrameImage{
+		{
+			first,
+			first,
+			testFrame(d2geom.Rectangle{Left: -18, Top: -61, Width: 37, Height: 58}, 0, false),
+			testFrame(d2geom.Rectangle{Left: -23, Top: -50, Width: 30, Height: 49}, 1, true),
+			testFrame(d2geom.Rectangle{Left: 3, Top: 1, Width: 1, Height: 1}, 2, false),
+		},
+		{
+			testFrame(d2geom.Rectangle{Left: 0, Top: 0, Width: 6, Height: 2}, 3, true),
+			testFrame(d2geom.Rectangle{Left: -1, Top: -2, Width: 5, Height: 9}, 4, false),
+			testFrame(d2geom.Rectangle{Left: -1, Top: -2, Width: 5, Height: 9}, 4, false),
+			testFrame(d2geom.Rectangle{Left: 2, Top: 1, Width: 5, Height: 2}, 5, false),
+			testFrame(d2geom.Rectangle{Left: -300, Top: -5, Width: 301, Height: 6}, 6, true),
+		},
+	}
+}
+
+func TestEncodedFramesDecodeToTheirPixels(t *testing.T) {
+	directions := testDirections()
+
+	data, err := Encode(directions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dcc, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dcc.NumberOfDirections != len(directions) || dcc.FramesPerDirection != len(directions[0]) {
+		t.Fatalf("%d directions of %d frames", dcc.NumberOfDirections, dcc.FramesPerDirection)
+	}
+
+	for dirIdx, direction := range dcc.Directions {
+		if direction.EqualCellsBitstreamSize == 0 {
+			t.Errorf("direction %d has no equal cells", dirIdx)
+		}
+
+		for frameIdx, frame := range direction.Frames {
+			want := directions[dirIdx][frameIdx]
+
+			if frame.Box != want.Box || frame.FrameIsBottomUp != want.BottomUp {
+				t.Errorf("frame %d of direction %d is %+v bottom up %v, want %+v", frameIdx, dirIdx, frame.Box,
+					frame.FrameIsBottomUp, want.Box)
+			}
+
+			if !bytes.Equal(frame.PixelData, directionPixels(want, direction.Box)) {
+				t.Errorf("frame %d of direction %d does not decode to its pixels", frameIdx, dirIdx)
+			}
+		}
+	}
+}
+
+func TestDecodedDCCFilesAreEncodedByteForByte(t *testing.T) {
+	data, err := Encode(testDirections(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dcc, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marshaled, err := dcc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(marshaled, data) {
+		t.Error("the decoded DCC is not encoded back byte for byte")
+	}
+}
+
+func TestCellsKeepTheirFourMostUsedColors(t *testing.T) {
+	frame := FrameImage{
+		Box: d2geom.Rectangle{Left: 0, Top: 0, Width: 4, Height: 4},
+		PixelData: []byte{
+			5, 5, 5, 5,
+			9, 9, 9, 0,
+			0, 7, 7, 8,
+			200, 5, 1, 6,
+		},
+	}
+
+	data, err := Encode([][]FrameImage{{frame}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dcc, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{
+		5, 5, 5, 5,
+		9, 9, 9, 0,
+		0, 7, 7, 5,
+		5, 5, 5, 5,
+	}
+
+	if got := dcc.Directions[0].Frames[0].PixelData; !bytes.Equal(got, want) {
+		t.Errorf("decoded to %v, want %v", got, want)
+	}
+}
+
+func TestCellCodingsUseTheFewestBits(t *testing.T) {
+	// a cell of a single color fills the cell without pixel codes
+	if coding := bestCellCoding([]byte{3}, &[4]byte{3, 3, 0, 0}, 16); coding.mask != 0 || coding.pixelBits != 0 {
+		t.Errorf("same color: mask %x, %d bits per pixel", coding.mask, coding.pixelBits)
+	}
+
+	// a new cell codes all its colors, from the lowest
+	coding := bestCellCoding([]byte{0, 20, 50}, nil, 16)
+	if coding.mask != allPixelsMask || coding.values != [4]byte{50, 20, 0, 0} || coding.pixelBits != 2 {
+		t.Errorf("new cell: mask %x values %v, %d bits per pixel", coding.mask, coding.values, coding.pixelBits)
+	}
+
+	if raw, cost := colorsCost([]byte{200, 250}, 4); !raw || cost != 1+3*rawPixelBits {
+		t.Errorf("far colors: raw %v, %d bits", raw, cost)
+	}
+
+	if raw, cost := colorsCost([]byte{2, 17}, 2); raw || cost != 1+3*displacementBits {
+		t.Errorf("near colors: raw %v, %d bits", raw, cost)
+	}
+}
+
+func TestInvalidFramesAreNotEncoded(t *testing.T) {
+	frame := testFrame(d2geom.Rectangle{Left: 0, Top: 0, Width: 4, Height: 4}, 0, false)
+
+	table := []struct {
+		name       string
+		directions [][]FrameImage
+	}{
+		{"no directions", nil},
+		{"direction without frames", [][]FrameImage{{}}},
+		{"directions with different frame counts", [][]FrameImage{{frame, frame}, {frame}}},
+		{"empty frame", [][]FrameImage{{{Box: d2geom.Rectangle{Width: 0, Height: 3}}}}},
+		{"pixels of another box", [][]FrameImage{{{Box: d2geom.Rectangle{Width: 4, Height: 3}, PixelData: frame.PixelData}}}},
+	}
+
+	for _, row := range table {
+		if _, err := Encode(row.directions, nil); err == nil {
+			t.Errorf("%s was encoded", row.name)
+		}
+	}
+}
+
+// bottomUpDCC is a DCC of a single frame of 2x2 pixels at -1,-3, coded from its bottom row
+var bottomUpDCC = []byte{ //nolint:gochecknoglobals // test data
+	0x74, 0x06, 0x01, // signature, version, 1 direction
+	0x01, 0x00, 0x00, 0x00, // 1 frame per direction
+	0x01, 0x00, 0x00, 0x00,
+	0x2e, 0x00, 0x00, 0x00, // size of the directions
+	0x13, 0x00, 0x00, 0x00, // offset of the direction
+	0x2e, 0x00, 0x00, 0x00, // size of the direction
+	// no compression, 2 bits of width and height, 4 bits of offsets; the frame: 2x2 at -1,-3
+	// bottom up; no pixel masks
+	0x80, 0xc8, 0x0c, 0x80, 0x7e, 0x07, 0x00, 0x00,
+	// the bits of the palette entries from the last bit of the previous byte: colors 10 and 20
+	0x00, 0x02, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// the cell has the colors 20 and 10, then a bit per pixel from the bottom row: 20 20, 10 20
+	0x80, 0x00, 0x02,
+}
+
+func TestBottomUpFramesAreDecodedFromTheirBottomRow(t *testing.T) {
+	dcc, err := Load(bottomUpDCC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := dcc.Directions[0].Frames[0]
+
+	if !frame.FrameIsBottomUp || frame.Box != (d2geom.Rectangle{Left: -1, Top: -3, Width: 2, Height: 2}) {
+		t.Errorf("frame is %+v bottom up %v, want the box at -1,-3 bottom up", frame.Box, frame.FrameIsBottomUp)
+	}
+
+	want := []byte{
+		10, 20,
+		20, 20,
+	}
+
+	if !bytes.Equal(frame.PixelData, want) {
+		t.Errorf("decoded to %v, want %v", frame.PixelData, want)
+	}
+
+	marshaled, err := dcc.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := Load(marshaled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if frame := again.Directions[0].Frames[0]; !frame.FrameIsBottomUp || !bytes.Equal(frame.PixelData, want) {
+		t.Errorf("encoded again, the frame decodes to %v bottom up %v", frame.PixelData, frame.FrameIsBottomUp)
+	}
+}
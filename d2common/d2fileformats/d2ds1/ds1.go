@@ -8,7 +8,17 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2path"
 )
 
-const maxActNumber = 5
+const (
+	maxActNumber = 5
+	unknown1Size = 8
+)
+
+// dirLookup is the orientation of the walls of the DS1 files before version 7, by their value
+var dirLookup = [...]int32{ //nolint:gochecknoglobals // lookup table
+	0x00, 0x01, 0x02, 0x01, 0x02, 0x03, 0x03, 0x05, 0x05, 0x06,
+	0x06, 0x07, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E,
+	0x0F, 0x10, 0x11, 0x12, 0x14,
+}
 
 // DS1 represents the "stamp" data that is used to build up maps.
 type DS1 struct {
@@ -26,6 +36,8 @@ type DS1 struct {
 	NumberOfShadowLayers       int32               // ShadowNum number of shadow layer used
 	NumberOfSubstitutionLayers int32               // SubstitutionNum number of substitution layer used
 	SubstitutionGroupsNum      int32               // SubstitutionGroupsNum number of substitution groups, datas between objects & NPC paths
+	Unknown1                   [unknown1Size]byte  // the two dwords after the files of the versions 9 to 13
+	Unknown2                   uint32              // the dword before the substitution groups of the versions from 18
 }
 
 // LoadDS1 loads the specified DS1 file
@@ -73,11 +85,16 @@ func LoadDS1(fileData []byte) (*DS1, error) {
 	}
 
 	if ds1.Version >= 9 && ds1.Version <= 13 {
-		// Skipping two dwords because they are "meaningless"?
-		br.SkipBytes(8) //nolint:gomnd // We don't know what's here
+		// two dwords which are "meaningless"?
+		copy(ds1.Unknown1[:], br.ReadBytes(unknown1Size))
 	}
 
-	if ds1.Version >= 4 { //nolint:gomnd // Version number
+	if ds1.Version < 4 { //nolint:gomnd // Version number
+		// the layers of the files before version 4 are fixed, see setupStreamLayerTypes
+		ds1.NumberOfWalls = 1
+		ds1.NumberOfFloors = 1
+		ds1.NumberOfSubstitutionLayers = 1
+	} else {
 		ds1.NumberOfWalls = br.GetInt32()
 		if ds1.Version >= 16 { //nolint:gomnd // Version number
 			ds1.NumberOfFloors = br.GetInt32()
@@ -131,7 +148,7 @@ func (ds1 *DS1) loadObjects(br *d2datautils.StreamReader) {
 func (ds1 *DS1) loadSubstitutions(br *d2datautils.StreamReader) {
 	if ds1.Version >= 12 && (ds1.SubstitutionType == 1 || ds1.SubstitutionType == 2) {
 		if ds1.Version >= 18 { //nolint:gomnd // Version number
-			br.GetUInt32()
+			ds1.Unknown2 = br.GetUInt32()
 		}
 
 		numberOfSubGroups := br.GetInt32()
@@ -238,12 +255,6 @@ func (ds1 *DS1) loadNpcPaths(br *d2datautils.StreamReader, objIdx, numPaths int)
 }
 
 func (ds1 *DS1) loadLayerStreams(br *d2datautils.StreamReader, layerStream []d2enum.LayerStreamType) {
-	var dirLookup = []int32{
-		0x00, 0x01, 0x02, 0x01, 0x02, 0x03, 0x03, 0x05, 0x05, 0x06,
-		0x06, 0x07, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E,
-		0x0F, 0x10, 0x11, 0x12, 0x14,
-	}
-
 	for lIdx := range layerStream {
 		layerStreamType := layerStream[lIdx]
 
@@ -0,0 +1,185 @@
+package d2ds1
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+)
+
+// Marshal encodes the DS1 to the bytes of a DS1 file of its version, the opposite of LoadDS1
+func (ds1 *DS1) Marshal() []byte {
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushInt32(ds1.Version)
+	sw.PushInt32(ds1.Width - 1)
+	sw.PushInt32(ds1.Height - 1)
+
+	if ds1.Version >= 8 { //nolint:gomnd // Version number
+		sw.PushInt32(ds1.Act - 1)
+	}
+
+	if ds1.Version >= 10 { //nolint:gomnd // Version number
+		sw.PushInt32(ds1.SubstitutionType)
+	}
+
+	if ds1.Version >= 3 { //nolint:gomnd // Version number
+		sw.PushInt32(int32(len(ds1.Files)))
+
+		for _, file := range ds1.Files {
+			sw.PushBytes([]byte(file)...)
+			sw.PushByte(0)
+		}
+	}
+
+	if ds1.Version >= 9 && ds1.Version <= 13 {
+		sw.PushBytes(ds1.Unknown1[:]...)
+	}
+
+	if ds1.Version >= 4 { //nolint:gomnd // Version number
+		sw.PushInt32(ds1.NumberOfWalls)
+
+		if ds1.Version >= 16 { //nolint:gomnd // Version number
+			sw.PushInt32(ds1.NumberOfFloors)
+		}
+	}
+
+	ds1.writeLayerStreams(sw, ds1.setupStreamLayerTypes())
+	ds1.writeObjects(sw)
+	ds1.writeSubstitutions(sw)
+	ds1.writeNPCs(sw)
+
+	return sw.GetBytes()
+}
+
+func (ds1 *DS1) writeObjects(sw *d2datautils.StreamWriter) {
+	if ds1.Version < 2 { //nolint:gomnd // Version number
+		return
+	}
+
+	sw.PushInt32(int32(len(ds1.Objects)))
+
+	for _, object := range ds1.Objects {
+		sw.PushInt32(int32(object.Type))
+		sw.PushInt32(int32(object.ID))
+		sw.PushInt32(int32(object.X))
+		sw.PushInt32(int32(object.Y))
+		sw.PushInt32(int32(object.Flags))
+	}
+}
+
+func (ds1 *DS1) writeSubstitutions(sw *d2datautils.StreamWriter) {
+	if ds1.Version < 12 || (ds1.SubstitutionType != 1 && ds1.SubstitutionType != 2) {
+		return
+	}
+
+	if ds1.Version >= 18 { //nolint:gomnd // Version number
+		sw.PushUint32(ds1.Unknown2)
+	}
+
+	sw.PushInt32(int32(len(ds1.SubstitutionGroups)))
+
+	for _, group := range ds1.SubstitutionGroups {
+		sw.PushInt32(group.TileX)
+		sw.PushInt32(group.TileY)
+		sw.PushInt32(group.WidthInTiles)
+		sw.PushInt32(group.HeightInTiles)
+		sw.PushInt32(group.Unknown)
+	}
+}
+
+// writeNPCs writes the paths of the objects which have some, an object is found by its position
+func (ds1 *DS1) writeNPCs(sw *d2datautils.StreamWriter) {
+	if ds1.Version < 14 { //nolint:gomnd // Version number
+		return
+	}
+
+	count := 0
+
+	for _, object := range ds1.Objects {
+		if len(object.Paths) > 0 {
+			count++
+		}
+	}
+
+	sw.PushInt32(int32(count))
+
+	for _, object := range ds1.Objects {
+		if len(object.Paths) == 0 {
+			continue
+		}
+
+		sw.PushInt32(int32(len(object.Paths)))
+		sw.PushInt32(int32(object.X))
+		sw.PushInt32(int32(object.Y))
+
+		for _, path := range object.Paths {
+			sw.PushInt32(int32(path.Position.X()))
+			sw.PushInt32(int32(path.Position.Y()))
+
+			if ds1.Version >= 15 { //nolint:gomnd // Version number
+				sw.PushInt32(int32(path.Action))
+			}
+		}
+	}
+}
+
+func (ds1 *DS1) writeLayerStreams(sw *d2datautils.StreamWriter, layerStream []d2enum.LayerStreamType) {
+	for _, layerStreamType := range layerStream {
+		for y := 0; y < int(ds1.Height); y++ {
+			for x := 0; x < int(ds1.Width); x++ {
+				tile := &ds1.Tiles[y][x]
+
+				var dw uint32
+
+				switch layerStreamType {
+				case d2enum.LayerStreamWall1, d2enum.LayerStreamWall2, d2enum.LayerStreamWall3, d2enum.LayerStreamWall4:
+					wall := tile.Walls[int(layerStreamType)-int(d2enum.LayerStreamWall1)]
+					dw = layerBits(wall.Prop1, wall.Sequence, wall.Unknown1, wall.Style, wall.Unknown2, wall.Hidden)
+				case d2enum.LayerStreamOrientation1, d2enum.LayerStreamOrientation2,
+					d2enum.LayerStreamOrientation3, d2enum.LayerStreamOrientation4:
+					wall := tile.Walls[int(layerStreamType)-int(d2enum.LayerStreamOrientation1)]
+					dw = ds1.orientationValue(wall.Type) | uint32(wall.Zero)<<8 //nolint:gomnd // Bitmask
+				case d2enum.LayerStreamFloor1, d2enum.LayerStreamFloor2:
+					floor := tile.Floors[int(layerStreamType)-int(d2enum.LayerStreamFloor1)]
+					dw = layerBits(floor.Prop1, floor.Sequence, floor.Unknown1, floor.Style, floor.Unknown2, floor.Hidden)
+				case d2enum.LayerStreamShadow:
+					shadow := tile.Shadows[0]
+					dw = layerBits(shadow.Prop1, shadow.Sequence, shadow.Unknown1, shadow.Style, shadow.Unknown2, shadow.Hidden)
+				case d2enum.LayerStreamSubstitute:
+					dw = tile.Substitutions[0].Unknown
+				}
+
+				sw.PushUint32(dw)
+			}
+		}
+	}
+}
+
+// layerBits returns the value of a wall, floor or shadow record in its layer stream
+// nolint:gomnd // Bitmask
+func layerBits(prop1, sequence, unknown1, style, unknown2 byte, hidden bool) uint32 {
+	dw := uint32(prop1) |
+		uint32(sequence)<<8&0x00003F00 |
+		uint32(unknown1)<<14&0x000FC000 |
+		uint32(style)<<20&0x03F00000 |
+		uint32(unknown2)<<26&0x7C000000
+
+	if hidden {
+		dw |= 0x80000000
+	}
+
+	return dw
+}
+
+// orientationValue returns the value of the given type of wall in the orientation streams, the
+// files before version 7 have the first value of the type in dirLookup
+func (ds1 *DS1) orientationValue(tileType d2enum.TileType) uint32 {
+	if ds1.Version < 7 { //nolint:gomnd // Version number
+		for value, lookup := range dirLookup {
+			if lookup == int32(tileType) {
+				return uint32(value)
+			}
+		}
+	}
+
+	return uint32(tileType) & 0x000000FF //nolint:gomnd // Bitmask
+}
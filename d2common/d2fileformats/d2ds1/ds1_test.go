@@ -0,0 +1,147 @@
+package d2ds1
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2path"
+)
+
+// testDS1 returns a DS1 of the given version shaped like LoadDS1 loads it
+func testDS1(version int32) *DS1 {
+	ds1 := &DS1{
+		Version:              version,
+		Width:                3,
+		Height:               2,
+		Act:                  1,
+		NumberOfShadowLayers: 1,
+		Objects:              []Object{},
+		SubstitutionGroups:   []SubstitutionGroup{},
+	}
+
+	switch {
+	case version < 4:
+		ds1.NumberOfWalls, ds1.NumberOfFloors, ds1.NumberOfSubstitutionLayers = 1, 1, 1
+	case version < 16:
+		ds1.NumberOfWalls, ds1.NumberOfFloors = 2, 1
+	default:
+		ds1.NumberOfWalls, ds1.NumberOfFloors = 4, 2
+	}
+
+	if version >= 3 {
+		ds1.Files = []string{"\\d2\\data\\global\\tiles\\act1\\town\\floor.tg1", "\\d2\\data\\global\\tiles\\act1\\town\\fence.tg1"}
+	}
+
+	if version >= 8 {
+		ds1.Act = 3
+	}
+
+	if version >= 9 && version <= 13 {
+		ds1.Unknown1 = [unknown1Size]byte{1, 2, 3, 4, 0xfd, 0xfe, 0xff, 0}
+	}
+
+	if version >= 10 {
+		ds1.SubstitutionType = 2
+		ds1.NumberOfSubstitutionLayers = 1
+	}
+
+	if version >= 18 {
+		ds1.Unknown2 = 0xcafe
+	}
+
+	wallTypes := []d2enum.TileType{d2enum.TileLeftWall, d2enum.TileRightWall, d2enum.TileRightPartOfNorthCornerWall,
+		d2enum.TileSpecialTile1, d2enum.TileRoof, d2enum.TileShadow}
+
+	ds1.Tiles = make([][]TileRecord, ds1.Height)
+
+	for y := range ds1.Tiles {
+		ds1.Tiles[y] = make([]TileRecord, ds1.Width)
+
+		for x := range ds1.Tiles[y] {
+			seed := byte(x + y*int(ds1.Width))
+			tile := &ds1.Tiles[y][x]
+
+			tile.Walls = make([]WallRecord, ds1.NumberOfWalls)
+			for idx := range tile.Walls {
+				tile.Walls[idx] = WallRecord{Type: wallTypes[(int(seed)+idx)%len(wallTypes)], Zero: seed, Prop1: seed + 1,
+					Sequence: seed % 64, Unknown1: 3, Style: 63 - seed, Unknown2: 31, Hidden: seed%2 == 0}
+			}
+
+			tile.Floors = make([]FloorShadowRecord, ds1.NumberOfFloors)
+			for idx := range tile.Floors {
+				tile.Floors[idx] = FloorShadowRecord{Prop1: 200 + seed, Sequence: seed, Style: byte(idx), Hidden: idx == 1}
+			}
+
+			tile.Shadows = []FloorShadowRecord{{Prop1: seed, Unknown1: 63, Unknown2: 1}}
+
+			tile.Substitutions = make([]SubstitutionRecord, ds1.NumberOfSubstitutionLayers)
+			for idx := range tile.Substitutions {
+				tile.Substitutions[idx].Unknown = 0xdeadbeef + uint32(seed)
+			}
+		}
+	}
+
+	if version >= 2 {
+		ds1.Objects = []Object{
+			{Type: 1, ID: 146, X: 12, Y: 7, Flags: 0},
+			{Type: 2, ID: 29, X: 3, Y: 3, Flags: 8},
+		}
+	}
+
+	if version >= 14 {
+		for idx := 0; idx < 3; idx++ {
+			path := d2path.Path{Position: d2vector.NewPosition(float64(12+idx), float64(9-idx))}
+			if version >= 15 {
+				path.Action = idx
+			}
+
+			ds1.Objects[0].Paths = append(ds1.Objects[0].Paths, path)
+		}
+	}
+
+	if version >= 12 {
+		ds1.SubstitutionGroups = []SubstitutionGroup{
+			{TileX: 0, TileY: 0, WidthInTiles: 2, HeightInTiles: 1, Unknown: 0},
+			{TileX: 1, TileY: 1, WidthInTiles: 2, HeightInTiles: 1, Unknown: -1},
+		}
+	}
+
+	return ds1
+}
+
+func TestDS1FilesAreWrittenAndLoadedBackForEveryVersion(t *testing.T) {
+	for version := int32(1); version <= 18; version++ {
+		want := testDS1(version)
+		data := want.Marshal()
+
+		loaded, err := LoadDS1(data)
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+
+		if !reflect.DeepEqual(loaded, want) {
+			t.Errorf("version %d: loaded %+v, want %+v", version, loaded, want)
+		}
+
+		if !bytes.Equal(loaded.Marshal(), data) {
+			t.Errorf("version %d: the loaded DS1 is not written back byte for byte", version)
+		}
+	}
+}
+
+func TestOldWallOrientationsAreWrittenAsTheirFirstValue(t *testing.T) {
+	ds1 := &DS1{Version: 6}
+
+	for value, tileType := range dirLookup {
+		if got := ds1.orientationValue(d2enum.TileType(tileType)); dirLookup[got] != tileType {
+			t.Errorf("the orientation %d is written as %d", value, got)
+		}
+	}
+
+	if got := (&DS1{Version: 7}).orientationValue(d2enum.TileShadow); got != uint32(d2enum.TileShadow) {
+		t.Errorf("version 7 writes the shadow orientation as %d", got)
+	}
+}
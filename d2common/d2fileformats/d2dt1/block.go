@@ -10,4 +10,6 @@ type Block struct {
 	EncodedData []byte
 	Length      int32
 	FileOffset  int32
+	Unknown1    [blockUnknown1Size]byte // after the position
+	Unknown2    [blockUnknown2Size]byte // after the length
 }
@@ -6,9 +6,21 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
 )
 
+// sizes of the data of unknown purpose in the headers of the file, the tiles and the blocks
+const (
+	dt1UnknownSize    = 260
+	tileUnknown1Size  = 4
+	tileUnknown2Size  = 4
+	tileUnknown3Size  = 7
+	tileUnknown4Size  = 12
+	blockUnknown1Size = 2
+	blockUnknown2Size = 2
+)
+
 // DT1 represents a DT1 file.
 type DT1 struct {
-	Tiles []Tile
+	Tiles   []Tile
+	Unknown [dt1UnknownSize]byte // the bytes of the header after the versions
 }
 
 // BlockDataFormat represents the format of the block data
@@ -34,7 +46,7 @@ func LoadDT1(fileData []byte) (*DT1, error) {
 		return nil, fmt.Errorf("expected to have a version of 7.6, but got %d.%d instead", ver1, ver2)
 	}
 
-	br.SkipBytes(260) //nolint:gomnd // Unknown data
+	copy(result.Unknown[:], br.ReadBytes(dt1UnknownSize))
 
 	numberOfTiles := br.GetInt32()
 	br.SetPosition(uint64(br.GetInt32()))
@@ -49,26 +61,26 @@ func LoadDT1(fileData []byte) (*DT1, error) {
 		newTile.Height = br.GetInt32()
 		newTile.Width = br.GetInt32()
 
-		br.SkipBytes(4) //nolint:gomnd // Unknown data
+		copy(newTile.Unknown1[:], br.ReadBytes(tileUnknown1Size))
 
 		newTile.Type = br.GetInt32()
 		newTile.Style = br.GetInt32()
 		newTile.Sequence = br.GetInt32()
 		newTile.RarityFrameIndex = br.GetInt32()
 
-		br.SkipBytes(4) //nolint:gomnd // Unknown data
+		copy(newTile.Unknown2[:], br.ReadBytes(tileUnknown2Size))
 
 		for i := range newTile.SubTileFlags {
 			newTile.SubTileFlags[i] = NewSubTileFlags(br.GetByte())
 		}
 
-		br.SkipBytes(7) //nolint:gomnd // Unknown data
+		copy(newTile.Unknown3[:], br.ReadBytes(tileUnknown3Size))
 
 		newTile.blockHeaderPointer = br.GetInt32()
 		newTile.blockHeaderSize = br.GetInt32()
 		newTile.Blocks = make([]Block, br.GetInt32())
 
-		br.SkipBytes(12) //nolint:gomnd // Unknown data
+		copy(newTile.Unknown4[:], br.ReadBytes(tileUnknown4Size))
 
 		result.Tiles[tileIdx] = newTile
 	}
@@ -81,7 +93,7 @@ func LoadDT1(fileData []byte) (*DT1, error) {
 			result.Tiles[tileIdx].Blocks[blockIdx].X = br.GetInt16()
 			result.Tiles[tileIdx].Blocks[blockIdx].Y = br.GetInt16()
 
-			br.SkipBytes(2) //nolint:gomnd // Unknown data
+			copy(result.Tiles[tileIdx].Blocks[blockIdx].Unknown1[:], br.ReadBytes(blockUnknown1Size))
 
 			result.Tiles[tileIdx].Blocks[blockIdx].GridX = br.GetByte()
 			result.Tiles[tileIdx].Blocks[blockIdx].GridY = br.GetByte()
@@ -95,7 +107,7 @@ func LoadDT1(fileData []byte) (*DT1, error) {
 
 			result.Tiles[tileIdx].Blocks[blockIdx].Length = br.GetInt32()
 
-			br.SkipBytes(2) //nolint:gomnd // Unknown data
+			copy(result.Tiles[tileIdx].Blocks[blockIdx].Unknown2[:], br.ReadBytes(blockUnknown2Size))
 
 			result.Tiles[tileIdx].Blocks[blockIdx].FileOffset = br.GetInt32()
 		}
@@ -0,0 +1,92 @@
+package d2dt1
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+)
+
+const (
+	dt1Version1     = 7
+	dt1Version2     = 6
+	dt1HeaderSize   = 276 // the versions, the unknown bytes, the number of tiles and where their headers are
+	tileHeaderSize  = 96
+	blockHeaderSize = 20
+)
+
+// Marshal encodes the DT1 to the bytes of a DT1 file, the opposite of LoadDT1: the block headers
+// and the data of the blocks of each tile follow the headers of the tiles
+func (d *DT1) Marshal() []byte {
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushInt32(dt1Version1)
+	sw.PushInt32(dt1Version2)
+	sw.PushBytes(d.Unknown[:]...)
+	sw.PushInt32(int32(len(d.Tiles)))
+	sw.PushInt32(dt1HeaderSize)
+
+	blockHeaderPointer := int32(dt1HeaderSize + tileHeaderSize*len(d.Tiles))
+
+	for tileIdx := range d.Tiles {
+		tile := &d.Tiles[tileIdx]
+		size := tile.blocksSize()
+
+		sw.PushInt32(tile.Direction)
+		sw.PushInt16(tile.RoofHeight)
+		sw.PushUint16(tile.MaterialFlags.Encode())
+		sw.PushInt32(tile.Height)
+		sw.PushInt32(tile.Width)
+		sw.PushBytes(tile.Unknown1[:]...)
+		sw.PushInt32(tile.Type)
+		sw.PushInt32(tile.Style)
+		sw.PushInt32(tile.Sequence)
+		sw.PushInt32(tile.RarityFrameIndex)
+		sw.PushBytes(tile.Unknown2[:]...)
+
+		for _, flags := range tile.SubTileFlags {
+			sw.PushByte(flags.Encode())
+		}
+
+		sw.PushBytes(tile.Unknown3[:]...)
+		sw.PushInt32(blockHeaderPointer)
+		sw.PushInt32(size)
+		sw.PushInt32(int32(len(tile.Blocks)))
+		sw.PushBytes(tile.Unknown4[:]...)
+
+		blockHeaderPointer += size
+	}
+
+	for tileIdx := range d.Tiles {
+		blocks := d.Tiles[tileIdx].Blocks
+		fileOffset := int32(blockHeaderSize * len(blocks))
+
+		for _, block := range blocks {
+			sw.PushInt16(block.X)
+			sw.PushInt16(block.Y)
+			sw.PushBytes(block.Unknown1[:]...)
+			sw.PushByte(block.GridX)
+			sw.PushByte(block.GridY)
+			sw.PushInt16(int16(block.Format))
+			sw.PushInt32(int32(len(block.EncodedData)))
+			sw.PushBytes(block.Unknown2[:]...)
+			sw.PushInt32(fileOffset)
+
+			fileOffset += int32(len(block.EncodedData))
+		}
+
+		for _, block := range blocks {
+			sw.PushBytes(block.EncodedData...)
+		}
+	}
+
+	return sw.GetBytes()
+}
+
+// blocksSize returns the size of the block headers and data of the tile
+func (t *Tile) blocksSize() int32 {
+	size := int32(blockHeaderSize * len(t.Blocks))
+
+	for _, block := range t.Blocks {
+		size += int32(len(block.EncodedData))
+	}
+
+	return size
+}
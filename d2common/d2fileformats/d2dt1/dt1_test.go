@@ -0,0 +1,147 @@
+package d2dt1
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// testPixels returns the pixels of a tile of the given size, with transparent runs, which have
+// the given pixels in the blocks and are transparent elsewhere
+func testPixels(blocks []Block, width, height int32) []byte {
+	pixels := make([]byte, width*height)
+	for idx := range pixels {
+		if idx%7 != 0 && idx%13 > 2 {
+			pixels[idx] = byte(idx%250 + 1)
+		}
+	}
+
+	// a decoded tile only has the pixels of its blocks
+	inBlocks := make([]byte, len(pixels))
+	DecodeTileGfxData(encodedBlocks(blocks, pixels, width), &inBlocks, 0, width)
+
+	return inBlocks
+}
+
+func encodedBlocks(blocks []Block, pixels []byte, width int32) []Block {
+	encoded := append([]Block{}, blocks...)
+	EncodeTileGfxData(encoded, pixels, 0, width)
+
+	return encoded
+}
+
+func TestEncodedBlocksDecodeToTheirPixels(t *testing.T) {
+	tests := []struct {
+		name          string
+		blocks        []Block
+		width, height int32
+	}{
+		{
+			name: "isometric",
+			blocks: []Block{
+				{X: 0, Y: 0, Format: BlockFormatIsometric},
+				{X: 32, Y: 0, Format: BlockFormatIsometric},
+				{X: 16, Y: 8, Format: BlockFormatIsometric},
+			},
+			width: 64, height: 23,
+		},
+		{
+			name: "rle",
+			blocks: []Block{
+				{X: 0, Y: 0, Format: BlockFormatRLE},
+				{X: 32, Y: 0, Format: BlockFormatRLE},
+				{X: 0, Y: 32, Format: BlockFormatRLE},
+			},
+			width: 64, height: 64,
+		},
+	}
+
+	for _, test := range tests {
+		pixels := testPixels(test.blocks, test.width, test.height)
+		blocks := encodedBlocks(test.blocks, pixels, test.width)
+
+		decoded := make([]byte, len(pixels))
+		DecodeTileGfxData(blocks, &decoded, 0, test.width)
+
+		if !bytes.Equal(decoded, pixels) {
+			t.Errorf("%s: the blocks do not decode to their pixels", test.name)
+		}
+
+		for _, block := range blocks {
+			if int(block.Length) != len(block.EncodedData) {
+				t.Errorf("%s: block of %d bytes has the length %d", test.name, len(block.EncodedData), block.Length)
+			}
+		}
+	}
+
+	// the rows of a RLE block end with two zeros, the transparent pixels are skipped
+	blocks := encodedBlocks([]Block{{Format: BlockFormatRLE}}, []byte{0, 0, 5, 6, 0, 7}, 6)
+	if want := append([]byte{2, 2, 5, 6, 1, 1, 7, 0, 0}, make([]byte, 62)...); !bytes.Equal(blocks[0].EncodedData, want) {
+		t.Errorf("RLE block %v", blocks[0].EncodedData)
+	}
+}
+
+func TestDT1FilesAreWrittenAndLoadedBack(t *testing.T) {
+	floor := []Block{
+		{X: 0, Y: 0, GridX: 0, GridY: 0, Format: BlockFormatIsometric},
+		{X: 32, Y: 0, GridX: 1, GridY: 0, Format: BlockFormatIsometric},
+	}
+	floor = encodedBlocks(floor, testPixels(floor, 64, 15), 64)
+
+	wall := []Block{
+		{X: 0, Y: 32, GridX: 0, GridY: 0, Format: BlockFormatRLE},
+		{X: 0, Y: 0, GridX: 0, GridY: 1, Format: BlockFormatRLE},
+		{X: 32, Y: 32, GridX: 1, GridY: 0, Format: BlockFormatRLE},
+	}
+	wall = encodedBlocks(wall, testPixels(wall, 64, 64), 64)
+
+	want := &DT1{Tiles: []Tile{
+		{Direction: 3, RoofHeight: 0, MaterialFlags: MaterialFlags{Dirt: true}, Height: 0, Width: 160, Type: 0,
+			Style: 1, Sequence: 2, RarityFrameIndex: 10, Blocks: floor},
+		{Direction: 1, RoofHeight: 80, MaterialFlags: MaterialFlags{InsideStone: true, Snow: true}, Height: -96,
+			Width: 160, Type: 1, Style: 3, Sequence: 0, RarityFrameIndex: 1, Blocks: wall},
+		{Direction: 2, Width: 160, Type: 13, Style: 30, Blocks: []Block{}},
+	}}
+
+	want.Unknown[0], want.Unknown[259] = 0x11, 0x22
+	want.Tiles[0].Unknown1 = [tileUnknown1Size]byte{1, 2, 3, 4}
+	want.Tiles[0].Unknown2 = [tileUnknown2Size]byte{5, 6, 7, 8}
+	want.Tiles[1].Unknown3 = [tileUnknown3Size]byte{9, 0, 0, 0, 0, 0, 10}
+	want.Tiles[2].Unknown4 = [tileUnknown4Size]byte{11: 0xff}
+	want.Tiles[1].Blocks[2].Unknown1 = [blockUnknown1Size]byte{0x12, 0x34}
+	want.Tiles[0].Blocks[0].Unknown2 = [blockUnknown2Size]byte{0x56, 0x78}
+
+	want.Tiles[0].SubTileFlags[6] = SubTileFlags{BlockWalk: true, BlockLOS: true, BlockLight: true}
+	want.Tiles[1].SubTileFlags[24] = SubTileFlags{BlockJump: true, BlockPlayerWalk: true, Unknown3: true}
+
+	// the block headers and data of the tiles follow the headers of the tiles
+	pointer := int32(dt1HeaderSize + tileHeaderSize*len(want.Tiles))
+
+	for tileIdx := range want.Tiles {
+		tile := &want.Tiles[tileIdx]
+		offset := int32(blockHeaderSize * len(tile.Blocks))
+
+		for blockIdx := range tile.Blocks {
+			tile.Blocks[blockIdx].FileOffset = offset
+			offset += tile.Blocks[blockIdx].Length
+		}
+
+		tile.blockHeaderPointer, tile.blockHeaderSize = pointer, offset
+		pointer += offset
+	}
+
+	data := want.Marshal()
+
+	loaded, err := LoadDT1(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("loaded %+v, want %+v", loaded, want)
+	}
+
+	if !bytes.Equal(loaded.Marshal(), data) {
+		t.Error("the loaded DT1 is not written back byte for byte")
+	}
+}
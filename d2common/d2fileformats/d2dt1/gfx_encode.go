@@ -0,0 +1,74 @@
+package d2dt1
+
+const (
+	rleBlockWidth  = 32
+	rleBlockHeight = 32
+	maxRunLength   = 255
+)
+
+// EncodeTileGfxData encodes the given tile graphics to the data of the given dt1 blocks, the
+// opposite of DecodeTileGfxData: the blocks keep their positions and formats, transparent pixels
+// are left out of the RLE blocks
+func EncodeTileGfxData(blocks []Block, pixels []byte, tileYOffset, tileWidth int32) {
+	pixel := func(x, y int32) byte {
+		offset := (y+tileYOffset)*tileWidth + x
+		if x < 0 || x >= tileWidth || offset < 0 || offset >= int32(len(pixels)) {
+			return 0
+		}
+
+		return pixels[offset]
+	}
+
+	for idx := range blocks {
+		block := &blocks[idx]
+		blockX, blockY := int32(block.X), int32(block.Y)
+
+		if block.Format == BlockFormatIsometric {
+			xjump := []int32{14, 12, 10, 8, 6, 4, 2, 0, 2, 4, 6, 8, 10, 12, 14}
+			nbpix := []int32{4, 8, 12, 16, 20, 24, 28, 32, 28, 24, 20, 16, 12, 8, 4}
+			data := make([]byte, 0, blockDataLength)
+
+			for y := range xjump {
+				for x := xjump[y]; x < xjump[y]+nbpix[y]; x++ {
+					data = append(data, pixel(blockX+x, blockY+int32(y)))
+				}
+			}
+
+			block.EncodedData = data
+			block.Length = int32(len(data))
+
+			continue
+		}
+
+		data := make([]byte, 0)
+
+		for y := int32(0); y < rleBlockHeight; y++ {
+			for x := int32(0); x < rleBlockWidth; {
+				start := x
+				for x < rleBlockWidth && x-start < maxRunLength && pixel(blockX+x, blockY+y) == 0 {
+					x++
+				}
+
+				run := x
+				for x < rleBlockWidth && x-run < maxRunLength && pixel(blockX+x, blockY+y) != 0 {
+					x++
+				}
+
+				if x == run {
+					break
+				}
+
+				data = append(data, byte(run-start), byte(x-run))
+
+				for ; run < x; run++ {
+					data = append(data, pixel(blockX+run, blockY+y))
+				}
+			}
+
+			data = append(data, 0, 0)
+		}
+
+		block.EncodedData = data
+		block.Length = int32(len(data))
+	}
+}
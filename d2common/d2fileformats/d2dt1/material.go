@@ -30,3 +30,28 @@ func NewMaterialFlags(data uint16) MaterialFlags {
 		Snow:         data&0x0400 == 0x0400,
 	}
 }
+
+// Encode returns the material flags as they are stored in a DT1 file
+// nolint:gomnd // Binary values
+func (m MaterialFlags) Encode() uint16 {
+	var data uint16
+
+	for bit, set := range map[uint16]bool{
+		0x0001: m.Other,
+		0x0002: m.Water,
+		0x0004: m.WoodObject,
+		0x0008: m.InsideStone,
+		0x0010: m.OutsideStone,
+		0x0020: m.Dirt,
+		0x0040: m.Sand,
+		0x0080: m.Wood,
+		0x0100: m.Lava,
+		0x0400: m.Snow,
+	} {
+		if set {
+			data |= bit
+		}
+	}
+
+	return data
+}
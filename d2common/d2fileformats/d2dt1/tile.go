@@ -12,6 +12,10 @@ type Tile struct {
 	Sequence           int32
 	RarityFrameIndex   int32
 	SubTileFlags       [25]SubTileFlags
+	Unknown1           [tileUnknown1Size]byte // after the width
+	Unknown2           [tileUnknown2Size]byte // after the rarity
+	Unknown3           [tileUnknown3Size]byte // after the sub tile flags
+	Unknown4           [tileUnknown4Size]byte // after the number of blocks
 	blockHeaderPointer int32
 	blockHeaderSize    int32
 	Blocks             []Block
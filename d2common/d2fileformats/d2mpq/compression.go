@@ -0,0 +1,203 @@
+package d2mpq
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/JoshVarga/blast"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2data/d2compression"
+)
+
+// CompressionType is a bit mask describing the compression methods which were applied to
+// a sector of a compressed file. It is stored as the first byte of the sector.
+type CompressionType byte
+
+// Compression types, as used by StormLib
+const (
+	// CompressionHuffman - Huffman compression, used on WAVE files
+	CompressionHuffman CompressionType = 0x01
+	// CompressionZLib - ZLib/Deflate compression
+	CompressionZLib CompressionType = 0x02
+	// CompressionPKWare - PKWARE Data Compression Library (implode)
+	CompressionPKWare CompressionType = 0x08
+	// CompressionBZip2 - BZip2 compression
+	CompressionBZip2 CompressionType = 0x10
+	// CompressionSparse - Sparse (run length of zeroes) compression
+	CompressionSparse CompressionType = 0x20
+	// CompressionADPCMMono - IMA ADPCM compression for mono WAVE files
+	CompressionADPCMMono CompressionType = 0x40
+	// CompressionADPCMStereo - IMA ADPCM compression for stereo WAVE files
+	CompressionADPCMStereo CompressionType = 0x80
+	// CompressionLZMA - LZMA compression. Unlike the other values, this is not a bit mask and
+	// can not be combined with another compression.
+	CompressionLZMA CompressionType = 0x12
+)
+
+const (
+	lzmaFilterSize    = 1
+	lzmaPropsSize     = 5
+	lzmaSizeFieldSize = 8
+	lzmaHeaderSize    = lzmaFilterSize + lzmaPropsSize + lzmaSizeFieldSize
+
+	monoChannels   = 1
+	stereoChannels = 2
+)
+
+type decompressFunc func(data []byte, expectedLength uint32) ([]byte, error)
+
+type decompressStep struct {
+	compression CompressionType
+	decompress  decompressFunc
+}
+
+// decompressSteps lists the decompression methods in the order they are undone. Compression
+// is applied in the reverse order.
+func decompressSteps() []decompressStep {
+	return []decompressStep{
+		{CompressionBZip2, bzip2Decompress},
+		{CompressionPKWare, pkDecompressSized},
+		{CompressionZLib, deflateSized},
+		{CompressionHuffman, huffmanDecompress},
+		{CompressionADPCMStereo, adpcmDecompress(stereoChannels)},
+		{CompressionADPCMMono, adpcmDecompress(monoChannels)},
+		{CompressionSparse, sparseDecompress},
+	}
+}
+
+// decompressMulti decompresses a sector whose first byte describes the applied compressions
+func decompressMulti(data []byte, expectedLength uint32) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("compressed sector is empty")
+	}
+
+	compression := CompressionType(data[0])
+	data = data[1:]
+
+	if compression == CompressionLZMA {
+		return lzmaDecompress(data, expectedLength)
+	}
+
+	remaining := compression
+
+	steps := decompressSteps()
+	for idx := range steps {
+		if compression&steps[idx].compression == 0 {
+			continue
+		}
+
+		var err error
+
+		data, err = safeDecompress(steps[idx].decompress, data, expectedLength)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining &^= steps[idx].compression
+	}
+
+	if remaining != 0 {
+		return nil, fmt.Errorf("decompression not supported for unknown compression type %X", compression)
+	}
+
+	return data, nil
+}
+
+// safeDecompress runs the given decompression, turning a panic caused by corrupted data into an error
+func safeDecompress(fn decompressFunc, data []byte, expectedLength uint32) (result []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = fmt.Errorf("decompression failed: %v", r)
+		}
+	}()
+
+	return fn(data, expectedLength)
+}
+
+func bzip2Decompress(data []byte, _ uint32) ([]byte, error) {
+	return ioutil.ReadAll(bzip2.NewReader(bytes.NewReader(data)))
+}
+
+func deflateSized(data []byte, _ uint32) ([]byte, error) {
+	return deflate(data)
+}
+
+func pkDecompressSized(data []byte, _ uint32) ([]byte, error) {
+	return pkDecompress(data)
+}
+
+func huffmanDecompress(data []byte, _ uint32) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("huffman: data is empty")
+	}
+
+	return d2compression.HuffmanDecompress(data), nil
+}
+
+func adpcmDecompress(channels int) decompressFunc {
+	return func(data []byte, _ uint32) ([]byte, error) {
+		return d2compression.WavDecompress(data, channels), nil
+	}
+}
+
+func sparseDecompress(data []byte, _ uint32) ([]byte, error) {
+	return d2compression.SparseDecompress(data)
+}
+
+func lzmaDecompress(data []byte, expectedLength uint32) ([]byte, error) {
+	if len(data) < lzmaHeaderSize {
+		return nil, errors.New("lzma: data is too short")
+	}
+
+	if data[0] != 0 {
+		return nil, fmt.Errorf("lzma: unsupported filter %d", data[0])
+	}
+
+	// the 8 bytes following the properties are reserved and ignored, just like StormLib does
+	props := data[lzmaFilterSize : lzmaFilterSize+lzmaPropsSize]
+
+	return d2compression.LzmaDecompress(props, data[lzmaHeaderSize:], int(expectedLength))
+}
+
+func deflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := new(bytes.Buffer)
+
+	if _, err = buffer.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	if err = r.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func pkDecompress(data []byte) ([]byte, error) {
+	r, err := blast.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := new(bytes.Buffer)
+
+	if _, err = buffer.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	if err = r.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,173 @@
+package d2mpq
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2data/d2compression"
+)
+
+const (
+	// bzip2 compressed sparseTestData
+	bzip2SparseHex = "425a68393141592653594682e9b40000005fc24000020010000402e0003225c00018000080200050a64c4c832302aa" +
+		"468018c939d15b8b88ad299efdc0b09161810244898cfe2ee48a70a1208d05d368"
+	// lzma (alone format) compressed plainTestData
+	lzmaPlainHex = "5d00008000ffffffffffffffff00279c08a70f5a9d939fcc2992326d76a8aaef1744e4b38269fffeb76800"
+	// lzma (alone format) compressed wordsTestData
+	lzmaWordsFile = "testdata/words.lzma"
+
+	lzmaAloneHeaderSize = lzmaPropsSize + lzmaSizeFieldSize
+)
+
+func plainTestData() []byte {
+	data := bytes.Repeat([]byte("OpenDiablo2"), 8)
+	data = append(data, make([]byte, 40)...)
+
+	return append(data, []byte("MPQ")...)
+}
+
+func sparseTestData() []byte {
+	plain := plainTestData()
+
+	data := []byte{0, 0, 0, byte(len(plain)), 0xD7}
+	data = append(data, plain[:88]...)
+
+	return append(data, 37, 0x82, 'M', 'P', 'Q')
+}
+
+// wordsTestData is a pseudo random sequence of words, which compresses with plenty of matches
+func wordsTestData() []byte {
+	words := [][]byte{
+		[]byte("diablo"), []byte("tristram"), []byte("rogue"), []byte("encampment"),
+		[]byte("cow"), []byte("level"), []byte("mpq"), []byte(" "), {0, 0, 0, 0},
+	}
+
+	seed := uint32(1)
+	data := make([]byte, 0)
+
+	for len(data) < 4000 {
+		seed = (seed*1103515245 + 12345) & 0x7fffffff
+		data = append(data, words[(seed>>16)%uint32(len(words))]...)
+	}
+
+	return data[:4000]
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func zlibCompress(t *testing.T, data []byte) []byte {
+	var buffer bytes.Buffer
+
+	w := zlib.NewWriter(&buffer)
+
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// mpqLzma converts lzma alone format data into an MPQ lzma sector body
+func mpqLzma(alone []byte) []byte {
+	return append([]byte{0}, alone...)
+}
+
+func sector(compression CompressionType, data []byte) []byte {
+	return append([]byte{byte(compression)}, data...)
+}
+
+func TestDecompressMulti(t *testing.T) {
+	plain := plainTestData()
+	words := wordsTestData()
+
+	lzmaWords, err := ioutil.ReadFile(lzmaWordsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		name   string
+		sector []byte
+		result []byte
+	}{
+		{"zlib", sector(CompressionZLib, zlibCompress(t, plain)), plain},
+		{"bzip2", sector(CompressionBZip2, mustDecodeHex(t, bzip2SparseHex)), sparseTestData()},
+		{"sparse", sector(CompressionSparse, sparseTestData()), plain},
+		{"sparse+zlib", sector(CompressionSparse|CompressionZLib, zlibCompress(t, sparseTestData())), plain},
+		{"sparse+bzip2", sector(CompressionSparse|CompressionBZip2, mustDecodeHex(t, bzip2SparseHex)), plain},
+		{"lzma", sector(CompressionLZMA, mpqLzma(mustDecodeHex(t, lzmaPlainHex))), plain},
+		{"lzma with matches", sector(CompressionLZMA, mpqLzma(lzmaWords)), words},
+	}
+
+	for _, row := range table {
+		result, err := decompressMulti(row.sector, uint32(len(row.result)))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", row.name, err)
+			continue
+		}
+
+		if !bytes.Equal(result, row.result) {
+			t.Errorf("%s: decompressed data does not match, got %d bytes, want %d", row.name, len(result), len(row.result))
+		}
+	}
+}
+
+func TestDecompressMultiErrors(t *testing.T) {
+	plain := plainTestData()
+	lzmaPlain := mustDecodeHex(t, lzmaPlainHex)
+
+	table := []struct {
+		name   string
+		sector []byte
+	}{
+		{"empty", []byte{}},
+		{"unknown compression", sector(0x04, plain)},
+		{"corrupted zlib", sector(CompressionZLib, plain)},
+		{"corrupted bzip2", sector(CompressionBZip2, plain)},
+		{"corrupted pkware", sector(CompressionPKWare, plain)},
+		{"corrupted huffman", sector(CompressionHuffman, []byte{0xFF, 0xFF})},
+		{"truncated sparse", sector(CompressionSparse, sparseTestData()[:20])},
+		{"truncated lzma", sector(CompressionLZMA, mpqLzma(lzmaPlain[:lzmaAloneHeaderSize+8]))},
+		{"lzma filter", sector(CompressionLZMA, append([]byte{1}, lzmaPlain...))},
+		{"lzma header", sector(CompressionLZMA, []byte{0, 0x5d})},
+	}
+
+	for _, row := range table {
+		if _, err := decompressMulti(row.sector, uint32(len(plain))); err == nil {
+			t.Errorf("%s: expected an error", row.name)
+		}
+	}
+}
+
+func TestSparseCompress(t *testing.T) {
+	plain := plainTestData()
+
+	if compressed := d2compression.SparseCompress(plain); !bytes.Equal(compressed, sparseTestData()) {
+		t.Errorf("sparse compression gave %x, want %x", compressed, sparseTestData())
+	}
+
+	words := wordsTestData()
+
+	result, err := d2compression.SparseDecompress(d2compression.SparseCompress(words))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(result, words) {
+		t.Error("sparse compression did not round trip")
+	}
+}
@@ -0,0 +1,387 @@
+package d2mpq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// HET and BET tables were added in format version 3. The HET table maps a 64 bit Jenkins hash of
+// the file name to an index into the BET table, which holds the file positions, sizes and flags.
+const (
+	hetSignature     = 0x1A544548 // "HET\x1A"
+	betSignature     = 0x1A544542 // "BET\x1A"
+	extHeaderSize    = 12
+	hetHeaderDwords  = 8
+	betHeaderDwords  = 19
+	hetEntryFree     = 0x00
+	hetHashByteBits  = 8
+	maxNameHashBits  = 64
+	jenkinsBlockSize = 12
+)
+
+// extTableHeader starts the HET and BET tables
+type extTableHeader struct {
+	Signature uint32
+	Version   uint32
+	DataSize  uint32
+}
+
+type hetTable struct {
+	nameHashBits   uint32
+	totalCount     uint32
+	indexSizeTotal uint32
+	indexSize      uint32
+	nameHashes     []byte
+	betIndexes     []byte
+}
+
+type betTable struct {
+	entries        []BlockTableEntry
+	nameHashes     []byte
+	nameHashTotal  uint32
+	nameHashBits   uint32
+	nameHashOffset uint32
+}
+
+// loadHetBetTables loads the HET and BET tables. Both are needed for a lookup, so the tables
+// are only kept when both of them are present.
+func (v *MPQ) loadHetBetTables() error {
+	if v.dataExtension.HetTableOffset == 0 || v.dataExtension.BetTableOffset == 0 {
+		return nil
+	}
+
+	hetData, err := v.readExtTable(v.dataExtension.HetTableOffset, v.dataExtension.HetTableSize64,
+		hetSignature, hashString("(hash table)", 3))
+	if err != nil {
+		return fmt.Errorf("reading HET table: %w", err)
+	}
+
+	betData, err := v.readExtTable(v.dataExtension.BetTableOffset, v.dataExtension.BetTableSize64,
+		betSignature, hashString("(block table)", 3))
+	if err != nil {
+		return fmt.Errorf("reading BET table: %w", err)
+	}
+
+	if v.hetTable, err = parseHetTable(hetData); err != nil {
+		return err
+	}
+
+	if v.betTable, err = parseBetTable(betData); err != nil {
+		v.hetTable = nil
+		return err
+	}
+
+	return nil
+}
+
+// readExtTable reads the data of a HET or BET table, which is encrypted and may be compressed
+func (v *MPQ) readExtTable(offset, storedSize uint64, signature, key uint32) ([]byte, error) {
+	headerData := make([]byte, extHeaderSize)
+	if err := v.readAt(offset, headerData); err != nil {
+		return nil, err
+	}
+
+	var header extTableHeader
+
+	if err := binary.Read(bytes.NewReader(headerData), binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+
+	if header.Signature != signature {
+		return nil, errors.New("invalid table signature")
+	}
+
+	if storedSize == 0 {
+		storedSize = v.extTableSize(offset, header.DataSize)
+	}
+
+	if storedSize < extHeaderSize {
+		return nil, errors.New("table is truncated")
+	}
+
+	data := make([]byte, storedSize-extHeaderSize)
+	if err := v.readAt(offset+extHeaderSize, data); err != nil {
+		return nil, err
+	}
+
+	decryptBytes(data, key)
+
+	if uint32(len(data)) >= header.DataSize {
+		return data[:header.DataSize], nil
+	}
+
+	return decompressMulti(data, header.DataSize)
+}
+
+// extTableSize works out the stored size of a HET or BET table in a version 3 archive, whose
+// header does not record it, from the position of the table stored after it
+func (v *MPQ) extTableSize(offset uint64, dataSize uint32) uint64 {
+	end := v.dataExtension.ArchiveSize64
+	if end == 0 {
+		end = uint64(v.data.ArchiveSize)
+	}
+
+	tables := []uint64{
+		v.dataExtension.HetTableOffset,
+		v.dataExtension.BetTableOffset,
+		uint64(v.dataExtension.HashTableOffsetHigh)<<32 | uint64(v.data.HashTableOffset),
+		uint64(v.dataExtension.BlockTableOffsetHigh)<<32 | uint64(v.data.BlockTableOffset),
+		v.dataExtension.HiBlockTableOffset,
+	}
+
+	for _, table := range tables {
+		if table > offset && table < end {
+			end = table
+		}
+	}
+
+	if end <= offset {
+		return extHeaderSize + uint64(dataSize)
+	}
+
+	return end - offset
+}
+
+func readDwords(data []byte, count int) ([]uint32, error) {
+	if len(data) < count*dwordSize {
+		return nil, errors.New("table header is truncated")
+	}
+
+	values := make([]uint32, count)
+
+	for idx := range values {
+		values[idx] = binary.LittleEndian.Uint32(data[idx*dwordSize:])
+	}
+
+	return values, nil
+}
+
+func parseHetTable(data []byte) (*hetTable, error) {
+	header, err := readDwords(data, hetHeaderDwords)
+	if err != nil {
+		return nil, err
+	}
+
+	// table size, entry count, total count, name hash bits, index size total, extra and
+	// effective index sizes and the size of the index table
+	table := &hetTable{
+		totalCount:     header[2],
+		nameHashBits:   header[3],
+		indexSizeTotal: header[4],
+		indexSize:      header[6],
+	}
+
+	indexTableSize := header[7]
+	data = data[hetHeaderDwords*dwordSize:]
+
+	if table.totalCount == 0 || table.nameHashBits < hetHashByteBits || table.nameHashBits > maxNameHashBits ||
+		uint64(len(data)) < uint64(table.totalCount)+uint64(indexTableSize) ||
+		uint64(table.indexSizeTotal)*uint64(table.totalCount) > uint64(indexTableSize)*8 {
+		return nil, errors.New("invalid HET table")
+	}
+
+	table.nameHashes = data[:table.totalCount]
+	table.betIndexes = data[table.totalCount : table.totalCount+indexTableSize]
+
+	return table, nil
+}
+
+func parseBetTable(data []byte) (*betTable, error) {
+	header, err := readDwords(data, betHeaderDwords)
+	if err != nil {
+		return nil, err
+	}
+
+	entryCount, entrySize := header[1], header[3]
+	bitIndex, bitCount := header[4:9], header[9:14]
+	nameHashArraySize, flagCount := header[17], header[18]
+
+	table := &betTable{
+		entries:       make([]BlockTableEntry, entryCount),
+		nameHashTotal: header[14],
+		nameHashBits:  header[16],
+	}
+
+	data = data[betHeaderDwords*dwordSize:]
+
+	flags, err := readDwords(data, int(flagCount))
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[flagCount*dwordSize:]
+	fileTableSize := (uint64(entryCount)*uint64(entrySize) + 7) / 8 //nolint:gomnd // bits to bytes
+
+	if uint64(len(data)) < fileTableSize+uint64(nameHashArraySize) ||
+		uint64(entryCount)*uint64(table.nameHashTotal) > uint64(nameHashArraySize)*8 {
+		return nil, errors.New("invalid BET table")
+	}
+
+	fileTable := data[:fileTableSize]
+	table.nameHashes = data[fileTableSize : fileTableSize+uint64(nameHashArraySize)]
+
+	for idx := range table.entries {
+		base := uint64(idx) * uint64(entrySize)
+		field := func(n int) uint64 {
+			return readBits(fileTable, base+uint64(bitIndex[n]), bitCount[n])
+		}
+
+		position := field(0)
+		entry := BlockTableEntry{
+			FilePosition:         uint32(position),
+			FilePositionHigh:     uint16(position >> 32), //nolint:gomnd // high dword of the position
+			UncompressedFileSize: uint32(field(1)),
+			CompressedFileSize:   uint32(field(2)),
+		}
+
+		if flagCount > 0 {
+			flagIndex := field(3)
+			if flagIndex >= uint64(flagCount) {
+				return nil, fmt.Errorf("invalid flag index %d in BET table", flagIndex)
+			}
+
+			entry.Flags = FileFlag(flags[flagIndex])
+		}
+
+		table.entries[idx] = entry
+	}
+
+	return table, nil
+}
+
+// readBits reads a little endian value of up to 64 bits from a bit array
+func readBits(data []byte, offset uint64, count uint32) uint64 {
+	value := uint64(0)
+
+	for bit := uint32(0); bit < count; bit++ {
+		position := offset + uint64(bit)
+		if position/8 >= uint64(len(data)) {
+			break
+		}
+
+		value |= uint64(data[position/8]>>(position%8)&1) << bit
+	}
+
+	return value
+}
+
+// find returns the BET table index of the given file
+func (het *hetTable) find(fileName string, bet *betTable) (int, bool) {
+	andMask := ^uint64(0)
+	if het.nameHashBits != maxNameHashBits {
+		andMask = (uint64(1) << het.nameHashBits) - 1
+	}
+
+	nameHash := jenkinsHash(fileName)&andMask | uint64(1)<<(het.nameHashBits-1)
+	hetHash := byte(nameHash >> (het.nameHashBits - hetHashByteBits))
+	betHash := nameHash & (andMask >> hetHashByteBits)
+
+	start := uint32(nameHash % uint64(het.totalCount))
+
+	for idx := start; het.nameHashes[idx] != hetEntryFree; {
+		if het.nameHashes[idx] == hetHash {
+			betIndex := readBits(het.betIndexes, uint64(idx)*uint64(het.indexSizeTotal), het.indexSize)
+
+			if betIndex < uint64(len(bet.entries)) && bet.nameHash(int(betIndex)) == betHash {
+				return int(betIndex), true
+			}
+		}
+
+		if idx = (idx + 1) % het.totalCount; idx == start {
+			break
+		}
+	}
+
+	return 0, false
+}
+
+func (bet *betTable) nameHash(idx int) uint64 {
+	return readBits(bet.nameHashes, uint64(idx)*uint64(bet.nameHashTotal), bet.nameHashBits)
+}
+
+// jenkinsHash returns the 64 bit hash of a file name used by the HET table, which is lookup3's
+// hashlittle2 of the lower case name
+func jenkinsHash(fileName string) uint64 {
+	name := strings.ReplaceAll(strings.ToLower(fileName), "/", "\\")
+	primary, secondary := hashLittle2([]byte(name), 1, 2) //nolint:gomnd // initial values used by the MPQ format
+
+	return uint64(primary)<<32 | uint64(secondary)
+}
+
+// hashLittle2 is Bob Jenkins' lookup3 hashlittle2, it returns the primary and secondary hashes
+func hashLittle2(key []byte, primarySeed, secondarySeed uint32) (primary, secondary uint32) {
+	a := 0xdeadbeef + uint32(len(key)) + secondarySeed //nolint:gomnd // lookup3 initial value
+	b, c := a, a+primarySeed
+
+	for len(key) > jenkinsBlockSize {
+		a += binary.LittleEndian.Uint32(key)
+		b += binary.LittleEndian.Uint32(key[4:])
+		c += binary.LittleEndian.Uint32(key[8:])
+		a, b, c = jenkinsMix(a, b, c)
+		key = key[jenkinsBlockSize:]
+	}
+
+	if len(key) == 0 {
+		return b, c
+	}
+
+	var tail [jenkinsBlockSize]byte
+
+	copy(tail[:], key)
+
+	a += binary.LittleEndian.Uint32(tail[:])
+	b += binary.LittleEndian.Uint32(tail[4:])
+	c += binary.LittleEndian.Uint32(tail[8:])
+
+	a, b, c = jenkinsFinal(a, b, c)
+
+	return b, c
+}
+
+//nolint:gomnd // lookup3 rotations
+func jenkinsMix(a, b, c uint32) (x, y, z uint32) {
+	a -= c
+	a ^= bits.RotateLeft32(c, 4)
+	c += b
+	b -= a
+	b ^= bits.RotateLeft32(a, 6)
+	a += c
+	c -= b
+	c ^= bits.RotateLeft32(b, 8)
+	b += a
+	a -= c
+	a ^= bits.RotateLeft32(c, 16)
+	c += b
+	b -= a
+	b ^= bits.RotateLeft32(a, 19)
+	a += c
+	c -= b
+	c ^= bits.RotateLeft32(b, 4)
+	b += a
+
+	return a, b, c
+}
+
+//nolint:gomnd // lookup3 rotations
+func jenkinsFinal(a, b, c uint32) (x, y, z uint32) {
+	c ^= b
+	c -= bits.RotateLeft32(b, 14)
+	a ^= c
+	a -= bits.RotateLeft32(c, 11)
+	b ^= a
+	b -= bits.RotateLeft32(a, 25)
+	c ^= b
+	c -= bits.RotateLeft32(b, 16)
+	a ^= c
+	a -= bits.RotateLeft32(c, 4)
+	b ^= a
+	b -= bits.RotateLeft32(a, 14)
+	c ^= b
+	c -= bits.RotateLeft32(b, 24)
+
+	return a, b, c
+}
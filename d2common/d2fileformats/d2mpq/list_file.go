@@ -0,0 +1,96 @@
+package d2mpq
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2resource"
+)
+
+// ReadListFile reads an external listfile, with one file name per line, which can be added to
+// archives whose (listfile) is missing or incomplete with AddListFile
+func ReadListFile(fileName string) ([]string, error) {
+	data, err := ioutil.ReadFile(fileName) //nolint:gosec // the listfile is chosen by the user
+	if err != nil {
+		return nil, err
+	}
+
+	return parseListFile(data), nil
+}
+
+func parseListFile(data []byte) []string {
+	raw := strings.TrimRight(string(data), "\x00")
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == '\r' || r == '\n' || r == ';'
+	})
+
+	names := make([]string, 0, len(fields))
+
+	for _, field := range fields {
+		if name := strings.TrimSpace(field); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// AddListFile adds file names, such as those of an external listfile, which GetFileList uses
+// to name the files the (listfile) of the archive does not list
+func (v *MPQ) AddListFile(names []string) {
+	v.listFileNames = append(v.listFileNames, names...)
+}
+
+// ResolveFileNames returns the names of the candidates which are files in the archive. Each file
+// is returned once, with backslash separators and without a leading separator.
+func (v *MPQ) ResolveFileNames(candidates []string) []string {
+	found := make(map[uint64]bool)
+	names := make([]string, 0)
+
+	for _, candidate := range candidates {
+		name := strings.TrimLeft(strings.ReplaceAll(candidate, "/", "\\"), "\\")
+		key := nameKey(name)
+
+		if name == "" || found[key] || !v.FileExists(name) {
+			continue
+		}
+
+		found[key] = true
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// GetFileList returns the list of files in this MPQ. The names are taken from the (listfile) of
+// the archive, and completed with the names added by AddListFile and the resource paths known to
+// d2resource which are found in the archive, so archives without a (listfile) can be listed too.
+func (v *MPQ) GetFileList() ([]string, error) {
+	var filePaths []string
+
+	data, listErr := v.ReadFile(listFileName)
+	if listErr == nil {
+		filePaths = parseListFile(data)
+	}
+
+	listed := make(map[uint64]bool, len(filePaths))
+
+	for _, filePath := range filePaths {
+		listed[nameKey(filePath)] = true
+	}
+
+	candidates := append(append([]string{}, v.listFileNames...), d2resource.KnownPaths()...)
+
+	for _, name := range v.ResolveFileNames(candidates) {
+		if !listed[nameKey(name)] {
+			filePaths = append(filePaths, name)
+		}
+	}
+
+	if listErr != nil && len(filePaths) == 0 {
+		return nil, listErr
+	}
+
+	return filePaths, nil
+}
@@ -1,9 +1,11 @@
 package d2mpq
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -21,11 +23,27 @@ var _ d2interface.Archive = &MPQ{} // Static check to confirm struct conforms to
 type MPQ struct {
 	filePath          string
 	file              *os.File
+	headerOffset      int64
 	hashEntryMap      HashEntryMap
+	hashTableEntries  []HashTableEntry
 	blockTableEntries []BlockTableEntry
+	hetTable          *hetTable
+	betTable          *betTable
+	listFileNames     []string
 	data              Data
+	dataExtension     headerExtension
 }
 
+const (
+	userDataMagic      = "MPQ\x1B"
+	userDataHeaderSize = 16
+	headerSearchStep   = 0x200
+	headerSizeV2       = 44
+	headerSizeV3       = 68
+	headerSizeV4       = 208
+	hiBlockEntrySize   = 2
+)
+
 // Data Represents a MPQ file
 type Data struct {
 	Magic             [4]byte
@@ -39,6 +57,40 @@ type Data struct {
 	BlockTableEntries uint32
 }
 
+// headerExtension holds the header fields added by format versions 2 to 4, which are zero for
+// older archives
+type headerExtension struct {
+	// format version 2, The Burning Crusade
+	HiBlockTableOffset   uint64
+	HashTableOffsetHigh  uint16
+	BlockTableOffsetHigh uint16
+	// format version 3, Cataclysm beta
+	ArchiveSize64  uint64
+	BetTableOffset uint64
+	HetTableOffset uint64
+	// format version 4, Cataclysm
+	HashTableSize64    uint64
+	BlockTableSize64   uint64
+	HiBlockTableSize64 uint64
+	HetTableSize64     uint64
+	BetTableSize64     uint64
+	RawChunkSize       uint32
+	MD5BlockTable      [16]byte
+	MD5HashTable       [16]byte
+	MD5HiBlockTable    [16]byte
+	MD5BetTable        [16]byte
+	MD5HetTable        [16]byte
+	MD5Header          [16]byte
+}
+
+// userDataHeader precedes the MPQ header in archives which are embedded in other files
+type userDataHeader struct {
+	Magic              [4]byte
+	UserDataSize       uint32
+	HeaderOffset       uint32
+	UserDataHeaderSize uint32
+}
+
 // HashTableEntry represents a hashed file entry in the MPQ file
 type HashTableEntry struct { // 16 bytes
 	NamePartA  uint32
@@ -88,6 +140,8 @@ type BlockTableEntry struct { // 16 bytes
 	CompressedFileSize   uint32
 	UncompressedFileSize uint32
 	Flags                FileFlag
+	// FilePositionHigh holds bits 32 to 47 of the file position, from the hi-block table
+	FilePositionHigh uint16
 	// Local Stuff...
 	FileName       string
 	EncryptionSeed uint32
@@ -98,6 +152,11 @@ func (v BlockTableEntry) HasFlag(flag FileFlag) bool {
 	return (v.Flags & flag) != 0
 }
 
+// offset returns the position of the file relative to the MPQ header
+func (v BlockTableEntry) offset() uint64 {
+	return uint64(v.FilePositionHigh)<<32 | uint64(v.FilePosition)
+}
+
 // Load loads an MPQ file and returns a MPQ structure
 func Load(fileName string) (d2interface.Archive, error) {
 	result := &MPQ{filePath: fileName}
@@ -148,88 +207,183 @@ func openIgnoreCase(mpqPath string) (*os.File, error) {
 }
 
 func (v *MPQ) readHeader() error {
-	err := binary.Read(v.file, binary.LittleEndian, &v.data)
-
-	if err != nil {
+	if err := v.findHeader(); err != nil {
 		return err
 	}
 
-	if string(v.data.Magic[:]) != "MPQ\x1A" {
-		return errors.New("invalid mpq header")
+	if err := v.readHeaderExtensions(); err != nil {
+		return err
 	}
 
-	err = v.loadHashTable()
-	if err != nil {
+	if err := v.loadHashTable(); err != nil {
 		return err
 	}
 
-	v.loadBlockTable()
+	if err := v.loadBlockTable(); err != nil {
+		return err
+	}
 
-	return nil
+	return v.loadHetBetTables()
 }
 
-func (v *MPQ) loadHashTable() error {
-	_, err := v.file.Seek(int64(v.data.HashTableOffset), 0)
+// findHeader finds the MPQ header, which is either at the start of the file, at a 512 byte
+// boundary further into it, or pointed to by a user data header
+func (v *MPQ) findHeader() error {
+	info, err := v.file.Stat()
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
 
-	hashData := make([]uint32, v.data.HashTableEntries*4) //nolint:gomnd // // Decryption magic
-	hash := make([]byte, 4)
+	magic := make([]byte, len(mpqMagic))
 
-	for i := range hashData {
-		_, err := v.file.Read(hash)
-		if err != nil {
-			log.Print(err)
+	for offset := int64(0); offset+headerSizeV1 <= info.Size(); offset += headerSearchStep {
+		if _, err := v.file.ReadAt(magic, offset); err != nil {
+			return err
+		}
+
+		headerOffset := offset
+
+		if string(magic) == userDataMagic {
+			var userData userDataHeader
+			if err := binary.Read(io.NewSectionReader(v.file, offset, userDataHeaderSize), binary.LittleEndian, &userData); err != nil {
+				return err
+			}
+
+			headerOffset += int64(userData.HeaderOffset)
+		} else if string(magic) != mpqMagic {
+			continue
 		}
 
-		hashData[i] = binary.LittleEndian.Uint32(hash)
+		if err := binary.Read(io.NewSectionReader(v.file, headerOffset, headerSizeV1), binary.LittleEndian, &v.data); err != nil {
+			return err
+		}
+
+		if string(v.data.Magic[:]) == mpqMagic {
+			v.headerOffset = headerOffset
+			return nil
+		}
 	}
 
-	decrypt(hashData, hashString("(hash table)", 3))
+	return errors.New("invalid mpq header")
+}
 
-	for i := uint32(0); i < v.data.HashTableEntries; i++ {
-		v.hashEntryMap.Insert(&HashTableEntry{
-			NamePartA: hashData[i*4],
-			NamePartB: hashData[(i*4)+1],
+// readHeaderExtensions reads the header fields added by format versions 2 to 4
+func (v *MPQ) readHeaderExtensions() error {
+	sizes := []uint32{headerSizeV1, headerSizeV2, headerSizeV3, headerSizeV4}
+
+	// old readers ignored the header size, so it is only trusted for newer format versions
+	if int(v.data.FormatVersion) >= len(sizes) || v.data.HeaderSize < sizes[v.data.FormatVersion] {
+		v.data.FormatVersion = 0
+		return nil
+	}
+
+	extension := make([]byte, sizes[v.data.FormatVersion]-headerSizeV1)
+	if err := v.readAt(headerSizeV1, extension); err != nil {
+		return err
+	}
+
+	// the extensions are read into one struct, so the fields of later versions are left zero
+	extension = append(extension, make([]byte, headerSizeV4-headerSizeV1-len(extension))...)
+
+	return binary.Read(bytes.NewReader(extension), binary.LittleEndian, &v.dataExtension)
+}
+
+// readAt reads data from the given offset relative to the MPQ header
+func (v *MPQ) readAt(offset uint64, data []byte) error {
+	_, err := v.file.ReadAt(data, v.headerOffset+int64(offset))
+	return err
+}
+
+// readTable reads a hash, block or hi-block table. Tables are compressed when they are stored
+// in fewer bytes than they hold, which format version 4 records, and data missing at the end of
+// the file is left zero.
+func (v *MPQ) readTable(offset, storedSize, size uint64, key uint32) ([]byte, error) {
+	if storedSize == 0 || storedSize > size {
+		storedSize = size
+	}
+
+	data := make([]byte, storedSize)
+
+	if err := v.readAt(offset, data); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if key != 0 {
+		decryptBytes(data, key)
+	}
+
+	if storedSize == size {
+		return data, nil
+	}
+
+	return decompressMulti(data, uint32(size))
+}
+
+func (v *MPQ) loadHashTable() error {
+	offset := uint64(v.dataExtension.HashTableOffsetHigh)<<32 | uint64(v.data.HashTableOffset)
+	size := uint64(v.data.HashTableEntries) * hashEntryDwords * dwordSize
+
+	data, err := v.readTable(offset, v.dataExtension.HashTableSize64, size, hashString("(hash table)", 3))
+	if err != nil {
+		return fmt.Errorf("reading hash table: %w", err)
+	}
+
+	v.hashTableEntries = make([]HashTableEntry, v.data.HashTableEntries)
+
+	for i := range v.hashTableEntries {
+		entry := data[i*hashEntryDwords*dwordSize:]
+		v.hashTableEntries[i] = HashTableEntry{
+			NamePartA: binary.LittleEndian.Uint32(entry),
+			NamePartB: binary.LittleEndian.Uint32(entry[4:]),
 			// https://github.com/OpenDiablo2/OpenDiablo2/issues/812
-			Locale:     uint16(hashData[(i*4)+2] >> 16),    //nolint:gomnd // // binary data
-			Platform:   uint16(hashData[(i*4)+2] & 0xFFFF), //nolint:gomnd // // binary data
-			BlockIndex: hashData[(i*4)+3],
-		})
+			Locale:     binary.LittleEndian.Uint16(entry[10:]),
+			Platform:   binary.LittleEndian.Uint16(entry[8:]),
+			BlockIndex: binary.LittleEndian.Uint32(entry[12:]),
+		}
+
+		v.hashEntryMap.Insert(&v.hashTableEntries[i])
 	}
 
 	return nil
 }
 
-func (v *MPQ) loadBlockTable() {
-	_, err := v.file.Seek(int64(v.data.BlockTableOffset), 0)
+func (v *MPQ) loadBlockTable() error {
+	offset := uint64(v.dataExtension.BlockTableOffsetHigh)<<32 | uint64(v.data.BlockTableOffset)
+	size := uint64(v.data.BlockTableEntries) * blockEntryDwords * dwordSize
+
+	data, err := v.readTable(offset, v.dataExtension.BlockTableSize64, size, hashString("(block table)", 3))
 	if err != nil {
-		log.Panic(err)
+		return fmt.Errorf("reading block table: %w", err)
 	}
 
-	blockData := make([]uint32, v.data.BlockTableEntries*4) //nolint:gomnd // // binary data
-	hash := make([]byte, 4)
+	var hiBlockData []byte
+
+	if v.dataExtension.HiBlockTableOffset != 0 {
+		hiBlockSize := uint64(v.data.BlockTableEntries) * hiBlockEntrySize
 
-	for i := range blockData {
-		_, err = v.file.Read(hash) //nolint:errcheck // Will fix later
+		hiBlockData, err = v.readTable(v.dataExtension.HiBlockTableOffset, v.dataExtension.HiBlockTableSize64, hiBlockSize, 0)
 		if err != nil {
-			log.Print(err)
+			return fmt.Errorf("reading hi-block table: %w", err)
 		}
-
-		blockData[i] = binary.LittleEndian.Uint32(hash)
 	}
 
-	decrypt(blockData, hashString("(block table)", 3))
+	v.blockTableEntries = make([]BlockTableEntry, v.data.BlockTableEntries)
+
+	for i := range v.blockTableEntries {
+		entry := data[i*blockEntryDwords*dwordSize:]
+		v.blockTableEntries[i] = BlockTableEntry{
+			FilePosition:         binary.LittleEndian.Uint32(entry),
+			CompressedFileSize:   binary.LittleEndian.Uint32(entry[4:]),
+			UncompressedFileSize: binary.LittleEndian.Uint32(entry[8:]),
+			Flags:                FileFlag(binary.LittleEndian.Uint32(entry[12:])),
+		}
 
-	for i := uint32(0); i < v.data.BlockTableEntries; i++ {
-		v.blockTableEntries = append(v.blockTableEntries, BlockTableEntry{
-			FilePosition:         blockData[(i * 4)],
-			CompressedFileSize:   blockData[(i*4)+1],
-			UncompressedFileSize: blockData[(i*4)+2],
-			Flags:                FileFlag(blockData[(i*4)+3]),
-		})
+		if hiBlockData != nil {
+			v.blockTableEntries[i].FilePositionHigh = binary.LittleEndian.Uint16(hiBlockData[i*hiBlockEntrySize:])
+		}
 	}
+
+	return nil
 }
 
 func decrypt(data []uint32, seed uint32) {
@@ -262,6 +416,43 @@ func decryptBytes(data []byte, seed uint32) {
 	}
 }
 
+func encrypt(data []uint32, seed uint32) {
+	seed2 := uint32(0xeeeeeeee) //nolint:gomnd // Decryption magic
+
+	for i := 0; i < len(data); i++ {
+		seed2 += cryptoLookup(0x400 + (seed & 0xff)) //nolint:gomnd // Decryption magic
+		plain := data[i]
+		data[i] ^= seed + seed2
+
+		seed = ((^seed << 21) + 0x11111111) | (seed >> 11)
+		seed2 = plain + seed2 + (seed2 << 5) + 3 //nolint:gomnd // Decryption magic
+	}
+}
+
+func encryptBytes(data []byte, seed uint32) {
+	seed2 := uint32(0xEEEEEEEE) //nolint:gomnd // Decryption magic
+	for i := 0; i < len(data)-3; i += 4 {
+		seed2 += cryptoLookup(0x400 + (seed & 0xFF)) //nolint:gomnd // Decryption magic
+		plain := binary.LittleEndian.Uint32(data[i : i+4])
+		binary.LittleEndian.PutUint32(data[i:i+4], plain^(seed+seed2))
+		seed = ((^seed << 21) + 0x11111111) | (seed >> 11)
+		seed2 = plain + seed2 + (seed2 << 5) + 3 //nolint:gomnd // Decryption magic
+	}
+}
+
+// fileEncryptionSeed returns the key a file is encrypted with, which is derived from the file
+// name without its directory and, for FileFixKey files, the position and size of the file
+func fileEncryptionSeed(fileName string, entry BlockTableEntry) uint32 {
+	fileSegs := strings.Split(fileName, `\`)
+	seed := hashString(fileSegs[len(fileSegs)-1], 3)
+
+	if entry.HasFlag(FileFixKey) {
+		seed = (seed + entry.FilePosition) ^ entry.UncompressedFileSize
+	}
+
+	return seed
+}
+
 func hashString(key string, hashType uint32) uint32 {
 	seed1 := uint32(0x7FED7FED) //nolint:gomnd // Decryption magic
 	seed2 := uint32(0xEEEEEEEE) //nolint:gomnd // Decryption magic
@@ -277,13 +468,28 @@ func hashString(key string, hashType uint32) uint32 {
 
 // GetFileBlockData gets a block table entry
 func (v *MPQ) getFileBlockData(fileName string) (BlockTableEntry, error) {
+	if block, found := v.findBlock(fileName); found {
+		return block, nil
+	}
+
+	return BlockTableEntry{}, errors.New("file not found")
+}
+
+// findBlock finds the block of a file in the hash table, or in the HET table when the hash
+// table does not contain the file, like StormLib does
+func (v *MPQ) findBlock(fileName string) (BlockTableEntry, bool) {
 	fileEntry, found := v.hashEntryMap.Find(fileName)
+	if found && fileEntry.BlockIndex < uint32(len(v.blockTableEntries)) {
+		return v.blockTableEntries[fileEntry.BlockIndex], true
+	}
 
-	if !found || fileEntry.BlockIndex >= uint32(len(v.blockTableEntries)) {
-		return BlockTableEntry{}, errors.New("file not found")
+	if v.hetTable != nil {
+		if idx, found := v.hetTable.find(fileName, v.betTable); found {
+			return v.betTable.entries[idx], true
+		}
 	}
 
-	return v.blockTableEntries[fileEntry.BlockIndex], nil
+	return BlockTableEntry{}, false
 }
 
 // Close closes the MPQ file
@@ -296,7 +502,8 @@ func (v *MPQ) Close() {
 
 // FileExists checks the mpq to see if the file exists
 func (v *MPQ) FileExists(fileName string) bool {
-	return v.hashEntryMap.Contains(fileName)
+	_, found := v.findBlock(fileName)
+	return found
 }
 
 // ReadFile reads a file from the MPQ and returns a memory stream
@@ -316,7 +523,10 @@ func (v *MPQ) ReadFile(fileName string) ([]byte, error) {
 	}
 
 	buffer := make([]byte, fileBlockData.UncompressedFileSize)
-	mpqStream.Read(buffer, 0, fileBlockData.UncompressedFileSize)
+
+	if _, err := mpqStream.Read(buffer, 0, fileBlockData.UncompressedFileSize); err != nil {
+		return []byte{}, err
+	}
 
 	return buffer, nil
 }
@@ -362,27 +572,6 @@ func (v *BlockTableEntry) calculateEncryptionSeed() {
 	v.EncryptionSeed = (v.EncryptionSeed + v.FilePosition) ^ v.UncompressedFileSize
 }
 
-// GetFileList returns the list of files in this MPQ
-func (v *MPQ) GetFileList() ([]string, error) {
-	data, err := v.ReadFile("(listfile)")
-
-	if err != nil {
-		return nil, err
-	}
-
-	raw := strings.TrimRight(string(data), "\x00")
-	s := bufio.NewScanner(strings.NewReader(raw))
-
-	var filePaths []string
-
-	for s.Scan() {
-		filePath := s.Text()
-		filePaths = append(filePaths, filePath)
-	}
-
-	return filePaths, nil
-}
-
 // Path returns the MPQ file path
 func (v *MPQ) Path() string {
 	return v.filePath
@@ -390,7 +579,7 @@ func (v *MPQ) Path() string {
 
 // Contains returns bool for whether the given filename exists in the mpq
 func (v *MPQ) Contains(filename string) bool {
-	return v.hashEntryMap.Contains(filename)
+	return v.FileExists(filename)
 }
 
 // Size returns the size of the mpq in bytes
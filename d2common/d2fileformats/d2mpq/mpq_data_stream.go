@@ -11,8 +11,8 @@ type MpqDataStream struct {
 
 // Read reads data from the data stream
 func (m *MpqDataStream) Read(p []byte) (n int, err error) {
-	totalRead := m.stream.Read(p, 0, uint32(len(p)))
-	return int(totalRead), nil
+	totalRead, err := m.stream.Read(p, 0, uint32(len(p)))
+	return int(totalRead), err
 }
 
 // Seek sets the position of the data stream
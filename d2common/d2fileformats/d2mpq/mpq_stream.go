@@ -1,17 +1,11 @@
 package d2mpq
 
 import (
-	"bytes"
-	"compress/zlib"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"log"
-	"strings"
 
-	"github.com/JoshVarga/blast"
-
-	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2data/d2compression"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math"
 )
 
@@ -31,21 +25,17 @@ type Stream struct {
 // CreateStream creates an MPQ stream
 func CreateStream(mpq *MPQ, blockTableEntry BlockTableEntry, fileName string) (*Stream, error) {
 	result := &Stream{
+		FileName:          fileName,
 		MPQData:           mpq,
 		BlockTableEntry:   blockTableEntry,
 		CurrentBlockIndex: 0xFFFFFFFF, //nolint:gomnd // MPQ magic
 	}
-	fileSegs := strings.Split(fileName, `\`)
-	result.EncryptionSeed = hashString(fileSegs[len(fileSegs)-1], 3)
-
-	if result.BlockTableEntry.HasFlag(FileFixKey) {
-		result.EncryptionSeed = (result.EncryptionSeed + result.BlockTableEntry.FilePosition) ^ result.BlockTableEntry.UncompressedFileSize
-	}
+	result.EncryptionSeed = fileEncryptionSeed(fileName, blockTableEntry)
 
 	result.BlockSize = 0x200 << result.MPQData.data.BlockSize //nolint:gomnd // MPQ magic
 
 	if result.BlockTableEntry.HasFlag(FilePatchFile) {
-		log.Fatal("Patching is not supported")
+		return nil, errors.New("patching is not supported")
 	}
 
 	var err error
@@ -62,15 +52,9 @@ func (v *Stream) loadBlockOffsets() error {
 	blockPositionCount := ((v.BlockTableEntry.UncompressedFileSize + v.BlockSize - 1) / v.BlockSize) + 1
 	v.BlockPositions = make([]uint32, blockPositionCount)
 
-	_, err := v.MPQData.file.Seek(int64(v.BlockTableEntry.FilePosition), 0)
-	if err != nil {
-		return err
-	}
-
 	mpqBytes := make([]byte, blockPositionCount*4) //nolint:gomnd // MPQ magic
 
-	_, err = v.MPQData.file.Read(mpqBytes)
-	if err != nil {
+	if err := v.MPQData.readAt(v.BlockTableEntry.offset(), mpqBytes); err != nil {
 		return err
 	}
 
@@ -98,7 +82,7 @@ func (v *Stream) loadBlockOffsets() error {
 	return nil
 }
 
-func (v *Stream) Read(buffer []byte, offset, count uint32) uint32 {
+func (v *Stream) Read(buffer []byte, offset, count uint32) (uint32, error) {
 	if v.BlockTableEntry.HasFlag(FileSingleUnit) {
 		return v.readInternalSingleUnit(buffer, offset, count)
 	}
@@ -107,7 +91,10 @@ func (v *Stream) Read(buffer []byte, offset, count uint32) uint32 {
 	readTotal := uint32(0)
 
 	for toRead > 0 {
-		read := v.readInternal(buffer, offset, toRead)
+		read, err := v.readInternal(buffer, offset, toRead)
+		if err != nil {
+			return readTotal, err
+		}
 
 		if read == 0 {
 			break
@@ -118,12 +105,18 @@ func (v *Stream) Read(buffer []byte, offset, count uint32) uint32 {
 		toRead -= read
 	}
 
-	return readTotal
+	return readTotal, nil
 }
 
-func (v *Stream) readInternalSingleUnit(buffer []byte, offset, count uint32) uint32 {
+func (v *Stream) readInternalSingleUnit(buffer []byte, offset, count uint32) (uint32, error) {
 	if len(v.CurrentData) == 0 {
-		v.loadSingleUnit()
+		if err := v.loadSingleUnit(); err != nil {
+			return 0, err
+		}
+	}
+
+	if v.CurrentPosition >= uint32(len(v.CurrentData)) {
+		return 0, nil
 	}
 
 	bytesToCopy := d2math.Min(uint32(len(v.CurrentData))-v.CurrentPosition, count)
@@ -132,66 +125,90 @@ func (v *Stream) readInternalSingleUnit(buffer []byte, offset, count uint32) uin
 
 	v.CurrentPosition += bytesToCopy
 
-	return bytesToCopy
+	return bytesToCopy, nil
 }
 
-func (v *Stream) readInternal(buffer []byte, offset, count uint32) uint32 {
-	v.bufferData()
+func (v *Stream) readInternal(buffer []byte, offset, count uint32) (uint32, error) {
+	if v.CurrentPosition >= v.BlockTableEntry.UncompressedFileSize {
+		return 0, nil
+	}
+
+	if err := v.bufferData(); err != nil {
+		return 0, err
+	}
 
 	localPosition := v.CurrentPosition % v.BlockSize
 	bytesToCopy := d2math.MinInt32(int32(len(v.CurrentData))-int32(localPosition), int32(count))
 
 	if bytesToCopy <= 0 {
-		return 0
+		return 0, nil
 	}
 
 	copy(buffer[offset:offset+uint32(bytesToCopy)], v.CurrentData[localPosition:localPosition+uint32(bytesToCopy)])
 
 	v.CurrentPosition += uint32(bytesToCopy)
 
-	return uint32(bytesToCopy)
+	return uint32(bytesToCopy), nil
 }
 
-func (v *Stream) bufferData() {
+func (v *Stream) bufferData() error {
 	requiredBlock := v.CurrentPosition / v.BlockSize
 
 	if requiredBlock == v.CurrentBlockIndex {
-		return
+		return nil
 	}
 
 	expectedLength := d2math.Min(v.BlockTableEntry.UncompressedFileSize-(requiredBlock*v.BlockSize), v.BlockSize)
-	v.CurrentData = v.loadBlock(requiredBlock, expectedLength)
+
+	data, err := v.loadBlock(requiredBlock, expectedLength)
+	if err != nil {
+		return err
+	}
+
+	v.CurrentData = data
 	v.CurrentBlockIndex = requiredBlock
+
+	return nil
 }
 
-func (v *Stream) loadSingleUnit() {
-	fileData := make([]byte, v.BlockSize)
+func (v *Stream) loadSingleUnit() error {
+	fileData := make([]byte, v.BlockTableEntry.CompressedFileSize)
 
-	_, err := v.MPQData.file.Seek(int64(v.MPQData.data.HeaderSize), 0)
-	if err != nil {
-		log.Print(err)
+	if err := v.MPQData.readAt(v.BlockTableEntry.offset(), fileData); err != nil {
+		return err
 	}
 
-	_, err = v.MPQData.file.Read(fileData)
-	if err != nil {
-		log.Print(err)
+	if v.BlockTableEntry.HasFlag(FileEncrypted) {
+		if v.EncryptionSeed == 0 {
+			return errors.New("unable to determine encryption key")
+		}
+
+		decryptBytes(fileData, v.EncryptionSeed)
 	}
 
-	if v.BlockSize == v.BlockTableEntry.UncompressedFileSize {
-		v.CurrentData = fileData
-		return
+	expectedLength := v.BlockTableEntry.UncompressedFileSize
+
+	data, err := v.decompressBlock(fileData, expectedLength)
+	if err != nil {
+		return err
 	}
 
-	v.CurrentData = decompressMulti(fileData, v.BlockTableEntry.UncompressedFileSize)
+	v.CurrentData = data
+
+	return nil
 }
 
-func (v *Stream) loadBlock(blockIndex, expectedLength uint32) []byte {
+func (v *Stream) loadBlock(blockIndex, expectedLength uint32) ([]byte, error) {
 	var (
 		offset uint32
 		toRead uint32
 	)
 
 	if v.BlockTableEntry.HasFlag(FileCompress) || v.BlockTableEntry.HasFlag(FileImplode) {
+		if int(blockIndex)+1 >= len(v.BlockPositions) || v.BlockPositions[blockIndex+1] < v.BlockPositions[blockIndex] {
+			return nil, fmt.Errorf("invalid sector offset table for block %d", blockIndex)
+		}
+
 		offset = v.BlockPositions[blockIndex]
 		toRead = v.BlockPositions[blockIndex+1] - offset
 	} else {
@@ -199,138 +216,51 @@ func (v *Stream) loadBlock(blockIndex, expectedLength uint32) []byte {
 		toRead = expectedLength
 	}
 
-	offset += v.BlockTableEntry.FilePosition
 	data := make([]byte, toRead)
 
-	_, err := v.MPQData.file.Seek(int64(offset), 0)
-	if err != nil {
-		log.Print(err)
-	}
-
-	_, err = v.MPQData.file.Read(data)
-	if err != nil {
-		log.Print(err)
+	if err := v.MPQData.readAt(v.BlockTableEntry.offset()+uint64(offset), data); err != nil {
+		return nil, err
 	}
 
 	if v.BlockTableEntry.HasFlag(FileEncrypted) && v.BlockTableEntry.UncompressedFileSize > 3 {
 		if v.EncryptionSeed == 0 {
-			panic("Unable to determine encryption key")
+			return nil, errors.New("unable to determine encryption key")
 		}
 
 		decryptBytes(data, blockIndex+v.EncryptionSeed)
 	}
 
-	if v.BlockTableEntry.HasFlag(FileCompress) && (toRead != expectedLength) {
-		if !v.BlockTableEntry.HasFlag(FileSingleUnit) {
-			data = decompressMulti(data, expectedLength)
-		} else {
-			data = pkDecompress(data)
-		}
-	}
-
-	if v.BlockTableEntry.HasFlag(FileImplode) && (toRead != expectedLength) {
-		data = pkDecompress(data)
-	}
-
-	return data
+	return v.decompressBlock(data, expectedLength)
 }
 
-//nolint:gomnd // Will fix enum values later
-func decompressMulti(data []byte /*expectedLength*/, _ uint32) []byte {
-	compressionType := data[0]
-
-	switch compressionType {
-	case 1: // Huffman
-		panic("huffman decompression not supported")
-	case 2: // ZLib/Deflate
-		return deflate(data[1:])
-	case 8: // PKLib/Impode
-		return pkDecompress(data[1:])
-	case 0x10: // BZip2
-		panic("bzip2 decompression not supported")
-	case 0x80: // IMA ADPCM Stereo
-		return d2compression.WavDecompress(data[1:], 2)
-	case 0x40: // IMA ADPCM Mono
-		return d2compression.WavDecompress(data[1:], 1)
-	case 0x12:
-		panic("lzma decompression not supported")
-	// Combos
-	case 0x22:
-		// sparse then zlib
-		panic("sparse decompression + deflate decompression not supported")
-	case 0x30:
-		// sparse then bzip2
-		panic("sparse decompression + bzip2 decompression not supported")
-	case 0x41:
-		sinput := d2compression.HuffmanDecompress(data[1:])
-		sinput = d2compression.WavDecompress(sinput, 1)
-		tmp := make([]byte, len(sinput))
-
-		copy(tmp, sinput)
-
-		return tmp
-	case 0x48:
-		// byte[] result = PKDecompress(sinput, outputLength);
-		// return MpqWavCompression.Decompress(new MemoryStream(result), 1);
-		panic("pk + mpqwav decompression not supported")
-	case 0x81:
-		sinput := d2compression.HuffmanDecompress(data[1:])
-		sinput = d2compression.WavDecompress(sinput, 2)
-		tmp := make([]byte, len(sinput))
-		copy(tmp, sinput)
-
-		return tmp
-	case 0x88:
-		// byte[] result = PKDecompress(sinput, outputLength);
-		// return MpqWavCompression.Decompress(new MemoryStream(result), 2);
-		panic("pk + wav decompression not supported")
-	default:
-		panic(fmt.Sprintf("decompression not supported for unknown compression type %X", compressionType))
+// decompressBlock decompresses a sector of the file, if it is stored compressed
+func (v *Stream) decompressBlock(data []byte, expectedLength uint32) ([]byte, error) {
+	if uint32(len(data)) >= expectedLength {
+		// the compression did not reduce the size, so the data is stored uncompressed
+		return data[:expectedLength], nil
 	}
-}
-
-func deflate(data []byte) []byte {
-	b := bytes.NewReader(data)
-	r, err := zlib.NewReader(b)
 
-	if err != nil {
-		panic(err)
-	}
-
-	buffer := new(bytes.Buffer)
-
-	_, err = buffer.ReadFrom(r)
-	if err != nil {
-		log.Panic(err)
-	}
-
-	err = r.Close()
-	if err != nil {
-		log.Panic(err)
-	}
-
-	return buffer.Bytes()
-}
-
-func pkDecompress(data []byte) []byte {
-	b := bytes.NewReader(data)
-	r, err := blast.NewReader(b)
+	var (
+		result []byte
+		err    error
+	)
 
-	if err != nil {
-		panic(err)
+	switch {
+	case v.BlockTableEntry.HasFlag(FileCompress):
+		result, err = decompressMulti(data, expectedLength)
+	case v.BlockTableEntry.HasFlag(FileImplode):
+		result, err = safeDecompress(pkDecompressSized, data, expectedLength)
+	default:
+		return nil, fmt.Errorf("file %s is smaller than its uncompressed size but not compressed", v.FileName)
 	}
 
-	buffer := new(bytes.Buffer)
-
-	_, err = buffer.ReadFrom(r)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("decompressing %s: %w", v.FileName, err)
 	}
 
-	err = r.Close()
-	if err != nil {
-		panic(err)
+	if uint32(len(result)) != expectedLength {
+		return nil, fmt.Errorf("decompressing %s: expected %d bytes, got %d", v.FileName, expectedLength, len(result))
 	}
 
-	return buffer.Bytes()
+	return result, nil
 }
@@ -0,0 +1,188 @@
+package d2mpq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2resource"
+)
+
+// the test archive has a version 4 header, and both the hash and block tables and HET and BET tables
+var testArchiveFiles = []string{"common.txt", "exclusive_d.txt", testSourceFile} //nolint:gochecknoglobals // test data
+
+func loadTestArchive(t *testing.T, fileName string) *MPQ {
+	archive, err := Load(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return archive.(*MPQ)
+}
+
+func readTestArchiveFiles(t *testing.T) map[string][]byte {
+	archive := loadTestArchive(t, testSourceArchive)
+	defer archive.Close()
+
+	contents := make(map[string][]byte)
+
+	for _, name := range testArchiveFiles {
+		data, err := archive.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents[name] = data
+	}
+
+	return contents
+}
+
+func TestHashLittle2(t *testing.T) {
+	const key = "Four score and seven years ago"
+
+	// test vectors from lookup3.c
+	table := []struct {
+		key                     string
+		primarySeed, secondSeed uint32
+		primary, secondary      uint32
+	}{
+		{"", 0, 0, 0xdeadbeef, 0xdeadbeef},
+		{"", 0xdeadbeef, 0, 0xdeadbeef, 0xbd5b7dde},
+		{"", 0xdeadbeef, 0xdeadbeef, 0xbd5b7dde, 0x9c093ccd},
+		{key, 0, 0, 0xce7226e6, 0x17770551},
+		{key, 1, 0, 0xbd371de4, 0xe3607cae},
+		{key, 0, 1, 0x6cbea4b3, 0xcd628161},
+	}
+
+	for _, row := range table {
+		primary, secondary := hashLittle2([]byte(row.key), row.primarySeed, row.secondSeed)
+		if primary != row.primary || secondary != row.secondary {
+			t.Errorf("hashLittle2(%q, %x, %x) = %x %x, want %x %x", row.key, row.primarySeed, row.secondSeed,
+				primary, secondary, row.primary, row.secondary)
+		}
+	}
+}
+
+func TestHetBetTables(t *testing.T) {
+	expected := readTestArchiveFiles(t)
+
+	archive := loadTestArchive(t, testSourceArchive)
+	defer archive.Close()
+
+	if archive.data.FormatVersion != 3 || archive.hetTable == nil || archive.betTable == nil {
+		t.Fatalf("format version %d archive was loaded without HET and BET tables", archive.data.FormatVersion)
+	}
+
+	// without the hash table, files are found through the HET table
+	archive.hashEntryMap = HashEntryMap{}
+
+	for name, data := range expected {
+		checkArchiveFile(t, archive, name, data)
+	}
+
+	if archive.FileExists("missing.txt") {
+		t.Error("missing file was found in the HET table")
+	}
+}
+
+func TestHeaderOffset(t *testing.T) {
+	expected := readTestArchiveFiles(t)
+
+	source, err := ioutil.ReadFile(testSourceArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "d2mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	userData := make([]byte, 3*headerSearchStep)
+	copy(userData, userDataMagic)
+	binary.LittleEndian.PutUint32(userData[4:], headerSearchStep)
+	binary.LittleEndian.PutUint32(userData[8:], 3*headerSearchStep)
+	binary.LittleEndian.PutUint32(userData[12:], userDataHeaderSize)
+
+	table := []struct {
+		name   string
+		prefix []byte
+	}{
+		{"search.mpq", bytes.Repeat([]byte{0xAA}, 2*headerSearchStep)},
+		{"userdata.mpq", userData},
+	}
+
+	for _, row := range table {
+		fileName := filepath.Join(dir, row.name)
+
+		if err := ioutil.WriteFile(fileName, append(row.prefix, source...), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		archive := loadTestArchive(t, fileName)
+
+		if archive.headerOffset != int64(len(row.prefix)) {
+			t.Errorf("%s: header found at %d, want %d", row.name, archive.headerOffset, len(row.prefix))
+		}
+
+		for name, data := range expected {
+			checkArchiveFile(t, archive, name, data)
+		}
+
+		archive.Close()
+	}
+}
+
+func TestGetFileListWithoutListFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "d2mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	w, err := NewWriter(testHashTableSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resourcePath := "data\\global\\excel\\misc.txt"
+	externalPath := "data\\global\\custom.txt"
+
+	for _, name := range []string{resourcePath, externalPath, "unknown.txt"} {
+		if err := w.AddFile(name, []byte(name), FileOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive := writeTestArchive(t, w, dir, "test.mpq")
+	defer archive.Close()
+
+	// strip the listfile
+	delete(archive.hashEntryMap.entries, nameKey(listFileName))
+
+	if list, err := archive.GetFileList(); err != nil || len(list) != 1 || list[0] != resourcePath {
+		t.Errorf("file list from resource paths is %v (%v), want %s", list, err, resourcePath)
+	}
+
+	archive.AddListFile([]string{"/data/global/custom.txt", "data\\global\\missing.txt"})
+
+	list, err := archive.GetFileList()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 2 || list[0] != externalPath || list[1] != resourcePath {
+		t.Errorf("file list with external listfile is %v, want %s and %s", list, externalPath, resourcePath)
+	}
+
+	if len(d2resource.KnownPaths()) == 0 {
+		t.Error("no known resource paths")
+	}
+}
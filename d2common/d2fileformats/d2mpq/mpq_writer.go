@@ -0,0 +1,798 @@
+package d2mpq
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5" //nolint:gosec // MD5 is what the (attributes) file format uses
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2data/d2compression"
+)
+
+const (
+	mpqMagic           = "MPQ\x1A"
+	headerSizeV1       = 32
+	hashEntryDwords    = 4
+	blockEntryDwords   = 4
+	dwordSize          = 4
+	sectorSizeBase     = 0x200
+	defaultBlockSize   = 3 // 4096 byte sectors
+	hashTableEmpty     = 0xFFFFFFFF
+	hashTableDeleted   = 0xFFFFFFFE
+	listFileName       = "(listfile)"
+	attributesFileName = "(attributes)"
+	attributesVersion  = 100
+	attributesCRC32    = 0x1
+	attributesMD5      = 0x4
+)
+
+// FileOptions describes how a file is stored in an archive
+type FileOptions struct {
+	// Compression is applied to each sector of the file: CompressionZLib, CompressionPKWare,
+	// CompressionBZip2, or zero to store the file uncompressed
+	Compression CompressionType
+	// Implode stores the file with the FileImplode flag, which compresses every sector with
+	// PKWARE and no compression type byte. Compression is ignored when it is set.
+	Implode bool
+	// SingleUnit stores the file as a single unit instead of splitting it into sectors
+	SingleUnit bool
+	// Encrypted encrypts the file with a key derived from its name
+	Encrypted bool
+	// FixKey alters the encryption key with the position of the file in the archive, it implies Encrypted
+	FixKey bool
+}
+
+// writerFile is a file which will be written to the archive. It is either new, in which case
+// data holds its contents, or copied from an existing archive, in which case raw holds the data
+// exactly as it was stored.
+type writerFile struct {
+	name       string
+	hashA      uint32
+	hashB      uint32
+	locale     uint16
+	platform   uint16
+	hashIndex  int // position in the hash table, kept for files whose name is unknown
+	data       []byte
+	options    FileOptions
+	raw        []byte
+	rawBlock   BlockTableEntry
+	copied     bool
+	crc32      uint32
+	md5        [md5.Size]byte
+	checksumed bool
+}
+
+func (f *writerFile) setChecksums(data []byte) {
+	f.crc32 = crc32.ChecksumIEEE(data)
+	f.md5 = md5.Sum(data) //nolint:gosec // MD5 is what the (attributes) file format uses
+	f.checksumed = true
+}
+
+// Writer creates MPQ archives, either from scratch or as a modified copy of an existing archive
+type Writer struct {
+	hashTableSize uint32
+	blockSize     uint16
+	files         []*writerFile
+	// hash table slots used by the archive the writer was opened from, set when files without a
+	// known name keep their slots so their hash chains can be preserved with deletion markers
+	sourceSlots []bool
+}
+
+// NewWriter creates a writer for an empty archive. The hash table size must be a power of two
+// and limits the number of files the archive can hold, including the (listfile) and (attributes).
+func NewWriter(hashTableSize uint32) (*Writer, error) {
+	if hashTableSize == 0 || hashTableSize&(hashTableSize-1) != 0 {
+		return nil, fmt.Errorf("hash table size %d is not a power of two", hashTableSize)
+	}
+
+	return &Writer{hashTableSize: hashTableSize, blockSize: defaultBlockSize}, nil
+}
+
+// OpenWriter creates a writer holding a copy of the files of an existing archive, which can then
+// be added to, replaced or deleted. Files are copied as they are stored, their names are taken from
+// the (listfile) of the archive. A hash table size of zero keeps the size of the existing archive.
+func OpenWriter(fileName string, hashTableSize uint32) (*Writer, error) {
+	archive, err := Load(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	mpq, ok := archive.(*MPQ)
+	if !ok {
+		return nil, errors.New("unexpected archive type")
+	}
+
+	defer mpq.Close()
+
+	return newWriterFromArchive(mpq, hashTableSize)
+}
+
+func newWriterFromArchive(mpq *MPQ, hashTableSize uint32) (*Writer, error) {
+	if hashTableSize == 0 {
+		hashTableSize = mpq.data.HashTableEntries
+	}
+
+	w, err := NewWriter(hashTableSize)
+	if err != nil {
+		return nil, err
+	}
+
+	w.blockSize = mpq.data.BlockSize
+
+	names := map[uint64]string{
+		nameKey(listFileName):       listFileName,
+		nameKey(attributesFileName): attributesFileName,
+	}
+
+	// archives without a (listfile) are still copied, but none of their files can be renamed
+	if list, err := mpq.GetFileList(); err == nil {
+		for _, name := range list {
+			names[nameKey(name)] = name
+		}
+	}
+
+	unnamed := false
+
+	for idx := range mpq.hashTableEntries {
+		entry := mpq.hashTableEntries[idx]
+		if entry.BlockIndex >= uint32(len(mpq.blockTableEntries)) {
+			continue
+		}
+
+		block := mpq.blockTableEntries[entry.BlockIndex]
+		if !block.HasFlag(FileExists) {
+			continue
+		}
+
+		name := names[uint64(entry.NamePartA)<<32|uint64(entry.NamePartB)]
+		if isSpecialFile(name) {
+			continue
+		}
+
+		f, err := copyArchiveFile(mpq, entry, block, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if name == "" {
+			if block.HasFlag(FileFixKey) {
+				return nil, fmt.Errorf("can not move file %d, its name is unknown and its key depends on its position", idx)
+			}
+
+			f.hashIndex = idx
+			unnamed = true
+		}
+
+		w.files = append(w.files, f)
+	}
+
+	if unnamed {
+		if hashTableSize != mpq.data.HashTableEntries {
+			return nil, errors.New("can not resize the hash table, the archive contains files whose names are unknown")
+		}
+
+		w.sourceSlots = make([]bool, hashTableSize)
+
+		for idx := range mpq.hashTableEntries {
+			w.sourceSlots[idx] = mpq.hashTableEntries[idx].BlockIndex != hashTableEmpty
+		}
+	}
+
+	// keep the files in the order they were stored in
+	sort.SliceStable(w.files, func(i, j int) bool {
+		return w.files[i].rawBlock.FilePosition < w.files[j].rawBlock.FilePosition
+	})
+
+	return w, nil
+}
+
+func copyArchiveFile(mpq *MPQ, entry HashTableEntry, block BlockTableEntry, name string) (*writerFile, error) {
+	raw := make([]byte, block.CompressedFileSize)
+
+	if err := mpq.readAt(block.offset(), raw); err != nil {
+		return nil, err
+	}
+
+	f := &writerFile{
+		name:      name,
+		hashA:     entry.NamePartA,
+		hashB:     entry.NamePartB,
+		locale:    entry.Locale,
+		platform:  entry.Platform,
+		hashIndex: -1,
+		raw:       raw,
+		rawBlock:  block,
+		copied:    true,
+	}
+
+	if name != "" {
+		// checksums for the (attributes) are left empty when the file can not be read
+		if data, err := mpq.ReadFile(name); err == nil {
+			f.setChecksums(data)
+		}
+	}
+
+	return f, nil
+}
+
+func nameKey(fileName string) uint64 {
+	return uint64(hashString(fileName, 1))<<32 | uint64(hashString(fileName, 2))
+}
+
+func isSpecialFile(fileName string) bool {
+	return strings.EqualFold(fileName, listFileName) || strings.EqualFold(fileName, attributesFileName)
+}
+
+// SetBlockSize sets the sector size of the archive to 512 << blockSize bytes
+func (w *Writer) SetBlockSize(blockSize uint16) error {
+	for _, f := range w.files {
+		if f.copied && blockSize != w.blockSize {
+			return errors.New("can not change the sector size of an archive with copied files")
+		}
+	}
+
+	w.blockSize = blockSize
+
+	return nil
+}
+
+// Contains returns true if the archive will contain the given file
+func (w *Writer) Contains(fileName string) bool {
+	return w.find(fileName) >= 0
+}
+
+func (w *Writer) find(fileName string) int {
+	key := nameKey(fileName)
+
+	for idx, f := range w.files {
+		if uint64(f.hashA)<<32|uint64(f.hashB) == key {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func newWriterFile(fileName string, data []byte, options FileOptions) (*writerFile, error) {
+	if fileName == "" {
+		return nil, errors.New("file name is empty")
+	}
+
+	if isSpecialFile(fileName) {
+		return nil, fmt.Errorf("%s is written by the archive writer", fileName)
+	}
+
+	switch options.Compression {
+	case 0, CompressionZLib, CompressionPKWare, CompressionBZip2:
+	default:
+		return nil, fmt.Errorf("compression %X is not supported for writing", options.Compression)
+	}
+
+	f := &writerFile{
+		name:      fileName,
+		hashA:     hashString(fileName, 1),
+		hashB:     hashString(fileName, 2),
+		hashIndex: -1,
+		data:      data,
+		options:   options,
+	}
+
+	f.setChecksums(data)
+
+	return f, nil
+}
+
+// AddFile adds a file to the archive, it fails if the archive already contains the file
+func (w *Writer) AddFile(fileName string, data []byte, options FileOptions) error {
+	if w.Contains(fileName) {
+		return fmt.Errorf("file %s already exists", fileName)
+	}
+
+	f, err := newWriterFile(fileName, data, options)
+	if err != nil {
+		return err
+	}
+
+	w.files = append(w.files, f)
+
+	return nil
+}
+
+// ReplaceFile replaces the contents of a file in the archive, it fails if the archive does
+// not contain the file
+func (w *Writer) ReplaceFile(fileName string, data []byte, options FileOptions) error {
+	idx := w.find(fileName)
+	if idx < 0 {
+		return fmt.Errorf("file %s not found", fileName)
+	}
+
+	f, err := newWriterFile(fileName, data, options)
+	if err != nil {
+		return err
+	}
+
+	f.locale, f.platform = w.files[idx].locale, w.files[idx].platform
+	w.files[idx] = f
+
+	return nil
+}
+
+// DeleteFile removes a file from the archive
+func (w *Writer) DeleteFile(fileName string) error {
+	idx := w.find(fileName)
+	if idx < 0 {
+		return fmt.Errorf("file %s not found", fileName)
+	}
+
+	w.files = append(w.files[:idx], w.files[idx+1:]...)
+
+	return nil
+}
+
+// Save writes the archive to the given path
+func (w *Writer) Save(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.WriteTo(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// WriteTo writes the archive, followed by its hash and block tables
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	// the (listfile) and (attributes) are always the last two blocks
+	blockCount := len(w.files) + 2 //nolint:gomnd // (listfile) and (attributes)
+	if uint32(blockCount) > w.hashTableSize {
+		return 0, fmt.Errorf("%d files do not fit in a hash table of size %d", blockCount, w.hashTableSize)
+	}
+
+	files := append(append([]*writerFile{}, w.files...), specialFile(listFileName, w.listFile()))
+	files = append(files, specialFile(attributesFileName, attributesFile(files, blockCount)))
+
+	var body bytes.Buffer
+
+	blocks := make([]BlockTableEntry, len(files))
+
+	for idx, f := range files {
+		stored, block, err := w.encodeFile(f, uint32(headerSizeV1+body.Len()))
+		if err != nil {
+			return 0, fmt.Errorf("writing %s: %w", f.name, err)
+		}
+
+		body.Write(stored)
+
+		blocks[idx] = block
+	}
+
+	hashTable, err := w.hashTable(files)
+	if err != nil {
+		return 0, err
+	}
+
+	header := Data{
+		HeaderSize:        headerSizeV1,
+		BlockSize:         w.blockSize,
+		HashTableOffset:   uint32(headerSizeV1 + body.Len()),
+		HashTableEntries:  w.hashTableSize,
+		BlockTableEntries: uint32(len(blocks)),
+	}
+
+	copy(header.Magic[:], mpqMagic)
+	header.BlockTableOffset = header.HashTableOffset + w.hashTableSize*hashEntryDwords*dwordSize
+	header.ArchiveSize = header.BlockTableOffset + uint32(len(blocks))*blockEntryDwords*dwordSize
+
+	var archive bytes.Buffer
+
+	if err := binary.Write(&archive, binary.LittleEndian, &header); err != nil {
+		return 0, err
+	}
+
+	archive.Write(body.Bytes())
+
+	if err := binary.Write(&archive, binary.LittleEndian, hashTable); err != nil {
+		return 0, err
+	}
+
+	if err := binary.Write(&archive, binary.LittleEndian, blockTable(blocks)); err != nil {
+		return 0, err
+	}
+
+	return archive.WriteTo(out)
+}
+
+func specialFile(fileName string, data []byte) *writerFile {
+	return &writerFile{
+		name:      fileName,
+		hashA:     hashString(fileName, 1),
+		hashB:     hashString(fileName, 2),
+		hashIndex: -1,
+		data:      data,
+		options:   FileOptions{Compression: CompressionZLib},
+	}
+}
+
+func (w *Writer) listFile() []byte {
+	names := make([]string, 0, len(w.files))
+
+	for _, f := range w.files {
+		if f.name != "" {
+			names = append(names, f.name)
+		}
+	}
+
+	sort.Strings(names)
+
+	var buffer bytes.Buffer
+
+	for _, name := range names {
+		buffer.WriteString(name)
+		buffer.WriteString("\r\n")
+	}
+
+	return buffer.Bytes()
+}
+
+// attributesFile creates the (attributes) file with the CRC32 and MD5 of each block. The
+// checksums of the (attributes) itself, and of copied files which could not be read, are zero.
+func attributesFile(files []*writerFile, blockCount int) []byte {
+	var buffer bytes.Buffer
+
+	header := []uint32{attributesVersion, attributesCRC32 | attributesMD5}
+	crcs := make([]uint32, blockCount)
+	md5s := make([]byte, blockCount*md5.Size)
+
+	for idx, f := range files {
+		if !f.checksumed {
+			continue
+		}
+
+		crcs[idx] = f.crc32
+		copy(md5s[idx*md5.Size:], f.md5[:])
+	}
+
+	_ = binary.Write(&buffer, binary.LittleEndian, header)
+	_ = binary.Write(&buffer, binary.LittleEndian, crcs)
+	buffer.Write(md5s)
+
+	return buffer.Bytes()
+}
+
+func (w *Writer) hashTable(files []*writerFile) ([]uint32, error) {
+	entries := make([]HashTableEntry, w.hashTableSize)
+
+	for idx := range entries {
+		entries[idx] = HashTableEntry{
+			NamePartA:  hashTableEmpty,
+			NamePartB:  hashTableEmpty,
+			Locale:     0xFFFF, //nolint:gomnd // empty hash table entry
+			Platform:   0xFFFF, //nolint:gomnd // empty hash table entry
+			BlockIndex: hashTableEmpty,
+		}
+	}
+
+	newEntry := func(f *writerFile, blockIndex int) HashTableEntry {
+		return HashTableEntry{
+			NamePartA:  f.hashA,
+			NamePartB:  f.hashB,
+			Locale:     f.locale,
+			Platform:   f.platform,
+			BlockIndex: uint32(blockIndex),
+		}
+	}
+
+	// files without a known name keep their slots, so they are placed first
+	for blockIndex, f := range files {
+		if f.hashIndex >= 0 {
+			entries[f.hashIndex] = newEntry(f, blockIndex)
+		}
+	}
+
+	for blockIndex, f := range files {
+		if f.hashIndex >= 0 {
+			continue
+		}
+
+		slot := hashString(f.name, 0) & (w.hashTableSize - 1)
+
+		for probes := uint32(0); entries[slot].BlockIndex != hashTableEmpty; probes++ {
+			if probes == w.hashTableSize {
+				return nil, errors.New("hash table is full")
+			}
+
+			slot = (slot + 1) & (w.hashTableSize - 1)
+		}
+
+		entries[slot] = newEntry(f, blockIndex)
+	}
+
+	// slots emptied since the archive was opened become deletion markers, so the lookup of files
+	// further along the same hash chain does not stop early
+	for idx, used := range w.sourceSlots {
+		if used && entries[idx].BlockIndex == hashTableEmpty {
+			entries[idx].BlockIndex = hashTableDeleted
+		}
+	}
+
+	data := make([]uint32, 0, w.hashTableSize*hashEntryDwords)
+
+	for _, entry := range entries {
+		// https://github.com/OpenDiablo2/OpenDiablo2/issues/812
+		data = append(data, entry.NamePartA, entry.NamePartB,
+			uint32(entry.Locale)<<16|uint32(entry.Platform), entry.BlockIndex) //nolint:gomnd // binary data
+	}
+
+	encrypt(data, hashString("(hash table)", 3))
+
+	return data, nil
+}
+
+func blockTable(blocks []BlockTableEntry) []uint32 {
+	data := make([]uint32, 0, len(blocks)*blockEntryDwords)
+
+	for _, block := range blocks {
+		data = append(data, block.FilePosition, block.CompressedFileSize, block.UncompressedFileSize, uint32(block.Flags))
+	}
+
+	encrypt(data, hashString("(block table)", 3))
+
+	return data
+}
+
+// encodeFile returns the data of a file as it is stored at the given position of the archive,
+// along with its block table entry
+func (w *Writer) encodeFile(f *writerFile, position uint32) ([]byte, BlockTableEntry, error) {
+	sectorSize := uint32(sectorSizeBase) << w.blockSize
+
+	if f.copied {
+		return f.relocate(position, sectorSize)
+	}
+
+	block := BlockTableEntry{
+		FilePosition:         position,
+		UncompressedFileSize: uint32(len(f.data)),
+		Flags:                FileExists,
+	}
+
+	if len(f.data) == 0 {
+		return nil, block, nil
+	}
+
+	options := f.options
+	compressed := options.Implode || options.Compression != 0
+
+	switch {
+	case options.Implode:
+		block.Flags |= FileImplode
+	case options.Compression != 0:
+		block.Flags |= FileCompress
+	}
+
+	if options.SingleUnit {
+		block.Flags |= FileSingleUnit
+	}
+
+	if options.Encrypted || options.FixKey {
+		block.Flags |= FileEncrypted
+	}
+
+	if options.FixKey {
+		block.Flags |= FileFixKey
+	}
+
+	var (
+		stored []byte
+		err    error
+	)
+
+	switch {
+	case options.SingleUnit:
+		stored = append([]byte{}, f.data...)
+
+		if compressed {
+			stored, err = compressSector(f.data, options)
+		}
+	case compressed:
+		stored, err = compressSectors(f.data, options, sectorSize)
+	default:
+		stored = append([]byte{}, f.data...)
+	}
+
+	if err != nil {
+		return nil, block, err
+	}
+
+	block.CompressedFileSize = uint32(len(stored))
+
+	if block.HasFlag(FileEncrypted) {
+		err = cryptFile(stored, block, fileEncryptionSeed(f.name, block), sectorSize, true)
+	}
+
+	return stored, block, err
+}
+
+// relocate returns the data of a copied file for its new position in the archive, FileFixKey
+// files are encrypted again with the key for that position
+func (f *writerFile) relocate(position, sectorSize uint32) ([]byte, BlockTableEntry, error) {
+	block := f.rawBlock
+	oldSeed := fileEncryptionSeed(f.name, block)
+	block.FilePosition = position
+
+	if !block.HasFlag(FileEncrypted) || !block.HasFlag(FileFixKey) || position == f.rawBlock.FilePosition {
+		return f.raw, block, nil
+	}
+
+	stored := append([]byte{}, f.raw...)
+
+	if err := cryptFile(stored, f.rawBlock, oldSeed, sectorSize, false); err != nil {
+		return nil, block, err
+	}
+
+	if err := cryptFile(stored, block, fileEncryptionSeed(f.name, block), sectorSize, true); err != nil {
+		return nil, block, err
+	}
+
+	return stored, block, nil
+}
+
+func compressSector(data []byte, options FileOptions) ([]byte, error) {
+	var (
+		compressed []byte
+		err        error
+	)
+
+	switch {
+	case options.Implode:
+		compressed = d2compression.PKCompress(data)
+	case options.Compression == CompressionZLib:
+		compressed, err = compressZLib(data)
+	case options.Compression == CompressionPKWare:
+		compressed = d2compression.PKCompress(data)
+	case options.Compression == CompressionBZip2:
+		compressed = d2compression.BZip2Compress(data)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !options.Implode {
+		compressed = append([]byte{byte(options.Compression)}, compressed...)
+	}
+
+	// sectors which do not get smaller are stored uncompressed, the reader tells them apart by size
+	if len(compressed) >= len(data) {
+		return append([]byte{}, data...), nil
+	}
+
+	return compressed, nil
+}
+
+// compressSectors compresses each sector of the data, preceded by the sector offset table
+func compressSectors(data []byte, options FileOptions, sectorSize uint32) ([]byte, error) {
+	sectorCount := (uint32(len(data)) + sectorSize - 1) / sectorSize
+	offsets := make([]uint32, sectorCount+1)
+	offsets[0] = uint32(len(offsets)) * dwordSize
+
+	var sectors bytes.Buffer
+
+	for idx := uint32(0); idx < sectorCount; idx++ {
+		end := (idx + 1) * sectorSize
+		if end > uint32(len(data)) {
+			end = uint32(len(data))
+		}
+
+		sector, err := compressSector(data[idx*sectorSize:end], options)
+		if err != nil {
+			return nil, err
+		}
+
+		sectors.Write(sector)
+
+		offsets[idx+1] = offsets[0] + uint32(sectors.Len())
+	}
+
+	stored := make([]byte, offsets[0], offsets[sectorCount])
+
+	for idx, offset := range offsets {
+		binary.LittleEndian.PutUint32(stored[idx*dwordSize:], offset)
+	}
+
+	return append(stored, sectors.Bytes()...), nil
+}
+
+// cryptFile encrypts or decrypts the stored data of a file in place, sector by sector
+func cryptFile(stored []byte, block BlockTableEntry, seed, sectorSize uint32, encrypting bool) error {
+	crypt := decryptBytes
+	if encrypting {
+		crypt = encryptBytes
+	}
+
+	if block.HasFlag(FileSingleUnit) {
+		crypt(stored, seed)
+		return nil
+	}
+
+	if !block.HasFlag(FileCompress) && !block.HasFlag(FileImplode) {
+		for idx, offset := uint32(0), uint32(0); offset < uint32(len(stored)); idx, offset = idx+1, offset+sectorSize {
+			end := offset + sectorSize
+			if end > uint32(len(stored)) {
+				end = uint32(len(stored))
+			}
+
+			crypt(stored[offset:end], seed+idx)
+		}
+
+		return nil
+	}
+
+	sectorCount := (block.UncompressedFileSize + sectorSize - 1) / sectorSize
+	tableSize := (sectorCount + 1) * dwordSize
+
+	if block.HasFlag(FileSectorCrc) {
+		tableSize += dwordSize
+	}
+
+	if tableSize > uint32(len(stored)) {
+		return errors.New("sector offset table is truncated")
+	}
+
+	table := stored[:tableSize]
+	offsets := make([]uint32, sectorCount+1)
+
+	// the offsets are read before encrypting, or after decrypting the table
+	readOffsets := func() {
+		for idx := range offsets {
+			offsets[idx] = binary.LittleEndian.Uint32(table[idx*dwordSize:])
+		}
+	}
+
+	if encrypting {
+		readOffsets()
+	}
+
+	crypt(table, seed-1)
+
+	if !encrypting {
+		readOffsets()
+	}
+
+	for idx := uint32(0); idx < sectorCount; idx++ {
+		start, end := offsets[idx], offsets[idx+1]
+		if start > end || end > uint32(len(stored)) {
+			return fmt.Errorf("invalid sector offset table for sector %d", idx)
+		}
+
+		crypt(stored[start:end], seed+idx)
+	}
+
+	return nil
+}
+
+func compressZLib(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	w := zlib.NewWriter(&buffer)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
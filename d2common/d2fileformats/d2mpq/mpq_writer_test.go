@@ -0,0 +1,253 @@
+package d2mpq
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testHashTableSize = 16
+	testSourceArchive = "../../d2loader/testdata/D.mpq"
+	testSourceFile    = "dir\\common.txt"
+)
+
+type writerTestFile struct {
+	name    string
+	data    []byte
+	options FileOptions
+}
+
+func writerTestFiles() []writerTestFile {
+	words := bytes.Repeat(wordsTestData(), 3)
+	random := make([]byte, 5000)
+
+	seed := uint32(7)
+	for idx := range random {
+		seed = seed*1664525 + 1013904223
+		random[idx] = byte(seed >> 24)
+	}
+
+	return []writerTestFile{
+		{"data\\global\\stored.txt", words, FileOptions{}},
+		{"data\\global\\zlib.txt", words, FileOptions{Compression: CompressionZLib}},
+		{"data\\global\\pkware.txt", words, FileOptions{Compression: CompressionPKWare}},
+		{"data\\global\\bzip2.txt", words, FileOptions{Compression: CompressionBZip2}},
+		{"data\\global\\implode.txt", words, FileOptions{Implode: true}},
+		{"data\\global\\single.txt", words, FileOptions{Compression: CompressionZLib, SingleUnit: true}},
+		{"data\\global\\encrypted.txt", words, FileOptions{Compression: CompressionZLib, Encrypted: true}},
+		{"data\\global\\fixkey.txt", words, FileOptions{Compression: CompressionBZip2, FixKey: true}},
+		{"data\\global\\fixkeysingle.txt", words, FileOptions{Implode: true, SingleUnit: true, FixKey: true}},
+		{"data\\global\\fixkeystored.txt", plainTestData(), FileOptions{FixKey: true}},
+		{"data\\global\\random.bin", random, FileOptions{Compression: CompressionZLib, Encrypted: true}},
+		{"data\\global\\empty.txt", []byte{}, FileOptions{Compression: CompressionZLib, FixKey: true}},
+		{"tiny.txt", []byte("abc"), FileOptions{Compression: CompressionPKWare, Encrypted: true}},
+	}
+}
+
+func writeTestArchive(t *testing.T, w *Writer, dir, name string) *MPQ {
+	fileName := filepath.Join(dir, name)
+
+	if err := w.Save(fileName); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := Load(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return archive.(*MPQ)
+}
+
+func checkArchiveFile(t *testing.T, archive *MPQ, name string, expected []byte) {
+	data, err := archive.ReadFile(name)
+	if err != nil {
+		t.Errorf("%s: unexpected error: %v", name, err)
+		return
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Errorf("%s: read %d bytes which do not match the %d bytes written", name, len(data), len(expected))
+	}
+}
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "d2mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	w, err := NewWriter(testHashTableSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := writerTestFiles()
+
+	for _, file := range files {
+		if err := w.AddFile(file.name, file.data, file.options); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive := writeTestArchive(t, w, dir, "test.mpq")
+	defer archive.Close()
+
+	for _, file := range files {
+		checkArchiveFile(t, archive, file.name, file.data)
+	}
+
+	list, err := archive.GetFileList()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != len(files) {
+		t.Errorf("listfile has %d entries, want %d", len(list), len(files))
+	}
+
+	if !archive.Contains(attributesFileName) {
+		t.Error("archive has no (attributes)")
+	}
+
+	stored, _ := archive.getFileBlockData("data\\global\\stored.txt")
+	compressed, _ := archive.getFileBlockData("data\\global\\zlib.txt")
+
+	if compressed.CompressedFileSize >= stored.CompressedFileSize {
+		t.Error("compressed file is not smaller than the stored file")
+	}
+}
+
+func TestWriterEdit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "d2mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	w, err := NewWriter(testHashTableSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := writerTestFiles()
+
+	for _, file := range files {
+		if err := w.AddFile(file.name, file.data, file.options); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Save(filepath.Join(dir, "original.mpq")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first file is deleted and the second shrinks, so the FixKey files all have to move
+	edit, err := OpenWriter(filepath.Join(dir, "original.mpq"), testHashTableSize*2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replaced := []byte("replaced")
+
+	if err := edit.DeleteFile(files[0].name); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := edit.ReplaceFile(files[1].name, replaced, FileOptions{FixKey: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := edit.AddFile("added.txt", replaced, FileOptions{Compression: CompressionZLib}); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := writeTestArchive(t, edit, dir, "edited.mpq")
+	defer archive.Close()
+
+	if archive.Contains(files[0].name) {
+		t.Errorf("%s was not deleted", files[0].name)
+	}
+
+	checkArchiveFile(t, archive, files[1].name, replaced)
+	checkArchiveFile(t, archive, "added.txt", replaced)
+
+	for _, file := range files[2:] {
+		checkArchiveFile(t, archive, file.name, file.data)
+	}
+}
+
+func TestWriterEditExisting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "d2mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	source, err := Load(testSourceArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := source.ReadFile(testSourceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source.Close()
+
+	w, err := OpenWriter(testSourceArchive, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddFile("added.txt", plainTestData(), FileOptions{Compression: CompressionBZip2}); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := writeTestArchive(t, w, dir, "patched.mpq")
+	defer archive.Close()
+
+	checkArchiveFile(t, archive, testSourceFile, expected)
+	checkArchiveFile(t, archive, "added.txt", plainTestData())
+}
+
+func TestWriterErrors(t *testing.T) {
+	if _, err := NewWriter(12); err == nil {
+		t.Error("expected an error for a hash table size which is not a power of two")
+	}
+
+	w, err := NewWriter(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		name string
+		err  error
+	}{
+		{"add", w.AddFile("a.txt", nil, FileOptions{})},
+		{"add twice", w.AddFile("A.TXT", nil, FileOptions{})},
+		{"add listfile", w.AddFile(listFileName, nil, FileOptions{})},
+		{"unsupported compression", w.AddFile("b.txt", nil, FileOptions{Compression: CompressionSparse})},
+		{"replace missing", w.ReplaceFile("b.txt", nil, FileOptions{})},
+		{"delete missing", w.DeleteFile("b.txt")},
+	}
+
+	for idx, row := range table {
+		if (idx == 0) != (row.err == nil) {
+			t.Errorf("%s: unexpected result %v", row.name, row.err)
+		}
+	}
+
+	if _, err := w.WriteTo(ioutil.Discard); err == nil {
+		t.Error("expected an error when the files do not fit in the hash table")
+	}
+}
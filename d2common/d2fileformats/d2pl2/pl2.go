@@ -11,7 +11,7 @@ type PL2 struct {
 	BasePalette PL2Palette
 
 	LightLevelVariations [32]PL2PaletteTransform
-	InvColorVariations   [16]PL2PaletteTransform
+	InvColorVariations   [16]PL2PaletteTransform // not known, see Generate
 	SelectedUintShift    PL2PaletteTransform
 	AlphaBlend           [3][256]PL2PaletteTransform
 	AdditiveBlend        [256]PL2PaletteTransform
@@ -20,7 +20,7 @@ type PL2 struct {
 	RedTones             PL2PaletteTransform
 	GreenTones           PL2PaletteTransform
 	BlueTones            PL2PaletteTransform
-	UnknownVariations    [14]PL2PaletteTransform
+	UnknownVariations    [14]PL2PaletteTransform // not known, see Generate
 	MaxComponentBlend    [256]PL2PaletteTransform
 	DarkendColorShift    PL2PaletteTransform
 
@@ -41,3 +41,8 @@ func Load(data []byte) (*PL2, error) {
 
 	return result, nil
 }
+
+// Marshal encodes the PL2 to the bytes of a PL2 file, the opposite of Load
+func (p *PL2) Marshal() ([]byte, error) {
+	return restruct.Pack(binary.LittleEndian, p)
+}
@@ -0,0 +1,269 @@
+package d2pl2
+
+import (
+	"math"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+)
+
+const (
+	numLightLevels = 32
+	maxComponent   = 255
+	fullTurn       = 360.0 // degrees of the color wheel
+	hueSector      = 60.0  // degrees of the color wheel between a primary and a secondary color
+	// selectedShift is the part of the way to white taken by the colors of a selected unit
+	selectedShift = 4
+)
+
+// alphaLevels are the opacities, out of 4, of the source color of the three alpha blends
+var alphaLevels = [3]int{1, 2, 3} //nolint:gochecknoglobals // lookup table
+
+// textColors are the colors of the text color codes, white, red, green, blue, gold, grey, black,
+// tan, orange, yellow, dark green, purple and dark green
+// nolint:gochecknoglobals // lookup table
+var textColors = [13]PL2Color24Bits{
+	{R: 255, G: 255, B: 255},
+	{R: 255, G: 77, B: 77},
+	{R: 0, G: 255, B: 0},
+	{R: 105, G: 105, B: 255},
+	{R: 199, G: 179, B: 119},
+	{R: 105, G: 105, B: 105},
+	{R: 0, G: 0, B: 0},
+	{R: 208, G: 194, B: 125},
+	{R: 255, G: 168, B: 0},
+	{R: 255, G: 255, B: 100},
+	{R: 0, G: 128, B: 0},
+	{R: 174, G: 0, B: 255},
+	{R: 0, G: 200, B: 0},
+}
+
+// Generate computes the PL2 of the given base palette: every transform maps a color of the
+// palette, or a pair of colors for the blends, to the palette color closest to the result. The
+// index zero is transparent and stays zero in every transform. The hue variations turn the hue of
+// the colors by even steps around the color wheel.
+//
+// The inventory and unknown variations are not supported: how the game computes them is not
+// known, they are left as the identity.
+func Generate(base d2interface.Palette) *PL2 {
+	result := &PL2{TextColors: textColors}
+	colors := base.GetColors()
+
+	for idx := 0; idx < base.NumColors() && idx < len(colors); idx++ {
+		if colors[idx] != nil {
+			result.BasePalette.Colors[idx] = PL2Color{R: colors[idx].R(), G: colors[idx].G(), B: colors[idx].B()}
+		}
+	}
+
+	m := &colorMatcher{colors: &result.BasePalette.Colors, cache: make(map[PL2Color24Bits]uint8)}
+
+	for level := range result.LightLevelVariations {
+		result.LightLevelVariations[level] = m.transform(func(c PL2Color) PL2Color24Bits {
+			return scale(c, level, numLightLevels-1)
+		})
+	}
+
+	for idx := range result.InvColorVariations {
+		result.InvColorVariations[idx] = identity()
+	}
+
+	result.SelectedUintShift = m.transform(func(c PL2Color) PL2Color24Bits {
+		return PL2Color24Bits{
+			R: c.R + (maxComponent-c.R)/selectedShift,
+			G: c.G + (maxComponent-c.G)/selectedShift,
+			B: c.B + (maxComponent-c.B)/selectedShift,
+		}
+	})
+
+	for level, alpha := range alphaLevels {
+		result.AlphaBlend[level] = m.blends(func(src, dst uint8) uint8 {
+			return uint8((int(src)*alpha + int(dst)*(4-alpha)) / 4) //nolint:gomnd // out of 4
+		})
+	}
+
+	result.AdditiveBlend = m.blends(func(src, dst uint8) uint8 {
+		if sum := int(src) + int(dst); sum < maxComponent {
+			return uint8(sum)
+		}
+
+		return maxComponent
+	})
+
+	result.MultiplicativeBlend = m.blends(func(src, dst uint8) uint8 {
+		return uint8(int(src) * int(dst) / maxComponent)
+	})
+
+	for idx := range result.HueVariations {
+		degrees := fullTurn * float64(idx) / float64(len(result.HueVariations))
+
+		result.HueVariations[idx] = m.transform(func(c PL2Color) PL2Color24Bits {
+			return turnHue(c, degrees)
+		})
+	}
+
+	result.RedTones = m.transform(func(c PL2Color) PL2Color24Bits { return PL2Color24Bits{R: luminance(c)} })
+	result.GreenTones = m.transform(func(c PL2Color) PL2Color24Bits { return PL2Color24Bits{G: luminance(c)} })
+	result.BlueTones = m.transform(func(c PL2Color) PL2Color24Bits { return PL2Color24Bits{B: luminance(c)} })
+
+	for idx := range result.UnknownVariations {
+		result.UnknownVariations[idx] = identity()
+	}
+
+	result.MaxComponentBlend = m.blends(func(src, dst uint8) uint8 {
+		if src > dst {
+			return src
+		}
+
+		return dst
+	})
+
+	result.DarkendColorShift = m.transform(func(c PL2Color) PL2Color24Bits {
+		return scale(c, 1, 2) //nolint:gomnd // half as bright
+	})
+
+	for idx, text := range result.TextColors {
+		result.TextColorShifts[idx] = m.transform(func(c PL2Color) PL2Color24Bits {
+			lum := int(luminance(c))
+
+			return PL2Color24Bits{
+				R: uint8(lum * int(text.R) / maxComponent),
+				G: uint8(lum * int(text.G) / maxComponent),
+				B: uint8(lum * int(text.B) / maxComponent),
+			}
+		})
+	}
+
+	return result
+}
+
+// colorMatcher finds the closest colors of a palette, index zero left out as it is transparent
+type colorMatcher struct {
+	colors *[256]PL2Color
+	cache  map[PL2Color24Bits]uint8
+}
+
+func (m *colorMatcher) closest(c PL2Color24Bits) uint8 {
+	if idx, found := m.cache[c]; found {
+		return idx
+	}
+
+	best, bestDistance := 1, -1
+
+	for idx := 1; idx < len(m.colors); idx++ {
+		dr := int(m.colors[idx].R) - int(c.R)
+		dg := int(m.colors[idx].G) - int(c.G)
+		db := int(m.colors[idx].B) - int(c.B)
+
+		if distance := dr*dr + dg*dg + db*db; bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = idx, distance
+			if distance == 0 {
+				break
+			}
+		}
+	}
+
+	m.cache[c] = uint8(best)
+
+	return uint8(best)
+}
+
+// transform maps every color of the palette to the closest color to its result of fn
+func (m *colorMatcher) transform(fn func(c PL2Color) PL2Color24Bits) PL2PaletteTransform {
+	result := PL2PaletteTransform{}
+
+	for idx := 1; idx < len(m.colors); idx++ {
+		result.Indices[idx] = m.closest(fn(m.colors[idx]))
+	}
+
+	return result
+}
+
+// blends returns, for every source color, the transform mapping a destination color to the
+// closest color to the blend of both, fn blending each component
+func (m *colorMatcher) blends(fn func(src, dst uint8) uint8) [256]PL2PaletteTransform {
+	result := [256]PL2PaletteTransform{}
+
+	for srcIdx := 1; srcIdx < len(m.colors); srcIdx++ {
+		src := m.colors[srcIdx]
+
+		result[srcIdx] = m.transform(func(dst PL2Color) PL2Color24Bits {
+			return PL2Color24Bits{R: fn(src.R, dst.R), G: fn(src.G, dst.G), B: fn(src.B, dst.B)}
+		})
+	}
+
+	return result
+}
+
+func identity() PL2PaletteTransform {
+	result := PL2PaletteTransform{}
+
+	for idx := range result.Indices {
+		result.Indices[idx] = uint8(idx)
+	}
+
+	return result
+}
+
+// scale returns the color with its components multiplied by num/den
+func scale(c PL2Color, num, den int) PL2Color24Bits {
+	return PL2Color24Bits{
+		R: uint8(int(c.R) * num / den),
+		G: uint8(int(c.G) * num / den),
+		B: uint8(int(c.B) * num / den),
+	}
+}
+
+// luminance returns the perceived brightness of the color
+// nolint:gomnd // Rec. 601 luma weights
+func luminance(c PL2Color) uint8 {
+	return uint8((299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000)
+}
+
+// turnHue returns the color with its hue turned by the given degrees, its saturation and value
+// kept. The greys have no hue and are returned as they are.
+// nolint:gomnd // sectors of the color wheel
+func turnHue(c PL2Color, degrees float64) PL2Color24Bits {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	chroma := max - min
+
+	if chroma == 0 {
+		return PL2Color24Bits{R: c.R, G: c.G, B: c.B}
+	}
+
+	var hue float64
+
+	switch max {
+	case r:
+		hue = (g - b) / chroma
+	case g:
+		hue = (b-r)/chroma + 2
+	default:
+		hue = (r-g)/chroma + 4
+	}
+
+	hue = math.Mod(hue*hueSector+degrees+fullTurn, fullTurn) / hueSector
+	x := chroma * (1 - math.Abs(math.Mod(hue, 2)-1))
+
+	var rgb [3]float64
+
+	switch int(hue) {
+	case 0:
+		rgb = [3]float64{chroma, x, 0}
+	case 1:
+		rgb = [3]float64{x, chroma, 0}
+	case 2:
+		rgb = [3]float64{0, chroma, x}
+	case 3:
+		rgb = [3]float64{0, x, chroma}
+	case 4:
+		rgb = [3]float64{x, 0, chroma}
+	default:
+		rgb = [3]float64{chroma, 0, x}
+	}
+
+	return PL2Color24Bits{
+		R: uint8(math.Round(rgb[0] + min)),
+		G: uint8(math.Round(rgb[1] + min)),
+		B: uint8(math.Round(rgb[2] + min)),
+	}
+}
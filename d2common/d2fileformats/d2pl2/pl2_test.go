@@ -0,0 +1,127 @@
+package d2pl2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2dat"
+)
+
+// pl2FileSize is the size of the PL2 files of the game
+const pl2FileSize = 443175
+
+// testPL2 generates the PL2 of a palette of distinct colors, black at index 1
+func testPL2(t *testing.T) *PL2 {
+	data := make([]byte, 256*3)
+
+	for idx := 2; idx < 256; idx++ {
+		data[idx*3], data[idx*3+1], data[idx*3+2] = byte(idx*7), byte(idx*13), byte(idx)
+	}
+
+	palette, err := d2dat.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Generate(palette)
+}
+
+func TestGeneratedTransformsMapToTheClosestColors(t *testing.T) {
+	pl2 := testPL2(t)
+
+	if pl2.BasePalette.Colors[2] != (PL2Color{R: 2, G: 26, B: 14}) {
+		t.Errorf("the base palette color 2 is %+v", pl2.BasePalette.Colors[2])
+	}
+
+	for idx := 1; idx < 256; idx++ {
+		if got := pl2.LightLevelVariations[31].Indices[idx]; got != uint8(idx) {
+			t.Errorf("the full light level maps %d to %d", idx, got)
+		}
+
+		if got := pl2.LightLevelVariations[0].Indices[idx]; got != 1 {
+			t.Errorf("the darkest light level maps %d to %d", idx, got)
+		}
+
+		if got := pl2.AlphaBlend[1][idx].Indices[idx]; got != uint8(idx) {
+			t.Errorf("blending %d with itself gives %d", idx, got)
+		}
+
+		if got := pl2.MaxComponentBlend[idx].Indices[1]; got != uint8(idx) {
+			t.Errorf("the max component blend of %d and black gives %d", idx, got)
+		}
+
+		if got := pl2.AdditiveBlend[1].Indices[idx]; got != uint8(idx) {
+			t.Errorf("adding black to %d gives %d", idx, got)
+		}
+
+		if got := pl2.MultiplicativeBlend[idx].Indices[1]; got != 1 {
+			t.Errorf("multiplying %d by black gives %d", idx, got)
+		}
+	}
+
+	for idx := range pl2.AlphaBlend {
+		if pl2.AlphaBlend[idx][0].Indices[5] != 0 || pl2.LightLevelVariations[idx].Indices[0] != 0 {
+			t.Error("the transparent index is transformed")
+		}
+	}
+
+	if pl2.InvColorVariations[4].Indices[200] != 200 || pl2.UnknownVariations[4].Indices[200] != 200 {
+		t.Error("the unsupported variations are not the identity")
+	}
+}
+
+func TestGeneratedHueVariationsTurnTheHue(t *testing.T) {
+	const red, green, blue, grey = 2, 3, 4, 5
+
+	// the colors of a palette file are in blue, green, red order
+	data := make([]byte, 256*3)
+	copy(data[red*3:], []byte{0, 0, 255, 0, 255, 0, 255, 0, 0, 128, 128, 128})
+
+	palette, err := d2dat.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pl2 := Generate(palette)
+
+	// the 111 variations turn the hue by 360 / 111 degrees each, a third of a turn is 37 of them
+	tests := []struct {
+		variation int
+		from, to  uint8
+	}{
+		{0, red, red},
+		{37, red, green},
+		{37, green, blue},
+		{74, red, blue},
+		{74, blue, green},
+		{37, grey, grey},
+	}
+
+	for _, test := range tests {
+		if got := pl2.HueVariations[test.variation].Indices[test.from]; got != test.to {
+			t.Errorf("hue variation %d maps %d to %d, want %d", test.variation, test.from, got, test.to)
+		}
+	}
+}
+
+func TestGeneratedPL2FilesAreWrittenAndLoadedBack(t *testing.T) {
+	want := testPL2(t)
+
+	data, err := want.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != pl2FileSize {
+		t.Fatalf("%d bytes written, want %d", len(data), pl2FileSize)
+	}
+
+	loaded, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded, want) {
+		t.Error("the loaded PL2 differs from the written one")
+	}
+}
@@ -0,0 +1,98 @@
+package d2s
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+)
+
+// Attribute is the ItemStatCost.txt index of a character attribute
+type Attribute int
+
+// Character attributes, life, mana and stamina are saved with 8 fractional bits
+const (
+	AttributeStrength Attribute = iota
+	AttributeEnergy
+	AttributeDexterity
+	AttributeVitality
+	AttributeStatPoints
+	AttributeSkillPoints
+	AttributeLife
+	AttributeMaxLife
+	AttributeMana
+	AttributeMaxMana
+	AttributeStamina
+	AttributeMaxStamina
+	AttributeLevel
+	AttributeExperience
+	AttributeGold
+	AttributeStashGold
+)
+
+// FractionBits is the number of fractional bits of the life, mana and stamina attributes
+const FractionBits = 8
+
+const (
+	statIDBits = 9
+	statEnd    = 0x1FF
+	charBits   = 7
+)
+
+func readAttributes(r *bitReader, records Records) (map[Attribute]int, error) {
+	if err := r.marker(attributeMarker); err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[Attribute]int)
+
+	for {
+		id := int(r.bits(statIDBits))
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		if id == statEnd {
+			break
+		}
+
+		info, ok := records.StatInfo(id)
+		if !ok || info.CharacterBits == 0 {
+			return nil, fmt.Errorf("unknown character attribute %d", id)
+		}
+
+		attributes[Attribute(id)] = int(r.bits(info.CharacterBits))
+	}
+
+	r.align()
+
+	return attributes, r.err
+}
+
+// marshalAttributes writes the attributes in the order of their IDs, like the game does
+func marshalAttributes(attributes map[Attribute]int, records Records) ([]byte, error) {
+	ids := make([]int, 0, len(attributes))
+
+	for id := range attributes {
+		ids = append(ids, int(id))
+	}
+
+	sort.Ints(ids)
+
+	sw := d2datautils.CreateStreamWriter()
+	sw.PushBytes([]byte(attributeMarker)...)
+
+	for _, id := range ids {
+		info, ok := records.StatInfo(id)
+		if !ok || info.CharacterBits == 0 {
+			return nil, fmt.Errorf("unknown character attribute %d", id)
+		}
+
+		sw.PushBits(uint32(id), statIDBits)
+		sw.PushBits(uint32(attributes[Attribute(id)]), info.CharacterBits)
+	}
+
+	sw.PushBits(statEnd, statIDBits)
+
+	return sw.GetBytes(), nil
+}
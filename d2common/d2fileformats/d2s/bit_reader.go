@@ -0,0 +1,90 @@
+package d2s
+
+import (
+	"errors"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+)
+
+const byteBits = 8
+
+var errTruncated = errors.New("save data is truncated") //nolint:gochecknoglobals // sentinel error
+
+// bitReader wraps a BitMuncher with bounds checks, the first read past the end of the data
+// is recorded in err and every read after it returns zero
+type bitReader struct {
+	*d2datautils.BitMuncher
+	size int
+	err  error
+}
+
+func newBitReader(data []byte, offset int) *bitReader {
+	return &bitReader{
+		BitMuncher: d2datautils.CreateBitMuncher(data, offset*byteBits),
+		size:       len(data) * byteBits,
+	}
+}
+
+func (r *bitReader) bits(count int) uint32 {
+	if r.err != nil {
+		return 0
+	}
+
+	if r.Offset()+count > r.size {
+		r.err = errTruncated
+		return 0
+	}
+
+	return r.GetBits(count)
+}
+
+func (r *bitReader) bit() bool {
+	return r.bits(1) == 1
+}
+
+func (r *bitReader) align() {
+	r.SetOffset((r.Offset() + byteBits - 1) / byteBits * byteBits)
+}
+
+func (r *bitReader) bytes(count int) []byte {
+	data := make([]byte, count)
+
+	for idx := range data {
+		data[idx] = byte(r.bits(byteBits))
+	}
+
+	return data
+}
+
+// marker reads a byte aligned section marker
+func (r *bitReader) marker(marker string) error {
+	r.align()
+
+	if string(r.bytes(len(marker))) != marker && r.err == nil {
+		return errors.New("invalid section marker, expected " + marker)
+	}
+
+	return r.err
+}
+
+// string reads 7 bit characters up to a terminating zero
+func (r *bitReader) string() string {
+	var name []byte
+
+	for {
+		char := byte(r.bits(charBits))
+		if char == 0 || r.err != nil {
+			return string(name)
+		}
+
+		name = append(name, char)
+	}
+}
+
+func pushString(sw *d2datautils.StreamWriter, value string) {
+	for idx := 0; idx < len(value); idx++ {
+		sw.PushBits(uint32(value[idx]), charBits)
+	}
+
+	sw.PushBits(0, charBits)
+}
@@ -0,0 +1,301 @@
+package d2s
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+const (
+	fileSignature = 0xAA55AA55
+
+	// Version96 is the save version of the 1.10 to 1.14 game versions, the only one supported
+	Version96 = 96
+)
+
+// NumSkills is the number of skills of each class
+const NumSkills = 30
+
+const (
+	fileSizeOffset   = 0x08
+	checksumOffset   = 0x0C
+	attributesOffset = 0x2FD
+	checksumSize     = 4
+	corpseDataSize   = 12
+)
+
+// section markers, the quest, waypoint and NPC markers include the section version and size,
+// which are fixed for version 96
+const (
+	questMarker     = "Woo!\x06\x00\x00\x00\x2a\x01"
+	waypointMarker  = "WS\x01\x00\x00\x00\x50\x00"
+	npcMarker       = "\x01\x77\x34\x00"
+	attributeMarker = "gf"
+	skillMarker     = "if"
+	itemMarker      = "JM"
+	mercenaryMarker = "jf"
+	golemMarker     = "kf"
+)
+
+// D2S is a character save
+type D2S struct {
+	Header
+	Quests     [NumDifficulties]Quests
+	Waypoints  [NumDifficulties]Waypoints
+	NPCs       NPCs
+	Attributes map[Attribute]int
+	Skills     [NumSkills]byte
+
+	Items          []*Item
+	Corpses        []*Corpse
+	MercenaryItems []*Item // only used by expansion characters
+	IronGolem      *Item   // the item an expansion necromancer turned into an iron golem
+}
+
+// Corpse is a corpse of the character, with the items which were left on it
+type Corpse struct {
+	Unknown uint32
+	X       uint32
+	Y       uint32
+	Items   []*Item
+}
+
+// Load reads a character save
+func Load(data []byte, records Records) (*D2S, error) {
+	if len(data) < attributesOffset {
+		return nil, errTruncated
+	}
+
+	save := &D2S{}
+	r := bytes.NewReader(data)
+
+	if err := binary.Read(r, binary.LittleEndian, &save.Header); err != nil {
+		return nil, err
+	}
+
+	if save.Magic != fileSignature {
+		return nil, errors.New("invalid save file signature")
+	}
+
+	if save.Version != Version96 {
+		return nil, fmt.Errorf("unsupported save version %d", save.Version)
+	}
+
+	if save.Checksum != Checksum(data) {
+		return nil, errors.New("save file checksum mismatch")
+	}
+
+	sections := []struct {
+		marker string
+		data   interface{}
+	}{
+		{questMarker, &save.Quests},
+		{waypointMarker, &save.Waypoints},
+		{npcMarker, &save.NPCs},
+	}
+
+	for _, section := range sections {
+		marker := make([]byte, len(section.marker))
+		if _, err := r.Read(marker); err != nil || string(marker) != section.marker {
+			return nil, errors.New("invalid save section")
+		}
+
+		if err := binary.Read(r, binary.LittleEndian, section.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := save.readBitSections(newBitReader(data, attributesOffset), records); err != nil {
+		return nil, err
+	}
+
+	return save, nil
+}
+
+func (d *D2S) readBitSections(r *bitReader, records Records) error {
+	var err error
+
+	if d.Attributes, err = readAttributes(r, records); err != nil {
+		return err
+	}
+
+	if err = r.marker(skillMarker); err != nil {
+		return err
+	}
+
+	copy(d.Skills[:], r.bytes(NumSkills))
+
+	if d.Items, err = readItemList(r, records); err != nil {
+		return err
+	}
+
+	if d.Corpses, err = readCorpses(r, records); err != nil {
+		return err
+	}
+
+	if !d.Expansion() {
+		return nil
+	}
+
+	if err = r.marker(mercenaryMarker); err != nil {
+		return err
+	}
+
+	if d.Mercenary.ID != 0 {
+		if d.MercenaryItems, err = readItemList(r, records); err != nil {
+			return err
+		}
+	}
+
+	if err = r.marker(golemMarker); err != nil {
+		return err
+	}
+
+	if r.bits(byteBits) != 0 {
+		if d.IronGolem, err = readItem(r, records); err != nil {
+			return err
+		}
+	}
+
+	return r.err
+}
+
+func readCorpses(r *bitReader, records Records) ([]*Corpse, error) {
+	if err := r.marker(itemMarker); err != nil {
+		return nil, err
+	}
+
+	corpses := make([]*Corpse, r.bits(16)) //nolint:gomnd // corpse count
+
+	for idx := range corpses {
+		data := r.bytes(corpseDataSize)
+		corpse := &Corpse{
+			Unknown: binary.LittleEndian.Uint32(data),
+			X:       binary.LittleEndian.Uint32(data[4:]),
+			Y:       binary.LittleEndian.Uint32(data[8:]),
+		}
+
+		var err error
+
+		if corpse.Items, err = readItemList(r, records); err != nil {
+			return nil, err
+		}
+
+		corpses[idx] = corpse
+	}
+
+	return corpses, r.err
+}
+
+// Marshal writes the character save, with the file size and checksum fields updated
+func (d *D2S) Marshal(records Records) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	header := d.Header
+	header.Magic = fileSignature
+	header.Version = Version96
+
+	if err := binary.Write(buf, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+
+	sections := []struct {
+		marker string
+		data   interface{}
+	}{
+		{questMarker, &d.Quests},
+		{waypointMarker, &d.Waypoints},
+		{npcMarker, &d.NPCs},
+	}
+
+	for _, section := range sections {
+		buf.WriteString(section.marker)
+
+		if err := binary.Write(buf, binary.LittleEndian, section.data); err != nil {
+			return nil, err
+		}
+	}
+
+	attributes, err := marshalAttributes(d.Attributes, records)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.Write(attributes)
+	buf.WriteString(skillMarker)
+	buf.Write(d.Skills[:])
+
+	if err := d.writeItemSections(buf, records); err != nil {
+		return nil, err
+	}
+
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint32(data[fileSizeOffset:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(data[checksumOffset:], Checksum(data))
+
+	return data, nil
+}
+
+func (d *D2S) writeItemSections(buf *bytes.Buffer, records Records) error {
+	if err := writeItemList(buf, d.Items, records); err != nil {
+		return err
+	}
+
+	buf.WriteString(itemMarker)
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(d.Corpses)))
+
+	for _, corpse := range d.Corpses {
+		_ = binary.Write(buf, binary.LittleEndian, [3]uint32{corpse.Unknown, corpse.X, corpse.Y})
+
+		if err := writeItemList(buf, corpse.Items, records); err != nil {
+			return err
+		}
+	}
+
+	if !d.Expansion() {
+		return nil
+	}
+
+	buf.WriteString(mercenaryMarker)
+
+	if d.Mercenary.ID != 0 {
+		if err := writeItemList(buf, d.MercenaryItems, records); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteString(golemMarker)
+
+	if d.IronGolem == nil {
+		buf.WriteByte(0)
+		return nil
+	}
+
+	buf.WriteByte(1)
+
+	data, err := d.IronGolem.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(data)
+
+	return nil
+}
+
+// Checksum returns the checksum of a save, the checksum field itself is counted as zero
+func Checksum(data []byte) uint32 {
+	sum := uint32(0)
+
+	for idx, value := range data {
+		if idx >= checksumOffset && idx < checksumOffset+checksumSize {
+			value = 0
+		}
+
+		sum = bits.RotateLeft32(sum, 1) + uint32(value)
+	}
+
+	return sum
+}
@@ -0,0 +1,187 @@
+package d2s
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+)
+
+type testRecords struct{}
+
+func (testRecords) StatInfo(id int) (StatInfo, bool) {
+	stats := map[int]StatInfo{
+		int(AttributeStrength):   {CharacterBits: 10},
+		int(AttributeLife):       {CharacterBits: 21},
+		int(AttributeLevel):      {CharacterBits: 7},
+		int(AttributeExperience): {CharacterBits: 32},
+		int(AttributeGold):       {CharacterBits: 25},
+		7:                        {Bits: 9, Add: 32}, // maxhp
+		17:                       {Bits: 9},          // item_maxdamage_percent
+		18:                       {Bits: 9},          // item_mindamage_percent
+		54:                       {Bits: 8},          // coldmindam
+		55:                       {Bits: 9},          // coldmaxdam
+		56:                       {Bits: 8},          // coldlength
+		93:                       {Bits: 7, Add: 20}, // item_fasterattackrate
+		107:                      {Bits: 3, ParamBits: 9},
+	}
+
+	info, ok := stats[id]
+
+	return info, ok
+}
+
+func (testRecords) ItemInfo(code string) (ItemInfo, bool) {
+	items := map[string]ItemInfo{
+		"cap": {Kind: ItemKindArmor},
+		"jav": {Kind: ItemKindWeapon, Stackable: true},
+		"tbk": {Stackable: true},
+		"gsv": {},
+		"rin": {},
+		"lsd": {Kind: ItemKindWeapon},
+		"r08": {},
+		"r09": {},
+	}
+
+	info, ok := items[code]
+
+	return info, ok
+}
+
+func testItems() []*Item {
+	gem := &Item{Identified: true, Simple: true, Version: ItemVersionExpansion, Location: LocationSocketed, Code: "gsv"}
+	helm := &Item{
+		Identified: true, Socketed: true, Version: ItemVersionExpansion, Location: LocationEquipped,
+		BodyLocation: BodyHead, Code: "cap", ID: 0x12345678, Level: 12, Quality: d2enum.Superior, QualityID: 2,
+		Defense: 5, MaxDurability: 12, Durability: 11, TotalSockets: 2, Sockets: []*Item{gem},
+		Properties: []Property{{ID: 17, Values: []int{15, 15}}, {ID: 7, Values: []int{-10}}},
+	}
+
+	return []*Item{
+		helm,
+		{
+			Identified: true, Version: ItemVersionExpansion, Page: PageInventory, X: 3, Y: 1, Code: "jav",
+			ID: 7, Level: 30, Quality: d2enum.Rare, RareNames: [2]int{12, 34}, Prefixes: [3]int{5, 0, 9},
+			Suffixes: [3]int{0, 400, 0}, MaxDurability: 0, Quantity: 60, Personalized: true, PersonalizedName: "Someone",
+			Properties: []Property{{ID: 54, Values: []int{3, 7, 50}}, {ID: 107, Param: 36, Values: []int{2}}},
+		},
+		{
+			Version: ItemVersionExpansion, Page: PageStash, Code: "rin", Level: 80, Quality: d2enum.Set, SetID: 3,
+			HasClassAffix: true, ClassAffix: 100, RealmData: []uint32{1, 2, 3}, Properties: []Property{},
+			SetProperties: [NumSetPropertyLists][]Property{1: {{ID: 7, Values: []int{5}}}},
+		},
+		{Simple: true, Version: ItemVersionExpansion, Ear: &Ear{Class: ClassDruid, Level: 77, Name: "Victim"}},
+		{
+			Version: ItemVersionExpansion, Code: "tbk", ID: 1, Level: 1, Quality: d2enum.Normal, TomeData: 3,
+			Quantity: 20, Properties: []Property{},
+		},
+	}
+}
+
+func testSave() *D2S {
+	save := &D2S{
+		Header: Header{
+			Status:       StatusExpansion | StatusHardcore,
+			Class:        ClassNecromancer,
+			Level:        31,
+			LeftSkill:    66,
+			RightSkill:   0,
+			ActiveWeapon: 1,
+		},
+		Attributes: map[Attribute]int{
+			AttributeStrength:   45,
+			AttributeLife:       250 << FractionBits,
+			AttributeLevel:      31,
+			AttributeExperience: 3000000,
+			AttributeGold:       12345,
+		},
+		Items:          testItems(),
+		Corpses:        []*Corpse{{X: 100, Y: 200, Items: testItems()[1:2]}},
+		MercenaryItems: testItems()[:1],
+		IronGolem:      testItems()[0],
+	}
+
+	save.SetCharacterName("Tester")
+	save.SetActiveDifficulty(d2enum.DifficultyNightmare, 3)
+	save.Mercenary.ID = 42
+	save.Quests[0][1] = questCompleted
+	save.Waypoints[1].SetActive(9, true)
+	save.NPCs.Introductions[2][0] = 0xff
+	save.Skills[3] = 20
+
+	return save
+}
+
+func TestRoundTrip(t *testing.T) {
+	records := testRecords{}
+	save := testSave()
+
+	data, err := save.Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(data, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if int(loaded.FileSize) != len(data) || loaded.Checksum != Checksum(data) {
+		t.Errorf("file size %d and checksum %x were not updated", loaded.FileSize, loaded.Checksum)
+	}
+
+	save.Magic, save.Version, save.FileSize, save.Checksum = loaded.Magic, loaded.Version, loaded.FileSize, loaded.Checksum
+
+	if !reflect.DeepEqual(loaded, save) {
+		t.Errorf("loaded save does not match the saved one:\n%+v\n%+v", loaded, save)
+	}
+
+	if difficulty, act := loaded.ActiveDifficulty(); difficulty != d2enum.DifficultyNightmare || act != 3 {
+		t.Errorf("active difficulty is %d act %d, want nightmare act 3", difficulty, act)
+	}
+
+	if !loaded.Quests[0].Completed(1) || !loaded.Waypoints[1].Active(9) || loaded.Waypoints[1].Active(8) {
+		t.Error("quest and waypoint flags were not kept")
+	}
+
+	if again, err := loaded.Marshal(records); err != nil || !bytes.Equal(again, data) {
+		t.Errorf("saving a loaded save changed the data (%v)", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	records := testRecords{}
+
+	data, err := testSave().Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badChecksum := append([]byte{}, data...)
+	badChecksum[len(badChecksum)-1] ^= 1
+
+	// the checksum of the truncated save is valid, so the item list is read past the end
+	truncated := append([]byte{}, data[:len(data)-8]...)
+	setChecksum(truncated)
+
+	table := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated", truncated},
+		{"checksum", badChecksum},
+	}
+
+	for _, row := range table {
+		if _, err := Load(row.data, records); err == nil {
+			t.Errorf("%s: expected an error", row.name)
+		}
+	}
+}
+
+func setChecksum(data []byte) {
+	binary.LittleEndian.PutUint32(data[checksumOffset:], Checksum(data))
+}
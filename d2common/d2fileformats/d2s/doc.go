@@ -0,0 +1,2 @@
+// Package d2s provides a reader and writer for the .d2s character save files of the original game.
+package d2s
@@ -0,0 +1,207 @@
+package d2s
+
+import (
+	"bytes"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+)
+
+// NumDifficulties is the number of difficulties tracked by the quest, waypoint and NPC sections
+const NumDifficulties = 3
+
+// NumWaypoints is the number of waypoint flags of a difficulty
+const NumWaypoints = waypointBytes * byteBits
+
+const (
+	nameLength        = 16
+	numHotkeys        = 16
+	appearanceSize    = 32
+	headerUnknownSize = 144
+	questWords        = 48
+	waypointBytes     = 5
+	waypointReserved  = 17
+	npcFlagBytes      = 8
+)
+
+const (
+	difficultyActive = 0x80
+	difficultyAct    = 0x07
+	questCompleted   = 0x01
+)
+
+// Status holds the character status flags
+type Status byte
+
+// Status flags
+const (
+	StatusHardcore  Status = 0x04
+	StatusDied      Status = 0x08
+	StatusExpansion Status = 0x20
+	StatusLadder    Status = 0x40
+)
+
+// Class is the character class as it is saved
+type Class byte
+
+// Character classes
+const (
+	ClassAmazon Class = iota
+	ClassSorceress
+	ClassNecromancer
+	ClassPaladin
+	ClassBarbarian
+	ClassDruid
+	ClassAssassin
+)
+
+//nolint:gochecknoglobals // lookup table
+var classHeroes = [...]d2enum.Hero{
+	ClassAmazon:      d2enum.HeroAmazon,
+	ClassSorceress:   d2enum.HeroSorceress,
+	ClassNecromancer: d2enum.HeroNecromancer,
+	ClassPaladin:     d2enum.HeroPaladin,
+	ClassBarbarian:   d2enum.HeroBarbarian,
+	ClassDruid:       d2enum.HeroDruid,
+	ClassAssassin:    d2enum.HeroAssassin,
+}
+
+// Hero returns the hero type of the class
+func (c Class) Hero() d2enum.Hero {
+	if int(c) >= len(classHeroes) {
+		return d2enum.HeroNone
+	}
+
+	return classHeroes[c]
+}
+
+// ClassOf returns the saved class of a hero type
+func ClassOf(hero d2enum.Hero) (Class, bool) {
+	for class, classHero := range classHeroes {
+		if classHero == hero {
+			return Class(class), true
+		}
+	}
+
+	return 0, false
+}
+
+// Mercenary holds the hired mercenary, its ID is zero when there is none
+type Mercenary struct {
+	Dead       uint16
+	ID         uint32
+	NameID     uint16
+	Type       uint16
+	Experience uint32
+}
+
+// Header is the fixed size character header at the start of a save
+type Header struct {
+	Magic          uint32
+	Version        uint32
+	FileSize       uint32
+	Checksum       uint32
+	ActiveWeapon   uint32
+	Name           [nameLength]byte
+	Status         Status
+	Progression    byte
+	Unknown26      [2]byte
+	Class          Class
+	Unknown29      [2]byte
+	Level          byte
+	Unknown2C      [4]byte
+	LastPlayed     uint32
+	Unknown34      [4]byte
+	Hotkeys        [numHotkeys]uint32
+	LeftSkill      uint32
+	RightSkill     uint32
+	LeftSwapSkill  uint32
+	RightSwapSkill uint32
+	Appearance     [appearanceSize]byte
+	Difficulty     [NumDifficulties]byte
+	MapSeed        uint32
+	UnknownAF      [2]byte
+	Mercenary      Mercenary
+	UnknownBF      [headerUnknownSize]byte
+}
+
+// CharacterName returns the name of the character
+func (h *Header) CharacterName() string {
+	if end := bytes.IndexByte(h.Name[:], 0); end >= 0 {
+		return string(h.Name[:end])
+	}
+
+	return string(h.Name[:])
+}
+
+// SetCharacterName sets the name of the character, the name is cut to 15 characters
+func (h *Header) SetCharacterName(name string) {
+	h.Name = [nameLength]byte{}
+	copy(h.Name[:nameLength-1], name)
+}
+
+// Expansion returns true for Lord of Destruction characters
+func (h *Header) Expansion() bool {
+	return h.Status&StatusExpansion != 0
+}
+
+// ActiveDifficulty returns the difficulty the character last played, and the act (starting
+// from 1) the character is in there
+func (h *Header) ActiveDifficulty() (difficulty d2enum.DifficultyType, act int) {
+	for idx, value := range h.Difficulty {
+		if value&difficultyActive != 0 {
+			return d2enum.DifficultyType(idx), int(value&difficultyAct) + 1
+		}
+	}
+
+	return d2enum.DifficultyNormal, 1
+}
+
+// SetActiveDifficulty sets the difficulty and act (starting from 1) the character is in
+func (h *Header) SetActiveDifficulty(difficulty d2enum.DifficultyType, act int) {
+	for idx := range h.Difficulty {
+		h.Difficulty[idx] &^= difficultyActive
+	}
+
+	if int(difficulty) < 0 || int(difficulty) >= NumDifficulties || act < 1 {
+		return
+	}
+
+	h.Difficulty[difficulty] = difficultyActive | byte(act-1)&difficultyAct
+}
+
+// Quests holds the quest flags of a difficulty, one word per quest. Bit 0 of a word is set
+// once the quest is completed.
+type Quests [questWords]uint16
+
+// Completed returns true when the quest with the given word index is completed
+func (q *Quests) Completed(idx int) bool {
+	return q[idx]&questCompleted != 0
+}
+
+// Waypoints holds the activated waypoints of a difficulty
+type Waypoints struct {
+	Unknown  [2]byte
+	Flags    [waypointBytes]byte
+	Reserved [waypointReserved]byte
+}
+
+// Active returns true when the waypoint with the given index is activated
+func (w *Waypoints) Active(idx int) bool {
+	return w.Flags[idx/byteBits]&(1<<uint(idx%byteBits)) != 0
+}
+
+// SetActive activates or deactivates the waypoint with the given index
+func (w *Waypoints) SetActive(idx int, active bool) {
+	if active {
+		w.Flags[idx/byteBits] |= 1 << uint(idx%byteBits)
+	} else {
+		w.Flags[idx/byteBits] &^= 1 << uint(idx%byteBits)
+	}
+}
+
+// NPCs holds the flags of the NPCs the character was introduced to, and the NPCs which
+// congratulated the character, for each difficulty
+type NPCs struct {
+	Introductions   [NumDifficulties][npcFlagBytes]byte
+	Congratulations [NumDifficulties][npcFlagBytes]byte
+}
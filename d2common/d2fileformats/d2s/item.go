@@ -0,0 +1,775 @@
+package d2s
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+)
+
+// ItemVersionExpansion is the item version written by the 1.10 and later expansion
+const ItemVersionExpansion = 101
+
+// NumRareAffixes is the maximum number of prefixes, and of suffixes, on a rare item
+const NumRareAffixes = 3
+
+// NumSetPropertyLists is the number of property lists an item can have for the set bonuses
+const NumSetPropertyLists = 5
+
+// ItemLocation is where an item is kept
+type ItemLocation byte
+
+// Item locations
+const (
+	LocationStored   ItemLocation = 0
+	LocationEquipped ItemLocation = 1
+	LocationBelt     ItemLocation = 2
+	LocationCursor   ItemLocation = 4
+	LocationSocketed ItemLocation = 6
+)
+
+// BodyLocation is the slot of an equipped item
+type BodyLocation byte
+
+// Body locations
+const (
+	BodyNone BodyLocation = iota
+	BodyHead
+	BodyNeck
+	BodyTorso
+	BodyRightHand
+	BodyLeftHand
+	BodyRightRing
+	BodyLeftRing
+	BodyBelt
+	BodyFeet
+	BodyGloves
+	BodyRightHandSwap
+	BodyLeftHandSwap
+)
+
+// ItemPage is the grid a stored item is in
+type ItemPage byte
+
+// Item pages
+const (
+	PageNone      ItemPage = 0
+	PageInventory ItemPage = 1
+	PageCube      ItemPage = 4
+	PageStash     ItemPage = 5
+)
+
+// item flag bits, counted from the end of the item marker
+const (
+	flagIdentified   = 1 << 4
+	flagSocketed     = 1 << 11
+	flagNew          = 1 << 13
+	flagEar          = 1 << 16
+	flagStarter      = 1 << 17
+	flagSimple       = 1 << 21
+	flagEthereal     = 1 << 22
+	flagPersonalized = 1 << 24
+	flagRuneword     = 1 << 26
+
+	knownFlags = flagIdentified | flagSocketed | flagNew | flagEar | flagStarter | flagSimple |
+		flagEthereal | flagPersonalized | flagRuneword
+)
+
+// bit widths of the item fields
+const (
+	flagBits           = 32
+	versionBits        = 10
+	locationBits       = 3
+	bodyLocationBits   = 4
+	positionBits       = 4
+	pageBits           = 3
+	codeBytes          = 4
+	earClassBits       = 3
+	earLevelBits       = 7
+	simpleSocketBits   = 1
+	socketCountBits    = 3
+	idBits             = 32
+	levelBits          = 7
+	qualityBits        = 4
+	pictureBits        = 3
+	classAffixBits     = 11
+	qualityIDBits      = 3
+	affixBits          = 11
+	setUniqueBits      = 12
+	rareNameBits       = 8
+	runewordBits       = 12
+	runewordExtraBits  = 4
+	tomeBits           = 5
+	realmDwords        = 3
+	defenseBits        = 11
+	defenseAdd         = 10
+	maxDurabilityBits  = 8
+	durabilityBits     = 9
+	quantityBits       = 9
+	totalSocketBits    = 4
+	setListBits        = NumSetPropertyLists
+	itemListCountBits  = 16
+	maxSimpleSocketted = 1
+)
+
+//nolint:gochecknoglobals // lookup tables
+var (
+	// some stats are saved with the values of the stats which follow them, without their IDs
+	chainedStats = map[int][]int{
+		17: {18},     // item_maxdamage_percent, item_mindamage_percent
+		48: {49},     // firemindam, firemaxdam
+		50: {51},     // lightmindam, lightmaxdam
+		52: {53},     // magicmindam, magicmaxdam
+		54: {55, 56}, // coldmindam, coldmaxdam, coldlength
+		57: {58, 59}, // poisonmindam, poisonmaxdam, poisonlength
+	}
+
+	tomeCodes = map[string]bool{"tbk": true, "ibk": true}
+)
+
+// Ear is the ear of a player killed in a duel
+type Ear struct {
+	Class Class
+	Level int
+	Name  string
+}
+
+// Property is an item stat, with the values of the stats which are saved along with it
+type Property struct {
+	ID     int
+	Param  int
+	Values []int
+}
+
+// JoinChainedStats turns a list of properties with one value each into the properties as they
+// are saved, the chained stats are joined into the property of the stat leading the chain, which
+// also gives the param
+func JoinChainedStats(properties []Property) []Property {
+	result := make([]Property, 0, len(properties))
+	chains := make(map[int]int)
+
+	for _, property := range properties {
+		id, position := chainHead(property.ID)
+		if _, found := chainedStats[id]; !found {
+			result = append(result, property)
+			continue
+		}
+
+		idx, found := chains[id]
+		if !found {
+			idx = len(result)
+			chains[id] = idx
+			result = append(result, Property{ID: id, Values: make([]int, len(chainedStats[id])+1)})
+		}
+
+		if position == 0 {
+			result[idx].Param = property.Param
+		}
+
+		if len(property.Values) > 0 {
+			result[idx].Values[position] = property.Values[0]
+		}
+	}
+
+	return result
+}
+
+// SplitChainedStats is the reverse of JoinChainedStats, it returns a property for each stat
+func SplitChainedStats(properties []Property) []Property {
+	result := make([]Property, 0, len(properties))
+
+	for _, property := range properties {
+		for idx, id := range append([]int{property.ID}, chainedStats[property.ID]...) {
+			split := Property{ID: id, Param: property.Param}
+
+			if idx < len(property.Values) {
+				split.Values = []int{property.Values[idx]}
+			}
+
+			result = append(result, split)
+		}
+	}
+
+	return result
+}
+
+// chainHead returns the stat leading the chain of the given stat, and the position in the chain
+func chainHead(id int) (head, position int) {
+	for head, chain := range chainedStats {
+		for idx, chained := range chain {
+			if chained == id {
+				return head, idx + 1
+			}
+		}
+	}
+
+	return id, 0
+}
+
+// Item is an item as it is saved, the same format is used to send items to other players
+type Item struct {
+	Identified   bool
+	Socketed     bool
+	New          bool // picked up since the last save
+	Starter      bool
+	Simple       bool // simple items have no extended data
+	Ethereal     bool
+	Personalized bool
+	Runeword     bool
+
+	Version      int
+	Location     ItemLocation
+	BodyLocation BodyLocation
+	X            int
+	Y            int
+	Page         ItemPage
+
+	Ear  *Ear // set for ears, which have no item code
+	Code string
+
+	ID            uint32
+	Level         int
+	Quality       d2enum.ItemQuality
+	HasPicture    bool
+	Picture       int
+	HasClassAffix bool
+	ClassAffix    int
+
+	QualityID        int // the low or superior quality type
+	Prefixes         [NumRareAffixes]int
+	Suffixes         [NumRareAffixes]int // magic items only use the first prefix and suffix
+	RareNames        [2]int
+	SetID            int
+	UniqueID         int
+	RunewordID       int
+	PersonalizedName string
+	TomeData         int
+	RealmData        []uint32
+
+	Defense       int
+	MaxDurability int
+	Durability    int
+	Quantity      int
+	TotalSockets  int
+
+	Properties         []Property
+	SetProperties      [NumSetPropertyLists][]Property // a nil list is not saved
+	RunewordProperties []Property
+
+	Sockets []*Item // the items in the sockets, saved after the item
+
+	unknownFlags    uint32
+	runewordUnknown uint32
+}
+
+func readItemList(r *bitReader, records Records) ([]*Item, error) {
+	if err := r.marker(itemMarker); err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, r.bits(itemListCountBits))
+
+	for idx := range items {
+		item, err := readItem(r, records)
+		if err != nil {
+			return nil, err
+		}
+
+		items[idx] = item
+	}
+
+	return items, r.err
+}
+
+func writeItemList(buf *bytes.Buffer, items []*Item, records Records) error {
+	buf.WriteString(itemMarker)
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(items)))
+
+	for _, item := range items {
+		data, err := item.Marshal(records)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(data)
+	}
+
+	return nil
+}
+
+// LoadItem reads an item and the items in its sockets
+func LoadItem(data []byte, records Records) (*Item, error) {
+	return readItem(newBitReader(data, 0), records)
+}
+
+func readItem(r *bitReader, records Records) (*Item, error) {
+	if err := r.marker(itemMarker); err != nil {
+		return nil, err
+	}
+
+	item := &Item{}
+	flags := r.bits(flagBits)
+
+	item.setFlags(flags)
+	item.Version = int(r.bits(versionBits))
+	item.Location = ItemLocation(r.bits(locationBits))
+	item.BodyLocation = BodyLocation(r.bits(bodyLocationBits))
+	item.X = int(r.bits(positionBits))
+	item.Y = int(r.bits(positionBits))
+	item.Page = ItemPage(r.bits(pageBits))
+
+	if flags&flagEar != 0 {
+		item.Ear = &Ear{
+			Class: Class(r.bits(earClassBits)),
+			Level: int(r.bits(earLevelBits)),
+			Name:  r.string(),
+		}
+
+		r.align()
+
+		return item, r.err
+	}
+
+	item.Code = strings.TrimRight(string(r.bytes(codeBytes)), " ")
+
+	socketBits := socketCountBits
+	if item.Simple {
+		socketBits = simpleSocketBits
+	}
+
+	socketCount := int(r.bits(socketBits))
+
+	if !item.Simple {
+		if err := item.readExtended(r, records); err != nil {
+			return nil, err
+		}
+	}
+
+	r.align()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for idx := 0; idx < socketCount; idx++ {
+		socket, err := readItem(r, records)
+		if err != nil {
+			return nil, err
+		}
+
+		item.Sockets = append(item.Sockets, socket)
+	}
+
+	return item, nil
+}
+
+func (i *Item) setFlags(flags uint32) {
+	i.Identified = flags&flagIdentified != 0
+	i.Socketed = flags&flagSocketed != 0
+	i.New = flags&flagNew != 0
+	i.Starter = flags&flagStarter != 0
+	i.Simple = flags&flagSimple != 0
+	i.Ethereal = flags&flagEthereal != 0
+	i.Personalized = flags&flagPersonalized != 0
+	i.Runeword = flags&flagRuneword != 0
+	i.unknownFlags = flags &^ knownFlags
+}
+
+func (i *Item) flags() uint32 {
+	flags := i.unknownFlags
+
+	for flag, set := range map[uint32]bool{
+		flagIdentified:   i.Identified,
+		flagSocketed:     i.Socketed,
+		flagNew:          i.New,
+		flagEar:          i.Ear != nil,
+		flagStarter:      i.Starter,
+		flagSimple:       i.Simple,
+		flagEthereal:     i.Ethereal,
+		flagPersonalized: i.Personalized,
+		flagRuneword:     i.Runeword,
+	} {
+		if set {
+			flags |= flag
+		}
+	}
+
+	return flags
+}
+
+func (i *Item) readExtended(r *bitReader, records Records) error {
+	i.ID = r.bits(idBits)
+	i.Level = int(r.bits(levelBits))
+	i.Quality = d2enum.ItemQuality(r.bits(qualityBits))
+
+	if i.HasPicture = r.bit(); i.HasPicture {
+		i.Picture = int(r.bits(pictureBits))
+	}
+
+	if i.HasClassAffix = r.bit(); i.HasClassAffix {
+		i.ClassAffix = int(r.bits(classAffixBits))
+	}
+
+	i.readQuality(r)
+
+	if i.Runeword {
+		i.RunewordID = int(r.bits(runewordBits))
+		i.runewordUnknown = r.bits(runewordExtraBits)
+	}
+
+	if i.Personalized {
+		i.PersonalizedName = r.string()
+	}
+
+	if tomeCodes[i.Code] {
+		i.TomeData = int(r.bits(tomeBits))
+	}
+
+	if r.bit() {
+		i.RealmData = make([]uint32, realmDwords)
+
+		for idx := range i.RealmData {
+			i.RealmData[idx] = r.bits(idBits)
+		}
+	}
+
+	info, ok := records.ItemInfo(i.Code)
+	if !ok {
+		return fmt.Errorf("unknown item code %q", i.Code)
+	}
+
+	i.readTypeFields(r, info)
+
+	var setLists uint32
+
+	if i.Quality == d2enum.Set {
+		setLists = r.bits(setListBits)
+	}
+
+	var err error
+
+	if i.Properties, err = readProperties(r, records); err != nil {
+		return err
+	}
+
+	for idx := range i.SetProperties {
+		if setLists&(1<<uint(idx)) == 0 {
+			continue
+		}
+
+		if i.SetProperties[idx], err = readProperties(r, records); err != nil {
+			return err
+		}
+	}
+
+	if i.Runeword {
+		if i.RunewordProperties, err = readProperties(r, records); err != nil {
+			return err
+		}
+	}
+
+	return r.err
+}
+
+func (i *Item) readQuality(r *bitReader) {
+	switch i.Quality {
+	case d2enum.LowQuality, d2enum.Superior:
+		i.QualityID = int(r.bits(qualityIDBits))
+	case d2enum.Magic:
+		i.Prefixes[0] = int(r.bits(affixBits))
+		i.Suffixes[0] = int(r.bits(affixBits))
+	case d2enum.Set:
+		i.SetID = int(r.bits(setUniqueBits))
+	case d2enum.Unique:
+		i.UniqueID = int(r.bits(setUniqueBits))
+	case d2enum.Rare, d2enum.Crafted:
+		i.RareNames[0] = int(r.bits(rareNameBits))
+		i.RareNames[1] = int(r.bits(rareNameBits))
+
+		for idx := 0; idx < NumRareAffixes; idx++ {
+			if r.bit() {
+				i.Prefixes[idx] = int(r.bits(affixBits))
+			}
+
+			if r.bit() {
+				i.Suffixes[idx] = int(r.bits(affixBits))
+			}
+		}
+	}
+}
+
+func (i *Item) readTypeFields(r *bitReader, info ItemInfo) {
+	if info.Kind == ItemKindArmor {
+		i.Defense = int(r.bits(defenseBits)) - defenseAdd
+	}
+
+	if info.Kind == ItemKindArmor || info.Kind == ItemKindWeapon {
+		if i.MaxDurability = int(r.bits(maxDurabilityBits)); i.MaxDurability > 0 {
+			i.Durability = int(r.bits(durabilityBits))
+		}
+	}
+
+	if info.Stackable {
+		i.Quantity = int(r.bits(quantityBits))
+	}
+
+	if i.Socketed {
+		i.TotalSockets = int(r.bits(totalSocketBits))
+	}
+}
+
+func readProperties(r *bitReader, records Records) ([]Property, error) {
+	properties := make([]Property, 0)
+
+	for {
+		id := int(r.bits(statIDBits))
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		if id == statEnd {
+			return properties, nil
+		}
+
+		info, ok := records.StatInfo(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown item stat %d", id)
+		}
+
+		property := Property{ID: id, Param: int(r.bits(info.ParamBits))}
+
+		for _, statID := range append([]int{id}, chainedStats[id]...) {
+			if info, ok = records.StatInfo(statID); !ok {
+				return nil, fmt.Errorf("unknown item stat %d", statID)
+			}
+
+			property.Values = append(property.Values, int(r.bits(info.Bits))-info.Add)
+		}
+
+		properties = append(properties, property)
+	}
+}
+
+// Marshal writes the item, followed by the items in its sockets
+func (i *Item) Marshal(records Records) ([]byte, error) {
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushBytes([]byte(itemMarker)...)
+	sw.PushBits(i.flags(), flagBits)
+	sw.PushBits(uint32(i.Version), versionBits)
+	sw.PushBits(uint32(i.Location), locationBits)
+	sw.PushBits(uint32(i.BodyLocation), bodyLocationBits)
+	sw.PushBits(uint32(i.X), positionBits)
+	sw.PushBits(uint32(i.Y), positionBits)
+	sw.PushBits(uint32(i.Page), pageBits)
+
+	if i.Ear != nil {
+		sw.PushBits(uint32(i.Ear.Class), earClassBits)
+		sw.PushBits(uint32(i.Ear.Level), earLevelBits)
+		pushString(sw, i.Ear.Name)
+
+		return sw.GetBytes(), nil
+	}
+
+	if len(i.Code) > codeBytes {
+		return nil, fmt.Errorf("invalid item code %q", i.Code)
+	}
+
+	for _, char := range []byte(i.Code + strings.Repeat(" ", codeBytes-len(i.Code))) {
+		sw.PushBits(uint32(char), byteBits)
+	}
+
+	if i.Simple {
+		if len(i.Sockets) > maxSimpleSocketted {
+			return nil, errors.New("simple items can only have one socketed item")
+		}
+
+		sw.PushBits(uint32(len(i.Sockets)), simpleSocketBits)
+	} else {
+		sw.PushBits(uint32(len(i.Sockets)), socketCountBits)
+
+		if err := i.writeExtended(sw, records); err != nil {
+			return nil, err
+		}
+	}
+
+	data := sw.GetBytes()
+
+	for _, socket := range i.Sockets {
+		socketData, err := socket.Marshal(records)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, socketData...)
+	}
+
+	return data, nil
+}
+
+func (i *Item) writeExtended(sw *d2datautils.StreamWriter, records Records) error {
+	sw.PushBits(i.ID, idBits)
+	sw.PushBits(uint32(i.Level), levelBits)
+	sw.PushBits(uint32(i.Quality), qualityBits)
+	sw.PushBit(i.HasPicture)
+
+	if i.HasPicture {
+		sw.PushBits(uint32(i.Picture), pictureBits)
+	}
+
+	sw.PushBit(i.HasClassAffix)
+
+	if i.HasClassAffix {
+		sw.PushBits(uint32(i.ClassAffix), classAffixBits)
+	}
+
+	i.writeQuality(sw)
+
+	if i.Runeword {
+		sw.PushBits(uint32(i.RunewordID), runewordBits)
+		sw.PushBits(i.runewordUnknown, runewordExtraBits)
+	}
+
+	if i.Personalized {
+		pushString(sw, i.PersonalizedName)
+	}
+
+	if tomeCodes[i.Code] {
+		sw.PushBits(uint32(i.TomeData), tomeBits)
+	}
+
+	sw.PushBit(i.RealmData != nil)
+
+	for idx := 0; idx < realmDwords && i.RealmData != nil; idx++ {
+		if idx < len(i.RealmData) {
+			sw.PushBits(i.RealmData[idx], idBits)
+		} else {
+			sw.PushBits(0, idBits)
+		}
+	}
+
+	info, ok := records.ItemInfo(i.Code)
+	if !ok {
+		return fmt.Errorf("unknown item code %q", i.Code)
+	}
+
+	i.writeTypeFields(sw, info)
+
+	return i.writePropertyLists(sw, records)
+}
+
+func (i *Item) writeQuality(sw *d2datautils.StreamWriter) {
+	switch i.Quality {
+	case d2enum.LowQuality, d2enum.Superior:
+		sw.PushBits(uint32(i.QualityID), qualityIDBits)
+	case d2enum.Magic:
+		sw.PushBits(uint32(i.Prefixes[0]), affixBits)
+		sw.PushBits(uint32(i.Suffixes[0]), affixBits)
+	case d2enum.Set:
+		sw.PushBits(uint32(i.SetID), setUniqueBits)
+	case d2enum.Unique:
+		sw.PushBits(uint32(i.UniqueID), setUniqueBits)
+	case d2enum.Rare, d2enum.Crafted:
+		sw.PushBits(uint32(i.RareNames[0]), rareNameBits)
+		sw.PushBits(uint32(i.RareNames[1]), rareNameBits)
+
+		for idx := 0; idx < NumRareAffixes; idx++ {
+			for _, affix := range []int{i.Prefixes[idx], i.Suffixes[idx]} {
+				sw.PushBit(affix != 0)
+
+				if affix != 0 {
+					sw.PushBits(uint32(affix), affixBits)
+				}
+			}
+		}
+	}
+}
+
+func (i *Item) writeTypeFields(sw *d2datautils.StreamWriter, info ItemInfo) {
+	if info.Kind == ItemKindArmor {
+		sw.PushBits(uint32(i.Defense+defenseAdd), defenseBits)
+	}
+
+	if info.Kind == ItemKindArmor || info.Kind == ItemKindWeapon {
+		sw.PushBits(uint32(i.MaxDurability), maxDurabilityBits)
+
+		if i.MaxDurability > 0 {
+			sw.PushBits(uint32(i.Durability), durabilityBits)
+		}
+	}
+
+	if info.Stackable {
+		sw.PushBits(uint32(i.Quantity), quantityBits)
+	}
+
+	if i.Socketed {
+		sw.PushBits(uint32(i.TotalSockets), totalSocketBits)
+	}
+}
+
+func (i *Item) writePropertyLists(sw *d2datautils.StreamWriter, records Records) error {
+	if i.Quality == d2enum.Set {
+		setLists := uint32(0)
+
+		for idx, list := range i.SetProperties {
+			if list != nil {
+				setLists |= 1 << uint(idx)
+			}
+		}
+
+		sw.PushBits(setLists, setListBits)
+	}
+
+	lists := [][]Property{i.Properties}
+
+	for _, list := range i.SetProperties {
+		if list != nil {
+			lists = append(lists, list)
+		}
+	}
+
+	if i.Runeword {
+		lists = append(lists, i.RunewordProperties)
+	}
+
+	for _, list := range lists {
+		if err := writeProperties(sw, list, records); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeProperties(sw *d2datautils.StreamWriter, properties []Property, records Records) error {
+	for _, property := range properties {
+		info, ok := records.StatInfo(property.ID)
+		if !ok {
+			return fmt.Errorf("unknown item stat %d", property.ID)
+		}
+
+		statIDs := append([]int{property.ID}, chainedStats[property.ID]...)
+		if len(property.Values) != len(statIDs) {
+			return fmt.Errorf("item stat %d needs %d values", property.ID, len(statIDs))
+		}
+
+		sw.PushBits(uint32(property.ID), statIDBits)
+		sw.PushBits(uint32(property.Param), info.ParamBits)
+
+		for idx, statID := range statIDs {
+			if info, ok = records.StatInfo(statID); !ok {
+				return fmt.Errorf("unknown item stat %d", statID)
+			}
+
+			sw.PushBits(uint32(property.Values[idx]+info.Add), info.Bits)
+		}
+	}
+
+	sw.PushBits(statEnd, statIDBits)
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package d2s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+)
+
+// compareProperties reports the properties of the given list which did not keep their values
+func compareProperties(t *testing.T, list string, got, want []Property) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s: %d properties, want %d", list, len(got), len(want))
+		return
+	}
+
+	for idx := range want {
+		if got[idx].ID != want[idx].ID || got[idx].Param != want[idx].Param || !reflect.DeepEqual(got[idx].Values, want[idx].Values) {
+			t.Errorf("%s: property %d is stat %d param %d values %v, want stat %d param %d values %v", list, idx,
+				got[idx].ID, got[idx].Param, got[idx].Values, want[idx].ID, want[idx].Param, want[idx].Values)
+		}
+	}
+}
+
+func TestSetAndRunewordItemsKeepTheirProperties(t *testing.T) {
+	socketedRune := func(code string) *Item {
+		return &Item{Identified: true, Simple: true, Version: ItemVersionExpansion, Location: LocationSocketed, Code: code}
+	}
+
+	table := []struct {
+		name string
+		item *Item
+	}{
+		{
+			name: "set item",
+			item: &Item{
+				Identified: true, Version: ItemVersionExpansion, Location: LocationEquipped, BodyLocation: BodyHead,
+				Code: "cap", ID: 0xcafe, Level: 40, Quality: d2enum.Set, SetID: 12, Defense: 8, MaxDurability: 12,
+				Durability: 12,
+				Properties: []Property{{ID: 7, Values: []int{-10}}, {ID: 107, Param: 54, Values: []int{3}}},
+				SetProperties: [NumSetPropertyLists][]Property{
+					0: {{ID: 54, Values: []int{4, 9, 75}}},
+					3: {{ID: 17, Values: []int{20, 20}}, {ID: 93, Values: []int{-5}}},
+				},
+			},
+		},
+		{
+			name: "runeword",
+			item: &Item{
+				Identified: true, Socketed: true, Runeword: true, Version: ItemVersionExpansion, Page: PageInventory,
+				X: 4, Code: "lsd", ID: 0xbeef, Level: 25, Quality: d2enum.Normal, RunewordID: 27, MaxDurability: 44,
+				Durability: 30, TotalSockets: 2, Sockets: []*Item{socketedRune("r08"), socketedRune("r09")},
+				Properties: []Property{},
+				RunewordProperties: []Property{
+					{ID: 17, Values: []int{75, 75}}, {ID: 93, Values: []int{40}}, {ID: 107, Param: 36, Values: []int{1}},
+				},
+			},
+		},
+	}
+
+	records := testRecords{}
+
+	for _, row := range table {
+		data, err := row.item.Marshal(records)
+		if err != nil {
+			t.Fatalf("%s: %v", row.name, err)
+		}
+
+		loaded, err := LoadItem(data, records)
+		if err != nil {
+			t.Fatalf("%s: %v", row.name, err)
+		}
+
+		if loaded.Quality != row.item.Quality || loaded.SetID != row.item.SetID || loaded.Runeword != row.item.Runeword ||
+			loaded.RunewordID != row.item.RunewordID {
+			t.Errorf("%s: loaded quality %d set %d runeword %v %d", row.name, loaded.Quality, loaded.SetID, loaded.Runeword,
+				loaded.RunewordID)
+		}
+
+		compareProperties(t, row.name+" properties", loaded.Properties, row.item.Properties)
+		compareProperties(t, row.name+" runeword properties", loaded.RunewordProperties, row.item.RunewordProperties)
+
+		for idx, list := range row.item.SetProperties {
+			if (loaded.SetProperties[idx] == nil) != (list == nil) {
+				t.Errorf("%s: set property list %d was saved = %v, want %v", row.name, idx,
+					loaded.SetProperties[idx] != nil, list != nil)
+				continue
+			}
+
+			compareProperties(t, row.name+" set properties", loaded.SetProperties[idx], list)
+		}
+
+		if len(loaded.Sockets) != len(row.item.Sockets) {
+			t.Fatalf("%s: %d socketed items, want %d", row.name, len(loaded.Sockets), len(row.item.Sockets))
+		}
+
+		for idx, socket := range row.item.Sockets {
+			if loaded.Sockets[idx].Code != socket.Code {
+				t.Errorf("%s: socket %d holds %q, want %q", row.name, idx, loaded.Sockets[idx].Code, socket.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package d2s
+
+// StatInfo describes how a stat is saved, the values come from ItemStatCost.txt
+type StatInfo struct {
+	CharacterBits int // CSvBits, the width of the stat in the attribute section
+	Bits          int // Save Bits, the width of the stat in an item property list
+	Add           int // Save Add, subtracted from the saved value
+	ParamBits     int // Save Param Bits
+}
+
+// ItemKind tells which of the item excel files an item code comes from
+type ItemKind int
+
+// Item kinds
+const (
+	ItemKindMisc ItemKind = iota
+	ItemKindArmor
+	ItemKindWeapon
+)
+
+// ItemInfo describes the parts of an item base type which change the item layout
+type ItemInfo struct {
+	Kind      ItemKind
+	Stackable bool
+}
+
+// Records supplies the excel data needed to read and write the bit packed parts of a save
+type Records interface {
+	StatInfo(id int) (StatInfo, bool)
+	ItemInfo(code string) (ItemInfo, bool)
+}
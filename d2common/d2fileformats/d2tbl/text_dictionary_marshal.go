@@ -0,0 +1,144 @@
+package d2tbl
+
+import (
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+)
+
+const (
+	headerSize       = 21
+	elementIndexSize = 2
+	hashEntrySize    = 17
+	// hashTableRatio is the number of hash table entries for an element
+	hashTableRatio = 2
+)
+
+// Marshal encodes the dictionary to the bytes of a string table, the keys sorted. An element is
+// in the hash table entry of the hash of its key, or in the next free entry; the keys are written
+// as they are, a "#<index>" key of LoadTextDictionary is not turned back into "x".
+func (td TextDictionary) Marshal() []byte {
+	keys := make([]string, 0, len(td))
+
+	for key := range td {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	hashTableSize := len(keys) * hashTableRatio
+	if hashTableSize == 0 {
+		hashTableSize = 1
+	}
+
+	hashEntries := make([]textDictionaryHashEntry, hashTableSize)
+	elementIndex := make([]uint16, len(keys))
+	maxTries := uint32(0)
+
+	stringWriter := d2datautils.CreateStreamWriter()
+	stringOffset := headerSize + elementIndexSize*len(keys) + hashEntrySize*hashTableSize
+
+	for idx, key := range keys {
+		hash := hashKey(key)
+		slot := int(hash % uint32(hashTableSize))
+		tries := uint32(1)
+
+		for hashEntries[slot].IsActive {
+			slot = (slot + 1) % hashTableSize
+			tries++
+		}
+
+		if tries > maxTries {
+			maxTries = tries
+		}
+
+		keyOffset := stringOffset + len(stringWriter.GetBytes())
+		stringWriter.PushBytes([]byte(key)...)
+		stringWriter.PushByte(0)
+
+		valueOffset := stringOffset + len(stringWriter.GetBytes())
+		stringWriter.PushBytes([]byte(td[key])...)
+		stringWriter.PushByte(0)
+
+		hashEntries[slot] = textDictionaryHashEntry{
+			IsActive:    true,
+			Index:       uint16(idx),
+			HashValue:   hash,
+			IndexString: uint32(keyOffset),
+			NameString:  uint32(valueOffset),
+			NameLength:  uint16(len(td[key]) + 1),
+		}
+		elementIndex[idx] = uint16(slot)
+	}
+
+	stringData := stringWriter.GetBytes()
+	sw := d2datautils.CreateStreamWriter()
+
+	sw.PushUint16(crc16(stringData))
+	sw.PushUint16(uint16(len(keys)))
+	sw.PushUint32(uint32(hashTableSize))
+	sw.PushByte(0) // Version
+	sw.PushUint32(uint32(stringOffset))
+	sw.PushUint32(maxTries)
+	sw.PushUint32(uint32(stringOffset + len(stringData)))
+
+	for _, slot := range elementIndex {
+		sw.PushUint16(slot)
+	}
+
+	for _, entry := range hashEntries {
+		if entry.IsActive {
+			sw.PushByte(1)
+		} else {
+			sw.PushByte(0)
+		}
+
+		sw.PushUint16(entry.Index)
+		sw.PushUint32(entry.HashValue)
+		sw.PushUint32(entry.IndexString)
+		sw.PushUint32(entry.NameString)
+		sw.PushUint16(entry.NameLength)
+	}
+
+	sw.PushBytes(stringData...)
+
+	return sw.GetBytes()
+}
+
+// hashKey returns the hash of a key of a string table, the table entry of a key is its hash
+// modulo the size of the table
+// nolint:gomnd // hash function
+func hashKey(key string) uint32 {
+	var hash uint32
+
+	for _, c := range []byte(key) {
+		hash = hash<<4 + uint32(c)
+
+		if nibble := hash & 0xF0000000; nibble != 0 {
+			hash &= 0x0FFFFFFF
+			hash ^= nibble >> 24
+		}
+	}
+
+	return hash
+}
+
+// crc16 returns the CRC-16/CCITT-FALSE of the given data, the strings of the table
+// nolint:gomnd // polynomial
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
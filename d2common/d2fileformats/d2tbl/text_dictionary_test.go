@@ -0,0 +1,78 @@
+package d2tbl
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2datautils"
+)
+
+func TestTextDictionariesAreWrittenAndLoadedBack(t *testing.T) {
+	want := TextDictionary{
+		"WarrivAct1Intro": "Greetings, stranger.",
+		"strEmpty":        "",
+		"ModStr1a":        "%d%% Enhanced Damage",
+		"#12":             "a key loaded from an x entry",
+	}
+
+	for idx := 0; idx < 100; idx++ {
+		want["key"+string(rune('a'+idx%26))+string(rune('A'+idx/26))] = string(rune('0' + idx%10))
+	}
+
+	data := want.Marshal()
+
+	if got := LoadTextDictionary(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+
+	if got := LoadTextDictionary(TextDictionary{}.Marshal()); len(got) != 0 {
+		t.Errorf("the empty dictionary is loaded as %v", got)
+	}
+}
+
+func TestElementsAreInTheHashTableEntriesOfTheirKeys(t *testing.T) {
+	if hashKey("abc") != 0x6783 || hashKey("ModStr1a") != 0x59ab031 {
+		t.Errorf("hashes %x and %x", hashKey("abc"), hashKey("ModStr1a"))
+	}
+
+	keys := []string{"abc", "bca", "cab", "ModStr1a", "x1", "x2"}
+	dictionary := TextDictionary{}
+
+	for _, key := range keys {
+		dictionary[key] = key
+	}
+
+	br := d2datautils.CreateStreamReader(dictionary.Marshal())
+	br.SkipBytes(crcByteCount)
+
+	numberOfElements := int(br.GetUInt16())
+	hashTableSize := br.GetUInt32()
+	br.SkipBytes(5) //nolint:gomnd // version and string offset
+	maxTries := int(br.GetUInt32())
+
+	if numberOfElements != len(keys) {
+		t.Fatalf("%d elements", numberOfElements)
+	}
+
+	sort.Strings(keys)
+
+	for idx, key := range keys {
+		br.SetPosition(uint64(headerSize + elementIndexSize*idx))
+		slot := uint32(br.GetUInt16())
+
+		br.SetPosition(uint64(headerSize + elementIndexSize*numberOfElements + hashEntrySize*int(slot)))
+
+		if br.GetByte() != 1 || int(br.GetUInt16()) != idx || br.GetUInt32() != hashKey(key) {
+			t.Errorf("the hash table entry %d is not the one of %q", slot, key)
+		}
+
+		if tries := int((slot+hashTableSize-hashKey(key)%hashTableSize)%hashTableSize) + 1; tries > maxTries {
+			t.Errorf("%q is found after %d tries, at most %d", key, tries, maxTries)
+		}
+	}
+
+	if crc16([]byte("123456789")) != 0x29b1 {
+		t.Errorf("check value %x", crc16([]byte("123456789")))
+	}
+}
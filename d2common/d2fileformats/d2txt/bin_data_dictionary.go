@@ -0,0 +1,147 @@
+package d2txt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	binHeaderSize = 4
+	binCodeSize   = 4
+	binFlagSize   = 4
+)
+
+// BinColumnType is the type of the value of a column in a compiled .bin table
+type BinColumnType int
+
+// Column types of the .bin tables
+const (
+	BinInt8 BinColumnType = iota
+	BinUint8
+	BinInt16
+	BinUint16
+	BinInt32
+	BinUint32
+	BinString // a NUL padded string of Size bytes
+	BinCode   // a 4 character code padded with spaces or NULs
+	BinFlag   // the bit Bit of a 32 bit field, 1 or 0
+)
+
+// BinColumn is a column of a .bin table: where its value is in a record and how it is stored
+type BinColumn struct {
+	Name   string
+	Type   BinColumnType
+	Offset int
+	Size   int  // the size of a BinString
+	Bit    uint // the bit of a BinFlag
+}
+
+// BinSchema is the layout of the records of a compiled .bin table, the columns are in the
+// order of the txt file. The columns of the txt file which the game does not compile are left out.
+type BinSchema struct {
+	RecordSize int
+	Columns    []BinColumn
+}
+
+// LoadBinDataDictionary loads the records of a compiled .bin table, a record count followed by
+// records of the size of the schema, as a data dictionary of the columns of the schema
+func LoadBinDataDictionary(buf []byte, schema *BinSchema) (*DataDictionary, error) {
+	if len(buf) < binHeaderSize {
+		return nil, errors.New("bin table has no record count")
+	}
+
+	for idx := range schema.Columns {
+		if column := &schema.Columns[idx]; column.Offset < 0 || column.Offset+column.size() > schema.RecordSize {
+			return nil, fmt.Errorf("bin column %s is out of the records of %d bytes", column.Name, schema.RecordSize)
+		}
+	}
+
+	numRecords := int(binary.LittleEndian.Uint32(buf))
+	if len(buf) != binHeaderSize+numRecords*schema.RecordSize {
+		return nil, fmt.Errorf("bin table of %d bytes does not hold %d records of %d bytes", len(buf), numRecords,
+			schema.RecordSize)
+	}
+
+	columns := make([]string, len(schema.Columns))
+	rows := make([][]string, numRecords)
+
+	for idx := range schema.Columns {
+		columns[idx] = schema.Columns[idx].Name
+	}
+
+	for recordIdx := range rows {
+		record := buf[binHeaderSize+recordIdx*schema.RecordSize:][:schema.RecordSize]
+		rows[recordIdx] = make([]string, len(schema.Columns))
+
+		for idx := range schema.Columns {
+			rows[recordIdx][idx] = schema.Columns[idx].value(record)
+		}
+	}
+
+	data, err := Marshal(columns, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadDataDictionary(data), nil
+}
+
+// size returns the number of bytes of the value of the column
+// nolint:gomnd // type sizes
+func (c *BinColumn) size() int {
+	switch c.Type {
+	case BinInt8, BinUint8:
+		return 1
+	case BinInt16, BinUint16:
+		return 2
+	case BinInt32, BinUint32:
+		return 4
+	case BinString:
+		return c.Size
+	case BinCode:
+		return binCodeSize
+	case BinFlag:
+		return binFlagSize
+	}
+
+	return 0
+}
+
+// value returns the value of the column in the given record, as it is written in a txt file
+func (c *BinColumn) value(record []byte) string {
+	data := record[c.Offset:][:c.size()]
+
+	switch c.Type {
+	case BinInt8:
+		return strconv.Itoa(int(int8(data[0])))
+	case BinUint8:
+		return strconv.Itoa(int(data[0]))
+	case BinInt16:
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(data))))
+	case BinUint16:
+		return strconv.Itoa(int(binary.LittleEndian.Uint16(data)))
+	case BinInt32:
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(data))))
+	case BinUint32:
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data)), 10) //nolint:gomnd // decimal
+	case BinString:
+		if end := strings.IndexByte(string(data), 0); end >= 0 {
+			data = data[:end]
+		}
+
+		return string(data)
+	case BinCode:
+		return strings.TrimRight(string(data), " \x00")
+	case BinFlag:
+		if binary.LittleEndian.Uint32(data)&(1<<c.Bit) != 0 {
+			return "1"
+		}
+
+		return "0"
+	}
+
+	return ""
+}
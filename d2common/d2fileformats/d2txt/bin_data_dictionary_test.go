@@ -0,0 +1,65 @@
+package d2txt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBinTablesAreLoadedByTheirSchema(t *testing.T) {
+	schema := &BinSchema{
+		RecordSize: 24,
+		Columns: []BinColumn{
+			{Name: "name", Type: BinString, Offset: 0, Size: 8},
+			{Name: "code", Type: BinCode, Offset: 8},
+			{Name: "int8", Type: BinInt8, Offset: 12},
+			{Name: "uint8", Type: BinUint8, Offset: 13},
+			{Name: "int16", Type: BinInt16, Offset: 14},
+			{Name: "uint32", Type: BinUint32, Offset: 16},
+			{Name: "flag0", Type: BinFlag, Offset: 20, Bit: 0},
+			{Name: "flag9", Type: BinFlag, Offset: 20, Bit: 9},
+		},
+	}
+
+	data := []byte{
+		2, 0, 0, 0,
+		'h', 'a', 'x', 'e', 0, 'z', 'z', 'z', 'h', 'a', 'x', ' ', 0xff, 0xff, 0x18, 0xfc, 0xff, 0xff, 0xff, 0xff, 0x01, 0x02, 0, 0,
+		'n', 'a', 'm', 'e', 's', 'i', 'z', 'e', 'x', 'b', 0, 0, 7, 7, 7, 0, 1, 0, 0, 0, 0, 0, 0, 0,
+	}
+
+	dictionary, err := LoadBinDataDictionary(data, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"name", "code", "int8", "uint8", "int16", "uint32", "flag0", "flag9"}; !reflect.DeepEqual(dictionary.Columns(), want) {
+		t.Errorf("columns %v, want %v", dictionary.Columns(), want)
+	}
+
+	want := [][]string{
+		{"haxe", "hax", "-1", "255", "-1000", "4294967295", "1", "1"},
+		{"namesize", "xb", "7", "7", "7", "1", "0", "0"},
+	}
+
+	for idx := range want {
+		if !dictionary.Next() {
+			t.Fatalf("%d rows, want %d", idx, len(want))
+		}
+
+		if got := dictionary.Row(); !reflect.DeepEqual(got, want[idx]) {
+			t.Errorf("row %d is %q, want %q", idx, got, want[idx])
+		}
+	}
+
+	if dictionary.Next() {
+		t.Error("more rows than records")
+	}
+
+	if _, err := LoadBinDataDictionary(data[:len(data)-1], schema); err == nil {
+		t.Error("a truncated table was loaded")
+	}
+
+	schema.Columns = append(schema.Columns, BinColumn{Name: "out", Type: BinInt32, Offset: 22})
+	if _, err := LoadBinDataDictionary(data, schema); err == nil {
+		t.Error("a column out of the records was loaded")
+	}
+}
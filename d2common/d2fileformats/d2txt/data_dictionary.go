@@ -11,10 +11,11 @@ import (
 
 // DataDictionary represents a data file (Excel)
 type DataDictionary struct {
-	lookup map[string]int
-	r      *csv.Reader
-	record []string
-	Err    error
+	columns []string
+	lookup  map[string]int
+	r       *csv.Reader
+	record  []string
+	Err     error
 }
 
 // LoadDataDictionary loads the contents of a spreadsheet style txt file
@@ -29,8 +30,9 @@ func LoadDataDictionary(buf []byte) *DataDictionary {
 	}
 
 	data := &DataDictionary{
-		lookup: make(map[string]int, len(fieldNames)),
-		r:      cr,
+		columns: append([]string(nil), fieldNames...),
+		lookup:  make(map[string]int, len(fieldNames)),
+		r:       cr,
 	}
 
 	for i, name := range fieldNames {
@@ -60,9 +62,24 @@ func (d *DataDictionary) Next() bool {
 	return true
 }
 
-// String gets a string from the given column
+// Columns returns the names of the columns, in the order of the file
+func (d *DataDictionary) Columns() []string {
+	return d.columns
+}
+
+// Row returns a copy of the fields of the current row, in the order of the columns
+func (d *DataDictionary) Row() []string {
+	return append([]string(nil), d.record...)
+}
+
+// String gets a string from the given column, empty if the table has no such column
 func (d *DataDictionary) String(field string) string {
-	return d.record[d.lookup[field]]
+	idx, found := d.lookup[field]
+	if !found {
+		return ""
+	}
+
+	return d.record[idx]
 }
 
 // Number gets a number for the given column
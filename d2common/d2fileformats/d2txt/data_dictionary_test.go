@@ -0,0 +1,68 @@
+package d2txt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDataDictionariesAreWrittenAndLoadedBack(t *testing.T) {
+	columns := []string{"name", "id", "list", "enabled", "*eol"}
+	rows := [][]string{
+		{"Act 1 - Town", "1", "a,b,c", "1", "0"},
+		{"Expansion"},
+		{"short", "2"},
+		{"Act 2 - Sewer's Level 1", "3", "", "0", "0"},
+	}
+
+	data, err := Marshal(columns, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dictionary := LoadDataDictionary(data)
+
+	if !reflect.DeepEqual(dictionary.Columns(), columns) {
+		t.Errorf("columns %v, want %v", dictionary.Columns(), columns)
+	}
+
+	want := [][]string{rows[0], {"short", "2", "", "", ""}, rows[3]}
+	got := make([][]string, 0)
+
+	for dictionary.Next() {
+		got = append(got, dictionary.Row())
+	}
+
+	if dictionary.Err != nil {
+		t.Fatal(dictionary.Err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows %q, want %q", got, want)
+	}
+
+	dictionary = LoadDataDictionary(data)
+	dictionary.Next()
+
+	if value := dictionary.String("missing"); value != "" {
+		t.Errorf("column missing from the table is %q, want it empty", value)
+	}
+
+	if _, err := Marshal(columns[:2], rows[:1]); err == nil {
+		t.Error("a row longer than the columns was written")
+	}
+
+	if _, err := Marshal(columns[:1], [][]string{{"two\tfields"}}); err == nil {
+		t.Error("a field with a tab was written")
+	}
+}
+
+func TestDataDictionariesAreWrittenAsTabSeparatedLines(t *testing.T) {
+	data, err := Marshal([]string{"name", "id"}, [][]string{{`say "hi"`, "1"}, {"short"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "name\tid\r\nsay \"hi\"\t1\r\nshort\t\r\n"; string(data) != want {
+		t.Errorf("wrote %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,58 @@
+package d2txt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	txtFieldSeparator = "\t"
+	txtLineSeparator  = "\r\n"
+)
+
+// Marshal encodes the given rows to the bytes of a tab separated txt file, the names of the
+// columns first, in the given order. A row shorter than the columns is padded with empty fields.
+// The fields are written as they are, like the game writes them, so they cannot hold tabs or
+// line breaks.
+func Marshal(columns []string, rows [][]string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	if err := writeLine(buf, columns); err != nil {
+		return nil, fmt.Errorf("columns: %w", err)
+	}
+
+	record := make([]string, len(columns))
+
+	for idx, row := range rows {
+		if len(row) > len(columns) {
+			return nil, fmt.Errorf("row %d has %d fields for %d columns", idx, len(row), len(columns))
+		}
+
+		copy(record, row)
+
+		for field := len(row); field < len(record); field++ {
+			record[field] = ""
+		}
+
+		if err := writeLine(buf, record); err != nil {
+			return nil, fmt.Errorf("row %d: %w", idx, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeLine writes the fields joined by tabs and ended by a line break
+func writeLine(buf *bytes.Buffer, fields []string) error {
+	for idx, field := range fields {
+		if strings.ContainsAny(field, "\t\r\n") {
+			return fmt.Errorf("field %d %q holds a tab or a line break", idx, field)
+		}
+	}
+
+	buf.WriteString(strings.Join(fields, txtFieldSeparator))
+	buf.WriteString(txtLineSeparator)
+
+	return nil
+}
@@ -43,6 +43,10 @@ func (a *Asset) Path() string {
 // Read will read asset data into the given buffer
 func (a *Asset) Read(buf []byte) (n int, err error) {
 	totalRead, err := a.stream.Read(buf)
+	if err != nil {
+		return totalRead, err
+	}
+
 	if totalRead == 0 {
 		return 0, io.EOF
 	}
@@ -88,9 +92,13 @@ func (a *Asset) Data() ([]byte, error) {
 
 		data = append(data, buf[:numBytesRead]...)
 
-		if readErr != nil || numBytesRead == 0 {
+		if readErr == io.EOF || numBytesRead == 0 {
 			break
 		}
+
+		if readErr != nil {
+			return nil, readErr
+		}
 	}
 
 	a.data = data
@@ -0,0 +1,73 @@
+package mpq
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2mpq"
+)
+
+func TestSource_WrittenArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "d2loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	content := bytes.Repeat([]byte("OpenDiablo2 "), 1000)
+
+	table := []struct {
+		path    string
+		options d2mpq.FileOptions
+	}{
+		{"data\\global\\excel\\stored.txt", d2mpq.FileOptions{}},
+		{"data\\global\\excel\\zlib.txt", d2mpq.FileOptions{Compression: d2mpq.CompressionZLib}},
+		{"data\\global\\excel\\bzip2.txt", d2mpq.FileOptions{Compression: d2mpq.CompressionBZip2, SingleUnit: true}},
+		{"data\\global\\excel\\implode.txt", d2mpq.FileOptions{Implode: true, FixKey: true}},
+	}
+
+	w, err := d2mpq.NewWriter(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, row := range table {
+		if err = w.AddFile(row.path, content, row.options); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archivePath := filepath.Join(dir, "test.mpq")
+
+	if err = w.Save(archivePath); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewSource(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, row := range table {
+		a, err := source.Open("/" + strings.ReplaceAll(row.path, "\\", "/"))
+		if err != nil {
+			t.Errorf("%s: %v", row.path, err)
+			continue
+		}
+
+		data, err := a.Data()
+		if err != nil {
+			t.Errorf("%s: %v", row.path, err)
+			continue
+		}
+
+		if !bytes.Equal(data, content) {
+			t.Errorf("%s: read %d bytes which do not match the %d bytes written", row.path, len(data), len(content))
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package d2resource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	knownActs          = 5
+	knownQuestsPerAct  = 6
+	actAndQuestFormats = 2 // format verbs of the paths with the number of a quest
+)
+
+// KnownPaths returns the paths of the files named by the resource paths of this package, with the
+// language and font tokens expanded for every language and the numbered paths for every act and
+// quest. Archives without a (listfile) are listed by looking up these paths.
+func KnownPaths() []string {
+	paths := make([]string, 0)
+
+	for _, path := range filePaths() {
+		paths = append(paths, expandTokens(path)...)
+	}
+
+	for _, path := range fontPaths() {
+		for _, ext := range []string{".dc6", ".tbl"} {
+			paths = append(paths, expandTokens(path+ext)...)
+		}
+	}
+
+	for _, path := range numberedPaths() {
+		for act := 1; act <= knownActs; act++ {
+			if strings.Count(path, "%d") < actAndQuestFormats {
+				paths = append(paths, fmt.Sprintf(path, act))
+				continue
+			}
+
+			for quest := 1; quest <= knownQuestsPerAct; quest++ {
+				paths = append(paths, fmt.Sprintf(path, act, quest))
+			}
+		}
+	}
+
+	return paths
+}
+
+// expandTokens replaces the language and font tokens of a path with every language and charset
+func expandTokens(path string) []string {
+	if !strings.Contains(path, LanguageTableToken) && !strings.Contains(path, LanguageFontToken) {
+		return []string{path}
+	}
+
+	languages := make([]string, 0)
+	for _, language := range getLanguages() {
+		languages = append(languages, language)
+	}
+
+	sort.Strings(languages)
+
+	paths := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, language := range languages {
+		expanded := strings.ReplaceAll(path, LanguageTableToken, language)
+		expanded = strings.ReplaceAll(expanded, LanguageFontToken, GetFontCharset(language))
+
+		if !seen[expanded] {
+			seen[expanded] = true
+			paths = append(paths, expanded)
+		}
+	}
+
+	return paths
+}
+
+// fontPaths are the paths of the fonts, which are a .dc6 and a .tbl file
+func fontPaths() []string {
+	return []string{
+		Font6,
+		Font8,
+		Font16,
+		Font24,
+		Font30,
+		Font42,
+		FontFormal12,
+		FontFormal11,
+		FontFormal10,
+		FontExocet10,
+		FontExocet8,
+		FontSucker,
+		FontRediculous,
+	}
+}
+
+// numberedPaths are the paths with the number of an act, and of a quest of the act, as format
+// verbs
+func numberedPaths() []string {
+	return []string{
+		AutomapCells,
+		QuestLogAQuestAnimation,
+	}
+}
+
+// directoryPaths are the paths of directories, the names of their files come from the records
+// and are not known
+func directoryPaths() []string {
+	return []string{
+		ObjectData,
+		PlayerAnimationBase,
+		MissileData,
+		ItemGraphics,
+	}
+}
+
+// filePaths are the paths which name a single file
+func filePaths() []string {
+	return []string{
+		LocalLanguage,
+		LoadingScreen,
+		TrademarkScreen,
+		GameSelectScreen,
+		TCPIPBackground,
+		Diablo2LogoFireLeft,
+		Diablo2LogoFireRight,
+		Diablo2LogoBlackLeft,
+		Diablo2LogoBlackRight,
+		CreditsBackground,
+		CreditsText,
+		CinematicsBackground,
+		Act1Intro,
+		Act2Intro,
+		Act3Intro,
+		Act4Intro,
+		Act4Outro,
+		Act5Intro,
+		Act5Outro,
+		CharacterSelectBackground,
+		CharacterSelectCampfire,
+		CharacterSelectBarbarianUnselected,
+		CharacterSelectBarbarianUnselectedH,
+		CharacterSelectBarbarianSelected,
+		CharacterSelectBarbarianForwardWalk,
+		CharacterSelectBarbarianForwardWalkOverlay,
+		CharacterSelectBarbarianBackWalk,
+		CharacterSelectSorceressUnselected,
+		CharacterSelectSorceressUnselectedH,
+		CharacterSelectSorceressSelected,
+		CharacterSelectSorceressSelectedOverlay,
+		CharacterSelectSorceressForwardWalk,
+		CharacterSelectSorceressForwardWalkOverlay,
+		CharacterSelectSorceressBackWalk,
+		CharacterSelectSorceressBackWalkOverlay,
+		CharacterSelectNecromancerUnselected,
+		CharacterSelectNecromancerUnselectedH,
+		CharacterSelectNecromancerSelected,
+		CharacterSelectNecromancerSelectedOverlay,
+		CharacterSelectNecromancerForwardWalk,
+		CharacterSelectNecromancerForwardWalkOverlay,
+		CharacterSelectNecromancerBackWalk,
+		CharacterSelectNecromancerBackWalkOverlay,
+		CharacterSelectPaladinUnselected,
+		CharacterSelectPaladinUnselectedH,
+		CharacterSelectPaladinSelected,
+		CharacterSelectPaladinForwardWalk,
+		CharacterSelectPaladinForwardWalkOverlay,
+		CharacterSelectPaladinBackWalk,
+		CharacterSelectAmazonUnselected,
+		CharacterSelectAmazonUnselectedH,
+		CharacterSelectAmazonSelected,
+		CharacterSelectAmazonForwardWalk,
+		CharacterSelectAmazonForwardWalkOverlay,
+		CharacterSelectAmazonBackWalk,
+		CharacterSelectAssassinUnselected,
+		CharacterSelectAssassinUnselectedH,
+		CharacterSelectAssassinSelected,
+		CharacterSelectAssassinForwardWalk,
+		CharacterSelectAssassinBackWalk,
+		CharacterSelectDruidUnselected,
+		CharacterSelectDruidUnselectedH,
+		CharacterSelectDruidSelected,
+		CharacterSelectDruidForwardWalk,
+		CharacterSelectDruidBackWalk,
+		CharacterSelectionBackground,
+		CharacterSelectionSelectBox,
+		PopUpOkCancel,
+		GamePanels,
+		GameGlobeOverlap,
+		HealthManaIndicator,
+		AddSkillButton,
+		MoveGoldDialog,
+		WPTabs,
+		WPBg,
+		WPIcons,
+		UpDownArrows,
+		EscapeOptions,
+		EscapeExit,
+		EscapeReturnToGame,
+		EscapeOptSoundOptions,
+		EscapeOptVideoOptions,
+		EscapeOptAutoMapOptions,
+		EscapeOptCfgOptions,
+		EscapeOptPrevious,
+		EscapeSndOptSoundVolume,
+		EscapeSndOptMusicVolume,
+		EscapeSndOpt3DBias,
+		EscapeSndOptNPCSpeech,
+		EscapeSndOptNPCSpeechAudioAndText,
+		EscapeSndOptNPCSpeechAudioOnly,
+		EscapeSndOptNPCSpeechTextOnly,
+		EscapeVidOptRes,
+		EscapeVidOptLightQuality,
+		EscapeVidOptBlendShadow,
+		EscapeVidOptPerspective,
+		EscapeVidOptGamma,
+		EscapeVidOptContrast,
+		EscapeAutoMapOptSize,
+		EscapeAutoMapOptFade,
+		EscapeAutoMapOptCenter,
+		EscapeAutoMapOptNames,
+		EscapeAutoMapOptFullScreen,
+		EscapeAutoMapOptMiniMap,
+		EscapeVideoOptRes640x480,
+		EscapeVideoOptRes800x600,
+		EscapeOn,
+		EscapeOff,
+		EscapeYes,
+		EscapeNo,
+		EscapeSlideBar,
+		EscapeSlideBarSkull,
+		HelpBorder,
+		HelpYellowBullet,
+		HelpWhiteBullet,
+		BoxPieces,
+		TextSlider,
+		GameSmallMenuButton,
+		SkillIcon,
+		QuestLogBg,
+		QuestLogDone,
+		QuestLogTabs,
+		QuestLogQDescrBtn,
+		QuestLogSocket,
+		CursorDefault,
+		ExpansionStringTable,
+		StringTable,
+		PatchStringTable,
+		WideButtonBlank,
+		MediumButtonBlank,
+		CancelButton,
+		NarrowButtonBlank,
+		ShortButtonBlank,
+		TextBox2,
+		TallButtonBlank,
+		Checkbox,
+		Scrollbar,
+		PopUpLarge,
+		PopUpLargest,
+		PopUpWide,
+		PopUpOk,
+		PopUpOk2,
+		PopUpOkCancel2,
+		PopUp340x224,
+		PentSpin,
+		Minipanel,
+		MinipanelSmall,
+		MinipanelButton,
+		Frame,
+		InventoryCharacterPanel,
+		HeroStatsPanelStatsPoints,
+		HeroStatsPanelSocket,
+		InventoryWeaponsTab,
+		SkillsPanelAmazon,
+		SkillsPanelBarbarian,
+		SkillsPanelDruid,
+		SkillsPanelAssassin,
+		SkillsPanelNecromancer,
+		SkillsPanelPaladin,
+		SkillsPanelSorcerer,
+		GenericSkills,
+		AmazonSkills,
+		BarbarianSkills,
+		DruidSkills,
+		AssassinSkills,
+		NecromancerSkills,
+		PaladinSkills,
+		SorcererSkills,
+		RunButton,
+		MenuButton,
+		GoldCoinButton,
+		BuySellButton,
+		BuySellPanel,
+		HirelingPanel,
+		ArmorPlaceholder,
+		BeltPlaceholder,
+		BootsPlaceholder,
+		HelmGlovePlaceholder,
+		RingAmuletPlaceholder,
+		WeaponsPlaceholder,
+		LevelPreset,
+		LevelType,
+		ObjectType,
+		LevelWarp,
+		LevelDetails,
+		LevelMaze,
+		LevelSubstitutions,
+		ObjectDetails,
+		ObjectMode,
+		SoundSettings,
+		ItemStatCost,
+		ItemRatio,
+		ItemTypes,
+		QualityItems,
+		LowQualityItems,
+		Overlays,
+		Runes,
+		Sets,
+		SetItems,
+		AutoMagic,
+		BodyLocations,
+		Events,
+		Properties,
+		Hireling,
+		HirelingDescription,
+		DifficultyLevels,
+		AutoMap,
+		CubeRecipes,
+		CubeModifier,
+		CubeType,
+		Skills,
+		SkillDesc,
+		SkillCalc,
+		MissileCalc,
+		TreasureClass,
+		TreasureClassEx,
+		States,
+		SoundEnvirons,
+		Shrines,
+		MonProp,
+		ElemType,
+		PlrMode,
+		PetType,
+		NPC,
+		MonsterUniqueModifier,
+		MonsterEquipment,
+		UniqueAppellation,
+		MonsterLevel,
+		MonsterSound,
+		MonsterSequence,
+		PlayerClass,
+		PlayerType,
+		Composite,
+		HitClass,
+		ObjectGroup,
+		CompCode,
+		Belts,
+		Gamble,
+		Colors,
+		StorePage,
+		AnimationData,
+		Inventory,
+		Weapons,
+		Armor,
+		ArmorType,
+		WeaponClass,
+		Books,
+		Misc,
+		UniqueItems,
+		Gems,
+		MagicPrefix,
+		MagicSuffix,
+		RarePrefix,
+		RareSuffix,
+		UniquePrefix,
+		UniqueSuffix,
+		Experience,
+		CharStats,
+		BGMTitle,
+		BGMOptions,
+		BGMAct1AndarielAction,
+		BGMAct1BloodRavenResolution,
+		BGMAct1Caves,
+		BGMAct1Crypt,
+		BGMAct1DenOfEvilAction,
+		BGMAct1Monastery,
+		BGMAct1Town1,
+		BGMAct1Tristram,
+		BGMAct1Wild,
+		BGMAct2Desert,
+		BGMAct2Harem,
+		BGMAct2HoradricAction,
+		BGMAct2Lair,
+		BGMAct2RadamentResolution,
+		BGMAct2Sanctuary,
+		BGMAct2Sewer,
+		BGMAct2TaintedSunAction,
+		BGMAct2Tombs,
+		BGMAct2Town2,
+		BGMAct2Valley,
+		BGMAct3Jungle,
+		BGMAct3Kurast,
+		BGMAct3KurastSewer,
+		BGMAct3MefDeathAction,
+		BGMAct3OrbAction,
+		BGMAct3Spider,
+		BGMAct3Town3,
+		BGMAct4Diablo,
+		BGMAct4DiabloAction,
+		BGMAct4ForgeAction,
+		BGMAct4IzualAction,
+		BGMAct4Mesa,
+		BGMAct4Town4,
+		BGMAct5Baal,
+		BGMAct5Siege,
+		BGMAct5Shenk,
+		BGMAct5XTown,
+		BGMAct5XTemple,
+		BGMAct5IceCaves,
+		BGMAct5Nihlathak,
+		MonStats,
+		MonStats2,
+		MonPreset,
+		MonType,
+		SuperUniques,
+		MonMode,
+		MonsterPlacement,
+		MonsterAI,
+		Missiles,
+		PaletteAct1,
+		PaletteAct2,
+		PaletteAct3,
+		PaletteAct4,
+		PaletteAct5,
+		PaletteEndGame,
+		PaletteEndGame2,
+		PaletteFechar,
+		PaletteLoading,
+		PaletteMenu0,
+		PaletteMenu1,
+		PaletteMenu2,
+		PaletteMenu3,
+		PaletteMenu4,
+		PaletteSky,
+		PaletteStatic,
+		PaletteTrademark,
+		PaletteUnits,
+		PaletteTransformAct1,
+		PaletteTransformAct2,
+		PaletteTransformAct3,
+		PaletteTransformAct4,
+		PaletteTransformAct5,
+		PaletteTransformEndGame,
+		PaletteTransformEndGame2,
+		PaletteTransformFechar,
+		PaletteTransformLoading,
+		PaletteTransformMenu0,
+		PaletteTransformMenu1,
+		PaletteTransformMenu2,
+		PaletteTransformMenu3,
+		PaletteTransformMenu4,
+		PaletteTransformSky,
+		PaletteTransformTrademark,
+	}
+}
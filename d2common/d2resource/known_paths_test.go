@@ -0,0 +1,79 @@
+package d2resource
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// pathConstants returns the names and values of the constants of resource_paths.go which are paths
+func pathConstants(t *testing.T) map[string]string {
+	t.Helper()
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, "resource_paths.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := (&types.Config{}).Check("d2resource", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := make(map[string]string)
+
+	for _, name := range pkg.Scope().Names() {
+		object, ok := pkg.Scope().Lookup(name).(*types.Const)
+		if !ok || object.Val().Kind() != constant.String {
+			continue
+		}
+
+		if value := constant.StringVal(object.Val()); strings.HasPrefix(value, "/") {
+			paths[name] = value
+		}
+	}
+
+	return paths
+}
+
+func TestKnownPathsListEveryResourcePath(t *testing.T) {
+	listed := make(map[string]bool)
+
+	for _, paths := range [][]string{filePaths(), fontPaths(), numberedPaths(), directoryPaths()} {
+		for _, path := range paths {
+			listed[path] = true
+		}
+	}
+
+	for name, path := range pathConstants(t) {
+		if !listed[path] {
+			t.Errorf("%s (%s) is not listed in known_paths.go", name, path)
+		}
+	}
+}
+
+func TestKnownPathsExpandTheNumberedPaths(t *testing.T) {
+	known := make(map[string]bool)
+
+	for _, path := range KnownPaths() {
+		known[path] = true
+	}
+
+	for _, path := range []string{"/data/global/ui/AutoMap/Act5/MaxiMap.dc6", "/data/global/ui/MENU/a4q3.dc6"} {
+		if !known[path] {
+			t.Errorf("%s is not a known path", path)
+		}
+	}
+
+	for path := range known {
+		if strings.Contains(path, "%") {
+			t.Errorf("%s is not expanded", path)
+		}
+	}
+}
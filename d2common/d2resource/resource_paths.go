@@ -270,6 +270,9 @@ const (
 	MenuButton     = "/data/global/ui/PANEL/menubutton.DC6"
 	GoldCoinButton = "/data/global/ui/panel/goldcoinbtn.dc6"
 	BuySellButton  = "/data/global/ui/panel/buysellbtn.dc6"
+	BuySellPanel   = "/data/global/ui/PANEL/buysell.dc6"
+	HirelingPanel  = "/data/global/ui/PANEL/NPCInv.dc6"
+	AutomapCells   = "/data/global/ui/AutoMap/Act%d/MaxiMap.dc6"
 
 	ArmorPlaceholder      = "/data/global/ui/PANEL/inv_armor.DC6"
 	BeltPlaceholder       = "/data/global/ui/PANEL/inv_belt.DC6"
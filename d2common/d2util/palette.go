@@ -1,6 +1,8 @@
 package d2util
 
 import (
+	"image"
+	"image/color"
 	"log"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
@@ -31,3 +33,67 @@ func ImgIndexToRGBA(indexData []byte, palette d2interface.Palette) []byte {
 
 	return colorData
 }
+
+// opaqueAlpha is the alpha, out of 0xffff, from which a pixel is opaque
+const opaqueAlpha = 0x8000
+
+// RGBAToImgIndex converts the pixels of the given image, row by row, to indices of the given
+// palette: the transparent pixels get the index zero, the other pixels the index of the closest
+// color among the other colors of the palette
+func RGBAToImgIndex(img image.Image, palette d2interface.Palette) []byte {
+	bounds := img.Bounds()
+	indexData := make([]byte, bounds.Dx()*bounds.Dy())
+	colors := palette.GetColors()
+	closest := make(map[color.RGBA]byte)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			if a < opaqueAlpha {
+				continue
+			}
+
+			pixel := color.RGBA{R: unpremultiply(r, a), G: unpremultiply(g, a), B: unpremultiply(b, a)}
+
+			idx, found := closest[pixel]
+			if !found {
+				idx = closestColor(pixel, colors[:palette.NumColors()])
+				closest[pixel] = idx
+			}
+
+			indexData[(y-bounds.Min.Y)*bounds.Dx()+x-bounds.Min.X] = idx
+		}
+	}
+
+	return indexData
+}
+
+// unpremultiply returns the 8 bit value of a 16 bit color component premultiplied by the alpha
+func unpremultiply(value, alpha uint32) uint8 {
+	return uint8((value * 0xffff / alpha) >> 8) //nolint:gomnd // 16 bit to 8 bit
+}
+
+// closestColor returns the index of the color closest to the given color, index zero is left out
+// as it is transparent
+func closestColor(pixel color.RGBA, colors []d2interface.Color) byte {
+	best, bestDistance := 1, -1
+
+	for idx := 1; idx < len(colors); idx++ {
+		if colors[idx] == nil {
+			continue
+		}
+
+		dr := int(colors[idx].R()) - int(pixel.R)
+		dg := int(colors[idx].G()) - int(pixel.G)
+		db := int(colors[idx].B()) - int(pixel.B)
+
+		if distance := dr*dr + dg*dg + db*db; bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = idx, distance
+			if distance == 0 {
+				break
+			}
+		}
+	}
+
+	return byte(best)
+}
@@ -0,0 +1,55 @@
+package d2ai
+
+// ActionType is the kind of an action a monster decides to take
+type ActionType int
+
+// Action types
+const (
+	// ActionNone keeps the monster doing what it does
+	ActionNone ActionType = iota
+	// ActionMove moves the monster to the position of the action
+	ActionMove
+	// ActionAttack attacks the target of the action in melee
+	ActionAttack
+	// ActionShoot fires the missile of the monster at the target of the action
+	ActionShoot
+)
+
+func (a ActionType) String() string {
+	switch a {
+	case ActionMove:
+		return "Move"
+	case ActionAttack:
+		return "Attack"
+	case ActionShoot:
+		return "Shoot"
+	default:
+		return "None"
+	}
+}
+
+// Action is what a monster decides to do next
+type Action struct {
+	Type     ActionType
+	X, Y     float64 // world tile position to move to or to shoot at
+	TargetID string  // the unit attacked or shot at
+	Running  bool    // true if the monster runs to the position
+}
+
+// Decision is the action a monster of a controller decided to take
+type Decision struct {
+	MonsterID string
+	Action
+}
+
+func moveTo(x, y float64, running bool) Action {
+	return Action{Type: ActionMove, X: x, Y: y, Running: running}
+}
+
+func attack(target *Unit) Action {
+	return Action{Type: ActionAttack, X: target.X, Y: target.Y, TargetID: target.ID}
+}
+
+func shoot(target *Unit) Action {
+	return Action{Type: ActionShoot, X: target.X, Y: target.Y, TargetID: target.ID}
+}
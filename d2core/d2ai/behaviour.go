@@ -0,0 +1,159 @@
+package d2ai
+
+const (
+	kiteDistance = 3.0 // tiles a ranged monster keeps between itself and its target
+	retreatTime  = 1.5 // seconds a hit and run monster keeps away after an attack
+)
+
+// Behaviour decides what a monster does next. The behaviours are registered for the AI codes of
+// monai.txt.
+type Behaviour interface {
+	Decide(monster *Monster, view *View) Action
+}
+
+// BehaviourFunc adapts a function to a Behaviour
+type BehaviourFunc func(monster *Monster, view *View) Action
+
+// Decide calls the function
+func (f BehaviourFunc) Decide(monster *Monster, view *View) Action {
+	return f(monster, view)
+}
+
+// DeathWitness is implemented by the behaviours which react to the death of a monster of the
+// same pack or a monster nearby
+type DeathWitness interface {
+	WitnessDeath(monster, dead *Monster)
+}
+
+// Stationary returns the behaviour of the monsters which never act on their own
+func Stationary() Behaviour {
+	return BehaviourFunc(func(*Monster, *View) Action {
+		return Action{}
+	})
+}
+
+// Idle returns the behaviour of the monsters which wander around and never attack
+func Idle() Behaviour {
+	return BehaviourFunc(func(m *Monster, v *View) Action {
+		return v.idle(m)
+	})
+}
+
+// Melee returns the behaviour of the monsters which walk up to their target and hit it
+func Melee() Behaviour {
+	return BehaviourFunc(melee)
+}
+
+func melee(m *Monster, v *View) Action {
+	if m.Feared() {
+		return v.flee(m, nil)
+	}
+
+	target, distance := v.Target(m)
+	if target == nil {
+		return v.idle(m)
+	}
+
+	if distance <= m.Reach {
+		return attack(target)
+	}
+
+	return moveTo(target.X, target.Y, m.CanRun)
+}
+
+// Ranged returns the behaviour of the monsters which shoot at their target from a distance and
+// back off when it comes too close. Monsters without a missile fight in melee.
+func Ranged() Behaviour {
+	return BehaviourFunc(ranged)
+}
+
+func ranged(m *Monster, v *View) Action {
+	if m.Range <= 0 {
+		return melee(m, v)
+	}
+
+	if m.Feared() {
+		return v.flee(m, nil)
+	}
+
+	target, distance := v.Target(m)
+	if target == nil {
+		return v.idle(m)
+	}
+
+	if distance < kiteDistance {
+		if action := v.flee(m, target); action.Type != ActionNone {
+			return action
+		}
+
+		// cornered
+		if distance <= m.Reach {
+			return attack(target)
+		}
+	}
+
+	if distance <= m.Range && v.Terrain.LineOfSight(m.X, m.Y, target.X, target.Y) {
+		return shoot(target)
+	}
+
+	return moveTo(target.X, target.Y, false)
+}
+
+// Hireling returns the behaviour of the monsters which follow their owner around and fight the
+// targets close to it, with their missile if they have one. They run back to their owner when
+// they get too far away from it.
+func Hireling() Behaviour {
+	return BehaviourFunc(func(m *Monster, v *View) Action {
+		if v.Owner != nil && v.Owner.Distance(m.X, m.Y) > leashDistance {
+			m.TargetID = ""
+			return moveTo(v.Owner.X, v.Owner.Y, true)
+		}
+
+		return ranged(m, v)
+	})
+}
+
+// HitAndRun returns the behaviour of the monsters which back off for a while after each hit
+func HitAndRun() Behaviour {
+	return BehaviourFunc(func(m *Monster, v *View) Action {
+		if m.retreatTime > 0 {
+			target, _ := v.Target(m)
+			return v.flee(m, target)
+		}
+
+		action := melee(m, v)
+		if action.Type == ActionAttack {
+			m.retreatTime = retreatTime
+		}
+
+		return action
+	})
+}
+
+// Cowardly returns a behaviour which flees from its target when the life of the monster drops
+// below the given fraction of its maximum life and acts like the given behaviour otherwise
+func Cowardly(behaviour Behaviour, lifeFraction float64) Behaviour {
+	return BehaviourFunc(func(m *Monster, v *View) Action {
+		if m.Life < m.MaxLife*lifeFraction {
+			return v.flee(m, nil)
+		}
+
+		return behaviour.Decide(m, v)
+	})
+}
+
+// Scared returns a behaviour which acts like the given behaviour and flees for the given number
+// of seconds when it sees a monster of its pack or nearby die
+func Scared(behaviour Behaviour, seconds float64) Behaviour {
+	return &scared{Behaviour: behaviour, seconds: seconds}
+}
+
+type scared struct {
+	Behaviour
+	seconds float64
+}
+
+// WitnessDeath scares the monster
+func (s *scared) WitnessDeath(monster, _ *Monster) {
+	monster.Scare(s.seconds)
+}
@@ -0,0 +1,65 @@
+package d2ai
+
+const (
+	fallenFearTime  = 3.0 // seconds the fallen flee after one of them died
+	lowLifeFraction = 0.25
+)
+
+// DefaultBehaviours returns the behaviours of the monai.txt AI codes known to the package, the
+// controllers start with them
+func DefaultBehaviours() map[string]Behaviour {
+	melee, ranged, hitAndRun, stationary := Melee(), Ranged(), HitAndRun(), Stationary()
+
+	behaviours := map[string]Behaviour{
+		// the town folk are moved by the paths of their DS1 files
+		"Npc":           stationary,
+		"NpcStationary": stationary,
+		"Towner":        stationary,
+		"Vendor":        stationary,
+		"Idle":          Idle(),
+
+		// the hirelings follow their players and fight the monsters around them
+		"Hireling": Hireling(),
+
+		// the fallen run away when one of them dies, their shamans cast from behind
+		"Fallen":       Scared(melee, fallenFearTime),
+		"FallenShaman": Scared(ranged, fallenFearTime),
+
+		// the animals flee when they are about to die
+		"Baboon":       Cowardly(melee, lowLifeFraction),
+		"PantherWoman": Cowardly(melee, lowLifeFraction),
+		"SandLeaper":   Cowardly(melee, lowLifeFraction),
+
+		"CorruptArcher": ranged,
+		"SkeletonBow":   ranged,
+		"QuillRat":      ranged,
+		"Arach":         ranged,
+		"SandMaggot":    ranged,
+
+		"BloodHawk": hitAndRun,
+		"Vulture":   hitAndRun,
+		"Mosquito":  hitAndRun,
+		"BatDemon":  hitAndRun,
+		"Wraith":    hitAndRun,
+	}
+
+	for _, code := range []string{"Zombie", "Skeleton", "Brute", "Goatman", "CorruptRogue", "CorruptLancer",
+		"SandRaider", "Scarab", "Mummy", "ClawViper", "ThornHulk", "Fetish", "SpikeFist", "Bighead"} {
+		behaviours[code] = melee
+	}
+
+	return behaviours
+}
+
+// fallbackBehaviour returns the behaviour of a monster with an AI code without a registered
+// behaviour, it is chosen from the monstats.txt record of the monster
+func fallbackBehaviour(m *Monster) Behaviour {
+	switch {
+	case !m.hostile():
+		return Stationary()
+	case m.Range > 0:
+		return Ranged()
+	default:
+		return Melee()
+	}
+}
@@ -0,0 +1,141 @@
+package d2ai
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// witnessDistance is how far, in tiles, from a dying monster the monsters outside its pack
+// notice its death
+const witnessDistance = 10.0
+
+// Controller runs the AI of the monsters on a map
+type Controller struct {
+	terrain    Terrain
+	behaviours map[string]Behaviour
+	monsters   map[string]*Monster
+	rand       *rand.Rand
+}
+
+// NewController creates a controller for the monsters on the given terrain with the default
+// behaviours. Controllers with the same seed make the same decisions.
+func NewController(terrain Terrain, seed int64) *Controller {
+	return &Controller{
+		terrain:    terrain,
+		behaviours: DefaultBehaviours(),
+		monsters:   make(map[string]*Monster),
+		rand:       rand.New(rand.NewSource(seed)), //nolint:gosec // the AI does not need a secure source
+	}
+}
+
+// Register sets the behaviour of the monsters with the given monai.txt AI code
+func (c *Controller) Register(aiCode string, behaviour Behaviour) {
+	c.behaviours[aiCode] = behaviour
+}
+
+// Add adds a monster to the controller
+func (c *Controller) Add(monster *Monster) {
+	c.monsters[monster.ID] = monster
+}
+
+// Monster returns the monster with the given ID, nil if the controller has no such monster
+func (c *Controller) Monster(id string) *Monster {
+	return c.monsters[id]
+}
+
+// Remove removes the monster with the given ID
+func (c *Controller) Remove(id string) {
+	delete(c.monsters, id)
+}
+
+// Kill removes the monster with the given ID and lets the monsters of its pack and the monsters
+// nearby witness its death
+func (c *Controller) Kill(id string) {
+	dead, found := c.monsters[id]
+	if !found {
+		return
+	}
+
+	dead.Life = 0
+
+	delete(c.monsters, id)
+
+	for _, monster := range c.sorted() {
+		witness, ok := c.behaviour(monster).(DeathWitness)
+		if !ok {
+			continue
+		}
+
+		if samePack(monster, dead) || monster.Distance(dead.X, dead.Y) <= witnessDistance {
+			witness.WitnessDeath(monster, dead)
+		}
+	}
+}
+
+// Advance advances the timers of the monsters by the given number of seconds and returns the
+// decisions of the monsters whose turn it is to think. The monsters update their positions
+// themselves, the targets are the units the monsters may attack.
+func (c *Controller) Advance(elapsed float64, targets []*Unit) []Decision {
+	return c.AdvanceOwned(elapsed, targets, nil)
+}
+
+// AdvanceOwned advances the monsters like Advance, the owners are the units the monsters with an
+// owner, like the hirelings, follow around
+func (c *Controller) AdvanceOwned(elapsed float64, targets, owners []*Unit) []Decision {
+	decisions := make([]Decision, 0)
+
+	for _, monster := range c.sorted() {
+		if !monster.Alive() || !monster.advance(elapsed) {
+			continue
+		}
+
+		view := &View{
+			Terrain: c.terrain,
+			Targets: targets,
+			Rand:    c.rand,
+		}
+
+		if leader, found := c.monsters[monster.LeaderID]; found && leader.Alive() {
+			view.Leader = leader
+		}
+
+		if monster.OwnerID != "" {
+			view.Owner = findUnit(owners, monster.OwnerID)
+		}
+
+		action := c.behaviour(monster).Decide(monster, view)
+		if action.Type != ActionNone {
+			decisions = append(decisions, Decision{MonsterID: monster.ID, Action: action})
+		}
+	}
+
+	return decisions
+}
+
+func (c *Controller) behaviour(monster *Monster) Behaviour {
+	if behaviour, found := c.behaviours[monster.AI]; found {
+		return behaviour
+	}
+
+	return fallbackBehaviour(monster)
+}
+
+// sorted returns the monsters ordered by ID, so that the decisions do not depend on the order
+// of the map
+func (c *Controller) sorted() []*Monster {
+	monsters := make([]*Monster, 0, len(c.monsters))
+	for _, monster := range c.monsters {
+		monsters = append(monsters, monster)
+	}
+
+	sort.Slice(monsters, func(i, j int) bool {
+		return monsters[i].ID < monsters[j].ID
+	})
+
+	return monsters
+}
+
+// samePack returns true if one monster leads the other or both follow the same leader
+func samePack(a, b *Monster) bool {
+	return a.LeaderID == b.ID || b.LeaderID == a.ID || (a.LeaderID != "" && a.LeaderID == b.LeaderID)
+}
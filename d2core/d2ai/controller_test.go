@@ -0,0 +1,264 @@
+package d2ai
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const testTickTime = 1.0
+
+func newTestMonster(terrain gridTerrain, id string, char byte, record *d2records.MonStatRecord) *Monster {
+	x, y := terrain.find(char)
+
+	return NewMonster(id, record, nil, d2enum.DifficultyNormal, x, y)
+}
+
+func newTestTarget(terrain gridTerrain, id string, char byte) *Unit {
+	x, y := terrain.find(char)
+
+	return &Unit{ID: id, X: x, Y: y, Life: 10, MaxLife: 10}
+}
+
+func decisionOf(decisions []Decision, id string) Action {
+	for _, decision := range decisions {
+		if decision.MonsterID == id {
+			return decision.Action
+		}
+	}
+
+	return Action{}
+}
+
+func TestMeleeApproachesAndAttacks(t *testing.T) {
+	terrain := newGridTerrain(
+		"..........",
+		".m......p.",
+		"..........",
+	)
+
+	controller := NewController(terrain, 1)
+	monster := newTestMonster(terrain, "zombie", 'm', &d2records.MonStatRecord{AiKey: "Zombie"})
+	player := newTestTarget(terrain, "player", 'p')
+
+	controller.Add(monster)
+
+	action := decisionOf(controller.Advance(testTickTime, []*Unit{player}), monster.ID)
+	if action.Type != ActionMove || action.X != player.X || action.Y != player.Y {
+		t.Fatalf("expected the monster to walk to the player, got %+v", action)
+	}
+
+	monster.X = player.X - 1
+
+	action = decisionOf(controller.Advance(testTickTime, []*Unit{player}), monster.ID)
+	if action.Type != ActionAttack || action.TargetID != player.ID {
+		t.Fatalf("expected the monster to attack the player, got %+v", action)
+	}
+}
+
+func TestWallsBlockSight(t *testing.T) {
+	terrain := newGridTerrain(
+		"....#.....",
+		".m..#...p.",
+		"....#.....",
+	)
+
+	controller := NewController(terrain, 1)
+	monster := newTestMonster(terrain, "zombie", 'm', &d2records.MonStatRecord{AiKey: "Zombie"})
+	player := newTestTarget(terrain, "player", 'p')
+
+	controller.Add(monster)
+
+	for tick := 0; tick < 20; tick++ {
+		action := decisionOf(controller.Advance(testTickTime, []*Unit{player}), monster.ID)
+
+		if action.TargetID != "" || monster.TargetID != "" {
+			t.Fatalf("monster noticed the player through a wall: %+v", action)
+		}
+
+		if action.Type == ActionMove && !terrain.Walkable(action.X, action.Y) {
+			t.Fatalf("monster wandered into a wall: %+v", action)
+		}
+	}
+}
+
+func TestRangedKitesAndShoots(t *testing.T) {
+	terrain := newGridTerrain(
+		"..........",
+		"...mp.....",
+		"..........",
+	)
+
+	controller := NewController(terrain, 1)
+	monster := newTestMonster(terrain, "archer", 'm', &d2records.MonStatRecord{AiKey: "SkeletonBow", MissileA1: "arrow"})
+	player := newTestTarget(terrain, "player", 'p')
+
+	controller.Add(monster)
+
+	action := decisionOf(controller.Advance(testTickTime, []*Unit{player}), monster.ID)
+	if action.Type != ActionMove || player.Distance(action.X, action.Y) <= player.Distance(monster.X, monster.Y) {
+		t.Fatalf("expected the monster to back off, got %+v", action)
+	}
+
+	monster.X, monster.Y = action.X, action.Y
+
+	action = decisionOf(controller.Advance(testTickTime, []*Unit{player}), monster.ID)
+	if action.Type != ActionShoot || action.TargetID != player.ID {
+		t.Fatalf("expected the monster to shoot at the player, got %+v", action)
+	}
+}
+
+func TestFallenFleeWhenPackMemberDies(t *testing.T) {
+	terrain := newGridTerrain(
+		"..........",
+		"..l.f.p...",
+		"..........",
+	)
+
+	controller := NewController(terrain, 1)
+	leader := newTestMonster(terrain, "leader", 'l', &d2records.MonStatRecord{AiKey: "Fallen"})
+	fallen := newTestMonster(terrain, "fallen", 'f', &d2records.MonStatRecord{AiKey: "Fallen"})
+	player := newTestTarget(terrain, "player", 'p')
+
+	fallen.LeaderID = leader.ID
+
+	controller.Add(leader)
+	controller.Add(fallen)
+	controller.Kill(leader.ID)
+
+	if !fallen.Feared() {
+		t.Fatal("expected the fallen to be scared by the death of its leader")
+	}
+
+	action := decisionOf(controller.Advance(testTickTime, []*Unit{player}), fallen.ID)
+	if action.Type != ActionMove || player.Distance(action.X, action.Y) <= player.Distance(fallen.X, fallen.Y) {
+		t.Fatalf("expected the fallen to flee, got %+v", action)
+	}
+}
+
+func TestMinionsFollowTheirLeader(t *testing.T) {
+	terrain := newGridTerrain(
+		"....#.....",
+		".n..#.l.p.",
+		"....#.....",
+		"..........",
+	)
+
+	controller := NewController(terrain, 1)
+	leader := newTestMonster(terrain, "leader", 'l', &d2records.MonStatRecord{AiKey: "Goatman"})
+	minion := newTestMonster(terrain, "minion", 'n', &d2records.MonStatRecord{AiKey: "Goatman"})
+	player := newTestTarget(terrain, "player", 'p')
+
+	minion.LeaderID = leader.ID
+
+	controller.Add(leader)
+	controller.Add(minion)
+
+	action := decisionOf(controller.Advance(testTickTime, nil), minion.ID)
+	if action.Type != ActionMove || action.X != leader.X || action.Y != leader.Y {
+		t.Fatalf("expected the minion to follow its leader, got %+v", action)
+	}
+
+	decisions := controller.Advance(testTickTime, []*Unit{player})
+
+	if leader.TargetID != player.ID {
+		t.Fatalf("expected the leader to notice the player, got %+v", decisionOf(decisions, leader.ID))
+	}
+
+	action = decisionOf(decisions, minion.ID)
+	if action.Type != ActionMove || action.TargetID != "" || minion.TargetID != player.ID {
+		t.Fatalf("expected the minion to go after the target of its leader, got %+v", action)
+	}
+}
+
+func TestHitAndRun(t *testing.T) {
+	terrain := newGridTerrain(
+		"..........",
+		"....mp....",
+		"..........",
+	)
+
+	controller := NewController(terrain, 1)
+	monster := newTestMonster(terrain, "hawk", 'm', &d2records.MonStatRecord{AiKey: "BloodHawk"})
+	player := newTestTarget(terrain, "player", 'p')
+
+	controller.Add(monster)
+
+	action := decisionOf(controller.Advance(testTickTime, []*Unit{player}), monster.ID)
+	if action.Type != ActionAttack {
+		t.Fatalf("expected the monster to attack, got %+v", action)
+	}
+
+	action = decisionOf(controller.Advance(retreatTime/2, []*Unit{player}), monster.ID)
+	if action.Type != ActionMove || player.Distance(action.X, action.Y) <= player.Distance(monster.X, monster.Y) {
+		t.Fatalf("expected the monster to back off after its attack, got %+v", action)
+	}
+}
+
+func TestRegisteredAndFallbackBehaviours(t *testing.T) {
+	terrain := newGridTerrain(
+		"..........",
+		".a.b...p..",
+		"..........",
+	)
+
+	controller := NewController(terrain, 1)
+	custom := newTestMonster(terrain, "custom", 'a', &d2records.MonStatRecord{AiKey: "Custom"})
+	neutral := newTestMonster(terrain, "neutral", 'b', &d2records.MonStatRecord{AiKey: "Unknown",
+		Alignment: d2enum.MonsterNeutral})
+	player := newTestTarget(terrain, "player", 'p')
+
+	controller.Register("Custom", BehaviourFunc(func(m *Monster, v *View) Action {
+		return Action{Type: ActionShoot, TargetID: "custom"}
+	}))
+
+	controller.Add(custom)
+	controller.Add(neutral)
+
+	decisions := controller.Advance(testTickTime, []*Unit{player})
+
+	if action := decisionOf(decisions, custom.ID); action.TargetID != "custom" {
+		t.Errorf("expected the registered behaviour to decide, got %+v", action)
+	}
+
+	if action := decisionOf(decisions, neutral.ID); action.Type != ActionNone {
+		t.Errorf("expected a neutral monster to stay put, got %+v", action)
+	}
+}
+
+func TestHirelingFollowsItsOwnerAndFights(t *testing.T) {
+	terrain := newGridTerrain(
+		"....................",
+		".h............p..m..",
+		"....................",
+	)
+
+	controller := NewController(terrain, 1)
+	hireling := newTestMonster(terrain, "hireling", 'h', &d2records.MonStatRecord{})
+	hireling.AI = "Hireling"
+	hireling.OwnerID = "player"
+	owner := newTestTarget(terrain, "player", 'p')
+	monster := newTestTarget(terrain, "monster", 'm')
+
+	controller.Add(hireling)
+
+	action := decisionOf(controller.AdvanceOwned(testTickTime, []*Unit{monster}, []*Unit{owner}), hireling.ID)
+	if action.Type != ActionMove || action.X != owner.X || !action.Running {
+		t.Fatalf("expected the hireling to run back to its owner, got %+v", action)
+	}
+
+	hireling.X = owner.X - 1
+
+	action = decisionOf(controller.AdvanceOwned(testTickTime, []*Unit{monster}, []*Unit{owner}), hireling.ID)
+	if action.Type != ActionMove || action.X != monster.X {
+		t.Fatalf("expected the hireling to go after the monster, got %+v", action)
+	}
+
+	monster.Life = 0
+
+	action = decisionOf(controller.AdvanceOwned(testTickTime, []*Unit{monster}, []*Unit{owner}), hireling.ID)
+	if action.Type != ActionNone {
+		t.Fatalf("expected the hireling to stay by its owner, got %+v", action)
+	}
+}
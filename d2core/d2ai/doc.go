@@ -0,0 +1,5 @@
+// Package d2ai provides the artificial intelligence of the monsters. A Controller decides what
+// each monster does next from its monstats.txt record and the behaviour registered for its
+// monai.txt AI code. It only needs to know what the terrain looks like, so it runs on the game
+// server as well as in tests without graphics.
+package d2ai
@@ -0,0 +1,54 @@
+package d2ai
+
+import (
+	"math"
+	"strings"
+)
+
+// gridTerrain is a terrain drawn with text, every character is a tile and '#' is a wall which
+// blocks walking and sight
+type gridTerrain []string
+
+func newGridTerrain(rows ...string) gridTerrain {
+	return gridTerrain(rows)
+}
+
+func (g gridTerrain) wall(x, y float64) bool {
+	tileX, tileY := int(math.Floor(x)), int(math.Floor(y))
+
+	if tileX < 0 || tileY < 0 || tileY >= len(g) || tileX >= len(g[tileY]) {
+		return true
+	}
+
+	return g[tileY][tileX] == '#'
+}
+
+// find returns the middle of the first tile with the given character
+func (g gridTerrain) find(char byte) (x, y float64) {
+	for row, line := range g {
+		if col := strings.IndexByte(line, char); col >= 0 {
+			return float64(col) + 0.5, float64(row) + 0.5 //nolint:gomnd // middle of the tile
+		}
+	}
+
+	return -1, -1
+}
+
+func (g gridTerrain) Walkable(x, y float64) bool {
+	return !g.wall(x, y)
+}
+
+func (g gridTerrain) LineOfSight(fromX, fromY, toX, toY float64) bool {
+	const stepsPerTile = 4
+
+	steps := int(math.Hypot(toX-fromX, toY-fromY)*stepsPerTile) + 1
+
+	for step := 0; step <= steps; step++ {
+		t := float64(step) / float64(steps)
+		if g.wall(fromX+(toX-fromX)*t, fromY+(toY-fromY)*t) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,131 @@
+package d2ai
+
+import (
+	"math"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const (
+	framesPerSecond = 25
+
+	// baseThinkFrames are the frames every monster waits between two decisions, aidel is added
+	baseThinkFrames = 5
+
+	defaultSight = 15.0 // tiles within which a monster without an aidist notices its targets
+	baseReach    = 1.5  // tiles within which a monster without a melee range hits its target
+	missileRange = 10.0 // tiles within which a monster with a missile shoots at its target
+
+	subtilesPerTile = 5
+)
+
+// Unit is something a monster can attack, usually a player
+type Unit struct {
+	ID      string
+	X, Y    float64 // world tile position
+	Life    float64
+	MaxLife float64
+}
+
+// Alive returns true if the unit has life left
+func (u *Unit) Alive() bool {
+	return u.Life > 0
+}
+
+// Distance returns the distance, in tiles, between the unit and the given position
+func (u *Unit) Distance(x, y float64) float64 {
+	return math.Hypot(u.X-x, u.Y-y)
+}
+
+// Monster is the state the AI keeps of a monster
+type Monster struct {
+	Unit
+	Record       *d2records.MonStatRecord
+	AI           string  // the monai.txt AI code
+	LeaderID     string  // the leader of the pack of the monster, empty if it leads or has no pack
+	OwnerID      string  // the unit the monster follows around, empty if it belongs to no one
+	HomeX, HomeY float64 // where the monster spawned, it wanders around it
+	Delay        float64 // seconds between two decisions
+	Sight        float64 // tiles within which the monster notices its targets
+	Reach        float64 // tiles within which the monster hits its target in melee
+	Range        float64 // tiles within which the monster shoots at its target, 0 if it has no missile
+	CanRun       bool    // true if the monster runs when it chases a target
+	TargetID     string  // the unit the monster is after
+
+	thinkTime   float64 // seconds until the next decision
+	fearTime    float64 // seconds the monster keeps fleeing
+	retreatTime float64 // seconds the monster keeps away after a hit and run attack
+}
+
+// NewMonster creates the AI state of a monster with the given records at the given world tile
+// position. The monster gets the highest life of its difficulty, callers rolling the life of
+// their monsters set it afterwards.
+func NewMonster(id string, record *d2records.MonStatRecord, stats2 *d2records.MonStat2Record,
+	difficulty d2enum.DifficultyType, x, y float64) *Monster {
+	m := &Monster{
+		Unit:   Unit{ID: id, X: x, Y: y, Life: 1, MaxLife: 1},
+		Record: record,
+		HomeX:  x,
+		HomeY:  y,
+		Delay:  float64(baseThinkFrames) / framesPerSecond,
+		Sight:  defaultSight,
+		Reach:  baseReach,
+	}
+
+	if stats2 != nil {
+		m.Reach += float64(stats2.MeleeRng) / subtilesPerTile
+	}
+
+	if record == nil {
+		return m
+	}
+
+	m.AI = record.AiKey
+	m.Delay = float64(baseThinkFrames+record.AIDelay(difficulty)) / framesPerSecond
+	m.CanRun = record.SpeedRun > 0
+
+	if sight := record.AIDistance(difficulty); sight > 0 {
+		m.Sight = float64(sight)
+	}
+
+	if record.MissileA1 != "" {
+		m.Range = missileRange
+	}
+
+	if _, maxLife := record.LifeRange(difficulty); maxLife > 0 {
+		m.Life, m.MaxLife = float64(maxLife), float64(maxLife)
+	}
+
+	return m
+}
+
+// Scare makes the monster flee for the given number of seconds
+func (m *Monster) Scare(seconds float64) {
+	m.fearTime = math.Max(m.fearTime, seconds)
+}
+
+// Feared returns true if the monster is fleeing
+func (m *Monster) Feared() bool {
+	return m.fearTime > 0
+}
+
+// hostile returns true if the monster attacks players
+func (m *Monster) hostile() bool {
+	return m.Record == nil || m.Record.IsHostile()
+}
+
+// advance counts down the timers of the monster and returns true if it is time for a decision
+func (m *Monster) advance(elapsed float64) bool {
+	m.fearTime = math.Max(m.fearTime-elapsed, 0)
+	m.retreatTime = math.Max(m.retreatTime-elapsed, 0)
+	m.thinkTime -= elapsed
+
+	if m.thinkTime > 0 {
+		return false
+	}
+
+	m.thinkTime = m.Delay
+
+	return true
+}
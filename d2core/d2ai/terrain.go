@@ -0,0 +1,10 @@
+package d2ai
+
+// Terrain tells the monsters where they can walk and see. The positions are world tile
+// positions.
+type Terrain interface {
+	// Walkable returns true if a monster can stand at the given position
+	Walkable(x, y float64) bool
+	// LineOfSight returns true if nothing blocks the sight between the two positions
+	LineOfSight(fromX, fromY, toX, toY float64) bool
+}
@@ -0,0 +1,142 @@
+package d2ai
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	chaseFactor = 1.5 // a target is chased until it is this many times the sight away
+
+	followDistance = 3.0  // tiles a minion keeps to its leader
+	leashDistance  = 12.0 // tiles an owned monster goes away from its owner before it runs back
+	wanderRadius   = 4.0  // tiles a monster without a target wanders from its home
+	wanderChance   = 10   // percent chance per decision to wander off
+	fleeDistance   = 4.0  // tiles a monster flees per decision, less when a wall is in the way
+)
+
+// fleeAngles are the directions, relative to straight away from the threat, tried by a fleeing
+// monster which runs into a wall
+var fleeAngles = []float64{0, math.Pi / 4, -math.Pi / 4, math.Pi / 2, -math.Pi / 2} //nolint:gochecknoglobals // lookup
+
+// View is what a monster knows about its surroundings when it decides what to do
+type View struct {
+	Terrain Terrain
+	Targets []*Unit
+	Leader  *Monster // the leader of the pack of the monster, nil if it has none or it is dead
+	Owner   *Unit    // the unit the monster follows around, nil if it has none
+	Rand    *rand.Rand
+}
+
+// Target returns the unit the monster is after and its distance. A monster keeps chasing its
+// target, follows the target of its leader or picks the closest target it can see.
+func (v *View) Target(m *Monster) (target *Unit, distance float64) {
+	if target := v.unit(m.TargetID); target != nil {
+		if distance := target.Distance(m.X, m.Y); distance <= m.Sight*chaseFactor {
+			return target, distance
+		}
+	}
+
+	if v.Leader != nil {
+		if target := v.unit(v.Leader.TargetID); target != nil {
+			m.TargetID = target.ID
+			return target, target.Distance(m.X, m.Y)
+		}
+	}
+
+	m.TargetID = ""
+	distance = math.Inf(1)
+
+	for _, unit := range v.Targets {
+		unitDistance := unit.Distance(m.X, m.Y)
+
+		if !unit.Alive() || unitDistance > m.Sight || unitDistance >= distance || !v.Terrain.LineOfSight(m.X, m.Y, unit.X, unit.Y) {
+			continue
+		}
+
+		target, distance = unit, unitDistance
+	}
+
+	if target != nil {
+		m.TargetID = target.ID
+	}
+
+	return target, distance
+}
+
+func (v *View) unit(id string) *Unit {
+	return findUnit(v.Targets, id)
+}
+
+// findUnit returns the living unit with the given ID, nil if there is none
+func findUnit(units []*Unit, id string) *Unit {
+	if id == "" {
+		return nil
+	}
+
+	for _, unit := range units {
+		if unit.ID == id && unit.Alive() {
+			return unit
+		}
+	}
+
+	return nil
+}
+
+// idle follows the owner or the leader of the monster or wanders around its home
+func (v *View) idle(m *Monster) Action {
+	if v.Owner != nil {
+		if v.Owner.Distance(m.X, m.Y) <= followDistance {
+			return Action{}
+		}
+
+		return moveTo(v.Owner.X, v.Owner.Y, m.CanRun)
+	}
+
+	if v.Leader != nil {
+		if v.Leader.Distance(m.X, m.Y) <= followDistance {
+			return Action{}
+		}
+
+		return moveTo(v.Leader.X, v.Leader.Y, m.CanRun)
+	}
+
+	if v.Rand.Intn(100) >= wanderChance { //nolint:gomnd // percent
+		return Action{}
+	}
+
+	x := m.HomeX + (v.Rand.Float64()*2-1)*wanderRadius
+	y := m.HomeY + (v.Rand.Float64()*2-1)*wanderRadius
+
+	if !v.Terrain.Walkable(x, y) {
+		return Action{}
+	}
+
+	return moveTo(x, y, false)
+}
+
+// flee moves the monster away from the given unit, or away from its target if there is none
+func (v *View) flee(m *Monster, threat *Unit) Action {
+	if threat == nil {
+		threat, _ = v.Target(m)
+	}
+
+	if threat == nil {
+		return Action{}
+	}
+
+	away := math.Atan2(m.Y-threat.Y, m.X-threat.X)
+
+	for distance := fleeDistance; distance >= 1; distance /= 2 {
+		for _, angle := range fleeAngles {
+			x := m.X + math.Cos(away+angle)*distance
+			y := m.Y + math.Sin(away+angle)*distance
+
+			if v.Terrain.Walkable(x, y) {
+				return moveTo(x, y, m.CanRun)
+			}
+		}
+	}
+
+	return Action{}
+}
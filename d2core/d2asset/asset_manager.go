@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image/color"
 	"strconv"
+	"strings"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2resource"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
@@ -35,6 +36,11 @@ const (
 	paletteTransformBudget = 64
 )
 
+const (
+	txtExtension = ".txt"
+	binExtension = ".bin"
+)
+
 const (
 	defaultLanguage    = "ENG"
 	logPrefix          = "Asset Manager"
@@ -353,10 +359,24 @@ func (am *AssetManager) LoadDataDictionary(path string) (*d2txt.DataDictionary,
 	return d2txt.LoadDataDictionary(data), nil
 }
 
+// LoadBinDataDictionary loads a compiled .bin data file, the records laid out as in the schema
+func (am *AssetManager) LoadBinDataDictionary(path string, schema *d2txt.BinSchema) (*d2txt.DataDictionary, error) {
+	data, err := am.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	am.Debugf(fmtLoadDict, path)
+
+	return d2txt.LoadBinDataDictionary(data, schema)
+}
+
 // LoadRecords will load the records for the given path into the record manager.
 // This is dependant on the record manager having bound a loader for the given path.
+// When the txt file is missing, the records are loaded from its compiled .bin file
+// if the record manager has the schema of the table.
 func (am *AssetManager) LoadRecords(path string) error {
-	dict, err := am.LoadDataDictionary(path)
+	dict, err := am.loadRecordsDictionary(path)
 	if err != nil {
 		return err
 	}
@@ -369,6 +389,24 @@ func (am *AssetManager) LoadRecords(path string) error {
 	return nil
 }
 
+func (am *AssetManager) loadRecordsDictionary(path string) (*d2txt.DataDictionary, error) {
+	schema, found := am.Records.BinSchema(path)
+	if !found {
+		dict, err := am.LoadDataDictionary(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s cannot be loaded and has no .bin schema: %w", path, err)
+		}
+
+		return dict, nil
+	}
+
+	if exists, err := am.FileExists(path); err != nil || !exists {
+		return am.LoadBinDataDictionary(strings.TrimSuffix(path, txtExtension)+binExtension, schema)
+	}
+
+	return am.LoadDataDictionary(path)
+}
+
 // loadDC6 creates an Animation from d2dc6.DC6 and d2dat.DATPalette
 func (am *AssetManager) loadDC6(path string,
 	palette d2interface.Palette, effect d2enum.DrawEffect) (d2interface.Animation, error) {
@@ -0,0 +1,258 @@
+package d2automap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+// tileNames are the TileName values of AutoMap.txt, by the tile type they stand for
+var tileNames = [...]string{ //nolint:gochecknoglobals // lookup table
+	d2enum.TileFloor:                                          "fl",
+	d2enum.TileLeftWall:                                       "wl",
+	d2enum.TileRightWall:                                      "wr",
+	d2enum.TileRightPartOfNorthCornerWall:                     "wtlr",
+	d2enum.TileLeftPartOfNorthCornerWall:                      "wtll",
+	d2enum.TileLeftEndWall:                                    "wtr",
+	d2enum.TileRightEndWall:                                   "wbl",
+	d2enum.TileSouthCornerWall:                                "wbr",
+	d2enum.TileLeftWallWithDoor:                               "wld",
+	d2enum.TileRightWallWithDoor:                              "wrd",
+	d2enum.TileSpecialTile1:                                   "wle",
+	d2enum.TileSpecialTile2:                                   "wre",
+	d2enum.TilePillarsColumnsAndStandaloneObjects:             "co",
+	d2enum.TileShadow:                                         "sh",
+	d2enum.TileTree:                                           "tr",
+	d2enum.TileRoof:                                           "rf",
+	d2enum.TileLowerWallsEquivalentToLeftWall:                 "ld",
+	d2enum.TileLowerWallsEquivalentToRightWall:                "lr",
+	d2enum.TileLowerWallsEquivalentToRightLeftNorthCornerWall: "lf",
+	d2enum.TileLowerWallsEquivalentToSouthCornerwall:          "ls",
+}
+
+// Cell is a frame of the MaxiMap.dc6 of an act drawn for a tile
+type Cell struct {
+	TileX, TileY int
+	Act          int // 1 to 5
+	Frame        int
+}
+
+// Exit is a level exit of the map
+type Exit struct {
+	X, Y    float64 // tile position of the middle of the exit
+	LevelID int     // the level the exit leads to
+}
+
+// Automap is the automap of the map of a map engine, the cells of the explored tiles of a player
+type Automap struct {
+	engine   *d2mapengine.MapEngine
+	index    cellIndex
+	explored *Explored
+	cells    []Cell // the cells of the explored tiles, in the order the tiles were explored
+	exits    []Exit
+}
+
+// New returns the automap of the map of the given map engine with no tile explored
+func New(records *d2records.RecordManager, engine *d2mapengine.MapEngine) *Automap {
+	size := engine.Size()
+
+	return &Automap{
+		engine:   engine,
+		index:    newCellIndex(records),
+		explored: NewExplored(size.Width, size.Height, nil),
+		exits:    exits(engine.Warps()),
+	}
+}
+
+// LevelKey returns the level the explored tiles of the automap are saved by
+func (a *Automap) LevelKey() int {
+	return LevelKey(a.engine.LevelIDs())
+}
+
+// SetExplored replaces the explored tiles with the given ones
+func (a *Automap) SetExplored(bits []byte) {
+	a.explored = NewExplored(a.explored.width, a.explored.height, bits)
+	a.cells = a.cells[:0]
+
+	for tileY := 0; tileY < a.explored.height; tileY++ {
+		for tileX := 0; tileX < a.explored.width; tileX++ {
+			if a.explored.IsExplored(tileX, tileY) {
+				a.addCells(tileX, tileY)
+			}
+		}
+	}
+}
+
+// Explored returns the explored tiles
+func (a *Automap) Explored() *Explored {
+	return a.explored
+}
+
+// Explore explores the tiles around the given tile position
+func (a *Automap) Explore(tileX, tileY float64) bool {
+	return a.explored.Explore(tileX, tileY, a.addCells)
+}
+
+// Cells returns the cells of the explored tiles
+func (a *Automap) Cells() []Cell {
+	return a.cells
+}
+
+// Exits returns the level exits of the map
+func (a *Automap) Exits() []Exit {
+	return a.exits
+}
+
+func (a *Automap) addCells(tileX, tileY int) {
+	tile := a.engine.TileAt(tileX, tileY)
+	if tile == nil {
+		return
+	}
+
+	levelType := int(tile.RegionType)
+
+	for idx := range tile.Components.Floors {
+		floor := &tile.Components.Floors[idx]
+		if floor.Hidden {
+			continue
+		}
+
+		a.addCell(tileX, tileY, levelType, d2enum.TileFloor, int(floor.Style), int(floor.Sequence))
+	}
+
+	for idx := range tile.Components.Walls {
+		wall := &tile.Components.Walls[idx]
+		if wall.Hidden {
+			continue
+		}
+
+		a.addCell(tileX, tileY, levelType, wall.Type, int(wall.Style), int(wall.Sequence))
+	}
+}
+
+func (a *Automap) addCell(tileX, tileY, levelType int, tileType d2enum.TileType, style, sequence int) {
+	act, frame, found := a.index.frame(levelType, tileType, style, sequence, tileX, tileY)
+	if found {
+		a.cells = append(a.cells, Cell{TileX: tileX, TileY: tileY, Act: act, Frame: frame})
+	}
+}
+
+// levelName is the LevelName of AutoMap.txt of a level type and the act of the level type
+type levelName struct {
+	name string
+	act  int
+}
+
+// levelNames returns the LevelName values of AutoMap.txt by level type, the names of the level
+// types are like "Act 1 - Town" and the LevelName values like "1 Town"
+func levelNames(records *d2records.RecordManager) map[int]levelName {
+	names := make(map[int]levelName)
+
+	for id, levelType := range records.Level.Types {
+		if levelType == nil {
+			continue
+		}
+
+		name := levelType.Name
+		if idx := strings.Index(name, " - "); idx >= 0 {
+			name = name[idx+len(" - "):]
+		}
+
+		names[id] = levelName{name: fmt.Sprintf("%d %s", levelType.Act, name), act: levelType.Act}
+	}
+
+	return names
+}
+
+// cellKey is what the rows of AutoMap.txt are looked up by
+type cellKey struct {
+	levelName string
+	tileName  string
+	style     int
+}
+
+// cellIndex looks up the AutoMap.txt rows of the tiles
+type cellIndex struct {
+	rows       map[cellKey][]*d2records.AutoMapRecord
+	levelNames map[int]levelName
+}
+
+func newCellIndex(records *d2records.RecordManager) cellIndex {
+	index := cellIndex{
+		rows:       make(map[cellKey][]*d2records.AutoMapRecord),
+		levelNames: levelNames(records),
+	}
+
+	for _, record := range records.Level.AutoMaps {
+		key := cellKey{levelName: record.LevelName, tileName: record.TileName, style: record.Style}
+		index.rows[key] = append(index.rows[key], record)
+	}
+
+	return index
+}
+
+// frame returns the act and the MaxiMap.dc6 frame of the tile of the given level type, type,
+// style and sequence. A row may have several frames, the frame of a tile is picked by its position.
+func (c *cellIndex) frame(levelType int, tileType d2enum.TileType, style, sequence, tileX, tileY int) (act, frame int, found bool) {
+	level, found := c.levelNames[levelType]
+	if !found || int(tileType) >= len(tileNames) {
+		return 0, 0, false
+	}
+
+	key := cellKey{levelName: level.name, tileName: tileNames[tileType], style: style}
+
+	for _, record := range c.rows[key] {
+		if record.StartSequence >= 0 && (sequence < record.StartSequence || sequence > record.EndSequence) {
+			continue
+		}
+
+		frames := make([]int, 0, len(record.Frames))
+
+		for _, frame := range record.Frames {
+			if frame >= 0 {
+				frames = append(frames, frame)
+			}
+		}
+
+		if len(frames) == 0 {
+			return 0, 0, false
+		}
+
+		return level.act, frames[(tileX+tileY)%len(frames)], true
+	}
+
+	return 0, 0, false
+}
+
+// exits returns the level exits of the given warp tiles, a warp spanning several tiles is a
+// single exit in the middle of its tiles
+func exits(warps []d2mapengine.Warp) []Exit {
+	byLevel := make(map[int][]d2mapengine.Warp)
+	levelIDs := make([]int, 0)
+
+	for _, warp := range warps {
+		if _, found := byLevel[warp.LevelID]; !found {
+			levelIDs = append(levelIDs, warp.LevelID)
+		}
+
+		byLevel[warp.LevelID] = append(byLevel[warp.LevelID], warp)
+	}
+
+	result := make([]Exit, 0, len(levelIDs))
+
+	for _, levelID := range levelIDs {
+		exit := Exit{LevelID: levelID}
+
+		for _, warp := range byLevel[levelID] {
+			exit.X += warp.X / float64(len(byLevel[levelID]))
+			exit.Y += warp.Y / float64(len(byLevel[levelID]))
+		}
+
+		result = append(result, exit)
+	}
+
+	return result
+}
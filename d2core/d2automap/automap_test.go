@@ -0,0 +1,115 @@
+package d2automap
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func TestExploreRevealsTheTilesAroundThePlayer(t *testing.T) {
+	explored := NewExplored(40, 30, nil)
+
+	revealed := 0
+	if !explored.Explore(20.5, 15.5, func(int, int) { revealed++ }) {
+		t.Fatal("nothing was explored")
+	}
+
+	if !explored.IsExplored(20, 15) || !explored.IsExplored(20+RevealRadius-1, 15) {
+		t.Error("the tiles within the reveal radius are not explored")
+	}
+
+	if explored.IsExplored(20+RevealRadius+1, 15) || explored.IsExplored(20+RevealRadius, 15+RevealRadius) {
+		t.Error("the tiles beyond the reveal radius are explored")
+	}
+
+	if explored.Explore(20.5, 15.5, func(int, int) { revealed++ }) {
+		t.Error("exploring the same place again explored new tiles")
+	}
+
+	count := 0
+
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			if explored.IsExplored(x, y) {
+				count++
+			}
+		}
+	}
+
+	if count != revealed {
+		t.Errorf("%d tiles are explored, %d were reported", count, revealed)
+	}
+}
+
+func TestExploredTilesAreRestoredFromTheirBytes(t *testing.T) {
+	explored := NewExplored(17, 9, nil)
+	explored.Explore(0, 0, nil)
+
+	restored := NewExplored(17, 9, explored.Bytes())
+
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 17; x++ {
+			if restored.IsExplored(x, y) != explored.IsExplored(x, y) {
+				t.Fatalf("tile %d,%d was not restored", x, y)
+			}
+		}
+	}
+
+	if resized := NewExplored(18, 9, explored.Bytes()); resized.IsExplored(0, 0) {
+		t.Error("the tiles of a map of another size were restored")
+	}
+}
+
+func TestTilesAreLookedUpByLevelStyleAndSequence(t *testing.T) {
+	records := &d2records.RecordManager{}
+	records.Level.Types = d2records.LevelTypes{
+		nil,
+		{Name: "Act 1 - Town", ID: 1, Act: 1},
+		{Name: "Act 1 - Wilderness", ID: 2, Act: 1},
+	}
+	records.Level.AutoMaps = d2records.AutoMaps{
+		{LevelName: "1 Town", TileName: "fl", Style: 0, StartSequence: 0, EndSequence: 3, Frames: []int{10, -1, -1, -1}},
+		{LevelName: "1 Town", TileName: "wl", Style: 2, StartSequence: -1, EndSequence: -1, Frames: []int{20, 21, -1, -1}},
+		{LevelName: "1 Wilderness", TileName: "fl", Style: 0, StartSequence: 0, EndSequence: 9, Frames: []int{-1, -1, -1, -1}},
+	}
+
+	index := newCellIndex(records)
+
+	if act, frame, found := index.frame(1, d2enum.TileFloor, 0, 2, 5, 5); !found || act != 1 || frame != 10 {
+		t.Errorf("floor of the town: act %d frame %d found %v", act, frame, found)
+	}
+
+	if _, _, found := index.frame(1, d2enum.TileFloor, 0, 4, 5, 5); found {
+		t.Error("a floor beyond the sequences of the row has a cell")
+	}
+
+	if _, frame, found := index.frame(1, d2enum.TileLeftWall, 2, 7, 5, 6); !found || frame != 21 {
+		t.Errorf("the wall of every sequence has frame %d, found %v", frame, found)
+	}
+
+	if _, _, found := index.frame(2, d2enum.TileFloor, 0, 1, 0, 0); found {
+		t.Error("a row without frames has a cell")
+	}
+}
+
+func TestWarpTilesMakeOneExitForEachLevel(t *testing.T) {
+	result := exits([]d2mapengine.Warp{
+		{X: 10.5, Y: 4.5, LevelID: 3},
+		{X: 11.5, Y: 4.5, LevelID: 3},
+		{X: 40.5, Y: 20.5, LevelID: 8},
+	})
+
+	if len(result) != 2 {
+		t.Fatalf("%d exits", len(result))
+	}
+
+	if result[0].LevelID != 3 || result[0].X != 11 || result[0].Y != 4.5 {
+		t.Errorf("exit to level 3 at %g,%g", result[0].X, result[0].Y)
+	}
+
+	if result[1].LevelID != 8 || result[1].X != 40.5 {
+		t.Errorf("exit to level 8 at %g,%g", result[1].X, result[1].Y)
+	}
+}
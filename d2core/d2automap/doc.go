@@ -0,0 +1,4 @@
+// Package d2automap provides the automap of a map: the tiles a player has explored and the
+// automap cells, from the MaxiMap.dc6 of the act, AutoMap.txt draws for them. The explored tiles
+// are saved with the hero by the first level of the map, the cells are read from the records again.
+package d2automap
@@ -0,0 +1,109 @@
+package d2automap
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+)
+
+const (
+	// RevealRadius is how far, in tiles, around a player the tiles are explored
+	RevealRadius = 10
+
+	bitsPerByte = 8
+)
+
+// Explored is the tiles of a map a player has explored, one bit for each tile in rows
+type Explored struct {
+	width  int
+	height int
+	bits   []byte
+}
+
+// NewExplored returns the explored tiles of a map of the given size in tiles. The given bits are
+// used as they are if they fit the size, the explored tiles change them in place.
+func NewExplored(width, height int, bits []byte) *Explored {
+	size := (width*height + bitsPerByte - 1) / bitsPerByte
+
+	if len(bits) != size {
+		bits = make([]byte, size)
+	}
+
+	return &Explored{width: width, height: height, bits: bits}
+}
+
+// ForHero returns the explored tiles of the map of the given map engine which are saved with the
+// hero, they are added to the hero if it has not been on the map yet
+func ForHero(state *d2hero.HeroState, engine *d2mapengine.MapEngine) *Explored {
+	key := LevelKey(engine.LevelIDs())
+	size := engine.Size()
+
+	if state.Automap == nil {
+		state.Automap = make(map[int][]byte)
+	}
+
+	explored := NewExplored(size.Width, size.Height, state.Automap[key])
+	state.Automap[key] = explored.bits
+
+	return explored
+}
+
+// LevelKey returns the level the explored tiles of the map made of the given levels are saved by
+func LevelKey(levelIDs []int) int {
+	if len(levelIDs) == 0 {
+		return 0
+	}
+
+	return levelIDs[0]
+}
+
+// Bytes returns the explored tiles, one bit for each tile in rows
+func (e *Explored) Bytes() []byte {
+	return e.bits
+}
+
+// Size returns the size of the map in tiles
+func (e *Explored) Size() (width, height int) {
+	return e.width, e.height
+}
+
+// IsExplored returns true if the given tile has been explored
+func (e *Explored) IsExplored(tileX, tileY int) bool {
+	if tileX < 0 || tileY < 0 || tileX >= e.width || tileY >= e.height {
+		return false
+	}
+
+	idx := tileX + tileY*e.width
+
+	return e.bits[idx/bitsPerByte]&(1<<(idx%bitsPerByte)) != 0
+}
+
+// Explore explores the tiles within the reveal radius of the given tile position and calls the
+// given function, if any, for every tile which was not explored before. It returns true if a tile
+// was explored.
+func (e *Explored) Explore(tileX, tileY float64, onExplored func(tileX, tileY int)) bool {
+	changed := false
+	radiusSquared := float64(RevealRadius * RevealRadius)
+
+	for y := int(tileY) - RevealRadius; y <= int(tileY)+RevealRadius; y++ {
+		for x := int(tileX) - RevealRadius; x <= int(tileX)+RevealRadius; x++ {
+			if x < 0 || y < 0 || x >= e.width || y >= e.height || e.IsExplored(x, y) {
+				continue
+			}
+
+			dx, dy := float64(x)+0.5-tileX, float64(y)+0.5-tileY //nolint:gomnd // middle of the tile
+			if dx*dx+dy*dy > radiusSquared {
+				continue
+			}
+
+			idx := x + y*e.width
+			e.bits[idx/bitsPerByte] |= 1 << (idx % bitsPerByte)
+			changed = true
+
+			if onExplored != nil {
+				onExplored(x, y)
+			}
+		}
+	}
+
+	return changed
+}
@@ -0,0 +1,92 @@
+package d2combat
+
+import (
+	"sort"
+)
+
+// Afflictions keeps track of the damage over time dealt to the units of a fight. A new damage
+// over time of an element replaces the one the target suffers from if it deals more damage per
+// second.
+type Afflictions struct {
+	active map[string]map[string]*affliction // by target and element
+}
+
+type affliction struct {
+	sourceID string
+	perSec   float64
+	left     float64 // seconds
+}
+
+// NewAfflictions creates an empty set of afflictions
+func NewAfflictions() *Afflictions {
+	return &Afflictions{active: make(map[string]map[string]*affliction)}
+}
+
+// Add adds the damage over time of the event, the other events are ignored
+func (a *Afflictions) Add(event *Event) {
+	if event.Type != EventDamageOverTime || event.Duration <= 0 {
+		return
+	}
+
+	perSec := event.Amount / event.Duration
+
+	elements, found := a.active[event.TargetID]
+	if !found {
+		elements = make(map[string]*affliction)
+		a.active[event.TargetID] = elements
+	}
+
+	if current, found := elements[event.Element]; found && current.perSec > perSec {
+		return
+	}
+
+	elements[event.Element] = &affliction{sourceID: event.SourceID, perSec: perSec, left: event.Duration}
+}
+
+// Remove cures the target of its afflictions
+func (a *Afflictions) Remove(targetID string) {
+	delete(a.active, targetID)
+}
+
+// Advance deals the damage of the given number of seconds and returns it as damage events, one
+// per target and element, ordered by target
+func (a *Afflictions) Advance(elapsed float64) []Event {
+	events := make([]Event, 0)
+
+	for targetID, elements := range a.active {
+		for element, current := range elements {
+			seconds := elapsed
+			if current.left < seconds {
+				seconds = current.left
+			}
+
+			current.left -= seconds
+
+			events = append(events, Event{
+				Type:     EventDamage,
+				SourceID: current.sourceID,
+				TargetID: targetID,
+				Element:  element,
+				Amount:   current.perSec * seconds,
+			})
+
+			if current.left <= 0 {
+				delete(elements, element)
+			}
+		}
+
+		if len(elements) == 0 {
+			delete(a.active, targetID)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].TargetID != events[j].TargetID {
+			return events[i].TargetID < events[j].TargetID
+		}
+
+		return events[i].Element < events[j].Element
+	})
+
+	return events
+}
@@ -0,0 +1,102 @@
+package d2combat
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2skill"
+)
+
+// the ElemTypes.txt codes of the elements
+const (
+	ElementPhysical  = ""
+	ElementFire      = "fire"
+	ElementLightning = "ltng"
+	ElementCold      = "cold"
+	ElementPoison    = "pois"
+	ElementMagic     = "mag"
+)
+
+// Damage is a damage range of one element
+type Damage struct {
+	Element  string  // ElemTypes.txt code, empty for physical damage
+	Min, Max int     // damage dealt, over the whole duration for damage over time
+	Duration float64 // seconds the poison lasts or the cold chills, 0 for damage dealt at once
+}
+
+// Attack is an attack made with a weapon, a skill or a missile
+type Attack struct {
+	Melee      bool // melee attacks can be blocked and dodged, the other attacks blocked and avoided
+	UsesWeapon bool // weapon attacks roll to hit and add the damage of the weapon stats of the attacker
+	ToHit      int  // attack rating added by the skill
+	Damages    []Damage
+}
+
+// WeaponAttack returns a melee attack with a weapon of the given damage
+func WeaponAttack(minDamage, maxDamage int) *Attack {
+	return &Attack{
+		Melee:      true,
+		UsesWeapon: true,
+		Damages:    []Damage{{Min: minDamage, Max: maxDamage}},
+	}
+}
+
+// SkillAttack returns the attack of a skill of a caster
+func SkillAttack(skill *d2skill.Skill) *Attack {
+	record := skill.Record
+	attack := &Attack{
+		Melee:      record.Range == "m" || record.Range == "h",
+		UsesWeapon: record.SrcDam > 0,
+		ToHit:      skill.ToHit(),
+	}
+
+	minDamage, maxDamage := skill.Damage()
+	attack.add(Damage{Min: minDamage, Max: maxDamage})
+
+	minDamage, maxDamage = skill.ElementalDamage()
+	attack.add(Damage{
+		Element:  record.EType,
+		Min:      minDamage,
+		Max:      maxDamage,
+		Duration: float64(skill.ElementalLength()) / d2skill.FramesPerSecond,
+	})
+
+	return attack
+}
+
+// MissileAttack returns the attack of a missile of the given level which does the damage of its
+// own missiles.txt record
+func MissileAttack(missile *d2records.MissileRecord, level int) *Attack {
+	attack := &Attack{}
+
+	attack.add(Damage{
+		Min: d2skill.LevelDamage(missile.Damage.MinDamage, missile.Damage.MinLevelDamage, level),
+		Max: d2skill.LevelDamage(missile.Damage.MaxDamage, missile.Damage.MaxLevelDamage, level),
+	})
+
+	elemental := &missile.ElementalDamage
+
+	attack.add(Damage{
+		Element:  elemental.ElementType,
+		Min:      d2skill.LevelDamage(elemental.Damage.MinDamage, elemental.Damage.MinLevelDamage, level),
+		Max:      d2skill.LevelDamage(elemental.Damage.MaxDamage, elemental.Damage.MaxLevelDamage, level),
+		Duration: float64(elemental.Duration+d2skill.LevelDuration(elemental.LevelDuration, level)) / d2skill.FramesPerSecond,
+	})
+
+	return attack
+}
+
+// add adds a damage which does more than nothing to the attack
+func (a *Attack) add(damage Damage) {
+	if damage.Max <= 0 {
+		return
+	}
+
+	if damage.Min > damage.Max {
+		damage.Min = damage.Max
+	}
+
+	if damage.Element != ElementCold && damage.Element != ElementPoison {
+		damage.Duration = 0
+	}
+
+	a.Damages = append(a.Damages, damage)
+}
@@ -0,0 +1,27 @@
+package d2combat
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+)
+
+// Combatant is a unit taking part in a fight
+type Combatant struct {
+	ID        string
+	Level     int
+	Stats     d2stats.StatList
+	Life      float64
+	MaxLife   float64
+	Mana      float64
+	MaxMana   float64
+	IsMonster bool
+	IsBoss    bool // crushing blows take less life of bosses
+	CanBlock  bool // players need a shield to block, monsters block with the chance of their stats
+	Dodge     int  // percent chance to dodge melee attacks
+	Avoid     int  // percent chance to avoid the other attacks
+	Leech     int  // percent of the leech of the attacker which works on the combatant, 100 for players
+}
+
+// Alive returns true if the combatant has life left
+func (c *Combatant) Alive() bool {
+	return c.Life > 0
+}
@@ -0,0 +1,5 @@
+// Package d2combat resolves attacks: whether an attack hits, is blocked or dodged, and how much
+// physical, elemental and over time damage it does after the resistances of the defender. The
+// stats of the attacker and the defender are read from their stat lists, the outcome of an
+// attack is a list of events the game server broadcasts to its clients.
+package d2combat
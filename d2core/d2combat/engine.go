@@ -0,0 +1,326 @@
+package d2combat
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2skill"
+)
+
+const (
+	percent = 100
+
+	minHitChance = 5
+	maxHitChance = 95
+
+	// blockDexterityBase is the dexterity below which a player cannot block
+	blockDexterityBase = 15
+	maxBlockChance     = 75
+
+	maxPhysicalResist  = 50 // highest physical resistance of a player
+	maxElementalResist = 75 // highest elemental resistance of a player before the maximum resistance stats
+	maxResistCap       = 95 // highest elemental resistance of a player with the maximum resistance stats
+	minResist          = -100
+
+	// crushingBlowPlayer, crushingBlowMonster and crushingBlowBoss are the fractions of their
+	// current life a crushing blow takes from the defenders, ranged crushing blows take half
+	crushingBlowPlayer  = 0.1
+	crushingBlowMonster = 0.25
+	crushingBlowBoss    = 0.125
+	crushingBlowRanged  = 0.5
+)
+
+// Engine resolves the attacks of a fight
+type Engine struct {
+	elementNames map[string]string // ElemTypes.txt names by code
+	rand         *rand.Rand
+}
+
+// NewEngine creates an engine which looks up the resistances of the elements in the given
+// ElemTypes.txt records. Engines with the same seed roll the same outcomes.
+func NewEngine(elemTypes d2records.ElemTypes, seed int64) *Engine {
+	engine := &Engine{
+		elementNames: make(map[string]string, len(elemTypes)),
+		rand:         rand.New(rand.NewSource(seed)), //nolint:gosec // combat does not need a secure source
+	}
+
+	for _, record := range elemTypes {
+		engine.elementNames[record.Code] = record.ElemType
+	}
+
+	return engine
+}
+
+// Resolve makes the attacker attack the defender. The life of the defender and the life and mana
+// the attacker leeches are updated, the returned events tell what happened.
+func (e *Engine) Resolve(attacker, defender *Combatant, attack *Attack) []Event {
+	newEvent := func(eventType EventType) Event {
+		return Event{Type: eventType, SourceID: attacker.ID, TargetID: defender.ID}
+	}
+
+	if attack.UsesWeapon {
+		if !e.chance(e.HitChance(attacker, defender, attack)) {
+			return []Event{newEvent(EventMiss)}
+		}
+
+		if defender.CanBlock && e.chance(BlockChance(defender)) {
+			return []Event{newEvent(EventBlock)}
+		}
+
+		avoid := defender.Avoid
+		if attack.Melee {
+			avoid = defender.Dodge
+		}
+
+		if e.chance(avoid) {
+			return []Event{newEvent(EventDodge)}
+		}
+	}
+
+	events := make([]Event, 0)
+	dealt, physical := 0.0, 0.0
+
+	for _, damage := range e.damages(attacker, attack) {
+		amount := float64(e.between(damage.Min, damage.Max))
+
+		if damage.Element == ElementPhysical && attack.UsesWeapon {
+			amount *= float64(percent+statValue(attacker.Stats, StatDamageBonus)) / percent
+
+			if e.chance(statValue(attacker.Stats, StatDeadlyStrike)) {
+				amount *= 2
+				events = append(events, newEvent(EventDeadlyStrike))
+			}
+		}
+
+		amount = e.reduce(defender, damage.Element, amount)
+		if amount <= 0 {
+			continue
+		}
+
+		event := newEvent(EventDamage)
+		event.Element, event.Amount = damage.Element, amount
+
+		switch {
+		case damage.Element == ElementPoison && damage.Duration > 0:
+			event.Type, event.Duration = EventDamageOverTime, damage.Duration
+			events = append(events, event)
+
+			continue
+		case damage.Element == ElementCold && damage.Duration > 0:
+			chill := newEvent(EventChill)
+			chill.Element, chill.Duration = damage.Element, damage.Duration
+			events = append(events, chill)
+		}
+
+		dealt += amount
+		events = append(events, event)
+
+		if damage.Element == ElementPhysical {
+			physical += amount
+		}
+	}
+
+	if attack.UsesWeapon && e.chance(statValue(attacker.Stats, StatCrushingBlow)) {
+		event := newEvent(EventCrushingBlow)
+		event.Amount = defender.Life * crushingBlowFraction(defender, attack)
+		dealt += event.Amount
+		events = append(events, event)
+	}
+
+	defender.Life = math.Max(defender.Life-dealt, 0)
+	events = append(events, e.leech(attacker, defender, physical)...)
+
+	if !defender.Alive() {
+		events = append(events, newEvent(EventDeath))
+	}
+
+	return events
+}
+
+// HitChance returns the percent chance of the attack of the attacker to hit the defender, it
+// compares the attack rating of the attacker to the defense of the defender and their levels
+func (e *Engine) HitChance(attacker, defender *Combatant, attack *Attack) int {
+	rating := float64(statValue(attacker.Stats, StatAttackRating)+attack.ToHit) *
+		float64(percent+statValue(attacker.Stats, StatAttackRatingBonus)) / percent
+	defense := float64(statValue(defender.Stats, StatDefense)) *
+		float64(percent+statValue(defender.Stats, StatDefenseBonus)) / percent
+
+	switch {
+	case rating <= 0:
+		return minHitChance
+	case defense <= 0:
+		return maxHitChance
+	}
+
+	levels := 0.5 //nolint:gomnd // equal levels
+	if attacker.Level+defender.Level > 0 {
+		levels = float64(attacker.Level) / float64(attacker.Level+defender.Level)
+	}
+
+	chance := int(2 * percent * rating / (rating + defense) * levels) //nolint:gomnd // 200% at most
+
+	return clamp(chance, minHitChance, maxHitChance)
+}
+
+// BlockChance returns the percent chance of the defender to block an attack. The block of a
+// player improves with its dexterity and worsens with its level.
+func BlockChance(defender *Combatant) int {
+	block := statValue(defender.Stats, StatBlock)
+
+	if !defender.IsMonster && defender.Level > 0 {
+		block = block * (statValue(defender.Stats, StatDexterity) - blockDexterityBase) / (2 * defender.Level)
+	}
+
+	return clamp(block, 0, maxBlockChance)
+}
+
+// Resistance returns the resistance of the defender to the element with the given
+// ElemTypes.txt code, monsters with a resistance of 100 or more are immune
+func (e *Engine) Resistance(defender *Combatant, element string) int {
+	if element == ElementPhysical {
+		resist := statValue(defender.Stats, StatPhysicalResist)
+		if !defender.IsMonster && resist > maxPhysicalResist {
+			resist = maxPhysicalResist
+		}
+
+		return resist
+	}
+
+	stats, found := resistStats[e.elementNames[element]]
+	if !found {
+		return 0
+	}
+
+	resist := statValue(defender.Stats, stats.resist)
+
+	if !defender.IsMonster {
+		highest := maxElementalResist + statValue(defender.Stats, stats.max)
+		if highest > maxResistCap {
+			highest = maxResistCap
+		}
+
+		if resist > highest {
+			resist = highest
+		}
+	}
+
+	if resist < minResist {
+		resist = minResist
+	}
+
+	return resist
+}
+
+// damages returns the damages of the attack, weapon attacks add the elemental damage of the
+// weapon stats of the attacker
+func (e *Engine) damages(attacker *Combatant, attack *Attack) []Damage {
+	damages := attack.Damages
+
+	if !attack.UsesWeapon {
+		return damages
+	}
+
+	damages = append([]Damage(nil), damages...)
+
+	if minDamage, maxDamage := statValue(attacker.Stats, StatMinDamage), statValue(attacker.Stats, StatMaxDamage); maxDamage > 0 {
+		damages = append(damages, Damage{Min: minDamage, Max: maxDamage})
+	}
+
+	for _, weapon := range weaponElements {
+		maxDamage := statValue(attacker.Stats, weapon.max)
+		if maxDamage <= 0 {
+			continue
+		}
+
+		damages = append(damages, Damage{
+			Element:  weapon.element,
+			Min:      statValue(attacker.Stats, weapon.min),
+			Max:      maxDamage,
+			Duration: float64(statValue(attacker.Stats, weapon.length)) / d2skill.FramesPerSecond,
+		})
+	}
+
+	return damages
+}
+
+// reduce applies the resistance and the damage reduction of the defender to the damage
+func (e *Engine) reduce(defender *Combatant, element string, amount float64) float64 {
+	amount *= float64(percent-e.Resistance(defender, element)) / percent
+
+	switch element {
+	case ElementPhysical:
+		amount -= float64(statValue(defender.Stats, StatDamageReduction))
+	case ElementMagic:
+		amount -= float64(statValue(defender.Stats, StatMagicReduction))
+	}
+
+	return math.Max(amount, 0)
+}
+
+// leech gives the attacker the life and mana it steals from the physical damage it dealt
+func (e *Engine) leech(attacker, defender *Combatant, physical float64) []Event {
+	if physical <= 0 || defender.Leech <= 0 {
+		return nil
+	}
+
+	events := make([]Event, 0)
+	sensitivity := float64(defender.Leech) / percent
+
+	if life := physical * float64(statValue(attacker.Stats, StatLifeLeech)) / percent * sensitivity; life > 0 {
+		attacker.Life = math.Min(attacker.Life+life, attacker.MaxLife)
+		events = append(events, Event{Type: EventLifeLeech, SourceID: attacker.ID, TargetID: defender.ID, Amount: life})
+	}
+
+	if mana := physical * float64(statValue(attacker.Stats, StatManaLeech)) / percent * sensitivity; mana > 0 {
+		attacker.Mana = math.Min(attacker.Mana+mana, attacker.MaxMana)
+		events = append(events, Event{Type: EventManaLeech, SourceID: attacker.ID, TargetID: defender.ID, Amount: mana})
+	}
+
+	return events
+}
+
+// crushingBlowFraction returns the fraction of the current life of the defender a crushing blow
+// of the attack takes
+func crushingBlowFraction(defender *Combatant, attack *Attack) float64 {
+	fraction := crushingBlowPlayer
+
+	switch {
+	case defender.IsBoss:
+		fraction = crushingBlowBoss
+	case defender.IsMonster:
+		fraction = crushingBlowMonster
+	}
+
+	if !attack.Melee {
+		fraction *= crushingBlowRanged
+	}
+
+	return fraction
+}
+
+// chance rolls the given percent chance
+func (e *Engine) chance(chance int) bool {
+	return chance > 0 && e.rand.Intn(percent) < chance
+}
+
+// between returns a random number of the given range
+func (e *Engine) between(low, high int) int {
+	if high <= low {
+		return low
+	}
+
+	return low + e.rand.Intn(high-low+1)
+}
+
+func clamp(value, low, high int) int {
+	if value < low {
+		return low
+	}
+
+	if value > high {
+		return high
+	}
+
+	return value
+}
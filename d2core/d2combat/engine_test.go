@@ -0,0 +1,262 @@
+package d2combat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2skill"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+)
+
+type testStat struct {
+	d2stats.Stat
+	name  string
+	value int
+}
+
+func (s *testStat) Name() string {
+	return s.name
+}
+
+func (s *testStat) Values() []d2stats.StatValue {
+	return []d2stats.StatValue{testStatValue{value: s.value}}
+}
+
+type testStatValue struct {
+	d2stats.StatValue
+	value int
+}
+
+func (v testStatValue) Int() int {
+	return v.value
+}
+
+type testStatList struct {
+	d2stats.StatList
+	stats []d2stats.Stat
+}
+
+func (l *testStatList) Stats() []d2stats.Stat {
+	return l.stats
+}
+
+func testStats(values map[string]int) d2stats.StatList {
+	list := &testStatList{}
+
+	for name, value := range values {
+		list.stats = append(list.stats, &testStat{name: name, value: value})
+	}
+
+	return list
+}
+
+func testEngine() *Engine {
+	return NewEngine(d2records.ElemTypes{
+		"Fire":   {ElemType: "Fire", Code: ElementFire},
+		"Cold":   {ElemType: "Cold", Code: ElementCold},
+		"Poison": {ElemType: "Poison", Code: ElementPoison},
+	}, 1)
+}
+
+func TestHitChance(t *testing.T) {
+	engine := testEngine()
+
+	tests := []struct {
+		rating, defense, attackerLevel, defenderLevel, expected int
+	}{
+		{100, 100, 10, 10, 50},
+		{300, 100, 10, 10, 75},
+		{300, 100, 30, 10, 95},
+		{0, 100, 10, 10, minHitChance},
+		{100, 0, 1, 99, maxHitChance},
+		{10, 1000, 1, 30, minHitChance},
+	}
+
+	for _, test := range tests {
+		attacker := &Combatant{Level: test.attackerLevel, Stats: testStats(map[string]int{StatAttackRating: test.rating})}
+		defender := &Combatant{Level: test.defenderLevel, Stats: testStats(map[string]int{StatDefense: test.defense})}
+
+		if chance := engine.HitChance(attacker, defender, &Attack{}); chance != test.expected {
+			t.Errorf("%+v: expected a hit chance of %d, got %d", test, test.expected, chance)
+		}
+	}
+}
+
+func TestBlockChance(t *testing.T) {
+	player := &Combatant{Level: 30, Stats: testStats(map[string]int{StatBlock: 50, StatDexterity: 75})}
+	if chance := BlockChance(player); chance != 50 {
+		t.Errorf("expected the player to block 50%% of the attacks, got %d", chance)
+	}
+
+	monster := &Combatant{IsMonster: true, Stats: testStats(map[string]int{StatBlock: 90})}
+	if chance := BlockChance(monster); chance != maxBlockChance {
+		t.Errorf("expected the monster to block %d%% of the attacks, got %d", maxBlockChance, chance)
+	}
+}
+
+func TestResistance(t *testing.T) {
+	engine := testEngine()
+
+	tests := []struct {
+		name      string
+		isMonster bool
+		element   string
+		stats     map[string]int
+		expected  int
+	}{
+		{"capped", false, ElementFire, map[string]int{"fireresist": 90}, maxElementalResist},
+		{"raised cap", false, ElementFire, map[string]int{"fireresist": 90, "maxfireresist": 10}, 85},
+		{"immune monster", true, ElementFire, map[string]int{"fireresist": 120}, 120},
+		{"lowest", false, ElementCold, map[string]int{"coldresist": -150}, minResist},
+		{"physical", false, ElementPhysical, map[string]int{StatPhysicalResist: 60}, maxPhysicalResist},
+		{"unknown element", false, ElementLightning, map[string]int{"lightresist": 40}, 0},
+	}
+
+	for _, test := range tests {
+		defender := &Combatant{IsMonster: test.isMonster, Stats: testStats(test.stats)}
+
+		if resist := engine.Resistance(defender, test.element); resist != test.expected {
+			t.Errorf("%s: expected a resistance of %d, got %d", test.name, test.expected, resist)
+		}
+	}
+}
+
+func TestResolveSpellDamage(t *testing.T) {
+	engine := testEngine()
+	attacker := &Combatant{ID: "attacker"}
+	defender := &Combatant{ID: "defender", Life: 200, IsMonster: true, Stats: testStats(map[string]int{"fireresist": 50})}
+	attack := &Attack{Damages: []Damage{{Element: ElementFire, Min: 100, Max: 100}}}
+
+	events := engine.Resolve(attacker, defender, attack)
+
+	if len(events) != 1 || events[0].Type != EventDamage || events[0].Element != ElementFire || events[0].Amount != 50 {
+		t.Fatalf("expected 50 fire damage, got %+v", events)
+	}
+
+	if defender.Life != 150 {
+		t.Errorf("expected the defender to have 150 life left, got %v", defender.Life)
+	}
+
+	immune := &Combatant{ID: "immune", Life: 200, IsMonster: true, Stats: testStats(map[string]int{"fireresist": 100})}
+	if events := engine.Resolve(attacker, immune, attack); len(events) != 0 || immune.Life != 200 {
+		t.Errorf("expected an immune defender to take no damage, got %+v", events)
+	}
+
+	defender.Life = 10
+	events = engine.Resolve(attacker, defender, attack)
+
+	if last := events[len(events)-1]; last.Type != EventDeath || defender.Life != 0 {
+		t.Errorf("expected the defender to die, got %+v", events)
+	}
+}
+
+func TestResolveWeaponAttack(t *testing.T) {
+	engine := testEngine()
+
+	for tries := 0; tries < 100; tries++ {
+		attacker := &Combatant{ID: "attacker", Level: 10, Life: 10, MaxLife: 100, Stats: testStats(map[string]int{
+			StatAttackRating: 1000,
+			StatDeadlyStrike: 100,
+			StatCrushingBlow: 100,
+			StatLifeLeech:    10,
+		})}
+		defender := &Combatant{ID: "defender", Level: 1, Life: 400, MaxLife: 400, IsMonster: true, Leech: 100}
+
+		events := engine.Resolve(attacker, defender, WeaponAttack(20, 20))
+		if events[0].Type == EventMiss {
+			continue
+		}
+
+		expected := []EventType{EventDeadlyStrike, EventDamage, EventCrushingBlow, EventLifeLeech}
+		if len(events) != len(expected) {
+			t.Fatalf("expected the events %v, got %+v", expected, events)
+		}
+
+		for idx, eventType := range expected {
+			if events[idx].Type != eventType {
+				t.Fatalf("expected the events %v, got %+v", expected, events)
+			}
+		}
+
+		// 40 damage from the deadly strike, then a quarter of the life left before the attack
+		if events[1].Amount != 40 || events[2].Amount != 100 || defender.Life != 260 {
+			t.Errorf("unexpected damage: %+v, %v life left", events, defender.Life)
+		}
+
+		if attacker.Life != 14 {
+			t.Errorf("expected the attacker to leech 4 life, has %v life", attacker.Life)
+		}
+
+		return
+	}
+
+	t.Fatal("the attack never hit")
+}
+
+func TestPoisonOverTime(t *testing.T) {
+	engine := testEngine()
+	attacker := &Combatant{ID: "attacker"}
+	defender := &Combatant{ID: "defender", Life: 200}
+	attack := &Attack{Damages: []Damage{{Element: ElementPoison, Min: 100, Max: 100, Duration: 4}}}
+
+	events := engine.Resolve(attacker, defender, attack)
+	if len(events) != 1 || events[0].Type != EventDamageOverTime || defender.Life != 200 {
+		t.Fatalf("expected the poison to deal its damage over time, got %+v", events)
+	}
+
+	afflictions := NewAfflictions()
+	afflictions.Add(&events[0])
+
+	ticks := afflictions.Advance(1)
+	if len(ticks) != 1 || ticks[0].Amount != 25 || ticks[0].TargetID != defender.ID {
+		t.Fatalf("expected 25 poison damage per second, got %+v", ticks)
+	}
+
+	weaker := events[0]
+	weaker.Amount = 10
+	afflictions.Add(&weaker)
+
+	total := 0.0
+	for _, tick := range afflictions.Advance(10) {
+		total += tick.Amount
+	}
+
+	if math.Abs(total-75) > 1e-9 {
+		t.Errorf("expected the stronger poison to deal its last 75 damage, got %v", total)
+	}
+
+	if ticks := afflictions.Advance(1); len(ticks) != 0 {
+		t.Errorf("expected the poison to wear off, got %+v", ticks)
+	}
+}
+
+func TestSkillAttack(t *testing.T) {
+	skill := &d2records.SkillRecord{
+		Range:    "rang",
+		HitShift: 8,
+		EType:    ElementCold,
+		EMin:     10,
+		EMax:     20,
+		EMinLev1: 2,
+		EMinLev2: 3,
+		EMaxLev1: 4,
+		EMaxLev2: 5,
+		ELen:     50,
+		ELevLen1: 5,
+		ToHit:    10,
+		LevToHit: 2,
+	}
+
+	attack := SkillAttack(d2skill.New(nil, skill, 10, nil))
+
+	if attack.Melee || attack.UsesWeapon || attack.ToHit != 28 {
+		t.Errorf("unexpected attack %+v", attack)
+	}
+
+	// levels 2 to 8 add the first bonus, levels 9 and 10 the second
+	expected := Damage{Element: ElementCold, Min: 10 + 7*2 + 2*3, Max: 20 + 7*4 + 2*5, Duration: float64(50+9*5) / d2skill.FramesPerSecond}
+	if len(attack.Damages) != 1 || attack.Damages[0] != expected {
+		t.Errorf("expected the damages %+v, got %+v", expected, attack.Damages)
+	}
+}
@@ -0,0 +1,58 @@
+package d2combat
+
+// EventType is the kind of a combat event
+type EventType int
+
+// Combat event types
+const (
+	// EventMiss is an attack which did not hit
+	EventMiss EventType = iota
+	// EventBlock is an attack the defender blocked
+	EventBlock
+	// EventDodge is an attack the defender dodged or avoided
+	EventDodge
+	// EventDamage is damage of an element dealt to the defender
+	EventDamage
+	// EventDeadlyStrike is an attack which did double physical damage
+	EventDeadlyStrike
+	// EventCrushingBlow is damage dealt by a crushing blow
+	EventCrushingBlow
+	// EventDamageOverTime is damage dealt to the defender over the duration of the event
+	EventDamageOverTime
+	// EventChill slows the defender for the duration of the event
+	EventChill
+	// EventLifeLeech is life the attacker stole
+	EventLifeLeech
+	// EventManaLeech is mana the attacker stole
+	EventManaLeech
+	// EventDeath is the death of the defender
+	EventDeath
+)
+
+func (e EventType) String() string {
+	names := map[EventType]string{
+		EventMiss:           "Miss",
+		EventBlock:          "Block",
+		EventDodge:          "Dodge",
+		EventDamage:         "Damage",
+		EventDeadlyStrike:   "DeadlyStrike",
+		EventCrushingBlow:   "CrushingBlow",
+		EventDamageOverTime: "DamageOverTime",
+		EventChill:          "Chill",
+		EventLifeLeech:      "LifeLeech",
+		EventManaLeech:      "ManaLeech",
+		EventDeath:          "Death",
+	}
+
+	return names[e]
+}
+
+// Event is something that happened in a fight
+type Event struct {
+	Type     EventType `json:"type"`
+	SourceID string    `json:"sourceId"`
+	TargetID string    `json:"targetId"`
+	Element  string    `json:"element,omitempty"`  // ElemTypes.txt code of the damage, empty for physical
+	Amount   float64   `json:"amount,omitempty"`   // damage dealt or life and mana leeched
+	Duration float64   `json:"duration,omitempty"` // seconds of the damage over time and the chill
+}
@@ -0,0 +1,66 @@
+package d2combat
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+)
+
+// the itemstatcost.txt names of the stats used in combat
+const (
+	StatStrength          = "strength"
+	StatDexterity         = "dexterity"
+	StatLevel             = "level"
+	StatAttackRating      = "tohit"
+	StatAttackRatingBonus = "item_tohit_percent"
+	StatDefense           = "armorclass"
+	StatDefenseBonus      = "item_armor_percent"
+	StatBlock             = "toblock"
+	StatMinDamage         = "mindamage"
+	StatMaxDamage         = "maxdamage"
+	StatDamageBonus       = "item_maxdamage_percent"
+	StatDeadlyStrike      = "item_deadlystrike"
+	StatCrushingBlow      = "item_crushingblow"
+	StatLifeLeech         = "lifedrainmindam"
+	StatManaLeech         = "manadrainmindam"
+	StatPhysicalResist    = "damageresist"
+	StatDamageReduction   = "normal_damage_reduction"
+	StatMagicReduction    = "magic_damage_reduction"
+)
+
+// weaponElements are the stats of the elemental damage a weapon adds to an attack
+var weaponElements = []struct{ element, min, max, length string }{ //nolint:gochecknoglobals // lookup table
+	{ElementFire, "firemindam", "firemaxdam", ""},
+	{ElementLightning, "lightmindam", "lightmaxdam", ""},
+	{ElementCold, "coldmindam", "coldmaxdam", "coldlength"},
+	{ElementPoison, "poisonmindam", "poisonmaxdam", "poisonlength"},
+	{ElementMagic, "magicmindam", "magicmaxdam", ""},
+}
+
+// resistStats are the resistance stats of the elements, by their ElemTypes.txt name
+var resistStats = map[string]struct{ resist, max string }{ //nolint:gochecknoglobals // lookup table
+	"Fire":      {"fireresist", "maxfireresist"},
+	"Lightning": {"lightresist", "maxlightresist"},
+	"Cold":      {"coldresist", "maxcoldresist"},
+	"Poison":    {"poisonresist", "maxpoisonresist"},
+	"Magic":     {"magicresist", "maxmagicresist"},
+}
+
+// statValue returns the sum of the values of the stats with the given name in the list
+func statValue(list d2stats.StatList, name string) int {
+	if list == nil || name == "" {
+		return 0
+	}
+
+	total := 0
+
+	for _, stat := range list.Stats() {
+		if stat == nil || stat.Name() != name {
+			continue
+		}
+
+		if values := stat.Values(); len(values) > 0 {
+			total += values[0].Int()
+		}
+	}
+
+	return total
+}
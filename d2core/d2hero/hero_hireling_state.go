@@ -0,0 +1,17 @@
+package d2hero
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+)
+
+// HirelingState stores the state of the hireling of the player
+type HirelingState struct {
+	Seed       uint32                         `json:"seed"`   // the unique ID of the hireling, never zero
+	ID         int                            `json:"id"`     // the Id of the row of the hireling in hireling.txt
+	NameID     int                            `json:"nameId"` // the name of the hireling, counted from the first name of its row
+	Level      int                            `json:"level"`
+	Experience int                            `json:"experience"`
+	Life       int                            `json:"life"`
+	Dead       bool                           `json:"dead,omitempty"`
+	Equipment  map[d2enum.EquippedSlot][]byte `json:"equipment,omitempty"` // the items of the hireling in the saved item format
+}
@@ -21,6 +21,12 @@ type shallowHeroSkill struct {
 	SkillPoints int `json:"skillPoints"`
 }
 
+// NewShallowHeroSkill creates a HeroSkill which only knows its ID and skill points, like the
+// skills received from the network. The records are added by HydrateSkills.
+func NewShallowHeroSkill(skillID, skillPoints int) *HeroSkill {
+	return &HeroSkill{Shallow: &shallowHeroSkill{SkillID: skillID, SkillPoints: skillPoints}}
+}
+
 // MarshalJSON overrides the default logic used when the HeroSkill is serialized to a byte array.
 func (hs *HeroSkill) MarshalJSON() ([]byte, error) {
 	// only serialize the Shallow object instead of the SkillRecord & SkillDescriptionRecord
@@ -0,0 +1,58 @@
+package d2hero
+
+// SkillCaster lets the calculations of the skills of a hero refer to the other skills and the
+// stats of the hero. The skills have to be hydrated.
+type SkillCaster struct {
+	Stats  *HeroStatsState
+	Skills map[int]*HeroSkill
+	Bonus  int // levels the items of the hero add to all of its skills
+}
+
+// SkillLevel returns the skill points the hero put into the skill with the given name and the
+// levels its items add, 0 if the hero does not have the skill
+func (c *SkillCaster) SkillLevel(name string) int {
+	for _, skill := range c.Skills {
+		if skill != nil && skill.SkillRecord != nil && skill.Skill == name {
+			return skill.SkillPoints + c.Bonus
+		}
+	}
+
+	return 0
+}
+
+// BaseSkillLevel returns the skill points the hero put into the skill with the given name
+func (c *SkillCaster) BaseSkillLevel(name string) int {
+	for _, skill := range c.Skills {
+		if skill != nil && skill.SkillRecord != nil && skill.Skill == name {
+			return skill.SkillPoints
+		}
+	}
+
+	return 0
+}
+
+// StatValue returns the value of a stat of the hero, the stats of the items are not counted yet
+func (c *SkillCaster) StatValue(name string) int {
+	if c.Stats == nil {
+		return 0
+	}
+
+	switch name {
+	case "strength":
+		return c.Stats.Strength
+	case "dexterity":
+		return c.Stats.Dexterity
+	case "vitality":
+		return c.Stats.Vitality
+	case "energy":
+		return c.Stats.Energy
+	case "level":
+		return c.Stats.Level
+	case "maxhp":
+		return c.Stats.MaxHealth
+	case "maxmana":
+		return c.Stats.MaxMana
+	}
+
+	return 0
+}
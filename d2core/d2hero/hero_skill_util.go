@@ -7,7 +7,13 @@ import "github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
 // We cant do this while unmarshalling because there is no reference to the asset manager.
 func HydrateSkills(skills map[int]*HeroSkill, asset *d2asset.AssetManager) {
 	for skillID, skill := range skills {
-		skill.SkillRecord = asset.Records.Skill.Details[skillID]
-		skill.SkillDescriptionRecord = asset.Records.Skill.Descriptions[skill.SkillRecord.Skilldesc]
+		// the skills come from the network, so unknown skills are left without records
+		record := asset.Records.Skill.Details[skillID]
+		if skill == nil || record == nil {
+			continue
+		}
+
+		skill.SkillRecord = record
+		skill.SkillDescriptionRecord = asset.Records.Skill.Descriptions[record.Skilldesc]
 	}
 }
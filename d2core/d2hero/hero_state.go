@@ -1,7 +1,10 @@
 package d2hero
 
 import (
+	"sort"
+
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2inventory"
 )
 
@@ -12,6 +15,7 @@ type HeroState struct {
 	Act        int                            `json:"act"`
 	FilePath   string                         `json:"-"`
 	Equipment  d2inventory.CharacterEquipment `json:"equipment"`
+	Items      map[string]*d2s.Item           `json:"items,omitempty"` // worn, carried and stashed items, by their ID
 	Stats      *HeroStatsState                `json:"stats"`
 	Skills     map[int]*HeroSkill             `json:"skills"`
 	X          float64                        `json:"x"`
@@ -20,4 +24,81 @@ type HeroState struct {
 	RightSkill int                            `json:"rightSkill"`
 	Gold       int                            `json:"Gold"`
 	Difficulty d2enum.DifficultyType          `json:"difficulty"`
+	Hireling   *HirelingState                 `json:"hireling,omitempty"`
+	Automap    map[int][]byte                 `json:"automap,omitempty"`   // explored tiles of the maps, by their first level
+	Waypoints  []int                          `json:"waypoints,omitempty"` // levels whose waypoints the hero has activated
+}
+
+// HasWaypoint returns true if the hero has activated the waypoint of the given level
+func (s *HeroState) HasWaypoint(levelID int) bool {
+	idx := sort.SearchInts(s.Waypoints, levelID)
+
+	return idx < len(s.Waypoints) && s.Waypoints[idx] == levelID
+}
+
+// ActivateWaypoint activates the waypoint of the given level for the hero, it returns false if
+// the waypoint was already activated
+func (s *HeroState) ActivateWaypoint(levelID int) bool {
+	if s.HasWaypoint(levelID) {
+		return false
+	}
+
+	s.Waypoints = append(s.Waypoints, levelID)
+	sort.Ints(s.Waypoints)
+
+	return true
+}
+
+// GiveItem gives the item to the hero by the given ID
+func (s *HeroState) GiveItem(id string, item *d2s.Item) {
+	if s.Items == nil {
+		s.Items = make(map[string]*d2s.Item)
+	}
+
+	s.Items[id] = item
+}
+
+// TakeItem takes the item with the given ID from the hero and returns it, or nil if the hero has
+// none. A worn item is taken off the equipment too.
+func (s *HeroState) TakeItem(id string) *d2s.Item {
+	item, found := s.Items[id]
+	if !found {
+		return nil
+	}
+
+	delete(s.Items, id)
+
+	if item.Location == d2s.LocationEquipped {
+		s.unequip(item)
+	}
+
+	return item
+}
+
+// unequip empties the equipment slot of the worn item, if it still shows the item
+func (s *HeroState) unequip(item *d2s.Item) {
+	equipment := &s.Equipment
+
+	switch item.BodyLocation {
+	case d2s.BodyHead:
+		if equipment.Head != nil && equipment.Head.ItemCode == item.Code {
+			equipment.Head = nil
+		}
+	case d2s.BodyTorso:
+		if equipment.Torso != nil && equipment.Torso.ItemCode == item.Code {
+			equipment.Torso, equipment.Legs, equipment.RightArm, equipment.LeftArm = nil, nil, nil, nil
+		}
+	case d2s.BodyRightHand, d2s.BodyRightHandSwap:
+		if equipment.RightHand != nil && equipment.RightHand.ItemCode == item.Code {
+			equipment.RightHand = nil
+		}
+	case d2s.BodyLeftHand, d2s.BodyLeftHandSwap:
+		if equipment.Shield != nil && equipment.Shield.ItemCode == item.Code {
+			equipment.Shield = nil
+		}
+
+		if equipment.LeftHand != nil && equipment.LeftHand.ItemCode == item.Code {
+			equipment.LeftHand = nil
+		}
+	}
 }
@@ -0,0 +1,484 @@
+package d2hero
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2inventory"
+)
+
+const (
+	d2sExtension  = ".d2s"
+	newItemLevel  = 1
+	weaponSetSwap = 1
+)
+
+func isD2S(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), d2sExtension)
+}
+
+// LoadD2S loads a character save of the original game
+func (f *HeroStateFactory) LoadD2S(filePath string) (*HeroState, error) {
+	data, err := ioutil.ReadFile(filepath.Clean(filePath))
+	if err != nil {
+		return nil, err
+	}
+
+	save, err := d2s.Load(data, f.asset.Records.SaveRecords())
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := f.HeroStateFromD2S(save)
+	if err != nil {
+		return nil, err
+	}
+
+	state.FilePath = filePath
+
+	return state, nil
+}
+
+// HeroStateFromD2S creates a HeroState from a character save of the original game
+func (f *HeroStateFactory) HeroStateFromD2S(save *d2s.D2S) (*HeroState, error) {
+	hero := save.Class.Hero()
+	if hero == d2enum.HeroNone {
+		return nil, errors.New("unknown character class")
+	}
+
+	difficulty, act := save.ActiveDifficulty()
+	attributes := save.Attributes
+
+	state := &HeroState{
+		HeroName:   save.CharacterName(),
+		HeroType:   hero,
+		Act:        act,
+		Difficulty: difficulty,
+		LeftSkill:  int(save.LeftSkill),
+		RightSkill: int(save.RightSkill),
+		Gold:       attributes[d2s.AttributeGold],
+		Stats: &HeroStatsState{
+			Level:       attributes[d2s.AttributeLevel],
+			Experience:  attributes[d2s.AttributeExperience],
+			Strength:    attributes[d2s.AttributeStrength],
+			Energy:      attributes[d2s.AttributeEnergy],
+			Dexterity:   attributes[d2s.AttributeDexterity],
+			Vitality:    attributes[d2s.AttributeVitality],
+			StatsPoints: attributes[d2s.AttributeStatPoints],
+			SkillPoints: attributes[d2s.AttributeSkillPoints],
+			Health:      attributes[d2s.AttributeLife] >> d2s.FractionBits,
+			MaxHealth:   attributes[d2s.AttributeMaxLife] >> d2s.FractionBits,
+			Mana:        attributes[d2s.AttributeMana] >> d2s.FractionBits,
+			MaxMana:     attributes[d2s.AttributeMaxMana] >> d2s.FractionBits,
+			Stamina:     float64(attributes[d2s.AttributeStamina] >> d2s.FractionBits),
+			MaxStamina:  attributes[d2s.AttributeMaxStamina] >> d2s.FractionBits,
+		},
+	}
+
+	state.Stats.NextLevelExp = f.asset.Records.GetExperienceBreakpoint(hero, state.Stats.Level)
+
+	skills, err := f.CreateHeroSkillsState(f.asset.Records.Character.Stats[hero], hero)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, id := range f.classSkillIDs(hero) {
+		if skill, found := skills[id]; found && idx < d2s.NumSkills {
+			skill.SkillPoints = int(save.Skills[idx])
+			skill.Shallow.SkillPoints = skill.SkillPoints
+		}
+	}
+
+	state.Skills = skills
+	state.Equipment = f.equipmentFromItems(save.Items, save.ActiveWeapon == weaponSetSwap)
+	state.Items = itemsByID(save.Items)
+	state.Hireling = f.hirelingFromD2S(&save.Mercenary)
+
+	if int(difficulty) < d2s.NumDifficulties {
+		state.Waypoints = f.waypointsFromD2S(&save.Waypoints[difficulty])
+	}
+
+	return state, nil
+}
+
+// D2SFromHeroState creates a character save of the original game from a HeroState. The
+// sections which the HeroState does not cover, such as the quests, are taken from the base save
+// when it is given, and so are the items of a HeroState without items.
+func (f *HeroStateFactory) D2SFromHeroState(state *HeroState, base *d2s.D2S) (*d2s.D2S, error) {
+	class, ok := d2s.ClassOf(state.HeroType)
+	if !ok || state.Stats == nil {
+		return nil, errors.New("hero has no class or stats")
+	}
+
+	save := base
+	if save == nil {
+		save = &d2s.D2S{Header: d2s.Header{Status: d2s.StatusExpansion}}
+	}
+
+	save.SetCharacterName(state.HeroName)
+	save.Class = class
+	save.Level = byte(state.Stats.Level)
+	save.LeftSkill = uint32(state.LeftSkill)
+	save.RightSkill = uint32(state.RightSkill)
+	save.SetActiveDifficulty(state.Difficulty, state.Act)
+
+	stats := state.Stats
+	attributes := map[d2s.Attribute]int{
+		d2s.AttributeStrength:    stats.Strength,
+		d2s.AttributeEnergy:      stats.Energy,
+		d2s.AttributeDexterity:   stats.Dexterity,
+		d2s.AttributeVitality:    stats.Vitality,
+		d2s.AttributeStatPoints:  stats.StatsPoints,
+		d2s.AttributeSkillPoints: stats.SkillPoints,
+		d2s.AttributeLife:        stats.Health << d2s.FractionBits,
+		d2s.AttributeMaxLife:     stats.MaxHealth << d2s.FractionBits,
+		d2s.AttributeMana:        stats.Mana << d2s.FractionBits,
+		d2s.AttributeMaxMana:     stats.MaxMana << d2s.FractionBits,
+		d2s.AttributeStamina:     int(stats.Stamina) << d2s.FractionBits,
+		d2s.AttributeMaxStamina:  stats.MaxStamina << d2s.FractionBits,
+		d2s.AttributeLevel:       stats.Level,
+		d2s.AttributeExperience:  stats.Experience,
+		d2s.AttributeGold:        state.Gold,
+		d2s.AttributeStashGold:   save.Attributes[d2s.AttributeStashGold],
+	}
+
+	// the game leaves out the attributes which are zero
+	for id, value := range attributes {
+		if value == 0 {
+			delete(attributes, id)
+		}
+	}
+
+	save.Attributes = attributes
+	save.Skills = [d2s.NumSkills]byte{}
+
+	for idx, id := range f.classSkillIDs(state.HeroType) {
+		if skill, found := state.Skills[id]; found && skill != nil && idx < d2s.NumSkills {
+			save.Skills[idx] = byte(skill.SkillPoints)
+		}
+	}
+
+	items := save.Items
+	if state.Items != nil {
+		items = sortedItems(state.Items)
+	}
+
+	save.Items = f.itemsFromEquipment(&state.Equipment, items, save.ActiveWeapon == weaponSetSwap)
+	save.Mercenary = mercenaryFromHireling(state.Hireling)
+
+	if int(state.Difficulty) >= 0 && int(state.Difficulty) < d2s.NumDifficulties {
+		f.setD2SWaypoints(&save.Waypoints[state.Difficulty], state.Waypoints)
+	}
+
+	// the items of the mercenary are only kept for the mercenary they were saved with
+	if save.Mercenary.ID == 0 || base == nil || base.Mercenary.ID != save.Mercenary.ID {
+		save.MercenaryItems = nil
+	}
+
+	return save, nil
+}
+
+// waypointsFromD2S returns the levels whose waypoints are activated in the waypoint flags of a
+// save, the flags are indexed by the Waypoint column of levels.txt
+func (f *HeroStateFactory) waypointsFromD2S(waypoints *d2s.Waypoints) []int {
+	levelIDs := make([]int, 0)
+
+	for id, details := range f.asset.Records.Level.Details {
+		if details.HasWaypoint() && details.WaypointID < d2s.NumWaypoints && waypoints.Active(details.WaypointID) {
+			levelIDs = append(levelIDs, id)
+		}
+	}
+
+	sort.Ints(levelIDs)
+
+	return levelIDs
+}
+
+// setD2SWaypoints sets the waypoint flags of a save to the activated waypoints of the given levels
+func (f *HeroStateFactory) setD2SWaypoints(waypoints *d2s.Waypoints, levelIDs []int) {
+	for idx := 0; idx < d2s.NumWaypoints; idx++ {
+		waypoints.SetActive(idx, false)
+	}
+
+	for _, id := range levelIDs {
+		details := f.asset.Records.Level.Details[id]
+		if details != nil && details.HasWaypoint() && details.WaypointID < d2s.NumWaypoints {
+			waypoints.SetActive(details.WaypointID, true)
+		}
+	}
+}
+
+// hirelingFromD2S returns the hireling of the mercenary of a save, nil if the save has none. The
+// items of the mercenary are not read yet.
+func (f *HeroStateFactory) hirelingFromD2S(mercenary *d2s.Mercenary) *HirelingState {
+	if mercenary.ID == 0 {
+		return nil
+	}
+
+	hireling := &HirelingState{
+		Seed:       mercenary.ID,
+		ID:         int(mercenary.Type),
+		NameID:     int(mercenary.NameID),
+		Experience: int(mercenary.Experience),
+		Dead:       mercenary.Dead != 0,
+		Level:      1,
+	}
+
+	if record := f.asset.Records.Hireling.Details.ByID(hireling.ID); record != nil {
+		hireling.Level = record.LevelForExperience(hireling.Experience)
+		hireling.Life = record.HP + record.HPPerLvl*(hireling.Level-record.Level)
+	}
+
+	if hireling.Dead {
+		hireling.Life = 0
+	}
+
+	return hireling
+}
+
+// mercenaryFromHireling returns the mercenary of a save for a hireling
+func mercenaryFromHireling(hireling *HirelingState) d2s.Mercenary {
+	if hireling == nil || hireling.Seed == 0 {
+		return d2s.Mercenary{}
+	}
+
+	mercenary := d2s.Mercenary{
+		ID:         hireling.Seed,
+		NameID:     uint16(hireling.NameID),
+		Type:       uint16(hireling.ID),
+		Experience: uint32(hireling.Experience),
+	}
+
+	if hireling.Dead {
+		mercenary.Dead = 1
+	}
+
+	return mercenary
+}
+
+// classSkillIDs returns the IDs of the class skills, in the order they are saved
+func (f *HeroStateFactory) classSkillIDs(hero d2enum.Hero) []int {
+	token := strings.ToLower(hero.GetToken3())
+	ids := make([]int, 0, d2s.NumSkills)
+
+	for id, skill := range f.asset.Records.Skill.Details {
+		if skill.Charclass == token {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+// handSlots returns the body locations of the weapon set in use
+func handSlots(swapped bool) (right, left d2s.BodyLocation) {
+	if swapped {
+		return d2s.BodyRightHandSwap, d2s.BodyLeftHandSwap
+	}
+
+	return d2s.BodyRightHand, d2s.BodyLeftHand
+}
+
+func (f *HeroStateFactory) equipmentFromItems(items []*d2s.Item, swapped bool) d2inventory.CharacterEquipment {
+	equipment := d2inventory.CharacterEquipment{}
+	rightHand, leftHand := handSlots(swapped)
+
+	for _, item := range items {
+		if item.Location != d2s.LocationEquipped {
+			continue
+		}
+
+		switch item.BodyLocation {
+		case d2s.BodyHead:
+			equipment.Head, _ = f.GetArmorItemByCode(item.Code)
+		case d2s.BodyTorso:
+			// the body armor also dresses the arms and legs
+			equipment.Torso, _ = f.GetArmorItemByCode(item.Code)
+			equipment.Legs, equipment.RightArm, equipment.LeftArm = equipment.Torso, equipment.Torso, equipment.Torso
+		case rightHand:
+			equipment.RightHand, _ = f.GetWeaponItemByCode(item.Code)
+		case leftHand:
+			if shield, err := f.GetArmorItemByCode(item.Code); err == nil {
+				equipment.Shield = shield
+			} else {
+				equipment.LeftHand, _ = f.GetWeaponItemByCode(item.Code)
+			}
+		}
+	}
+
+	return equipment
+}
+
+// itemsFromEquipment replaces the equipped items of the save which differ from the equipment,
+// the items which did not change are kept with their properties
+func (f *HeroStateFactory) itemsFromEquipment(equipment *d2inventory.CharacterEquipment, items []*d2s.Item,
+	swapped bool) []*d2s.Item {
+	rightHand, leftHand := handSlots(swapped)
+
+	leftHandCode := itemCode(equipment.Shield)
+	if equipment.Shield == nil && equipment.LeftHand != nil {
+		leftHandCode = equipment.LeftHand.ItemCode
+	}
+
+	rightHandCode := ""
+	if equipment.RightHand != nil {
+		rightHandCode = equipment.RightHand.ItemCode
+	}
+
+	slots := []struct {
+		location d2s.BodyLocation
+		code     string
+		kept     bool
+	}{
+		{d2s.BodyHead, itemCode(equipment.Head), false},
+		{d2s.BodyTorso, itemCode(equipment.Torso), false},
+		{rightHand, rightHandCode, false},
+		{leftHand, leftHandCode, false},
+	}
+
+	result := make([]*d2s.Item, 0, len(items)+len(slots))
+
+	for _, item := range items {
+		keep := true
+
+		for idx := range slots {
+			if item.Location == d2s.LocationEquipped && item.BodyLocation == slots[idx].location {
+				keep = item.Code == slots[idx].code
+				slots[idx].kept = slots[idx].kept || keep
+			}
+		}
+
+		if keep {
+			result = append(result, item)
+		}
+	}
+
+	for _, slot := range slots {
+		if slot.kept || slot.code == "" {
+			continue
+		}
+
+		if item := f.newSaveItem(slot.code, slot.location); item != nil {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// itemsByID returns the items of a save by new IDs
+func itemsByID(items []*d2s.Item) map[string]*d2s.Item {
+	result := make(map[string]*d2s.Item, len(items))
+
+	for _, item := range items {
+		result[uuid.New().String()] = item
+	}
+
+	return result
+}
+
+// sortedItems returns the items sorted by their IDs, so a hero is always saved the same way
+func sortedItems(items map[string]*d2s.Item) []*d2s.Item {
+	ids := make([]string, 0, len(items))
+
+	for id := range items {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	result := make([]*d2s.Item, len(ids))
+
+	for idx, id := range ids {
+		result[idx] = items[id]
+	}
+
+	return result
+}
+
+func itemCode(armor *d2inventory.InventoryItemArmor) string {
+	if armor == nil {
+		return ""
+	}
+
+	return armor.ItemCode
+}
+
+// newSaveItem creates a normal quality item for an equipment slot
+func (f *HeroStateFactory) newSaveItem(code string, location d2s.BodyLocation) *d2s.Item {
+	record := f.asset.Records.Item.All[code]
+	if record == nil {
+		return nil
+	}
+
+	item := &d2s.Item{
+		Identified:   true,
+		Version:      d2s.ItemVersionExpansion,
+		Location:     d2s.LocationEquipped,
+		BodyLocation: location,
+		Code:         code,
+		ID:           rand.Uint32(), //nolint:gosec // item IDs only need to be unique
+		Level:        newItemLevel,
+		Quality:      d2enum.Normal,
+		Properties:   []d2s.Property{},
+	}
+
+	_, armor := f.asset.Records.Item.Armors[code]
+	_, weapon := f.asset.Records.Item.Weapons[code]
+
+	if armor {
+		item.Defense = record.MinAC
+	}
+
+	if (armor || weapon) && !record.NoDurability {
+		item.MaxDurability, item.Durability = record.Durability, record.Durability
+	}
+
+	if record.Stackable {
+		item.Quantity = record.MaxStack
+	}
+
+	return item
+}
+
+// saveD2S writes the hero to a character save of the original game, the sections which the
+// hero state does not cover are kept from the existing save
+func (f *HeroStateFactory) saveD2S(state *HeroState) error {
+	records := f.asset.Records.SaveRecords()
+
+	var base *d2s.D2S
+
+	if data, err := ioutil.ReadFile(filepath.Clean(state.FilePath)); err == nil {
+		if base, err = d2s.Load(data, records); err != nil {
+			return err
+		}
+	}
+
+	save, err := f.D2SFromHeroState(state, base)
+	if err != nil {
+		return err
+	}
+
+	data, err := save.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path.Dir(state.FilePath), mkdirPermission); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(state.FilePath, data, writefilePermission)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
 )
 
@@ -66,11 +67,17 @@ func (f *HeroStateFactory) CreateHeroState(
 	}
 
 	result.Skills = skillState
+	result.Items = f.equipmentItems(&result.Equipment)
 
 	return result, nil
 }
 
-// GetAllHeroStates returns all player saves
+// equipmentItems returns the items of the equipment, by new IDs
+func (f *HeroStateFactory) equipmentItems(equipment *d2inventory.CharacterEquipment) map[string]*d2s.Item {
+	return itemsByID(f.itemsFromEquipment(equipment, nil, false))
+}
+
+// GetAllHeroStates returns all player saves, both our own and the character saves of the original game
 func (f *HeroStateFactory) GetAllHeroStates() ([]*HeroState, error) {
 	basePath, _ := f.getGameBaseSavePath()
 	files, _ := ioutil.ReadDir(basePath)
@@ -78,7 +85,7 @@ func (f *HeroStateFactory) GetAllHeroStates() ([]*HeroState, error) {
 
 	for _, file := range files {
 		fileName := file.Name()
-		if file.IsDir() || len(fileName) < 5 || !strings.EqualFold(fileName[len(fileName)-4:], ".od2") {
+		if file.IsDir() || len(fileName) < 5 || !(strings.EqualFold(fileName[len(fileName)-4:], ".od2") || isD2S(fileName)) {
 			continue
 		}
 
@@ -184,8 +191,17 @@ func (f *HeroStateFactory) CreateTestGameState() *HeroState {
 	return result
 }
 
-// LoadHeroState loads the player state from the file
+// LoadHeroState loads the player state from the file, which may be a character save of the original game
 func (f *HeroStateFactory) LoadHeroState(filePath string) *HeroState {
+	if isD2S(filePath) {
+		result, err := f.LoadD2S(filePath)
+		if err != nil {
+			return nil
+		}
+
+		return result
+	}
+
 	strData, err := ioutil.ReadFile(filepath.Clean(filePath))
 	if err != nil {
 		return nil
@@ -215,6 +231,11 @@ func (f *HeroStateFactory) LoadHeroState(filePath string) *HeroState {
 		hs.SkillPoints = hs.Shallow.SkillPoints
 	}
 
+	// the heroes saved before their items were saved get the items of their equipment
+	if result.Items == nil {
+		result.Items = f.equipmentItems(&result.Equipment)
+	}
+
 	return result
 }
 
@@ -240,12 +261,17 @@ func (f *HeroStateFactory) getFirstFreeFileName() string {
 	}
 }
 
-// Save saves the player state to a file
+// Save saves the player state to a file, heroes loaded from a character save of the original
+// game are saved back in that format
 func (f *HeroStateFactory) Save(state *HeroState) error {
 	if state.FilePath == "" {
 		state.FilePath = f.getFirstFreeFileName()
 	}
 
+	if isD2S(state.FilePath) {
+		return f.saveD2S(state)
+	}
+
 	if err := os.MkdirAll(path.Dir(state.FilePath), mkdirPermission); err != nil {
 		return err
 	}
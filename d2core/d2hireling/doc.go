@@ -0,0 +1,5 @@
+// Package d2hireling provides the hirelings (mercenaries) of the players. A hireling is rolled
+// from the rows of hireling.txt of its act and difficulty, its stats, skills and prices grow with
+// its level and it levels with the experience it earns, up to the level of the player. Only the
+// state of a hireling is saved with the hero, the rest is read from the records again.
+package d2hireling
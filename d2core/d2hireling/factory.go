@@ -0,0 +1,143 @@
+package d2hireling
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const (
+	// offerLevelSpread is how many levels below its player a hireling offered for hire may be
+	offerLevelSpread = 3
+)
+
+// sellerActs are the acts of the town folk who offer hirelings, by their rows in monstats.txt
+var sellerActs = map[string]int{ //nolint:gochecknoglobals // lookup table
+	"kashya":    1,
+	"greiz":     2,
+	"asheara":   3,
+	"qual-kehk": 5,
+}
+
+// SellerAct returns the act of the hirelings the town folk of the given monstats.txt row offers,
+// 0 if it offers none
+func SellerAct(key string) int {
+	return sellerActs[key]
+}
+
+// Factory rolls the hirelings offered for hire and loads the saved hirelings
+type Factory struct {
+	records  *d2records.RecordManager
+	monstats map[int]*d2records.MonStatRecord // by the hcIdx the class of hireling.txt refers to
+	rand     *rand.Rand
+}
+
+// NewFactory creates a factory for the hirelings of the given records. Factories with the same
+// seed offer the same hirelings.
+func NewFactory(records *d2records.RecordManager, seed int64) *Factory {
+	f := &Factory{
+		records:  records,
+		monstats: make(map[int]*d2records.MonStatRecord),
+		rand:     rand.New(rand.NewSource(seed)), //nolint:gosec // the offers do not need a secure source
+	}
+
+	for _, record := range records.Monster.Stats {
+		f.monstats[record.ID] = record
+	}
+
+	return f
+}
+
+// Load returns the hireling of a saved state
+func (f *Factory) Load(state *d2hero.HirelingState) (*Hireling, error) {
+	record := f.records.Hireling.Details.ByID(state.ID)
+	if record == nil {
+		return nil, fmt.Errorf("unknown hireling %d", state.ID)
+	}
+
+	monstat := f.monstats[record.Class]
+	if monstat == nil {
+		return nil, fmt.Errorf("hireling %d has an unknown class %d", state.ID, record.Class)
+	}
+
+	return &Hireling{State: state, Record: record, MonStat: monstat}, nil
+}
+
+// Offers rolls the given number of hirelings offered for hire in the given act and difficulty to
+// a player of the given level. The hirelings are of the rows with the highest level the player
+// has reached and are up to a few levels below the player.
+func (f *Factory) Offers(act int, difficulty d2enum.DifficultyType, playerLevel, count int) []*Hireling {
+	rows := f.offeredRows(act, difficulty, playerLevel)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	offers := make([]*Hireling, 0, count)
+
+	for len(offers) < count {
+		record := rows[f.rand.Intn(len(rows))]
+
+		level := playerLevel - f.rand.Intn(offerLevelSpread+1)
+		if level < record.Level {
+			level = record.Level
+		}
+
+		if level < 1 {
+			level = 1
+		}
+
+		state := &d2hero.HirelingState{
+			Seed:       f.rand.Uint32() | 1, // the seed of a hireling is never zero
+			ID:         record.ID,
+			NameID:     f.rand.Intn(record.NameCount()),
+			Level:      level,
+			Experience: record.ExperienceForLevel(level),
+		}
+
+		hireling := &Hireling{State: state, Record: record, MonStat: f.monstats[record.Class]}
+		state.Life = hireling.Stats().MaxLife
+		offers = append(offers, hireling)
+	}
+
+	return offers
+}
+
+// offeredRows returns, for every kind of hireling of the act and difficulty, the row with the
+// highest level the player has reached, or the lowest row if the player has reached none
+func (f *Factory) offeredRows(act int, difficulty d2enum.DifficultyType, playerLevel int) []*d2records.HirelingRecord {
+	best := make(map[string]*d2records.HirelingRecord)
+
+	for _, record := range f.records.Hireling.Details {
+		// the difficulties of hireling.txt start at 1
+		if record.Act != act || record.Difficulty != int(difficulty)+1 || f.monstats[record.Class] == nil {
+			continue
+		}
+
+		kind := record.Hireling + "/" + record.SubType
+		current := best[kind]
+
+		switch {
+		case current == nil:
+			best[kind] = record
+		case record.Level <= playerLevel && (current.Level > playerLevel || record.Level > current.Level):
+			best[kind] = record
+		case current.Level > playerLevel && record.Level < current.Level:
+			best[kind] = record
+		}
+	}
+
+	rows := make([]*d2records.HirelingRecord, 0, len(best))
+	for _, record := range best {
+		rows = append(rows, record)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].ID < rows[j].ID
+	})
+
+	return rows
+}
@@ -0,0 +1,291 @@
+package d2hireling
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const (
+	// MaxLevel is the highest level a hireling reaches
+	MaxLevel = 98
+
+	// the strength, dexterity and damage per level of hireling.txt are in eighths, the resistance
+	// per level in quarters and the skill level per level in 32nds
+	statFraction       = 8
+	resistFraction     = 4
+	skillLevelFraction = 32
+
+	hirePricePerLevel   = 15 // percent of the gold of its row a hireling costs more for each level above its row
+	resurrectPriceScale = 15 // half the gold per squared level it costs to resurrect a hireling
+	maxResurrectPrice   = 50000
+
+	numSkills = 6
+)
+
+// Stats are the stats of a hireling at its level
+type Stats struct {
+	MaxLife      int
+	Defense      int
+	Strength     int
+	Dexterity    int
+	AttackRating int
+	MinDamage    int
+	MaxDamage    int
+	Resistance   int // the resistance to every element, in percent
+}
+
+// Skill is a skill a hireling uses at its level
+type Skill struct {
+	Name   string // the skill in skills.txt
+	Mode   int    // the mode the hireling is animated with when it uses the skill
+	Chance int    // the weight of the skill when the hireling picks what to do
+	Level  int
+}
+
+// Hireling is the hireling of a player, the state is what is saved with the hero
+type Hireling struct {
+	State   *d2hero.HirelingState
+	Record  *d2records.HirelingRecord // the row of the hireling at its level
+	MonStat *d2records.MonStatRecord  // the monster the hireling looks and moves like
+}
+
+// MonsterKey returns the monstats.txt row of the hireling
+func (h *Hireling) MonsterKey() string {
+	if h.MonStat == nil {
+		return ""
+	}
+
+	return h.MonStat.Key
+}
+
+// Name returns the string table key of the name of the hireling
+func (h *Hireling) Name() string {
+	return h.Record.Name(h.State.NameID)
+}
+
+// Alive returns true if the hireling has not died
+func (h *Hireling) Alive() bool {
+	return !h.State.Dead
+}
+
+// levels returns the levels of the hireling above the level of its row
+func (h *Hireling) levels() int {
+	if levels := h.State.Level - h.Record.Level; levels > 0 {
+		return levels
+	}
+
+	return 0
+}
+
+// Stats returns the stats of the hireling at its level
+func (h *Hireling) Stats() Stats {
+	r, levels := h.Record, h.levels()
+
+	return Stats{
+		MaxLife:      r.HP + r.HPPerLvl*levels,
+		Defense:      r.Defense + r.DefPerLvl*levels,
+		Strength:     r.Str + r.StrPerLvl*levels/statFraction,
+		Dexterity:    r.Dex + r.DexPerLvl*levels/statFraction,
+		AttackRating: r.AR + r.ARPerLvl*levels,
+		MinDamage:    r.DmgMin + r.DmgPerLvl*levels/statFraction,
+		MaxDamage:    r.DmgMax + r.DmgPerLvl*levels/statFraction,
+		Resistance:   r.Resist + r.ResistPerLvl*levels/resistFraction,
+	}
+}
+
+// Skills returns the skills of the hireling at its level
+func (h *Hireling) Skills() []Skill {
+	r, levels := h.Record, h.levels()
+
+	names := [numSkills]string{r.Skill1, r.Skill2, r.Skill3, r.Skill4, r.Skill5, r.Skill6}
+	modes := [numSkills]int{r.Mode1, r.Mode2, r.Mode3, r.Mode4, r.Mode5, r.Mode6}
+	chances := [numSkills]int{r.Chance1, r.Chance2, r.Chance3, r.Chance4, r.Chance5, r.Chance6}
+	chancesPerLevel := [numSkills]int{
+		r.ChancePerLevel1, r.ChancePerLevel2, r.ChancePerLevel3,
+		r.ChancePerLevel4, r.ChancePerLevel5, r.ChancePerLevel6,
+	}
+	skillLevels := [numSkills]int{r.Level1, r.Level2, r.Level3, r.Level4, r.Level5, r.Level6}
+	levelsPerLevel := [numSkills]int{r.LvlPerLvl1, r.LvlPerLvl2, r.LvlPerLvl3, r.LvlPerLvl4, r.LvlPerLvl5, r.LvlPerLvl6}
+
+	skills := make([]Skill, 0, numSkills)
+
+	for idx := range names {
+		if names[idx] == "" {
+			continue
+		}
+
+		skill := Skill{
+			Name:   names[idx],
+			Mode:   modes[idx],
+			Chance: chances[idx] + chancesPerLevel[idx]*levels,
+			Level:  skillLevels[idx] + levelsPerLevel[idx]*levels/skillLevelFraction,
+		}
+
+		if skill.Level < 1 {
+			skill.Level = 1
+		}
+
+		skills = append(skills, skill)
+	}
+
+	return skills
+}
+
+// SkillLevel returns the level the hireling uses the skill with the given name at, 0 if it does
+// not have the skill
+func (h *Hireling) SkillLevel(name string) int {
+	for _, skill := range h.Skills() {
+		if skill.Name == name {
+			return skill.Level
+		}
+	}
+
+	return 0
+}
+
+// BaseSkillLevel returns the level of the skill with the given name, the items of a hireling add
+// no levels to its skills
+func (h *Hireling) BaseSkillLevel(name string) int {
+	return h.SkillLevel(name)
+}
+
+// StatValue returns the value of a stat of the hireling, the stats of its items are not counted yet
+func (h *Hireling) StatValue(name string) int {
+	stats := h.Stats()
+
+	switch name {
+	case "strength":
+		return stats.Strength
+	case "dexterity":
+		return stats.Dexterity
+	case "level":
+		return h.State.Level
+	case "maxhp":
+		return stats.MaxLife
+	}
+
+	return 0
+}
+
+// PickSkill picks what the hireling does next with the given roll function, which returns a
+// number in [0, n). It returns nil when the hireling attacks with its weapon.
+func (h *Hireling) PickSkill(roll func(n int) int) *Skill {
+	skills := h.Skills()
+	total := h.Record.DefaultChance
+
+	for idx := range skills {
+		total += skills[idx].Chance
+	}
+
+	if total <= 0 {
+		return nil
+	}
+
+	pick := roll(total) - h.Record.DefaultChance
+
+	for idx := range skills {
+		if pick < 0 {
+			break
+		}
+
+		if pick < skills[idx].Chance {
+			return &skills[idx]
+		}
+
+		pick -= skills[idx].Chance
+	}
+
+	return nil
+}
+
+// AddExperience gives the hireling experience and returns the number of levels it gained. The
+// hireling does not level past the given level of its player. A hireling which gains a level
+// has its life refilled.
+func (h *Hireling) AddExperience(experience, playerLevel int) int {
+	if experience <= 0 || !h.Alive() {
+		return 0
+	}
+
+	h.State.Experience += experience
+
+	maxLevel := playerLevel
+	if maxLevel > MaxLevel {
+		maxLevel = MaxLevel
+	}
+
+	gained := 0
+
+	for h.State.Level < maxLevel && h.State.Experience >= h.Record.ExperienceForLevel(h.State.Level+1) {
+		h.State.Level++
+		gained++
+	}
+
+	if gained > 0 {
+		h.State.Life = h.Stats().MaxLife
+	}
+
+	return gained
+}
+
+// HirePrice returns the gold it costs to hire the hireling
+func (h *Hireling) HirePrice() int {
+	return h.Record.Gold * (100 + hirePricePerLevel*h.levels()) / 100 //nolint:gomnd // percent
+}
+
+// ResurrectPrice returns the gold it costs to resurrect the hireling, it grows with the square of
+// the level of the hireling up to a limit
+func (h *Hireling) ResurrectPrice() int {
+	price := h.State.Level * h.State.Level * resurrectPriceScale / 2 //nolint:gomnd // half the scale
+
+	if price > maxResurrectPrice {
+		return maxResurrectPrice
+	}
+
+	return price
+}
+
+// Resurrect brings the hireling back to life with all of its life
+func (h *Hireling) Resurrect() {
+	h.State.Dead = false
+	h.State.Life = h.Stats().MaxLife
+}
+
+// Die marks the hireling dead, it stays dead until it is resurrected
+func (h *Hireling) Die() {
+	h.State.Dead = true
+	h.State.Life = 0
+}
+
+// CanEquip returns true if the hireling can wear an item of the given item types in the given
+// slot. The item types are the types of the item and the types they are equivalent to.
+func (h *Hireling) CanEquip(slot d2enum.EquippedSlot, itemTypes []string) bool {
+	r := h.Record
+
+	switch slot {
+	case d2enum.EquippedSlotHead:
+		return r.Head > 0 && hasType(itemTypes, "helm")
+	case d2enum.EquippedSlotTorso:
+		return r.Torso > 0 && hasType(itemTypes, "tors")
+	case d2enum.EquippedSlotRightArm:
+		return r.Weapon > 0 && (hasType(itemTypes, r.WType1) || hasType(itemTypes, r.WType2))
+	case d2enum.EquippedSlotLeftArm:
+		return r.Shield > 0 && hasType(itemTypes, "shie")
+	}
+
+	return false
+}
+
+func hasType(itemTypes []string, want string) bool {
+	if want == "" {
+		return false
+	}
+
+	for _, itemType := range itemTypes {
+		if itemType == want {
+			return true
+		}
+	}
+
+	return false
+}
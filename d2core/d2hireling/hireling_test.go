@@ -0,0 +1,158 @@
+package d2hireling
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func testRecords() *d2records.RecordManager {
+	records := &d2records.RecordManager{}
+	records.Monster.Stats = d2records.MonStats{
+		"roguehire": {Key: "roguehire", ID: 271},
+	}
+
+	rogue := func(id, difficulty, level int) *d2records.HirelingRecord {
+		return &d2records.HirelingRecord{
+			Hireling: "Rogue Scout", SubType: "Fire Arrow", ID: id, Class: 271, Act: 1,
+			Difficulty: difficulty, Level: level, NameFirst: "merc01", NameLast: "merc41",
+			Gold: 250, ExpPerLvl: 100, HP: 45, HPPerLvl: 9, Str: 35, StrPerLvl: 8, Dex: 45, DexPerLvl: 12,
+			DmgMin: 1, DmgMax: 3, DmgPerLvl: 8, Resist: 0, ResistPerLvl: 4,
+			WType1: "bow", DefaultChance: 50,
+			Skill1: "Fire Arrow", Chance1: 50, Level1: 1, LvlPerLvl1: 32,
+			Head: 1, Torso: 1, Weapon: 1,
+		}
+	}
+
+	records.Hireling.Details = d2records.Hirelings{rogue(0, 1, 3), rogue(1, 1, 15), rogue(2, 2, 30)}
+
+	return records
+}
+
+func TestStatsGrowWithLevel(t *testing.T) {
+	f := NewFactory(testRecords(), 1)
+
+	hireling, err := f.Load(&d2hero.HirelingState{ID: 0, Level: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := hireling.Stats()
+	if stats.MaxLife != 63 || stats.Strength != 37 || stats.Dexterity != 48 || stats.MaxDamage != 5 || stats.Resistance != 2 {
+		t.Fatalf("unexpected stats at level 5: %+v", stats)
+	}
+
+	skills := hireling.Skills()
+	if len(skills) != 1 || skills[0].Level != 3 || hireling.SkillLevel("Fire Arrow") != 3 {
+		t.Fatalf("unexpected skills at level 5: %+v", skills)
+	}
+}
+
+func TestLevelsWithExperience(t *testing.T) {
+	f := NewFactory(testRecords(), 1)
+	state := &d2hero.HirelingState{ID: 0, Level: 3, Experience: 3600}
+
+	hireling, err := f.Load(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// level 4 takes 100*4*4*5 and level 5 takes 100*5*5*6 experience
+	if gained := hireling.AddExperience(8000, 10); gained != 1 || state.Level != 4 {
+		t.Fatalf("expected to reach level 4, gained %d to level %d", gained, state.Level)
+	}
+
+	if state.Life != hireling.Stats().MaxLife {
+		t.Fatalf("expected the life to be refilled on a level up, got %d", state.Life)
+	}
+
+	if gained := hireling.AddExperience(100000, 5); gained != 1 || state.Level != 5 {
+		t.Fatalf("expected the level of the player to cap the hireling, gained %d to level %d", gained, state.Level)
+	}
+
+	if level := hireling.Record.LevelForExperience(state.Experience); level < state.Level {
+		t.Fatalf("experience %d gives level %d, below %d", state.Experience, level, state.Level)
+	}
+}
+
+func TestOffersPickTheRowOfThePlayerLevel(t *testing.T) {
+	f := NewFactory(testRecords(), 1)
+
+	offers := f.Offers(1, d2enum.DifficultyNormal, 20, 5)
+	if len(offers) != 5 {
+		t.Fatalf("expected 5 offers, got %d", len(offers))
+	}
+
+	for _, offer := range offers {
+		if offer.Record.ID != 1 {
+			t.Fatalf("expected the row of level 15, got %d", offer.Record.ID)
+		}
+
+		if offer.State.Level < 17 || offer.State.Level > 20 || offer.State.Seed == 0 {
+			t.Fatalf("unexpected offer %+v", offer.State)
+		}
+
+		if offer.HirePrice() <= offer.Record.Gold || offer.MonsterKey() != "roguehire" {
+			t.Fatalf("unexpected price %d or monster %q", offer.HirePrice(), offer.MonsterKey())
+		}
+	}
+
+	if offers := f.Offers(2, d2enum.DifficultyNormal, 20, 5); len(offers) != 0 {
+		t.Fatalf("expected no offers in act 2, got %d", len(offers))
+	}
+}
+
+func TestEquipmentSlots(t *testing.T) {
+	f := NewFactory(testRecords(), 1)
+
+	hireling, err := f.Load(&d2hero.HirelingState{ID: 0, Level: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		slot  d2enum.EquippedSlot
+		types []string
+		want  bool
+	}{
+		{d2enum.EquippedSlotHead, []string{"helm", "armo"}, true},
+		{d2enum.EquippedSlotTorso, []string{"tors", "armo"}, true},
+		{d2enum.EquippedSlotRightArm, []string{"bow", "miss", "weap"}, true},
+		{d2enum.EquippedSlotRightArm, []string{"swor", "mele", "weap"}, false},
+		{d2enum.EquippedSlotLeftArm, []string{"shie", "armo"}, false},
+		{d2enum.EquippedSlotGloves, []string{"glov", "armo"}, false},
+	}
+
+	for _, test := range tests {
+		if got := hireling.CanEquip(test.slot, test.types); got != test.want {
+			t.Errorf("CanEquip(%d, %v) = %v, want %v", test.slot, test.types, got, test.want)
+		}
+	}
+}
+
+func TestPickSkillAndNames(t *testing.T) {
+	f := NewFactory(testRecords(), 1)
+
+	hireling, err := f.Load(&d2hero.HirelingState{ID: 0, Level: 3, NameID: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if skill := hireling.PickSkill(func(int) int { return 10 }); skill != nil {
+		t.Fatalf("expected a weapon attack, got %+v", skill)
+	}
+
+	if skill := hireling.PickSkill(func(int) int { return 60 }); skill == nil || skill.Name != "Fire Arrow" {
+		t.Fatalf("expected Fire Arrow, got %+v", skill)
+	}
+
+	if name := hireling.Name(); name != "merc10" {
+		t.Fatalf("expected the name merc10, got %q", name)
+	}
+
+	if count := hireling.Record.NameCount(); count != 41 {
+		t.Fatalf("expected 41 names, got %d", count)
+	}
+}
@@ -0,0 +1,672 @@
+package diablo2item
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+// the codes of the inputs and outputs of the recipes which are not item or item type codes
+const (
+	cubeAnyItem = "any"
+	cubeUseItem = "useitem" // the output is the first input, changed by the output qualifiers
+	cubeUseType = "usetype" // the output is a new item with the base of the first input
+)
+
+// the qualifiers of the inputs and outputs of the recipes, besides the qualities
+const (
+	cubeNoSockets   = "nos"
+	cubeSockets     = "sock="
+	cubeEthereal    = "eth"
+	cubeNotEthereal = "noe"
+	cubeNoRuneword  = "nru"
+	cubeUpgraded    = "upg"
+	cubeBasic       = "bas"
+	cubeExceptional = "exc"
+	cubeElite       = "eli"
+	cubeModifiers   = "mod" // the output keeps the modifiers of the first input
+	cubeUnsocket    = "uns" // the items in the sockets are destroyed
+	cubeRemove      = "rem" // the items in the sockets are put in the cube
+	cubeRegenerate  = "reg" // the modifiers are rolled again
+	cubeRepair      = "rep"
+	cubeRecharge    = "rch"
+	cubePrefix      = "pre="
+	cubeSuffix      = "suf="
+)
+
+// cubeVersionExpansion is the version of the recipes which only work in expansion games
+const cubeVersionExpansion = 100
+
+// the operations of the requirements of the recipes: the first two and the last two compare a
+// stat of the player with the value of the requirement, the others a stat of the first input
+const (
+	cubeOpNone = iota
+	cubeOpPlayerAtLeast
+	cubeOpPlayerBelow
+	cubeOpEqual
+	cubeOpNotEqual
+	cubeOpGreater
+	cubeOpAtLeast
+	cubeOpLess
+	cubeOpAtMost
+)
+
+const (
+	cubeOpPlayerEqual = iota + 27 //nolint:gomnd // the unusual operations are numbered last
+	cubeOpPlayerNotEqual
+)
+
+// cubeChances are the chances of the output modifiers in percent
+const cubeChances = 100
+
+// ErrNoCubeRecipe is returned when no recipe can be made from the items in the cube
+var ErrNoCubeRecipe = errors.New("no cube recipe matches the items")
+
+// cubeQualities are the quality qualifiers of the inputs and outputs of the recipes
+var cubeQualities = map[string]d2enum.ItemQuality{ //nolint:gochecknoglobals // lookup table
+	"low": d2enum.LowQuality,
+	"nor": d2enum.Normal,
+	"hiq": d2enum.Superior,
+	"mag": d2enum.Magic,
+	"set": d2enum.Set,
+	"rar": d2enum.Rare,
+	"uni": d2enum.Unique,
+	"crf": d2enum.Crafted,
+	"tmp": d2enum.Tempered,
+}
+
+// cubeDropModifiers are the drop modifiers the output qualities are rolled with, crafted and
+// tempered items are rolled like rare items
+var cubeDropModifiers = map[d2enum.ItemQuality]dropModifier{ //nolint:gochecknoglobals // lookup table
+	d2enum.Magic:    dropModifierMagic,
+	d2enum.Set:      dropModifierSet,
+	d2enum.Rare:     dropModifierRare,
+	d2enum.Unique:   dropModifierUnique,
+	d2enum.Crafted:  dropModifierRare,
+	d2enum.Tempered: dropModifierRare,
+}
+
+// cubePortals are the outputs which open a portal instead of making an item
+var cubePortals = map[string]bool{ //nolint:gochecknoglobals // lookup table
+	"Cow Portal":                true,
+	"Pandemonium Portal":        true,
+	"Pandemonium Finale Portal": true,
+	"Red Portal":                true,
+}
+
+// CubeContext holds what a transmutation in the Horadric Cube depends on besides the items in
+// the cube
+type CubeContext struct {
+	Difficulty d2enum.DifficultyType
+	Ladder     bool // ladder games have recipes of their own
+	Expansion  bool
+	Class      d2enum.Hero
+	Level      int            // level of the player, some outputs get a part of it as their level
+	Stats      map[string]int // stats of the player by name, for the requirements of the recipes
+}
+
+// CubeResult is the outcome of a transmutation in the Horadric Cube
+type CubeResult struct {
+	Recipe *d2records.CubeRecipeRecord
+	Items  []*Item // the items in the cube after the transmutation, the other inputs are used up
+	Portal string  // the portal the recipe opens, like "Cow Portal", or an empty string
+}
+
+// FindCubeRecipe returns the first enabled recipe which can be made from the given items, or nil
+// if there is none
+func (f *ItemFactory) FindCubeRecipe(items []*Item, ctx *CubeContext) *d2records.CubeRecipeRecord {
+	recipe, _ := f.findCubeRecipe(items, ctx)
+	return recipe
+}
+
+// Transmute makes the outputs of the recipe which matches the given items. The items are not
+// changed if no recipe matches, ErrNoCubeRecipe is returned then.
+func (f *ItemFactory) Transmute(items []*Item, ctx *CubeContext) (*CubeResult, error) {
+	recipe, first := f.findCubeRecipe(items, ctx)
+	if recipe == nil {
+		return nil, ErrNoCubeRecipe
+	}
+
+	result := &CubeResult{Recipe: recipe, Items: make([]*Item, 0)}
+
+	for idx := range recipe.Outputs {
+		if err := f.cubeOutput(&recipe.Outputs[idx], first, ctx, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// findCubeRecipe returns the first recipe which can be made from the given items, and the item
+// used as its first input
+func (f *ItemFactory) findCubeRecipe(items []*Item, ctx *CubeContext) (*d2records.CubeRecipeRecord, *Item) {
+	for _, recipe := range f.asset.Records.Item.Cube.Recipes {
+		if !cubeRecipeAllowed(recipe, ctx) {
+			continue
+		}
+
+		first, matched := f.matchCubeInputs(recipe.Inputs, items)
+		if !matched || !f.cubeRequirementMet(recipe, ctx, first) {
+			continue
+		}
+
+		return recipe, first
+	}
+
+	return nil, nil
+}
+
+// cubeRecipeAllowed returns true if the recipe can be used in the game and by the class of the
+// context
+func cubeRecipeAllowed(recipe *d2records.CubeRecipeRecord, ctx *CubeContext) bool {
+	switch {
+	case !recipe.Enabled,
+		recipe.Ladder && !ctx.Ladder,
+		int(ctx.Difficulty) < recipe.MinDiff,
+		recipe.Version >= cubeVersionExpansion && !ctx.Expansion:
+		return false
+	}
+
+	classSpecific := false
+
+	for _, class := range recipe.Class {
+		if class == d2enum.HeroNone {
+			continue
+		}
+
+		if class == ctx.Class {
+			return true
+		}
+
+		classSpecific = true
+	}
+
+	return !classSpecific
+}
+
+// cubeRequirementMet returns true if the stat of the requirement of the recipe passes its
+// comparison
+func (f *ItemFactory) cubeRequirementMet(recipe *d2records.CubeRecipeRecord, ctx *CubeContext, first *Item) bool {
+	if recipe.ReqOperation == cubeOpNone {
+		return true
+	}
+
+	name := ""
+
+	for _, record := range f.asset.Records.Item.Stats {
+		if record.Index == recipe.ReqStatID {
+			name = record.Name
+			break
+		}
+	}
+
+	player, item, value := ctx.Stats[name], first.statValue(name), recipe.ReqValue
+
+	switch recipe.ReqOperation {
+	case cubeOpPlayerAtLeast:
+		return player >= value
+	case cubeOpPlayerBelow:
+		return player < value
+	case cubeOpEqual:
+		return item == value
+	case cubeOpNotEqual:
+		return item != value
+	case cubeOpGreater:
+		return item > value
+	case cubeOpAtLeast:
+		return item >= value
+	case cubeOpLess:
+		return item < value
+	case cubeOpAtMost:
+		return item <= value
+	case cubeOpPlayerEqual:
+		return player == value
+	case cubeOpPlayerNotEqual:
+		return player != value
+	}
+
+	return false
+}
+
+// matchCubeInputs returns true if every item is used by an input of the recipe and every input
+// gets as many items as its count, and the item used by the first input
+func (f *ItemFactory) matchCubeInputs(inputs []d2records.CubeRecipeItem, items []*Item) (first *Item, matched bool) {
+	remaining := make([]int, len(inputs))
+	total := 0
+
+	for idx := range inputs {
+		if inputs[idx].Code != "" {
+			remaining[idx] = inputs[idx].Count
+			total += inputs[idx].Count
+		}
+	}
+
+	if total == 0 || total != len(items) {
+		return nil, false
+	}
+
+	assigned := make([]int, len(items))
+	if !f.assignCubeInputs(inputs, items, remaining, assigned, 0) {
+		return nil, false
+	}
+
+	for idx := range inputs {
+		for itemIdx, input := range assigned {
+			if input == idx {
+				return items[itemIdx], true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// assignCubeInputs finds an input for every item from the given one on, the inputs the items
+// are given are written to assigned
+func (f *ItemFactory) assignCubeInputs(inputs []d2records.CubeRecipeItem, items []*Item, remaining, assigned []int,
+	itemIdx int) bool {
+	if itemIdx == len(items) {
+		return true
+	}
+
+	for idx := range inputs {
+		if remaining[idx] == 0 || !f.cubeItemMatches(items[itemIdx], &inputs[idx]) {
+			continue
+		}
+
+		remaining[idx]--
+		assigned[itemIdx] = idx
+
+		if f.assignCubeInputs(inputs, items, remaining, assigned, itemIdx+1) {
+			return true
+		}
+
+		remaining[idx]++
+	}
+
+	return false
+}
+
+// cubeItemMatches returns true if the item is of the code of the input and has its qualifiers
+func (f *ItemFactory) cubeItemMatches(item *Item, input *d2records.CubeRecipeItem) bool {
+	if !f.cubeCodeMatches(item, input.Code) {
+		return false
+	}
+
+	for _, param := range input.Params {
+		if !item.hasCubeQualifier(param) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cubeCodeMatches returns true if the code is the code of the item, of its type or of a type
+// its type is equivalent to
+func (f *ItemFactory) cubeCodeMatches(item *Item, code string) bool {
+	common := item.CommonRecord()
+
+	switch {
+	case code == cubeAnyItem, code == item.CommonCode:
+		return true
+	case common == nil:
+		return false
+	case code == common.Type, code == common.Type2:
+		return true
+	}
+
+	for _, equivalent := range f.asset.Records.FindEquivalentTypesByItemCommonRecord(common) {
+		if equivalent == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasCubeQualifier returns true if the item has the qualifier of an input. The qualifiers of the
+// outputs do not restrict the inputs.
+func (i *Item) hasCubeQualifier(param string) bool {
+	if quality, found := cubeQualities[param]; found {
+		return i.Quality() == quality
+	}
+
+	switch {
+	case param == cubeNoSockets:
+		return i.numSockets() == 0
+	case strings.HasPrefix(param, cubeSockets):
+		count, found := cubeParamValue(param, cubeSockets)
+		return found && i.numSockets() == count
+	case param == cubeEthereal:
+		return i.attributes.ethereal
+	case param == cubeNotEthereal:
+		return !i.attributes.ethereal
+	case param == cubeNoRuneword:
+		return i.RunewordCode == ""
+	case param == cubeUpgraded:
+		return i.tier() != cubeBasic
+	case param == cubeBasic, param == cubeExceptional, param == cubeElite:
+		return i.tier() == param
+	}
+
+	return true
+}
+
+// cubeOutput adds an output of the recipe to the result
+func (f *ItemFactory) cubeOutput(output *d2records.CubeRecipeResult, first *Item, ctx *CubeContext,
+	result *CubeResult) error {
+	code := output.Item.Code
+
+	switch {
+	case code == "":
+		return nil
+	case cubePortals[code]:
+		result.Portal = code
+		return nil
+	case code == cubeUseItem:
+		if level := cubeOutputLevel(output, first, ctx); level > 0 {
+			first.level = level
+			first.updateItemAttributes()
+		}
+
+		result.Items = append(result.Items, first)
+		f.changeCubeItem(first, output, result)
+
+		return nil
+	case code == cubeUseType:
+		code = first.CommonCode
+	}
+
+	// a stackable output is one stack of the count, the others are as many items
+	count := output.Item.Count
+	if common := f.asset.Records.Item.All[code]; common != nil && common.Stackable {
+		count = 1
+	}
+
+	for idx := 0; idx < count; idx++ {
+		item, err := f.newCubeItem(code, output, first, ctx)
+		if err != nil {
+			return err
+		}
+
+		result.Items = append(result.Items, item)
+		f.changeCubeItem(item, output, result)
+	}
+
+	return nil
+}
+
+// newCubeItem makes a new item for an output, the code is an item code or an item type code
+func (f *ItemFactory) newCubeItem(code string, output *d2records.CubeRecipeResult, first *Item,
+	ctx *CubeContext) (*Item, error) {
+	level := cubeOutputLevel(output, first, ctx)
+
+	common := f.asset.Records.Item.All[code]
+	if common == nil {
+		common = f.pickCubeItemOfType(code, level)
+	}
+
+	if common == nil {
+		return nil, fmt.Errorf("unknown cube output %q", code)
+	}
+
+	// nolint:gosec // we're not concerned with crypto-strong randomness
+	item := &Item{factory: f, CommonCode: common.Code, level: level, rand: rand.New(rand.NewSource(f.rand.Int63()))}
+
+	quality := d2enum.Normal
+
+	for _, param := range output.Item.Params {
+		if found, ok := cubeQualities[param]; ok {
+			quality = found
+		}
+	}
+
+	if quality == d2enum.Crafted {
+		item.attributes = &itemAttributes{crafted: true}
+	}
+
+	if modifier, found := cubeDropModifiers[quality]; found {
+		item.applyDropModifier(modifier)
+	}
+
+	for _, param := range output.Item.Params {
+		if id, found := cubeParamValue(param, cubePrefix); found {
+			item.PrefixCodes = append(item.PrefixCodes, affixCodes([]int{id}, f.asset.Records.Item.Magic.Prefix)...)
+		}
+
+		if id, found := cubeParamValue(param, cubeSuffix); found {
+			item.SuffixCodes = append(item.SuffixCodes, affixCodes([]int{id}, f.asset.Records.Item.Magic.Suffix)...)
+		}
+	}
+
+	item.init()
+
+	if hasCubeParam(output.Item.Params, cubeModifiers) {
+		item.copyModifiers(first)
+	}
+
+	if common.Stackable && output.Item.Count > 1 {
+		item.quantity = output.Item.Count
+		item.updateItemAttributes()
+	}
+
+	return item, nil
+}
+
+// pickCubeItemOfType picks an item of the given type with a level up to the given level, or of
+// any level if there is none
+func (f *ItemFactory) pickCubeItemOfType(code string, level int) *d2records.ItemCommonRecord {
+	all := f.asset.Records.Item.Equivalency[code]
+	candidates := make([]*d2records.ItemCommonRecord, 0, len(all))
+
+	for _, common := range all {
+		if common.Level <= level {
+			candidates = append(candidates, common)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = all
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return candidates[f.rand.Intn(len(candidates))]
+}
+
+// changeCubeItem applies the qualifiers and modifiers of an output to an output item
+func (f *ItemFactory) changeCubeItem(item *Item, output *d2records.CubeRecipeResult, result *CubeResult) {
+	for _, param := range output.Item.Params {
+		switch {
+		case param == cubeBasic, param == cubeExceptional, param == cubeElite:
+			item.changeTier(param)
+		case param == cubeRemove:
+			result.Items = append(result.Items, item.socketedItems()...)
+			item.unsocket()
+		case param == cubeUnsocket:
+			item.unsocket()
+		case param == cubeRegenerate:
+			item.regenerate()
+		case param == cubeRepair, param == cubeRecharge: // charges are not simulated, recharging repairs
+			item.attributes.currentDurability = item.attributes.durability.max
+		}
+	}
+
+	for _, param := range output.Item.Params {
+		if param == cubeEthereal {
+			item.attributes.ethereal = true
+		}
+
+		if count, found := cubeParamValue(param, cubeSockets); found {
+			item.attributes.numSockets = count
+		}
+	}
+
+	for idx := range output.Properties {
+		modifier := &output.Properties[idx]
+
+		if modifier.Code == "" || modifier.Chance > 0 && f.rand.Intn(cubeChances) >= modifier.Chance {
+			continue
+		}
+
+		if property := f.NewProperty(modifier.Code, modifier.Param, modifier.Min, modifier.Max); property != nil {
+			if item.properties == nil {
+				item.properties = make(map[PropertyPool][]*Property)
+			}
+
+			item.properties[PropertyPoolCube] = append(item.properties[PropertyPoolCube], property)
+		}
+	}
+}
+
+// cubeOutputLevel returns the level of an output: its own level, plus its parts of the level of
+// the player and of the first input
+func cubeOutputLevel(output *d2records.CubeRecipeResult, first *Item, ctx *CubeContext) int {
+	const percent = 100
+
+	return output.Level + output.PLevel*ctx.Level/percent + output.ILevel*first.ItemLevel()/percent
+}
+
+// hasCubeParam returns true if the params have the given qualifier
+func hasCubeParam(params []string, param string) bool {
+	for idx := range params {
+		if params[idx] == param {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cubeParamValue returns the number of a qualifier with the given prefix, like the 3 of sock=3
+func cubeParamValue(param, prefix string) (int, bool) {
+	if !strings.HasPrefix(param, prefix) {
+		return 0, false
+	}
+
+	value, err := strconv.Atoi(strings.TrimPrefix(param, prefix))
+
+	return value, err == nil
+}
+
+// numSockets returns the number of sockets of the item
+func (i *Item) numSockets() int {
+	if len(i.sockets) > i.attributes.numSockets {
+		return len(i.sockets)
+	}
+
+	return i.attributes.numSockets
+}
+
+// statValue returns the sum of the values of the stats of the item with the given name
+func (i *Item) statValue(name string) int {
+	value := 0
+
+	for _, properties := range i.properties {
+		for _, property := range properties {
+			for _, stat := range property.stats {
+				if stat.Name() == name && len(stat.Values()) > 0 {
+					value += stat.Values()[0].Int()
+				}
+			}
+		}
+	}
+
+	return value
+}
+
+// tier returns the cube qualifier of the tier of the base of the item: basic, exceptional or
+// elite
+func (i *Item) tier() string {
+	common := i.CommonRecord()
+
+	switch {
+	case common == nil:
+		return cubeBasic
+	case common.UltraCode != "" && common.UltraCode != common.NormalCode && i.CommonCode == common.UltraCode:
+		return cubeElite
+	case common.UberCode != "" && common.UberCode != common.NormalCode && i.CommonCode == common.UberCode:
+		return cubeExceptional
+	}
+
+	return cubeBasic
+}
+
+// changeTier changes the base of the item to the base of the given tier, if the item has one
+func (i *Item) changeTier(tier string) {
+	common := i.CommonRecord()
+	if common == nil {
+		return
+	}
+
+	code := map[string]string{cubeBasic: common.NormalCode, cubeExceptional: common.UberCode, cubeElite: common.UltraCode}[tier]
+
+	if code == "" || code == i.CommonCode || i.factory.asset.Records.Item.All[code] == nil {
+		return
+	}
+
+	i.CommonCode = code
+	i.updateItemAttributes()
+}
+
+// socketedItems returns the items in the sockets of the item
+func (i *Item) socketedItems() []*Item {
+	items := make([]*Item, 0, len(i.sockets))
+
+	for _, socketed := range i.sockets {
+		if socketed == nil {
+			continue
+		}
+
+		if item, ok := (*socketed).(*Item); ok {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// unsocket empties the sockets of the item, a runeword in them is gone
+func (i *Item) unsocket() {
+	i.sockets = nil
+	i.RunewordCode = ""
+
+	delete(i.properties, PropertyPoolRuneword)
+}
+
+// regenerate rolls the modifiers of the item again
+func (i *Item) regenerate() {
+	ethereal := i.attributes.ethereal
+
+	// nolint:gosec // we're not concerned with crypto-strong randomness
+	i.rand = rand.New(rand.NewSource(i.rand.Int63()))
+	i.init()
+
+	i.attributes.ethereal = ethereal
+}
+
+// copyModifiers gives the item the quality and the rolled modifiers of the other item
+func (i *Item) copyModifiers(other *Item) {
+	i.PrefixCodes, i.SuffixCodes, i.rareNames = other.PrefixCodes, other.SuffixCodes, other.rareNames
+	i.UniqueCode, i.SetCode, i.SetItemCode = other.UniqueCode, other.SetCode, other.SetItemCode
+	i.attributes.crafted = other.attributes.crafted
+
+	i.properties = make(map[PropertyPool][]*Property)
+
+	for pool, properties := range other.properties {
+		if pool != PropertyPoolRuneword {
+			i.properties[pool] = properties
+		}
+	}
+
+	i.updateItemAttributes()
+}
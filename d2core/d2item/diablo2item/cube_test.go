@@ -0,0 +1,499 @@
+package diablo2item
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func cubeTestFactory(t *testing.T) *ItemFactory {
+	factory := serializeTestFactory(t)
+	items := &factory.asset.Records.Item
+
+	axe := func(code string, level int) *d2records.ItemCommonRecord {
+		return &d2records.ItemCommonRecord{Code: code, Type: "axe", Level: level, Durability: 28,
+			NormalCode: "hax", UberCode: "9ha", UltraCode: "7ha"}
+	}
+
+	items.Weapons["hax"], items.Weapons["9ha"], items.Weapons["7ha"] = axe("hax", 3), axe("9ha", 25), axe("7ha", 43)
+	items.Misc["r01"] = &d2records.ItemCommonRecord{Code: "r01", Type: "rune"}
+	items.Misc["r02"] = &d2records.ItemCommonRecord{Code: "r02", Type: "rune"}
+
+	for _, code := range []string{"hax", "9ha", "7ha"} {
+		items.All[code] = items.Weapons[code]
+	}
+
+	items.All["r01"], items.All["r02"] = items.Misc["r01"], items.Misc["r02"]
+
+	items.Types = d2records.ItemTypes{
+		"helm": {Code: "helm", Rare: true},
+		"axe":  {Code: "axe", Rare: true},
+		"jave": {Code: "jave", Rare: true},
+		"gema": {Code: "gema", Normal: true},
+		"rune": {Code: "rune", Normal: true},
+	}
+
+	items.Equivalency = d2records.ItemEquivalenceMap{
+		"helm": {items.All["cap"]},
+		"armo": {items.All["cap"]},
+		"axe":  {items.All["hax"], items.All["9ha"], items.All["7ha"]},
+		"weap": {items.All["hax"], items.All["9ha"], items.All["7ha"], items.All["jav"]},
+		"gem":  {items.All["gsv"]},
+		"rune": {items.All["r01"], items.All["r02"]},
+	}
+
+	return factory
+}
+
+func cubeRecipe(inputs []d2records.CubeRecipeItem, outputs ...d2records.CubeRecipeResult) *d2records.CubeRecipeRecord {
+	return &d2records.CubeRecipeRecord{Enabled: true, Inputs: inputs, Outputs: outputs}
+}
+
+func cubeTestItem(t *testing.T, factory *ItemFactory, codes ...string) *Item {
+	item, err := factory.NewItem(codes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return item
+}
+
+func TestCubeRecipeAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		recipe d2records.CubeRecipeRecord
+		ctx    CubeContext
+		want   bool
+	}{
+		{"enabled", d2records.CubeRecipeRecord{Enabled: true}, CubeContext{}, true},
+		{"disabled", d2records.CubeRecipeRecord{}, CubeContext{}, false},
+		{"ladder in ladder game", d2records.CubeRecipeRecord{Enabled: true, Ladder: true}, CubeContext{Ladder: true}, true},
+		{"ladder in other game", d2records.CubeRecipeRecord{Enabled: true, Ladder: true}, CubeContext{}, false},
+		{"hell in nightmare", d2records.CubeRecipeRecord{Enabled: true, MinDiff: 2},
+			CubeContext{Difficulty: d2enum.DifficultyNightmare}, false},
+		{"nightmare in hell", d2records.CubeRecipeRecord{Enabled: true, MinDiff: 1},
+			CubeContext{Difficulty: d2enum.DifficultyHell}, true},
+		{"expansion in classic", d2records.CubeRecipeRecord{Enabled: true, Version: 100}, CubeContext{}, false},
+		{"expansion in expansion", d2records.CubeRecipeRecord{Enabled: true, Version: 100},
+			CubeContext{Expansion: true}, true},
+		{"class of the player", d2records.CubeRecipeRecord{Enabled: true,
+			Class: []d2enum.Hero{d2enum.HeroAmazon, d2enum.HeroDruid}}, CubeContext{Class: d2enum.HeroDruid}, true},
+		{"class of another player", d2records.CubeRecipeRecord{Enabled: true,
+			Class: []d2enum.Hero{d2enum.HeroAmazon}}, CubeContext{Class: d2enum.HeroDruid}, false},
+		{"empty class", d2records.CubeRecipeRecord{Enabled: true,
+			Class: []d2enum.Hero{d2enum.HeroNone}}, CubeContext{Class: d2enum.HeroDruid}, true},
+	}
+
+	for idx := range tests {
+		test := &tests[idx]
+
+		if got := cubeRecipeAllowed(&test.recipe, &test.ctx); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCubeRequirementMet(t *testing.T) {
+	factory := cubeTestFactory(t)
+	item := cubeTestItem(t, factory, "cap")
+
+	item.properties = map[PropertyPool][]*Property{
+		PropertyPoolPrefix: {factory.NewProperty("str", 12, 12)},
+	}
+
+	tests := []struct {
+		op, value int
+		want      bool
+	}{
+		{cubeOpNone, 0, true},
+		{cubeOpPlayerAtLeast, 30, true},
+		{cubeOpPlayerAtLeast, 31, false},
+		{cubeOpPlayerBelow, 31, true},
+		{cubeOpPlayerBelow, 30, false},
+		{cubeOpEqual, 12, true},
+		{cubeOpEqual, 11, false},
+		{cubeOpNotEqual, 11, true},
+		{cubeOpGreater, 11, true},
+		{cubeOpGreater, 12, false},
+		{cubeOpAtLeast, 12, true},
+		{cubeOpLess, 12, false},
+		{cubeOpLess, 13, true},
+		{cubeOpAtMost, 12, true},
+		{cubeOpAtMost, 11, false},
+		{cubeOpPlayerEqual, 30, true},
+		{cubeOpPlayerNotEqual, 30, false},
+		{99, 0, false},
+	}
+
+	ctx := &CubeContext{Stats: map[string]int{"strength": 30}}
+
+	for _, test := range tests {
+		recipe := &d2records.CubeRecipeRecord{ReqStatID: 0, ReqOperation: test.op, ReqValue: test.value}
+
+		if got := factory.cubeRequirementMet(recipe, ctx, item); got != test.want {
+			t.Errorf("operation %d with value %d: got %v, want %v", test.op, test.value, got, test.want)
+		}
+	}
+}
+
+func TestCubeQualifiers(t *testing.T) {
+	factory := cubeTestFactory(t)
+
+	normal := cubeTestItem(t, factory, "hax")
+	magic := cubeTestItem(t, factory, "cap", "Strong")
+	rare := cubeTestItem(t, factory, "cap", "Strong", "Mighty", "of Charging")
+	unique := cubeTestItem(t, factory, "cap", "Biggin's Bonnet")
+	elite := cubeTestItem(t, factory, "7ha")
+	exceptional := cubeTestItem(t, factory, "9ha")
+
+	socketed := cubeTestItem(t, factory, "hax")
+	socketed.attributes.numSockets = 3
+	socketed.attributes.ethereal = true
+	socketed.RunewordCode = "Runeword1"
+
+	tests := []struct {
+		name  string
+		item  *Item
+		param string
+		want  bool
+	}{
+		{"normal is nor", normal, "nor", true},
+		{"normal is not mag", normal, "mag", false},
+		{"magic is mag", magic, "mag", true},
+		{"rare is rar", rare, "rar", true},
+		{"rare is not mag", rare, "mag", false},
+		{"unique is uni", unique, "uni", true},
+		{"no sockets is nos", normal, cubeNoSockets, true},
+		{"sockets are not nos", socketed, cubeNoSockets, false},
+		{"3 sockets are sock=3", socketed, "sock=3", true},
+		{"3 sockets are not sock=2", socketed, "sock=2", false},
+		{"ethereal is eth", socketed, cubeEthereal, true},
+		{"ethereal is not noe", socketed, cubeNotEthereal, false},
+		{"normal is noe", normal, cubeNotEthereal, true},
+		{"runeword is not nru", socketed, cubeNoRuneword, false},
+		{"normal is nru", normal, cubeNoRuneword, true},
+		{"basic is bas", normal, cubeBasic, true},
+		{"basic is not upg", normal, cubeUpgraded, false},
+		{"exceptional is exc", exceptional, cubeExceptional, true},
+		{"exceptional is upg", exceptional, cubeUpgraded, true},
+		{"elite is eli", elite, cubeElite, true},
+		{"elite is not exc", elite, cubeExceptional, false},
+		{"output qualifiers do not restrict", normal, cubeRegenerate, true},
+	}
+
+	for _, test := range tests {
+		if got := test.item.hasCubeQualifier(test.param); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMatchCubeInputs(t *testing.T) {
+	factory := cubeTestFactory(t)
+
+	inputs := []d2records.CubeRecipeItem{
+		{Code: "weap", Params: []string{cubeNoSockets}, Count: 1},
+		{Code: "gem", Count: 2},
+		{Code: "", Count: 1},
+	}
+
+	axe, capItem := cubeTestItem(t, factory, "hax"), cubeTestItem(t, factory, "cap")
+	gem1, gem2, gem3 := cubeTestItem(t, factory, "gsv"), cubeTestItem(t, factory, "gsv"), cubeTestItem(t, factory, "gsv")
+
+	socketed := cubeTestItem(t, factory, "hax")
+	socketed.attributes.numSockets = 2
+
+	tests := []struct {
+		name  string
+		items []*Item
+		want  bool
+	}{
+		{"in order", []*Item{axe, gem1, gem2}, true},
+		{"any order", []*Item{gem1, axe, gem2}, true},
+		{"too few", []*Item{axe, gem1}, false},
+		{"too many", []*Item{axe, gem1, gem2, gem3}, false},
+		{"wrong type", []*Item{capItem, gem1, gem2}, false},
+		{"wrong qualifier", []*Item{socketed, gem1, gem2}, false},
+		{"nothing", []*Item{}, false},
+	}
+
+	for _, test := range tests {
+		first, matched := factory.matchCubeInputs(inputs, test.items)
+		if matched != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, matched, test.want)
+			continue
+		}
+
+		if matched && first != axe {
+			t.Errorf("%s: got first input %v, want the axe", test.name, first)
+		}
+	}
+}
+
+func TestMatchCubeInputsBacktracks(t *testing.T) {
+	factory := cubeTestFactory(t)
+
+	// the axe matches both inputs, it must be given to the second for the cap to match the first
+	inputs := []d2records.CubeRecipeItem{
+		{Code: cubeAnyItem, Count: 1},
+		{Code: "axe", Count: 1},
+	}
+
+	axe, capItem := cubeTestItem(t, factory, "hax"), cubeTestItem(t, factory, "cap")
+
+	first, matched := factory.matchCubeInputs(inputs, []*Item{axe, capItem})
+	if !matched {
+		t.Fatal("inputs did not match")
+	}
+
+	if first != capItem {
+		t.Errorf("got first input %s, want cap", first.CommonCode)
+	}
+}
+
+func TestTransmute(t *testing.T) {
+	factory := cubeTestFactory(t)
+	runes := &d2records.CubeRecipeRecord{
+		Enabled: true,
+		Inputs:  []d2records.CubeRecipeItem{{Code: "r01", Count: 3}},
+		Outputs: []d2records.CubeRecipeResult{{Item: d2records.CubeRecipeItem{Code: "r02", Count: 1}}},
+	}
+	ladder := &d2records.CubeRecipeRecord{
+		Enabled: true,
+		Ladder:  true,
+		Inputs:  []d2records.CubeRecipeItem{{Code: "r02", Count: 1}},
+		Outputs: []d2records.CubeRecipeResult{{Item: d2records.CubeRecipeItem{Code: "r01", Count: 2}}},
+	}
+	factory.asset.Records.Item.Cube.Recipes = d2records.CubeRecipes{ladder, runes}
+
+	items := []*Item{cubeTestItem(t, factory, "r01"), cubeTestItem(t, factory, "r01"), cubeTestItem(t, factory, "r01")}
+
+	result, err := factory.Transmute(items, &CubeContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Recipe != runes || len(result.Items) != 1 || result.Items[0].CommonCode != "r02" {
+		t.Fatalf("got %+v, want one r02", result)
+	}
+
+	if _, err := factory.Transmute(items[:2], &CubeContext{}); err != ErrNoCubeRecipe {
+		t.Errorf("got error %v, want ErrNoCubeRecipe", err)
+	}
+
+	if recipe := factory.FindCubeRecipe(result.Items, &CubeContext{}); recipe != nil {
+		t.Error("found a ladder recipe outside of a ladder game")
+	}
+
+	result, err = factory.Transmute(result.Items, &CubeContext{Ladder: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Items) != 2 {
+		t.Errorf("got %d items, want 2", len(result.Items))
+	}
+}
+
+func TestTransmuteStack(t *testing.T) {
+	factory := cubeTestFactory(t)
+	factory.asset.Records.Item.Cube.Recipes = d2records.CubeRecipes{cubeRecipe(
+		[]d2records.CubeRecipeItem{{Code: "gem", Count: 1}},
+		d2records.CubeRecipeResult{Item: d2records.CubeRecipeItem{Code: "jav", Count: 20}},
+	)}
+
+	result, err := factory.Transmute([]*Item{cubeTestItem(t, factory, "gsv")}, &CubeContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Items) != 1 || result.Items[0].Quantity() != 20 {
+		t.Errorf("got %d items, want one stack of 20", len(result.Items))
+	}
+}
+
+func TestTransmuteUseItem(t *testing.T) {
+	factory := cubeTestFactory(t)
+
+	tests := []struct {
+		name   string
+		params []string
+		check  func(item *Item) bool
+	}{
+		{"sockets", []string{"sock=3"}, func(item *Item) bool { return item.numSockets() == 3 }},
+		{"exceptional", []string{cubeExceptional}, func(item *Item) bool { return item.CommonCode == "9ha" }},
+		{"elite", []string{cubeElite}, func(item *Item) bool { return item.CommonCode == "7ha" }},
+		{"ethereal", []string{cubeEthereal}, func(item *Item) bool { return item.attributes.ethereal }},
+		{"repair", []string{cubeRepair}, func(item *Item) bool { return item.attributes.currentDurability == 28 }},
+	}
+
+	for _, test := range tests {
+		factory.asset.Records.Item.Cube.Recipes = d2records.CubeRecipes{cubeRecipe(
+			[]d2records.CubeRecipeItem{{Code: "axe", Params: []string{cubeNoSockets}, Count: 1}, {Code: "r01", Count: 1}},
+			d2records.CubeRecipeResult{Item: d2records.CubeRecipeItem{Code: cubeUseItem, Params: test.params, Count: 1}},
+		)}
+
+		axe := cubeTestItem(t, factory, "hax")
+		axe.attributes.currentDurability = 1
+
+		result, err := factory.Transmute([]*Item{cubeTestItem(t, factory, "r01"), axe}, &CubeContext{})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if len(result.Items) != 1 || result.Items[0] != axe {
+			t.Errorf("%s: got %d items, want the axe", test.name, len(result.Items))
+			continue
+		}
+
+		if !test.check(axe) {
+			t.Errorf("%s: the axe was not changed", test.name)
+		}
+	}
+}
+
+func TestTransmuteRemoveSocketed(t *testing.T) {
+	factory := cubeTestFactory(t)
+	factory.asset.Records.Item.Cube.Recipes = d2records.CubeRecipes{cubeRecipe(
+		[]d2records.CubeRecipeItem{{Code: "weap", Count: 1}, {Code: "r02", Count: 1}},
+		d2records.CubeRecipeResult{Item: d2records.CubeRecipeItem{Code: cubeUseItem, Params: []string{cubeRemove}, Count: 1}},
+	)}
+
+	axe := cubeTestItem(t, factory, "hax")
+
+	var gem d2item.Item = cubeTestItem(t, factory, "gsv")
+
+	axe.attributes.numSockets = 2
+	axe.sockets = append(axe.sockets, &gem)
+	axe.RunewordCode = "Runeword1"
+
+	result, err := factory.Transmute([]*Item{axe, cubeTestItem(t, factory, "r02")}, &CubeContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Items) != 2 || result.Items[1] != gem {
+		t.Fatalf("got %d items, want the axe and the gem", len(result.Items))
+	}
+
+	if len(axe.sockets) != 0 || axe.RunewordCode != "" {
+		t.Error("the sockets of the axe were not emptied")
+	}
+}
+
+func TestTransmuteNewItem(t *testing.T) {
+	factory := cubeTestFactory(t)
+	recipe := cubeRecipe(
+		[]d2records.CubeRecipeItem{{Code: "helm", Params: []string{"mag"}, Count: 1}, {Code: "gem", Count: 1}},
+		d2records.CubeRecipeResult{
+			Item:   d2records.CubeRecipeItem{Code: cubeUseType, Params: []string{"crf"}, Count: 1},
+			Level:  5,
+			PLevel: 50,
+			ILevel: 50,
+			Properties: []d2records.CubeRecipeItemProperty{
+				{Code: "str", Min: 7, Max: 7},
+				{Code: "str", Chance: 1, Min: 100, Max: 100},
+			},
+		},
+		d2records.CubeRecipeResult{
+			Item:   d2records.CubeRecipeItem{Code: "weap", Count: 1},
+			Level:  10,
+			ILevel: 0,
+		},
+	)
+	factory.asset.Records.Item.Cube.Recipes = d2records.CubeRecipes{recipe}
+
+	magic := cubeTestItem(t, factory, "cap", "Strong")
+	magic.level = 20
+	magic.updateItemAttributes()
+
+	result, err := factory.Transmute([]*Item{cubeTestItem(t, factory, "gsv"), magic}, &CubeContext{Level: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(result.Items))
+	}
+
+	crafted, weapon := result.Items[0], result.Items[1]
+
+	if crafted == magic || crafted.CommonCode != "cap" || crafted.Quality() != d2enum.Crafted {
+		t.Errorf("got a %v %s, want a new crafted cap", crafted.Quality(), crafted.CommonCode)
+	}
+
+	// 5 + 50% of the level of the player + 50% of the level of the first input
+	if crafted.ItemLevel() != 30 {
+		t.Errorf("got item level %d, want 30", crafted.ItemLevel())
+	}
+
+	// the second modifier has a chance of 1%, it may be rolled but is at least 100
+	if strength := crafted.statValue("strength"); strength != 7 && strength != 107 {
+		t.Errorf("got strength %d, want the modifier of the output", strength)
+	}
+
+	if weapon.CommonCode != "hax" && weapon.CommonCode != "jav" {
+		t.Errorf("got weapon %s, want a weapon up to level 10", weapon.CommonCode)
+	}
+}
+
+func TestTransmuteModifiers(t *testing.T) {
+	factory := cubeTestFactory(t)
+	factory.asset.Records.Item.Cube.Recipes = d2records.CubeRecipes{cubeRecipe(
+		[]d2records.CubeRecipeItem{{Code: "helm", Count: 1}, {Code: "r01", Count: 1}},
+		d2records.CubeRecipeResult{Item: d2records.CubeRecipeItem{Code: "cap", Params: []string{cubeModifiers}, Count: 1}},
+	)}
+
+	unique := cubeTestItem(t, factory, "cap", "Biggin's Bonnet")
+
+	result, err := factory.Transmute([]*Item{unique, cubeTestItem(t, factory, "r01")}, &CubeContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := result.Items[0]
+	if item == unique || item.Quality() != d2enum.Unique || item.statValue("strength") != unique.statValue("strength") {
+		t.Errorf("got a %v item, want a copy of the unique", item.Quality())
+	}
+}
+
+func TestTransmutePortal(t *testing.T) {
+	factory := cubeTestFactory(t)
+	factory.asset.Records.Item.Cube.Recipes = d2records.CubeRecipes{cubeRecipe(
+		[]d2records.CubeRecipeItem{{Code: "r01", Count: 1}, {Code: "r02", Count: 1}},
+		d2records.CubeRecipeResult{Item: d2records.CubeRecipeItem{Code: "Cow Portal", Count: 1}},
+	)}
+
+	result, err := factory.Transmute([]*Item{cubeTestItem(t, factory, "r02"), cubeTestItem(t, factory, "r01")},
+		&CubeContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Portal != "Cow Portal" || len(result.Items) != 0 {
+		t.Errorf("got portal %q and %d items, want a cow portal", result.Portal, len(result.Items))
+	}
+}
+
+func TestCubeParamValue(t *testing.T) {
+	tests := []struct {
+		param, prefix string
+		value         int
+		found         bool
+	}{
+		{"sock=3", cubeSockets, 3, true},
+		{"pre=120", cubePrefix, 120, true},
+		{"sock=x", cubeSockets, 0, false},
+		{"nos", cubeSockets, 0, false},
+	}
+
+	for _, test := range tests {
+		if value, found := cubeParamValue(test.param, test.prefix); value != test.value || found != test.found {
+			t.Errorf("cubeParamValue(%q, %q) = %d, %v, want %d, %v", test.param, test.prefix, value, found,
+				test.value, test.found)
+		}
+	}
+}
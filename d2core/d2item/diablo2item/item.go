@@ -25,6 +25,8 @@ const (
 	PropertyPoolUnique
 	PropertyPoolSetItem
 	PropertyPoolSet
+	PropertyPoolRuneword
+	PropertyPoolCube
 )
 
 // for handling special cases
@@ -62,17 +64,20 @@ type Item struct {
 
 	slotType d2enum.EquippedSlot
 
-	TypeCode    string
-	CommonCode  string
-	UniqueCode  string
-	SetCode     string
-	SetItemCode string
-	PrefixCodes []string
-	SuffixCodes []string
+	TypeCode     string
+	CommonCode   string
+	UniqueCode   string
+	SetCode      string
+	SetItemCode  string
+	RunewordCode string
+	PrefixCodes  []string
+	SuffixCodes  []string
+	rareNames    []int // indices of the rare name prefix and suffix
+	level        int   // level the item dropped at, 0 for the level of its common record
+	quantity     int   // gold amount or stack size, 0 for a full stack
 
 	properties      map[PropertyPool][]*Property
 	statContext     d2item.StatContext
-	statList        d2stats.StatList
 	uniqueStatList  d2stats.StatList
 	setItemStatList d2stats.StatList
 
@@ -95,7 +100,10 @@ type itemAttributes struct {
 	stackSize     minMaxEnhanceable
 	durability    minMaxEnhanceable
 
-	personalization string
+	personalization string // also the name of the player an ear was taken from
+
+	earClass d2enum.Hero
+	earLevel int
 
 	quality                 int
 	defense                 int
@@ -185,6 +193,16 @@ func (i *Item) ItemLevel() int {
 	return i.attributes.baseItemLevel
 }
 
+// Quantity returns the stack size of the item, or the amount of gold of a gold item
+func (i *Item) Quantity() int {
+	return i.attributes.currentStackSize
+}
+
+// IsGold returns true if the item is a pile of gold
+func (i *Item) IsGold() bool {
+	return i.CommonCode == goldItemCode
+}
+
 // TypeRecord returns the ItemTypeRecord of the item
 func (i *Item) TypeRecord() *d2records.ItemTypeRecord {
 	return i.factory.asset.Records.Item.Types[i.TypeCode]
@@ -210,6 +228,11 @@ func (i *Item) SetItemRecord() *d2records.SetItemRecord {
 	return i.factory.asset.Records.Item.SetItems[i.SetItemCode]
 }
 
+// RunewordRecord returns the RuneRecord of the runeword in the sockets of the item
+func (i *Item) RunewordRecord() *d2records.RuneRecord {
+	return i.factory.asset.Records.Item.Runewords[i.RunewordCode]
+}
+
 // PrefixRecords returns the ItemAffixCommonRecords of the prefixes of the item
 func (i *Item) PrefixRecords() []*d2records.ItemAffixCommonRecord {
 	return affixRecords(i.PrefixCodes, i.factory.asset.Records.Item.Magic.Prefix)
@@ -238,14 +261,45 @@ func affixRecords(
 	return result
 }
 
+// Quality returns the quality of the item, items without affixes are normal
+func (i *Item) Quality() d2enum.ItemQuality {
+	prefixes, suffixes := i.PrefixRecords(), i.SuffixRecords()
+	numAffixes := len(prefixes) + len(suffixes)
+
+	switch {
+	case i.SetItemRecord() != nil:
+		return d2enum.Set
+	case i.UniqueRecord() != nil:
+		return d2enum.Unique
+	case i.attributes.crafted:
+		return d2enum.Crafted
+	case numAffixes > maxAffixesOnMagicItem || len(prefixes) > magicItemPrefixMax || len(suffixes) > magicItemSuffixMax:
+		return d2enum.Rare
+	case numAffixes > 0:
+		return d2enum.Magic
+	}
+
+	return d2enum.Normal
+}
+
 // SlotType returns the slot type (where it can be equipped)
 func (i *Item) SlotType() d2enum.EquippedSlot {
 	return i.slotType
 }
 
-// StatList returns the evaluated stat list
+// StatList returns the stats of the properties of the item
 func (i *Item) StatList() d2stats.StatList {
-	return i.statList
+	stats := make([]d2stats.Stat, 0)
+
+	for pool := range i.properties {
+		for _, prop := range i.properties[pool] {
+			if prop != nil {
+				stats = append(stats, prop.stats...)
+			}
+		}
+	}
+
+	return i.factory.stat.NewStatList(stats...)
 }
 
 // Description returns the full description string for the item
@@ -474,6 +528,7 @@ func (i *Item) updateItemAttributes() {
 	i.generateName()
 
 	r := i.CommonRecord()
+	previous := i.attributes
 	i.attributes = &itemAttributes{
 		damageOneHand: minMaxEnhanceable{
 			min: r.MinDamage,
@@ -498,6 +553,8 @@ func (i *Item) updateItemAttributes() {
 			max: r.Durability,
 		},
 
+		currentStackSize:  r.MaxStack,
+		currentDurability: r.Durability,
 		baseItemLevel:     r.Level,
 		requiredLevel:     r.RequiredLevel,
 		requiredStrength:  r.RequiredStrength,
@@ -506,8 +563,27 @@ func (i *Item) updateItemAttributes() {
 		throwable:         r.Throwable,
 	}
 
+	if i.level > 0 {
+		i.attributes.baseItemLevel = i.level
+	}
+
+	if i.quantity > 0 {
+		i.attributes.currentStackSize = i.quantity
+	}
+
 	def, minDef, maxDef := 0, r.MinAC, r.MaxAC
 
+	// the flags which were rolled by the properties or loaded with the item are kept
+	if previous != nil {
+		i.attributes.personalization = previous.personalization
+		i.attributes.earClass, i.attributes.earLevel = previous.earClass, previous.earLevel
+		i.attributes.numSockets = previous.numSockets
+		i.attributes.identitified = previous.identitified
+		i.attributes.crafted = previous.crafted
+		i.attributes.indestructable = previous.indestructable
+		i.attributes.ethereal = previous.ethereal
+	}
+
 	if maxDef < minDef {
 		minDef, maxDef = maxDef, minDef
 	}
@@ -647,17 +723,20 @@ func (i *Item) generateName() {
 	// rare items use entries from rareprefix.txt and raresuffix.txt to make their names,
 	// and the prefix and suffix actually go before thec current item name
 	if numAffixes > maxAffixesOnMagicItem {
-		i.rand.Seed(i.Seed)
-
 		prefixes := i.factory.asset.Records.Item.Rare.Prefix
 		suffixes := i.factory.asset.Records.Item.Rare.Suffix
 
 		numPrefix := len(prefixes)
 		numSuffix := len(suffixes)
 
-		preIdx, sufIdx := i.rand.Intn(numPrefix), i.rand.Intn(numSuffix)
-		prefix := prefixes[preIdx].Name
-		suffix := suffixes[sufIdx].Name
+		// the rare names are rolled once, or given by a saved item
+		if len(i.rareNames) != 2 || i.rareNames[0] >= numPrefix || i.rareNames[1] >= numSuffix {
+			i.rand.Seed(i.Seed)
+			i.rareNames = []int{i.rand.Intn(numPrefix), i.rand.Intn(numSuffix)}
+		}
+
+		prefix := prefixes[i.rareNames[0]].Name
+		suffix := suffixes[i.rareNames[1]].Name
 
 		name = fmt.Sprintf("%s %s\n%s", strings.Title(prefix), strings.Title(suffix), name)
 	}
@@ -668,26 +747,7 @@ func (i *Item) generateName() {
 // GetStatStrings is a test function for getting all stat strings
 func (i *Item) GetStatStrings() []string {
 	result := make([]string, 0)
-	stats := make([]d2stats.Stat, 0)
-
-	for pool := range i.properties {
-		propPool := i.properties[pool]
-		if propPool == nil {
-			continue
-		}
-
-		for propIdx := range propPool {
-			if propPool[propIdx] == nil {
-				continue
-			}
-
-			prop := propPool[propIdx]
-
-			for statIdx := range prop.stats {
-				stats = append(stats, prop.stats[statIdx])
-			}
-		}
-	}
+	stats := i.StatList().Stats()
 
 	if len(stats) > 0 {
 		stats = i.factory.stat.NewStatList(stats...).ReduceStats().Stats()
@@ -778,9 +838,15 @@ func (i *Item) GetItemCode() string {
 	return i.CommonRecord().Code
 }
 
-// Serialize the item to a byte slize
+// Serialize the item to a byte slice, in the format items are saved in. Nil is returned for an
+// item which can not be saved, use Marshal to get the error
 func (i *Item) Serialize() []byte {
-	panic("item serialization not yet implemented")
+	data, err := i.Marshal()
+	if err != nil {
+		return nil
+	}
+
+	return data
 }
 
 // InventoryGridSlot returns the inventory grid slot x and y
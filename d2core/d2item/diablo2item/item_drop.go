@@ -0,0 +1,109 @@
+package diablo2item
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const (
+	// the magic find of a player has diminishing returns for the better qualities
+	magicFindUniqueFactor = 250
+	magicFindSetFactor    = 500
+	magicFindRareFactor   = 600
+
+	// goldMultiplierBase is the multiplier of the gold amount, `gld,mul=256` drops the base amount
+	goldMultiplierBase = 256
+)
+
+// DropContext holds what a drop depends on besides its treasure class
+type DropContext struct {
+	Level     int // level of the monster or the area which drops, the level of the dropped items
+	Players   int // players in the game, the treasure classes drop more often with more players
+	MagicFind int // magic find of the player the drop is for, in percent
+}
+
+// dropItem prepares an item rolled from a treasure of the treasure class for the drop: it gets a
+// seed of its own, the level of the drop and either its quality or its gold amount
+func (f *ItemFactory) dropItem(item *Item, tcr *d2records.TreasureClassRecord, treasure *d2records.Treasure,
+	ctx *DropContext) *Item {
+	// nolint:gosec // we're not concerned with crypto-strong randomness
+	item.rand = rand.New(rand.NewSource(f.rand.Int63()))
+	item.factory = f
+	item.level = ctx.Level
+
+	if item.IsGold() {
+		item.quantity = f.rollGold(treasure.Code, ctx.Level)
+		return item.init()
+	}
+
+	item.applyDropModifier(f.rollDropModifier(tcr, ctx.MagicFind))
+
+	return item.init()
+}
+
+// rollGold returns the amount of a gold drop, the base amount is between the level of the drop
+// and twice the level. A `gld,mul=` treasure multiplies it by its multiplier in 256ths.
+func (f *ItemFactory) rollGold(code string, level int) int {
+	if level < 1 {
+		level = 1
+	}
+
+	amount := level + f.rand.Intn(level+1)
+
+	if strings.HasPrefix(code, goldItemCodeWithMult) {
+		multiplier, err := strconv.Atoi(strings.TrimPrefix(code, goldItemCodeWithMult))
+		if err == nil && multiplier > 0 {
+			amount = amount * multiplier / goldMultiplierBase
+		}
+	}
+
+	if amount < 1 {
+		amount = 1
+	}
+
+	return amount
+}
+
+// applyMagicFind returns the frequency of a quality for the given magic find, a factor above 0
+// gives the magic find diminishing returns for the quality
+func applyMagicFind(frequency, magicFind, factor int) int {
+	if magicFind <= 0 {
+		return frequency
+	}
+
+	effective := magicFind
+	if factor > 0 {
+		effective = magicFind * factor / (magicFind + factor)
+	}
+
+	const percent = 100
+
+	return frequency * (percent + effective) / percent
+}
+
+// adjustNoDrop returns the NoDrop frequency of the treasure class for the given number of players.
+// Every two more players make the chance that a pick drops nothing as small as if the treasure
+// class was picked once more.
+func adjustNoDrop(tcr *d2records.TreasureClassRecord, players int) int {
+	if tcr.FreqNoDrop <= 0 || players <= 1 {
+		return tcr.FreqNoDrop
+	}
+
+	total := 0
+	for idx := range tcr.Treasures {
+		total += tcr.Treasures[idx].Probability
+	}
+
+	if total <= 0 {
+		return tcr.FreqNoDrop
+	}
+
+	picks := float64(1 + (players-1)/2) //nolint:gomnd // every two more players
+	ratio := float64(tcr.FreqNoDrop) / float64(tcr.FreqNoDrop+total)
+
+	return int(float64(total) / (1/math.Pow(ratio, picks) - 1))
+}
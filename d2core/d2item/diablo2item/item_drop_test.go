@@ -0,0 +1,84 @@
+package diablo2item
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func TestAdjustNoDrop(t *testing.T) {
+	tcr := &d2records.TreasureClassRecord{
+		FreqNoDrop: 100,
+		Treasures:  []*d2records.Treasure{{Code: "gld", Probability: 60}, {Code: "cap", Probability: 40}},
+	}
+
+	tests := []struct {
+		players int
+		noDrop  int
+	}{
+		{1, 100},
+		{2, 100},
+		{3, 33},
+		{5, 14},
+		{8, 6},
+	}
+
+	for _, test := range tests {
+		if noDrop := adjustNoDrop(tcr, test.players); noDrop != test.noDrop {
+			t.Errorf("%d players: got NoDrop %d, want %d", test.players, noDrop, test.noDrop)
+		}
+	}
+}
+
+func TestApplyMagicFind(t *testing.T) {
+	tests := []struct {
+		frequency, magicFind, factor int
+		want                         int
+	}{
+		{100, 0, magicFindUniqueFactor, 100},
+		{100, 100, 0, 200},
+		{100, 250, magicFindUniqueFactor, 225},
+		{100, 500, magicFindSetFactor, 350},
+		{100, 600, magicFindRareFactor, 400},
+	}
+
+	for _, test := range tests {
+		if got := applyMagicFind(test.frequency, test.magicFind, test.factor); got != test.want {
+			t.Errorf("applyMagicFind(%d, %d, %d) = %d, want %d", test.frequency, test.magicFind, test.factor, got, test.want)
+		}
+	}
+}
+
+func TestItemsFromDropGold(t *testing.T) {
+	factory := serializeTestFactory(t)
+
+	gold := &d2records.ItemCommonRecord{Code: goldItemCode, Type: "gold", Level: 1}
+	factory.asset.Records.Item.Misc[goldItemCode] = gold
+	factory.asset.Records.Item.All[goldItemCode] = gold
+
+	tcr := &d2records.TreasureClassRecord{
+		Name:      "gold",
+		NumPicks:  -2,
+		Treasures: []*d2records.Treasure{{Code: goldItemCodeWithMult + "512", Probability: 2}},
+	}
+
+	items := factory.ItemsFromDrop(tcr, &DropContext{Level: 10})
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+
+	for _, item := range items {
+		if !item.IsGold() {
+			t.Errorf("got item %s, want gold", item.CommonCode)
+		}
+
+		if item.ItemLevel() != 10 {
+			t.Errorf("got item level %d, want 10", item.ItemLevel())
+		}
+
+		// the base amount is 10 to 20, doubled by the multiplier
+		if amount := item.Quantity(); amount < 20 || amount > 40 {
+			t.Errorf("got %d gold, want 20 to 40", amount)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats/diablo2stats"
 
@@ -160,7 +161,7 @@ func (f *ItemFactory) NewProperty(code string, values ...int) *Property {
 	return result.init()
 }
 
-func (f *ItemFactory) rollDropModifier(tcr *d2records.TreasureClassRecord) dropModifier {
+func (f *ItemFactory) rollDropModifier(tcr *d2records.TreasureClassRecord, magicFind int) dropModifier {
 	modMap := map[int]dropModifier{
 		0: dropModifierNone,
 		1: dropModifierUnique,
@@ -171,10 +172,10 @@ func (f *ItemFactory) rollDropModifier(tcr *d2records.TreasureClassRecord) dropM
 
 	dropModifiers := []int{
 		dropModifierBaseProbability,
-		tcr.FreqUnique,
-		tcr.FreqSet,
-		tcr.FreqRare,
-		tcr.FreqMagic,
+		applyMagicFind(tcr.FreqUnique, magicFind, magicFindUniqueFactor),
+		applyMagicFind(tcr.FreqSet, magicFind, magicFindSetFactor),
+		applyMagicFind(tcr.FreqRare, magicFind, magicFindRareFactor),
+		applyMagicFind(tcr.FreqMagic, magicFind, 0),
 	}
 
 	for idx := range dropModifiers {
@@ -196,10 +197,10 @@ func (f *ItemFactory) rollDropModifier(tcr *d2records.TreasureClassRecord) dropM
 	return dropModifierNone
 }
 
-func (f *ItemFactory) rollTreasurePick(tcr *d2records.TreasureClassRecord) *d2records.Treasure {
+func (f *ItemFactory) rollTreasurePick(tcr *d2records.TreasureClassRecord, noDrop int) *d2records.Treasure {
 	// treasure probabilities
 	tprob := make([]int, len(tcr.Treasures)+1)
-	total := tcr.FreqNoDrop
+	total := noDrop
 	tprob[0] = total
 
 	for idx := range tcr.Treasures {
@@ -207,6 +208,10 @@ func (f *ItemFactory) rollTreasurePick(tcr *d2records.TreasureClassRecord) *d2re
 		tprob[idx+1] = total
 	}
 
+	if total <= 0 {
+		return nil
+	}
+
 	roll := f.rand.Intn(total)
 
 	for idx := range tprob {
@@ -224,6 +229,12 @@ func (f *ItemFactory) rollTreasurePick(tcr *d2records.TreasureClassRecord) *d2re
 
 // ItemsFromTreasureClass rolls for and creates items using a treasure class record
 func (f *ItemFactory) ItemsFromTreasureClass(tcr *d2records.TreasureClassRecord) []*Item {
+	return f.ItemsFromDrop(tcr, &DropContext{})
+}
+
+// ItemsFromDrop rolls for and creates the items of a monster or chest drop using a treasure class
+// record. The gold of the drop is returned as gold items, their amount is their quantity.
+func (f *ItemFactory) ItemsFromDrop(tcr *d2records.TreasureClassRecord, ctx *DropContext) []*Item {
 	result := make([]*Item, 0)
 
 	treasurePicks := make([]*d2records.Treasure, 0)
@@ -243,9 +254,11 @@ func (f *ItemFactory) ItemsFromTreasureClass(tcr *d2records.TreasureClassRecord)
 			}
 		}
 	} else {
+		noDrop := adjustNoDrop(tcr, ctx.Players)
+
 		// for N picks, we roll for a treasure and append to our treasures if it isn't a NoDrop
 		for picksLeft := tcr.NumPicks; picksLeft > 0; picksLeft-- {
-			rolledTreasure := f.rollTreasurePick(tcr)
+			rolledTreasure := f.rollTreasurePick(tcr, noDrop)
 
 			if rolledTreasure == nil {
 				continue
@@ -260,22 +273,20 @@ func (f *ItemFactory) ItemsFromTreasureClass(tcr *d2records.TreasureClassRecord)
 	// case we will roll that treasure class, eventually getting a slice of items
 	for idx := range treasurePicks {
 		picked := treasurePicks[idx]
-		if record, found := f.asset.Records.Item.Treasure.Normal[picked.Code]; found {
+		if record := f.asset.Records.GetTreasureClassByName(picked.Code); record != nil {
 			// the code is for a treasure class, we roll again using that TC
-			itemSlice := f.ItemsFromTreasureClass(record)
+			itemSlice := f.ItemsFromDrop(record, ctx)
 			for itemIdx := range itemSlice {
-				itemSlice[itemIdx].applyDropModifier(f.rollDropModifier(tcr))
-				itemSlice[itemIdx].init()
+				if !itemSlice[itemIdx].IsGold() {
+					itemSlice[itemIdx].applyDropModifier(f.rollDropModifier(tcr, ctx.MagicFind))
+					itemSlice[itemIdx].init()
+				}
+
 				result = append(result, itemSlice[itemIdx])
 			}
-		} else {
+		} else if item := f.ItemFromTreasure(picked); item != nil {
 			// the code is not for a treasure class, but for an item
-			item := f.ItemFromTreasure(picked)
-			if item != nil {
-				item.applyDropModifier(f.rollDropModifier(tcr))
-				item.init()
-				result = append(result, item)
-			}
+			result = append(result, f.dropItem(item, tcr, picked, ctx))
 		}
 	}
 
@@ -289,6 +300,12 @@ func (f *ItemFactory) ItemFromTreasure(treasure *d2records.Treasure) *Item {
 		rand: rand.New(rand.NewSource(f.Seed)),
 	}
 
+	// gold is dropped with a multiplier for its amount, like `gld,mul=1280`
+	if strings.HasPrefix(treasure.Code, goldItemCode) {
+		result.CommonCode = goldItemCode
+		return result
+	}
+
 	// in this case, the treasure code is a code used by an ItemCommonRecord
 	commonRecord := f.asset.Records.Item.All[treasure.Code]
 	if commonRecord != nil {
@@ -373,11 +390,6 @@ func (f *ItemFactory) resolveDynamicTreasureCode(code string) []*d2records.ItemC
 	numericComponent := getNumericComponent(code)
 	stringComponent := getStringComponent(code)
 
-	if stringComponent == goldItemCodeWithMult {
-		// need to do something with the numeric component (the gold multiplier)
-		stringComponent = goldItemCode
-	}
-
 	result := make([]*d2records.ItemCommonRecord, 0)
 	equivList := f.asset.Records.Item.Equivalency[stringComponent]
 
@@ -0,0 +1,519 @@
+package diablo2item
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+)
+
+const (
+	earItemCode = "ear"
+
+	// indestructible items are saved with this stat, instead of a flag like ethereal items
+	statIndestructible = "item_indesctructible"
+)
+
+// the stats of these description functions pack several values into the param and value
+// which are saved, the other stats are saved as their first value with the second as param
+const (
+	descFnSkillTab = 14 // [level, class, tab], the param is the tab and the class
+	descFnProcs    = 15 // [chance, level, skill], the param is the level and the skill
+	descFnCharges  = 24 // [level, skill, charges, max], the value is the charges and the max
+
+	skillTabBits   = 3
+	skillLevelBits = 6
+	chargesBits    = 8
+)
+
+// Marshal writes the item, and the items in its sockets, as it is saved
+func (i *Item) Marshal() ([]byte, error) {
+	saved, err := i.SaveItem()
+	if err != nil {
+		return nil, err
+	}
+
+	return saved.Marshal(i.factory.asset.Records.SaveRecords())
+}
+
+// SaveItem returns the item as it is saved. The item seed is saved as the item ID, so that the
+// loaded item generates the same way.
+func (i *Item) SaveItem() (*d2s.Item, error) {
+	if i.CommonRecord() == nil {
+		return nil, fmt.Errorf("unknown item code %q", i.CommonCode)
+	}
+
+	saved := &d2s.Item{
+		Identified: i.attributes.identitified,
+		Ethereal:   i.attributes.ethereal,
+		Version:    d2s.ItemVersionExpansion,
+		Location:   d2s.LocationStored,
+		Page:       d2s.PageInventory,
+		X:          i.GridX,
+		Y:          i.GridY,
+		Code:       i.CommonCode,
+	}
+
+	if i.CommonCode == earItemCode {
+		class, _ := d2s.ClassOf(i.attributes.earClass)
+		saved.Simple, saved.Code = true, ""
+		saved.Ear = &d2s.Ear{Class: class, Level: i.attributes.earLevel, Name: i.attributes.personalization}
+
+		return saved, nil
+	}
+
+	if i.CommonRecord().CompactSave {
+		saved.Simple = true
+		return saved, nil
+	}
+
+	saved.ID = uint32(i.Seed)
+	saved.Level = i.ItemLevel()
+	saved.Personalized = i.attributes.personalization != ""
+	saved.PersonalizedName = i.attributes.personalization
+
+	if err := i.saveQuality(saved); err != nil {
+		return nil, err
+	}
+
+	i.saveTypeFields(saved)
+
+	if err := i.saveProperties(saved); err != nil {
+		return nil, err
+	}
+
+	if err := i.saveSockets(saved); err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+func (i *Item) saveQuality(saved *d2s.Item) error {
+	prefixes, suffixes := i.PrefixRecords(), i.SuffixRecords()
+	saved.Quality = i.Quality()
+
+	switch saved.Quality {
+	case d2enum.Set:
+		saved.SetID = i.SetItemRecord().Index
+	case d2enum.Unique:
+		saved.UniqueID = i.UniqueRecord().Index
+	case d2enum.Rare, d2enum.Crafted:
+		if len(prefixes) > d2s.NumRareAffixes || len(suffixes) > d2s.NumRareAffixes {
+			return errors.New("item has too many affixes to be saved")
+		}
+
+		copy(saved.RareNames[:], i.rareNames)
+
+		for idx := range prefixes {
+			saved.Prefixes[idx] = affixID(prefixes[idx])
+		}
+
+		for idx := range suffixes {
+			saved.Suffixes[idx] = affixID(suffixes[idx])
+		}
+	case d2enum.Magic:
+		if len(prefixes) > 0 {
+			saved.Prefixes[0] = affixID(prefixes[0])
+		}
+
+		if len(suffixes) > 0 {
+			saved.Suffixes[0] = affixID(suffixes[0])
+		}
+	}
+
+	if i.RunewordCode != "" {
+		if i.RunewordRecord() == nil {
+			return fmt.Errorf("unknown runeword %q", i.RunewordCode)
+		}
+
+		saved.Runeword, saved.RunewordID = true, i.RunewordRecord().Index
+	}
+
+	return nil
+}
+
+// affixID returns the saved ID of an affix, zero is saved for no affix
+func affixID(record *d2records.ItemAffixCommonRecord) int {
+	if record == nil {
+		return 0
+	}
+
+	return record.Index + 1
+}
+
+func (i *Item) saveTypeFields(saved *d2s.Item) {
+	saved.Defense = i.attributes.defense
+
+	if i.attributes.durable {
+		saved.MaxDurability = i.attributes.durability.max
+		saved.Durability = i.attributes.currentDurability
+	}
+
+	if i.CommonRecord().Stackable {
+		saved.Quantity = i.attributes.currentStackSize
+	}
+
+	saved.TotalSockets = i.attributes.numSockets
+	if len(i.sockets) > saved.TotalSockets {
+		saved.TotalSockets = len(i.sockets)
+	}
+
+	saved.Socketed = saved.TotalSockets > 0
+}
+
+func (i *Item) saveProperties(saved *d2s.Item) error {
+	var err error
+
+	saved.Properties, err = i.savePropertyPools(PropertyPoolPrefix, PropertyPoolSuffix, PropertyPoolUnique,
+		PropertyPoolSetItem, PropertyPoolCube)
+	if err != nil {
+		return err
+	}
+
+	if i.attributes.indestructable {
+		if record := i.factory.asset.Records.Item.Stats[statIndestructible]; record != nil {
+			saved.Properties = append(saved.Properties, d2s.Property{ID: record.Index, Values: []int{1}})
+		}
+	}
+
+	// the bonuses of the set are kept in a single list
+	if len(i.properties[PropertyPoolSet]) > 0 && saved.Quality == d2enum.Set {
+		if saved.SetProperties[0], err = i.savePropertyPools(PropertyPoolSet); err != nil {
+			return err
+		}
+	}
+
+	if saved.Runeword {
+		if saved.RunewordProperties, err = i.savePropertyPools(PropertyPoolRuneword); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Item) savePropertyPools(pools ...PropertyPool) ([]d2s.Property, error) {
+	properties := make([]d2s.Property, 0)
+
+	for _, pool := range pools {
+		for _, property := range i.properties[pool] {
+			if property == nil {
+				continue
+			}
+
+			for _, stat := range property.stats {
+				record := i.factory.asset.Records.Item.Stats[stat.Name()]
+				if record == nil {
+					return nil, fmt.Errorf("unknown item stat %q", stat.Name())
+				}
+
+				properties = append(properties, statToProperty(record, stat))
+			}
+		}
+	}
+
+	return d2s.JoinChainedStats(properties), nil
+}
+
+// statToProperty returns the param and value a stat is saved with
+func statToProperty(record *d2records.ItemStatCostRecord, stat d2stats.Stat) d2s.Property {
+	statValues := stat.Values()
+	value := func(idx int) int {
+		if idx < len(statValues) {
+			return statValues[idx].Int()
+		}
+
+		return 0
+	}
+
+	property := d2s.Property{ID: record.Index}
+
+	switch record.DescFnID {
+	case descFnSkillTab:
+		property.Param = value(2) | value(1)<<skillTabBits
+		property.Values = []int{value(0)}
+	case descFnProcs:
+		property.Param = value(1) | value(2)<<skillLevelBits
+		property.Values = []int{value(0)}
+	case descFnCharges:
+		property.Param = value(0) | value(1)<<skillLevelBits
+		property.Values = []int{value(2) | value(3)<<chargesBits}
+	default:
+		property.Param = value(1)
+		property.Values = []int{value(0)}
+	}
+
+	return property
+}
+
+// statFromProperty is the reverse of statToProperty, it returns the values of the stat
+func statFromProperty(record *d2records.ItemStatCostRecord, property d2s.Property) []float64 {
+	value := 0
+	if len(property.Values) > 0 {
+		value = property.Values[0]
+	}
+
+	param := property.Param
+
+	switch record.DescFnID {
+	case descFnSkillTab:
+		return []float64{float64(value), float64(param >> skillTabBits), float64(param & (1<<skillTabBits - 1))}
+	case descFnProcs:
+		return []float64{float64(value), float64(param & (1<<skillLevelBits - 1)), float64(param >> skillLevelBits)}
+	case descFnCharges:
+		return []float64{
+			float64(param & (1<<skillLevelBits - 1)), float64(param >> skillLevelBits),
+			float64(value & (1<<chargesBits - 1)), float64(value >> chargesBits),
+		}
+	default:
+		return []float64{float64(value), float64(param)}
+	}
+}
+
+func (i *Item) saveSockets(saved *d2s.Item) error {
+	for idx, socketed := range i.sockets {
+		var child *Item
+
+		if socketed != nil {
+			child, _ = (*socketed).(*Item)
+		}
+
+		if child == nil {
+			return errors.New("only diablo2item items can be saved in sockets")
+		}
+
+		savedChild, err := child.SaveItem()
+		if err != nil {
+			return err
+		}
+
+		savedChild.Location, savedChild.Page = d2s.LocationSocketed, d2s.PageNone
+		savedChild.X, savedChild.Y = idx, 0
+		saved.Sockets = append(saved.Sockets, savedChild)
+	}
+
+	return nil
+}
+
+// Deserialize creates an item, and the items in its sockets, from the data an item was
+// serialized to
+func (f *ItemFactory) Deserialize(data []byte) (*Item, error) {
+	saved, err := d2s.LoadItem(data, f.asset.Records.SaveRecords())
+	if err != nil {
+		return nil, err
+	}
+
+	return f.ItemFromSave(saved)
+}
+
+// ItemFromSave creates an item from an item as it is saved
+func (f *ItemFactory) ItemFromSave(saved *d2s.Item) (*Item, error) {
+	code := saved.Code
+	if saved.Ear != nil {
+		code = earItemCode
+	}
+
+	if f.asset.Records.Item.All[code] == nil {
+		return nil, fmt.Errorf("unknown item code %q", code)
+	}
+
+	item := &Item{
+		factory:    f,
+		CommonCode: code,
+		level:      saved.Level,
+		GridX:      saved.X,
+		GridY:      saved.Y,
+		attributes: &itemAttributes{
+			identitified:    saved.Identified,
+			ethereal:        saved.Ethereal,
+			crafted:         saved.Quality == d2enum.Crafted,
+			personalization: saved.PersonalizedName,
+			numSockets:      saved.TotalSockets,
+		},
+	}
+
+	item.SetSeed(int64(saved.ID))
+
+	if saved.Ear != nil {
+		item.attributes.earClass = saved.Ear.Class.Hero()
+		item.attributes.earLevel = saved.Ear.Level
+		item.attributes.personalization = saved.Ear.Name
+	}
+
+	if err := item.loadQuality(saved); err != nil {
+		return nil, err
+	}
+
+	// the properties are not generated again, they are rolled and were saved with the item
+	item.updateItemAttributes()
+
+	if saved.Simple {
+		return item, nil
+	}
+
+	item.loadTypeFields(saved)
+
+	if err := item.loadProperties(saved); err != nil {
+		return nil, err
+	}
+
+	for _, savedChild := range saved.Sockets {
+		child, err := f.ItemFromSave(savedChild)
+		if err != nil {
+			return nil, err
+		}
+
+		var socketed d2item.Item = child
+
+		item.sockets = append(item.sockets, &socketed)
+	}
+
+	return item, nil
+}
+
+func (i *Item) loadQuality(saved *d2s.Item) error {
+	records := i.factory.asset.Records
+
+	switch saved.Quality {
+	case d2enum.Set:
+		for key, record := range records.Item.SetItems {
+			if record.Index == saved.SetID {
+				i.SetItemCode, i.SetCode = key, record.SetKey
+			}
+		}
+
+		if i.SetItemCode == "" {
+			return fmt.Errorf("unknown set item %d", saved.SetID)
+		}
+	case d2enum.Unique:
+		for key, record := range records.Item.Unique {
+			if record.Index == saved.UniqueID {
+				i.UniqueCode = key
+			}
+		}
+
+		if i.UniqueCode == "" {
+			return fmt.Errorf("unknown unique item %d", saved.UniqueID)
+		}
+	case d2enum.Magic, d2enum.Rare, d2enum.Crafted:
+		i.PrefixCodes = affixCodes(saved.Prefixes[:], records.Item.Magic.Prefix)
+		i.SuffixCodes = affixCodes(saved.Suffixes[:], records.Item.Magic.Suffix)
+
+		if saved.Quality != d2enum.Magic {
+			i.rareNames = []int{saved.RareNames[0], saved.RareNames[1]}
+		}
+	}
+
+	if saved.Runeword {
+		for key, record := range records.Item.Runewords {
+			if record.Index == saved.RunewordID {
+				i.RunewordCode = key
+			}
+		}
+
+		if i.RunewordCode == "" {
+			return fmt.Errorf("unknown runeword %d", saved.RunewordID)
+		}
+	}
+
+	return nil
+}
+
+// affixCodes returns the codes of the saved affixes. Affixes which share a name are only kept
+// once in the records, so not every ID is found; the stats of the affixes are saved anyway.
+func affixCodes(ids []int, affixes map[string]*d2records.ItemAffixCommonRecord) []string {
+	codes := make([]string, 0)
+
+	for _, id := range ids {
+		if id == 0 {
+			continue
+		}
+
+		for code, record := range affixes {
+			if affixID(record) == id {
+				codes = append(codes, code)
+				break
+			}
+		}
+	}
+
+	return codes
+}
+
+func (i *Item) loadTypeFields(saved *d2s.Item) {
+	i.attributes.defense = saved.Defense
+
+	if saved.MaxDurability > 0 {
+		i.attributes.durability.max = saved.MaxDurability
+		i.attributes.currentDurability = saved.Durability
+	}
+
+	if i.CommonRecord().Stackable {
+		i.quantity = saved.Quantity
+		i.attributes.currentStackSize = saved.Quantity
+	}
+}
+
+func (i *Item) loadProperties(saved *d2s.Item) error {
+	pool := PropertyPoolPrefix
+
+	switch saved.Quality {
+	case d2enum.Set:
+		pool = PropertyPoolSetItem
+	case d2enum.Unique:
+		pool = PropertyPoolUnique
+	}
+
+	setProperties := make([]d2s.Property, 0)
+	for _, list := range saved.SetProperties {
+		setProperties = append(setProperties, list...)
+	}
+
+	lists := []struct {
+		pool       PropertyPool
+		properties []d2s.Property
+	}{
+		{pool, saved.Properties},
+		{PropertyPoolSet, setProperties},
+		{PropertyPoolRuneword, saved.RunewordProperties},
+	}
+
+	stats := make(map[int]*d2records.ItemStatCostRecord, len(i.factory.asset.Records.Item.Stats))
+	for _, record := range i.factory.asset.Records.Item.Stats {
+		stats[record.Index] = record
+	}
+
+	i.properties = make(map[PropertyPool][]*Property)
+
+	for _, list := range lists {
+		if len(list.properties) == 0 {
+			continue
+		}
+
+		property := &Property{factory: i.factory, PropertyType: PropertyComputeStats}
+
+		for _, savedStat := range d2s.SplitChainedStats(list.properties) {
+			record := stats[savedStat.ID]
+			if record == nil {
+				return fmt.Errorf("unknown item stat %d", savedStat.ID)
+			}
+
+			if record.Name == statIndestructible {
+				i.attributes.indestructable = true
+				continue
+			}
+
+			stat := i.factory.stat.NewStat(record.Name, statFromProperty(record, savedStat)...)
+			property.stats = append(property.stats, stat)
+		}
+
+		i.properties[list.pool] = []*Property{property}
+	}
+
+	return nil
+}
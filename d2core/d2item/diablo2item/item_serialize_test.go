@@ -0,0 +1,176 @@
+package diablo2item
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func serializeTestFactory(t *testing.T) *ItemFactory {
+	asset := &d2asset.AssetManager{Records: &d2records.RecordManager{}}
+
+	factory, err := NewItemFactory(asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := &asset.Records.Item
+	items.Armors = d2records.CommonItems{
+		"cap": {Code: "cap", Type: "helm", MinAC: 3, MaxAC: 5, Durability: 12},
+	}
+	items.Weapons = d2records.CommonItems{
+		"jav": {Code: "jav", Type: "jave", Stackable: true, MaxStack: 60, NoDurability: true},
+	}
+	items.Misc = d2records.CommonItems{
+		"gsv": {Code: "gsv", Type: "gema", CompactSave: true},
+		"ear": {Code: "ear", Type: "play", CompactSave: true},
+	}
+	items.All = d2records.CommonItems{}
+
+	for _, list := range []d2records.CommonItems{items.Armors, items.Weapons, items.Misc} {
+		for code, record := range list {
+			items.All[code] = record
+		}
+	}
+
+	items.Stats = d2records.ItemStatCosts{
+		"strength":             {Name: "strength", Index: 0, DescFnID: 1, SaveBits: 8, SaveAdd: 32},
+		"poisonmindam":         {Name: "poisonmindam", Index: 57, SaveBits: 10},
+		"poisonmaxdam":         {Name: "poisonmaxdam", Index: 58, SaveBits: 10},
+		"poisonlength":         {Name: "poisonlength", Index: 59, SaveBits: 9},
+		"item_indesctructible": {Name: "item_indesctructible", Index: 152, SaveBits: 1},
+		"item_charged_skill":   {Name: "item_charged_skill", Index: 204, DescFnID: 24, SaveBits: 16, SaveParamBits: 16},
+	}
+
+	asset.Records.Properties = map[string]*d2records.PropertyRecord{
+		"str": {Code: "str", Stats: [7]*d2records.PropertyStatRecord{{FunctionID: 1, StatCode: "strength"}}},
+		"dmg-pois": {Code: "dmg-pois", Stats: [7]*d2records.PropertyStatRecord{
+			{FunctionID: 15, StatCode: "poisonmindam"},
+			{FunctionID: 16, StatCode: "poisonmaxdam"},
+			{FunctionID: 17, StatCode: "poisonlength"},
+		}},
+		"charged": {Code: "charged", Stats: [7]*d2records.PropertyStatRecord{{FunctionID: 19, StatCode: "item_charged_skill"}}},
+	}
+
+	affix := func(index int, name, code, param string, min, max int) *d2records.ItemAffixCommonRecord {
+		return &d2records.ItemAffixCommonRecord{Index: index, Name: name, Modifiers: []*d2records.ItemAffixCommonModifier{
+			{Code: code, Parameter: param, Min: min, Max: max},
+		}}
+	}
+
+	items.Magic.Prefix = d2records.MagicPrefix{
+		"Strong":  affix(3, "Strong", "str", "", 2, 5),
+		"Mighty":  affix(4, "Mighty", "str", "", 5, 9),
+		"Noxious": affix(9, "Noxious", "dmg-pois", "50", 4, 12),
+	}
+	items.Magic.Suffix = d2records.MagicSuffix{
+		"of Charging": affix(20, "of Charging", "charged", "54", 5, 3),
+	}
+	items.Rare.Prefix = d2records.RarePrefixes{{Name: "beast"}, {Name: "eagle"}}
+	items.Rare.Suffix = d2records.RareSuffixes{{Name: "bite"}, {Name: "song"}}
+
+	uniqueProperties := [12]*d2records.UniqueItemProperty{}
+	for idx := range uniqueProperties {
+		uniqueProperties[idx] = &d2records.UniqueItemProperty{}
+	}
+
+	uniqueProperties[0] = &d2records.UniqueItemProperty{Code: "str", Min: 10, Max: 15}
+	items.Unique = d2records.UniqueItems{
+		"Biggin's Bonnet": {Index: 7, Name: "Biggin's Bonnet", Code: "cap", Properties: uniqueProperties},
+	}
+
+	return factory
+}
+
+func TestItemRoundTrip(t *testing.T) {
+	factory := serializeTestFactory(t)
+
+	table := []struct {
+		name  string
+		codes []string
+	}{
+		{"normal", []string{"cap"}},
+		{"magic", []string{"cap", "Strong", "of Charging"}},
+		{"rare", []string{"jav", "Strong", "Mighty", "Noxious", "of Charging"}},
+		{"unique", []string{"cap", "Biggin's Bonnet"}},
+	}
+
+	for _, row := range table {
+		item, err := factory.NewItem(row.codes...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		item.SetSeed(12345)
+		item.SetInventoryGridSlot(2, 1)
+		item.Identify()
+
+		if row.name == "normal" {
+			gem, _ := factory.NewItem("gsv")
+
+			var socketed d2item.Item = gem
+
+			item.attributes.numSockets = 2
+			item.attributes.ethereal, item.attributes.indestructable = true, true
+			item.sockets = append(item.sockets, &socketed)
+		}
+
+		data, err := item.Marshal()
+		if err != nil {
+			t.Fatalf("%s: %v", row.name, err)
+		}
+
+		loaded, err := factory.Deserialize(data)
+		if err != nil {
+			t.Fatalf("%s: %v", row.name, err)
+		}
+
+		if again, err := loaded.Marshal(); err != nil || !bytes.Equal(again, data) {
+			t.Errorf("%s: saving a loaded item changed the data (%v)", row.name, err)
+		}
+
+		if loaded.name != item.name || loaded.UniqueCode != item.UniqueCode || len(loaded.sockets) != len(item.sockets) ||
+			!reflect.DeepEqual(loaded.PrefixCodes, item.PrefixCodes) || !reflect.DeepEqual(loaded.SuffixCodes, item.SuffixCodes) {
+			t.Errorf("%s: loaded item %q does not match %q", row.name, loaded.name, item.name)
+		}
+
+		if loaded.attributes.ethereal != item.attributes.ethereal ||
+			loaded.attributes.indestructable != item.attributes.indestructable {
+			t.Errorf("%s: flags were not kept", row.name)
+		}
+	}
+}
+
+func TestEarRoundTrip(t *testing.T) {
+	factory := serializeTestFactory(t)
+	records := factory.asset.Records.SaveRecords()
+
+	ear := &d2s.Item{
+		Simple: true, Version: d2s.ItemVersionExpansion, Page: d2s.PageInventory, X: 4,
+		Ear: &d2s.Ear{Class: d2s.ClassDruid, Level: 77, Name: "Victim"},
+	}
+
+	data, err := ear.Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item, err := factory.Deserialize(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := item.SaveItem()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(saved, ear) {
+		t.Errorf("saved ear %+v does not match %+v", saved, ear)
+	}
+}
@@ -0,0 +1,377 @@
+package diablo2item
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const (
+	// vendorLevelBonus is how much better than the level of the player the items of the vendors may be
+	vendorLevelBonus = 5
+
+	// priceScaleBase is the multiplier of the price scales of the affixes, 1024 keeps the price
+	priceScaleBase = 1024
+
+	// the odds of the qualities of gambled items in 10000ths, the other gambled items are magic
+	gambleOddsBase   = 10000
+	gambleOddsUnique = 5
+	gambleOddsSet    = 10
+	gambleOddsRare   = 1000
+
+	// gambled items are rolled up to gambleLevelBelow levels below and gambleLevelAbove levels
+	// above the level of the player
+	gambleLevelBelow = 5
+	gambleLevelAbove = 4
+
+	// gamblePricePerLevel is multiplied by the square of the level of the player for the gamble price
+	gamblePricePerLevel = 20
+
+	// the rates of the vendors without a row in npc.txt
+	defaultBuyRate    = 1.
+	defaultSellRate   = 0.125
+	defaultRepairRate = 1.
+)
+
+// vendorColumns are the names of the vendor columns of the item tables by the rows of the
+// vendors in monstats.txt, the column of Hratli has a typo
+var vendorColumns = map[string]string{ //nolint:gochecknoglobals // lookup table
+	"akara":    "Akara",
+	"charsi":   "Charsi",
+	"gheed":    "Gheed",
+	"fara":     "Fara",
+	"drognan":  "Drognan",
+	"elzix":    "Elzix",
+	"lysander": "Lysander",
+	"alkor":    "Alkor",
+	"ormus":    "Ormus",
+	"hratli":   "Hralti",
+	"asheara":  "Asheara",
+	"halbu":    "Halbu",
+	"jamella":  "Jamella",
+	"larzuk":   "Larzuk",
+	"malah":    "Malah",
+	"drehya":   "Drehya",
+}
+
+// gamblers are the vendors which let the players gamble
+var gamblers = map[string]bool{ //nolint:gochecknoglobals // lookup table
+	"gheed":   true,
+	"elzix":   true,
+	"alkor":   true,
+	"jamella": true,
+	"drehya":  true,
+}
+
+// repairers are the vendors which repair items
+var repairers = map[string]bool{ //nolint:gochecknoglobals // lookup table
+	"charsi": true,
+	"fara":   true,
+	"hratli": true,
+	"halbu":  true,
+	"larzuk": true,
+}
+
+// qualityPriceFactors multiply the value of the items of the better qualities, the affixes of
+// magic and rare items are priced on their own
+var qualityPriceFactors = map[d2enum.ItemQuality]int{ //nolint:gochecknoglobals // lookup table
+	d2enum.Superior: 2,
+	d2enum.Rare:     2,
+	d2enum.Crafted:  2,
+	d2enum.Tempered: 2,
+	d2enum.Set:      3,
+	d2enum.Unique:   4,
+}
+
+// ShopContext holds what the trade with a vendor depends on
+type ShopContext struct {
+	Vendor     string // row of the vendor in monstats.txt and npc.txt, like "akara"
+	Difficulty d2enum.DifficultyType
+	Level      int // level of the player, the vendors sell better items to players of higher levels
+}
+
+// IsVendor returns true if the given monster, by its row in monstats.txt, sells items
+func IsVendor(vendor string) bool {
+	_, found := vendorColumns[vendor]
+	return found
+}
+
+// CanGamble returns true if the given vendor lets the players gamble
+func CanGamble(vendor string) bool {
+	return gamblers[vendor]
+}
+
+// CanRepair returns true if the given vendor repairs items
+func CanRepair(vendor string) bool {
+	return repairers[vendor]
+}
+
+// VendorItems rolls the items a vendor sells. Each item the vendor columns of which are set for the
+// vendor is sold Min to Max times as a normal item and MagicMin to MagicMax times as a magic
+// item, the items the vendors always sell are sold once. The items better than the level of the
+// player allows are not sold.
+func (f *ItemFactory) VendorItems(ctx *ShopContext) []*Item {
+	column, found := vendorColumns[ctx.Vendor]
+	if !found {
+		return nil
+	}
+
+	level := ctx.Level + vendorLevelBonus
+	items := make([]*Item, 0)
+
+	for _, common := range f.sortedCommonRecords() {
+		params := common.Vendors[column]
+		if params == nil || params.Max == 0 && params.MagicMax == 0 || !common.Spawnable && !common.PermStoreItem {
+			continue
+		}
+
+		if common.PermStoreItem {
+			items = append(items, f.newShopItem(common, level, dropModifierNone))
+			continue
+		}
+
+		if common.Level > level {
+			continue
+		}
+
+		for count := f.rollCount(params.Min, params.Max); count > 0; count-- {
+			items = append(items, f.newShopItem(common, level, dropModifierNone))
+		}
+
+		magicLevel := level
+		if params.MagicLevel > 0 && params.MagicLevel < magicLevel {
+			magicLevel = params.MagicLevel
+		}
+
+		for count := f.rollCount(params.MagicMin, params.MagicMax); count > 0; count-- {
+			items = append(items, f.newShopItem(common, magicLevel, dropModifierMagic))
+		}
+	}
+
+	return items
+}
+
+// GambleItems returns the bases a gambler offers, one of each item in gamble.txt up to the level
+// of the player
+func (f *ItemFactory) GambleItems(ctx *ShopContext) []*Item {
+	if !CanGamble(ctx.Vendor) {
+		return nil
+	}
+
+	codes := make([]string, 0, len(f.asset.Records.Gamble))
+
+	for _, record := range f.asset.Records.Gamble {
+		codes = append(codes, record.Code)
+	}
+
+	sort.Strings(codes)
+
+	items := make([]*Item, 0, len(codes))
+
+	for _, code := range codes {
+		common := f.asset.Records.Item.All[code]
+		if common == nil || common.Level > ctx.Level {
+			continue
+		}
+
+		items = append(items, f.newShopItem(common, ctx.Level, dropModifierNone))
+	}
+
+	return items
+}
+
+// Gamble rolls the item a player gets for gambling on the given base. The item gets a level
+// around the level of the player and is unique, set, rare or else magic.
+func (f *ItemFactory) Gamble(base *Item, ctx *ShopContext) *Item {
+	level := ctx.Level - gambleLevelBelow + f.rand.Intn(gambleLevelBelow+gambleLevelAbove+1)
+	if level < 1 {
+		level = 1
+	}
+
+	modifier := dropModifierMagic
+
+	switch roll := f.rand.Intn(gambleOddsBase); {
+	case roll < gambleOddsUnique:
+		modifier = dropModifierUnique
+	case roll < gambleOddsUnique+gambleOddsSet:
+		modifier = dropModifierSet
+	case roll < gambleOddsUnique+gambleOddsSet+gambleOddsRare:
+		modifier = dropModifierRare
+	}
+
+	return f.newShopItem(base.CommonRecord(), level, modifier)
+}
+
+// BuyPrice returns what the player pays the vendor for the item
+func (f *ItemFactory) BuyPrice(item *Item, ctx *ShopContext) int {
+	buy, _, _ := f.vendorRates(ctx.Vendor)
+
+	return atLeastOne(float64(item.Value()) * buy)
+}
+
+// SellPrice returns what the vendor pays the player for the item. The worn items are worth less
+// and the vendors pay no more than their maximum for the difficulty.
+func (f *ItemFactory) SellPrice(item *Item, ctx *ShopContext) int {
+	_, sell, _ := f.vendorRates(ctx.Vendor)
+
+	price := float64(item.Value()) * sell
+
+	if current, max := item.Durability(); max > 0 {
+		price = price * float64(current) / float64(max)
+	}
+
+	if limit := f.vendorMaxBuy(ctx); limit > 0 && price > float64(limit) {
+		price = float64(limit)
+	}
+
+	return atLeastOne(price)
+}
+
+// RepairPrice returns what the player pays the vendor for repairing the item, the part of its
+// value which is worn out
+func (f *ItemFactory) RepairPrice(item *Item, ctx *ShopContext) int {
+	current, max := item.Durability()
+	if max <= 0 || current >= max {
+		return 0
+	}
+
+	_, _, repair := f.vendorRates(ctx.Vendor)
+
+	return atLeastOne(float64(item.Value()) * repair * float64(max-current) / float64(max))
+}
+
+// GamblePrice returns what the player pays for gambling on the given base, gambling gets
+// expensive quickly with the level of the player
+func (f *ItemFactory) GamblePrice(base *Item, ctx *ShopContext) int {
+	buy, _, _ := f.vendorRates(ctx.Vendor)
+	price := base.CommonRecord().Cost + gamblePricePerLevel*ctx.Level*ctx.Level
+
+	return atLeastOne(float64(price) * buy)
+}
+
+// Value returns what the item is worth before the rates of the vendors: the cost of its base,
+// scaled and raised by its affixes, multiplied for the better qualities. A stack is worth its
+// part of a full stack.
+func (i *Item) Value() int {
+	common := i.CommonRecord()
+	if common == nil {
+		return 0
+	}
+
+	value := common.Cost
+
+	for _, affix := range append(i.PrefixRecords(), i.SuffixRecords()...) {
+		if affix.PriceScale > 0 {
+			value = value * affix.PriceScale / priceScaleBase
+		}
+
+		value += affix.PriceAdd
+	}
+
+	if factor, found := qualityPriceFactors[i.Quality()]; found {
+		value *= factor
+	}
+
+	if common.Stackable && common.MaxStack > 0 && i.attributes != nil {
+		value = value * i.attributes.currentStackSize / common.MaxStack
+	}
+
+	return value
+}
+
+// Durability returns the current and maximum durability of the item, the maximum is zero for the
+// items without durability
+func (i *Item) Durability() (current, max int) {
+	if i.attributes == nil || !i.attributes.durable {
+		return 0, 0
+	}
+
+	return i.attributes.currentDurability, i.attributes.durability.max
+}
+
+// Repair restores the durability of the item
+func (i *Item) Repair() {
+	if i.attributes != nil {
+		i.attributes.currentDurability = i.attributes.durability.max
+	}
+}
+
+// newShopItem makes an identified item of the given base for a vendor
+func (f *ItemFactory) newShopItem(common *d2records.ItemCommonRecord, level int, modifier dropModifier) *Item {
+	// nolint:gosec // we're not concerned with crypto-strong randomness
+	item := &Item{factory: f, CommonCode: common.Code, level: level, rand: rand.New(rand.NewSource(f.rand.Int63()))}
+
+	if modifier != dropModifierNone {
+		item.applyDropModifier(modifier)
+	}
+
+	return item.init().Identify()
+}
+
+// sortedCommonRecords returns the item records sorted by their codes, so that the same seed rolls
+// the same items
+func (f *ItemFactory) sortedCommonRecords() []*d2records.ItemCommonRecord {
+	codes := make([]string, 0, len(f.asset.Records.Item.All))
+
+	for code := range f.asset.Records.Item.All {
+		codes = append(codes, code)
+	}
+
+	sort.Strings(codes)
+
+	records := make([]*d2records.ItemCommonRecord, len(codes))
+
+	for idx, code := range codes {
+		records[idx] = f.asset.Records.Item.All[code]
+	}
+
+	return records
+}
+
+// rollCount returns a count from min to max
+func (f *ItemFactory) rollCount(min, max int) int {
+	if max <= min {
+		return min
+	}
+
+	return min + f.rand.Intn(max-min+1)
+}
+
+// vendorRates returns the buy, sell and repair rates of a vendor
+func (f *ItemFactory) vendorRates(vendor string) (buy, sell, repair float64) {
+	record := f.asset.Records.NPCs[vendor]
+	if record == nil || record.Multipliers == nil {
+		return defaultBuyRate, defaultSellRate, defaultRepairRate
+	}
+
+	return record.Multipliers.Buy, record.Multipliers.Sell, record.Multipliers.Repair
+}
+
+// vendorMaxBuy returns the most a vendor pays for an item in the difficulty, or zero for no limit
+func (f *ItemFactory) vendorMaxBuy(ctx *ShopContext) int {
+	record := f.asset.Records.NPCs[ctx.Vendor]
+	if record == nil {
+		return 0
+	}
+
+	switch ctx.Difficulty {
+	case d2enum.DifficultyNightmare:
+		return record.MaxBuy.Nightmare
+	case d2enum.DifficultyHell:
+		return record.MaxBuy.Hell
+	default:
+		return record.MaxBuy.Normal
+	}
+}
+
+// atLeastOne rounds a price up, nothing is traded for free
+func atLeastOne(price float64) int {
+	if price < 1 {
+		return 1
+	}
+
+	return int(math.Ceil(price))
+}
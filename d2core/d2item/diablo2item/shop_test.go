@@ -0,0 +1,189 @@
+package diablo2item
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func shopTestFactory(t *testing.T) *ItemFactory {
+	factory := cubeTestFactory(t)
+	records := factory.asset.Records
+	items := &records.Item
+
+	for _, common := range items.All {
+		common.Vendors = map[string]*d2records.ItemVendorParams{"Charsi": {}, "Gheed": {}}
+	}
+
+	vendor := func(code string, spawnable bool, params d2records.ItemVendorParams) {
+		items.All[code].Spawnable = spawnable
+		items.All[code].Vendors["Charsi"] = &params
+	}
+
+	items.All["cap"].Cost = 100
+	vendor("cap", true, d2records.ItemVendorParams{Min: 2, Max: 2})
+	vendor("hax", true, d2records.ItemVendorParams{Min: 1, Max: 1, MagicMin: 1, MagicMax: 1})
+	vendor("9ha", true, d2records.ItemVendorParams{Min: 1, Max: 1})
+	vendor("r02", false, d2records.ItemVendorParams{Min: 1, Max: 1})
+
+	items.All["r01"].PermStoreItem = true
+	vendor("r01", false, d2records.ItemVendorParams{Min: 1, Max: 1})
+
+	records.Gamble = d2records.Gamble{
+		"Cap":       {Name: "Cap", Code: "cap"},
+		"Hand Axe":  {Name: "Hand Axe", Code: "hax"},
+		"Great Axe": {Name: "Great Axe", Code: "9ha"},
+	}
+
+	records.NPCs = d2records.NPCs{"charsi": {Name: "charsi"}}
+	records.NPCs["charsi"].MaxBuy.Normal = 5
+
+	return factory
+}
+
+func TestVendorCapabilities(t *testing.T) {
+	tests := []struct {
+		vendor         string
+		sells, gambles bool
+		repairs        bool
+	}{
+		{"charsi", true, false, true},
+		{"gheed", true, true, false},
+		{"hratli", true, false, true},
+		{"cain5", false, false, false},
+	}
+
+	for _, test := range tests {
+		if got := IsVendor(test.vendor); got != test.sells {
+			t.Errorf("IsVendor(%q) = %v, want %v", test.vendor, got, test.sells)
+		}
+
+		if got := CanGamble(test.vendor); got != test.gambles {
+			t.Errorf("CanGamble(%q) = %v, want %v", test.vendor, got, test.gambles)
+		}
+
+		if got := CanRepair(test.vendor); got != test.repairs {
+			t.Errorf("CanRepair(%q) = %v, want %v", test.vendor, got, test.repairs)
+		}
+	}
+}
+
+func TestVendorItems(t *testing.T) {
+	factory := shopTestFactory(t)
+
+	got := make(map[string]int)
+
+	for _, item := range factory.VendorItems(&ShopContext{Vendor: "charsi", Level: 1}) {
+		got[item.CommonCode]++
+
+		if !item.attributes.identitified {
+			t.Errorf("%s is not identified", item.CommonCode)
+		}
+	}
+
+	// the great axe is too good for the player, the second rune does not spawn in shops
+	want := map[string]int{"cap": 2, "hax": 2, "r01": 1}
+
+	if len(got) != len(want) {
+		t.Fatalf("VendorItems() = %v, want %v", got, want)
+	}
+
+	for code, count := range want {
+		if got[code] != count {
+			t.Errorf("VendorItems() = %v, want %v", got, want)
+		}
+	}
+
+	if items := factory.VendorItems(&ShopContext{Vendor: "gheed", Level: 1}); len(items) != 0 {
+		t.Errorf("VendorItems() of another vendor = %d items, want none", len(items))
+	}
+}
+
+func TestItemValue(t *testing.T) {
+	factory := shopTestFactory(t)
+	factory.asset.Records.Item.Magic.Prefix["Strong"].PriceScale = 2048
+	factory.asset.Records.Item.Magic.Prefix["Strong"].PriceAdd = 10
+
+	if got := cubeTestItem(t, factory, "cap").Value(); got != 100 {
+		t.Errorf("Value() = %d, want 100", got)
+	}
+
+	if got := cubeTestItem(t, factory, "cap", "Strong").Value(); got != 210 {
+		t.Errorf("Value() of a magic item = %d, want 210", got)
+	}
+
+	factory.asset.Records.Item.All["jav"].Cost = 60
+	javelins := cubeTestItem(t, factory, "jav")
+	javelins.attributes.currentStackSize = 30
+
+	if got := javelins.Value(); got != 30 {
+		t.Errorf("Value() of half a stack = %d, want 30", got)
+	}
+}
+
+func TestShopPrices(t *testing.T) {
+	factory := shopTestFactory(t)
+	ctx := &ShopContext{Vendor: "charsi"}
+
+	helm := cubeTestItem(t, factory, "cap")
+
+	if got := factory.BuyPrice(helm, ctx); got != 100 {
+		t.Errorf("BuyPrice() = %d, want 100", got)
+	}
+
+	if got := factory.RepairPrice(helm, ctx); got != 0 {
+		t.Errorf("RepairPrice() of an intact item = %d, want 0", got)
+	}
+
+	// charsi pays no more than 5 gold in normal
+	if got := factory.SellPrice(helm, ctx); got != 5 {
+		t.Errorf("SellPrice() = %d, want 5", got)
+	}
+
+	helm.attributes.currentDurability = 6
+
+	if got := factory.SellPrice(helm, &ShopContext{Vendor: "charsi", Difficulty: d2enum.DifficultyHell}); got != 7 {
+		t.Errorf("SellPrice() of a worn item = %d, want 7", got)
+	}
+
+	if got := factory.RepairPrice(helm, ctx); got != 50 {
+		t.Errorf("RepairPrice() = %d, want 50", got)
+	}
+
+	helm.Repair()
+
+	if current, max := helm.Durability(); current != max || max != 12 {
+		t.Errorf("Durability() after Repair() = %d/%d, want 12/12", current, max)
+	}
+}
+
+func TestGamble(t *testing.T) {
+	factory := shopTestFactory(t)
+	ctx := &ShopContext{Vendor: "gheed", Level: 10}
+
+	bases := factory.GambleItems(ctx)
+	if len(bases) != 2 || bases[0].CommonCode != "cap" || bases[1].CommonCode != "hax" {
+		t.Fatalf("GambleItems() = %v, want cap and hax", bases)
+	}
+
+	if items := factory.GambleItems(&ShopContext{Vendor: "charsi", Level: 10}); items != nil {
+		t.Errorf("GambleItems() of a vendor who does not gamble = %v, want none", items)
+	}
+
+	if got, want := factory.GamblePrice(bases[0], ctx), 100+20*10*10; got != want {
+		t.Errorf("GamblePrice() = %d, want %d", got, want)
+	}
+
+	for i := 0; i < 100; i++ {
+		item := factory.Gamble(bases[0], ctx)
+
+		if item.CommonCode != "cap" {
+			t.Fatalf("Gamble() = %s, want a cap", item.CommonCode)
+		}
+
+		if item.level < 5 || item.level > 14 {
+			t.Fatalf("Gamble() rolled level %d, want 5 to 14", item.level)
+		}
+	}
+}
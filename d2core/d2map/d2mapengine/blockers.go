@@ -0,0 +1,69 @@
+package d2mapengine
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+)
+
+// SetBlocked blocks or unblocks walking on the sub tiles of the given area, like the sub tiles a
+// closed door stands on. The areas of several objects may overlap, so a sub tile is walkable
+// again once every area which blocked it is unblocked.
+func (m *MapEngine) SetBlocked(area d2geom.Rectangle, blocked bool) {
+	if m.blocked == nil {
+		m.blocked = make(map[int]int)
+	}
+
+	for subY := area.Top; subY < area.Top+area.Height; subY++ {
+		for subX := area.Left; subX < area.Left+area.Width; subX++ {
+			if !m.subTileInBounds(subX, subY) {
+				continue
+			}
+
+			key := subY*m.size.Width*subtilesPerTile + subX
+
+			if blocked {
+				m.blocked[key]++
+				continue
+			}
+
+			if m.blocked[key] <= 1 {
+				delete(m.blocked, key)
+			} else {
+				m.blocked[key]--
+			}
+		}
+	}
+}
+
+// isBlocked returns true if an object blocks walking on the given sub tile
+func (m *MapEngine) isBlocked(subX, subY int) bool {
+	return m.blocked[subY*m.size.Width*subtilesPerTile+subX] > 0
+}
+
+// SetObjectMode plays the given animation mode of an object of the map, a door blocks walking on
+// the sub tiles it stands on while it is closed
+func (m *MapEngine) SetObjectMode(object *d2mapentity.Object, mode d2enum.ObjectAnimationMode) error {
+	blocked := closedDoor(object) != nil
+
+	if err := object.SetMode(mode); err != nil {
+		return err
+	}
+
+	if closed := closedDoor(object) != nil; closed != blocked {
+		m.SetBlocked(object.Footprint(), closed)
+	}
+
+	return nil
+}
+
+// closedDoor returns the entity if it is a door which blocks walking in its current mode
+func closedDoor(entity d2interface.MapEntity) *d2mapentity.Object {
+	door, ok := entity.(*d2mapentity.Object)
+	if !ok || door.Record() == nil || !door.Record().IsDoor || !door.BlocksWalk() {
+		return nil
+	}
+
+	return door
+}
@@ -31,6 +31,9 @@ type MapEngine struct {
 	tiles         []MapTile
 	size          d2geom.Size               // Size of the map, in tiles
 	levelType     d2records.LevelTypeRecord // Level type of this map
+	levelIDs      []int                     // levels.txt IDs of the levels on this map
+	spawnZones    []SpawnZone               // areas the monsters of the levels are spawned in
+	blocked       map[int]int               // how many objects block each sub tile, by sub tile index
 	dt1TileData   []d2dt1.Tile              // DT1 tile data
 	startSubTileX int                       // Starting X position
 	startSubTileY int                       // Starting Y position
@@ -77,6 +80,8 @@ func (m *MapEngine) ResetMap(levelType d2enum.RegionIdType, width, height int) {
 	m.levelType = *m.asset.Records.Level.Types[levelType]
 	m.size = d2geom.Size{Width: width, Height: height}
 	m.tiles = make([]MapTile, width*height)
+	m.spawnZones = nil
+	m.blocked = nil
 	m.dt1TileData = make([]d2dt1.Tile, 0)
 	m.dt1Files = make([]string, 0)
 
@@ -140,6 +145,16 @@ func (m *MapEngine) AddDS1(fileName string) {
 	}
 }
 
+// SetLevelIDs sets the levels.txt IDs of the levels the map is made of.
+func (m *MapEngine) SetLevelIDs(levelIDs []int) {
+	m.levelIDs = levelIDs
+}
+
+// LevelIDs returns the levels.txt IDs of the levels the map is made of.
+func (m *MapEngine) LevelIDs() []int {
+	return m.levelIDs
+}
+
 // LevelType returns the level type of this map.
 func (m *MapEngine) LevelType() d2records.LevelTypeRecord {
 	return m.levelType
@@ -201,11 +216,54 @@ func (m *MapEngine) PlaceStamp(stamp *d2mapstamp.Stamp, tileOffsetX, tileOffsetY
 	// Copy over the entities
 	stampEntities := stamp.Entities(tileOffsetX, tileOffsetY)
 	for idx := range stampEntities {
-		e := stampEntities[idx]
-		m.entities[e.ID()] = e
+		m.AddEntity(stampEntities[idx])
+	}
+}
+
+// SubstituteTiles copies the given area of a DS1, in tiles, over the tiles of the map at the given
+// location. The empty tiles of the DS1 leave the tiles of the map as they are, the DT1 files of
+// the DS1 have to be added with AddDS1 first.
+func (m *MapEngine) SubstituteTiles(ds1 *d2ds1.DS1, area d2geom.Rectangle, tileOffsetX, tileOffsetY int) {
+	for y := 0; y < area.Height; y++ {
+		for x := 0; x < area.Width; x++ {
+			sourceX, sourceY := area.Left+x, area.Top+y
+			targetX, targetY := tileOffsetX+x, tileOffsetY+y
+
+			if sourceY < 0 || sourceY >= len(ds1.Tiles) || sourceX < 0 || sourceX >= len(ds1.Tiles[sourceY]) ||
+				targetX < 0 || targetY < 0 || targetX >= m.size.Width || targetY >= m.size.Height {
+				continue
+			}
+
+			source := ds1.Tiles[sourceY][sourceX]
+			if isEmptyTile(&source) {
+				continue
+			}
+
+			tile := &m.tiles[m.tileCoordinateToIndex(targetX, targetY)]
+			tile.Components = source
+			tile.SubTiles = [len(tile.SubTiles)]d2dt1.SubTileFlags{}
+			tile.PrepareTile(targetX, targetY, m)
+		}
 	}
 }
 
+// isEmptyTile returns true if the tile of a DS1 has neither a floor nor a wall
+func isEmptyTile(tile *d2ds1.TileRecord) bool {
+	for idx := range tile.Floors {
+		if tile.Floors[idx].Prop1 != 0 {
+			return false
+		}
+	}
+
+	for idx := range tile.Walls {
+		if tile.Walls[idx].Prop1 != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 // converts x,y tile coordinate into index in MapEngine.tiles
 func (m *MapEngine) tileCoordinateToIndex(x, y int) int {
 	return x + (y * m.size.Width)
@@ -242,6 +300,10 @@ func (m *MapEngine) Seed() int64 {
 // AddEntity adds an entity to a slice containing all entities.
 func (m *MapEngine) AddEntity(entity d2interface.MapEntity) {
 	m.entities[entity.ID()] = entity
+
+	if door := closedDoor(entity); door != nil {
+		m.SetBlocked(door.Footprint(), true)
+	}
 }
 
 // RemoveEntity removes an entity from the map engine
@@ -250,6 +312,14 @@ func (m *MapEngine) RemoveEntity(entity d2interface.MapEntity) {
 		return
 	}
 
+	if _, found := m.entities[entity.ID()]; !found {
+		return
+	}
+
+	if door := closedDoor(entity); door != nil {
+		m.SetBlocked(door.Footprint(), false)
+	}
+
 	delete(m.entities, entity.ID())
 }
 
@@ -1,48 +1,342 @@
 package d2mapengine
 
 import (
+	"container/heap"
 	"math"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
 )
 
-// PathFind finds a path between given start and dest positions and returns the positions of the path
+const (
+	// defaultPathFindBudget is the maximum number of sub tiles the path finder will expand before giving up and
+	// returning a partial path towards the destination.
+	defaultPathFindBudget = 20000
+
+	straightStepCost = 1.0
+	diagonalStepCost = math.Sqrt2
+
+	subTileCenterOffset = 0.5
+)
+
+// pathNode is a single sub tile in the open set of the path finder.
+type pathNode struct {
+	x, y  int
+	g     float64 // cost from the start to this node
+	h     float64 // estimated cost from this node to the destination
+	order int     // insertion order, used for deterministic tie breaking
+	index int     // index in the heap
+}
+
+func (n *pathNode) f() float64 {
+	return n.g + n.h
+}
+
+// pathNodeQueue is a min-heap of path nodes ordered by f, then h, then insertion order.
+type pathNodeQueue []*pathNode
+
+func (q pathNodeQueue) Len() int {
+	return len(q)
+}
+
+func (q pathNodeQueue) Less(i, j int) bool {
+	fi, fj := q[i].f(), q[j].f()
+
+	if fi != fj {
+		return fi < fj
+	}
+
+	if q[i].h != q[j].h {
+		return q[i].h < q[j].h
+	}
+
+	return q[i].order < q[j].order
+}
+
+func (q pathNodeQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *pathNodeQueue) Push(x interface{}) {
+	node := x.(*pathNode)
+	node.index = len(*q)
+	*q = append(*q, node)
+}
+
+func (q *pathNodeQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	node := old[n-1]
+	old[n-1] = nil
+	node.index = -1
+	*q = old[:n-1]
+
+	return node
+}
+
+// neighbor offsets, straight moves first so that ties prefer them
+var pathNeighbors = [8][2]int{ //nolint:gochecknoglobals // constant lookup table
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{1, 1}, {-1, 1}, {1, -1}, {-1, -1},
+}
+
+// PathFind finds a path between given start and dest positions and returns the positions of the path.
+// The path does not include the start position. If the destination cannot be reached, the path leads
+// to the reachable sub tile closest to the destination. An empty path means no movement is possible.
 func (m *MapEngine) PathFind(start, dest d2vector.Position) []d2vector.Position {
-	points := make([]d2vector.Position, 0)
-	_, point := m.checkLos(start, dest)
-	points = append(points, point)
+	return m.pathFind(start, dest, defaultPathFindBudget)
+}
+
+func (m *MapEngine) pathFind(start, dest d2vector.Position, budget int) []d2vector.Position {
+	startX, startY := int(math.Floor(start.X())), int(math.Floor(start.Y()))
+	destX, destY := int(math.Floor(dest.X())), int(math.Floor(dest.Y()))
+
+	if !m.subTileInBounds(startX, startY) {
+		return nil
+	}
+
+	if startX == destX && startY == destY {
+		return []d2vector.Position{dest}
+	}
+
+	cells, reached := m.aStar(startX, startY, destX, destY, budget)
+	if len(cells) < 2 { // nolint:gomnd // a path needs at least the start and one more node
+		return nil
+	}
+
+	points := make([]d2vector.Position, len(cells))
+	points[0] = start
+
+	for idx := 1; idx < len(cells); idx++ {
+		points[idx] = d2vector.NewPosition(
+			float64(cells[idx][0])+subTileCenterOffset,
+			float64(cells[idx][1])+subTileCenterOffset,
+		)
+	}
+
+	if reached {
+		points[len(points)-1] = dest
+	}
+
+	return m.smoothPath(points)
+}
+
+// aStar searches the sub tile grid for a path between the given sub tiles. It returns the sub tiles
+// of the path, including the start, and whether the destination was reached. When the destination
+// is unreachable or the budget is exhausted, the path to the closest explored sub tile is returned.
+func (m *MapEngine) aStar(startX, startY, destX, destY, budget int) (cells [][2]int, reached bool) {
+	width := m.size.Width * subtilesPerTile
+	key := func(x, y int) int { return x + y*width }
+
+	open := &pathNodeQueue{}
+	nodes := make(map[int]*pathNode)
+	closed := make(map[int]bool)
+	parents := make(map[int]int)
+
+	order := 0
+	first := &pathNode{x: startX, y: startY, h: octileDistance(startX, startY, destX, destY)}
+
+	heap.Push(open, first)
+	nodes[key(startX, startY)] = first
+
+	best := first
+
+	for expanded := 0; open.Len() > 0 && expanded < budget; expanded++ {
+		current := heap.Pop(open).(*pathNode)
+		currentKey := key(current.x, current.y)
+		closed[currentKey] = true
+
+		if current.h < best.h || (current.h == best.h && current.g < best.g) {
+			best = current
+		}
+
+		if current.x == destX && current.y == destY {
+			reached = true
+			break
+		}
+
+		for _, offset := range pathNeighbors {
+			nx, ny := current.x+offset[0], current.y+offset[1]
+			neighborKey := key(nx, ny)
+
+			if closed[neighborKey] || !m.canStep(current.x, current.y, offset[0], offset[1]) {
+				continue
+			}
 
-	return points
+			cost := straightStepCost
+			if offset[0] != 0 && offset[1] != 0 {
+				cost = diagonalStepCost
+			}
+
+			g := current.g + cost
+
+			neighbor, found := nodes[neighborKey]
+			if found && g >= neighbor.g {
+				continue
+			}
+
+			parents[neighborKey] = currentKey
+
+			if found {
+				neighbor.g = g
+				heap.Fix(open, neighbor.index)
+
+				continue
+			}
+
+			order++
+			neighbor = &pathNode{x: nx, y: ny, g: g, h: octileDistance(nx, ny, destX, destY), order: order}
+			nodes[neighborKey] = neighbor
+
+			heap.Push(open, neighbor)
+		}
+	}
+
+	startKey := key(startX, startY)
+
+	for k := key(best.x, best.y); ; k = parents[k] {
+		cells = append(cells, [2]int{k % width, k / width})
+
+		if k == startKey {
+			break
+		}
+	}
+
+	for i, j := 0, len(cells)-1; i < j; i, j = i+1, j-1 {
+		cells[i], cells[j] = cells[j], cells[i]
+	}
+
+	return cells, reached
+}
+
+// canStep returns true if an entity can move from the given sub tile by the given offset. Diagonal
+// steps are only allowed when both of the adjacent straight sub tiles are walkable, so that entities
+// never cut the corner of a wall.
+func (m *MapEngine) canStep(x, y, dx, dy int) bool {
+	if !m.subTileWalkable(x+dx, y+dy) {
+		return false
+	}
+
+	if dx != 0 && dy != 0 {
+		return m.subTileWalkable(x+dx, y) && m.subTileWalkable(x, y+dy)
+	}
+
+	return true
+}
+
+// subTileInBounds returns true if the given sub tile lies within the map.
+func (m *MapEngine) subTileInBounds(subX, subY int) bool {
+	return subX >= 0 && subY >= 0 &&
+		subX < m.size.Width*subtilesPerTile && subY < m.size.Height*subtilesPerTile
+}
+
+// subTileWalkable returns true if the given sub tile lies within the map and neither its tile nor
+// an object like a closed door blocks walking on it.
+func (m *MapEngine) subTileWalkable(subX, subY int) bool {
+	return m.subTileInBounds(subX, subY) && !m.SubTileAt(subX, subY).BlockWalk && !m.isBlocked(subX, subY)
+}
+
+// smoothPath removes every path node which can be skipped because there is a clear line of
+// sight between the previous and the following node.
+func (m *MapEngine) smoothPath(points []d2vector.Position) []d2vector.Position {
+	smoothed := make([]d2vector.Position, 0, len(points)-1)
+	anchor := 0
+
+	for anchor < len(points)-1 {
+		next := anchor + 1
+
+		for next+1 < len(points) {
+			if clear, _ := m.checkLos(points[anchor], points[next+1]); !clear {
+				break
+			}
+
+			next++
+		}
+
+		smoothed = append(smoothed, points[next])
+		anchor = next
+	}
+
+	return smoothed
+}
+
+// octileDistance is the cost of the shortest path between two sub tiles on an empty grid
+// which allows diagonal movement.
+func octileDistance(x1, y1, x2, y2 int) float64 {
+	dx := math.Abs(float64(x2 - x1))
+	dy := math.Abs(float64(y2 - y1))
+
+	return straightStepCost*(dx+dy) + (diagonalStepCost-2*straightStepCost)*math.Min(dx, dy)
 }
 
 // checkLos finds out if there is a clear line of sight between two points
 func (m *MapEngine) checkLos(start, end d2vector.Position) (bool, d2vector.Position) {
-	dv := d2vector.Position{Vector: *end.Clone()}
-	dv.Subtract(&start.Vector)
-	dx := dv.X()
-	dy := dv.Y()
-	N := math.Max(math.Abs(dx), math.Abs(dy))
-
-	var divN float64
-	if N == 0 {
-		divN = 0.0
-	} else {
-		divN = 1.0 / N // nolint:gomnd // we're just taking inverse...
-	}
-
-	xstep := dx * divN
-	ystep := dy * divN
-	x := start.X()
-	y := start.Y()
-
-	for i := 0; i <= int(N); i++ {
-		x += xstep
-		y += ystep
-
-		if m.SubTileAt(int(math.Floor(x)), int(math.Floor(y))).BlockWalk {
-			return false, d2vector.NewPosition(x-xstep, y-ystep)
+	return m.traceLine(start, end, func(subX, subY int) bool {
+		return !m.subTileWalkable(subX, subY)
+	})
+}
+
+// traceLine walks every sub tile the line between two points crosses and returns false and the
+// point where the line enters the first sub tile which lies outside the map or is blocked. The
+// sub tile of the start is not checked. A line through the corner of two sub tiles crosses both
+// of them, so that it never squeezes between two diagonal walls.
+func (m *MapEngine) traceLine(start, end d2vector.Position,
+	blocked func(subX, subY int) bool) (bool, d2vector.Position) {
+	subX, subY := int(math.Floor(start.X())), int(math.Floor(start.Y()))
+	endX, endY := int(math.Floor(end.X())), int(math.Floor(end.Y()))
+	dx, dy := end.X()-start.X(), end.Y()-start.Y()
+
+	stepX, nextX, deltaX := lineAxis(start.X(), dx)
+	stepY, nextY, deltaY := lineAxis(start.Y(), dy)
+
+	isBlocked := func(x, y int) bool {
+		return !m.subTileInBounds(x, y) || blocked(x, y)
+	}
+
+	for subX != endX || subY != endY {
+		t := math.Min(nextX, nextY)
+		entry := d2vector.NewPosition(start.X()+dx*t, start.Y()+dy*t)
+
+		switch {
+		case subY == endY || (subX != endX && nextX < nextY-lineEpsilon):
+			subX += stepX
+			nextX += deltaX
+		case subX == endX || nextY < nextX-lineEpsilon:
+			subY += stepY
+			nextY += deltaY
+		default: // through a corner, both sub tiles beside the corner are crossed
+			if isBlocked(subX+stepX, subY) || isBlocked(subX, subY+stepY) {
+				return false, entry
+			}
+
+			subX += stepX
+			subY += stepY
+			nextX += deltaX
+			nextY += deltaY
+		}
+
+		if isBlocked(subX, subY) {
+			return false, entry
 		}
 	}
 
 	return true, end
 }
+
+// lineEpsilon is the tolerance under which a line crosses the borders of two axes at once
+const lineEpsilon = 1e-9
+
+// lineAxis returns, for one axis of a line starting at the given coordinate and moving by delta,
+// the sub tile step, the fraction of the line at which it crosses the first sub tile border and
+// the fraction of the line between two borders
+func lineAxis(from, delta float64) (step int, next, between float64) {
+	switch {
+	case delta > 0:
+		return 1, (math.Floor(from) + 1 - from) / delta, 1 / delta
+	case delta < 0:
+		return -1, (from - math.Floor(from)) / -delta, 1 / -delta
+	}
+
+	return 0, math.Inf(1), math.Inf(1)
+}
@@ -0,0 +1,327 @@
+package d2mapengine
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+)
+
+// testMapEngine creates a map engine from a grid of sub tiles, where '#' blocks walking.
+// The grid is padded with walkable sub tiles to a whole number of tiles.
+func testMapEngine(grid string) *MapEngine {
+	rows := strings.Split(strings.TrimSpace(grid), "\n")
+
+	width := (len(rows[0]) + subtilesPerTile - 1) / subtilesPerTile
+	height := (len(rows) + subtilesPerTile - 1) / subtilesPerTile
+
+	m := &MapEngine{
+		size:  d2geom.Size{Width: width, Height: height},
+		tiles: make([]MapTile, width*height),
+	}
+
+	for y, row := range rows {
+		for x, cell := range strings.TrimSpace(row) {
+			if cell == '#' {
+				m.SubTileAt(x, y).BlockWalk = true
+			}
+		}
+	}
+
+	return m
+}
+
+func TestPathFind(t *testing.T) {
+	table := []struct {
+		name    string
+		grid    string
+		start   [2]float64
+		dest    [2]float64
+		reaches bool
+		minLen  int
+	}{
+		{
+			name: "open grid",
+			grid: `
+				..........
+				..........
+				..........
+				..........
+				..........`,
+			start:   [2]float64{0.5, 0.5},
+			dest:    [2]float64{9.5, 4.5},
+			reaches: true,
+			minLen:  1,
+		},
+		{
+			name: "wall with a gap",
+			grid: `
+				.....#....
+				.....#....
+				.....#....
+				.....#....
+				..........`,
+			start:   [2]float64{1.5, 0.5},
+			dest:    [2]float64{8.5, 0.5},
+			reaches: true,
+			minLen:  2,
+		},
+		{
+			name: "corridor",
+			grid: `
+				..........
+				#########.
+				..........
+				.#########
+				..........`,
+			start:   [2]float64{0.5, 0.5},
+			dest:    [2]float64{0.5, 4.5},
+			reaches: true,
+			minLen:  4,
+		},
+		{
+			name: "enclosed destination",
+			grid: `
+				..........
+				......###.
+				......#.#.
+				......###.
+				..........`,
+			start:   [2]float64{0.5, 2.5},
+			dest:    [2]float64{7.5, 2.5},
+			reaches: false,
+			minLen:  1,
+		},
+		{
+			name: "blocked destination",
+			grid: `
+				..........
+				..........
+				.......#..
+				..........
+				..........`,
+			start:   [2]float64{0.5, 2.5},
+			dest:    [2]float64{7.5, 2.5},
+			reaches: false,
+			minLen:  1,
+		},
+		{
+			name: "line through a wall",
+			grid: `
+				..#..
+				.....
+				.....`,
+			start:   [2]float64{0.5, 0.5},
+			dest:    [2]float64{4.5, 1.5},
+			reaches: true,
+			minLen:  2,
+		},
+		{
+			name: "same sub tile",
+			grid: `
+				.....
+				.....`,
+			start:   [2]float64{2.2, 1.2},
+			dest:    [2]float64{2.8, 1.7},
+			reaches: true,
+			minLen:  1,
+		},
+	}
+
+	for _, row := range table {
+		m := testMapEngine(row.grid)
+		start := d2vector.NewPosition(row.start[0], row.start[1])
+		dest := d2vector.NewPosition(row.dest[0], row.dest[1])
+
+		path := m.PathFind(start, dest)
+
+		if len(path) < row.minLen {
+			t.Errorf("%s: path too short, got %d nodes, want at least %d", row.name, len(path), row.minLen)
+			continue
+		}
+
+		last := path[len(path)-1]
+		if reached := last.Equals(&dest.Vector); reached != row.reaches {
+			t.Errorf("%s: path ends at %s, reaching destination %s: got %v, want %v",
+				row.name, last.Vector, dest.Vector, reached, row.reaches)
+		}
+
+		from := start
+
+		for idx := range path {
+			if clear, _ := m.checkLos(from, path[idx]); !clear {
+				t.Errorf("%s: path segment %s -> %s is blocked", row.name, from.Vector, path[idx].Vector)
+			}
+
+			from = path[idx]
+		}
+
+		if again := m.PathFind(start, dest); !reflect.DeepEqual(path, again) {
+			t.Errorf("%s: path is not deterministic", row.name)
+		}
+	}
+}
+
+func TestCheckLos(t *testing.T) {
+	m := testMapEngine(`
+		..#..
+		.....
+		.#...
+		..#..
+		.....`)
+
+	table := []struct {
+		start, end [2]float64
+		clear      bool
+	}{
+		{[2]float64{0.5, 0.5}, [2]float64{4.5, 1.5}, false},
+		{[2]float64{0.5, 1.5}, [2]float64{4.5, 1.5}, true},
+		{[2]float64{0.5, 0.5}, [2]float64{1.5, 0.5}, true},
+		{[2]float64{1.5, 3.5}, [2]float64{2.5, 2.5}, false},
+		{[2]float64{1.0, 4.0}, [2]float64{3.0, 2.0}, false},
+		{[2]float64{3.5, 4.5}, [2]float64{4.5, 0.5}, true},
+		{[2]float64{4.5, 4.5}, [2]float64{0.5, 0.5}, false},
+		{[2]float64{2.5, 2.5}, [2]float64{10.5, 2.5}, false},
+	}
+
+	for _, row := range table {
+		start := d2vector.NewPosition(row.start[0], row.start[1])
+		end := d2vector.NewPosition(row.end[0], row.end[1])
+
+		if clear, _ := m.checkLos(start, end); clear != row.clear {
+			t.Errorf("checkLos(%v, %v) gave %v, want %v", row.start, row.end, clear, row.clear)
+		}
+	}
+}
+
+func TestPathFindPartial(t *testing.T) {
+	m := testMapEngine(`
+		..........
+		..........
+		......###.
+		......#.#.
+		......###.`)
+
+	start := d2vector.NewPosition(0.5, 3.5)
+	dest := d2vector.NewPosition(7.5, 3.5)
+	path := m.PathFind(start, dest)
+
+	if len(path) == 0 {
+		t.Fatal("expected a partial path towards the enclosed destination")
+	}
+
+	last := path[len(path)-1]
+	want := d2vector.NewPosition(5.5, 3.5)
+
+	if !last.Equals(&want.Vector) {
+		t.Errorf("partial path should end next to the enclosure, got %s, want %s", last.Vector, want.Vector)
+	}
+}
+
+func TestPathFindNoCornerCutting(t *testing.T) {
+	m := testMapEngine(`
+		.....
+		.#...
+		..#..
+		.....
+		.....`)
+
+	cells, reached := m.aStar(1, 2, 2, 1, defaultPathFindBudget)
+
+	if !reached {
+		t.Fatal("expected destination to be reached")
+	}
+
+	for idx := 1; idx < len(cells); idx++ {
+		dx := cells[idx][0] - cells[idx-1][0]
+		dy := cells[idx][1] - cells[idx-1][1]
+
+		if !m.canStep(cells[idx-1][0], cells[idx-1][1], dx, dy) {
+			t.Errorf("illegal step from %v to %v", cells[idx-1], cells[idx])
+		}
+	}
+
+	// going around the diagonal wall takes at least four steps
+	if len(cells) < 5 {
+		t.Errorf("path cuts the corner of a wall: %v", cells)
+	}
+}
+
+func TestPathFindBudget(t *testing.T) {
+	m := testMapEngine(`
+		....................
+		....................
+		....................
+		....................
+		....................`)
+
+	start := d2vector.NewPosition(0.5, 0.5)
+	dest := d2vector.NewPosition(19.5, 4.5)
+
+	if path := m.pathFind(start, dest, 1); len(path) != 0 {
+		t.Errorf("expected no movement with an exhausted budget, got %v", path)
+	}
+
+	path := m.pathFind(start, dest, 5)
+	if len(path) == 0 {
+		t.Fatal("expected a partial path with a small budget")
+	}
+
+	if last := path[len(path)-1]; last.Equals(&dest.Vector) {
+		t.Errorf("expected the partial path to stop short of the destination")
+	}
+}
+
+func TestBlockedAreasBlockPaths(t *testing.T) {
+	m := testMapEngine(`
+		....#....
+		....#....
+		.........
+		....#....
+		....#....`)
+
+	start := d2vector.NewPosition(1.5, 2.5)
+	dest := d2vector.NewPosition(7.5, 2.5)
+	door := d2geom.Rectangle{Left: 4, Top: 2, Width: 1, Height: 1}
+
+	m.SetBlocked(door, true)
+	m.SetBlocked(door, true)
+
+	if path := m.PathFind(start, dest); len(path) > 0 && path[len(path)-1].Equals(&dest.Vector) {
+		t.Fatal("the path goes through the closed door")
+	}
+
+	m.SetBlocked(door, false)
+
+	if m.Walkable(d2vector.NewPosition(4.5, 2.5)) {
+		t.Error("the door is open while another object still blocks it")
+	}
+
+	m.SetBlocked(door, false)
+
+	if path := m.PathFind(start, dest); len(path) == 0 || !path[len(path)-1].Equals(&dest.Vector) {
+		t.Errorf("the path does not go through the open door: %v", path)
+	}
+}
+
+func TestOctileDistance(t *testing.T) {
+	table := []struct {
+		x1, y1, x2, y2 int
+		result         float64
+	}{
+		{0, 0, 0, 0, 0},
+		{0, 0, 5, 0, 5},
+		{0, 0, 0, -3, 3},
+		{0, 0, 2, 2, 2 * math.Sqrt2},
+		{1, 1, 4, 2, 2 + math.Sqrt2},
+	}
+
+	for _, row := range table {
+		if res := octileDistance(row.x1, row.y1, row.x2, row.y2); math.Abs(res-row.result) > 1e-9 {
+			t.Errorf("octileDistance(%d, %d, %d, %d) gave %f, want %f", row.x1, row.y1, row.x2, row.y2, res, row.result)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package d2mapengine
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+)
+
+// LineOfSight returns true if no sub tile on the line between the two positions blocks the
+// sight. Missiles fly and monsters see through the sub tiles which block walking only.
+func (m *MapEngine) LineOfSight(start, end d2vector.Position) bool {
+	clear, _ := m.traceLine(start, end, func(subX, subY int) bool {
+		return m.SubTileAt(subX, subY).BlockLOS
+	})
+
+	return clear
+}
+
+// Walkable returns true if the sub tile at the given position lies within the map and does
+// not block walking
+func (m *MapEngine) Walkable(position d2vector.Position) bool {
+	return m.subTileWalkable(int(position.X()), int(position.Y()))
+}
@@ -0,0 +1,22 @@
+package d2mapengine
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+)
+
+// SpawnZone is an area of the map the random monsters of a level are spawned in
+type SpawnZone struct {
+	Area    d2geom.Rectangle // in tiles
+	LevelID int              // levels.txt ID of the level whose monsters are spawned
+}
+
+// AddSpawnZone adds an area the random monsters of the given level are spawned in
+func (m *MapEngine) AddSpawnZone(area d2geom.Rectangle, levelID int) {
+	m.spawnZones = append(m.spawnZones, SpawnZone{Area: area, LevelID: levelID})
+}
+
+// SpawnZones returns the areas the random monsters of the levels on the map are spawned in, in
+// the order they were added
+func (m *MapEngine) SpawnZones() []SpawnZone {
+	return m.spawnZones
+}
@@ -0,0 +1,59 @@
+package d2mapengine
+
+// WarpTileStyle is the style of the special tiles which mark the warps of a level, the
+// sequence of a warp tile is the Vis column of levels.txt the warp belongs to
+const WarpTileStyle = 8
+
+// Warp is a warp tile which takes the players to another level
+type Warp struct {
+	X, Y    float64 // world position of the middle of the tile
+	LevelID int     // levels.txt ID of the level the warp leads to
+	WarpID  int     // lvlwarp.txt ID of the warp graphics
+}
+
+// Warps returns the warp tiles of the levels on the map. A warp spanning several tiles is
+// returned once for every tile.
+func (m *MapEngine) Warps() []Warp {
+	warps := make([]Warp, 0)
+
+	for tileY := 0; tileY < m.size.Height; tileY++ {
+		for tileX := 0; tileX < m.size.Width; tileX++ {
+			tile := &m.tiles[tileX+(tileY*m.size.Width)]
+
+			for idx := range tile.Components.Walls {
+				wall := &tile.Components.Walls[idx]
+				if !wall.Type.Special() || wall.Style != WarpTileStyle {
+					continue
+				}
+
+				levelID, warpID := m.levelLink(int(tile.RegionType), int(wall.Sequence))
+				if levelID == 0 {
+					continue
+				}
+
+				warps = append(warps, Warp{
+					X:       float64(tileX) + 0.5, //nolint:gomnd // middle of the tile
+					Y:       float64(tileY) + 0.5, //nolint:gomnd // middle of the tile
+					LevelID: levelID,
+					WarpID:  warpID,
+				})
+			}
+		}
+	}
+
+	return warps
+}
+
+// levelLink returns the level linked by the given Vis column of the level of the given type
+func (m *MapEngine) levelLink(levelType, index int) (levelID, warpID int) {
+	for _, id := range m.levelIDs {
+		details := m.asset.Records.Level.Details[id]
+		if details == nil || details.LevelType != levelType {
+			continue
+		}
+
+		return details.LevelLink(index)
+	}
+
+	return 0, -1
+}
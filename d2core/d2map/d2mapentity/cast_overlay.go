@@ -13,6 +13,8 @@ type CastOverlay struct {
 	record     *d2records.OverlayRecord
 	playLoop   bool
 	onDoneFunc func()
+	follow     *d2vector.Position // the position of the unit the overlay stays on, nil if it does not follow a unit
+	offset     d2vector.Vector
 }
 
 // ID returns the overlay uuid
@@ -46,9 +48,30 @@ func (co *CastOverlay) SetOnDoneFunc(onDoneFunc func()) {
 	co.onDoneFunc = onDoneFunc
 }
 
+// SetPlayLoop makes the overlay play over and over until it is removed, the overlays of the
+// states which last for a while loop.
+func (co *CastOverlay) SetPlayLoop(playLoop bool) {
+	co.playLoop = playLoop
+	co.animation.SetPlayLoop(playLoop)
+}
+
+// Follow makes the overlay move along with the given position of a unit
+func (co *CastOverlay) Follow(position *d2vector.Position) {
+	co.follow = position
+	co.offset = *co.Position.Vector.Clone()
+	co.offset.Subtract(&position.Vector)
+}
+
 // Advance is called once per frame and processes a single game tick.
 func (co *CastOverlay) Advance(tickTime float64) {
 	co.Step(tickTime)
+
+	if co.follow != nil {
+		co.Position.Copy(&co.follow.Vector)
+		co.Position.Add(&co.offset)
+		co.Target.Copy(&co.Position.Vector)
+	}
+
 	co.AnimatedEntity.Advance(tickTime)
 
 	if !co.playLoop && co.AnimatedEntity.animation.GetPlayedCount() >= 1 {
@@ -14,6 +14,7 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2inventory"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
 )
 
 const (
@@ -96,6 +97,7 @@ func (f *MapEntityFactory) NewPlayer(id, name string, x, y, direction int, heroT
 		LeftSkill:  heroState.Skills[leftSkill],
 		RightSkill: heroState.Skills[rightSkill],
 		name:       name,
+		states:     d2states.NewStates(),
 		Class:      heroType,
 		//nameLabel:    d2ui.NewLabel(d2resource.FontFormal11, d2resource.PaletteStatic),
 		isRunToggled: false,
@@ -106,7 +108,7 @@ func (f *MapEntityFactory) NewPlayer(id, name string, x, y, direction int, heroT
 	}
 
 	result.mapEntity.uuid = id
-	result.SetSpeed(baseWalkSpeed)
+	result.SetSpeed(BaseWalkSpeed)
 	result.mapEntity.directioner = result.rotate
 	err = composite.SetMode(d2enum.PlayerAnimationModeTownNeutral, equipment.RightHand.GetWeaponClass())
 
@@ -159,6 +161,11 @@ func (f *MapEntityFactory) NewItem(x, y int, codes ...string) (*Item, error) {
 		return nil, err
 	}
 
+	return f.NewItemEntity(x*subtilesPerTile, y*subtilesPerTile, item)
+}
+
+// NewItemEntity creates a map entity for the given item at the given sub tile position
+func (f *MapEntityFactory) NewItemEntity(x, y int, item *diablo2item.Item) (*Item, error) {
 	filename := item.CommonRecord().FlippyFile
 	filepath := fmt.Sprintf("%s/%s.DC6", d2resource.ItemGraphics, filename)
 	animation, err := f.asset.LoadAnimation(filepath, d2resource.PaletteUnits)
@@ -169,7 +176,7 @@ func (f *MapEntityFactory) NewItem(x, y int, codes ...string) (*Item, error) {
 
 	animation.PlayForward()
 	animation.SetPlayLoop(false)
-	entity := NewAnimatedEntity(x*subtilesPerTile, y*subtilesPerTile, animation)
+	entity := NewAnimatedEntity(x, y, animation)
 
 	result := &Item{
 		AnimatedEntity: entity,
@@ -179,6 +186,11 @@ func (f *MapEntityFactory) NewItem(x, y int, codes ...string) (*Item, error) {
 	return result, nil
 }
 
+// ItemFactory returns the factory of the items of the map entities
+func (f *MapEntityFactory) ItemFactory() *diablo2item.ItemFactory {
+	return f.item
+}
+
 // NewNPC creates a new NPC and returns a pointer to it.
 func (f *MapEntityFactory) NewNPC(x, y int, monstat *d2records.MonStatRecord, direction int) (*NPC, error) {
 	// https://github.com/OpenDiablo2/OpenDiablo2/issues/803
@@ -187,6 +199,7 @@ func (f *MapEntityFactory) NewNPC(x, y int, monstat *d2records.MonStatRecord, di
 		HasPaths:      false,
 		monstatRecord: monstat,
 		monstatEx:     f.asset.Records.Monster.Stats2[monstat.ExtraDataKey],
+		states:        d2states.NewStates(),
 	}
 
 	var equipment [16]string
@@ -0,0 +1,52 @@
+package d2mapentity
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+)
+
+// static check that HeadlessEntity implements MapEntity
+var _ d2interface.MapEntity = &HeadlessEntity{}
+
+// HeadlessEntity is a map entity without graphics. The game server uses it to
+// simulate the movement of the entities it owns.
+type HeadlessEntity struct {
+	mapEntity
+}
+
+// NewHeadlessEntity creates a headless entity with the given ID at the given
+// sub tile position.
+func NewHeadlessEntity(id string, position d2vector.Position) *HeadlessEntity {
+	entity := &HeadlessEntity{
+		mapEntity: newMapEntity(0, 0),
+	}
+
+	entity.uuid = id
+	entity.Position = position
+	entity.Target = position
+
+	return entity
+}
+
+// ID returns the entity ID
+func (e *HeadlessEntity) ID() string {
+	return e.uuid
+}
+
+// GetPosition returns the position of the entity
+func (e *HeadlessEntity) GetPosition() d2vector.Position {
+	return e.Position
+}
+
+// GetVelocity returns the velocity vector of the entity
+func (e *HeadlessEntity) GetVelocity() d2vector.Vector {
+	return e.velocity
+}
+
+// Render does nothing, a headless entity is never drawn.
+func (e *HeadlessEntity) Render(_ d2interface.Surface) {}
+
+// Advance moves the entity along its path by one tick.
+func (e *HeadlessEntity) Advance(tickTime float64) {
+	e.Step(tickTime)
+}
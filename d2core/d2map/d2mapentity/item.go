@@ -13,6 +13,9 @@ var _ d2interface.MapEntity = &Item{}
 type Item struct {
 	*AnimatedEntity
 	Item *diablo2item.Item
+
+	owner     string  // ID of the player the item dropped for
+	ownerTime float64 // seconds left until any player can pick the item up
 }
 
 // ID returns the item uuid
@@ -59,3 +62,32 @@ func (i *Item) GetSize() (width, height int) {
 
 	return w, h
 }
+
+// SetOwner reserves the item for the player with the given ID for the given number of seconds
+func (i *Item) SetOwner(playerID string, seconds float64) {
+	i.owner, i.ownerTime = playerID, seconds
+}
+
+// Owner returns the ID of the player the item is reserved for, it is empty if the item can be
+// picked up by anyone
+func (i *Item) Owner() string {
+	if i.ownerTime <= 0 {
+		return ""
+	}
+
+	return i.owner
+}
+
+// CanPickUp returns true if the player with the given ID may pick the item up
+func (i *Item) CanPickUp(playerID string) bool {
+	owner := i.Owner()
+
+	return owner == "" || owner == playerID
+}
+
+// Advance counts down the time the item is reserved for its owner and updates the animation
+func (i *Item) Advance(elapsed float64) {
+	i.ownerTime -= elapsed
+
+	i.AnimatedEntity.Advance(elapsed)
+}
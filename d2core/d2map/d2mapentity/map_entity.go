@@ -103,6 +103,20 @@ func (m *mapEntity) Step(tickTime float64) {
 	}
 }
 
+// Destination returns the position the entity is moving to, which is the end of its path.
+func (m *mapEntity) Destination() d2vector.Position {
+	if m.hasPath() {
+		return m.path[len(m.path)-1]
+	}
+
+	return m.Target
+}
+
+// IsMoving returns true if the entity has not reached the end of its path.
+func (m *mapEntity) IsMoving() bool {
+	return !m.atTarget() || m.hasPath()
+}
+
 // atTarget returns true if the distance between entity and target is almost zero.
 func (m *mapEntity) atTarget() bool {
 	return m.Position.EqualsApprox(&m.Target.Vector)
@@ -10,6 +10,7 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2path"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
 )
 
 // NPC is a passive complex entity with which the player can interact.
@@ -26,6 +27,8 @@ type NPC struct {
 	monstatEx     *d2records.MonStat2Record
 	HasPaths      bool
 	isDone        bool
+	isAttacking   bool
+	states        *d2states.States
 }
 
 const (
@@ -98,6 +101,14 @@ func (v *NPC) Advance(tickTime float64) {
 		return
 	}
 
+	if v.isAttacking && v.composite.GetPlayedCount() > 0 {
+		v.isAttacking = false
+
+		if err := v.composite.SetMode(d2enum.MonsterAnimationModeNeutral, v.composite.GetWeaponClass()); err != nil {
+			return
+		}
+	}
+
 	if v.HasPaths && v.wait() {
 		// If at the target, set target to the next path.
 		v.isDone = false
@@ -164,6 +175,29 @@ func (v *NPC) rotate(direction int) {
 	}
 }
 
+// Attack stops the NPC, turns it towards the given sub tile position and plays its attack
+// animation once
+func (v *NPC) Attack(x, y float64) {
+	v.StopMoving()
+	v.composite.SetDirection(v.Position.DirectionTo(*d2vector.NewVector(x, y)))
+
+	if err := v.composite.SetMode(d2enum.MonsterAnimationModeAttack1, v.composite.GetWeaponClass()); err != nil {
+		return
+	}
+
+	v.isAttacking = true
+}
+
+// MonStatRecord returns the monstats.txt record of the NPC
+func (v *NPC) MonStatRecord() *d2records.MonStatRecord {
+	return v.monstatRecord
+}
+
+// States returns the states active on the NPC
+func (v *NPC) States() *d2states.States {
+	return v.states
+}
+
 // Selectable returns true if the object can be highlighted/selected.
 func (v *NPC) Selectable() bool {
 	// is there something handy that determines selectable npc's?
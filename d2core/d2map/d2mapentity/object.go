@@ -8,6 +8,7 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
@@ -66,6 +67,74 @@ func (ob *Object) ID() string {
 	return ob.uuid
 }
 
+// initFnWaypoint is the InitFn of objects.txt of the waypoints
+const initFnWaypoint = 17
+
+// IsWaypoint returns true if the object is a waypoint
+func (ob *Object) IsWaypoint() bool {
+	return ob.objectRecord.InitFn == initFnWaypoint
+}
+
+// objectSubClassContainer is the SubClass flag of objects.txt of the chests and other containers
+const objectSubClassContainer = 8
+
+// IsContainer returns true if the object is a chest or another container which drops treasure
+// when it is opened
+func (ob *Object) IsContainer() bool {
+	return ob.objectRecord.SubClass&objectSubClassContainer != 0
+}
+
+// IsOpen returns true if the object has been operated
+func (ob *Object) IsOpen() bool {
+	return ob.composite.ObjectAnimationMode() != d2enum.ObjectAnimationModeNeutral
+}
+
+// Open plays the operating animation of the object, the object stays open afterwards
+func (ob *Object) Open() error {
+	switch {
+	case ob.objectRecord.HasAnimationMode[d2enum.ObjectAnimationModeOperating]:
+		return ob.setMode(d2enum.ObjectAnimationModeOperating, 0, false)
+	case ob.objectRecord.HasAnimationMode[d2enum.ObjectAnimationModeOpened]:
+		return ob.setMode(d2enum.ObjectAnimationModeOpened, 0, false)
+	}
+
+	return nil
+}
+
+// Record returns the objects.txt row of the object
+func (ob *Object) Record() *d2records.ObjectDetailRecord {
+	return ob.objectRecord
+}
+
+// Mode returns the animation mode of the object
+func (ob *Object) Mode() d2enum.ObjectAnimationMode {
+	return ob.composite.ObjectAnimationMode()
+}
+
+// SetMode plays the given animation mode of the object, the modes the object does not have are
+// ignored
+func (ob *Object) SetMode(mode d2enum.ObjectAnimationMode) error {
+	if mode == ob.Mode() || int(mode) >= len(ob.objectRecord.HasAnimationMode) ||
+		!ob.objectRecord.HasAnimationMode[mode] {
+		return nil
+	}
+
+	return ob.setMode(mode, 0, false)
+}
+
+// BlocksWalk returns true if the object blocks walking in its current mode, like a closed door
+func (ob *Object) BlocksWalk() bool {
+	return ob.objectRecord.HasCollision[ob.Mode()]
+}
+
+// Footprint returns the sub tiles the object stands on
+func (ob *Object) Footprint() d2geom.Rectangle {
+	x, y := int(ob.Position.X()), int(ob.Position.Y())
+	width, height := ob.objectRecord.SizeX, ob.objectRecord.SizeY
+
+	return d2geom.Rectangle{Left: x - width/2, Top: y - height/2, Width: width, Height: height}
+}
+
 // Highlight sets the entity highlighted flag to true.
 func (ob *Object) Highlight() {
 	ob.highlight = true
@@ -11,7 +11,6 @@ func initObject(ob *Object) (bool, error) {
 	funcs := map[int]func(*Object) error{
 		8:  initTorch,
 		14: initTorch,
-		17: initWaypoint,
 		34: initTorchRnd,
 	}
 
@@ -36,15 +35,6 @@ func initTorch(ob *Object) error {
 	return nil
 }
 
-func initWaypoint(ob *Object) error {
-	// Turn these on unconditionally for now, they look nice :)
-	if ob.objectRecord.HasAnimationMode[d2enum.ObjectAnimationModeOpened] {
-		return ob.setMode(d2enum.ObjectAnimationModeOpened, 0, true)
-	}
-
-	return nil
-}
-
 // Randomly spawns in either NU or OP
 func initTorchRnd(ob *Object) error {
 	const coinToss = 2
@@ -9,6 +9,7 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2inventory"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
 )
 
 // Player is the player character entity.
@@ -31,12 +32,15 @@ type Player struct {
 	isCasting         bool
 	onFinishedCasting func()
 	Act               int
+	states            *d2states.States
 }
 
 // run speed should be walkspeed * 1.5, since in the original game it is 6 yards walk and 9 yards run.
 const (
-	baseWalkSpeed = 9.0
-	baseRunSpeed  = 13.0
+	// BaseWalkSpeed is the walking speed of a player, in sub tiles per second
+	BaseWalkSpeed = 9.0
+	// BaseRunSpeed is the running speed of a player, in sub tiles per second
+	BaseRunSpeed = 13.0
 )
 
 // ID returns the Player uuid
@@ -44,6 +48,11 @@ func (p *Player) ID() string {
 	return p.mapEntity.uuid
 }
 
+// States returns the states active on the player
+func (p *Player) States() *d2states.States {
+	return p.states
+}
+
 // SetIsInTown sets a flag indicating that the player is in town.
 func (p *Player) SetIsInTown(isInTown bool) {
 	p.isInTown = isInTown
@@ -71,9 +80,9 @@ func (p *Player) SetIsRunning(isRunning bool) {
 	p.isRunning = isRunning
 
 	if isRunning {
-		p.SetSpeed(baseRunSpeed)
+		p.SetSpeed(BaseRunSpeed)
 	} else {
-		p.SetSpeed(baseWalkSpeed)
+		p.SetSpeed(BaseWalkSpeed)
 	}
 }
 
@@ -135,13 +144,13 @@ func (p *Player) Advance(tickTime float64) {
 	if p.IsRunning() && !p.atTarget() && !p.IsInTown() {
 		p.Stats.Stamina -= staminaDrain * tickTime / magicStaminaDrainDivisor
 		if p.Stats.Stamina <= 0 {
-			p.SetSpeed(baseWalkSpeed)
+			p.SetSpeed(BaseWalkSpeed)
 			p.Stats.Stamina = 0
 		}
 	} else if p.Stats.Stamina < float64(p.Stats.MaxStamina) {
 		p.Stats.Stamina += staminaDrain * tickTime / magicStaminaDrainDivisor
 		if p.IsRunning() {
-			p.SetSpeed(baseRunSpeed)
+			p.SetSpeed(BaseRunSpeed)
 		}
 	}
 }
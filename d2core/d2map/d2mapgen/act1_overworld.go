@@ -273,23 +273,54 @@ func (g *MapGenerator) generateWilderness1TownWest(startX, startY int) {
 func (g *MapGenerator) generateWilderness1Contents(rect d2geom.Rectangle) {
 	levelDetails := g.asset.Records.GetLevelDetails(wildernessDetailsRecordID)
 
+	g.engine.AddSpawnZone(rect, wildernessDetailsRecordID)
+
 	denOfEvil := g.loadPreset(d2wilderness.DenOfEvilEntrance, 0)
 	denOfEvilLoc := d2geom.Point{
 		X: rect.Left + (rect.Width / 2) + rand.Intn(10),
 		Y: rect.Top + (rect.Height / 2) + rand.Intn(10),
 	}
 
-	// Fill in the grass
+	g.fillGround(rect, d2enum.RegionIdType(levelDetails.LevelType))
+
+	stuff := g.act1WildernessFill()
+
+	g.engine.PlaceStamp(denOfEvil, denOfEvilLoc.X, denOfEvilLoc.Y)
+
+	numPlaced := 0
+	for numPlaced < 25 {
+		stamp := stuff[rand.Intn(len(stuff))]
+
+		stampRect := d2geom.Rectangle{
+			Left:   rect.Left + rand.Intn(rect.Width) - stamp.Size().Width,
+			Top:    rect.Top + rand.Intn(rect.Height) - stamp.Size().Height,
+			Width:  stamp.Size().Width,
+			Height: stamp.Size().Height,
+		}
+
+		if areaEmpty(g.engine, stampRect) {
+			g.engine.PlaceStamp(stamp, stampRect.Left, stampRect.Top)
+			numPlaced++
+		}
+	}
+}
+
+// fillGround covers the tiles of the given area with the ground of the wilderness
+func (g *MapGenerator) fillGround(rect d2geom.Rectangle, regionType d2enum.RegionIdType) {
 	for y := 0; y < rect.Height; y++ {
 		for x := 0; x < rect.Width; x++ {
 			tile := g.engine.Tile(rect.Left+x, rect.Top+y)
-			tile.RegionType = d2enum.RegionIdType(levelDetails.LevelType)
+			tile.RegionType = regionType
 			tile.Components.Floors = []d2ds1.FloorShadowRecord{{Prop1: 1, Style: 0, Sequence: 0}} // wildernessGrass
 			tile.PrepareTile(x, y, g.engine)
 		}
 	}
+}
 
-	stuff := []*d2mapstamp.Stamp{
+// act1WildernessFill returns the stamps which fill the wilderness of act 1: stones, cottages,
+// camps of the fallen, ponds and swamps
+func (g *MapGenerator) act1WildernessFill() []*d2mapstamp.Stamp {
+	return []*d2mapstamp.Stamp{
 		g.loadPreset(d2wilderness.StoneFill1, presetA),
 		g.loadPreset(d2wilderness.StoneFill1, presetB),
 		g.loadPreset(d2wilderness.StoneFill1, presetC),
@@ -310,23 +341,4 @@ func (g *MapGenerator) generateWilderness1Contents(rect d2geom.Rectangle) {
 		g.loadPreset(d2wilderness.SwampFill1, presetA),
 		g.loadPreset(d2wilderness.SwampFill2, presetA),
 	}
-
-	g.engine.PlaceStamp(denOfEvil, denOfEvilLoc.X, denOfEvilLoc.Y)
-
-	numPlaced := 0
-	for numPlaced < 25 {
-		stamp := stuff[rand.Intn(len(stuff))]
-
-		stampRect := d2geom.Rectangle{
-			Left:   rect.Left + rand.Intn(rect.Width) - stamp.Size().Width,
-			Top:    rect.Top + rand.Intn(rect.Height) - stamp.Size().Height,
-			Width:  stamp.Size().Width,
-			Height: stamp.Size().Height,
-		}
-
-		if areaEmpty(g.engine, stampRect) {
-			g.engine.PlaceStamp(stamp, stampRect.Left, stampRect.Top)
-			numPlaced++
-		}
-	}
 }
@@ -0,0 +1,157 @@
+package d2mapgen
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapstamp"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const (
+	act1TownLevelID = 1
+)
+
+// ErrUnsupportedLevel is returned for the levels which cannot be generated yet
+var ErrUnsupportedLevel = errors.New("level cannot be generated")
+
+// the act 1 overworld is generated with the random source of math/rand, a server and a local
+// client generating their maps at the same time would otherwise get different maps from the same seed
+var generateMutex sync.Mutex //nolint:gochecknoglobals // guards the global random source
+
+// GenerateLevel generates the map of the level with the given levels.txt ID and returns the IDs
+// of the levels on the map, the first town of act 1 is generated together with its wilderness.
+// The other levels are generated by their DrlgType: a random maze of the rooms of lvlmaze.txt, an
+// outdoor area scattered with the presets of lvlprest.txt or a single preset. Generating the same
+// level with the same seed always gives the same map.
+func (g *MapGenerator) GenerateLevel(levelID int) ([]int, error) {
+	generateMutex.Lock()
+	defer generateMutex.Unlock()
+
+	var levelIDs []int
+
+	switch levelID {
+	case act1TownLevelID, wildernessDetailsRecordID:
+		g.GenerateAct1Overworld()
+
+		levelIDs = []int{act1TownLevelID, wildernessDetailsRecordID}
+	default:
+		details := g.asset.Records.Level.Details[levelID]
+		if details == nil {
+			return nil, fmt.Errorf("unknown level %d", levelID)
+		}
+
+		levelTypes := g.asset.Records.Level.Types
+		if details.LevelType <= 0 || details.LevelType >= len(levelTypes) || levelTypes[details.LevelType] == nil {
+			return nil, fmt.Errorf("%w: %s (%d)", ErrUnsupportedLevel, details.Name, levelID)
+		}
+
+		rng := rand.New(rand.NewSource(g.engine.Seed() + int64(levelID))) //nolint:gosec // not for security
+
+		var err error
+
+		switch details.LevelGenerationType {
+		case d2enum.LevelTypeRandomMaze:
+			err = g.generateMaze(details, rng)
+		case d2enum.LevelTypeWilderness:
+			err = g.generateWilderness(details, rng)
+		default:
+			err = g.generatePreset(details, rng)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		levelIDs = []int{levelID}
+	}
+
+	g.engine.SetLevelIDs(levelIDs)
+
+	return levelIDs, nil
+}
+
+// generatePreset generates a level which is made of a single preset of lvlprest.txt
+func (g *MapGenerator) generatePreset(details *d2records.LevelDetailRecord, rng *rand.Rand) error {
+	preset, found := g.levelPreset(details.ID)
+	if !found {
+		return fmt.Errorf("%w: %s (%d)", ErrUnsupportedLevel, details.Name, details.ID)
+	}
+
+	regionType := d2enum.RegionIdType(details.LevelType)
+	stamp := g.engine.LoadStamp(regionType, preset.DefinitionID, g.pickFile(rng, preset.DefinitionID))
+	size := stamp.Size()
+
+	g.engine.ResetMap(regionType, size.Width, size.Height)
+	g.engine.PlaceStamp(stamp, 0, 0)
+	g.newSubstitutions(details, rng).apply(stamp, 0, 0)
+	g.engine.AddSpawnZone(d2geom.Rectangle{Width: size.Width, Height: size.Height}, details.ID)
+
+	return nil
+}
+
+// loadStamp loads one of the files of the given preset, picked at random, as a stamp for a map of
+// the given level type
+func (g *MapGenerator) loadStamp(rng *rand.Rand, regionType d2enum.RegionIdType, presetID int) *d2mapstamp.Stamp {
+	for _, file := range g.asset.Records.LevelPreset(presetID).Files {
+		g.engine.AddDS1(file)
+	}
+
+	return g.engine.LoadStamp(regionType, presetID, g.pickFile(rng, presetID))
+}
+
+// pickFile returns the index of one of the files of the given preset, picked at random
+func (g *MapGenerator) pickFile(rng *rand.Rand, presetID int) int {
+	files := 0
+
+	for _, file := range g.asset.Records.LevelPreset(presetID).Files {
+		if file != "" && file != "0" {
+			files++
+		}
+	}
+
+	if files == 0 {
+		return autoFileIndex
+	}
+
+	return rng.Intn(files)
+}
+
+// levelPreset returns the preset which makes up the whole of the given level, the one with the
+// lowest ID if there are several
+func (g *MapGenerator) levelPreset(levelID int) (result d2records.LevelPresetRecord, found bool) {
+	presets := g.levelPresets(levelID)
+	if len(presets) == 0 {
+		return result, false
+	}
+
+	return presets[0], true
+}
+
+// levelPresets returns the presets of lvlprest.txt which belong to the given level and have files,
+// in the order of their IDs
+func (g *MapGenerator) levelPresets(levelID int) []d2records.LevelPresetRecord {
+	presets := make([]d2records.LevelPresetRecord, 0)
+
+	for _, preset := range g.asset.Records.Level.Presets {
+		if preset.LevelID != levelID {
+			continue
+		}
+
+		for _, file := range preset.Files {
+			if file != "" && file != "0" {
+				presets = append(presets, preset)
+				break
+			}
+		}
+	}
+
+	sort.Slice(presets, func(i, j int) bool { return presets[i].DefinitionID < presets[j].DefinitionID })
+
+	return presets
+}
@@ -0,0 +1,325 @@
+package d2mapgen
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+// the exits of a maze room, a room is joined to the room next to it on the side of an exit
+const (
+	mazeNorth = 1 << iota
+	mazeEast
+	mazeSouth
+	mazeWest
+)
+
+// mazeSides are the sides of a maze room, with the letters they are written as in the names of
+// the rooms in lvlprest.txt
+var mazeSides = [...]struct { //nolint:gochecknoglobals // lookup table
+	exit, opposite int
+	dx, dy         int
+	letter         rune
+}{
+	{exit: mazeNorth, opposite: mazeSouth, dx: 0, dy: -1, letter: 'N'},
+	{exit: mazeEast, opposite: mazeWest, dx: 1, dy: 0, letter: 'E'},
+	{exit: mazeSouth, opposite: mazeNorth, dx: 0, dy: 1, letter: 'S'},
+	{exit: mazeWest, opposite: mazeEast, dx: -1, dy: 0, letter: 'W'},
+}
+
+// mazeRoom is a room of a maze on the grid of the rooms
+type mazeRoom struct {
+	x, y  int
+	exits int
+	kind  string // the kind of special room, empty for the rooms in between
+	depth int    // how many rooms there are between the room and the entrance
+}
+
+// roomPreset is a lvlprest.txt row which can be a maze room, the rows of the rooms of a level
+// type are named like "Act 1 - Cave Prev W" or "Act 1 - Cave 2 NS": the name of the level type,
+// the kind of the room and its exits. Rooms whose kind is a number are the rooms in between.
+type roomPreset struct {
+	id    int
+	exits int
+	kind  string
+}
+
+// parseRoomPreset returns the maze room of the given preset of the level type with the given name
+func parseRoomPreset(levelTypeName string, preset *d2records.LevelPresetRecord) (roomPreset, bool) {
+	if preset.LevelID != 0 || !strings.HasPrefix(preset.Name, levelTypeName+" ") {
+		return roomPreset{}, false
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(preset.Name, levelTypeName))
+	if len(fields) == 0 {
+		return roomPreset{}, false
+	}
+
+	exits := 0
+
+	for _, letter := range fields[len(fields)-1] {
+		found := false
+
+		for _, side := range mazeSides {
+			if letter == side.letter {
+				exits |= side.exit
+				found = true
+			}
+		}
+
+		if !found {
+			return roomPreset{}, false
+		}
+	}
+
+	kind := strings.Join(fields[:len(fields)-1], " ")
+	if strings.Trim(kind, "0123456789") == "" {
+		kind = ""
+	}
+
+	return roomPreset{id: preset.DefinitionID, exits: exits, kind: kind}, true
+}
+
+// mazeGrowAttempts is how many times for every room of a maze a room is picked to grow the maze from
+const mazeGrowAttempts = 100
+
+// mazeLayout lays out a maze of the given number of rooms, the rooms only get the exits the given
+// function accepts for their kind. The first of the given kinds of special rooms is the entrance,
+// the first room, the others are dead ends as far from the entrance as possible. Every room is
+// joined to the room it has been grown from, so the maze has no loops.
+func mazeLayout(rng *rand.Rand, roomCount int, specials []string, fits func(kind string, exits int) bool) []*mazeRoom {
+	entrance := &mazeRoom{}
+	if len(specials) > 0 {
+		entrance.kind = specials[0]
+		specials = specials[1:]
+	}
+
+	rooms := []*mazeRoom{entrance}
+	taken := map[d2geom.Point]bool{{}: true}
+
+	grow := func(from *mazeRoom, kind string) bool {
+		sides := make([]int, 0, len(mazeSides))
+
+		for idx, side := range mazeSides {
+			if !taken[d2geom.Point{X: from.x + side.dx, Y: from.y + side.dy}] &&
+				fits(from.kind, from.exits|side.exit) && fits(kind, side.opposite) {
+				sides = append(sides, idx)
+			}
+		}
+
+		if len(sides) == 0 {
+			return false
+		}
+
+		side := mazeSides[sides[rng.Intn(len(sides))]]
+		room := &mazeRoom{x: from.x + side.dx, y: from.y + side.dy, exits: side.opposite, kind: kind, depth: from.depth + 1}
+		from.exits |= side.exit
+		taken[d2geom.Point{X: room.x, Y: room.y}] = true
+		rooms = append(rooms, room)
+
+		return true
+	}
+
+	// the rooms in between come first, the entrance stays a dead end
+	for attempt := 0; attempt < roomCount*mazeGrowAttempts && len(rooms) < maxInt(roomCount-len(specials), 2); attempt++ {
+		from := rooms[rng.Intn(len(rooms))]
+		if from != entrance || from.exits == 0 {
+			grow(from, "")
+		}
+	}
+
+	// then every special room on the deepest room in between it fits on
+	for _, kind := range specials {
+		candidates := make([]*mazeRoom, 0, len(rooms))
+
+		for _, room := range rooms {
+			if room.kind == "" {
+				candidates = append(candidates, room)
+			}
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].depth > candidates[j].depth })
+
+		for _, from := range candidates {
+			if grow(from, kind) {
+				break
+			}
+		}
+	}
+
+	return rooms
+}
+
+// generateMaze generates a level of lvlmaze.txt from the maze rooms of its level type
+func (g *MapGenerator) generateMaze(details *d2records.LevelDetailRecord, rng *rand.Rand) error {
+	maze := g.asset.Records.Level.Maze[details.ID]
+	if maze == nil || maze.SizeX <= 0 || maze.SizeY <= 0 {
+		return fmt.Errorf("%w: %s (%d) has no maze", ErrUnsupportedLevel, details.Name, details.ID)
+	}
+
+	regionType := d2enum.RegionIdType(details.LevelType)
+	levelType := g.asset.Records.Level.Types[details.LevelType]
+	presets := g.roomPresets(levelType.Name, maze)
+
+	if len(presets) == 0 {
+		return fmt.Errorf("%w: %s (%d) has no maze rooms", ErrUnsupportedLevel, details.Name, details.ID)
+	}
+
+	fits := func(kind string, exits int) bool {
+		for _, preset := range presets {
+			if preset.kind == kind && preset.exits == exits {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	rooms := mazeLayout(rng, maze.NumRoomsNormal, g.specialRooms(rng, regionType, details, presets), fits)
+
+	var left, top, right, bottom int
+
+	for _, room := range rooms {
+		left, top = minInt(left, room.x), minInt(top, room.y)
+		right, bottom = maxInt(right, room.x), maxInt(bottom, room.y)
+	}
+
+	g.engine.ResetMap(regionType, (right-left+1)*maze.SizeX, (bottom-top+1)*maze.SizeY)
+
+	substitutions := g.newSubstitutions(details, rng)
+
+	for idx, room := range rooms {
+		preset, found := pickRoomPreset(rng, presets, room)
+		if !found {
+			return fmt.Errorf("%w: %s (%d) has no room %q with the exits %d", ErrUnsupportedLevel,
+				details.Name, details.ID, room.kind, room.exits)
+		}
+
+		tileX, tileY := (room.x-left)*maze.SizeX, (room.y-top)*maze.SizeY
+
+		stamp := g.loadStamp(rng, regionType, preset.id)
+		if size := stamp.Size(); size.Width > maze.SizeX || size.Height > maze.SizeY {
+			return fmt.Errorf("maze room %d of %s is larger than %dx%d", preset.id, details.Name, maze.SizeX, maze.SizeY)
+		}
+
+		g.engine.PlaceStamp(stamp, tileX, tileY)
+		substitutions.apply(stamp, tileX, tileY)
+
+		// the players come into the maze in the entrance, no monsters wait for them there
+		if idx > 0 {
+			g.engine.AddSpawnZone(d2geom.Rectangle{Left: tileX, Top: tileY, Width: maze.SizeX, Height: maze.SizeY}, details.ID)
+		}
+	}
+
+	return nil
+}
+
+// roomPresets returns the maze rooms of the level type with the given name which fit the rooms of
+// the given maze, in the order of their lvlprest.txt IDs
+func (g *MapGenerator) roomPresets(levelTypeName string, maze *d2records.LevelMazeDetailRecord) []roomPreset {
+	presets := make([]roomPreset, 0)
+
+	for id := range g.asset.Records.Level.Presets {
+		preset := g.asset.Records.Level.Presets[id]
+		if (preset.SizeX > maze.SizeX) || (preset.SizeY > maze.SizeY) {
+			continue
+		}
+
+		if room, ok := parseRoomPreset(levelTypeName, &preset); ok {
+			presets = append(presets, room)
+		}
+	}
+
+	sort.Slice(presets, func(i, j int) bool { return presets[i].id < presets[j].id })
+
+	return presets
+}
+
+// specialRooms returns the kinds of special rooms the level needs: the rooms with the warps to the
+// levels it is linked to, in the order of the Vis columns of the warps
+func (g *MapGenerator) specialRooms(rng *rand.Rand, regionType d2enum.RegionIdType,
+	details *d2records.LevelDetailRecord, presets []roomPreset) []string {
+	links := make(map[string]int)
+
+	for _, preset := range presets {
+		if _, found := links[preset.kind]; found || preset.kind == "" {
+			continue
+		}
+
+		stamp := g.loadStamp(rng, regionType, preset.id)
+		size := stamp.Size()
+
+		for y := 0; y < size.Height; y++ {
+			for x := 0; x < size.Width; x++ {
+				for _, wall := range stamp.Tile(x, y).Walls {
+					if !wall.Type.Special() || wall.Style != d2mapengine.WarpTileStyle {
+						continue
+					}
+
+					link := int(wall.Sequence)
+					if levelID, _ := details.LevelLink(link); levelID == 0 {
+						continue
+					}
+
+					if current, found := links[preset.kind]; !found || link < current {
+						links[preset.kind] = link
+					}
+				}
+			}
+		}
+	}
+
+	kinds := make([]string, 0, len(links))
+	for kind := range links {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Slice(kinds, func(i, j int) bool {
+		if links[kinds[i]] != links[kinds[j]] {
+			return links[kinds[i]] < links[kinds[j]]
+		}
+
+		return kinds[i] < kinds[j]
+	})
+
+	return kinds
+}
+
+// pickRoomPreset picks one of the presets of the kind of the given room with exactly its exits
+func pickRoomPreset(rng *rand.Rand, presets []roomPreset, room *mazeRoom) (roomPreset, bool) {
+	candidates := make([]roomPreset, 0)
+
+	for _, preset := range presets {
+		if preset.kind == room.kind && preset.exits == room.exits {
+			candidates = append(candidates, preset)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return roomPreset{}, false
+	}
+
+	return candidates[rng.Intn(len(candidates))], true
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,123 @@
+package d2mapgen
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func TestRoomPresetsAreParsedFromTheirNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		room  roomPreset
+		found bool
+	}{
+		{name: "Act 1 - Cave Prev W", room: roomPreset{id: 1, exits: mazeWest, kind: "Prev"}, found: true},
+		{name: "Act 1 - Cave 3 NSE", room: roomPreset{id: 1, exits: mazeNorth | mazeSouth | mazeEast}, found: true},
+		{name: "Act 1 - Cave Treasure 2 N", room: roomPreset{id: 1, exits: mazeNorth, kind: "Treasure 2"}, found: true},
+		{name: "Act 1 - Cave Den Of Evil", found: false},
+		{name: "Act 1 - Crypt 1 N", found: false},
+		{name: "Act 1 - Cave", found: false},
+	}
+
+	for _, test := range tests {
+		preset := &d2records.LevelPresetRecord{Name: test.name, DefinitionID: 1}
+
+		room, found := parseRoomPreset("Act 1 - Cave", preset)
+		if found != test.found || room != test.room {
+			t.Errorf("%q: %+v %v, want %+v %v", test.name, room, found, test.room, test.found)
+		}
+	}
+
+	if _, found := parseRoomPreset("Act 1 - Cave", &d2records.LevelPresetRecord{Name: "Act 1 - Cave Prev W", LevelID: 8}); found {
+		t.Error("the preset of a level is a maze room")
+	}
+}
+
+func TestMazesAreJoinedTreesOfRooms(t *testing.T) {
+	fitsAll := func(string, int) bool { return true }
+	rooms := mazeLayout(rand.New(rand.NewSource(1)), 12, []string{"Prev", "Next"}, fitsAll)
+
+	if len(rooms) != 12 {
+		t.Fatalf("%d rooms", len(rooms))
+	}
+
+	byPosition := make(map[[2]int]*mazeRoom)
+	joins := 0
+
+	for _, room := range rooms {
+		if byPosition[[2]int{room.x, room.y}] != nil {
+			t.Fatalf("two rooms at %d,%d", room.x, room.y)
+		}
+
+		byPosition[[2]int{room.x, room.y}] = room
+	}
+
+	for _, room := range rooms {
+		for _, side := range mazeSides {
+			if room.exits&side.exit == 0 {
+				continue
+			}
+
+			joins++
+
+			next := byPosition[[2]int{room.x + side.dx, room.y + side.dy}]
+			if next == nil || next.exits&side.opposite == 0 {
+				t.Fatalf("the exit of room %d,%d leads nowhere", room.x, room.y)
+			}
+		}
+	}
+
+	if joins/2 != len(rooms)-1 {
+		t.Errorf("%d joins between %d rooms", joins/2, len(rooms))
+	}
+
+	if rooms[0].kind != "Prev" || !isDeadEnd(rooms[0]) {
+		t.Errorf("the entrance is %q with the exits %d", rooms[0].kind, rooms[0].exits)
+	}
+
+	last := rooms[len(rooms)-1]
+	if last.kind != "Next" || !isDeadEnd(last) {
+		t.Errorf("the last special room is %q with the exits %d", last.kind, last.exits)
+	}
+}
+
+func TestMazesOnlyHaveRoomsWhichFit(t *testing.T) {
+	// the rooms in between are corridors, the special rooms have a single exit
+	fits := func(kind string, exits int) bool {
+		if kind != "" {
+			return exits == mazeNorth || exits == mazeSouth
+		}
+
+		return exits == mazeNorth || exits == mazeSouth || exits == mazeNorth|mazeSouth
+	}
+
+	rooms := mazeLayout(rand.New(rand.NewSource(3)), 6, []string{"Prev", "Next"}, fits)
+
+	for _, room := range rooms {
+		if !fits(room.kind, room.exits) || room.x != 0 {
+			t.Errorf("room %q at %d,%d has the exits %d", room.kind, room.x, room.y, room.exits)
+		}
+	}
+
+	if len(rooms) != 6 {
+		t.Errorf("%d rooms", len(rooms))
+	}
+}
+
+func TestMazesAreLaidOutTheSameFromTheSameSeed(t *testing.T) {
+	fitsAll := func(string, int) bool { return true }
+
+	first := mazeLayout(rand.New(rand.NewSource(42)), 20, []string{"Prev", "Next", "Down"}, fitsAll)
+	second := mazeLayout(rand.New(rand.NewSource(42)), 20, []string{"Prev", "Next", "Down"}, fitsAll)
+
+	if !reflect.DeepEqual(first, second) {
+		t.Error("the same seed laid out different mazes")
+	}
+}
+
+func isDeadEnd(room *mazeRoom) bool {
+	return room.exits == mazeNorth || room.exits == mazeEast || room.exits == mazeSouth || room.exits == mazeWest
+}
@@ -0,0 +1,131 @@
+package d2mapgen
+
+import (
+	"math/rand"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2ds1"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapstamp"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const substitutionChanceScale = 100 // the Prob columns of lvlsub.txt are percentages
+
+// substitutions puts the tiles of the lvlsub.txt rows of a level into the substitution groups of
+// the stamps of the level: the SubType of the level is the group of rows to use, its SubTheme the
+// Prob, Trials and Max columns of the rows. The rows and pieces are picked with the random source
+// of the level, so a level gets the same substitutions from the same seed.
+type substitutions struct {
+	generator *MapGenerator
+	rng       *rand.Rand
+	rows      []*d2records.LevelSubstitutionRecord
+	theme     int
+	used      []int                 // how many times each row has been used on the level
+	files     map[string]*d2ds1.DS1 // the DS1 files of the rows which have been loaded
+}
+
+func (g *MapGenerator) newSubstitutions(details *d2records.LevelDetailRecord, rng *rand.Rand) *substitutions {
+	s := &substitutions{
+		generator: g,
+		rng:       rng,
+		theme:     details.SubTheme,
+		files:     make(map[string]*d2ds1.DS1),
+	}
+
+	if details.SubType >= 0 && details.SubTheme >= 0 && details.SubTheme < d2records.SubstitutionThemes {
+		s.rows = g.asset.Records.Level.Sub[details.SubType]
+	}
+
+	s.used = make([]int, len(s.rows))
+
+	return s
+}
+
+// apply substitutes the tiles of the substitution groups of the given stamp, placed at the given
+// tile position. A group gets the tiles of the first row which succeeds in one of its trials.
+func (s *substitutions) apply(stamp *d2mapstamp.Stamp, tileX, tileY int) {
+	for _, group := range stamp.SubstitutionGroups() {
+		for idx, row := range s.rows {
+			chance, trials, maximum := row.Theme(s.theme)
+			if maximum > 0 && s.used[idx] >= maximum {
+				continue
+			}
+
+			if !s.roll(chance, trials) {
+				continue
+			}
+
+			if s.substitute(row, group, tileX, tileY) {
+				s.used[idx]++
+				break
+			}
+		}
+	}
+}
+
+// roll returns true if one of the given number of trials succeeds with the given chance
+func (s *substitutions) roll(chance, trials int) bool {
+	if trials < 1 {
+		trials = 1
+	}
+
+	for trial := 0; trial < trials; trial++ {
+		if s.rng.Intn(substitutionChanceScale) < chance {
+			return true
+		}
+	}
+
+	return false
+}
+
+// substitute copies a random piece of the DS1 of the given row, the size of the given group, over
+// the tiles of the group. The DS1 of a row is made of pieces of the size of the groups it fills.
+func (s *substitutions) substitute(row *d2records.LevelSubstitutionRecord, group d2ds1.SubstitutionGroup,
+	tileX, tileY int) bool {
+	width, height := int(group.WidthInTiles), int(group.HeightInTiles)
+	if width <= 0 || height <= 0 {
+		return false
+	}
+
+	ds1 := s.load(row.File)
+	if ds1 == nil {
+		return false
+	}
+
+	piecesX, piecesY := int(ds1.Width)/width, int(ds1.Height)/height
+	if piecesX == 0 || piecesY == 0 {
+		return false
+	}
+
+	piece := s.rng.Intn(piecesX * piecesY)
+	area := d2geom.Rectangle{Left: piece % piecesX * width, Top: piece / piecesX * height, Width: width, Height: height}
+
+	s.generator.engine.SubstituteTiles(ds1, area, tileX+int(group.TileX), tileY+int(group.TileY))
+
+	return true
+}
+
+// load returns the DS1 file with the given path, its DT1 files are added to the map
+func (s *substitutions) load(path string) *d2ds1.DS1 {
+	if ds1, found := s.files[path]; found {
+		return ds1
+	}
+
+	var ds1 *d2ds1.DS1
+
+	data, err := s.generator.asset.LoadFile("/data/global/tiles/" + path)
+	if err == nil {
+		ds1, err = d2ds1.LoadDS1(data)
+	}
+
+	if err != nil {
+		s.generator.Errorf("could not load the substitution %s: %v", path, err)
+		ds1 = nil
+	} else {
+		s.generator.engine.AddDS1(path)
+	}
+
+	s.files[path] = ds1
+
+	return ds1
+}
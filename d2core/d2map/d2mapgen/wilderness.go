@@ -0,0 +1,230 @@
+package d2mapgen
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapgen/d2wilderness"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapstamp"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const (
+	wildernessFillArea = 256 // tiles of wilderness for each fill stamp
+	wildernessTries    = 20  // positions tried for each stamp
+	wildernessAttempts = 10  // times the stamps are scattered before the required ones are given up on
+	wildernessStampGap = 1   // tiles between the stamps scattered on the wilderness
+)
+
+// placement is the area of the map a stamp is placed on
+type placement struct {
+	stamp int // the index of the stamp in the required stamps followed by the fill stamps
+	area  d2geom.Rectangle
+}
+
+// generateWilderness generates an outdoor level of the size of its levels.txt row: the ground of
+// its level type scattered with the presets of lvlprest.txt which belong to it and, in act 1, the
+// stones, cottages and camps of the wilderness inside a border of trees. Monsters spawn all over
+// the level.
+func (g *MapGenerator) generateWilderness(details *d2records.LevelDetailRecord, rng *rand.Rand) error {
+	width, height := details.SizeXNormal, details.SizeYNormal
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("%w: %s (%d) has no size", ErrUnsupportedLevel, details.Name, details.ID)
+	}
+
+	regionType := d2enum.RegionIdType(details.LevelType)
+	area := d2geom.Rectangle{Width: width, Height: height}
+
+	g.engine.ResetMap(regionType, width, height)
+	g.fillGround(area, regionType)
+
+	var fill []*d2mapstamp.Stamp
+
+	if regionType == d2enum.RegionAct1Wilderness {
+		area = g.placeTreeBorder(area, rng)
+		fill = g.act1WildernessFill()
+	}
+
+	presets := g.levelPresets(details.ID)
+	stamps := make([]*d2mapstamp.Stamp, 0, len(presets)+len(fill))
+
+	for idx := range presets {
+		stamps = append(stamps, g.loadStamp(rng, regionType, presets[idx].DefinitionID))
+	}
+
+	required := stampSizes(stamps)
+	stamps = append(stamps, fill...)
+	substitutions := g.newSubstitutions(details, rng)
+
+	placements, err := scatterStamps(rng, area, required, stampSizes(fill), area.Width*area.Height/wildernessFillArea)
+	if err != nil {
+		return fmt.Errorf("%s (%d): %w", details.Name, details.ID, err)
+	}
+
+	for _, placed := range placements {
+		stamp := stamps[placed.stamp]
+
+		g.engine.PlaceStamp(stamp, placed.area.Left, placed.area.Top)
+		substitutions.apply(stamp, placed.area.Left, placed.area.Top)
+	}
+
+	g.engine.AddSpawnZone(area, details.ID)
+
+	return nil
+}
+
+// placeTreeBorder surrounds the given area with the tree borders of the act 1 wilderness and
+// returns the area inside the borders
+func (g *MapGenerator) placeTreeBorder(area d2geom.Rectangle, rng *rand.Rand) d2geom.Rectangle {
+	sides := make(map[int][]*d2mapstamp.Stamp)
+
+	for _, side := range []int{
+		d2wilderness.TreeBorderNorth, d2wilderness.TreeBorderSouth, d2wilderness.TreeBorderWest, d2wilderness.TreeBorderEast,
+	} {
+		sides[side] = []*d2mapstamp.Stamp{
+			g.loadPreset(side, presetA), g.loadPreset(side, presetB), g.loadPreset(side, presetC),
+		}
+	}
+
+	northWest := g.loadPreset(d2wilderness.TreeBorderNorthWest, presetA)
+	corner := northWest.Size()
+
+	if corner.Width <= 0 || corner.Height <= 0 || area.Width < 2*corner.Width || area.Height < 2*corner.Height {
+		return area
+	}
+
+	left, top := area.Left, area.Top
+	right, bottom := area.Right()-corner.Width, area.Bottom()-corner.Height
+
+	for x := left + corner.Width; x < right; x += corner.Width {
+		g.engine.PlaceStamp(sides[d2wilderness.TreeBorderNorth][rng.Intn(presetC+1)], x, top)
+		g.engine.PlaceStamp(sides[d2wilderness.TreeBorderSouth][rng.Intn(presetC+1)], x, bottom)
+	}
+
+	for y := top + corner.Height; y < bottom; y += corner.Height {
+		g.engine.PlaceStamp(sides[d2wilderness.TreeBorderWest][rng.Intn(presetC+1)], left, y)
+		g.engine.PlaceStamp(sides[d2wilderness.TreeBorderEast][rng.Intn(presetC+1)], right, y)
+	}
+
+	g.engine.PlaceStamp(northWest, left, top)
+	g.engine.PlaceStamp(g.loadPreset(d2wilderness.TreeBorderNorthEast, presetA), right, top)
+	g.engine.PlaceStamp(g.loadPreset(d2wilderness.TreeBorderSouthWest, presetA), left, bottom)
+	g.engine.PlaceStamp(g.loadPreset(d2wilderness.TreeBorderSouthEast, presetA), right, bottom)
+
+	return d2geom.Rectangle{
+		Left:   left + corner.Width,
+		Top:    top + corner.Height,
+		Width:  area.Width - 2*corner.Width,
+		Height: area.Height - 2*corner.Height,
+	}
+}
+
+// scatterStamps picks places in the given area for every required stamp, then for the given
+// number of fill stamps picked at random, so that no two stamps touch. The stamps are given by
+// their sizes, the empty stamps and the fill stamps which do not fit are left out. The stamps are
+// scattered again if a required stamp does not fit, the last time with the required stamps packed
+// from the top left of the area, and an error is returned if they still do not fit.
+func scatterStamps(rng *rand.Rand, area d2geom.Rectangle, required, fill []d2geom.Size,
+	fillCount int) ([]placement, error) {
+	for _, size := range required {
+		if size.Width > area.Width || size.Height > area.Height {
+			return nil, fmt.Errorf("required stamp of %dx%d is larger than %dx%d", size.Width, size.Height,
+				area.Width, area.Height)
+		}
+	}
+
+	for attempt := 1; attempt <= wildernessAttempts; attempt++ {
+		if placements, placed := scatterOnce(rng, area, required, fill, fillCount, attempt == wildernessAttempts); placed {
+			return placements, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%d required stamps do not fit in %dx%d after %d attempts", len(required),
+		area.Width, area.Height, wildernessAttempts)
+}
+
+// scatterOnce places the stamps like scatterStamps, it returns false if a required stamp was left
+// out. The required stamps are tried at every position of the area once the random ones are taken,
+// or right away if they are packed.
+func scatterOnce(rng *rand.Rand, area d2geom.Rectangle, required, fill []d2geom.Size, fillCount int,
+	packed bool) (placements []placement, placed bool) {
+	placements = make([]placement, 0, len(required)+fillCount)
+
+	place := func(stamp int, size d2geom.Size, tries int, everywhere bool) bool {
+		if size.Width <= 0 || size.Height <= 0 {
+			return true
+		}
+
+		if size.Width > area.Width || size.Height > area.Height {
+			return false
+		}
+
+		rect := d2geom.Rectangle{Width: size.Width, Height: size.Height}
+
+		for try := 0; try < tries; try++ {
+			rect.Left = area.Left + rng.Intn(area.Width-size.Width+1)
+			rect.Top = area.Top + rng.Intn(area.Height-size.Height+1)
+
+			if !overlapsAny(rect, placements) {
+				placements = append(placements, placement{stamp: stamp, area: rect})
+				return true
+			}
+		}
+
+		for rect.Top = area.Top; everywhere && rect.Bottom() <= area.Bottom(); rect.Top++ {
+			for rect.Left = area.Left; rect.Right() <= area.Right(); rect.Left++ {
+				if !overlapsAny(rect, placements) {
+					placements = append(placements, placement{stamp: stamp, area: rect})
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	tries := wildernessTries
+	if packed {
+		tries = 0
+	}
+
+	for idx, size := range required {
+		if !place(idx, size, tries, true) {
+			return nil, false
+		}
+	}
+
+	for count := 0; count < fillCount && len(fill) > 0; count++ {
+		pick := rng.Intn(len(fill))
+		place(len(required)+pick, fill[pick], wildernessTries, false)
+	}
+
+	return placements, true
+}
+
+// overlapsAny returns true if the given area is closer than wildernessStampGap to one of the
+// placed stamps
+func overlapsAny(rect d2geom.Rectangle, placements []placement) bool {
+	for idx := range placements {
+		other := &placements[idx].area
+
+		if rect.Left < other.Right()+wildernessStampGap && other.Left < rect.Right()+wildernessStampGap &&
+			rect.Top < other.Bottom()+wildernessStampGap && other.Top < rect.Bottom()+wildernessStampGap {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stampSizes(stamps []*d2mapstamp.Stamp) []d2geom.Size {
+	sizes := make([]d2geom.Size, len(stamps))
+
+	for idx, stamp := range stamps {
+		sizes[idx] = stamp.Size()
+	}
+
+	return sizes
+}
@@ -0,0 +1,154 @@
+package d2mapgen
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func TestScatteredStampsDoNotTouch(t *testing.T) {
+	area := d2geom.Rectangle{Left: 9, Top: 9, Width: 60, Height: 40}
+	required := []d2geom.Size{{Width: 12, Height: 10}, {Width: 8, Height: 8}}
+	fill := []d2geom.Size{{Width: 5, Height: 5}, {Width: 3, Height: 7}, {Width: 61, Height: 1}}
+
+	placements, err := scatterStamps(rand.New(rand.NewSource(1)), area, required, fill, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(placements) < len(required) || placements[0].stamp != 0 || placements[1].stamp != 1 {
+		t.Fatalf("the required stamps are not placed first: %+v", placements)
+	}
+
+	for idx, placed := range placements {
+		rect := placed.area
+		if rect.Left < area.Left || rect.Top < area.Top || rect.Right() > area.Right() || rect.Bottom() > area.Bottom() {
+			t.Errorf("stamp %d is out of the area at %+v", placed.stamp, rect)
+		}
+
+		if placed.stamp == len(required)+2 {
+			t.Error("a stamp wider than the area was placed")
+		}
+
+		if overlapsAny(rect, placements[:idx]) {
+			t.Errorf("stamp %d at %+v touches another stamp", placed.stamp, rect)
+		}
+	}
+
+	if again, _ := scatterStamps(rand.New(rand.NewSource(1)), area, required, fill, 30); !reflect.DeepEqual(again, placements) {
+		t.Error("the same seed scattered the stamps differently")
+	}
+}
+
+func TestEveryRequiredStampIsScattered(t *testing.T) {
+	area := d2geom.Rectangle{Width: 30, Height: 20}
+	required := []d2geom.Size{{Width: 10, Height: 8}, {Width: 10, Height: 8}, {Width: 10, Height: 8}, {Width: 9, Height: 9}}
+	fill := []d2geom.Size{{Width: 5, Height: 5}}
+
+	for seed := int64(0); seed < 100; seed++ {
+		placements, err := scatterStamps(rand.New(rand.NewSource(seed)), area, required, fill, 10)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		for idx := range required {
+			if len(placements) <= idx || placements[idx].stamp != idx {
+				t.Fatalf("seed %d: required stamp %d is not placed: %+v", seed, idx, placements)
+			}
+		}
+	}
+}
+
+func TestRequiredStampsWhichDoNotFitAreAnError(t *testing.T) {
+	area := d2geom.Rectangle{Width: 10, Height: 10}
+
+	table := []struct {
+		name     string
+		required []d2geom.Size
+	}{
+		{"stamp larger than the area", []d2geom.Size{{Width: 11, Height: 2}}},
+		{"stamps which do not fit together", []d2geom.Size{{Width: 8, Height: 8}, {Width: 8, Height: 8}}},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			if placements, err := scatterStamps(rand.New(rand.NewSource(1)), area, row.required, nil, 0); err == nil {
+				t.Errorf("stamps placed at %+v", placements)
+			}
+		})
+	}
+}
+
+func TestWildernessLevelsAreGenerated(t *testing.T) {
+	const levelID = 3
+
+	asset := &d2asset.AssetManager{Records: &d2records.RecordManager{}}
+	asset.Records.Level.Types = d2records.LevelTypes{{Name: "None"}, {Name: "Test"}}
+	asset.Records.Level.Details = d2records.LevelDetails{
+		levelID: {ID: levelID, Name: "Cold Plains", LevelType: 1, LevelGenerationType: d2enum.LevelTypeWilderness,
+			SizeXNormal: 40, SizeYNormal: 30},
+	}
+
+	engine := d2mapengine.CreateMapEngine(d2util.LogLevelNone, asset)
+	engine.SetSeed(1)
+
+	generator, err := NewMapGenerator(asset, d2util.LogLevelNone, engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	levelIDs, err := generator.GenerateLevel(levelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(levelIDs, []int{levelID}) {
+		t.Errorf("generated levels %v, want %d", levelIDs, levelID)
+	}
+
+	if size := engine.Size(); size.Width != 40 || size.Height != 30 {
+		t.Errorf("map of %dx%d tiles, want the size of the level", size.Width, size.Height)
+	}
+
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			if len(engine.Tile(x, y).Components.Floors) == 0 {
+				t.Fatalf("tile %d,%d has no ground", x, y)
+			}
+		}
+	}
+
+	zones := engine.SpawnZones()
+	if len(zones) != 1 || zones[0].LevelID != levelID || zones[0].Area != (d2geom.Rectangle{Width: 40, Height: 30}) {
+		t.Errorf("spawn zones %+v, want the whole level", zones)
+	}
+}
+
+func TestSubstitutionsRollWithTheRandomSourceOfTheLevel(t *testing.T) {
+	rolls := func(seed int64) []bool {
+		s := &substitutions{rng: rand.New(rand.NewSource(seed))}
+		result := make([]bool, 20)
+
+		for idx := range result {
+			result[idx] = s.roll(50, 1)
+		}
+
+		return result
+	}
+
+	if !reflect.DeepEqual(rolls(7), rolls(7)) {
+		t.Error("the same seed rolled different substitutions")
+	}
+
+	s := &substitutions{rng: rand.New(rand.NewSource(7))}
+	if !s.roll(substitutionChanceScale, 1) || s.roll(0, 3) {
+		t.Error("certain rolls are not certain")
+	}
+}
@@ -59,6 +59,11 @@ func (mr *Stamp) Tile(x, y int) *d2ds1.TileRecord {
 	return &mr.ds1.Tiles[y][x]
 }
 
+// SubstitutionGroups returns the areas of the stamp the tiles of lvlsub.txt can be substituted in.
+func (mr *Stamp) SubstitutionGroups() []d2ds1.SubstitutionGroup {
+	return mr.ds1.SubstitutionGroups
+}
+
 // TileData returns the tile data for the tile with given style, sequence and type.
 func (mr *Stamp) TileData(style, sequence int32, tileType d2enum.TileType) *d2dt1.Tile {
 	for idx := range mr.tiles {
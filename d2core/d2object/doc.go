@@ -0,0 +1,5 @@
+// Package d2object decides what the objects.txt objects do when a player operates them: chests
+// and barrels drop their treasure, doors open and close, shrines and wells help the player and
+// reset after a while, waypoints are activated and portals open. The effects of the shrines are
+// looked up from shrines.txt.
+package d2object
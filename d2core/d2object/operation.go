@@ -0,0 +1,104 @@
+package d2object
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+// Operation is what happens when a player operates an object
+type Operation int
+
+// the operations of the objects
+const (
+	OperationNone      Operation = iota // the object can not be operated
+	OperationContainer                  // a chest, casket or urn opens once and drops its treasure
+	OperationBarrel                     // a barrel breaks once and drops its treasure
+	OperationDoor                       // a door opens and closes, it blocks walking while it is closed
+	OperationShrine                     // a shrine puts its effect on the player and resets after a while
+	OperationWell                       // a well refills the life and mana of the player and resets after a while
+	OperationWaypoint                   // a waypoint is activated for the player
+	OperationPortal                     // a portal opens
+	OperationSwitch                     // a lever or another switch plays its operating animation once
+)
+
+// the SubClass flags of objects.txt
+const (
+	subClassShrine    = 1
+	subClassPortal    = 4
+	subClassContainer = 8
+	subClassWell      = 32
+	subClassWaypoint  = 64
+)
+
+// subClassOperations are the operations of the SubClass flags of objects.txt
+var subClassOperations = [...]struct { //nolint:gochecknoglobals // lookup table
+	flag      int
+	operation Operation
+}{
+	{flag: subClassShrine, operation: OperationShrine},
+	{flag: subClassWell, operation: OperationWell},
+	{flag: subClassWaypoint, operation: OperationWaypoint},
+	{flag: subClassPortal, operation: OperationPortal},
+	{flag: subClassContainer, operation: OperationContainer},
+}
+
+// operateFunctions are the operations of the OperateFn column of objects.txt, for the objects
+// without a SubClass
+var operateFunctions = map[int]Operation{ //nolint:gochecknoglobals // lookup table
+	1:  OperationContainer, // casket, sarcophagus
+	2:  OperationShrine,
+	3:  OperationContainer, // urn, basket, jar
+	4:  OperationContainer, // chest
+	5:  OperationBarrel,
+	7:  OperationBarrel, // exploding barrel
+	8:  OperationDoor,
+	19: OperationContainer, // armor stand
+	20: OperationContainer, // weapon rack
+	22: OperationWell,
+	23: OperationWaypoint,
+}
+
+// OperationOf returns the operation of the objects of the given objects.txt row. The doors and
+// the SubClass of the row come first, then its OperateFn. The other rows with an OperateFn and an
+// operating animation are switches.
+func OperationOf(record *d2records.ObjectDetailRecord) Operation {
+	if record == nil {
+		return OperationNone
+	}
+
+	if record.IsDoor {
+		return OperationDoor
+	}
+
+	for _, sub := range subClassOperations {
+		if record.SubClass&sub.flag != 0 {
+			return sub.operation
+		}
+	}
+
+	if operation, found := operateFunctions[record.OperateFn]; found {
+		return operation
+	}
+
+	if record.OperateFn != 0 && record.HasAnimationMode[d2enum.ObjectAnimationModeOperating] {
+		return OperationSwitch
+	}
+
+	return OperationNone
+}
+
+// Resets returns true if the objects of the operation can be used again after a while
+func (o Operation) Resets() bool {
+	return o == OperationShrine || o == OperationWell
+}
+
+// OperatedMode returns the animation mode of an operated object of the given objects.txt row:
+// its operating animation, which stays on its last frame, or else its opened mode
+func OperatedMode(record *d2records.ObjectDetailRecord) d2enum.ObjectAnimationMode {
+	if record != nil && !record.HasAnimationMode[d2enum.ObjectAnimationModeOperating] &&
+		record.HasAnimationMode[d2enum.ObjectAnimationModeOpened] {
+		return d2enum.ObjectAnimationModeOpened
+	}
+
+	return d2enum.ObjectAnimationModeOperating
+}
@@ -0,0 +1,47 @@
+package d2object
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func TestOperationsAreLookedUpFromTheObjectRecords(t *testing.T) {
+	operating := [8]bool{d2enum.ObjectAnimationModeOperating: true}
+
+	tests := []struct {
+		name   string
+		record *d2records.ObjectDetailRecord
+		want   Operation
+	}{
+		{name: "door", record: &d2records.ObjectDetailRecord{IsDoor: true, OperateFn: 8}, want: OperationDoor},
+		{name: "shrine", record: &d2records.ObjectDetailRecord{SubClass: subClassShrine, OperateFn: 2}, want: OperationShrine},
+		{name: "well", record: &d2records.ObjectDetailRecord{SubClass: subClassWell}, want: OperationWell},
+		{name: "waypoint", record: &d2records.ObjectDetailRecord{SubClass: subClassWaypoint}, want: OperationWaypoint},
+		{name: "chest", record: &d2records.ObjectDetailRecord{SubClass: subClassContainer, OperateFn: 4}, want: OperationContainer},
+		{name: "urn", record: &d2records.ObjectDetailRecord{OperateFn: 3}, want: OperationContainer},
+		{name: "barrel", record: &d2records.ObjectDetailRecord{OperateFn: 5}, want: OperationBarrel},
+		{name: "lever", record: &d2records.ObjectDetailRecord{OperateFn: 99, HasAnimationMode: operating}, want: OperationSwitch},
+		{name: "torch", record: &d2records.ObjectDetailRecord{}, want: OperationNone},
+		{name: "no record", want: OperationNone},
+	}
+
+	for _, test := range tests {
+		if got := OperationOf(test.record); got != test.want {
+			t.Errorf("%s: operation %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestOperatedObjectsKeepTheirOperatingAnimation(t *testing.T) {
+	both := &d2records.ObjectDetailRecord{HasAnimationMode: [8]bool{true, true, true}}
+	if mode := OperatedMode(both); mode != d2enum.ObjectAnimationModeOperating {
+		t.Errorf("an object with an operating animation is operated in mode %s", mode)
+	}
+
+	opened := &d2records.ObjectDetailRecord{HasAnimationMode: [8]bool{true, false, true}}
+	if mode := OperatedMode(opened); mode != d2enum.ObjectAnimationModeOpened {
+		t.Errorf("an object without an operating animation is operated in mode %s", mode)
+	}
+}
@@ -0,0 +1,145 @@
+package d2object
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+const (
+	framesPerSecond  = 25 // the durations of shrines.txt are in frames
+	secondsPerMinute = 60
+	fullRefill       = 100 // percent of the maximum life or mana
+)
+
+// the Code column of shrines.txt
+const (
+	shrineRefilling       = 1
+	shrineHealth          = 2
+	shrineMana            = 3
+	shrineArmor           = 6
+	shrineCombat          = 7
+	shrineResistFire      = 8
+	shrineResistCold      = 9
+	shrineResistLightning = 10
+	shrineResistPoison    = 11
+	shrineSkill           = 12
+	shrineManaRecharge    = 13
+	shrineStamina         = 14
+	shrineExperience      = 15
+)
+
+// ShrineEffect is what a shrine does to the player who uses it: it refills the life or the mana
+// of the player, or it puts a state with some stats on the player
+type ShrineEffect struct {
+	Life     int            // percent of the maximum life the player gets back
+	Mana     int            // percent of the maximum mana the player gets back
+	State    string         // the states.txt state put on the player, empty if there is none
+	Duration float64        // seconds the state lasts
+	Stats    map[string]int // the stats the state adds to the stats of the player
+}
+
+// boosters are the states and the stats of the shrines which put a state on the player, the stats
+// get the Arg0 and Arg1 columns of the shrine
+var boosters = map[int]struct { //nolint:gochecknoglobals // lookup table
+	state      string
+	arg0, arg1 string
+}{
+	shrineArmor:           {state: "shrine_armor", arg0: "item_armor_percent"},
+	shrineCombat:          {state: "shrine_combat", arg0: "item_tohit_percent", arg1: "item_maxdamage_percent"},
+	shrineResistFire:      {state: "shrine_resist_fire", arg0: "fireresist"},
+	shrineResistCold:      {state: "shrine_resist_cold", arg0: "coldresist"},
+	shrineResistLightning: {state: "shrine_resist_lightning", arg0: "lightresist"},
+	shrineResistPoison:    {state: "shrine_resist_poison", arg0: "poisonresist"},
+	shrineSkill:           {state: "shrine_skill", arg0: "item_allskills"},
+	shrineManaRecharge:    {state: "shrine_mana_regen", arg0: "manarecoverybonus"},
+	shrineStamina:         {state: "shrine_stamina", arg0: "staminarecoverybonus"},
+	shrineExperience:      {state: "shrine_experience", arg0: "item_addexperience"},
+}
+
+// NewShrineEffect returns the effect of the given shrines.txt row, false if the shrine has an
+// effect which is not supported
+func NewShrineEffect(record *d2records.ShrineRecord) (ShrineEffect, bool) {
+	switch record.Code {
+	case shrineRefilling:
+		return ShrineEffect{Life: fullRefill, Mana: fullRefill}, true
+	case shrineHealth:
+		return ShrineEffect{Life: fullRefill}, true
+	case shrineMana:
+		return ShrineEffect{Mana: fullRefill}, true
+	}
+
+	booster, found := boosters[record.Code]
+	if !found {
+		return ShrineEffect{}, false
+	}
+
+	effect := ShrineEffect{
+		State:    booster.state,
+		Duration: float64(record.DurationFrames) / framesPerSecond,
+		Stats:    map[string]int{booster.arg0: record.Arg0},
+	}
+
+	if booster.arg1 != "" {
+		effect.Stats[booster.arg1] = record.Arg1
+	}
+
+	return effect, true
+}
+
+// ResetTime returns the seconds until a used shrine of the given shrines.txt row can be used
+// again, zero if it never resets
+func ResetTime(record *d2records.ShrineRecord) float64 {
+	return float64(record.ResetTimeMinutes * secondsPerMinute)
+}
+
+// WellShrine returns the shrines.txt row the wells work like: they refill the life and the mana
+// of the player like a refilling shrine and reset as often
+func WellShrine(shrines d2records.Shrines) *d2records.ShrineRecord {
+	for _, record := range sortedShrines(shrines) {
+		if record.Code == shrineRefilling {
+			return record
+		}
+	}
+
+	return nil
+}
+
+// PickShrine picks one of the shrines with a supported effect which may appear on a level of the
+// given monster level, nil if there is none
+func PickShrine(rng *rand.Rand, shrines d2records.Shrines, areaLevel int) *d2records.ShrineRecord {
+	candidates := make([]*d2records.ShrineRecord, 0)
+
+	for _, record := range sortedShrines(shrines) {
+		if _, supported := NewShrineEffect(record); supported && record.LevelMin <= areaLevel {
+			candidates = append(candidates, record)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return candidates[rng.Intn(len(candidates))]
+}
+
+// sortedShrines returns the shrines in the order of their codes, so a shrine is picked the same
+// way from the same seed
+func sortedShrines(shrines d2records.Shrines) []*d2records.ShrineRecord {
+	records := make([]*d2records.ShrineRecord, 0, len(shrines))
+
+	for _, record := range shrines {
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Code != records[j].Code {
+			return records[i].Code < records[j].Code
+		}
+
+		return records[i].ShrineName < records[j].ShrineName
+	})
+
+	return records
+}
@@ -0,0 +1,55 @@
+package d2object
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+func TestShrineEffects(t *testing.T) {
+	refill, ok := NewShrineEffect(&d2records.ShrineRecord{Code: shrineRefilling})
+	if !ok || refill.Life != fullRefill || refill.Mana != fullRefill || refill.State != "" {
+		t.Errorf("refilling shrine: %+v %v", refill, ok)
+	}
+
+	combat, ok := NewShrineEffect(&d2records.ShrineRecord{Code: shrineCombat, Arg0: 200, Arg1: 150, DurationFrames: 2400})
+	if !ok || combat.State != "shrine_combat" || combat.Duration != 96 {
+		t.Fatalf("combat shrine: %+v %v", combat, ok)
+	}
+
+	if combat.Stats["item_tohit_percent"] != 200 || combat.Stats["item_maxdamage_percent"] != 150 {
+		t.Errorf("combat shrine stats: %v", combat.Stats)
+	}
+
+	if _, ok := NewShrineEffect(&d2records.ShrineRecord{Code: 20}); ok {
+		t.Error("the monster shrine is supported")
+	}
+}
+
+func TestShrinesArePickedByLevel(t *testing.T) {
+	shrines := d2records.Shrines{
+		"None":        {ShrineName: "None", Code: 0},
+		"Refilling":   {ShrineName: "Refilling", Code: shrineRefilling, ResetTimeMinutes: 2},
+		"Experience":  {ShrineName: "Experience", Code: shrineExperience, LevelMin: 10},
+		"Monster":     {ShrineName: "Monster", Code: 20},
+		"Resist Fire": {ShrineName: "Resist Fire", Code: shrineResistFire},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+
+	for idx := 0; idx < 50; idx++ {
+		shrine := PickShrine(rng, shrines, 5)
+		if shrine == nil || (shrine.Code != shrineRefilling && shrine.Code != shrineResistFire) {
+			t.Fatalf("picked %+v on a level of monster level 5", shrine)
+		}
+	}
+
+	if well := WellShrine(shrines); well == nil || ResetTime(well) != 120 {
+		t.Errorf("the wells work like %+v", well)
+	}
+
+	if PickShrine(rng, d2records.Shrines{}, 5) != nil {
+		t.Error("a shrine was picked without shrines")
+	}
+}
@@ -0,0 +1,389 @@
+package d2records
+
+import (
+	"strconv"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2txt"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2resource"
+)
+
+const (
+	tokenNameSize  = 32
+	tokenTokenSize = 20
+)
+
+// sizes of the records of the compiled excel tables
+const (
+	itemRecordSize         = 0x1a8
+	itemStatCostRecordSize = 0x144
+	monStatsRecordSize     = 0x1a8
+	skillRecordSize        = 0x23c
+	levelRecordSize        = 0x21c
+)
+
+const (
+	itemFileSize       = 32
+	levelNameSize      = 40
+	numItemVendors     = 17
+	numMonsterSkills   = 8
+	numObjectGroups    = 8
+	numSkillParams     = 8
+	numSkillLevelDams  = 5
+	numDifficulties    = 3
+	numMonsterElements = 3
+)
+
+// tokenSchema returns the layout of the compiled animation token tables, a name and a token
+func tokenSchema(nameColumn, tokenColumn string) *d2txt.BinSchema {
+	return &d2txt.BinSchema{
+		RecordSize: tokenNameSize + tokenTokenSize,
+		Columns: []d2txt.BinColumn{
+			{Name: nameColumn, Type: d2txt.BinString, Offset: 0, Size: tokenNameSize},
+			{Name: tokenColumn, Type: d2txt.BinString, Offset: tokenNameSize, Size: tokenTokenSize},
+		},
+	}
+}
+
+// binTypeSize returns the size of the integer and code column types
+// nolint:gomnd // type sizes
+func binTypeSize(columnType d2txt.BinColumnType) int {
+	switch columnType {
+	case d2txt.BinInt8, d2txt.BinUint8:
+		return 1
+	case d2txt.BinInt16, d2txt.BinUint16:
+		return 2
+	}
+
+	return 4
+}
+
+// binColumns returns the columns of values of the same type stored one after the other, as the
+// values of the three difficulties or of the numbered columns of a table
+func binColumns(columnType d2txt.BinColumnType, offset int, names ...string) []d2txt.BinColumn {
+	columns := make([]d2txt.BinColumn, len(names))
+
+	for idx, name := range names {
+		columns[idx] = d2txt.BinColumn{Name: name, Type: columnType, Offset: offset + idx*binTypeSize(columnType)}
+	}
+
+	return columns
+}
+
+// binFlags returns the columns of the bits of the 32 bit field at the given offset, in the order
+// of the bits, an empty name is a bit which has no column
+func binFlags(offset int, names ...string) []d2txt.BinColumn {
+	columns := make([]d2txt.BinColumn, 0, len(names))
+
+	for bit, name := range names {
+		if name != "" {
+			columns = append(columns, d2txt.BinColumn{Name: name, Type: d2txt.BinFlag, Offset: offset, Bit: uint(bit)})
+		}
+	}
+
+	return columns
+}
+
+// numbered returns the names of the numbered columns from first to last, like aip1..aip8
+func numbered(prefix string, first, last int, suffix string) []string {
+	names := make([]string, 0, last-first+1)
+
+	for idx := first; idx <= last; idx++ {
+		names = append(names, prefix+strconv.Itoa(idx)+suffix)
+	}
+
+	return names
+}
+
+// difficulties returns the names of the normal, nightmare and hell columns of a value
+func difficulties(name string) []string {
+	return []string{name, name + "(N)", name + "(H)"}
+}
+
+// itemSchema returns the layout of the compiled weapons, armor and misc tables
+// nolint:gomnd,funlen // offsets of the compiled records
+func itemSchema() *d2txt.BinSchema {
+	columns := []d2txt.BinColumn{
+		{Name: "flippyfile", Type: d2txt.BinString, Offset: 0x00, Size: itemFileSize},
+		{Name: "invfile", Type: d2txt.BinString, Offset: 0x20, Size: itemFileSize},
+		{Name: "uniqueinvfile", Type: d2txt.BinString, Offset: 0x40, Size: itemFileSize},
+		{Name: "setinvfile", Type: d2txt.BinString, Offset: 0x60, Size: itemFileSize},
+	}
+
+	columns = append(columns, binColumns(d2txt.BinCode, 0x80, "code", "normcode", "ubercode", "ultracode",
+		"alternategfx")...)
+	columns = append(columns, []d2txt.BinColumn{
+		{Name: "pSpell", Type: d2txt.BinUint32, Offset: 0x94},
+		{Name: "len", Type: d2txt.BinInt32, Offset: 0xb0},
+		{Name: "spelldesc", Type: d2txt.BinUint8, Offset: 0xb4},
+	}...)
+	columns = append(columns, binColumns(d2txt.BinCode, 0xbc, "BetterGem", "wclass", "2handedwclass", "TMogType")...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0xcc, "minac", "maxac", "gamble cost", "speed",
+		"bitfield1", "cost", "minstack", "maxstack", "spawnstack", "gemoffset")...)
+	columns = append(columns, binColumns(d2txt.BinUint16, 0xf6, "version", "auto prefix", "missiletype")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0xfc, "rarity", "level", "mindam", "maxdam",
+		"minmisdam", "maxmisdam", "2handmindam", "2handmaxdam", "rangeadder")...)
+	columns = append(columns, binColumns(d2txt.BinInt16, 0x106, "StrBonus", "DexBonus")...)
+	columns = append(columns, binColumns(d2txt.BinUint16, 0x10a, "reqstr", "reqdex")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x10e, "absorbs", "invwidth", "invheight", "block",
+		"durability", "nodurability")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x115, "component", "rArm", "lArm", "Torso", "Legs",
+		"rSPad", "lSPad", "2handed", "useable")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x128, "dropsfxframe", "unique", "quest",
+		"questdiffcheck", "transparent", "transtbl")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x12f, "lightradius", "belt", "autobelt", "stackable",
+		"spawnable", "spellicon", "durwarning", "qntwarning", "hasinv", "gemsockets", "Transmogrify", "TMogMin",
+		"TMogMax")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x13d, "1or2handed", "gemapplytype", "levelreq",
+		"magic lvl", "Transform", "InvTrans", "compactsave", "SkipName", "Nameable")...)
+
+	for idx, suffix := range []string{"Min", "Max", "MagicMin", "MagicMax", "MagicLvl"} {
+		vendors := itemVendors()
+		for vendor := range vendors {
+			vendors[vendor] += suffix
+		}
+
+		columns = append(columns, binColumns(d2txt.BinUint8, 0x146+idx*numItemVendors, vendors...)...)
+	}
+
+	columns = append(columns, binColumns(d2txt.BinCode, 0x19c, "NightmareUpgrade", "HellUpgrade")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x1a4, "PermStoreItem", "multibuy")...)
+
+	return &d2txt.BinSchema{RecordSize: itemRecordSize, Columns: columns}
+}
+
+// itemStatCostSchema returns the layout of the compiled itemstatcost table
+// nolint:gomnd // offsets of the compiled records
+func itemStatCostSchema() *d2txt.BinSchema {
+	columns := []d2txt.BinColumn{{Name: "ID", Type: d2txt.BinUint32, Offset: 0x00}}
+
+	columns = append(columns, binFlags(0x04, "Send Other", "Signed", "damagerelated", "itemspecific", "direct",
+		"", "", "", "", "UpdateAnimRate", "", "fCallback", "", "CSvSigned")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x08, "Send Bits", "Send Param Bits", "CSvBits")...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x10, "Multiply", "Add")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x18, "ValShift", "Save Bits")...)
+	columns = append(columns, []d2txt.BinColumn{
+		{Name: "Save Add", Type: d2txt.BinInt32, Offset: 0x1c},
+		{Name: "Save Param Bits", Type: d2txt.BinUint32, Offset: 0x24},
+		{Name: "MinAccr", Type: d2txt.BinUint32, Offset: 0x2c},
+		{Name: "Encode", Type: d2txt.BinUint8, Offset: 0x30},
+		{Name: "descpriority", Type: d2txt.BinUint16, Offset: 0x34},
+		{Name: "descfunc", Type: d2txt.BinUint8, Offset: 0x36},
+		{Name: "descval", Type: d2txt.BinUint8, Offset: 0x37},
+		{Name: "dgrp", Type: d2txt.BinUint16, Offset: 0x3e},
+		{Name: "dgrpfunc", Type: d2txt.BinUint8, Offset: 0x40},
+		{Name: "dgrpval", Type: d2txt.BinUint8, Offset: 0x41},
+	}...)
+	columns = append(columns, binColumns(d2txt.BinUint16, 0x4c, "itemeventfunc1", "itemeventfunc2")...)
+	columns = append(columns, []d2txt.BinColumn{
+		{Name: "keepzero", Type: d2txt.BinUint8, Offset: 0x50},
+		{Name: "op", Type: d2txt.BinUint8, Offset: 0x54},
+		{Name: "op param", Type: d2txt.BinUint8, Offset: 0x55},
+		{Name: "stuff", Type: d2txt.BinUint32, Offset: 0x140},
+	}...)
+
+	return &d2txt.BinSchema{RecordSize: itemStatCostRecordSize, Columns: columns}
+}
+
+// monStatsSchema returns the layout of the compiled monstats table
+// nolint:gomnd,funlen // offsets of the compiled records
+func monStatsSchema() *d2txt.BinSchema {
+	columns := []d2txt.BinColumn{{Name: "hcIdx", Type: d2txt.BinInt16, Offset: 0x00}}
+
+	columns = append(columns, binFlags(0x0c, "isSpawn", "isMelee", "noRatio", "opendoors", "SetBoss", "BossXfer",
+		"boss", "primeevil", "npc", "interact", "inTown", "lUndead", "hUndead", "demon", "flying", "killable",
+		"switchai", "nomultishot", "neverCount", "petIgnore", "deathDmg", "genericSpawn", "", "placespawn",
+		"inventory", "enabled", "NoShldBlock", "noAura", "rangedtype")...)
+	columns = append(columns, d2txt.BinColumn{Name: "Code", Type: d2txt.BinCode, Offset: 0x10})
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x22, "spawnx", "spawny")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x2c, "PartyMin", "PartyMax", "Rarity", "MinGrp",
+		"MaxGrp", "sparsePopulate")...)
+	columns = append(columns, binColumns(d2txt.BinInt16, 0x32, "Velocity", "Run")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x4c, "Align", "TransLvl", "threat")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x4f, difficulties("aidel")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x52, difficulties("aidist")...)...)
+
+	for idx, difficulty := range []string{"", "(N)", "(H)"} {
+		columns = append(columns, binColumns(d2txt.BinInt16, 0x56+idx*16, numbered("aip", 1, 8, difficulty)...)...)
+	}
+
+	columns = append(columns, binColumns(d2txt.BinUint8, 0xa0, difficulties("Drain")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0xa3, difficulties("ToBlock")...)...)
+	columns = append(columns, d2txt.BinColumn{Name: "Crit", Type: d2txt.BinUint8, Offset: 0xa6})
+
+	values := [][]string{
+		difficulties("Level"),
+		{"minHP", "MinHP(N)", "MinHP(H)"},
+		{"maxHP", "MaxHP(N)", "MaxHP(H)"},
+		difficulties("AC"),
+		difficulties("A1TH"),
+		difficulties("A2TH"),
+		difficulties("S1TH"),
+		difficulties("Exp"),
+		difficulties("A1MinD"),
+		difficulties("A1MaxD"),
+		difficulties("A2MinD"),
+		difficulties("A2MaxD"),
+		difficulties("S1MinD"),
+		difficulties("S1MaxD"),
+	}
+
+	for idx := range values {
+		columns = append(columns, binColumns(d2txt.BinUint16, 0xaa+idx*6, values[idx]...)...)
+	}
+
+	for element := 1; element <= numMonsterElements; element++ {
+		name := "El" + strconv.Itoa(element)
+		offset := (element - 1) * 6
+
+		columns = append(columns, binColumns(d2txt.BinUint8, 0x104+(element-1)*3, difficulties(name+"Pct")...)...)
+		columns = append(columns, binColumns(d2txt.BinUint16, 0x10e+offset, difficulties(name+"MinD")...)...)
+		columns = append(columns, binColumns(d2txt.BinUint16, 0x120+offset, difficulties(name+"MaxD")...)...)
+		columns = append(columns, binColumns(d2txt.BinUint16, 0x132+offset, difficulties(name+"Dur")...)...)
+	}
+
+	for idx, resist := range []string{"ResDm", "ResMa", "ResFi", "ResLi", "ResCo", "ResPo"} {
+		columns = append(columns, binColumns(d2txt.BinInt16, 0x144+idx*6, difficulties(resist)...)...)
+	}
+
+	columns = append(columns, binColumns(d2txt.BinInt8, 0x168, difficulties("coldeffect")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x198, numbered("Sk", 1, numMonsterSkills, "lvl")...)...)
+	columns = append(columns, d2txt.BinColumn{Name: "DamageRegen", Type: d2txt.BinInt32, Offset: 0x1a0})
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x1a4, "SplEndDeath", "SplGetModeChart",
+		"SplEndGeneric", "SplClientEnd")...)
+
+	return &d2txt.BinSchema{RecordSize: monStatsRecordSize, Columns: columns}
+}
+
+// skillSchema returns the layout of the compiled skills table
+// nolint:gomnd,funlen // offsets of the compiled records
+func skillSchema() *d2txt.BinSchema {
+	columns := []d2txt.BinColumn{{Name: "Id", Type: d2txt.BinInt16, Offset: 0x00}}
+
+	columns = append(columns, binFlags(0x04, "decquant", "lob", "progressive", "finishing", "passive", "aura",
+		"periodic", "prgstack", "InTown", "Kick", "InGame", "repeat", "stsuccessonly", "stsounddelay", "weaponsnd",
+		"immediate", "noammo", "enhanceable", "durability", "UseAttackRate", "TargetableOnly", "SearchEnemyXY",
+		"SearchEnemyNear", "SearchOpenXY", "TargetCorpse", "TargetPet", "TargetAlly", "TargetItem", "AttackNoMana",
+		"ItemCheckStart", "ItemCltCheckStart", "general")...)
+	columns = append(columns, []d2txt.BinColumn{
+		{Name: "seqnum", Type: d2txt.BinUint8, Offset: 0x13},
+		{Name: "SelectProc", Type: d2txt.BinUint8, Offset: 0x15},
+		{Name: "seqinput", Type: d2txt.BinUint8, Offset: 0x16},
+	}...)
+	columns = append(columns, binColumns(d2txt.BinInt16, 0x2c, "srvstfunc", "srvdofunc", "srvprgfunc1",
+		"srvprgfunc2", "srvprgfunc3")...)
+	columns = append(columns, []d2txt.BinColumn{
+		{Name: "prgdam", Type: d2txt.BinUint8, Offset: 0x44},
+		{Name: "aurafilter", Type: d2txt.BinUint32, Offset: 0x50},
+	}...)
+	columns = append(columns, binColumns(d2txt.BinInt16, 0x8a, numbered("auraeventfunc", 1, 3, "")...)...)
+	columns = append(columns, d2txt.BinColumn{Name: "sumumod", Type: d2txt.BinInt16, Offset: 0xe4})
+	columns = append(columns, binColumns(d2txt.BinInt16, 0xf2, "cltstfunc", "cltdofunc", "cltprgfunc1",
+		"cltprgfunc2", "cltprgfunc3")...)
+	columns = append(columns, []d2txt.BinColumn{
+		{Name: "ItemTarget", Type: d2txt.BinUint8, Offset: 0x120},
+		{Name: "maxlvl", Type: d2txt.BinUint16, Offset: 0x12c},
+		{Name: "ResultFlags", Type: d2txt.BinUint16, Offset: 0x12e},
+		{Name: "HitFlags", Type: d2txt.BinUint32, Offset: 0x130},
+		{Name: "HitClass", Type: d2txt.BinUint32, Offset: 0x134},
+	}...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x148, numbered("Param", 1, numSkillParams, "")...)...)
+	columns = append(columns, d2txt.BinColumn{Name: "weapsel", Type: d2txt.BinUint8, Offset: 0x168})
+	columns = append(columns, binColumns(d2txt.BinInt16, 0x16a, "ItemEffect", "ItemCltEffect")...)
+	columns = append(columns, binColumns(d2txt.BinInt16, 0x174, "reqlevel", "reqstr", "reqdex", "reqint",
+		"reqvit")...)
+	columns = append(columns, binColumns(d2txt.BinInt16, 0x184, "startmana", "minmana")...)
+	columns = append(columns, d2txt.BinColumn{Name: "manashift", Type: d2txt.BinUint8, Offset: 0x188})
+	columns = append(columns, binColumns(d2txt.BinInt16, 0x18a, "mana", "lvlmana")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x18e, "attackrank", "LineOfSight")...)
+	columns = append(columns, d2txt.BinColumn{Name: "delay", Type: d2txt.BinInt32, Offset: 0x190})
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x198, "ToHit", "LevToHit")...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x1a4, "HitShift", "SrcDam")...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x1a8, "MinDam", "MaxDam")...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x1b0, numbered("MinLevDam", 1, numSkillLevelDams, "")...)...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x1c4, numbered("MaxLevDam", 1, numSkillLevelDams, "")...)...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x1e0, "EMin", "EMax")...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x1e8, numbered("EMinLev", 1, numSkillLevelDams, "")...)...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x1fc, numbered("EMaxLev", 1, numSkillLevelDams, "")...)...)
+	columns = append(columns, binColumns(d2txt.BinInt32, 0x214, "ELen", "ELevLen1", "ELevLen2", "ELevLen3")...)
+	columns = append(columns, []d2txt.BinColumn{
+		{Name: "restrict", Type: d2txt.BinUint8, Offset: 0x228},
+		{Name: "aitype", Type: d2txt.BinUint8, Offset: 0x230},
+		{Name: "aibonus", Type: d2txt.BinInt16, Offset: 0x232},
+		{Name: "cost mult", Type: d2txt.BinInt32, Offset: 0x234},
+		{Name: "cost add", Type: d2txt.BinInt32, Offset: 0x238},
+	}...)
+
+	return &d2txt.BinSchema{RecordSize: skillRecordSize, Columns: columns}
+}
+
+// levelSchema returns the layout of the compiled levels table. The level definitions (sizes,
+// offsets, links, generation type...) are compiled to their own table, which has no schema.
+// nolint:gomnd // offsets of the compiled records
+func levelSchema() *d2txt.BinSchema {
+	columns := []d2txt.BinColumn{{Name: "Id", Type: d2txt.BinUint16, Offset: 0x00}}
+
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x02, "Pal", "Act", "Teleport", "Rain", "Mud", "NoPer",
+		"IsInside", "DrawEdges")...)
+	columns = append(columns, d2txt.BinColumn{Name: "WarpDist", Type: d2txt.BinUint32, Offset: 0x0c})
+	columns = append(columns, binColumns(d2txt.BinUint16, 0x10, numbered("MonLvl", 1, numDifficulties, "")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint16, 0x16, numbered("MonLvl", 1, numDifficulties, "Ex")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint32, 0x1c, difficulties("MonDen")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x28, difficulties("MonUMin")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x2b, difficulties("MonUMax")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0x2e, "MonWndr", "MonSpcWalk", "Quest", "rangedspawn",
+		"NumMon")...)
+	columns = append(columns, binColumns(d2txt.BinUint16, 0xd2, numbered("cpct", 1, 4, "")...)...)
+	columns = append(columns, d2txt.BinColumn{Name: "Waypoint", Type: d2txt.BinUint8, Offset: 0xe2})
+	columns = append(columns, binColumns(d2txt.BinUint8, 0xe3, numbered("ObjGrp", 0, numObjectGroups-1, "")...)...)
+	columns = append(columns, binColumns(d2txt.BinUint8, 0xeb, numbered("ObjPrb", 0, numObjectGroups-1, "")...)...)
+
+	for idx, name := range []string{"LevelName", "LevelWarp", "EntryFile"} {
+		columns = append(columns, d2txt.BinColumn{Name: name, Type: d2txt.BinString, Offset: 0xf3 + idx*levelNameSize,
+			Size: levelNameSize})
+	}
+
+	columns = append(columns, binColumns(d2txt.BinUint32, 0x210, "FloorFilter", "BlankScreen", "SoundEnv")...)
+
+	return &d2txt.BinSchema{RecordSize: levelRecordSize, Columns: columns}
+}
+
+// initBinSchemas adds the schemas of the compiled tables the records can be loaded from: the
+// animation token tables and the excel tables needed to boot, in the layouts of the 1.13 game.
+// The compiled excel tables only hold values: the columns the game compiles to indices of other
+// tables or of the string tables (item types, stats, states, sounds, missiles, names...) have no
+// column in the schemas and are empty in the records loaded from a .bin table.
+func (r *RecordManager) initBinSchemas() {
+	schemas := []struct {
+		path   string
+		schema *d2txt.BinSchema
+	}{
+		{d2resource.ArmorType, tokenSchema("Name", "Token")},
+		{d2resource.WeaponClass, tokenSchema("Weapon Class", "Code")},
+		{d2resource.PlayerType, tokenSchema("Player Class", "Token")},
+		{d2resource.Composite, tokenSchema("Name", "Token")},
+		{d2resource.HitClass, tokenSchema("Hit Class", "Code")},
+		{d2resource.Weapons, itemSchema()},
+		{d2resource.Armor, itemSchema()},
+		{d2resource.Misc, itemSchema()},
+		{d2resource.ItemStatCost, itemStatCostSchema()},
+		{d2resource.MonStats, monStatsSchema()},
+		{d2resource.Skills, skillSchema()},
+		{d2resource.LevelDetails, levelSchema()},
+	}
+
+	for idx := range schemas {
+		r.AddBinSchema(schemas[idx].path, schemas[idx].schema)
+	}
+}
+
+// AddBinSchema associates a txt file path with the schema of its compiled .bin table
+func (r *RecordManager) AddBinSchema(path string, schema *d2txt.BinSchema) {
+	r.binSchemas[path] = schema
+}
+
+// BinSchema returns the schema of the compiled .bin table of the given txt file path, if any
+func (r *RecordManager) BinSchema(path string) (*d2txt.BinSchema, bool) {
+	schema, found := r.binSchemas[path]
+
+	return schema, found
+}
@@ -0,0 +1,100 @@
+package d2records
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+
+	testify "github.com/stretchr/testify/assert"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2txt"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2resource"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+)
+
+// Verify the tables needed to boot can be loaded from their compiled .bin tables.
+func TestBootTablesHaveBinSchemas(t *testing.T) {
+	assert := testify.New(t)
+
+	records, err := NewRecordManager(d2util.LogLevelNone)
+	assert.NoError(err)
+
+	tables := []string{
+		d2resource.ArmorType, d2resource.WeaponClass, d2resource.PlayerType, d2resource.Composite, d2resource.HitClass,
+		d2resource.Weapons, d2resource.Armor, d2resource.Misc, d2resource.ItemStatCost, d2resource.MonStats,
+		d2resource.Skills, d2resource.LevelDetails,
+	}
+
+	for _, path := range tables {
+		schema, found := records.BinSchema(path)
+		if assert.True(found, path) {
+			assert.NotEmpty(schema.Columns, path)
+		}
+	}
+}
+
+// Verify the records loaded from the compiled .bin tables are the ones loaded from the txt files,
+// the txt fixtures hold the columns the game compiles.
+func TestBinTablesLoadTheRecordsOfTheirTxtFiles(t *testing.T) {
+	assert := testify.New(t)
+
+	fromTxt, err := NewRecordManager(d2util.LogLevelNone)
+	assert.NoError(err)
+
+	fromBin, err := NewRecordManager(d2util.LogLevelNone)
+	assert.NoError(err)
+
+	assert.Len(fromTxt.binSchemas, 12)
+
+	for txtPath, schema := range fromTxt.binSchemas {
+		fixture := path.Join("testdata", strings.TrimSuffix(path.Base(txtPath), ".txt"))
+
+		txtData, err := ioutil.ReadFile(fixture + ".txt")
+		if !assert.NoError(err) {
+			continue
+		}
+
+		binData, err := ioutil.ReadFile(fixture + ".bin")
+		if !assert.NoError(err) {
+			continue
+		}
+
+		binDict, err := d2txt.LoadBinDataDictionary(binData, schema)
+		if !assert.NoError(err, txtPath) {
+			continue
+		}
+
+		assert.NoError(fromTxt.Load(txtPath, d2txt.LoadDataDictionary(txtData)))
+		assert.NoError(fromBin.Load(txtPath, binDict))
+	}
+
+	assert.NotEmpty(fromTxt.Animation.Token.Weapon)
+	assert.Len(fromBin.Animation.Token.Player, 7)
+	assert.Equal(&PlayerTypeRecord{Name: "Sorceress", Token: "SO"}, fromBin.Animation.Token.Player["Sorceress"])
+	assert.Equal(fromTxt.Animation.Token, fromBin.Animation.Token)
+
+	assert.Len(fromBin.Item.Weapons, 3)
+	assert.Len(fromBin.Item.All, 8)
+	assert.Len(fromBin.Item.Stats, 4)
+	assert.Len(fromBin.Monster.Stats, 3)
+	assert.Len(fromBin.Skill.Details, 3)
+	assert.Len(fromBin.Level.Details, 3)
+
+	if axe := fromBin.Item.Weapons["hax"]; assert.NotNil(axe) {
+		assert.Equal("1hs", axe.WeaponClass)
+		assert.Equal("9ha", axe.UberCode)
+	}
+
+	if level := fromBin.Level.Details[2]; assert.NotNil(level) {
+		assert.Equal("Blood Moor", level.LevelDisplayName)
+	}
+
+	assert.Equal(fromTxt.Item.Weapons, fromBin.Item.Weapons)
+	assert.Equal(fromTxt.Item.Armors, fromBin.Item.Armors)
+	assert.Equal(fromTxt.Item.Misc, fromBin.Item.Misc)
+	assert.Equal(fromTxt.Item.Stats, fromBin.Item.Stats)
+	assert.Equal(fromTxt.Monster.Stats, fromBin.Monster.Stats)
+	assert.Equal(fromTxt.Skill.Details, fromBin.Skill.Details)
+	assert.Equal(fromTxt.Level.Details, fromBin.Level.Details)
+}
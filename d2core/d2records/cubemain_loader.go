@@ -70,8 +70,8 @@ func cubeRecipeLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 			record.Outputs[o] = CubeRecipeResult{
 				Item:   item,
 				Level:  d.Number(outLabel + "lvl"),
-				ILevel: d.Number(outLabel + "plvl"),
-				PLevel: d.Number(outLabel + "ilvl"),
+				PLevel: d.Number(outLabel + "plvl"),
+				ILevel: d.Number(outLabel + "ilvl"),
 			}
 
 			// Create properties - mod 1-5
@@ -25,7 +25,7 @@ func hirelingLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 			HP:              d.Number("HP"),
 			HPPerLvl:        d.Number("HP/Lvl"),
 			Defense:         d.Number("Defense"),
-			DefPerLvl:       d.Number("Id"),
+			DefPerLvl:       d.Number("Def/Lvl"),
 			Str:             d.Number("Str"),
 			StrPerLvl:       d.Number("Str/Lvl"),
 			Dex:             d.Number("Dex"),
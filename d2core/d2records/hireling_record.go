@@ -1,5 +1,7 @@
 package d2records
 
+import "fmt"
+
 // Hirelings stores hireling (mercenary) records
 type Hirelings []*HirelingRecord
 
@@ -79,3 +81,84 @@ type HirelingRecord struct {
 	Weapon          int
 	Shield          int
 }
+
+// ByID returns the hireling with the given Id of hireling.txt, nil if there is none
+func (h Hirelings) ByID(id int) *HirelingRecord {
+	for _, record := range h {
+		if record.ID == id {
+			return record
+		}
+	}
+
+	return nil
+}
+
+// ExperienceForLevel returns the experience the hireling needs to reach the given level
+func (h *HirelingRecord) ExperienceForLevel(level int) int {
+	return h.ExpPerLvl * level * level * (level + 1)
+}
+
+// LevelForExperience returns the level the hireling has with the given experience, the hirelings
+// start at the level of their row
+func (h *HirelingRecord) LevelForExperience(experience int) int {
+	level := h.Level
+	if level < 1 {
+		level = 1
+	}
+
+	for h.ExpPerLvl > 0 && experience >= h.ExperienceForLevel(level+1) {
+		level++
+	}
+
+	return level
+}
+
+// NameCount returns the number of names the hirelings of the row are given
+func (h *HirelingRecord) NameCount() int {
+	first, last := nameNumber(h.NameFirst), nameNumber(h.NameLast)
+	if first < 0 || last < first {
+		return 1
+	}
+
+	return last - first + 1
+}
+
+// Name returns the string table key of the name with the given index, counted from the first name
+// of the row. The names are numbered keys like merc01 to merc41.
+func (h *HirelingRecord) Name(index int) string {
+	first := nameNumber(h.NameFirst)
+	if first < 0 {
+		return h.NameFirst
+	}
+
+	digits := 0
+	for digits < len(h.NameFirst) && isDigit(h.NameFirst[len(h.NameFirst)-1-digits]) {
+		digits++
+	}
+
+	prefix := h.NameFirst[:len(h.NameFirst)-digits]
+
+	return fmt.Sprintf("%s%0*d", prefix, digits, first+index)
+}
+
+// nameNumber returns the number at the end of a name key, -1 if it has none
+func nameNumber(key string) int {
+	number, factor := 0, 1
+	end := len(key)
+
+	for end > 0 && isDigit(key[end-1]) {
+		number += int(key[end-1]-'0') * factor
+		factor *= 10
+		end--
+	}
+
+	if end == len(key) {
+		return -1
+	}
+
+	return number
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
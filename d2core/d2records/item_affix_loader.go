@@ -83,8 +83,9 @@ func createItemAffixRecords(
 	records := make(map[string]*ItemAffixCommonRecord)
 	groups := make(ItemAffixGroups)
 
-	for d.Next() {
+	for index := 0; d.Next(); index++ {
 		affix := &ItemAffixCommonRecord{
+			Index:          index,
 			Name:           d.String("Name"),
 			Version:        d.Number("version"),
 			Type:           subType,
@@ -30,6 +30,7 @@ type ItemAffixCommonRecord struct {
 	ItemInclude []string
 	ItemExclude []string
 
+	Index          int // line number in the file, the ID used by item saves
 	Name           string
 	Class          string
 	TransformColor string
@@ -182,8 +182,9 @@ func loadCommonItems(d *d2txt.DataDictionary, source d2enum.InventoryItemType) (
 	return records, nil
 }
 
-func createItemVendorParams(d *d2txt.DataDictionary) map[string]*ItemVendorParams {
-	vs := []string{
+// itemVendors returns the names of the vendors of the vendor columns of the item tables
+func itemVendors() []string {
+	return []string{
 		"Charsi",
 		"Gheed",
 		"Akara",
@@ -202,6 +203,10 @@ func createItemVendorParams(d *d2txt.DataDictionary) map[string]*ItemVendorParam
 		"Malah",
 		"Drehya",
 	}
+}
+
+func createItemVendorParams(d *d2txt.DataDictionary) map[string]*ItemVendorParams {
+	vs := itemVendors()
 
 	result := make(map[string]*ItemVendorParams)
 
@@ -1,6 +1,8 @@
 package d2records
 
 import (
+	"strconv"
+
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2txt"
 )
@@ -88,7 +90,12 @@ func itemStatCostLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 			record.DescVal = 1
 		}
 
-		records[record.Name] = record
+		key := record.Name
+		if key == "" { // the compiled table has no stat names
+			key = strconv.Itoa(record.Index)
+		}
+
+		records[key] = record
 	}
 
 	if d.Err != nil {
@@ -360,3 +360,94 @@ type LevelDetailRecord struct {
 	MonsterPreferRanged bool // rangedspawn
 
 }
+
+// NoWaypoint is the value of the Waypoint column of the levels without a waypoint
+const NoWaypoint = 255
+
+// LevelLinks is the number of Vis and Warp columns of a level
+const LevelLinks = 8
+
+// LevelLink returns the level linked by the given Vis column and the lvlwarp.txt
+// record of the warp to it. A level ID of 0 means there is no link, a warp ID of
+// -1 means the levels are linked without a warp tile.
+func (l *LevelDetailRecord) LevelLink(index int) (levelID, warpID int) {
+	levelIDs := [LevelLinks]int{
+		l.LevelLinkID0, l.LevelLinkID1, l.LevelLinkID2, l.LevelLinkID3,
+		l.LevelLinkID4, l.LevelLinkID5, l.LevelLinkID6, l.LevelLinkID7,
+	}
+
+	warpIDs := [LevelLinks]int{
+		l.WarpGraphicsID0, l.WarpGraphicsID1, l.WarpGraphicsID2, l.WarpGraphicsID3,
+		l.WarpGraphicsID4, l.WarpGraphicsID5, l.WarpGraphicsID6, l.WarpGraphicsID7,
+	}
+
+	if index < 0 || index >= LevelLinks {
+		return 0, -1
+	}
+
+	return levelIDs[index], warpIDs[index]
+}
+
+// HasWaypoint returns true if the level has a waypoint
+func (l *LevelDetailRecord) HasWaypoint() bool {
+	return l.WaypointID != NoWaypoint
+}
+
+// MonsterLevel returns the level of the monsters of the level in the given difficulty
+func (l *LevelDetailRecord) MonsterLevel(difficulty d2enum.DifficultyType) int {
+	switch difficulty {
+	case d2enum.DifficultyNightmare:
+		return l.MonsterLevelNightmareEx
+	case d2enum.DifficultyHell:
+		return l.MonsterLevelHellEx
+	default:
+		return l.MonsterLevelNormalEx
+	}
+}
+
+// Monsters returns the monstats.txt IDs of the monsters which are spawned on the level in the
+// given difficulty
+func (l *LevelDetailRecord) Monsters(difficulty d2enum.DifficultyType) []string {
+	var ids [10]string
+
+	switch difficulty {
+	case d2enum.DifficultyNightmare:
+		ids = [...]string{
+			l.MonsterID1Nightmare, l.MonsterID2Nightmare, l.MonsterID3Nightmare, l.MonsterID4Nightmare,
+			l.MonsterID5Nightmare, l.MonsterID6Nightmare, l.MonsterID7Nightmare, l.MonsterID8Nightmare,
+			l.MonsterID9Nightmare, l.MonsterID10Nightmare,
+		}
+	case d2enum.DifficultyHell:
+		ids = [...]string{
+			l.MonsterID1Hell, l.MonsterID2Hell, l.MonsterID3Hell, l.MonsterID4Hell, l.MonsterID5Hell,
+			l.MonsterID6Hell, l.MonsterID7Hell, l.MonsterID8Hell, l.MonsterID9Hell, l.MonsterID10Hell,
+		}
+	default:
+		ids = [...]string{
+			l.MonsterID1Normal, l.MonsterID2Normal, l.MonsterID3Normal, l.MonsterID4Normal, l.MonsterID5Normal,
+			l.MonsterID6Normal, l.MonsterID7Normal, l.MonsterID8Normal, l.MonsterID9Normal, l.MonsterID10Normal,
+		}
+	}
+
+	monsters := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		if id != "" {
+			monsters = append(monsters, id)
+		}
+	}
+
+	return monsters
+}
+
+// MonsterDensity returns the density of the monsters of the level in the given difficulty
+func (l *LevelDetailRecord) MonsterDensity(difficulty d2enum.DifficultyType) int {
+	switch difficulty {
+	case d2enum.DifficultyNightmare:
+		return l.MonsterDensityNightmare
+	case d2enum.DifficultyHell:
+		return l.MonsterDensityHell
+	default:
+		return l.MonsterDensityNormal
+	}
+}
@@ -33,7 +33,7 @@ func levelSubstitutionsLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 			GridMax4:     d.Number("Max4"),
 		}
 
-		records[record.ID] = record
+		records[record.ID] = append(records[record.ID], record)
 	}
 
 	if d.Err != nil {
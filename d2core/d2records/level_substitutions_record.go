@@ -1,7 +1,11 @@
 package d2records
 
-// LevelSubstitutions stores all of the LevelSubstitutionRecords
-type LevelSubstitutions map[int]*LevelSubstitutionRecord
+// LevelSubstitutions stores all of the LevelSubstitutionRecords by their Type, the rows of
+// a Type are in the order of lvlsub.txt
+type LevelSubstitutions map[int][]*LevelSubstitutionRecord
+
+// SubstitutionThemes is the number of Prob, Trials and Max columns of lvlsub.txt
+const SubstitutionThemes = 5
 
 // LevelSubstitutionRecord is a representation of a row from lvlsub.txt
 // these records are parameters for levels and describe substitution rules
@@ -60,3 +64,16 @@ type LevelSubstitutionRecord struct {
 
 	// Beta
 }
+
+// Theme returns the Prob, Trials and Max columns of the given theme, the SubTheme of levels.txt
+func (l *LevelSubstitutionRecord) Theme(theme int) (chance, trials, maximum int) {
+	chances := [SubstitutionThemes]int{l.ChanceSpawn0, l.ChanceSpawn1, l.ChanceSpawn2, l.ChanceSpawn3, l.ChanceSpawn4}
+	trialCounts := [SubstitutionThemes]int{l.ChanceFloor0, l.ChanceFloor1, l.ChanceFloor2, l.ChanceFloor3, l.ChanceFloor4}
+	maximums := [SubstitutionThemes]int{l.GridMax0, l.GridMax1, l.GridMax2, l.GridMax3, l.GridMax4}
+
+	if theme < 0 || theme >= SubstitutionThemes {
+		return 0, 0, 0
+	}
+
+	return chances[theme], trialCounts[theme], maximums[theme]
+}
@@ -1,6 +1,8 @@
 package d2records
 
 import (
+	"strconv"
+
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2txt"
 )
@@ -265,6 +267,10 @@ func monsterStatsLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 			SpecialClientEnd:               d.Number("SplClientEnd") > 0,
 		}
 
+		if record.Key == "" { // the compiled table has no monster ids
+			record.Key = strconv.Itoa(record.ID)
+		}
+
 		records[record.Key] = record
 	}
 
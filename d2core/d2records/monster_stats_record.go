@@ -676,3 +676,118 @@ type (
 
 	}
 )
+
+// TreasureClass returns the name of the treasure class the monster drops from in the given
+// difficulty
+func (m *MonStatRecord) TreasureClass(difficulty d2enum.DifficultyType) string {
+	switch difficulty {
+	case d2enum.DifficultyNightmare:
+		return m.TreasureClassNightmare
+	case d2enum.DifficultyHell:
+		return m.TreasureClassHell
+	default:
+		return m.TreasureClassNormal
+	}
+}
+
+// Level returns the level of the monster in the given difficulty. In nightmare and hell the
+// monsters which are not bosses take the monster level of the area they are in, which is given.
+func (m *MonStatRecord) Level(difficulty d2enum.DifficultyType, areaLevel int) int {
+	level := m.LevelNormal
+
+	switch difficulty {
+	case d2enum.DifficultyNightmare:
+		level = m.LevelNightmare
+	case d2enum.DifficultyHell:
+		level = m.LevelHell
+	}
+
+	if difficulty != d2enum.DifficultyNormal && !m.IsSpecialBoss && areaLevel > 0 {
+		return areaLevel
+	}
+
+	return level
+}
+
+// AIDelay returns the number of frames the AI of the monster waits between two decisions in
+// the given difficulty
+func (m *MonStatRecord) AIDelay(difficulty d2enum.DifficultyType) int {
+	return byDifficulty(difficulty, m.AiDelayNormal, m.AiDelayNightmare, m.AiDelayHell)
+}
+
+// AIDistance returns the distance within which the AI of the monster notices its targets in
+// the given difficulty
+func (m *MonStatRecord) AIDistance(difficulty d2enum.DifficultyType) int {
+	return byDifficulty(difficulty, m.AiDistanceNormal, m.AiDistanceNightmare, m.AiDistanceHell)
+}
+
+// LifeRange returns the range of the life of the monster in the given difficulty
+func (m *MonStatRecord) LifeRange(difficulty d2enum.DifficultyType) (minLife, maxLife int) {
+	return byDifficulty(difficulty, m.MinHPNormal, m.MinHPNightmare, m.MinHPHell),
+		byDifficulty(difficulty, m.MaxHPNormal, m.MaxHPNightmare, m.MaxHPHell)
+}
+
+// AttackDamage returns the damage range of the first attack of the monster in the given difficulty
+func (m *MonStatRecord) AttackDamage(difficulty d2enum.DifficultyType) (minDamage, maxDamage int) {
+	return byDifficulty(difficulty, m.DamageMinA1Normal, m.DamageMinA1Nightmare, m.DamageMinA1Hell),
+		byDifficulty(difficulty, m.DamageMaxA1Normal, m.DamageMaxA1Nightmare, m.DamageMaxA1Hell)
+}
+
+// MonsterResistances are the resistances of a monster in percent, 100 or more means immunity
+type MonsterResistances struct {
+	Physical, Magic, Fire, Lightning, Cold, Poison int
+}
+
+// Resistances returns the resistances of the monster in the given difficulty
+func (m *MonStatRecord) Resistances(difficulty d2enum.DifficultyType) MonsterResistances {
+	return MonsterResistances{
+		Physical:  byDifficulty(difficulty, m.ResistancePhysicalNormal, m.ResistancePhysicalNightmare, m.ResistancePhysicalHell),
+		Magic:     byDifficulty(difficulty, m.ResistanceMagicNormal, m.ResistanceMagicNightmare, m.ResistanceMagicHell),
+		Fire:      byDifficulty(difficulty, m.ResistanceFireNormal, m.ResistanceFireNightmare, m.ResistanceFireHell),
+		Lightning: byDifficulty(difficulty, m.ResistanceLightningNormal, m.ResistanceLightningNightmare, m.ResistanceLightningHell),
+		Cold:      byDifficulty(difficulty, m.ResistanceColdNormal, m.ResistanceColdNightmare, m.ResistanceColdHell),
+		Poison:    byDifficulty(difficulty, m.ResistancePoisonNormal, m.ResistancePoisonNightmare, m.ResistancePoisonHell),
+	}
+}
+
+// ArmorClass returns the defense of the monster in the given difficulty
+func (m *MonStatRecord) ArmorClass(difficulty d2enum.DifficultyType) int {
+	return byDifficulty(difficulty, m.ArmorClassNormal, m.ArmorClassNightmare, m.ArmorClassHell)
+}
+
+// AttackRating returns the attack rating of the first attack of the monster in the given difficulty
+func (m *MonStatRecord) AttackRating(difficulty d2enum.DifficultyType) int {
+	return byDifficulty(difficulty, m.AttackRatingA1Normal, m.AttackRatingA1Nightmare, m.AttackRatingA1Hell)
+}
+
+// BlockChance returns the percent chance of the monster to block in the given difficulty
+func (m *MonStatRecord) BlockChance(difficulty d2enum.DifficultyType) int {
+	return byDifficulty(difficulty, m.ChanceToBlockNormal, m.ChanceToBlockNightmare, m.ChanceToBlockHell)
+}
+
+// LeechSensitivity returns the percent of the life and mana leech which works on the monster in
+// the given difficulty
+func (m *MonStatRecord) LeechSensitivity(difficulty d2enum.DifficultyType) int {
+	return byDifficulty(difficulty, m.LeechSensitivityNormal, m.LeechSensitivityNightmare, m.LeechSensitivityHell)
+}
+
+// Experience returns the experience the monster is worth in the given difficulty
+func (m *MonStatRecord) Experience(difficulty d2enum.DifficultyType) int {
+	return byDifficulty(difficulty, m.ExperienceNormal, m.ExperienceNightmare, m.ExperienceHell)
+}
+
+func byDifficulty(difficulty d2enum.DifficultyType, normal, nightmare, hell int) int {
+	switch difficulty {
+	case d2enum.DifficultyNightmare:
+		return nightmare
+	case d2enum.DifficultyHell:
+		return hell
+	default:
+		return normal
+	}
+}
+
+// IsHostile returns true if the monster attacks the players
+func (m *MonStatRecord) IsHostile() bool {
+	return m.Alignment == d2enum.MonsterEnemy && !m.IsNpc
+}
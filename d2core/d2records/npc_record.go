@@ -27,10 +27,10 @@ type NPCRecord struct {
 }
 
 type costMultiplier struct {
-	// Buy is a percentage of base item price used when an item is bought by NPC
+	// Buy is a percentage of base item price used when an item is bought from the NPC
 	Buy float64
 
-	// Sell is a percentage of base item price used when an item is sold by NPC
+	// Sell is a percentage of base item price used when an item is sold to the NPC
 	Sell float64
 
 	// Repair is a percentage of base item price used to calculate the base repair price
@@ -20,6 +20,7 @@ const (
 func NewRecordManager(l d2util.LogLevel) (*RecordManager, error) {
 	rm := &RecordManager{
 		boundLoaders: make(map[string][]recordLoader),
+		binSchemas:   make(map[string]*d2txt.BinSchema),
 	}
 
 	rm.Logger = d2util.NewLogger()
@@ -37,7 +38,8 @@ func NewRecordManager(l d2util.LogLevel) (*RecordManager, error) {
 // RecordManager stores all of the records loaded from txt files
 type RecordManager struct {
 	*d2util.Logger
-	boundLoaders map[string][]recordLoader // there can be more than one loader bound for a file
+	boundLoaders map[string][]recordLoader   // there can be more than one loader bound for a file
+	binSchemas   map[string]*d2txt.BinSchema // the layouts of the compiled .bin tables, by txt file path
 	Animation    struct {
 		Data  d2data.AnimationData
 		Token struct {
@@ -275,6 +277,7 @@ func (r *RecordManager) init() error { // nolint:funlen // can't reduce
 	}
 
 	r.initObjectRecords(objectLookups)
+	r.initBinSchemas()
 
 	return nil
 }
@@ -458,3 +461,13 @@ func (r *RecordManager) GetSkillByName(skillName string) *SkillRecord {
 func (r *RecordManager) GetMissileByName(missileName string) *MissileRecord {
 	return r.missilesByName[sanitizeMissilesKey(missileName)]
 }
+
+// GetTreasureClassByName returns the treasure class with the given name, the treasure classes of
+// the expansion take precedence over those of the classic game
+func (r *RecordManager) GetTreasureClassByName(name string) *TreasureClassRecord {
+	if record, found := r.Item.Treasure.Expansion[name]; found {
+		return record
+	}
+
+	return r.Item.Treasure.Normal[name]
+}
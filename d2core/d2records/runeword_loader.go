@@ -28,8 +28,9 @@ const (
 func runewordLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 	records := make(map[string]*RuneRecord)
 
-	for d.Next() {
+	for index := 0; d.Next(); index++ {
 		record := &RuneRecord{
+			Index:    index,
 			Name:     d.String("name"),
 			RuneName: d.String("Rune Name"),
 			Complete: d.Bool("complete"),
@@ -6,6 +6,7 @@ type Runewords map[string]*RuneRecord
 // RuneRecord is a representation of a single row of runes.txt. It defines
 // runewords available in the game.
 type RuneRecord struct {
+	Index    int // line number in the file, the ID used by item saves
 	Name     string
 	RuneName string // More of a note - the actual name should be read from the TBL files.
 	Complete bool   // An enabled/disabled flag. Only "Complete" runewords work in game.
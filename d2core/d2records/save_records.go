@@ -0,0 +1,57 @@
+package d2records
+
+import "github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+
+// static check that saveRecords implements the records needed by the save format
+var _ d2s.Records = &saveRecords{}
+
+type saveRecords struct {
+	records *RecordManager
+	stats   map[int]*ItemStatCostRecord
+}
+
+// SaveRecords returns the records needed to read and write character saves and items
+func (r *RecordManager) SaveRecords() d2s.Records {
+	stats := make(map[int]*ItemStatCostRecord, len(r.Item.Stats))
+
+	for _, record := range r.Item.Stats {
+		stats[record.Index] = record
+	}
+
+	return &saveRecords{records: r, stats: stats}
+}
+
+// StatInfo returns the save bits of the stat with the given ItemStatCost.txt index
+func (s *saveRecords) StatInfo(id int) (d2s.StatInfo, bool) {
+	record, found := s.stats[id]
+	if !found {
+		return d2s.StatInfo{}, false
+	}
+
+	return d2s.StatInfo{
+		CharacterBits: record.SavedBits,
+		Bits:          record.SaveBits,
+		Add:           record.SaveAdd,
+		ParamBits:     record.SaveParamBits,
+	}, true
+}
+
+// ItemInfo returns the layout information of an item base type
+func (s *saveRecords) ItemInfo(code string) (d2s.ItemInfo, bool) {
+	kinds := []struct {
+		items CommonItems
+		kind  d2s.ItemKind
+	}{
+		{s.records.Item.Armors, d2s.ItemKindArmor},
+		{s.records.Item.Weapons, d2s.ItemKindWeapon},
+		{s.records.Item.Misc, d2s.ItemKindMisc},
+	}
+
+	for _, kind := range kinds {
+		if record, found := kind.items[code]; found {
+			return d2s.ItemInfo{Kind: kind.kind, Stackable: record.Stackable}, true
+		}
+	}
+
+	return d2s.ItemInfo{}, false
+}
@@ -28,8 +28,9 @@ type SetItemProperty = PropertyDescriptor
 func setItemLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 	records := make(map[string]*SetItemRecord)
 
-	for d.Next() {
+	for index := 0; d.Next(); index++ {
 		record := &SetItemRecord{
+			Index:                     index,
 			SetItemKey:                d.String("index"),
 			SetKey:                    d.String("set"),
 			ItemCode:                  d.String("item"),
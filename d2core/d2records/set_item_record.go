@@ -5,6 +5,10 @@ type SetItems map[string]*SetItemRecord
 
 // SetItemRecord represents a set item
 type SetItemRecord struct {
+	// Index
+	// line number in the file, the ID used by item saves
+	Index int
+
 	// SetItemKey (index)
 	// string key to item's name in a .tbl file
 	SetItemKey string
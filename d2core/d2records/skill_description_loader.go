@@ -11,7 +11,9 @@ func skillDescriptionLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 	records := make(map[string]*SkillDescriptionRecord)
 
 	parser := d2parser.New()
-	parser.SetCurrentReference("skill", "TODO: connect skill with description!")
+	// a description is shared by the skills which refer to it, so the references without a skill
+	// name are resolved with the skill the description is evaluated for
+	parser.SetCurrentReference("skill", "")
 
 	for d.Next() {
 		record := &SkillDescriptionRecord{
@@ -8,8 +8,9 @@ import (
 func uniqueItemsLoader(r *RecordManager, d *d2txt.DataDictionary) error {
 	records := make(UniqueItems)
 
-	for d.Next() {
+	for index := 0; d.Next(); index++ {
 		record := &UniqueItemRecord{
+			Index:   index,
 			Name:    d.String("index"),
 			Version: d.Number("version"),
 			Enabled: d.Number("enabled") == 1,
@@ -7,6 +7,7 @@ type UniqueItems map[string]*UniqueItemRecord
 type UniqueItemRecord struct {
 	Properties [12]*UniqueItemProperty
 
+	Index                 int // line number in the file, the ID used by item saves
 	Name                  string
 	Code                  string // three letter code, points to a record in Weapons, Armor, or Misc
 	TypeDescription       string
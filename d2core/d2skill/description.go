@@ -0,0 +1,81 @@
+package d2skill
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2calculation"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+// descriptionLine is a line of skilldesc.txt, the texts are string table keys
+type descriptionLine struct {
+	function     string
+	textA, textB string
+	calcA, calcB d2calculation.Calculation
+}
+
+// Description returns the lines of the given description of the skill with the values of their
+// calculations: the mana cost, then the lines of the description. The string table keys of the
+// texts are looked up with the given function.
+func (s *Skill) Description(desc *d2records.SkillDescriptionRecord, translate func(key string) string) []string {
+	lines := make([]string, 0)
+
+	if desc == nil {
+		return lines
+	}
+
+	if desc.ManaKey != "" && s.ManaCost() > 0 {
+		lines = append(lines, fmt.Sprintf("%s %d", translate(desc.ManaKey), int(s.ManaCost())))
+	}
+
+	for _, line := range []descriptionLine{
+		{desc.Descline1, desc.Desctexta1, desc.Desctextb1, desc.Desccalca1, desc.Desccalcb1},
+		{desc.Descline2, desc.Desctexta2, desc.Desctextb2, desc.Desccalca2, desc.Desccalcb2},
+		{desc.Descline3, desc.Desctexta3, desc.Desctextb3, desc.Desccalca3, desc.Desccalcb3},
+		{desc.Descline4, desc.Desctexta4, desc.Desctextb4, desc.Desccalca4, desc.Desccalcb4},
+		{desc.Descline5, desc.Desctexta5, desc.Desctextb5, desc.Desccalca5, desc.Desccalcb5},
+		{desc.Descline6, desc.Desctexta6, desc.Desctextb6, desc.Desccalca6, desc.Desccalcb6},
+	} {
+		if text := s.describe(&line, translate); text != "" {
+			lines = append(lines, text)
+		}
+	}
+
+	return lines
+}
+
+// describe returns the text of a line with the values of its calculations. A text with a %d gets
+// the values in its place, the values of the other texts follow them as a single value or a range.
+func (s *Skill) describe(line *descriptionLine, translate func(key string) string) string {
+	if line.function == "" || line.function == "0" {
+		return ""
+	}
+
+	text := ""
+	if line.textA != "" {
+		text = strings.TrimSpace(translate(line.textA))
+	}
+
+	valueA, valueB := s.Eval(line.calcA), s.Eval(line.calcB)
+
+	switch strings.Count(text, "%d") {
+	case 1:
+		text = fmt.Sprintf(text, valueA)
+	case 2: //nolint:gomnd // the texts have up to two values
+		text = fmt.Sprintf(text, valueA, valueB)
+	default:
+		switch {
+		case valueB != 0 && valueB != valueA:
+			text = fmt.Sprintf("%s %d-%d", text, valueA, valueB)
+		case valueA != 0:
+			text = fmt.Sprintf("%s %d", text, valueA)
+		}
+	}
+
+	if line.textB != "" {
+		text += " " + strings.TrimSpace(translate(line.textB))
+	}
+
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,5 @@
+// Package d2skill evaluates the skills of skills.txt for a caster. A Skill binds a skill record to
+// its level and the unit using it, so the calculations of the record can refer to the level, the
+// parameters, the levels of the other skills of the caster and its stats. The mana cost, damage,
+// radius and duration of a skill and the lines of its description come from these calculations.
+package d2skill
@@ -0,0 +1,58 @@
+package d2skill
+
+// skill levels at which the per level damages of skills.txt and missiles.txt change
+const (
+	levelBreak1 = 8
+	levelBreak2 = 16
+	levelBreak3 = 22
+	levelBreak4 = 28
+)
+
+// the diminishing returns of skillcalc.txt approach 110% of the difference of the parameters
+const (
+	diminishingLimit  = 110
+	diminishingLevels = 6
+)
+
+// Linear returns the value of a linear qualifier like ln12: the first parameter at level 1 plus
+// the second one for each level after the first
+func Linear(first, second, level int) int {
+	return first + second*(level-1)
+}
+
+// Diminishing returns the value of a diminishing qualifier like dm12: it starts at the first
+// parameter and approaches the second one with the levels
+func Diminishing(first, second, level int) int {
+	return first + (second-first)*(diminishingLimit*level/(level+diminishingLevels))/percent
+}
+
+// LevelDamage adds the damage per level of the level brackets of skills.txt and missiles.txt to
+// the base damage
+func LevelDamage(base int, perLevel [5]int, level int) int {
+	return base + levelBonus(perLevel[:], []int{1, levelBreak1, levelBreak2, levelBreak3, levelBreak4}, level)
+}
+
+// LevelDuration returns the duration, in frames, added by the levels of a skill past the first.
+// The first bonus applies up to level 16, the second up to level 28 and the third above.
+func LevelDuration(perLevel [3]int, level int) int {
+	return levelBonus(perLevel[:], []int{1, levelBreak2, levelBreak4}, level)
+}
+
+// levelBonus sums the bonus per level of each bracket for the levels past the first, a bracket
+// starts at its break and ends at the next one
+func levelBonus(perLevel, breaks []int, level int) int {
+	bonus := 0
+
+	for idx, start := range breaks {
+		end := level
+		if idx+1 < len(breaks) && breaks[idx+1] < end {
+			end = breaks[idx+1]
+		}
+
+		if end > start {
+			bonus += (end - start) * perLevel[idx]
+		}
+	}
+
+	return bonus
+}
@@ -0,0 +1,235 @@
+package d2skill
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2calculation"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+// the property types of the calculations
+const (
+	referenceSkill   = "skill"
+	referenceMissile = "miss"
+	referenceStat    = "stat"
+)
+
+const (
+	// maxDepth is how many skills deep the references of the calculations are followed, it stops
+	// skills which refer to each other
+	maxDepth = 4
+
+	percent = 100
+)
+
+// Caster is the unit a skill is evaluated for
+type Caster interface {
+	// SkillLevel returns the level of the skill with the given name with the levels the items of
+	// the caster add, 0 if the caster does not have the skill
+	SkillLevel(name string) int
+
+	// BaseSkillLevel returns the level of the skill with the given name the caster has put points
+	// into, without the levels of its items
+	BaseSkillLevel(name string) int
+
+	// StatValue returns the value of the itemstatcost.txt stat with the given name
+	StatValue(name string) int
+}
+
+// Skill is a skill of a caster at a level. It resolves the references of the calculations of
+// the skill, so it is the context the calculations are evaluated in.
+type Skill struct {
+	Record    *d2records.SkillRecord
+	Level     int // the level with the levels the items of the caster add, lvl in the calculations
+	BaseLevel int // the level the caster has put points into, blvl in the calculations
+	records   *d2records.RecordManager
+	caster    Caster
+	depth     int
+}
+
+// static check that Skill is a calculation context
+var _ d2calculation.Context = &Skill{}
+
+// New returns the skill of the given record at the given base level, the levels the items of the
+// caster add to the skill are added to it. The records resolve the references to other skills and
+// missiles, the caster those to its skills and stats. Both may be nil, the references they would
+// resolve are 0 then.
+func New(records *d2records.RecordManager, record *d2records.SkillRecord, level int, caster Caster) *Skill {
+	if level < 1 {
+		level = 1
+	}
+
+	bonus := 0
+	if caster != nil && record != nil {
+		bonus = caster.SkillLevel(record.Skill) - caster.BaseSkillLevel(record.Skill)
+	}
+
+	if bonus < 0 {
+		bonus = 0
+	}
+
+	return &Skill{Record: record, Level: level + bonus, BaseLevel: level, records: records, caster: caster}
+}
+
+// Eval evaluates a calculation of the skill, a missing calculation is 0
+func (s *Skill) Eval(calc d2calculation.Calculation) int {
+	if calc == nil {
+		return 0
+	}
+
+	return calc.EvalWith(s)
+}
+
+// Reference resolves a property reference of a calculation: a qualifier of this skill or of
+// another skill, a property of a missile or a stat of the caster
+func (s *Skill) Reference(propType, propName, qualifier string) int {
+	switch propType {
+	case referenceSkill:
+		if propName == "" || propName == s.Record.Skill {
+			return s.qualifier(qualifier)
+		}
+
+		return s.otherSkill(propName, qualifier)
+	case referenceMissile:
+		return s.missile(propName, qualifier)
+	case referenceStat:
+		if s.caster == nil {
+			return 0
+		}
+
+		return s.caster.StatValue(propName)
+	}
+
+	return 0
+}
+
+// qualifier returns the value of a qualifier of the skill, the qualifiers are the codes of
+// skillcalc.txt
+func (s *Skill) qualifier(qualifier string) int {
+	switch qualifier {
+	case "lvl":
+		return s.Level
+	case "blvl":
+		return s.BaseLevel
+	case "mana":
+		return int(s.ManaCost())
+	case "edmn":
+		minDamage, _ := s.ElementalDamage()
+		return minDamage
+	case "edmx":
+		_, maxDamage := s.ElementalDamage()
+		return maxDamage
+	case "edln", "len":
+		return s.ElementalLength()
+	case "toht":
+		return s.ToHit()
+	}
+
+	if len(qualifier) == len("par1") && strings.HasPrefix(qualifier, "par") {
+		return s.param(qualifier[3:])
+	}
+
+	if len(qualifier) == len("clc1") && strings.HasPrefix(qualifier, "clc") {
+		calcs := []d2calculation.Calculation{s.Record.Calc1, s.Record.Calc2, s.Record.Calc3, s.Record.Calc4}
+
+		if idx, err := strconv.Atoi(qualifier[3:]); err == nil && idx >= 1 && idx <= len(calcs) {
+			return s.nested(func() int { return s.Eval(calcs[idx-1]) })
+		}
+
+		return 0
+	}
+
+	if len(qualifier) == len("ln12") && (strings.HasPrefix(qualifier, "ln") || strings.HasPrefix(qualifier, "dm")) {
+		first, second := s.param(qualifier[2:3]), s.param(qualifier[3:4])
+
+		if strings.HasPrefix(qualifier, "ln") {
+			return Linear(first, second, s.Level)
+		}
+
+		return Diminishing(first, second, s.Level)
+	}
+
+	return 0
+}
+
+// param returns the parameter of skills.txt with the given number
+func (s *Skill) param(number string) int {
+	params := []int{
+		s.Record.Param1, s.Record.Param2, s.Record.Param3, s.Record.Param4,
+		s.Record.Param5, s.Record.Param6, s.Record.Param7, s.Record.Param8,
+	}
+
+	idx, err := strconv.Atoi(number)
+	if err != nil || idx < 1 || idx > len(params) {
+		return 0
+	}
+
+	return params[idx-1]
+}
+
+// otherSkill returns a qualifier of another skill of the caster, the levels are those the caster
+// has and the other qualifiers are evaluated at those levels
+func (s *Skill) otherSkill(name, qualifier string) int {
+	level, baseLevel := 0, 0
+	if s.caster != nil {
+		level, baseLevel = s.caster.SkillLevel(name), s.caster.BaseSkillLevel(name)
+	}
+
+	switch qualifier {
+	case "lvl":
+		return level
+	case "blvl":
+		return baseLevel
+	}
+
+	if s.records == nil {
+		return 0
+	}
+
+	record := s.records.GetSkillByName(name)
+	if record == nil {
+		return 0
+	}
+
+	other := New(s.records, record, baseLevel, s.caster)
+	other.depth = s.depth + 1
+
+	return s.nested(func() int { return other.qualifier(qualifier) })
+}
+
+// missile returns a property of the missile with the given name
+func (s *Skill) missile(name, qualifier string) int {
+	if s.records == nil {
+		return 0
+	}
+
+	missile := s.records.GetMissileByName(name)
+	if missile == nil {
+		return 0
+	}
+
+	switch qualifier {
+	case "range":
+		return missile.Range
+	case "vel":
+		return missile.Velocity
+	case "maxvel":
+		return missile.MaxVelocity
+	}
+
+	return 0
+}
+
+// nested evaluates a calculation which refers to another calculation, the references stop at
+// the maximum depth
+func (s *Skill) nested(eval func() int) int {
+	if s.depth >= maxDepth {
+		return 0
+	}
+
+	s.depth++
+	defer func() { s.depth-- }()
+
+	return eval()
+}
@@ -0,0 +1,197 @@
+package d2skill
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2calculation/d2parser"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+)
+
+type testCaster struct {
+	skills map[string]int
+	stats  map[string]int
+	bonus  int
+}
+
+func (c *testCaster) SkillLevel(name string) int {
+	if c.skills[name] == 0 {
+		return 0
+	}
+
+	return c.skills[name] + c.bonus
+}
+
+func (c *testCaster) BaseSkillLevel(name string) int {
+	return c.skills[name]
+}
+
+func (c *testCaster) StatValue(name string) int {
+	return c.stats[name]
+}
+
+func testRecords() (*d2records.RecordManager, *d2records.SkillRecord) {
+	parser := d2parser.New()
+	parser.SetCurrentReference("skill", "Fire Ball")
+
+	fireBall := &d2records.SkillRecord{
+		Skill:          "Fire Ball",
+		Param1:         6,
+		Param2:         2,
+		Param3:         10,
+		Param4:         50,
+		Calc1:          parser.Parse("ln12*2"),
+		EMin:           3,
+		EMax:           7,
+		EMinLev1:       2,
+		EMaxLev1:       3,
+		HitShift:       8,
+		Mana:           20,
+		Lvlmana:        4,
+		Manashift:      7,
+		Minmana:        5,
+		EDmgSymPerCalc: parser.Parse("(skill('Fire Bolt'.blvl)+skill('Meteor'.blvl))*14"),
+		Auralencalc:    parser.Parse("ln12*25"),
+		Aurastat1:      "fireresist",
+		Aurastatcalc1:  parser.Parse("dm34"),
+	}
+
+	parser.SetCurrentReference("skill", "Fire Bolt")
+
+	fireBolt := &d2records.SkillRecord{
+		Skill:  "Fire Bolt",
+		Param1: 4,
+		Param2: 3,
+		Calc1:  parser.Parse("skill('Fire Ball'.clc1)"),
+	}
+
+	records := &d2records.RecordManager{}
+	records.Skill.Details = d2records.SkillDetails{47: fireBall, 36: fireBolt}
+
+	return records, fireBall
+}
+
+func TestSkillReferences(t *testing.T) {
+	records, fireBall := testRecords()
+	caster := &testCaster{
+		skills: map[string]int{"Fire Ball": 4, "Fire Bolt": 5, "Meteor": 2},
+		stats:  map[string]int{"energy": 35},
+		bonus:  1,
+	}
+
+	// the caster put 4 points into Fire Ball and its items add a level to every skill
+	skill := New(records, fireBall, 4, caster)
+	parser := d2parser.New()
+	parser.SetCurrentReference("skill", "Fire Ball")
+
+	table := []struct {
+		expr   string
+		result int
+	}{
+		{"lvl", 5},
+		{"blvl", 4},
+		{"par3", 10},
+		{"ln12", 6 + 4*2},
+		{"dm34", 10 + (50-10)*(110*5/(5+6))/100},
+		{"clc1", (6 + 4*2) * 2},
+		{"mana", (20 + 4*4) << 7 >> 8},
+		{"edmn", (3 + 4*2) * (100 + 7*14) / 100},
+		{"skill('Fire Bolt'.lvl)", 6},
+		{"skill('Fire Bolt'.blvl)", 5},
+		{"skill('Fire Bolt'.ln12)", 4 + 5*3},
+		{"skill('Meteor'.lvl)", 3},
+		{"skill('Meteor'.blvl)", 2},
+		{"skill('Blizzard'.lvl)", 0},
+		{"stat('energy'.accr)/5", 7},
+		{"par9", 0},
+	}
+
+	for _, row := range table {
+		c := parser.Parse(row.expr)
+		res := skill.Eval(c)
+
+		if res != row.result {
+			t.Errorf("Expression %v gave wrong result, got %d, want %d", row.expr, res, row.result)
+		}
+	}
+}
+
+func TestSkillReferenceCycle(t *testing.T) {
+	records, fireBall := testRecords()
+	parser := d2parser.New()
+	parser.SetCurrentReference("skill", "Fire Ball")
+
+	// Fire Ball refers to Fire Bolt which refers back to Fire Ball
+	fireBall.Calc2 = parser.Parse("skill('Fire Bolt'.clc1)+1")
+	fireBall.Calc1 = parser.Parse("clc2")
+
+	skill := New(records, fireBall, 1, &testCaster{skills: map[string]int{"Fire Bolt": 1}})
+
+	if res := skill.Eval(parser.Parse("clc1")); res > maxDepth {
+		t.Errorf("cyclic reference gave %d, want at most %d", res, maxDepth)
+	}
+}
+
+func TestSkillValues(t *testing.T) {
+	records, fireBall := testRecords()
+	skill := New(records, fireBall, 10, nil)
+
+	if got, want := skill.ManaCost(), float64((20+9*4)<<7)/256; got != want {
+		t.Errorf("mana cost is %v, want %v", got, want)
+	}
+
+	minDamage, maxDamage := skill.ElementalDamage()
+	if minDamage != 3+7*2 || maxDamage != 7+7*3 {
+		t.Errorf("elemental damage is %d-%d, want %d-%d", minDamage, maxDamage, 3+7*2, 7+7*3)
+	}
+
+	if got, want := skill.Duration(), float64((6+9*2)*25)/FramesPerSecond; got != want {
+		t.Errorf("duration is %v, want %v", got, want)
+	}
+
+	stats := skill.AuraStats()
+	if len(stats) != 1 || stats[0].Name != "fireresist" || stats[0].Value != Diminishing(10, 50, 10) {
+		t.Errorf("aura stats are %+v, want fireresist %d", stats, Diminishing(10, 50, 10))
+	}
+
+	if got := New(records, fireBall, 1, nil).ManaCost(); got != 10 {
+		t.Errorf("mana cost at level 1 is %v, want 10", got)
+	}
+}
+
+func TestDescription(t *testing.T) {
+	records, fireBall := testRecords()
+	parser := d2parser.New()
+	parser.SetCurrentReference("skill", "")
+
+	desc := &d2records.SkillDescriptionRecord{
+		ManaKey:    "StrSkill3",
+		Descline1:  "1",
+		Desctexta1: "StrSkill11",
+		Desccalca1: parser.Parse("edmn"),
+		Desccalcb1: parser.Parse("edmx"),
+		Descline2:  "2",
+		Desctexta2: "StrSkill12",
+		Desccalca2: parser.Parse("ln12"),
+		Desctextb2: "StrSkill13",
+	}
+
+	strings := map[string]string{
+		"StrSkill3":  "Mana Cost:",
+		"StrSkill11": "Fire Damage:",
+		"StrSkill12": "Radius: %d",
+		"StrSkill13": "yards",
+	}
+
+	lines := New(records, fireBall, 2, nil).Description(desc, func(key string) string { return strings[key] })
+	expected := []string{"Mana Cost: 12", "Fire Damage: 5-10", "Radius: 8 yards"}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("description is %q, want %q", lines, expected)
+	}
+
+	for idx := range lines {
+		if lines[idx] != expected[idx] {
+			t.Errorf("line %d is %q, want %q", idx, lines[idx], expected[idx])
+		}
+	}
+}
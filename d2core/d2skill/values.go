@@ -0,0 +1,106 @@
+package d2skill
+
+import (
+	"math"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2calculation"
+)
+
+const (
+	// FramesPerSecond is the number of frames of the durations of skills.txt in a second
+	FramesPerSecond = 25
+
+	// the mana costs and damages of skills.txt are in 256ths, shifted left by manashift and hitshift
+	fractionShift = 8
+)
+
+// AuraStat is a stat a skill gives to the units its state is put on
+type AuraStat struct {
+	Name  string
+	Value int
+}
+
+// ManaCost returns the mana it costs to use the skill
+func (s *Skill) ManaCost() float64 {
+	record := s.Record
+	cost := float64((record.Mana+record.Lvlmana*(s.Level-1))<<record.Manashift) / (1 << fractionShift)
+
+	return math.Max(cost, float64(record.Minmana))
+}
+
+// ToHit returns the attack rating the skill adds to the attack of the caster
+func (s *Skill) ToHit() int {
+	return s.Record.ToHit + s.Record.LevToHit*(s.Level-1) + s.Eval(s.Record.ToHitCalc)
+}
+
+// Damage returns the physical damage range of the skill with the synergies of the caster
+func (s *Skill) Damage() (minDamage, maxDamage int) {
+	record := s.Record
+	minLevel := [5]int{record.MinLevDam1, record.MinLevDam2, record.MinLevDam3, record.MinLevDam4, record.MinLevDam5}
+	maxLevel := [5]int{record.MaxLevDam1, record.MaxLevDam2, record.MaxLevDam3, record.MaxLevDam4, record.MaxLevDam5}
+	synergy := s.Eval(record.DmgSymPerCalc)
+
+	return s.points(LevelDamage(record.MinDam, minLevel, s.Level), synergy),
+		s.points(LevelDamage(record.MaxDam, maxLevel, s.Level), synergy)
+}
+
+// ElementalDamage returns the elemental damage range of the skill with the synergies of the
+// caster, the damage over time is the damage of the whole duration
+func (s *Skill) ElementalDamage() (minDamage, maxDamage int) {
+	record := s.Record
+	minLevel := [5]int{record.EMinLev1, record.EMinLev2, record.EMinLev3, record.EMinLev4, record.EMinLev5}
+	maxLevel := [5]int{record.EMaxLev1, record.EMaxLev2, record.EMaxLev3, record.EMaxLev4, record.EMaxLev5}
+	synergy := s.Eval(record.EDmgSymPerCalc)
+
+	return s.points(LevelDamage(record.EMin, minLevel, s.Level), synergy),
+		s.points(LevelDamage(record.EMax, maxLevel, s.Level), synergy)
+}
+
+// ElementalLength returns how many frames the elemental damage of the skill lasts
+func (s *Skill) ElementalLength() int {
+	record := s.Record
+	length := record.ELen + LevelDuration([3]int{record.ELevLen1, record.ELevLen2, record.ELevLen3}, s.Level)
+
+	return length * (percent + s.Eval(record.ELenSymPerCalc)) / percent
+}
+
+// Duration returns how many seconds the state of the skill lasts, 0 for the states which last
+// until they are removed
+func (s *Skill) Duration() float64 {
+	return float64(s.Eval(s.Record.Auralencalc)) / FramesPerSecond
+}
+
+// Radius returns the radius, in sub tiles, of the area the state of the skill is put on the units
+func (s *Skill) Radius() float64 {
+	return float64(s.Eval(s.Record.Aurarangecalc))
+}
+
+// AuraStats returns the stats the state of the skill gives, the stats without a name are left out
+func (s *Skill) AuraStats() []AuraStat {
+	record := s.Record
+	stats := make([]AuraStat, 0)
+
+	for _, stat := range []struct {
+		name string
+		calc d2calculation.Calculation
+	}{
+		{record.Aurastat1, record.Aurastatcalc1},
+		{record.Aurastat2, record.Aurastatcalc2},
+		{record.Aurastat3, record.Aurastatcalc3},
+		{record.Aurastat4, record.Aurastatcalc4},
+		{record.Aurastat5, record.Aurastatcalc5},
+		{record.Aurastat6, record.Aurastatcalc6},
+	} {
+		if stat.name != "" {
+			stats = append(stats, AuraStat{Name: stat.name, Value: s.Eval(stat.calc)})
+		}
+	}
+
+	return stats
+}
+
+// points converts a damage of skills.txt to points of damage and adds the synergy bonus, in
+// percent
+func (s *Skill) points(damage, synergy int) int {
+	return (damage << s.Record.HitShift >> fractionShift) * (percent + synergy) / percent
+}
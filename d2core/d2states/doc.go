@@ -0,0 +1,5 @@
+// Package d2states keeps track of the states.txt states active on a unit: buffs, curses, auras
+// and the poison and chill of attacks. States last for a while or until they are removed, a
+// state replaces the states of its group and the curse on the unit, and the stats of the states
+// are added to the stats of the unit.
+package d2states
@@ -0,0 +1,44 @@
+package d2states
+
+import (
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+)
+
+// Forever is the duration of the states which last until they are removed
+const Forever = -1.0
+
+// State is a state active on a unit
+type State struct {
+	Record    *d2records.StateRecord
+	SourceID  string           // the unit which put the state on the unit
+	Stats     d2stats.StatList // added to the stats of the unit, may be nil
+	Remaining float64          // seconds the state lasts, Forever for states without a duration
+}
+
+// NewState creates a state of the given record which lasts the given number of seconds
+func NewState(record *d2records.StateRecord, sourceID string, duration float64) *State {
+	return &State{Record: record, SourceID: sourceID, Remaining: duration}
+}
+
+// Name returns the states.txt name of the state
+func (s *State) Name() string {
+	return s.Record.State
+}
+
+// Timed returns true if the state expires on its own
+func (s *State) Timed() bool {
+	return s.Remaining != Forever
+}
+
+// excludes returns true if the state cannot be active together with the other state
+func (s *State) excludes(other *State) bool {
+	switch {
+	case s.Name() == other.Name():
+		return true
+	case s.Record.Group != 0 && s.Record.Group == other.Record.Group:
+		return true
+	default:
+		return s.Record.Curse && other.Record.Curse
+	}
+}
@@ -0,0 +1,127 @@
+package d2states
+
+import (
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+)
+
+// States are the states active on a unit
+type States struct {
+	active map[string]*State
+}
+
+// NewStates creates an empty set of states
+func NewStates() *States {
+	return &States{active: make(map[string]*State)}
+}
+
+// Apply makes the state active and returns the states it replaced: the state of the same name,
+// the states of its group and, for a curse, the curse on the unit
+func (s *States) Apply(state *State) []*State {
+	replaced := make([]*State, 0)
+
+	for _, current := range s.Active() {
+		if state.excludes(current) {
+			delete(s.active, current.Name())
+			replaced = append(replaced, current)
+		}
+	}
+
+	s.active[state.Name()] = state
+
+	return replaced
+}
+
+// Remove removes the state with the given name, it returns nil if the state is not active
+func (s *States) Remove(name string) *State {
+	state, found := s.active[name]
+	if !found {
+		return nil
+	}
+
+	delete(s.active, name)
+
+	return state
+}
+
+// RemoveIf removes the states the given function returns true for and returns them ordered by
+// name, e.g. the cureable states or the states which do not stay on a dead unit
+func (s *States) RemoveIf(remove func(state *State) bool) []*State {
+	removed := make([]*State, 0)
+
+	for _, state := range s.Active() {
+		if remove(state) {
+			delete(s.active, state.Name())
+			removed = append(removed, state)
+		}
+	}
+
+	return removed
+}
+
+// Get returns the active state with the given name, or nil if the state is not active
+func (s *States) Get(name string) *State {
+	return s.active[name]
+}
+
+// Has returns true if the state with the given name is active
+func (s *States) Has(name string) bool {
+	_, found := s.active[name]
+
+	return found
+}
+
+// Active returns the active states ordered by name
+func (s *States) Active() []*State {
+	states := make([]*State, 0, len(s.active))
+
+	for _, state := range s.active {
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name() < states[j].Name()
+	})
+
+	return states
+}
+
+// Advance counts down the durations of the timed states and returns the states which expired,
+// ordered by name
+func (s *States) Advance(elapsed float64) []*State {
+	return s.RemoveIf(func(state *State) bool {
+		if !state.Timed() {
+			return false
+		}
+
+		state.Remaining -= elapsed
+
+		return state.Remaining <= 0
+	})
+}
+
+// StatList returns the given stats with the stats of the active states added. The given list is
+// not changed, a nil list is returned if neither the list nor the states have stats.
+func (s *States) StatList(base d2stats.StatList) d2stats.StatList {
+	merged := base
+
+	for _, state := range s.Active() {
+		if state.Stats == nil || len(state.Stats.Stats()) == 0 {
+			continue
+		}
+
+		if merged == nil {
+			merged = state.Stats.Clone()
+			continue
+		}
+
+		if merged == base {
+			merged = base.Clone()
+		}
+
+		merged.AppendStatList(state.Stats.Clone())
+	}
+
+	return merged
+}
@@ -0,0 +1,156 @@
+package d2states
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+)
+
+type testStat struct {
+	d2stats.Stat
+	name string
+}
+
+func (s *testStat) Name() string {
+	return s.name
+}
+
+func (s *testStat) Clone() d2stats.Stat {
+	return &testStat{name: s.name}
+}
+
+type testStatList struct {
+	d2stats.StatList
+	stats []d2stats.Stat
+}
+
+func (l *testStatList) Stats() []d2stats.Stat {
+	return l.stats
+}
+
+func (l *testStatList) Clone() d2stats.StatList {
+	clone := &testStatList{}
+
+	for _, stat := range l.stats {
+		clone.stats = append(clone.stats, stat.Clone())
+	}
+
+	return clone
+}
+
+func (l *testStatList) AppendStatList(other d2stats.StatList) d2stats.StatList {
+	l.stats = append(l.stats, other.Stats()...)
+
+	return l
+}
+
+func testStats(names ...string) d2stats.StatList {
+	list := &testStatList{}
+
+	for _, name := range names {
+		list.stats = append(list.stats, &testStat{name: name})
+	}
+
+	return list
+}
+
+func names(states []*State) []string {
+	result := make([]string, len(states))
+
+	for idx, state := range states {
+		result[idx] = state.Name()
+	}
+
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestApplyReplacesGroupAndCurse(t *testing.T) {
+	states := NewStates()
+
+	states.Apply(NewState(&d2records.StateRecord{State: "amplifydamage", Curse: true}, "", 5))
+	states.Apply(NewState(&d2records.StateRecord{State: "battleorders", Group: 1}, "", Forever))
+	states.Apply(NewState(&d2records.StateRecord{State: "poison"}, "", 2))
+
+	replaced := states.Apply(NewState(&d2records.StateRecord{State: "weaken", Curse: true}, "", 5))
+	if !equal(names(replaced), []string{"amplifydamage"}) {
+		t.Errorf("curse replaced %v, want [amplifydamage]", names(replaced))
+	}
+
+	replaced = states.Apply(NewState(&d2records.StateRecord{State: "shout", Group: 1}, "", Forever))
+	if !equal(names(replaced), []string{"battleorders"}) {
+		t.Errorf("state of group 1 replaced %v, want [battleorders]", names(replaced))
+	}
+
+	replaced = states.Apply(NewState(&d2records.StateRecord{State: "poison"}, "", 3))
+	if !equal(names(replaced), []string{"poison"}) {
+		t.Errorf("poison replaced %v, want [poison]", names(replaced))
+	}
+
+	if got := names(states.Active()); !equal(got, []string{"poison", "shout", "weaken"}) {
+		t.Errorf("active states are %v, want [poison shout weaken]", got)
+	}
+}
+
+func TestAdvanceExpiresTimedStates(t *testing.T) {
+	states := NewStates()
+
+	states.Apply(NewState(&d2records.StateRecord{State: "cold"}, "", 1))
+	states.Apply(NewState(&d2records.StateRecord{State: "poison"}, "", 2))
+	states.Apply(NewState(&d2records.StateRecord{State: "aura"}, "", Forever))
+
+	if expired := states.Advance(1.5); !equal(names(expired), []string{"cold"}) {
+		t.Errorf("expired %v after 1.5 s, want [cold]", names(expired))
+	}
+
+	if expired := states.Advance(1); !equal(names(expired), []string{"poison"}) {
+		t.Errorf("expired %v after 2.5 s, want [poison]", names(expired))
+	}
+
+	if !states.Has("aura") || len(states.Active()) != 1 {
+		t.Errorf("active states are %v, want [aura]", names(states.Active()))
+	}
+}
+
+func TestStatList(t *testing.T) {
+	states := NewStates()
+
+	if list := states.StatList(nil); list != nil {
+		t.Errorf("stat list without stats is %v, want nil", list)
+	}
+
+	frozen := NewState(&d2records.StateRecord{State: "frozenarmor"}, "", Forever)
+	frozen.Stats = testStats("item_armor_percent")
+	states.Apply(frozen)
+
+	base := testStats("dexterity", "tohit")
+	merged := states.StatList(base)
+
+	if got := len(merged.Stats()); got != 3 {
+		t.Errorf("merged stat list has %d stats, want 3", got)
+	}
+
+	if got := len(base.Stats()); got != 2 {
+		t.Errorf("base stat list has %d stats after the merge, want 2", got)
+	}
+
+	states.Remove("frozenarmor")
+
+	if merged := states.StatList(base); len(merged.Stats()) != 2 {
+		t.Errorf("stat list without states has %d stats, want 2", len(merged.Stats()))
+	}
+}
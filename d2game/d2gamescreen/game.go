@@ -16,6 +16,8 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2audio"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hireling"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2maprenderer"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2screen"
@@ -31,6 +33,9 @@ const (
 	bindControlsErrStr = "failed to add gameControls as input handler for player: %s\n"
 	castErrStr         = "failed to send CastSkill packet to the server, playerId: %s, skillId: %d, x: %g, x: %g\n"
 	spawnItemErrStr    = "failed to send SpawnItem packet to the server: (%d, %d) %+v"
+	waypointErrStr     = "failed to send WarpPlayer packet to the server, playerId: %s, levelId: %d\n"
+	tradeErrStr        = "failed to send Trade packet to the server, playerId: %s, action: %d, itemId: %s\n"
+	hireErrStr         = "failed to send Hire packet to the server, playerId: %s, action: %d, id: %s\n"
 )
 
 const (
@@ -142,6 +147,7 @@ func (v *Game) OnLoad(_ d2screen.LoadingState) {
 		{"spawnitemat", "spawns an item at the x,y coordinates",
 			[]string{"x", "y", "code1", "code2", "code3", "code4", "code5"}, v.commandSpawnItemAt},
 		{"spawnmon", "spawn monster at the local player position", []string{"name"}, v.commandSpawnMon},
+		{"waypoint", "uses the nearby waypoint to travel to a level", []string{"levelId"}, v.commandWaypoint},
 	}
 
 	for _, cmd := range commands {
@@ -171,7 +177,7 @@ func (v *Game) OnUnload() error {
 		return err
 	}
 
-	if err := v.terminal.Unbind("spawnitemat", "spawnitem", "spawnmon"); err != nil {
+	if err := v.terminal.Unbind("spawnitemat", "spawnitem", "spawnmon", "waypoint"); err != nil {
 		return err
 	}
 
@@ -231,6 +237,18 @@ func (v *Game) Advance(elapsed float64) error {
 	}
 
 	if v.gameControls != nil {
+		if v.gameClient.ShopChanged {
+			v.gameClient.ShopChanged = false
+			v.showTrade()
+		}
+
+		if v.gameClient.HirelingChanged {
+			v.gameClient.HirelingChanged = false
+			v.showHireling()
+		}
+
+		v.gameControls.SetAutomap(v.gameClient.Automap)
+
 		if err := v.gameControls.Advance(elapsed); err != nil {
 			return err
 		}
@@ -315,12 +333,16 @@ func (v *Game) OnPlayerMove(targetX, targetY float64) {
 	worldPosition := v.localPlayer.Position.World()
 
 	playerID, worldX, worldY := v.gameClient.PlayerID, worldPosition.X(), worldPosition.Y()
+	running := v.localPlayer.IsRunning()
 
-	createMovePlayerPacket, err := d2netpacket.CreateMovePlayerPacket(playerID, worldX, worldY, targetX, targetY)
+	createMovePlayerPacket, err := d2netpacket.CreateMovePlayerPacket(playerID, worldX, worldY, targetX, targetY, running)
 	if err != nil {
 		v.Errorf("MovePlayerPacket: %v", err)
 	}
 
+	// the server only confirms the move with its state deltas, so the move is predicted
+	v.gameClient.PredictMovePlayer(targetX, targetY)
+
 	err = v.gameClient.SendPacketToServer(createMovePlayerPacket)
 
 	if err != nil {
@@ -359,6 +381,183 @@ func (v *Game) OnPlayerCast(skillID int, targetX, targetY float64) {
 	}
 }
 
+// OnPlayerTrade asks the server to trade with the vendor of the open shop. Without an item, the
+// vendor is asked for its offers of the trade mode, or to repair every item.
+func (v *Game) OnPlayerTrade(mode d2player.TradeMode, itemID string) {
+	action := d2netpacket.TradeBuy
+
+	switch mode {
+	case d2player.TradeModeBuy:
+		if itemID == "" {
+			action = d2netpacket.TradeOpen
+		}
+	case d2player.TradeModeSell:
+		action = d2netpacket.TradeSell
+	case d2player.TradeModeRepair:
+		action = d2netpacket.TradeRepair
+	case d2player.TradeModeGamble:
+		action = d2netpacket.TradeGamble
+
+		if itemID == "" {
+			action = d2netpacket.TradeOpenGamble
+		}
+	case d2player.TradeModeHire:
+		shop := v.gameClient.Shop
+		if shop == nil {
+			return
+		}
+
+		if err := v.gameClient.RequestHireOffers(shop.Vendor, shop.X, shop.Y); err != nil {
+			v.Errorf(hireErrStr, v.gameClient.PlayerID, d2netpacket.HireOffers, "")
+		}
+
+		return
+	}
+
+	if err := v.gameClient.Trade(action, itemID); err != nil {
+		v.Errorf(tradeErrStr, v.gameClient.PlayerID, action, itemID)
+	}
+}
+
+// OnPlayerCloseTrade closes the shop the local player trades with
+func (v *Game) OnPlayerCloseTrade() {
+	v.gameClient.CloseShop()
+}
+
+// showTrade shows the shop the local player trades with on the trade panel
+func (v *Game) showTrade() {
+	shop := v.gameClient.Shop
+	if shop == nil {
+		return
+	}
+
+	state := &d2player.TradeState{
+		Vendor:    shop.Vendor,
+		Gambling:  shop.Gambling,
+		CanGamble: diablo2item.CanGamble(shop.Vendor),
+		CanRepair: diablo2item.CanRepair(shop.Vendor),
+		CanHire:   d2hireling.SellerAct(shop.Vendor) != 0,
+		Offers:    tradeOffers(shop.Offers),
+		Owned:     tradeOffers(v.gameClient.SellOffers()),
+		Worn:      tradeOffers(v.gameClient.RepairOffers()),
+	}
+
+	if v.gameClient.GameState != nil {
+		state.Gold = v.gameClient.GameState.Gold
+	}
+
+	v.gameControls.ShowTrade(state)
+}
+
+// OnPlayerHire asks the server to do the given hire action for the local player
+func (v *Game) OnPlayerHire(action d2player.HireAction, id string, slot d2enum.EquippedSlot) {
+	var err error
+
+	hireAction := d2netpacket.HireHire
+
+	switch action {
+	case d2player.HireActionHire:
+		err = v.gameClient.Hire(hireAction, id)
+	case d2player.HireActionResurrect:
+		hireAction = d2netpacket.HireResurrect
+		err = v.gameClient.Hire(hireAction, id)
+	case d2player.HireActionEquip:
+		hireAction = d2netpacket.HireEquip
+
+		if item := v.gameClient.Inventory[id]; item != nil {
+			err = v.gameClient.EquipHireling(id, v.gameClient.HirelingSlot(item))
+		}
+	case d2player.HireActionUnequip:
+		hireAction = d2netpacket.HireUnequip
+		err = v.gameClient.EquipHireling("", slot)
+	}
+
+	if err != nil {
+		v.Errorf(hireErrStr, v.gameClient.PlayerID, hireAction, id)
+	}
+}
+
+// OnPlayerCloseHire forgets the offers of the seller the local player hires from
+func (v *Game) OnPlayerCloseHire() {
+	v.gameClient.CloseHireOffers()
+	v.showHireling()
+}
+
+// showHireling shows the hireling of the local player, or the offers of the seller it hires
+// from, on the hireling panel
+func (v *Game) showHireling() {
+	state := &d2player.HirelingState{
+		Equipment: make(map[d2enum.EquippedSlot]*diablo2item.Item),
+		Wearable:  tradeOffers(v.gameClient.WearableItems()),
+	}
+
+	if player := v.gameClient.Players[v.gameClient.PlayerID]; player != nil {
+		state.Gold = player.Gold
+	}
+
+	if hireling := v.gameClient.Hireling; hireling != nil {
+		stats := hireling.Stats()
+
+		state.Hired = true
+		state.Name = v.asset.TranslateString(hireling.Name())
+		state.Type = hireling.Record.Hireling
+		state.Level = hireling.State.Level
+		state.Experience = hireling.State.Experience
+		state.NextExperience = hireling.Record.ExperienceForLevel(hireling.State.Level + 1)
+		state.Life = hireling.State.Life
+		state.MaxLife = stats.MaxLife
+		state.Dead = !hireling.Alive()
+		state.Strength = stats.Strength
+		state.Dexterity = stats.Dexterity
+		state.Defense = stats.Defense
+		state.AttackRating = stats.AttackRating
+		state.MinDamage = stats.MinDamage
+		state.MaxDamage = stats.MaxDamage
+		state.Resistance = stats.Resistance
+
+		for _, slot := range []d2enum.EquippedSlot{
+			d2enum.EquippedSlotHead,
+			d2enum.EquippedSlotTorso,
+			d2enum.EquippedSlotRightArm,
+			d2enum.EquippedSlotLeftArm,
+		} {
+			if item := v.gameClient.HirelingItem(slot); item != nil {
+				state.Equipment[slot] = item
+			}
+		}
+	}
+
+	if offers := v.gameClient.HireOffers; offers != nil && len(offers.Offers)+offers.ResurrectPrice > 0 {
+		state.Seller = offers.Seller
+		state.ResurrectPrice = offers.ResurrectPrice
+
+		for idx := range offers.Offers {
+			hireling := offers.Offers[idx].Hireling
+
+			state.Offers = append(state.Offers, d2player.HirelingOffer{
+				ID:    offers.Offers[idx].ID,
+				Name:  v.asset.TranslateString(hireling.Name()),
+				Type:  hireling.Record.Hireling,
+				Level: hireling.State.Level,
+				Life:  hireling.Stats().MaxLife,
+				Price: offers.Offers[idx].Price,
+			})
+		}
+	}
+
+	v.gameControls.ShowHireling(state)
+}
+
+func tradeOffers(offers []d2client.ShopOffer) []d2player.TradeOffer {
+	result := make([]d2player.TradeOffer, len(offers))
+
+	for idx := range offers {
+		result[idx] = d2player.TradeOffer{ID: offers[idx].ID, Item: offers[idx].Item, Price: offers[idx].Price}
+	}
+
+	return result
+}
+
 func (v *Game) debugSpawnItemAtPlayer(codes ...string) {
 	if v.localPlayer == nil {
 		return
@@ -416,13 +615,34 @@ func (v *Game) commandSpawnMon(args []string) error {
 		return nil
 	}
 
-	monster, npcErr := v.gameClient.MapEngine.NewNPC(x, y, monstat, 0)
-	if npcErr != nil {
-		v.terminal.Errorf("error generating monster \"%s\": %v", name, npcErr)
-		return nil
+	// the server spawns the monster and its minions, the client creates them when the server
+	// sends them back
+	packet, err := d2netpacket.CreateSpawnMonsterPacket("", name, x, y)
+	if err != nil {
+		return err
 	}
 
-	v.gameClient.MapEngine.AddEntity(monster)
+	if err := v.gameClient.SendPacketToServer(packet); err != nil {
+		v.terminal.Errorf("error spawning monster \"%s\": %v", name, err)
+	}
+
+	return nil
+}
+
+func (v *Game) commandWaypoint(args []string) error {
+	levelID, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid argument")
+	}
+
+	packet, err := d2netpacket.CreateWarpPlayerPacket(v.gameClient.PlayerID, levelID, true)
+	if err != nil {
+		return err
+	}
+
+	if err := v.gameClient.SendPacketToServer(packet); err != nil {
+		v.Errorf(waypointErrStr, v.gameClient.PlayerID, levelID)
+	}
 
 	return nil
 }
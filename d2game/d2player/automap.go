@@ -0,0 +1,297 @@
+package d2player
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2resource"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2automap"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2maprenderer"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2ui"
+)
+
+// automap modes, in the order the toggle key cycles through them
+const (
+	automapModeOff  = iota
+	automapModeFull // over the whole screen, centered on the hero
+	automapModeMini // in a small window at the top right of the screen
+	automapModeCount
+)
+
+const (
+	// a tile is drawn 1/5 of its size on the full automap and 1/10 on the mini automap
+	automapCellHalfWidth, automapCellHalfHeight = 16, 8
+	automapMiniScale                            = 0.5
+
+	automapMiniX, automapMiniY          = 560, 16
+	automapMiniWidth, automapMiniHeight = 224, 160
+
+	automapFadeBrightness = 0.5
+	automapMarkerSize     = 4
+	automapNameOffsetY    = 14
+)
+
+const (
+	automapPartyColor    = 0x00ff00ff
+	automapHeroColor     = 0xffffffff
+	automapWaypointColor = 0x4080ffff
+	automapExitColor     = 0xffff00ff
+	automapMiniBgColor   = 0x00000080
+)
+
+// automapPalettes are the palettes the cells of the acts are drawn with
+var automapPalettes = [...]string{ //nolint:gochecknoglobals // lookup table
+	1: d2resource.PaletteAct1,
+	2: d2resource.PaletteAct2,
+	3: d2resource.PaletteAct3,
+	4: d2resource.PaletteAct4,
+	5: d2resource.PaletteAct5,
+}
+
+// automapOverlay draws the explored tiles of the automap of the current map over the game
+type automapOverlay struct {
+	asset       *d2asset.AssetManager
+	uiManager   *d2ui.UIManager
+	mapEngine   *d2mapengine.MapEngine
+	mapRenderer *d2maprenderer.MapRenderer
+	hero        *d2mapentity.Player
+	automap     *d2automap.Automap
+	cells       map[int]*d2ui.Sprite // by act, nil if the act has no cells
+	nameLabel   *d2ui.Label
+	mode        int
+	showParty   bool
+	showNames   bool
+	faded       bool
+
+	*d2util.Logger
+}
+
+func newAutomapOverlay(asset *d2asset.AssetManager, ui *d2ui.UIManager, mapEngine *d2mapengine.MapEngine,
+	mapRenderer *d2maprenderer.MapRenderer, hero *d2mapentity.Player, l d2util.LogLevel) *automapOverlay {
+	overlay := &automapOverlay{
+		asset:       asset,
+		uiManager:   ui,
+		mapEngine:   mapEngine,
+		mapRenderer: mapRenderer,
+		hero:        hero,
+		cells:       make(map[int]*d2ui.Sprite),
+		showParty:   true,
+		showNames:   true,
+	}
+
+	overlay.Logger = d2util.NewLogger()
+	overlay.Logger.SetPrefix(logPrefix)
+	overlay.Logger.SetLevel(l)
+
+	return overlay
+}
+
+// Load creates the ui elements
+func (a *automapOverlay) Load() {
+	a.nameLabel = a.uiManager.NewLabel(d2resource.Font16, d2resource.PaletteStatic)
+	a.nameLabel.Alignment = d2ui.HorizontalAlignCenter
+}
+
+// SetAutomap sets the automap of the current map
+func (a *automapOverlay) SetAutomap(automap *d2automap.Automap) {
+	a.automap = automap
+}
+
+// IsOpen returns true if the automap is shown
+func (a *automapOverlay) IsOpen() bool {
+	return a.mode != automapModeOff
+}
+
+// Toggle shows the next mode of the automap
+func (a *automapOverlay) Toggle() {
+	a.mode = (a.mode + 1) % automapModeCount
+}
+
+// ToggleParty toggles the party members on the automap
+func (a *automapOverlay) ToggleParty() {
+	a.showParty = !a.showParty
+}
+
+// ToggleNames toggles the names of the party members on the automap
+func (a *automapOverlay) ToggleNames() {
+	a.showNames = !a.showNames
+}
+
+// ToggleFade toggles the brightness of the tiles of the automap
+func (a *automapOverlay) ToggleFade() {
+	a.faded = !a.faded
+}
+
+// Advance explores the tiles around the hero
+func (a *automapOverlay) Advance() {
+	if a.automap == nil || a.hero == nil {
+		return
+	}
+
+	a.automap.Explore(a.hero.GetPositionF())
+}
+
+// Render draws the automap in its current mode
+func (a *automapOverlay) Render(target d2interface.Surface) {
+	if a.automap == nil || a.hero == nil || a.mode == automapModeOff {
+		return
+	}
+
+	var (
+		centerX, centerY float64
+		scale            = 1.0
+		bounds           = [4]int{0, 0, screenWidth, screenHeight}
+	)
+
+	if a.mode == automapModeMini {
+		target.PushTranslation(automapMiniX, automapMiniY)
+		target.DrawRect(automapMiniWidth, automapMiniHeight, d2util.Color(automapMiniBgColor))
+		target.Pop()
+
+		centerX, centerY = automapMiniX+automapMiniWidth/2, automapMiniY+automapMiniHeight/2
+		scale = automapMiniScale
+		bounds = [4]int{automapMiniX, automapMiniY, automapMiniX + automapMiniWidth, automapMiniY + automapMiniHeight}
+	} else {
+		// the hero stays where the map renderer draws it, which moves when the side panels are open
+		centerX, centerY = a.mapRenderer.WorldToScreenF(a.hero.GetPositionF())
+	}
+
+	heroX, heroY := a.hero.GetPositionF()
+
+	project := func(tileX, tileY float64) (screenX, screenY int) {
+		x := ((tileX - heroX) - (tileY - heroY)) * automapCellHalfWidth * scale
+		y := ((tileX - heroX) + (tileY - heroY)) * automapCellHalfHeight * scale
+
+		return int(math.Floor(centerX + x)), int(math.Floor(centerY + y))
+	}
+
+	inBounds := func(x, y int) bool {
+		return x >= bounds[0] && y >= bounds[1] && x < bounds[2] && y < bounds[3]
+	}
+
+	a.renderCells(target, project, inBounds, scale)
+	a.renderMarkers(target, project, inBounds)
+}
+
+func (a *automapOverlay) renderCells(target d2interface.Surface, project func(x, y float64) (int, int),
+	inBounds func(x, y int) bool, scale float64) {
+	if a.faded {
+		target.PushBrightness(automapFadeBrightness)
+		defer target.Pop()
+	}
+
+	for _, cell := range a.automap.Cells() {
+		x, y := project(float64(cell.TileX), float64(cell.TileY))
+		if !inBounds(x, y) {
+			continue
+		}
+
+		sprite := a.cellSprite(cell.Act)
+		if sprite == nil {
+			continue
+		}
+
+		if err := sprite.SetCurrentFrame(cell.Frame); err != nil {
+			continue
+		}
+
+		target.PushTranslation(x, y)
+		target.PushScale(scale, scale)
+		sprite.Render(target)
+		target.PopN(2) //nolint:gomnd // translation and scale
+	}
+}
+
+func (a *automapOverlay) renderMarkers(target d2interface.Surface, project func(x, y float64) (int, int),
+	inBounds func(x, y int) bool) {
+	explored := a.automap.Explored()
+
+	for _, exit := range a.automap.Exits() {
+		if !explored.IsExplored(int(exit.X), int(exit.Y)) {
+			continue
+		}
+
+		x, y := project(exit.X, exit.Y)
+		if !inBounds(x, y) {
+			continue
+		}
+
+		a.renderMarker(target, x, y, automapExitColor)
+
+		if details := a.asset.Records.Level.Details[exit.LevelID]; details != nil {
+			a.renderName(target, x, y, details.LevelDisplayName)
+		}
+	}
+
+	for _, entity := range a.mapEngine.Entities() {
+		switch e := entity.(type) {
+		case *d2mapentity.Object:
+			tileX, tileY := e.GetPositionF()
+			if !e.IsWaypoint() || !explored.IsExplored(int(tileX), int(tileY)) {
+				continue
+			}
+
+			if x, y := project(tileX, tileY); inBounds(x, y) {
+				a.renderMarker(target, x, y, automapWaypointColor)
+			}
+		case *d2mapentity.Player:
+			if e != a.hero && !a.showParty {
+				continue
+			}
+
+			x, y := project(e.GetPositionF())
+			if !inBounds(x, y) {
+				continue
+			}
+
+			if e == a.hero {
+				a.renderMarker(target, x, y, automapHeroColor)
+				continue
+			}
+
+			a.renderMarker(target, x, y, automapPartyColor)
+
+			if a.showNames {
+				a.renderName(target, x, y, e.Name())
+			}
+		}
+	}
+}
+
+func (a *automapOverlay) renderMarker(target d2interface.Surface, x, y int, color uint32) {
+	target.PushTranslation(x-automapMarkerSize/2, y-automapMarkerSize/2)
+	target.DrawRect(automapMarkerSize, automapMarkerSize, d2util.Color(color))
+	target.Pop()
+}
+
+func (a *automapOverlay) renderName(target d2interface.Surface, x, y int, name string) {
+	a.nameLabel.SetText(name)
+	a.nameLabel.SetPosition(x, y-automapNameOffsetY)
+	a.nameLabel.Render(target)
+}
+
+// cellSprite returns the sprite with the automap cells of the given act, it is loaded the first
+// time it is needed
+func (a *automapOverlay) cellSprite(act int) *d2ui.Sprite {
+	if sprite, found := a.cells[act]; found {
+		return sprite
+	}
+
+	if act <= 0 || act >= len(automapPalettes) {
+		return nil
+	}
+
+	sprite, err := a.uiManager.NewSprite(fmt.Sprintf(d2resource.AutomapCells, act), automapPalettes[act])
+	if err != nil {
+		a.Errorf("could not load the automap cells of act %d: %v", act, err)
+	}
+
+	a.cells[act] = sprite
+
+	return sprite
+}
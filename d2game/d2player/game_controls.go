@@ -15,6 +15,7 @@ import (
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2automap"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2maprenderer"
@@ -209,6 +210,8 @@ func NewGameControls(
 
 	heroStatsPanel := NewHeroStatsPanel(asset, ui, hero.Name(), hero.Class, l, hero.Stats)
 	questLog := NewQuestLog(asset, ui, l, audioProvider, hero.Act)
+	tradePanel := NewTradePanel(asset, ui, l, inputListener.OnPlayerTrade)
+	hirelingPanel := NewHirelingPanel(asset, ui, l, inputListener.OnPlayerHire)
 
 	inventory, err := NewInventory(asset, ui, l, hero.Gold, inventoryRecord)
 	if err != nil {
@@ -241,6 +244,8 @@ func NewGameControls(
 		skilltree:      skilltree,
 		heroStatsPanel: heroStatsPanel,
 		questLog:       questLog,
+		tradePanel:     tradePanel,
+		hirelingPanel:  hirelingPanel,
 		HelpOverlay:    helpOverlay,
 		keyMap:         keyMap,
 		bottomMenuRect: &d2geom.Rectangle{
@@ -275,6 +280,8 @@ func NewGameControls(
 
 	gc.heroStatsPanel.SetOnCloseCb(gc.onCloseHeroStatsPanel)
 	gc.questLog.SetOnCloseCb(gc.onCloseQuestLog)
+	gc.tradePanel.SetOnCloseCb(gc.onCloseTradePanel)
+	gc.hirelingPanel.SetOnCloseCb(gc.onCloseHirelingPanel)
 	gc.inventory.SetOnCloseCb(gc.onCloseInventory)
 	gc.skilltree.SetOnCloseCb(gc.onCloseSkilltree)
 
@@ -310,6 +317,8 @@ type GameControls struct {
 	skilltree              *skillTree
 	heroStatsPanel         *HeroStatsPanel
 	questLog               *QuestLog
+	tradePanel             *TradePanel
+	hirelingPanel          *HirelingPanel
 	HelpOverlay            *HelpOverlay
 	bottomMenuRect         *d2geom.Rectangle
 	leftMenuRect           *d2geom.Rectangle
@@ -401,12 +410,22 @@ func (g *GameControls) OnKeyDown(event d2interface.KeyEvent) bool {
 		g.toggleHeroStatsPanel()
 	case d2enum.ToggleQuestLog:
 		g.toggleQuestLog()
+	case d2enum.ToggleHirelingPanel:
+		g.toggleHirelingPanel()
 	case d2enum.ToggleRunWalk:
 		g.hud.onToggleRunButton(false)
 	case d2enum.HoldRun:
 		g.hud.onToggleRunButton(true)
 	case d2enum.ToggleHelpScreen:
 		g.toggleHelpOverlay()
+	case d2enum.ToggleAutomap:
+		g.hud.automap.Toggle()
+	case d2enum.TogglePartyOnAutomap:
+		g.hud.automap.ToggleParty()
+	case d2enum.ToggleNamesOnAutomap:
+		g.hud.automap.ToggleNames()
+	case d2enum.FadeAutomap:
+		g.hud.automap.ToggleFade()
 	default:
 		return false
 	}
@@ -520,6 +539,10 @@ func (g *GameControls) OnMouseMove(event d2interface.MouseMoveEvent) bool {
 	g.lastMouseY = my
 	g.inventory.lastMouseX = mx
 	g.inventory.lastMouseY = my
+	g.tradePanel.lastMouseX = mx
+	g.tradePanel.lastMouseY = my
+	g.hirelingPanel.lastMouseX = mx
+	g.hirelingPanel.lastMouseY = my
 
 	for i := range g.actionableRegions {
 		// Mouse over a game control element
@@ -558,6 +581,16 @@ func (g *GameControls) OnMouseButtonDown(event d2interface.MouseEvent) bool {
 		return false
 	}
 
+	if g.tradePanel.IsOpen() && event.Button() == d2enum.MouseButtonLeft && g.tradePanel.HandleClick(mx, my) {
+		g.lastLeftBtnActionTime = d2util.Now()
+		return false
+	}
+
+	if g.hirelingPanel.IsOpen() && event.Button() == d2enum.MouseButtonLeft && g.hirelingPanel.HandleClick(mx, my) {
+		g.lastLeftBtnActionTime = d2util.Now()
+		return false
+	}
+
 	px, py := g.mapRenderer.ScreenToWorld(mx, my)
 	px = truncateFloat64(px)
 	py = truncateFloat64(py)
@@ -588,6 +621,8 @@ func (g *GameControls) OnMouseButtonDown(event d2interface.MouseEvent) bool {
 func (g *GameControls) clearLeftScreenSide() {
 	g.heroStatsPanel.Close()
 	g.questLog.Close()
+	g.tradePanel.Close()
+	g.hirelingPanel.Close()
 	g.hud.skillSelectMenu.ClosePanels()
 	g.hud.miniPanel.SetMovedRight(false)
 	g.updateLayout()
@@ -664,6 +699,37 @@ func (g *GameControls) toggleQuestLog() {
 func (g *GameControls) onCloseQuestLog() {
 }
 
+// ShowTrade shows the given trade on the trade panel, and opens the panel if it is closed
+func (g *GameControls) ShowTrade(state *TradeState) {
+	g.tradePanel.SetState(state)
+
+	if !g.tradePanel.IsOpen() {
+		g.openLeftPanel(g.tradePanel)
+	}
+}
+
+func (g *GameControls) onCloseTradePanel() {
+	g.inputListener.OnPlayerCloseTrade()
+}
+
+func (g *GameControls) toggleHirelingPanel() {
+	g.openLeftPanel(g.hirelingPanel)
+}
+
+// ShowHireling shows the given hireling on the hireling panel. The panel is opened when it shows
+// the offers of a seller.
+func (g *GameControls) ShowHireling(state *HirelingState) {
+	g.hirelingPanel.SetState(state)
+
+	if state.Seller != "" && !g.hirelingPanel.IsOpen() {
+		g.openLeftPanel(g.hirelingPanel)
+	}
+}
+
+func (g *GameControls) onCloseHirelingPanel() {
+	g.inputListener.OnPlayerCloseHire()
+}
+
 func (g *GameControls) toggleHelpOverlay() {
 	if !g.isRightPanelOpen() || g.isLeftPanelOpen() {
 		g.HelpOverlay.updateKeyMap(g.keyMap)
@@ -702,6 +768,8 @@ func (g *GameControls) Load() {
 	g.skilltree.load()
 	g.heroStatsPanel.Load()
 	g.questLog.Load()
+	g.tradePanel.Load()
+	g.hirelingPanel.Load()
 	g.HelpOverlay.Load()
 
 	g.loadAddButtons()
@@ -713,6 +781,7 @@ func (g *GameControls) Load() {
 		skilltreeToggle: g.toggleSkilltreePanel,
 		menuToggle:      g.openEscMenu,
 		questToggle:     g.toggleQuestLog,
+		automapToggle:   g.hud.automap.Toggle,
 	}
 	g.hud.miniPanel.load(miniPanelActions)
 }
@@ -750,7 +819,8 @@ func (g *GameControls) updateLayout() {
 }
 
 func (g *GameControls) isLeftPanelOpen() bool {
-	return g.heroStatsPanel.IsOpen() || g.questLog.IsOpen() || g.inventory.moveGoldPanel.IsOpen()
+	return g.heroStatsPanel.IsOpen() || g.questLog.IsOpen() || g.tradePanel.IsOpen() || g.hirelingPanel.IsOpen() ||
+		g.inventory.moveGoldPanel.IsOpen()
 }
 
 func (g *GameControls) isRightPanelOpen() bool {
@@ -795,6 +865,8 @@ func (g *GameControls) isInActiveMenusRect(px, py int) bool {
 
 // Render draws the GameControls onto the target
 func (g *GameControls) Render(target d2interface.Surface) error {
+	g.hud.automap.Render(target)
+
 	if err := g.renderPanels(target); err != nil {
 		return err
 	}
@@ -812,10 +884,17 @@ func (g *GameControls) Render(target d2interface.Surface) error {
 
 func (g *GameControls) renderPanels(target d2interface.Surface) error {
 	g.inventory.Render(target)
+	g.tradePanel.Render(target)
+	g.hirelingPanel.Render(target)
 
 	return nil
 }
 
+// SetAutomap sets the automap of the map the hero is on
+func (g *GameControls) SetAutomap(automap *d2automap.Automap) {
+	g.hud.automap.SetAutomap(automap)
+}
+
 // SetZoneChangeText sets the zoneChangeText
 func (g *GameControls) SetZoneChangeText(text string) {
 	g.hud.zoneChangeText.SetText(text)
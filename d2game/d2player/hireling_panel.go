@@ -0,0 +1,555 @@
+package d2player
+
+import (
+	"fmt"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2resource"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2ui"
+)
+
+const (
+	hirelingOffsetX, hirelingOffsetY = 80, 64
+)
+
+const (
+	hirelingCloseButtonX, hirelingCloseButtonY         = 358, 455
+	hirelingEquipButtonX, hirelingEquipButtonY         = 96, 455
+	hirelingResurrectButtonX, hirelingResurrectButtonY = 236, 455
+	hirelingTitleLabelX, hirelingTitleLabelY           = 240, 80
+	hirelingInfoLabelX, hirelingInfoLabelY             = 100, 110
+	hirelingGoldLabelX, hirelingGoldLabelY             = 300, 430
+	hirelingOfferX, hirelingOfferY                     = 100, 140
+	hirelingOfferWidth, hirelingOfferHeight            = 280, 60
+)
+
+// hirelingOfferCount is how many offers of a seller the hireling panel shows
+const hirelingOfferCount = 4
+
+// hirelingRecordKey is the row of inventory.txt with the equipment slots of the hireling screen
+const hirelingRecordKey = "Hireling2"
+
+// HireAction is what the player asks to do with a hireling on the hireling panel
+type HireAction int
+
+// Hire actions
+const (
+	HireActionHire      HireAction = iota // hire the offer of a seller
+	HireActionResurrect                   // resurrect the dead hireling at a seller
+	HireActionEquip                       // give an item of the player to the hireling
+	HireActionUnequip                     // take the item in a slot off the hireling
+)
+
+// hireling panel pages
+const (
+	hirelingPageHireling = iota // the stats and the equipment of the hireling
+	hirelingPageEquip           // the items of the player the hireling can wear
+	hirelingPageOffers          // the hirelings of a seller
+)
+
+// HirelingOffer is a hireling a seller offers on the hireling panel
+type HirelingOffer struct {
+	ID    string // ID the server knows the offer by
+	Name  string
+	Type  string // what kind of hireling it is
+	Level int
+	Life  int
+	Price int
+}
+
+// HirelingState is what the hireling panel shows
+type HirelingState struct {
+	Hired          bool // the player has a hireling
+	Name           string
+	Type           string
+	Level          int
+	Experience     int
+	NextExperience int // experience the hireling needs for the next level
+	Life           int
+	MaxLife        int
+	Dead           bool
+	Strength       int
+	Dexterity      int
+	Defense        int
+	AttackRating   int
+	MinDamage      int
+	MaxDamage      int
+	Resistance     int
+	Equipment      map[d2enum.EquippedSlot]*diablo2item.Item
+	Wearable       []TradeOffer // the items of the player the hireling can wear
+	Seller         string       // row of the seller in monstats.txt, empty if no seller is asked
+	Offers         []HirelingOffer
+	ResurrectPrice int // 0 if the hireling is alive
+	Gold           int
+}
+
+// NewHirelingPanel creates the hireling panel, the screen of the hireling of the player and of
+// the hirelings of the sellers
+func NewHirelingPanel(asset *d2asset.AssetManager,
+	ui *d2ui.UIManager,
+	l d2util.LogLevel,
+	onHire func(action HireAction, id string, slot d2enum.EquippedSlot)) *HirelingPanel {
+	itemTooltip := ui.NewTooltip(d2resource.FontFormal11, d2resource.PaletteStatic, d2ui.TooltipXCenter, d2ui.TooltipYBottom)
+
+	hp := &HirelingPanel{
+		asset:       asset,
+		uiManager:   ui,
+		itemTooltip: itemTooltip,
+		onHire:      onHire,
+		state:       &HirelingState{},
+	}
+
+	hp.Logger = d2util.NewLogger()
+	hp.Logger.SetLevel(l)
+	hp.Logger.SetPrefix(logPrefix)
+
+	if record := asset.Records.Layout.Inventory[hirelingRecordKey]; record != nil {
+		hp.equipment = NewItemGrid(asset, ui, l, record)
+	} else {
+		hp.Warningf("no inventory layout for the hireling screen (%s)", hirelingRecordKey)
+	}
+
+	if record := asset.Records.Layout.Inventory[tradeRecordKey]; record != nil {
+		hp.wearable = NewItemGrid(asset, ui, l, record)
+	}
+
+	return hp
+}
+
+// HirelingPanel is the screen of the hireling of the player, it shows the hireling with its
+// equipment, the items it can wear or the hirelings a seller offers
+type HirelingPanel struct {
+	asset           *d2asset.AssetManager
+	uiManager       *d2ui.UIManager
+	panel           *d2ui.Sprite
+	panelGroup      *d2ui.WidgetGroup
+	equipment       *ItemGrid
+	wearable        *ItemGrid
+	itemTooltip     *d2ui.Tooltip
+	titleLabel      *d2ui.Label
+	infoLabel       *d2ui.Label
+	goldLabel       *d2ui.Label
+	offerLabels     []*d2ui.Label
+	equipButton     *d2ui.Button
+	resurrectButton *d2ui.Button
+	onHire          func(action HireAction, id string, slot d2enum.EquippedSlot)
+	onCloseCb       func()
+	state           *HirelingState
+	page            int
+	lastMouseX      int
+	lastMouseY      int
+	isOpen          bool
+
+	*d2util.Logger
+}
+
+// Load the resources required by the hireling panel
+func (s *HirelingPanel) Load() {
+	var err error
+
+	s.panelGroup = s.uiManager.NewWidgetGroup(d2ui.RenderPriorityInventory)
+
+	frame := d2ui.NewUIFrame(s.asset, s.uiManager, d2ui.FrameLeft)
+	s.panelGroup.AddWidget(frame)
+
+	s.panel, err = s.uiManager.NewSprite(d2resource.HirelingPanel, d2resource.PaletteSky)
+	if err != nil {
+		s.Error(err.Error())
+	}
+
+	closeButton := s.uiManager.NewButton(d2ui.ButtonTypeSquareClose, "")
+	closeButton.SetVisible(false)
+	closeButton.SetPosition(hirelingCloseButtonX, hirelingCloseButtonY)
+	closeButton.OnActivated(func() { s.Close() })
+	s.panelGroup.AddWidget(closeButton)
+
+	s.equipButton = s.uiManager.NewButton(d2ui.ButtonTypeShort, s.asset.TranslateString("Items"))
+	s.equipButton.SetVisible(false)
+	s.equipButton.SetPosition(hirelingEquipButtonX, hirelingEquipButtonY)
+	s.equipButton.OnActivated(s.togglePage)
+	s.panelGroup.AddWidget(s.equipButton)
+
+	s.resurrectButton = s.uiManager.NewButton(d2ui.ButtonTypeShort, s.asset.TranslateString("Resurrect"))
+	s.resurrectButton.SetVisible(false)
+	s.resurrectButton.SetPosition(hirelingResurrectButtonX, hirelingResurrectButtonY)
+	s.resurrectButton.OnActivated(func() { s.onHire(HireActionResurrect, "", d2enum.EquippedSlotNone) })
+	s.panelGroup.AddWidget(s.resurrectButton)
+
+	s.titleLabel = s.uiManager.NewLabel(d2resource.Font16, d2resource.PaletteStatic)
+	s.titleLabel.Alignment = d2ui.HorizontalAlignCenter
+	s.titleLabel.Color[0] = d2util.Color(white)
+	s.titleLabel.SetPosition(hirelingTitleLabelX, hirelingTitleLabelY)
+	s.panelGroup.AddWidget(s.titleLabel)
+
+	s.infoLabel = s.uiManager.NewLabel(d2resource.FontFormal11, d2resource.PaletteStatic)
+	s.infoLabel.Alignment = d2ui.HorizontalAlignLeft
+	s.infoLabel.SetPosition(hirelingInfoLabelX, hirelingInfoLabelY)
+	s.panelGroup.AddWidget(s.infoLabel)
+
+	s.goldLabel = s.uiManager.NewLabel(d2resource.Font16, d2resource.PaletteStatic)
+	s.goldLabel.Alignment = d2ui.HorizontalAlignLeft
+	s.goldLabel.SetPosition(hirelingGoldLabelX, hirelingGoldLabelY)
+	s.panelGroup.AddWidget(s.goldLabel)
+
+	s.offerLabels = make([]*d2ui.Label, hirelingOfferCount)
+
+	for idx := range s.offerLabels {
+		s.offerLabels[idx] = s.uiManager.NewLabel(d2resource.FontFormal11, d2resource.PaletteStatic)
+		s.offerLabels[idx].Alignment = d2ui.HorizontalAlignLeft
+		s.offerLabels[idx].SetPosition(hirelingOfferX, hirelingOfferY+idx*hirelingOfferHeight)
+		s.panelGroup.AddWidget(s.offerLabels[idx])
+	}
+
+	s.panelGroup.SetVisible(false)
+}
+
+// SetState shows the given hireling on the panel. The panel shows the offers of a seller when
+// one is asked, and the hireling otherwise.
+func (s *HirelingPanel) SetState(state *HirelingState) {
+	switch {
+	case state.Seller != "":
+		s.page = hirelingPageOffers
+	case s.page == hirelingPageOffers:
+		s.page = hirelingPageHireling
+	}
+
+	s.state = state
+	s.goldLabel.SetText(fmt.Sprintln(state.Gold))
+
+	s.equipButton.SetVisible(s.isOpen && state.Hired && state.Seller == "")
+	s.resurrectButton.SetVisible(s.isOpen && state.Seller != "" && state.ResurrectPrice > 0)
+
+	s.setPage(s.page)
+}
+
+// togglePage switches between the hireling and the items it can wear
+func (s *HirelingPanel) togglePage() {
+	if s.page == hirelingPageHireling {
+		s.setPage(hirelingPageEquip)
+	} else {
+		s.setPage(hirelingPageHireling)
+	}
+}
+
+func (s *HirelingPanel) setPage(page int) {
+	s.page = page
+
+	for _, label := range s.offerLabels {
+		label.SetText("")
+	}
+
+	switch page {
+	case hirelingPageOffers:
+		s.titleLabel.SetText(s.sellerName())
+		s.infoLabel.SetText(s.resurrectText())
+		s.loadOffers()
+	case hirelingPageEquip:
+		s.titleLabel.SetText(s.state.Name)
+		s.infoLabel.SetText("")
+		s.loadWearable()
+	default:
+		s.titleLabel.SetText(s.state.Name)
+		s.infoLabel.SetText(s.hirelingText())
+		s.loadEquipment()
+	}
+}
+
+func (s *HirelingPanel) sellerName() string {
+	record := s.asset.Records.Monster.Stats[s.state.Seller]
+	if record == nil {
+		return s.state.Seller
+	}
+
+	return s.asset.TranslateString(record.NameString)
+}
+
+func (s *HirelingPanel) resurrectText() string {
+	if s.state.ResurrectPrice == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Resurrect %s: %d", s.state.Name, s.state.ResurrectPrice)
+}
+
+func (s *HirelingPanel) hirelingText() string {
+	state := s.state
+
+	if !state.Hired {
+		return "No hireling"
+	}
+
+	status := fmt.Sprintf("Life: %d/%d", state.Life, state.MaxLife)
+	if state.Dead {
+		status = "Dead"
+	}
+
+	return fmt.Sprintf("%s\nLevel: %d\nExperience: %d/%d\n%s\n\nStrength: %d\nDexterity: %d\n"+
+		"Damage: %d-%d\nAttack Rating: %d\nDefense: %d\nResistances: %d%%",
+		state.Type, state.Level, state.Experience, state.NextExperience, status,
+		state.Strength, state.Dexterity, state.MinDamage, state.MaxDamage, state.AttackRating, state.Defense,
+		state.Resistance)
+}
+
+func (s *HirelingPanel) loadOffers() {
+	for idx := range s.state.Offers {
+		if idx == len(s.offerLabels) {
+			s.Warningf("the hireling screen shows %d of %d offers", idx, len(s.state.Offers))
+			break
+		}
+
+		offer := &s.state.Offers[idx]
+		s.offerLabels[idx].SetText(fmt.Sprintf("%s\n%s  Level: %d  Life: %d\nCost: %d", offer.Name, offer.Type,
+			offer.Level, offer.Life, offer.Price))
+	}
+}
+
+func (s *HirelingPanel) loadEquipment() {
+	if s.equipment == nil {
+		return
+	}
+
+	items := make([]InventoryItem, 0, len(s.state.Equipment))
+
+	for _, slot := range []d2enum.EquippedSlot{
+		d2enum.EquippedSlotHead,
+		d2enum.EquippedSlotTorso,
+		d2enum.EquippedSlotRightArm,
+		d2enum.EquippedSlotLeftArm,
+	} {
+		item := s.state.Equipment[slot]
+		if item == nil {
+			s.equipment.ChangeEquippedSlot(slot, nil)
+			continue
+		}
+
+		s.equipment.ChangeEquippedSlot(slot, item)
+		items = append(items, item)
+	}
+
+	s.equipment.Load(items...)
+}
+
+func (s *HirelingPanel) loadWearable() {
+	if s.wearable == nil {
+		return
+	}
+
+	s.wearable.Clear()
+
+	items := make([]InventoryItem, len(s.state.Wearable))
+	for idx := range s.state.Wearable {
+		items[idx] = s.state.Wearable[idx].Item
+	}
+
+	if _, err := s.wearable.Add(items...); err != nil {
+		s.Warningf("not every item fits on the hireling screen: %v", err)
+	}
+}
+
+// HandleClick hires the offer, equips the item or unequips the slot at the given screen position,
+// it returns true if there is one
+func (s *HirelingPanel) HandleClick(mx, my int) bool {
+	if !s.isOpen {
+		return false
+	}
+
+	switch s.page {
+	case hirelingPageOffers:
+		offer := s.offerAt(mx, my)
+		if offer == nil {
+			return false
+		}
+
+		if offer.Price > s.state.Gold {
+			s.Infof("not enough gold for %d", offer.Price)
+			return true
+		}
+
+		s.onHire(HireActionHire, offer.ID, d2enum.EquippedSlotNone)
+	case hirelingPageEquip:
+		wearable := s.wearableAt(mx, my)
+		if wearable == nil {
+			return false
+		}
+
+		s.onHire(HireActionEquip, wearable.ID, d2enum.EquippedSlotNone)
+	default:
+		slot := s.slotAt(mx, my)
+		if slot == d2enum.EquippedSlotNone {
+			return false
+		}
+
+		s.onHire(HireActionUnequip, "", slot)
+	}
+
+	return true
+}
+
+func (s *HirelingPanel) offerAt(mx, my int) *HirelingOffer {
+	if mx < hirelingOfferX || mx >= hirelingOfferX+hirelingOfferWidth || my < hirelingOfferY {
+		return nil
+	}
+
+	idx := (my - hirelingOfferY) / hirelingOfferHeight
+	if idx >= len(s.state.Offers) || idx >= len(s.offerLabels) {
+		return nil
+	}
+
+	return &s.state.Offers[idx]
+}
+
+func (s *HirelingPanel) wearableAt(mx, my int) *TradeOffer {
+	if s.wearable == nil {
+		return nil
+	}
+
+	item := s.wearable.GetSlot(s.wearable.ScreenToSlot(mx, my))
+	if item == nil {
+		return nil
+	}
+
+	for idx := range s.state.Wearable {
+		if InventoryItem(s.state.Wearable[idx].Item) == item {
+			return &s.state.Wearable[idx]
+		}
+	}
+
+	return nil
+}
+
+func (s *HirelingPanel) slotAt(mx, my int) d2enum.EquippedSlot {
+	if s.equipment == nil {
+		return d2enum.EquippedSlotNone
+	}
+
+	for slot, equipped := range s.equipment.equipmentSlots {
+		if equipped.item == nil {
+			continue
+		}
+
+		// the slots are positioned by their bottom left corner
+		if mx >= equipped.x && mx < equipped.x+equipped.width && my <= equipped.y && my > equipped.y-equipped.height {
+			return slot
+		}
+	}
+
+	return d2enum.EquippedSlotNone
+}
+
+// IsOpen returns true if the hireling panel is open
+func (s *HirelingPanel) IsOpen() bool {
+	return s.isOpen
+}
+
+// Toggle opens or closes the hireling panel
+func (s *HirelingPanel) Toggle() {
+	if s.isOpen {
+		s.Close()
+	} else {
+		s.Open()
+	}
+}
+
+// Open opens the hireling panel
+func (s *HirelingPanel) Open() {
+	s.isOpen = true
+	s.panelGroup.SetVisible(true)
+	s.SetState(s.state)
+}
+
+// Close closes the hireling panel
+func (s *HirelingPanel) Close() {
+	wasOpen := s.isOpen
+
+	s.isOpen = false
+	s.panelGroup.SetVisible(false)
+
+	if wasOpen {
+		s.onCloseCb()
+	}
+}
+
+// SetOnCloseCb the callback run on closing the hireling panel
+func (s *HirelingPanel) SetOnCloseCb(cb func()) {
+	s.onCloseCb = cb
+}
+
+// Render draws the hireling panel onto the given surface
+func (s *HirelingPanel) Render(target d2interface.Surface) {
+	if !s.isOpen {
+		return
+	}
+
+	s.renderFrame(target)
+
+	switch s.page {
+	case hirelingPageEquip:
+		if s.wearable != nil {
+			s.wearable.Render(target)
+			s.renderItemHover(target, s.wearable)
+		}
+	case hirelingPageHireling:
+		if s.equipment != nil {
+			s.equipment.Render(target)
+		}
+	}
+}
+
+// nolint:dupl // the panels of the left side are drawn the same way
+func (s *HirelingPanel) renderFrame(target d2interface.Surface) {
+	if s.panel == nil {
+		return
+	}
+
+	frames := []int{
+		tradeTopLeft,
+		tradeTopRight,
+		tradeBottomRight,
+		tradeBottomLeft,
+	}
+
+	currentX := hirelingOffsetX
+	currentY := hirelingOffsetY
+
+	for _, frameIndex := range frames {
+		if err := s.panel.SetCurrentFrame(frameIndex); err != nil {
+			s.Error(err.Error())
+			return
+		}
+
+		w, h := s.panel.GetCurrentFrameSize()
+
+		switch frameIndex {
+		case tradeTopLeft:
+			s.panel.SetPosition(currentX, currentY+h)
+			currentX += w
+		case tradeTopRight:
+			s.panel.SetPosition(currentX, currentY+h)
+			currentY += h
+		case tradeBottomRight:
+			s.panel.SetPosition(currentX, currentY+h)
+		case tradeBottomLeft:
+			s.panel.SetPosition(currentX-w, currentY+h)
+		}
+
+		s.panel.Render(target)
+	}
+}
+
+func (s *HirelingPanel) renderItemHover(target d2interface.Surface, grid *ItemGrid) {
+	item := grid.GetSlot(grid.ScreenToSlot(s.lastMouseX, s.lastMouseY))
+	if item == nil {
+		return
+	}
+
+	s.itemTooltip.SetTextLines(item.GetItemDescription())
+
+	_, y := grid.SlotToScreen(item.InventoryGridSlot())
+
+	s.itemTooltip.SetPosition(s.lastMouseX, y)
+	s.itemTooltip.Render(target)
+}
@@ -93,6 +93,7 @@ type HUD struct {
 	runButton          *d2ui.Button
 	zoneChangeText     *d2ui.Label
 	miniPanel          *miniPanel
+	automap            *automapOverlay
 	isZoneTextShown    bool
 	hpStatsIsVisible   bool
 	manaStatsIsVisible bool
@@ -160,6 +161,7 @@ func NewHUD(
 		healthGlobe:       healthGlobe,
 		manaGlobe:         manaGlobe,
 		gameControls:      gameControls,
+		automap:           newAutomapOverlay(asset, ui, mapEngine, mapRenderer, hero, l),
 	}
 
 	hud.Logger = d2util.NewLogger()
@@ -174,6 +176,7 @@ func (h *HUD) Load() {
 	h.panelGroup = h.uiManager.NewWidgetGroup(d2ui.RenderPriorityHUDPanel)
 
 	h.loadSprites()
+	h.automap.Load()
 
 	h.healthGlobe.load()
 	h.healthGlobe.SetRenderPriority(d2ui.RenderPriorityForeground)
@@ -673,6 +676,8 @@ func (h *HUD) Advance(elapsed float64) {
 	if err := h.manaGlobe.Advance(elapsed); err != nil {
 		h.Error(err.Error())
 	}
+
+	h.automap.Advance()
 }
 
 // OnMouseMove handles mouse move events
@@ -1,6 +1,12 @@
 package d2player
 
+import "github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+
 type inputCallbackListener interface {
 	OnPlayerMove(x, y float64)
 	OnPlayerCast(skillID int, x, y float64)
+	OnPlayerTrade(mode TradeMode, itemID string)
+	OnPlayerCloseTrade()
+	OnPlayerHire(action HireAction, id string, slot d2enum.EquippedSlot)
+	OnPlayerCloseHire()
 }
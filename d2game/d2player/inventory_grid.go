@@ -239,6 +239,11 @@ func (g *ItemGrid) Remove(item InventoryItem) {
 	g.items = g.items[:n]
 }
 
+// Clear removes every item from the grid
+func (g *ItemGrid) Clear() {
+	g.items = g.items[:0]
+}
+
 func (g *ItemGrid) renderItem(item InventoryItem, target d2interface.Surface, x, y int) {
 	itemSprite := g.sprites[item.GetItemCode()]
 	if itemSprite != nil {
@@ -1,8 +1,8 @@
 package d2player
 
 import (
-	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2geom"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
@@ -11,6 +11,7 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2skill"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2ui"
 )
 
@@ -331,8 +332,7 @@ func (s *SkillPanel) HandleMouseMove(x, y int) bool {
 	s.hoveredSkill = s.getSkillAtPos(x, y)
 
 	if previousHovered != s.hoveredSkill && s.hoveredSkill != nil {
-		skillDescription := s.asset.TranslateString(s.hoveredSkill.ShortKey)
-		s.hoverTooltip.SetText(fmt.Sprintf("%s\n%s", s.hoveredSkill.Skill, skillDescription))
+		s.hoverTooltip.SetText(s.skillTooltip(s.hoveredSkill))
 
 		listRow := s.GetListRowByPos(x, y)
 
@@ -344,6 +344,23 @@ func (s *SkillPanel) HandleMouseMove(x, y int) bool {
 	return true
 }
 
+// skillTooltip returns the text of the tooltip of a skill: its name, its short description and
+// the lines of its description with the values of the level the hero has
+func (s *SkillPanel) skillTooltip(heroSkill *d2hero.HeroSkill) string {
+	lines := []string{heroSkill.Skill, s.asset.TranslateString(heroSkill.ShortKey)}
+
+	caster := &d2hero.SkillCaster{Stats: s.hero.Stats, Skills: s.hero.Skills}
+	skill := d2skill.New(s.asset.Records, heroSkill.SkillRecord, heroSkill.SkillPoints, caster)
+
+	translate := func(key string) string {
+		return s.asset.TranslateString(key)
+	}
+
+	lines = append(lines, skill.Description(heroSkill.SkillDescriptionRecord, translate)...)
+
+	return strings.Join(lines, "\n")
+}
+
 func (s *SkillPanel) getSkillResourceByClass(class string) string {
 	resource := ""
 
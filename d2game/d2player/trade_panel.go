@@ -0,0 +1,417 @@
+package d2player
+
+import (
+	"fmt"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2interface"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2resource"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2ui"
+)
+
+const ( // for the dc6 frames
+	tradeTopLeft = iota
+	tradeTopRight
+	tradeBottomLeft
+	tradeBottomRight
+)
+
+const (
+	tradeOffsetX, tradeOffsetY = 80, 64
+)
+
+const (
+	tradeCloseButtonX, tradeCloseButtonY         = 358, 455
+	tradeBuyButtonX, tradeBuyButtonY             = 96, 455
+	tradeSellButtonX, tradeSellButtonY           = 130, 455
+	tradeRepairButtonX, tradeRepairButtonY       = 164, 455
+	tradeRepairAllButtonX, tradeRepairAllButtonY = 198, 455
+	tradeGambleButtonX, tradeGambleButtonY       = 236, 455
+	tradeHireButtonX, tradeHireButtonY           = 236, 455 // no vendor gambles and sells hirelings
+	tradeVendorLabelX, tradeVendorLabelY         = 240, 80
+	tradeGoldLabelX, tradeGoldLabelY             = 300, 430
+)
+
+// tradeRecordKey is the row of inventory.txt with the grid of the trade screen
+const tradeRecordKey = "Store Page2"
+
+// TradeMode is what the items on the trade panel are shown for
+type TradeMode int
+
+// Trade modes
+const (
+	TradeModeBuy    TradeMode = iota // the items of the vendor, clicking one buys it
+	TradeModeSell                    // the items of the player, clicking one sells it
+	TradeModeRepair                  // the worn items of the player, clicking one repairs it
+	TradeModeGamble                  // the bases of the vendor, clicking one gambles on it
+	TradeModeHire                    // the hirelings of the vendor, they are shown on the hireling panel
+)
+
+// TradeOffer is an item on the trade panel with its price
+type TradeOffer struct {
+	ID    string // ID the server knows the item by
+	Item  *diablo2item.Item
+	Price int
+}
+
+// TradeState is what the trade panel shows
+type TradeState struct {
+	Vendor    string // row of the vendor in monstats.txt
+	Gambling  bool   // the offers of the vendor are gambled on
+	CanGamble bool
+	CanRepair bool
+	CanHire   bool
+	Offers    []TradeOffer // the items of the vendor
+	Owned     []TradeOffer // the items of the player with what the vendor pays for them
+	Worn      []TradeOffer // the worn items of the player with the price of repairing them
+	Gold      int
+}
+
+// NewTradePanel creates the trade panel, the trade screen of the vendors
+func NewTradePanel(asset *d2asset.AssetManager,
+	ui *d2ui.UIManager,
+	l d2util.LogLevel,
+	onTrade func(mode TradeMode, itemID string)) *TradePanel {
+	itemTooltip := ui.NewTooltip(d2resource.FontFormal11, d2resource.PaletteStatic, d2ui.TooltipXCenter, d2ui.TooltipYBottom)
+
+	tp := &TradePanel{
+		asset:       asset,
+		uiManager:   ui,
+		itemTooltip: itemTooltip,
+		onTrade:     onTrade,
+		state:       &TradeState{},
+	}
+
+	tp.Logger = d2util.NewLogger()
+	tp.Logger.SetLevel(l)
+	tp.Logger.SetPrefix(logPrefix)
+
+	if record := asset.Records.Layout.Inventory[tradeRecordKey]; record != nil {
+		tp.grid = NewItemGrid(asset, ui, l, record)
+	} else {
+		tp.Warningf("no inventory layout for the trade screen (%s)", tradeRecordKey)
+	}
+
+	return tp
+}
+
+// TradePanel is the trade screen of a vendor, it shows the items of the vendor or the player
+// for the trade mode
+type TradePanel struct {
+	asset           *d2asset.AssetManager
+	uiManager       *d2ui.UIManager
+	panel           *d2ui.Sprite
+	panelGroup      *d2ui.WidgetGroup
+	grid            *ItemGrid
+	itemTooltip     *d2ui.Tooltip
+	vendorLabel     *d2ui.Label
+	goldLabel       *d2ui.Label
+	repairButton    *d2ui.Button
+	repairAllButton *d2ui.Button
+	gambleButton    *d2ui.Button
+	hireButton      *d2ui.Button
+	onTrade         func(mode TradeMode, itemID string)
+	onCloseCb       func()
+	state           *TradeState
+	mode            TradeMode
+	offers          []TradeOffer // the offers shown for the mode
+	hoverX          int
+	hoverY          int
+	lastMouseX      int
+	lastMouseY      int
+	hovering        bool
+	isOpen          bool
+
+	*d2util.Logger
+}
+
+// Load the resources required by the trade panel
+func (s *TradePanel) Load() {
+	var err error
+
+	s.panelGroup = s.uiManager.NewWidgetGroup(d2ui.RenderPriorityInventory)
+
+	frame := d2ui.NewUIFrame(s.asset, s.uiManager, d2ui.FrameLeft)
+	s.panelGroup.AddWidget(frame)
+
+	s.panel, err = s.uiManager.NewSprite(d2resource.BuySellPanel, d2resource.PaletteSky)
+	if err != nil {
+		s.Error(err.Error())
+	}
+
+	closeButton := s.uiManager.NewButton(d2ui.ButtonTypeSquareClose, "")
+	closeButton.SetVisible(false)
+	closeButton.SetPosition(tradeCloseButtonX, tradeCloseButtonY)
+	closeButton.OnActivated(func() { s.Close() })
+	s.panelGroup.AddWidget(closeButton)
+
+	buyButton := s.uiManager.NewButton(d2ui.ButtonTypeBuy, "")
+	buyButton.SetVisible(false)
+	buyButton.SetPosition(tradeBuyButtonX, tradeBuyButtonY)
+	buyButton.OnActivated(func() { s.onTrade(TradeModeBuy, "") })
+	s.panelGroup.AddWidget(buyButton)
+
+	sellButton := s.uiManager.NewButton(d2ui.ButtonTypeSell, "")
+	sellButton.SetVisible(false)
+	sellButton.SetPosition(tradeSellButtonX, tradeSellButtonY)
+	sellButton.OnActivated(func() { s.setMode(TradeModeSell) })
+	s.panelGroup.AddWidget(sellButton)
+
+	s.repairButton = s.uiManager.NewButton(d2ui.ButtonTypeRepair, "")
+	s.repairButton.SetVisible(false)
+	s.repairButton.SetPosition(tradeRepairButtonX, tradeRepairButtonY)
+	s.repairButton.OnActivated(func() { s.setMode(TradeModeRepair) })
+	s.panelGroup.AddWidget(s.repairButton)
+
+	s.repairAllButton = s.uiManager.NewButton(d2ui.ButtonTypeRepairAll, "")
+	s.repairAllButton.SetVisible(false)
+	s.repairAllButton.SetPosition(tradeRepairAllButtonX, tradeRepairAllButtonY)
+	s.repairAllButton.OnActivated(func() { s.onTrade(TradeModeRepair, "") })
+	s.panelGroup.AddWidget(s.repairAllButton)
+
+	s.gambleButton = s.uiManager.NewButton(d2ui.ButtonTypeShort, s.asset.TranslateString("Gamble"))
+	s.gambleButton.SetVisible(false)
+	s.gambleButton.SetPosition(tradeGambleButtonX, tradeGambleButtonY)
+	s.gambleButton.OnActivated(func() { s.onTrade(TradeModeGamble, "") })
+	s.panelGroup.AddWidget(s.gambleButton)
+
+	s.hireButton = s.uiManager.NewButton(d2ui.ButtonTypeShort, s.asset.TranslateString("Hire"))
+	s.hireButton.SetVisible(false)
+	s.hireButton.SetPosition(tradeHireButtonX, tradeHireButtonY)
+	s.hireButton.OnActivated(func() { s.onTrade(TradeModeHire, "") })
+	s.panelGroup.AddWidget(s.hireButton)
+
+	s.vendorLabel = s.uiManager.NewLabel(d2resource.Font16, d2resource.PaletteStatic)
+	s.vendorLabel.Alignment = d2ui.HorizontalAlignCenter
+	s.vendorLabel.Color[0] = d2util.Color(white)
+	s.vendorLabel.SetPosition(tradeVendorLabelX, tradeVendorLabelY)
+	s.panelGroup.AddWidget(s.vendorLabel)
+
+	s.goldLabel = s.uiManager.NewLabel(d2resource.Font16, d2resource.PaletteStatic)
+	s.goldLabel.Alignment = d2ui.HorizontalAlignLeft
+	s.goldLabel.SetPosition(tradeGoldLabelX, tradeGoldLabelY)
+	s.panelGroup.AddWidget(s.goldLabel)
+
+	s.panelGroup.SetVisible(false)
+}
+
+// SetState shows the given trade on the panel. The panel switches to the offers of the vendor
+// when they change between buying and gambling.
+func (s *TradePanel) SetState(state *TradeState) {
+	if state.Vendor != s.state.Vendor || state.Gambling != s.state.Gambling {
+		s.mode = TradeModeBuy
+	}
+
+	s.state = state
+	s.vendorLabel.SetText(s.vendorName())
+	s.goldLabel.SetText(fmt.Sprintln(state.Gold))
+
+	s.repairButton.SetVisible(s.isOpen && state.CanRepair)
+	s.repairAllButton.SetVisible(s.isOpen && state.CanRepair)
+	s.gambleButton.SetVisible(s.isOpen && state.CanGamble)
+	s.hireButton.SetVisible(s.isOpen && state.CanHire)
+
+	s.setMode(s.mode)
+}
+
+func (s *TradePanel) vendorName() string {
+	record := s.asset.Records.Monster.Stats[s.state.Vendor]
+	if record == nil {
+		return s.state.Vendor
+	}
+
+	return s.asset.TranslateString(record.NameString)
+}
+
+// setMode shows the offers for the given trade mode
+func (s *TradePanel) setMode(mode TradeMode) {
+	s.mode = mode
+
+	switch mode {
+	case TradeModeBuy, TradeModeGamble:
+		s.offers = s.state.Offers
+
+		if s.state.Gambling {
+			s.mode = TradeModeGamble
+		} else {
+			s.mode = TradeModeBuy
+		}
+	case TradeModeSell:
+		s.offers = s.state.Owned
+	case TradeModeRepair:
+		s.offers = s.state.Worn
+	}
+
+	if s.grid == nil {
+		return
+	}
+
+	s.grid.Clear()
+
+	items := make([]InventoryItem, len(s.offers))
+	for idx := range s.offers {
+		items[idx] = s.offers[idx].Item
+	}
+
+	if _, err := s.grid.Add(items...); err != nil {
+		s.Warningf("not every item fits on the trade screen: %v", err)
+	}
+}
+
+// HandleClick trades the item at the given screen position, it returns true if there is one
+func (s *TradePanel) HandleClick(mx, my int) bool {
+	if !s.isOpen || s.grid == nil {
+		return false
+	}
+
+	offer := s.offerAt(mx, my)
+	if offer == nil {
+		return false
+	}
+
+	if (s.mode == TradeModeBuy || s.mode == TradeModeGamble || s.mode == TradeModeRepair) && offer.Price > s.state.Gold {
+		s.Infof("not enough gold for %d", offer.Price)
+		return true
+	}
+
+	s.onTrade(s.mode, offer.ID)
+
+	return true
+}
+
+func (s *TradePanel) offerAt(mx, my int) *TradeOffer {
+	item := s.grid.GetSlot(s.grid.ScreenToSlot(mx, my))
+	if item == nil {
+		return nil
+	}
+
+	for idx := range s.offers {
+		if InventoryItem(s.offers[idx].Item) == item {
+			return &s.offers[idx]
+		}
+	}
+
+	return nil
+}
+
+// IsOpen returns true if the trade panel is open
+func (s *TradePanel) IsOpen() bool {
+	return s.isOpen
+}
+
+// Open opens the trade panel
+func (s *TradePanel) Open() {
+	s.isOpen = true
+	s.panelGroup.SetVisible(true)
+	s.SetState(s.state)
+}
+
+// Close closes the trade panel
+func (s *TradePanel) Close() {
+	wasOpen := s.isOpen
+
+	s.isOpen = false
+	s.panelGroup.SetVisible(false)
+
+	if wasOpen {
+		s.onCloseCb()
+	}
+}
+
+// SetOnCloseCb the callback run on closing the trade panel
+func (s *TradePanel) SetOnCloseCb(cb func()) {
+	s.onCloseCb = cb
+}
+
+// Render draws the trade panel onto the given surface
+func (s *TradePanel) Render(target d2interface.Surface) {
+	if !s.isOpen {
+		return
+	}
+
+	s.renderFrame(target)
+
+	if s.grid != nil {
+		s.grid.Render(target)
+		s.renderItemHover(target)
+	}
+}
+
+// nolint:dupl // the panels of the left side are drawn the same way
+func (s *TradePanel) renderFrame(target d2interface.Surface) {
+	if s.panel == nil {
+		return
+	}
+
+	frames := []int{
+		tradeTopLeft,
+		tradeTopRight,
+		tradeBottomRight,
+		tradeBottomLeft,
+	}
+
+	currentX := tradeOffsetX
+	currentY := tradeOffsetY
+
+	for _, frameIndex := range frames {
+		if err := s.panel.SetCurrentFrame(frameIndex); err != nil {
+			s.Error(err.Error())
+			return
+		}
+
+		w, h := s.panel.GetCurrentFrameSize()
+
+		switch frameIndex {
+		case tradeTopLeft:
+			s.panel.SetPosition(currentX, currentY+h)
+			currentX += w
+		case tradeTopRight:
+			s.panel.SetPosition(currentX, currentY+h)
+			currentY += h
+		case tradeBottomRight:
+			s.panel.SetPosition(currentX, currentY+h)
+		case tradeBottomLeft:
+			s.panel.SetPosition(currentX-w, currentY+h)
+		}
+
+		s.panel.Render(target)
+	}
+}
+
+func (s *TradePanel) renderItemHover(target d2interface.Surface) {
+	mx, my := s.lastMouseX, s.lastMouseY
+	offer := s.offerAt(mx, my)
+
+	if offer == nil {
+		s.hovering = false
+		return
+	}
+
+	if !s.hovering {
+		// the description stays where the mouse started hovering the item
+		s.hoverX, s.hoverY = mx, my
+	}
+
+	s.hovering = true
+
+	lines := append(offer.Item.GetItemDescription(), s.priceText(offer.Price))
+	s.itemTooltip.SetTextLines(lines)
+
+	_, y := s.grid.SlotToScreen(offer.Item.InventoryGridSlot())
+
+	s.itemTooltip.SetPosition(s.hoverX, y)
+	s.itemTooltip.Render(target)
+}
+
+func (s *TradePanel) priceText(price int) string {
+	switch s.mode {
+	case TradeModeSell:
+		return fmt.Sprintf("Sell value: %d", price)
+	case TradeModeRepair:
+		return fmt.Sprintf("Repair cost: %d", price)
+	default:
+		return fmt.Sprintf("Cost: %d", price)
+	}
+}
@@ -1,7 +1,7 @@
 package d2remoteclient
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -28,6 +28,8 @@ type RemoteClientConnection struct {
 	clientListener d2networking.ClientListener // The GameClient
 	uniqueID       string                      // Unique ID generated on construction
 	tcpConnection  *net.TCPConn                // UDP connection to the server
+	writer         *d2netpacket.PacketWriter   // Writes the packets in the negotiated encoding
+	binaryVersion  int                         // The binary version requested from the server, 0 for JSON
 	active         bool                        // The connection is currently open
 
 	*d2util.Logger
@@ -42,9 +44,10 @@ func Create(l d2util.LogLevel, asset *d2asset.AssetManager) (*RemoteClientConnec
 	}
 
 	result := &RemoteClientConnection{
-		asset:     asset,
-		heroState: heroStateFactory,
-		uniqueID:  uuid.New().String(),
+		asset:         asset,
+		heroState:     heroStateFactory,
+		uniqueID:      uuid.New().String(),
+		binaryVersion: d2netpacket.BinaryVersion,
 	}
 
 	result.Logger = d2util.NewLogger()
@@ -72,14 +75,18 @@ func (r *RemoteClientConnection) Open(connectionString, saveFilePath string) err
 		return err
 	}
 
+	// the connection request is always sent as JSON, the server info
+	// packet tells which encoding to use afterwards
+	r.writer = d2netpacket.NewPacketWriter(r.tcpConnection, 0)
 	r.active = true
+
 	go r.serverListener()
 
 	r.Infof("Connected to server at %s", r.tcpConnection.RemoteAddr().String())
 
 	gameState := r.heroState.LoadHeroState(saveFilePath)
 
-	packet, err := d2netpacket.CreatePlayerConnectionRequestPacket(r.GetUniqueID(), gameState)
+	packet, err := d2netpacket.CreatePlayerConnectionRequestPacket(r.GetUniqueID(), gameState, r.binaryVersion)
 	if err != nil {
 		r.Errorf("PlayerConnectionRequestPacket: %v", err)
 	}
@@ -124,36 +131,40 @@ func (r RemoteClientConnection) GetConnectionType() d2clientconnectiontype.Clien
 	return d2clientconnectiontype.LANClient
 }
 
+// SetJSONPackets makes the connection ask the server for JSON packets instead of binary ones,
+// which is easier to debug. It has to be called before Open.
+func (r *RemoteClientConnection) SetJSONPackets(enabled bool) {
+	r.binaryVersion = d2netpacket.BinaryVersion
+
+	if enabled {
+		r.binaryVersion = 0
+	}
+}
+
 // SetClientListener sets RemoteClientConnection.clientListener to the given value.
 func (r *RemoteClientConnection) SetClientListener(listener d2networking.ClientListener) {
 	r.clientListener = listener
 }
 
-// SendPacketToServer compresses the JSON encoding of a NetPacket and
-// sends it to the server.
+// SendPacketToServer sends a NetPacket to the server, in the encoding
+// negotiated with the server.
 func (r *RemoteClientConnection) SendPacketToServer(packet d2netpacket.NetPacket) error {
-	encoder := json.NewEncoder(r.tcpConnection)
-
-	err := encoder.Encode(packet)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return r.writer.WritePacket(packet)
 }
 
 // serverListener runs a while loop, reading from the GameServer's TCP
 // connection.
 func (r *RemoteClientConnection) serverListener() {
-	decoder := json.NewDecoder(r.tcpConnection)
+	reader := d2netpacket.NewPacketReader(r.tcpConnection)
 
 	for {
-		var packet d2netpacket.NetPacket
-
-		err := decoder.Decode(&packet)
+		packet, err := reader.ReadPacket()
 		if err != nil {
-			switch err {
-			case io.EOF:
+			switch {
+			case errors.Is(err, d2netpacket.ErrMalformedPacket):
+				r.Warningf("skipping packet: %v", err)
+				continue
+			case err == io.EOF:
 				break // the other side closed the connection
 			default:
 				r.Errorf("failed to decode the packet, err: %v\n", err)
@@ -162,9 +173,17 @@ func (r *RemoteClientConnection) serverListener() {
 			return // allow the connection to close
 		}
 
-		p, err := r.decodeToPacket(packet.PacketType, string(packet.PacketData))
+		p, err := r.decodeToPacket(packet)
 		if err != nil {
 			r.Errorf("%v %v", packet.PacketType, err)
+			continue
+		}
+
+		if p.PacketType == d2netpackettype.UpdateServerInfo {
+			serverInfo, infoErr := d2netpacket.UnmarshalUpdateServerInfo(p)
+			if infoErr == nil {
+				r.writer.SetBinaryVersion(d2netpacket.NegotiateBinaryVersion(serverInfo.BinaryVersion))
+			}
 		}
 
 		err = r.clientListener.OnPacketReceived(p)
@@ -185,49 +204,57 @@ func (r *RemoteClientConnection) bytesToJSON(buffer []byte) (string, d2netpacket
 	return string(packet.PacketData), packet.PacketType, nil
 }
 
-// decodeToPacket unmarshals the JSON string into the correct struct
-// and returns a NetPacket declaring that struct.
+// decodeToPacket unmarshals the packet into the correct struct and
+// returns the packet if it is one the client handles.
 // nolint:gocyclo,funlen // switch statement on packet type makes sense, no need to change
 func (r *RemoteClientConnection) decodeToPacket(
-	t d2netpackettype.NetPacketType,
-	data string) (d2netpacket.NetPacket, error) {
-	var (
-		np  = d2netpacket.NetPacket{}
-		err error
-		p   interface{}
-	)
-
-	switch t {
+	packet d2netpacket.NetPacket) (d2netpacket.NetPacket, error) {
+	var err error
+
+	switch packet.PacketType {
 	case d2netpackettype.GenerateMap:
-		p, err = d2netpacket.UnmarshalGenerateMap([]byte(data))
+		_, err = d2netpacket.UnmarshalGenerateMap(packet)
 	case d2netpackettype.MovePlayer:
-		p, err = d2netpacket.UnmarshalMovePlayer([]byte(data))
+		_, err = d2netpacket.UnmarshalMovePlayer(packet)
 	case d2netpackettype.UpdateServerInfo:
-		p, err = d2netpacket.UnmarshalUpdateServerInfo([]byte(data))
+		_, err = d2netpacket.UnmarshalUpdateServerInfo(packet)
 	case d2netpackettype.AddPlayer:
-		p, err = d2netpacket.UnmarshalAddPlayer([]byte(data))
+		_, err = d2netpacket.UnmarshalAddPlayer(packet)
 	case d2netpackettype.CastSkill:
-		p, err = d2netpacket.UnmarshalCast([]byte(data))
+		_, err = d2netpacket.UnmarshalCast(packet)
 	case d2netpackettype.Ping:
-		p, err = d2netpacket.UnmarshalPing([]byte(data))
+		_, err = d2netpacket.UnmarshalPing(packet)
 	case d2netpackettype.PlayerDisconnectionNotification:
-		p, err = d2netpacket.UnmarshalPlayerDisconnectionRequest([]byte(data))
+		_, err = d2netpacket.UnmarshalPlayerDisconnectionRequest(packet)
 	case d2netpackettype.ServerClosed:
-		p, err = d2netpacket.UnmarshalServerClosed([]byte(data))
+		_, err = d2netpacket.UnmarshalServerClosed(packet)
+	case d2netpackettype.ServerFull:
+		_, err = d2netpacket.UnmarshalServerFull(packet)
+	case d2netpackettype.SpawnItem:
+		_, err = d2netpacket.UnmarshalSpawnItem(packet)
+	case d2netpackettype.StateDelta:
+		_, err = d2netpacket.UnmarshalStateDelta(packet)
+	case d2netpackettype.WarpPlayer:
+		_, err = d2netpacket.UnmarshalWarpPlayer(packet)
+	case d2netpackettype.PickUpItem:
+		_, err = d2netpacket.UnmarshalPickUpItem(packet)
+	case d2netpackettype.OperateObject:
+		_, err = d2netpacket.UnmarshalOperateObject(packet)
+	case d2netpackettype.SpawnMonster:
+		_, err = d2netpacket.UnmarshalSpawnMonster(packet)
+	case d2netpackettype.MonsterAttack:
+		_, err = d2netpacket.UnmarshalMonsterAttack(packet)
+	case d2netpackettype.Combat:
+		_, err = d2netpacket.UnmarshalCombat(packet)
+	case d2netpackettype.SetState:
+		_, err = d2netpacket.UnmarshalSetState(packet)
 	default:
-		err = fmt.Errorf("RemoteClientConnection: unrecognized packet type: %v", t)
+		err = fmt.Errorf("RemoteClientConnection: unrecognized packet type: %v", packet.PacketType)
 	}
 
 	if err != nil {
-		return np, err
-	}
-
-	mp, marshalErr := d2netpacket.MarshalPacket(p)
-	if marshalErr != nil {
-		r.Errorf("MarshalPacket: %v", marshalErr)
+		return d2netpacket.NetPacket{}, err
 	}
 
-	np = d2netpacket.NetPacket{PacketType: t, PacketData: mp}
-
-	return np, nil
+	return packet, nil
 }
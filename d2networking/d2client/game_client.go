@@ -3,12 +3,19 @@ package d2client
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2automap"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hireling"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapgen"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2object"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math"
 
@@ -30,6 +37,21 @@ const logPrefix = "Game Client"
 
 const (
 	numSubtilesPerTile = 5
+
+	// reconcileDistance is how far, in sub tiles, a predicted position may be from the position
+	// simulated by the server before it is corrected
+	reconcileDistance = 2.0
+
+	// warpDistance is how far, in tiles, from a warp tile the local player has to stop to take it
+	warpDistance = 1.0
+
+	// interactDistance is how far, in tiles, from an object or an item the local player has to stop
+	// to operate or pick it up
+	interactDistance = 1.5
+
+	// objectPositionTolerance is how far, in tiles, an object may be from the position the server
+	// sent for it
+	objectPositionTolerance = 0.5
 )
 
 // GameClient manages a connection to d2server.GameServer
@@ -39,13 +61,27 @@ type GameClient struct {
 	connectionType   d2clientconnectiontype.ClientConnectionType // Type of connection (local or remote)
 	asset            *d2asset.AssetManager
 	scriptEngine     *d2script.ScriptEngine
-	GameState        *d2hero.HeroState              // local player state
-	MapEngine        *d2mapengine.MapEngine         // Map and entities
-	mapGen           *d2mapgen.MapGenerator         // map generator
-	PlayerID         string                         // ID of the local player
-	Players          map[string]*d2mapentity.Player // IDs of the other players
-	Seed             int64                          // Map seed
-	RegenMap         bool                           // Regenerate tile cache on render (map has changed)
+	GameState        *d2hero.HeroState                   // local player state
+	MapEngine        *d2mapengine.MapEngine              // Map and entities
+	mapGen           *d2mapgen.MapGenerator              // map generator
+	warps            []d2mapengine.Warp                  // warps of the current level
+	items            map[string]*d2mapentity.Item        // items on the ground, by their server ID
+	monsters         map[string]*d2mapentity.NPC         // monsters of the server, by their server ID
+	stateOverlays    map[string]*d2mapentity.CastOverlay // overlays of the active states, by unit ID and state
+	PlayerID         string                              // ID of the local player
+	Players          map[string]*d2mapentity.Player      // IDs of the other players
+	Seed             int64                               // Map seed
+	RegenMap         bool                                // Regenerate tile cache on render (map has changed)
+	Inventory        map[string]*diablo2item.Item        // items of the local player, by their server ID
+	Shop             *Shop                               // shop of the vendor the local player trades with
+	ShopChanged      bool                                // Update the trade screen on render (shop has changed)
+	vendorX, vendorY float64                             // world position of the vendor the shop was opened at
+	Hireling         *d2hireling.Hireling                // hireling of the local player, nil if it has none
+	HireOffers       *HireOffers                         // hirelings a seller offers the local player
+	HirelingChanged  bool                                // Update the hireling screen on render (hireling has changed)
+	hirelingUnits    map[string]string                   // IDs of the hirelings on the map, by the IDs of their players
+	hireFactory      *d2hireling.Factory
+	Automap          *d2automap.Automap // automap of the current level, nil until a map is generated
 
 	*d2util.Logger
 }
@@ -59,6 +95,11 @@ func Create(connectionType d2clientconnectiontype.ClientConnectionType,
 		asset:          asset,
 		MapEngine:      d2mapengine.CreateMapEngine(l, asset),
 		Players:        make(map[string]*d2mapentity.Player),
+		items:          make(map[string]*d2mapentity.Item),
+		Inventory:      make(map[string]*diablo2item.Item),
+		monsters:       make(map[string]*d2mapentity.NPC),
+		stateOverlays:  make(map[string]*d2mapentity.CastOverlay),
+		hirelingUnits:  make(map[string]string),
 		connectionType: connectionType,
 		scriptEngine:   scriptEngine,
 	}
@@ -98,6 +139,15 @@ func Create(connectionType d2clientconnectiontype.ClientConnectionType,
 	return result, nil
 }
 
+// SetJSONPackets makes a remote client ask the server for JSON packets
+// instead of binary ones, which is easier to debug. It has to be called
+// before Open.
+func (g *GameClient) SetJSONPackets(enabled bool) {
+	if remote, ok := g.clientConnection.(*d2remoteclient.RemoteClientConnection); ok {
+		remote.SetJSONPackets(enabled)
+	}
+}
+
 // Open creates the server and connects to it if the client is local.
 // If the client is remote it sends a PlayerConnectionRequestPacket to the
 // server (see d2netpacket).
@@ -155,6 +205,66 @@ func (g *GameClient) OnPacketReceived(packet d2netpacket.NetPacket) error {
 		if err := g.handleSpawnItemPacket(packet); err != nil {
 			return err
 		}
+	case d2netpackettype.StateDelta:
+		if err := g.handleStateDeltaPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.WarpPlayer:
+		if err := g.handleWarpPlayerPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.PickUpItem:
+		if err := g.handlePickUpItemPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.ObjectState:
+		if err := g.handleObjectStatePacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.SpawnMonster:
+		if err := g.handleSpawnMonsterPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.MonsterAttack:
+		if err := g.handleMonsterAttackPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.Combat:
+		if err := g.handleCombatPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.SetState:
+		if err := g.handleSetStatePacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.ShopInventory:
+		if err := g.handleShopInventoryPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.Trade:
+		if err := g.handleTradePacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.Inventory:
+		if err := g.handleInventoryPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.Hire:
+		if err := g.handleHirePacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.HirelingOffers:
+		if err := g.handleHirelingOffersPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.Hireling:
+		if err := g.handleHirelingPacket(packet); err != nil {
+			return err
+		}
+	case d2netpackettype.Automap:
+		if err := g.handleAutomapPacket(packet); err != nil {
+			return err
+		}
 	case d2netpackettype.Ping:
 		if err := g.handlePingPacket(); err != nil {
 			g.Errorf("GameClient: error responding to server ping: %s", err)
@@ -184,13 +294,45 @@ func (g *GameClient) SendPacketToServer(packet d2netpacket.NetPacket) error {
 }
 
 func (g *GameClient) handleGenerateMapPacket(packet d2netpacket.NetPacket) error {
-	mapData, err := d2netpacket.UnmarshalGenerateMap(packet.PacketData)
+	mapData, err := d2netpacket.UnmarshalGenerateMap(packet)
 	if err != nil {
 		return err
 	}
 
-	if mapData.RegionType == d2enum.RegionAct1Town {
-		g.mapGen.GenerateAct1Overworld()
+	g.MapEngine.IsLoading = true
+
+	if _, err := g.mapGen.GenerateLevel(mapData.LevelID); err != nil {
+		return err
+	}
+
+	g.warps = g.MapEngine.Warps()
+	g.Automap = d2automap.New(g.asset.Records, g.MapEngine)
+	g.items = make(map[string]*d2mapentity.Item)
+	g.monsters = make(map[string]*d2mapentity.NPC)
+	g.stateOverlays = make(map[string]*d2mapentity.CastOverlay)
+	g.hirelingUnits = make(map[string]string)
+
+	// the states of the players belong to the world they left, the new world sends its own
+	for _, player := range g.Players {
+		player.States().RemoveIf(func(*d2states.State) bool { return true })
+	}
+
+	// the hostile monsters of the map are simulated by the server, which sends them after this packet
+	for _, entity := range g.MapEngine.Entities() {
+		if npc, ok := entity.(*d2mapentity.NPC); ok && npc.MonStatRecord() != nil && npc.MonStatRecord().IsHostile() {
+			g.MapEngine.RemoveEntity(npc)
+		}
+	}
+
+	// the new map has none of the players of the old level, the server adds the players of the
+	// new level after this packet. The local player is kept, the game screen holds on to it.
+	localPlayer := g.Players[g.PlayerID]
+	g.Players = make(map[string]*d2mapentity.Player)
+
+	if localPlayer != nil {
+		localPlayer.StopMoving()
+		g.Players[g.PlayerID] = localPlayer
+		g.MapEngine.AddEntity(localPlayer)
 	}
 
 	g.RegenMap = true
@@ -199,7 +341,7 @@ func (g *GameClient) handleGenerateMapPacket(packet d2netpacket.NetPacket) error
 }
 
 func (g *GameClient) handleUpdateServerInfoPacket(packet d2netpacket.NetPacket) error {
-	serverInfo, err := d2netpacket.UnmarshalUpdateServerInfo(packet.PacketData)
+	serverInfo, err := d2netpacket.UnmarshalUpdateServerInfo(packet)
 	if err != nil {
 		return err
 	}
@@ -212,12 +354,40 @@ func (g *GameClient) handleUpdateServerInfoPacket(packet d2netpacket.NetPacket)
 	return nil
 }
 
+// handleAutomapPacket restores the tiles of the current map the local player has explored
+func (g *GameClient) handleAutomapPacket(packet d2netpacket.NetPacket) error {
+	automap, err := d2netpacket.UnmarshalAutomap(packet)
+	if err != nil {
+		return err
+	}
+
+	if g.Automap == nil || g.Automap.LevelKey() != automap.LevelID {
+		g.Warningf("explored tiles of level %d are not for the current map", automap.LevelID)
+		return nil
+	}
+
+	g.Automap.SetExplored(automap.Explored)
+
+	return nil
+}
+
 func (g *GameClient) handleAddPlayerPacket(packet d2netpacket.NetPacket) error {
-	player, err := d2netpacket.UnmarshalAddPlayer(packet.PacketData)
+	player, err := d2netpacket.UnmarshalAddPlayer(packet)
 	if err != nil {
 		return err
 	}
 
+	// the local player enters a new level at the position of the server
+	if existing := g.Players[player.ID]; existing != nil {
+		position := d2vector.NewPosition(float64(player.X), float64(player.Y))
+
+		existing.StopMoving()
+		existing.Position.Copy(&position.Vector)
+		existing.Target.Copy(&position.Vector)
+
+		return nil
+	}
+
 	d2hero.HydrateSkills(player.Skills, g.asset)
 
 	newPlayer := g.MapEngine.NewPlayer(player.ID, player.Name, player.X, player.Y, 0,
@@ -229,30 +399,137 @@ func (g *GameClient) handleAddPlayerPacket(packet d2netpacket.NetPacket) error {
 	return nil
 }
 
+// handleSpawnItemPacket puts an item on the ground, the items of the server come in the saved
+// item format with the ID the server knows them by
 func (g *GameClient) handleSpawnItemPacket(packet d2netpacket.NetPacket) error {
-	item, err := d2netpacket.UnmarshalSpawnItem(packet.PacketData)
+	item, err := d2netpacket.UnmarshalSpawnItem(packet)
+	if err != nil {
+		return err
+	}
+
+	if item.ItemID == "" {
+		itemEntity, err := g.MapEngine.NewItem(item.X, item.Y, item.Codes...)
+
+		if err == nil {
+			g.MapEngine.AddEntity(itemEntity)
+		}
+
+		return err
+	}
+
+	dropped, err := g.MapEngine.ItemFactory().Deserialize(item.Item)
 	if err != nil {
 		return err
 	}
 
-	itemEntity, err := g.MapEngine.NewItem(item.X, item.Y, item.Codes...)
+	itemEntity, err := g.MapEngine.NewItemEntity(item.X*numSubtilesPerTile, item.Y*numSubtilesPerTile, dropped)
+	if err != nil {
+		return err
+	}
+
+	g.items[item.ItemID] = itemEntity
+	g.MapEngine.AddEntity(itemEntity)
+
+	return nil
+}
+
+// handlePickUpItemPacket removes an item which was picked up and gives it, or its gold, to the
+// player which picked it up
+func (g *GameClient) handlePickUpItemPacket(packet d2netpacket.NetPacket) error {
+	pickUp, err := d2netpacket.UnmarshalPickUpItem(packet)
+	if err != nil {
+		return err
+	}
+
+	if item := g.items[pickUp.ItemID]; item != nil {
+		g.MapEngine.RemoveEntity(item)
+		delete(g.items, pickUp.ItemID)
+
+		if pickUp.PlayerID == g.PlayerID && item.Item != nil && !item.Item.IsGold() {
+			g.Inventory[pickUp.ItemID] = item.Item
+		}
+	}
+
+	if player := g.Players[pickUp.PlayerID]; player != nil {
+		player.Gold += pickUp.Gold
+	}
+
+	if pickUp.PlayerID == g.PlayerID && g.GameState != nil {
+		g.GameState.Gold += pickUp.Gold
+	}
 
-	if err == nil {
-		g.MapEngine.AddEntity(itemEntity)
+	return nil
+}
+
+// handleObjectStatePacket plays the mode of the object at the position of the packet
+func (g *GameClient) handleObjectStatePacket(packet d2netpacket.NetPacket) error {
+	state, err := d2netpacket.UnmarshalObjectState(packet)
+	if err != nil {
+		return err
+	}
+
+	object := g.objectNear(d2vector.NewVector(state.X, state.Y), objectPositionTolerance, nil)
+	if object == nil {
+		return nil
+	}
+
+	return g.MapEngine.SetObjectMode(object, state.Mode)
+}
+
+// objectNear returns the operable object closest to the given world position within the given
+// distance which the given function accepts, if any, or nil if there is none
+func (g *GameClient) objectNear(position *d2vector.Vector, distance float64,
+	accept func(object *d2mapentity.Object) bool) *d2mapentity.Object {
+	var closest *d2mapentity.Object
+
+	for _, entity := range g.MapEngine.Entities() {
+		object, ok := entity.(*d2mapentity.Object)
+		if !ok || d2object.OperationOf(object.Record()) == d2object.OperationNone || accept != nil && !accept(object) {
+			continue
+		}
+
+		objectPosition := object.GetPosition()
+
+		if d := objectPosition.World().Distance(position); d <= distance {
+			closest, distance = object, d
+		}
 	}
 
-	return err
+	return closest
 }
 
 func (g *GameClient) handleMovePlayerPacket(packet d2netpacket.NetPacket) error {
-	movePlayer, err := d2netpacket.UnmarshalMovePlayer(packet.PacketData)
+	movePlayer, err := d2netpacket.UnmarshalMovePlayer(packet)
 	if err != nil {
 		return err
 	}
 
 	player := g.Players[movePlayer.PlayerID]
+	if player == nil {
+		return nil
+	}
+
 	start := d2vector.NewPositionTile(movePlayer.StartX, movePlayer.StartY)
 	dest := d2vector.NewPositionTile(movePlayer.DestX, movePlayer.DestY)
+
+	player.SetIsRunning(movePlayer.Running)
+	g.movePlayer(player, start, dest)
+
+	return nil
+}
+
+// PredictMovePlayer moves the local player right away, without waiting for the server. The
+// server validates the move and corrects the prediction with its state deltas.
+func (g *GameClient) PredictMovePlayer(destX, destY float64) {
+	player := g.Players[g.PlayerID]
+	if player == nil {
+		return
+	}
+
+	g.movePlayer(player, player.Position, d2vector.NewPositionTile(destX, destY))
+}
+
+func (g *GameClient) movePlayer(player *d2mapentity.Player, start, dest d2vector.Position) {
 	path := g.MapEngine.PathFind(start, dest)
 
 	if len(path) > 0 {
@@ -272,14 +549,354 @@ func (g *GameClient) handleMovePlayerPacket(packet d2netpacket.NetPacket) error
 				fmtStr := "GameClient: error setting animation mode for player %s: %s"
 				g.Errorf(fmtStr, player.ID(), err)
 			}
+
+			if player.ID() == g.PlayerID {
+				g.interact(player)
+			}
 		})
 	}
+}
+
+// interact asks the server to use what the local player stopped at: a warp, an object like a chest,
+// a door or a shrine, a vendor, a seller of hirelings or an item
+func (g *GameClient) interact(player *d2mapentity.Player) {
+	if g.takeWarp(player) {
+		return
+	}
+
+	position := player.Position.World()
+
+	// the objects which have been operated are left alone, so the player does not close the door
+	// it just opened when it stops next to it
+	closed := func(object *d2mapentity.Object) bool { return !object.IsOpen() }
+
+	if object := g.objectNear(position, interactDistance, closed); object != nil {
+		objectPosition := object.GetPosition()
+		objectWorld := objectPosition.World()
+
+		packet, err := d2netpacket.CreateOperateObjectPacket(g.PlayerID, objectWorld.X(), objectWorld.Y())
+		if err != nil {
+			g.Errorf("OperateObjectPacket: %v", err)
+			return
+		}
+
+		if err := g.SendPacketToServer(packet); err != nil {
+			g.Errorf("GameClient: error sending OperateObjectPacket: %s", err)
+		}
+
+		return
+	}
+
+	if g.openShop(player) || g.openHireOffers(player) {
+		return
+	}
+
+	for id, item := range g.items {
+		itemPosition := item.GetPosition()
+
+		if itemPosition.World().Distance(position) > interactDistance {
+			continue
+		}
+
+		packet, err := d2netpacket.CreatePickUpItemPacket(g.PlayerID, id, 0)
+		if err != nil {
+			g.Errorf("PickUpItemPacket: %v", err)
+			return
+		}
+
+		if err := g.SendPacketToServer(packet); err != nil {
+			g.Errorf("GameClient: error sending PickUpItemPacket: %s", err)
+		}
+
+		return
+	}
+}
+
+// takeWarp asks the server to move the local player to the level of the warp it stopped at, it
+// returns true if the player stopped at a warp
+func (g *GameClient) takeWarp(player *d2mapentity.Player) bool {
+	position := player.Position.World()
+
+	for idx := range g.warps {
+		warp := &g.warps[idx]
+
+		if position.Distance(d2vector.NewVector(warp.X, warp.Y)) > warpDistance {
+			continue
+		}
+
+		packet, err := d2netpacket.CreateWarpPlayerPacket(g.PlayerID, warp.LevelID, false)
+		if err != nil {
+			g.Errorf("WarpPlayerPacket: %v", err)
+			return true
+		}
+
+		if err := g.SendPacketToServer(packet); err != nil {
+			g.Errorf("GameClient: error sending WarpPlayerPacket: %s", err)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// handleWarpPlayerPacket removes a player which left the level of the local player
+func (g *GameClient) handleWarpPlayerPacket(packet d2netpacket.NetPacket) error {
+	warp, err := d2netpacket.UnmarshalWarpPlayer(packet)
+	if err != nil {
+		return err
+	}
+
+	if player := g.Players[warp.PlayerID]; player != nil && warp.PlayerID != g.PlayerID {
+		g.MapEngine.RemoveEntity(player)
+		delete(g.Players, warp.PlayerID)
+	}
+
+	return nil
+}
+
+// handleStateDeltaPacket reconciles the entities with the state simulated by the server. The
+// entities which drifted too far from the server are moved back and sent on their way again.
+func (g *GameClient) handleStateDeltaPacket(packet d2netpacket.NetPacket) error {
+	delta, err := d2netpacket.UnmarshalStateDelta(packet)
+	if err != nil {
+		return err
+	}
+
+	for idx := range delta.Entities {
+		state := &delta.Entities[idx]
+
+		player := g.Players[state.ID]
+		if player == nil {
+			g.updateMonster(state)
+			continue
+		}
+
+		if player.Stats != nil {
+			player.Stats.Health, player.Stats.MaxHealth = state.Life, state.MaxLife
+			player.Stats.Mana, player.Stats.MaxMana = state.Mana, state.MaxMana
+		}
+
+		position := d2vector.NewPositionTile(state.X, state.Y)
+		dest := d2vector.NewPositionTile(state.DestX, state.DestY)
+		predictedDest := player.Destination()
+
+		if position.Distance(&player.Position.Vector) <= reconcileDistance &&
+			dest.Distance(&predictedDest.Vector) <= reconcileDistance {
+			continue
+		}
+
+		player.StopMoving()
+		player.Position.Copy(&position.Vector)
+		player.Target.Copy(&position.Vector)
+
+		if !position.EqualsApprox(&dest.Vector) {
+			g.movePlayer(player, position, dest)
+		}
+	}
+
+	return nil
+}
+
+// updateMonster moves a monster of the server to its position and destination, monsters without
+// life are removed
+func (g *GameClient) updateMonster(state *d2netpacket.EntityState) {
+	monster := g.monsters[state.ID]
+	if monster == nil {
+		return
+	}
+
+	if state.Life <= 0 {
+		g.removeMonster(state.ID)
+		return
+	}
+
+	position := d2vector.NewPositionTile(state.X, state.Y)
+	dest := d2vector.NewPositionTile(state.DestX, state.DestY)
+
+	if position.Distance(&monster.Position.Vector) > reconcileDistance {
+		monster.StopMoving()
+		monster.Position.Copy(&position.Vector)
+		monster.Target.Copy(&position.Vector)
+	}
+
+	if !dest.EqualsApprox(&monster.Position.Vector) {
+		monster.SetPath([]d2vector.Position{dest}, nil)
+	}
+}
+
+// removeMonster takes the monster with the given ID off the map
+func (g *GameClient) removeMonster(id string) {
+	if monster := g.monsters[id]; monster != nil {
+		delete(g.monsters, id)
+		g.MapEngine.RemoveEntity(monster)
+		g.removeStateOverlays(id)
+	}
+}
+
+// handleCombatPacket removes the monsters killed by the attacks of the packet, the life lost by
+// the units is sent in the state deltas
+func (g *GameClient) handleCombatPacket(packet d2netpacket.NetPacket) error {
+	combat, err := d2netpacket.UnmarshalCombat(packet)
+	if err != nil {
+		return err
+	}
+
+	for idx := range combat.Events {
+		if combat.Events[idx].Type == d2combat.EventDeath {
+			g.removeMonster(combat.Events[idx].TargetID)
+		}
+	}
+
+	return nil
+}
+
+// handleSetStatePacket puts a state on a player or a monster or takes it off. The cast overlay of
+// the state plays once, its first overlay stays on the unit while the state lasts.
+func (g *GameClient) handleSetStatePacket(packet d2netpacket.NetPacket) error {
+	setState, err := d2netpacket.UnmarshalSetState(packet)
+	if err != nil {
+		return err
+	}
+
+	record := g.asset.Records.States[setState.State]
+	if record == nil {
+		return fmt.Errorf("unknown state %q", setState.State)
+	}
+
+	states, position := g.unitStates(setState.UnitID)
+	if states == nil {
+		return nil
+	}
+
+	key := setState.UnitID + "/" + setState.State
+
+	if overlay := g.stateOverlays[key]; overlay != nil {
+		g.MapEngine.RemoveEntity(overlay)
+		delete(g.stateOverlays, key)
+	}
+
+	overlays := g.asset.Records.Layout.Overlays
+	x, y := int(position.X()), int(position.Y())
+
+	if setState.Removed {
+		states.Remove(setState.State)
+		return g.playCastOverlay(overlays[record.RemOverlay], x, y)
+	}
+
+	duration := setState.Duration
+	if duration < 0 {
+		duration = d2states.Forever
+	}
+
+	states.Apply(d2states.NewState(record, "", duration))
+
+	if err := g.playCastOverlay(overlays[record.CastOverlay], x, y); err != nil {
+		return err
+	}
+
+	overlayRecord := overlays[record.Overlay1]
+	if record.NoOverlays || overlayRecord == nil {
+		return nil
+	}
+
+	overlay, err := g.MapEngine.NewCastOverlay(x, y, overlayRecord)
+	if err != nil {
+		return err
+	}
+
+	overlay.SetPlayLoop(true)
+	overlay.Follow(position)
+
+	g.stateOverlays[key] = overlay
+	g.MapEngine.AddEntity(overlay)
+
+	return nil
+}
+
+// unitStates returns the states and the position of the player or monster with the given ID, it
+// returns nil states if there is no such unit
+func (g *GameClient) unitStates(id string) (*d2states.States, *d2vector.Position) {
+	if player := g.Players[id]; player != nil {
+		return player.States(), &player.Position
+	}
+
+	if monster := g.monsters[id]; monster != nil {
+		return monster.States(), &monster.Position
+	}
+
+	return nil, nil
+}
+
+// removeStateOverlays takes the overlays of the states of the unit with the given ID off the map
+func (g *GameClient) removeStateOverlays(id string) {
+	for key, overlay := range g.stateOverlays {
+		if strings.HasPrefix(key, id+"/") {
+			g.MapEngine.RemoveEntity(overlay)
+			delete(g.stateOverlays, key)
+		}
+	}
+}
+
+// handleSpawnMonsterPacket puts a monster of the server on the map
+func (g *GameClient) handleSpawnMonsterPacket(packet d2netpacket.NetPacket) error {
+	spawn, err := d2netpacket.UnmarshalSpawnMonster(packet)
+	if err != nil {
+		return err
+	}
+
+	record := g.asset.Records.Monster.Stats[spawn.Code]
+	if record == nil {
+		return fmt.Errorf("unknown monster %q", spawn.Code)
+	}
+
+	monster, err := g.MapEngine.NewNPC(spawn.X, spawn.Y, record, 0)
+	if err != nil {
+		return err
+	}
+
+	if previous := g.monsters[spawn.MonsterID]; previous != nil {
+		g.MapEngine.RemoveEntity(previous)
+	}
+
+	g.monsters[spawn.MonsterID] = monster
+	g.MapEngine.AddEntity(monster)
+
+	return nil
+}
+
+// handleMonsterAttackPacket plays the attack of a monster and the missile it shoots
+func (g *GameClient) handleMonsterAttackPacket(packet d2netpacket.NetPacket) error {
+	attack, err := d2netpacket.UnmarshalMonsterAttack(packet)
+	if err != nil {
+		return err
+	}
+
+	monster := g.monsters[attack.MonsterID]
+	if monster == nil {
+		return nil
+	}
+
+	targetX, targetY := attack.X*numSubtilesPerTile, attack.Y*numSubtilesPerTile
+
+	monster.Attack(targetX, targetY)
+
+	if attack.Missile == "" {
+		return nil
+	}
+
+	missile, err := g.createMissileEntity(g.asset.Records.GetMissileByName(attack.Missile), monster.Position, targetX, targetY)
+	if err != nil || missile == nil {
+		return err
+	}
+
+	g.MapEngine.AddEntity(missile)
 
 	return nil
 }
 
 func (g *GameClient) handleCastSkillPacket(packet d2netpacket.NetPacket) error {
-	playerCast, err := d2netpacket.UnmarshalCast(packet.PacketData)
+	playerCast, err := d2netpacket.UnmarshalCast(packet)
 	if err != nil {
 		return err
 	}
@@ -365,7 +982,7 @@ func (g *GameClient) createMissileEntities(
 			continue
 		}
 
-		missileEntity, err := g.createMissileEntity(missileRecord, player, castX, castY)
+		missileEntity, err := g.createMissileEntity(missileRecord, player.Position, castX, castY)
 		if err != nil {
 			return nil, err
 		}
@@ -378,7 +995,7 @@ func (g *GameClient) createMissileEntities(
 
 func (g *GameClient) createMissileEntity(
 	missileRecord *d2records.MissileRecord,
-	player *d2mapentity.Player,
+	source d2vector.Position,
 	castX, castY float64,
 ) (*d2mapentity.Missile, error) {
 	if missileRecord == nil {
@@ -386,15 +1003,15 @@ func (g *GameClient) createMissileEntity(
 	}
 
 	radians := d2math.GetRadiansBetween(
-		player.Position.X(),
-		player.Position.Y(),
+		source.X(),
+		source.Y(),
 		castX,
 		castY,
 	)
 
 	missileEntity, err := g.MapEngine.NewMissile(
-		int(player.Position.X()),
-		int(player.Position.Y()),
+		int(source.X()),
+		int(source.Y()),
 		g.asset.Records.Missiles[missileRecord.Id],
 	)
 
@@ -448,13 +1065,14 @@ func (g *GameClient) handlePingPacket() error {
 }
 
 func (g *GameClient) handlePlayerDisconnectionPacket(packet d2netpacket.NetPacket) error {
-	disconnectPacket, err := d2netpacket.UnmarshalPlayerDisconnectionRequest(packet.PacketData)
+	disconnectPacket, err := d2netpacket.UnmarshalPlayerDisconnectionRequest(packet)
 	if err != nil {
 		return err
 	}
 
 	player := g.Players[disconnectPacket.ID]
 	g.MapEngine.RemoveEntity(player)
+	g.removeStateOverlays(disconnectPacket.ID)
 	delete(g.Players, disconnectPacket.ID)
 
 	return nil
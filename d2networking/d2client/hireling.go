@@ -0,0 +1,303 @@
+package d2client
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hireling"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+// HireOffer is a hireling offered to the local player, with its price
+type HireOffer struct {
+	ID       string // ID the server knows the offer by
+	Hireling *d2hireling.Hireling
+	Price    int
+}
+
+// HireOffers are the hirelings a seller offers the local player
+type HireOffers struct {
+	Seller         string  // row of the seller in monstats.txt
+	X, Y           float64 // world position the seller was asked at
+	Offers         []HireOffer
+	ResurrectPrice int // gold it costs to resurrect the dead hireling of the local player, 0 if it is alive
+}
+
+// HirelingItem returns the item the hireling of the local player wears in the given slot, nil if
+// it wears none
+func (g *GameClient) HirelingItem(slot d2enum.EquippedSlot) *diablo2item.Item {
+	if g.Hireling == nil || g.Hireling.State.Equipment[slot] == nil {
+		return nil
+	}
+
+	item, err := g.MapEngine.ItemFactory().Deserialize(g.Hireling.State.Equipment[slot])
+	if err != nil {
+		g.Errorf("item of the hireling in slot %d: %v", slot, err)
+		return nil
+	}
+
+	return item
+}
+
+// HirelingSlot returns the slot the hireling of the local player wears the given item in,
+// EquippedSlotNone if it cannot wear the item
+func (g *GameClient) HirelingSlot(item *diablo2item.Item) d2enum.EquippedSlot {
+	if g.Hireling == nil || item.CommonRecord() == nil {
+		return d2enum.EquippedSlotNone
+	}
+
+	itemTypes := g.asset.Records.FindEquivalentTypesByItemCommonRecord(item.CommonRecord())
+
+	for _, slot := range []d2enum.EquippedSlot{
+		d2enum.EquippedSlotRightArm,
+		d2enum.EquippedSlotLeftArm,
+		d2enum.EquippedSlotHead,
+		d2enum.EquippedSlotTorso,
+	} {
+		if g.Hireling.CanEquip(slot, itemTypes) {
+			return slot
+		}
+	}
+
+	return d2enum.EquippedSlotNone
+}
+
+// WearableItems returns the items of the local player its hireling can wear
+func (g *GameClient) WearableItems() []ShopOffer {
+	offers := make([]ShopOffer, 0)
+
+	for id, item := range g.Inventory {
+		if g.HirelingSlot(item) != d2enum.EquippedSlotNone {
+			offers = append(offers, ShopOffer{ID: id, Item: item})
+		}
+	}
+
+	sort.Slice(offers, func(i, j int) bool { return offers[i].ID < offers[j].ID })
+
+	return offers
+}
+
+// RequestHireOffers asks the seller of hirelings at the given world position for its offers
+func (g *GameClient) RequestHireOffers(seller string, x, y float64) error {
+	packet, err := d2netpacket.CreateHirePacket(g.PlayerID, seller, x, y, d2netpacket.HireOffers, "", d2enum.EquippedSlotNone)
+	if err != nil {
+		return err
+	}
+
+	// the seller keeps walking around, the hirelings are hired where it was asked
+	g.HireOffers = &HireOffers{Seller: seller, X: x, Y: y}
+
+	return g.SendPacketToServer(packet)
+}
+
+// Hire asks the server to hire the offer with the given ID or to resurrect the hireling of the
+// local player, at the seller which was asked for its offers
+func (g *GameClient) Hire(action d2netpacket.HireAction, offerID string) error {
+	if g.HireOffers == nil {
+		return nil
+	}
+
+	packet, err := d2netpacket.CreateHirePacket(g.PlayerID, g.HireOffers.Seller, g.HireOffers.X, g.HireOffers.Y,
+		action, offerID, d2enum.EquippedSlotNone)
+	if err != nil {
+		return err
+	}
+
+	return g.SendPacketToServer(packet)
+}
+
+// EquipHireling asks the server to give the item of the local player with the given ID to its
+// hireling, or to take the item in the slot off the hireling if the ID is empty
+func (g *GameClient) EquipHireling(itemID string, slot d2enum.EquippedSlot) error {
+	action := d2netpacket.HireEquip
+	if itemID == "" {
+		action = d2netpacket.HireUnequip
+	}
+
+	packet, err := d2netpacket.CreateHirePacket(g.PlayerID, "", 0, 0, action, itemID, slot)
+	if err != nil {
+		return err
+	}
+
+	return g.SendPacketToServer(packet)
+}
+
+// CloseHireOffers forgets the offers of the seller
+func (g *GameClient) CloseHireOffers() {
+	g.HireOffers = nil
+}
+
+// openHireOffers asks a seller of hirelings close to the local player for its offers, it returns
+// true if there is one. The sellers which are vendors too offer their hirelings in their shops.
+func (g *GameClient) openHireOffers(player *d2mapentity.Player) bool {
+	position := player.Position.World()
+
+	for _, entity := range g.MapEngine.Entities() {
+		npc, ok := entity.(*d2mapentity.NPC)
+		if !ok || npc.MonStatRecord() == nil || d2hireling.SellerAct(npc.MonStatRecord().Key) == 0 {
+			continue
+		}
+
+		npcPosition := npc.GetPosition()
+		npcWorld := npcPosition.World()
+
+		if npcWorld.Distance(position) > interactDistance {
+			continue
+		}
+
+		if err := g.RequestHireOffers(npc.MonStatRecord().Key, npcWorld.X(), npcWorld.Y()); err != nil {
+			g.Errorf("GameClient: error sending HirePacket: %s", err)
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (g *GameClient) hirelingFactory() *d2hireling.Factory {
+	if g.hireFactory == nil {
+		g.hireFactory = d2hireling.NewFactory(g.asset.Records, g.Seed)
+	}
+
+	return g.hireFactory
+}
+
+// handleHirelingOffersPacket shows the hirelings a seller offers
+func (g *GameClient) handleHirelingOffersPacket(packet d2netpacket.NetPacket) error {
+	offers, err := d2netpacket.UnmarshalHirelingOffers(packet)
+	if err != nil {
+		return err
+	}
+
+	hireOffers := &HireOffers{
+		Seller:         offers.Seller,
+		Offers:         make([]HireOffer, 0, len(offers.Offers)),
+		ResurrectPrice: offers.ResurrectPrice,
+	}
+
+	if g.HireOffers != nil {
+		hireOffers.X, hireOffers.Y = g.HireOffers.X, g.HireOffers.Y
+	}
+
+	for idx := range offers.Offers {
+		state := offers.Offers[idx].Hireling
+
+		hireling, err := g.hirelingFactory().Load(&state)
+		if err != nil {
+			return err
+		}
+
+		hireOffers.Offers = append(hireOffers.Offers, HireOffer{ID: offers.Offers[idx].ID, Hireling: hireling,
+			Price: offers.Offers[idx].Price})
+	}
+
+	g.HireOffers = hireOffers
+	g.HirelingChanged = true
+
+	return nil
+}
+
+// handleHirePacket applies a hire action the server has done for the local player, the hireling
+// itself is sent in its own packet
+func (g *GameClient) handleHirePacket(packet d2netpacket.NetPacket) error {
+	hire, err := d2netpacket.UnmarshalHire(packet)
+	if err != nil {
+		return err
+	}
+
+	if hire.PlayerID != g.PlayerID {
+		return nil
+	}
+
+	if g.GameState != nil {
+		g.GameState.Gold = hire.Gold
+	}
+
+	if player := g.Players[g.PlayerID]; player != nil {
+		player.Gold = hire.Gold
+	}
+
+	switch hire.Action {
+	case d2netpacket.HireHire:
+		g.removeHireOffer(hire.ID)
+	case d2netpacket.HireResurrect:
+		if g.HireOffers != nil {
+			g.HireOffers.ResurrectPrice = 0
+		}
+	case d2netpacket.HireEquip:
+		delete(g.Inventory, hire.ID)
+	case d2netpacket.HireUnequip:
+		item, err := g.MapEngine.ItemFactory().Deserialize(hire.Item)
+		if err != nil {
+			return err
+		}
+
+		g.Inventory[hire.ID] = item
+	}
+
+	g.HirelingChanged = true
+
+	return nil
+}
+
+func (g *GameClient) removeHireOffer(id string) {
+	if g.HireOffers == nil {
+		return
+	}
+
+	for idx := range g.HireOffers.Offers {
+		if g.HireOffers.Offers[idx].ID == id {
+			g.HireOffers.Offers = append(g.HireOffers.Offers[:idx], g.HireOffers.Offers[idx+1:]...)
+			return
+		}
+	}
+}
+
+// handleHirelingPacket puts the hireling of a player on the map, or takes it off if the hireling
+// is not on the map of the server. The server keeps the state of the hirelings with the heroes, the
+// client keeps a copy of the hireling of the local player.
+func (g *GameClient) handleHirelingPacket(packet d2netpacket.NetPacket) error {
+	hireling, err := d2netpacket.UnmarshalHireling(packet)
+	if err != nil {
+		return err
+	}
+
+	if unitID, found := g.hirelingUnits[hireling.PlayerID]; found && unitID != hireling.HirelingID {
+		g.removeMonster(unitID)
+		delete(g.hirelingUnits, hireling.PlayerID)
+	}
+
+	if hireling.HirelingID != "" && g.monsters[hireling.HirelingID] == nil {
+		record := g.asset.Records.Monster.Stats[hireling.Code]
+		if record == nil {
+			return fmt.Errorf("unknown hireling %q", hireling.Code)
+		}
+
+		npc, err := g.MapEngine.NewNPC(hireling.X, hireling.Y, record, 0)
+		if err != nil {
+			return err
+		}
+
+		g.monsters[hireling.HirelingID] = npc
+		g.hirelingUnits[hireling.PlayerID] = hireling.HirelingID
+		g.MapEngine.AddEntity(npc)
+	}
+
+	if hireling.PlayerID != g.PlayerID {
+		return nil
+	}
+
+	state := hireling.Hireling
+
+	if g.Hireling, err = g.hirelingFactory().Load(&state); err != nil {
+		return err
+	}
+
+	g.HirelingChanged = true
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package d2client
+
+import (
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+// ShopOffer is an item offered in a trade, with its price
+type ShopOffer struct {
+	ID    string // ID the server knows the item by
+	Item  *diablo2item.Item
+	Price int
+}
+
+// Shop is the trade screen of a vendor the local player has opened
+type Shop struct {
+	Vendor   string  // row of the vendor in monstats.txt
+	X, Y     float64 // world position the vendor was traded with at
+	Gambling bool    // the offers are gambled on instead of bought
+	Offers   []ShopOffer
+}
+
+// SellOffers returns the items of the local player with what the vendor of the open shop pays
+// for them
+func (g *GameClient) SellOffers() []ShopOffer {
+	return g.inventoryOffers(g.MapEngine.ItemFactory().SellPrice)
+}
+
+// RepairOffers returns the worn items of the local player with what the vendor of the open shop
+// asks for repairing them
+func (g *GameClient) RepairOffers() []ShopOffer {
+	offers := make([]ShopOffer, 0)
+
+	for _, offer := range g.inventoryOffers(g.MapEngine.ItemFactory().RepairPrice) {
+		if current, max := offer.Item.Durability(); current < max {
+			offers = append(offers, offer)
+		}
+	}
+
+	return offers
+}
+
+func (g *GameClient) inventoryOffers(price func(*diablo2item.Item, *diablo2item.ShopContext) int) []ShopOffer {
+	if g.Shop == nil {
+		return nil
+	}
+
+	ctx := g.shopContext()
+	offers := make([]ShopOffer, 0, len(g.Inventory))
+
+	for id, item := range g.Inventory {
+		offers = append(offers, ShopOffer{ID: id, Item: item, Price: price(item, ctx)})
+	}
+
+	sort.Slice(offers, func(i, j int) bool { return offers[i].ID < offers[j].ID })
+
+	return offers
+}
+
+func (g *GameClient) shopContext() *diablo2item.ShopContext {
+	ctx := &diablo2item.ShopContext{Vendor: g.Shop.Vendor}
+
+	if g.GameState != nil {
+		ctx.Difficulty = g.GameState.Difficulty
+
+		if g.GameState.Stats != nil {
+			ctx.Level = g.GameState.Stats.Level
+		}
+	}
+
+	return ctx
+}
+
+// Trade asks the server to trade the given item with the vendor of the open shop
+func (g *GameClient) Trade(action d2netpacket.TradeAction, itemID string) error {
+	if g.Shop == nil {
+		return nil
+	}
+
+	packet, err := d2netpacket.CreateTradePacket(g.PlayerID, g.Shop.Vendor, g.Shop.X, g.Shop.Y, action, itemID)
+	if err != nil {
+		return err
+	}
+
+	return g.SendPacketToServer(packet)
+}
+
+// CloseShop forgets the open shop
+func (g *GameClient) CloseShop() {
+	g.Shop = nil
+}
+
+// openShop asks the server for the items of a vendor close to the local player, it returns true
+// if there is one
+func (g *GameClient) openShop(player *d2mapentity.Player) bool {
+	position := player.Position.World()
+
+	for _, entity := range g.MapEngine.Entities() {
+		npc, ok := entity.(*d2mapentity.NPC)
+		if !ok || npc.MonStatRecord() == nil || !diablo2item.IsVendor(npc.MonStatRecord().Key) {
+			continue
+		}
+
+		npcPosition := npc.GetPosition()
+		npcWorld := npcPosition.World()
+
+		if npcWorld.Distance(position) > interactDistance {
+			continue
+		}
+
+		packet, err := d2netpacket.CreateTradePacket(g.PlayerID, npc.MonStatRecord().Key, npcWorld.X(), npcWorld.Y(),
+			d2netpacket.TradeOpen, "")
+		if err != nil {
+			g.Errorf("TradePacket: %v", err)
+			return true
+		}
+
+		if err := g.SendPacketToServer(packet); err != nil {
+			g.Errorf("GameClient: error sending TradePacket: %s", err)
+		}
+
+		// the vendor keeps walking around, it is traded with where the shop was opened
+		g.vendorX, g.vendorY = npcWorld.X(), npcWorld.Y()
+
+		return true
+	}
+
+	return false
+}
+
+// handleShopInventoryPacket opens the shop of a vendor with the items it offers
+func (g *GameClient) handleShopInventoryPacket(packet d2netpacket.NetPacket) error {
+	inventory, err := d2netpacket.UnmarshalShopInventory(packet)
+	if err != nil {
+		return err
+	}
+
+	shop := &Shop{
+		Vendor:   inventory.Vendor,
+		X:        g.vendorX,
+		Y:        g.vendorY,
+		Gambling: inventory.Gambling,
+		Offers:   make([]ShopOffer, 0, len(inventory.Items)),
+	}
+
+	for idx := range inventory.Items {
+		item, err := g.MapEngine.ItemFactory().Deserialize(inventory.Items[idx].Item)
+		if err != nil {
+			return err
+		}
+
+		shop.Offers = append(shop.Offers, ShopOffer{ID: inventory.Items[idx].ID, Item: item, Price: inventory.Items[idx].Price})
+	}
+
+	g.Shop = shop
+	g.ShopChanged = true
+
+	return nil
+}
+
+// handleInventoryPacket replaces the items of the local player with the items of its hero
+func (g *GameClient) handleInventoryPacket(packet d2netpacket.NetPacket) error {
+	inventory, err := d2netpacket.UnmarshalInventory(packet)
+	if err != nil {
+		return err
+	}
+
+	if inventory.PlayerID != g.PlayerID {
+		return nil
+	}
+
+	items := make(map[string]*diablo2item.Item, len(inventory.Items))
+
+	for idx := range inventory.Items {
+		owned := &inventory.Items[idx]
+
+		item, err := g.MapEngine.ItemFactory().Deserialize(owned.Item)
+		if err != nil {
+			return err
+		}
+
+		items[owned.ID] = item
+	}
+
+	g.Inventory = items
+
+	return nil
+}
+
+// handleTradePacket applies a trade the server has done for the local player
+func (g *GameClient) handleTradePacket(packet d2netpacket.NetPacket) error {
+	trade, err := d2netpacket.UnmarshalTrade(packet)
+	if err != nil {
+		return err
+	}
+
+	if trade.PlayerID != g.PlayerID {
+		return nil
+	}
+
+	if g.GameState != nil {
+		g.GameState.Gold = trade.Gold
+	}
+
+	if player := g.Players[g.PlayerID]; player != nil {
+		player.Gold = trade.Gold
+	}
+
+	var item *diablo2item.Item
+
+	if len(trade.Item) > 0 {
+		if item, err = g.MapEngine.ItemFactory().Deserialize(trade.Item); err != nil {
+			return err
+		}
+	}
+
+	switch trade.Action {
+	case d2netpacket.TradeBuy:
+		g.removeOffer(trade.ItemID)
+		g.Inventory[trade.ItemID] = item
+	case d2netpacket.TradeGamble:
+		g.Inventory[trade.ItemID] = item
+	case d2netpacket.TradeSell:
+		delete(g.Inventory, trade.ItemID)
+	case d2netpacket.TradeRepair:
+		for id, owned := range g.Inventory {
+			if trade.ItemID == "" || id == trade.ItemID {
+				owned.Repair()
+			}
+		}
+	}
+
+	g.ShopChanged = true
+
+	return nil
+}
+
+func (g *GameClient) removeOffer(id string) {
+	if g.Shop == nil {
+		return
+	}
+
+	for idx := range g.Shop.Offers {
+		if g.Shop.Offers[idx].ID == id {
+			g.Shop.Offers = append(g.Shop.Offers[:idx], g.Shop.Offers[idx+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package d2netpacket
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// BinaryVersion is the newest version of the binary encoding. A binary frame is laid out as
+//
+//	version (1 byte) | length (uvarint) | packet type (uvarint) | body
+//
+// where the length counts the bytes of the packet type and the body. Integers in the bodies
+// are varints and positions are fixed-point numbers with fixedPointBits fraction bits.
+const BinaryVersion = 1
+
+const (
+	// MaxPacketSize is the largest packet a connection accepts, in either encoding
+	MaxPacketSize = 1 << 20
+
+	fixedPointBits = 8
+	fixedPointOne  = 1 << fixedPointBits
+	maxFixedPoint  = 1 << 52 // beyond this a float64 cannot hold the fraction bits
+)
+
+// ErrMalformedPacket is returned for packets which cannot be decoded. The packets before and
+// after a malformed packet can still be read.
+var ErrMalformedPacket = errors.New("malformed packet")
+
+// NegotiateBinaryVersion returns the binary version to use with a peer which supports the binary
+// versions up to the given one. A version of 0 selects the JSON encoding.
+func NegotiateBinaryVersion(peerVersion int) int {
+	if peerVersion <= 0 {
+		return 0
+	}
+
+	if peerVersion > BinaryVersion {
+		return BinaryVersion
+	}
+
+	return peerVersion
+}
+
+// MarshalBinaryPacket encodes the body of the packet to a binary frame of the given version
+func MarshalBinaryPacket(packet NetPacket, version int) ([]byte, error) {
+	if version != BinaryVersion {
+		return nil, fmt.Errorf("unsupported binary version %d", version)
+	}
+
+	empty, found := newBinaryPacket(packet.PacketType)
+	if !found {
+		return nil, fmt.Errorf("packet %s has no binary encoding", packet.PacketType)
+	}
+
+	body, ok := packet.Body.(binaryPacket)
+	if !ok || reflect.TypeOf(body) != reflect.TypeOf(empty) {
+		return nil, fmt.Errorf("packet %s has a body of type %T", packet.PacketType, packet.Body)
+	}
+
+	payload := &binaryWriter{}
+	payload.uvarint(uint64(packet.PacketType))
+	body.writeBinary(payload)
+
+	frame := &binaryWriter{data: make([]byte, 0, len(payload.data)+binary.MaxVarintLen64+1)}
+	frame.data = append(frame.data, byte(version))
+	frame.uvarint(uint64(len(payload.data)))
+	frame.data = append(frame.data, payload.data...)
+
+	return frame.data, nil
+}
+
+// UnmarshalBinaryPacket decodes a binary frame, as created by MarshalBinaryPacket, to a packet
+// with the decoded body and no JSON data
+func UnmarshalBinaryPacket(frame []byte) (NetPacket, error) {
+	if len(frame) == 0 {
+		return NetPacket{}, fmt.Errorf("%w: empty frame", ErrMalformedPacket)
+	}
+
+	reader := &binaryReader{data: frame[1:]}
+	length := reader.uvarint()
+
+	if reader.err != nil || length != uint64(reader.remaining()) {
+		return NetPacket{}, fmt.Errorf("%w: bad frame length", ErrMalformedPacket)
+	}
+
+	return decodeBinaryPayload(int(frame[0]), reader.data[reader.pos:])
+}
+
+// decodeBinaryPayload decodes the packet type and body of a frame of the given version
+func decodeBinaryPayload(version int, payload []byte) (NetPacket, error) {
+	if version != BinaryVersion {
+		return NetPacket{}, fmt.Errorf("%w: unsupported binary version %d", ErrMalformedPacket, version)
+	}
+
+	reader := &binaryReader{data: payload}
+	packetType := d2netpackettype.NetPacketType(reader.uvarint())
+
+	body, found := newBinaryPacket(packetType)
+	if reader.err != nil || !found {
+		return NetPacket{}, fmt.Errorf("%w: unknown packet type %d", ErrMalformedPacket, packetType)
+	}
+
+	body.readBinary(reader)
+
+	if reader.err == nil && reader.remaining() > 0 {
+		reader.fail("trailing bytes")
+	}
+
+	if reader.err != nil {
+		return NetPacket{}, fmt.Errorf("%w: %s: %v", ErrMalformedPacket, packetType, reader.err)
+	}
+
+	return NetPacket{PacketType: packetType, Body: body}, nil
+}
+
+// binaryPacket is a packet body with a binary encoding
+type binaryPacket interface {
+	writeBinary(w *binaryWriter)
+	readBinary(r *binaryReader)
+}
+
+// binaryWriter appends the values of a binary packet body
+type binaryWriter struct {
+	data []byte
+}
+
+func (w *binaryWriter) uvarint(value uint64) {
+	var buf [binary.MaxVarintLen64]byte
+
+	w.data = append(w.data, buf[:binary.PutUvarint(buf[:], value)]...)
+}
+
+func (w *binaryWriter) varint(value int64) {
+	var buf [binary.MaxVarintLen64]byte
+
+	w.data = append(w.data, buf[:binary.PutVarint(buf[:], value)]...)
+}
+
+func (w *binaryWriter) int(value int) {
+	w.varint(int64(value))
+}
+
+func (w *binaryWriter) bool(value bool) {
+	if value {
+		w.data = append(w.data, 1)
+		return
+	}
+
+	w.data = append(w.data, 0)
+}
+
+func (w *binaryWriter) string(value string) {
+	w.uvarint(uint64(len(value)))
+	w.data = append(w.data, value...)
+}
+
+// fixed writes a fixed-point number, the values out of range are clamped
+func (w *binaryWriter) fixed(value float64) {
+	scaled := math.Round(value * fixedPointOne)
+
+	switch {
+	case math.IsNaN(scaled):
+		scaled = 0
+	case scaled > maxFixedPoint:
+		scaled = maxFixedPoint
+	case scaled < -maxFixedPoint:
+		scaled = -maxFixedPoint
+	}
+
+	w.varint(int64(scaled))
+}
+
+func (w *binaryWriter) time(value time.Time) {
+	w.varint(value.UnixNano())
+}
+
+// binaryReader reads the values of a binary packet body. The first error is kept and all reads
+// after it return zero values, so a body is decoded without checking every value.
+type binaryReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *binaryReader) fail(reason string) {
+	if r.err == nil {
+		r.err = errors.New(reason)
+	}
+}
+
+func (r *binaryReader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	value, size := binary.Uvarint(r.data[r.pos:])
+	if size <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+
+	r.pos += size
+
+	return value
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+
+	value, size := binary.Varint(r.data[r.pos:])
+	if size <= 0 {
+		r.fail("bad varint")
+		return 0
+	}
+
+	r.pos += size
+
+	return value
+}
+
+func (r *binaryReader) int() int {
+	value := r.varint()
+
+	if value > math.MaxInt32 || value < math.MinInt32 {
+		r.fail("integer out of range")
+		return 0
+	}
+
+	return int(value)
+}
+
+func (r *binaryReader) bool() bool {
+	if r.err != nil {
+		return false
+	}
+
+	if r.remaining() < 1 {
+		r.fail("unexpected end of packet")
+		return false
+	}
+
+	value := r.data[r.pos]
+	r.pos++
+
+	if value > 1 {
+		r.fail("bad bool")
+	}
+
+	return value == 1
+}
+
+func (r *binaryReader) string() string {
+	length := r.uvarint()
+
+	if r.err != nil {
+		return ""
+	}
+
+	if length > uint64(r.remaining()) {
+		r.fail("string longer than the packet")
+		return ""
+	}
+
+	value := string(r.data[r.pos : r.pos+int(length)])
+	r.pos += int(length)
+
+	return value
+}
+
+// count reads the number of elements of a list, each element takes at least one byte
+func (r *binaryReader) count() int {
+	count := r.uvarint()
+
+	if count > uint64(r.remaining()) {
+		r.fail("list longer than the packet")
+		return 0
+	}
+
+	return int(count)
+}
+
+func (r *binaryReader) fixed() float64 {
+	value := r.varint()
+
+	if value > maxFixedPoint || value < -maxFixedPoint {
+		r.fail("fixed-point number out of range")
+		return 0
+	}
+
+	return float64(value) / fixedPointOne
+}
+
+func (r *binaryReader) time() time.Time {
+	return time.Unix(0, r.varint()).UTC()
+}
@@ -0,0 +1,975 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2inventory"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// newBinaryPacket returns an empty body of the given packet type
+//
+//nolint:gocyclo // switch statement on packet type makes sense, no need to change
+func newBinaryPacket(packetType d2netpackettype.NetPacketType) (binaryPacket, bool) {
+	switch packetType {
+	case d2netpackettype.UpdateServerInfo:
+		return &UpdateServerInfoPacket{}, true
+	case d2netpackettype.GenerateMap:
+		return &GenerateMapPacket{}, true
+	case d2netpackettype.AddPlayer:
+		return &AddPlayerPacket{}, true
+	case d2netpackettype.MovePlayer:
+		return &MovePlayerPacket{}, true
+	case d2netpackettype.PlayerConnectionRequest:
+		return &PlayerConnectionRequestPacket{}, true
+	case d2netpackettype.PlayerDisconnectionNotification:
+		return &PlayerDisconnectRequestPacket{}, true
+	case d2netpackettype.Ping:
+		return &PingPacket{}, true
+	case d2netpackettype.Pong:
+		return &PongPacket{}, true
+	case d2netpackettype.ServerClosed:
+		return &ServerClosedPacket{}, true
+	case d2netpackettype.CastSkill:
+		return &CastPacket{}, true
+	case d2netpackettype.SpawnItem:
+		return &SpawnItemPacket{}, true
+	case d2netpackettype.SavePlayer:
+		return &SavePlayerPacket{}, true
+	case d2netpackettype.ServerFull:
+		return &ServerFullPacket{}, true
+	case d2netpackettype.StateDelta:
+		return &StateDeltaPacket{}, true
+	case d2netpackettype.WarpPlayer:
+		return &WarpPlayerPacket{}, true
+	case d2netpackettype.PickUpItem:
+		return &PickUpItemPacket{}, true
+	case d2netpackettype.OperateObject:
+		return &OperateObjectPacket{}, true
+	case d2netpackettype.SpawnMonster:
+		return &SpawnMonsterPacket{}, true
+	case d2netpackettype.MonsterAttack:
+		return &MonsterAttackPacket{}, true
+	case d2netpackettype.Combat:
+		return &CombatPacket{}, true
+	case d2netpackettype.SetState:
+		return &SetStatePacket{}, true
+	case d2netpackettype.Trade:
+		return &TradePacket{}, true
+	case d2netpackettype.ShopInventory:
+		return &ShopInventoryPacket{}, true
+	case d2netpackettype.Hire:
+		return &HirePacket{}, true
+	case d2netpackettype.HirelingOffers:
+		return &HirelingOffersPacket{}, true
+	case d2netpackettype.Hireling:
+		return &HirelingPacket{}, true
+	case d2netpackettype.Automap:
+		return &AutomapPacket{}, true
+	case d2netpackettype.ObjectState:
+		return &ObjectStatePacket{}, true
+	case d2netpackettype.Inventory:
+		return &InventoryPacket{}, true
+	}
+
+	return nil, false
+}
+
+func (p *UpdateServerInfoPacket) writeBinary(w *binaryWriter) {
+	w.varint(p.Seed)
+	w.string(p.PlayerID)
+	w.int(p.BinaryVersion)
+}
+
+func (p *UpdateServerInfoPacket) readBinary(r *binaryReader) {
+	p.Seed = r.varint()
+	p.PlayerID = r.string()
+	p.BinaryVersion = r.int()
+}
+
+func (p *GenerateMapPacket) writeBinary(w *binaryWriter) {
+	w.int(p.LevelID)
+	w.int(int(p.RegionType))
+}
+
+func (p *GenerateMapPacket) readBinary(r *binaryReader) {
+	p.LevelID = r.int()
+	p.RegionType = d2enum.RegionIdType(r.int())
+}
+
+func (p *AddPlayerPacket) writeBinary(w *binaryWriter) {
+	w.string(p.ID)
+	w.string(p.Name)
+	w.int(p.X)
+	w.int(p.Y)
+	w.int(int(p.HeroType))
+	writeEquipment(w, &p.Equipment)
+	writeStats(w, p.Stats)
+	writeSkills(w, p.Skills)
+	w.int(p.LeftSkill)
+	w.int(p.RightSkill)
+	w.int(p.Gold)
+}
+
+func (p *AddPlayerPacket) readBinary(r *binaryReader) {
+	p.ID = r.string()
+	p.Name = r.string()
+	p.X = r.int()
+	p.Y = r.int()
+	p.HeroType = d2enum.Hero(r.int())
+	p.Equipment = readEquipment(r)
+	p.Stats = readStats(r)
+	p.Skills = readSkills(r)
+	p.LeftSkill = r.int()
+	p.RightSkill = r.int()
+	p.Gold = r.int()
+}
+
+func (p *MovePlayerPacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.fixed(p.StartX)
+	w.fixed(p.StartY)
+	w.fixed(p.DestX)
+	w.fixed(p.DestY)
+	w.bool(p.Running)
+}
+
+func (p *MovePlayerPacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.StartX = r.fixed()
+	p.StartY = r.fixed()
+	p.DestX = r.fixed()
+	p.DestY = r.fixed()
+	p.Running = r.bool()
+}
+
+func (p *PlayerConnectionRequestPacket) writeBinary(w *binaryWriter) {
+	w.string(p.ID)
+	writeHeroState(w, p.PlayerState)
+	w.int(p.BinaryVersion)
+}
+
+func (p *PlayerConnectionRequestPacket) readBinary(r *binaryReader) {
+	p.ID = r.string()
+	p.PlayerState = readHeroState(r)
+	p.BinaryVersion = r.int()
+}
+
+func (p *PlayerDisconnectRequestPacket) writeBinary(w *binaryWriter) {
+	w.string(p.ID)
+	writeHeroState(w, p.PlayerState)
+}
+
+func (p *PlayerDisconnectRequestPacket) readBinary(r *binaryReader) {
+	p.ID = r.string()
+	p.PlayerState = readHeroState(r)
+}
+
+func (p *PingPacket) writeBinary(w *binaryWriter) {
+	w.time(p.TS)
+}
+
+func (p *PingPacket) readBinary(r *binaryReader) {
+	p.TS = r.time()
+}
+
+func (p *PongPacket) writeBinary(w *binaryWriter) {
+	w.string(p.ID)
+	w.time(p.TS)
+}
+
+func (p *PongPacket) readBinary(r *binaryReader) {
+	p.ID = r.string()
+	p.TS = r.time()
+}
+
+func (p *ServerClosedPacket) writeBinary(w *binaryWriter) {
+	w.time(p.TS)
+}
+
+func (p *ServerClosedPacket) readBinary(r *binaryReader) {
+	p.TS = r.time()
+}
+
+func (p *CastPacket) writeBinary(w *binaryWriter) {
+	w.string(p.SourceEntityID)
+	w.int(p.SkillID)
+	w.fixed(p.TargetX)
+	w.fixed(p.TargetY)
+	w.string(p.TargetEntityID)
+}
+
+func (p *CastPacket) readBinary(r *binaryReader) {
+	p.SourceEntityID = r.string()
+	p.SkillID = r.int()
+	p.TargetX = r.fixed()
+	p.TargetY = r.fixed()
+	p.TargetEntityID = r.string()
+}
+
+func (p *SpawnItemPacket) writeBinary(w *binaryWriter) {
+	w.int(p.X)
+	w.int(p.Y)
+	w.uvarint(uint64(len(p.Codes)))
+
+	for _, code := range p.Codes {
+		w.string(code)
+	}
+
+	w.string(p.ItemID)
+	w.string(p.OwnerID)
+	w.string(string(p.Item))
+	w.int(p.Quantity)
+}
+
+func (p *SpawnItemPacket) readBinary(r *binaryReader) {
+	p.X = r.int()
+	p.Y = r.int()
+	p.Codes = make([]string, r.count())
+
+	for idx := range p.Codes {
+		p.Codes[idx] = r.string()
+	}
+
+	p.ItemID = r.string()
+	p.OwnerID = r.string()
+
+	if item := r.string(); item != "" {
+		p.Item = []byte(item)
+	}
+
+	p.Quantity = r.int()
+}
+
+// writeBinary writes the parts of the player which the server uses to save it
+func (p *SavePlayerPacket) writeBinary(w *binaryWriter) {
+	w.int(int(p.Difficulty))
+	w.bool(p.Player != nil)
+
+	if p.Player == nil {
+		return
+	}
+
+	w.int(int(p.Player.Class))
+	w.int(p.Player.Act)
+	w.int(p.Player.Gold)
+	w.bool(p.Player.Equipment != nil)
+
+	if p.Player.Equipment != nil {
+		writeEquipment(w, p.Player.Equipment)
+	}
+
+	writeStats(w, p.Player.Stats)
+	writeSkills(w, p.Player.Skills)
+	writeSkill(w, p.Player.LeftSkill)
+	writeSkill(w, p.Player.RightSkill)
+}
+
+func (p *SavePlayerPacket) readBinary(r *binaryReader) {
+	p.Difficulty = d2enum.DifficultyType(r.int())
+
+	if !r.bool() {
+		return
+	}
+
+	p.Player = &d2mapentity.Player{}
+	p.Player.Class = d2enum.Hero(r.int())
+	p.Player.Act = r.int()
+	p.Player.Gold = r.int()
+
+	if r.bool() {
+		equipment := readEquipment(r)
+		p.Player.Equipment = &equipment
+	}
+
+	p.Player.Stats = readStats(r)
+	p.Player.Skills = readSkills(r)
+	p.Player.LeftSkill = readSkill(r)
+	p.Player.RightSkill = readSkill(r)
+}
+
+func (p *ServerFullPacket) writeBinary(_ *binaryWriter) {}
+
+func (p *ServerFullPacket) readBinary(_ *binaryReader) {}
+
+func (p *StateDeltaPacket) writeBinary(w *binaryWriter) {
+	w.uvarint(p.Tick)
+	w.uvarint(uint64(len(p.Entities)))
+
+	for idx := range p.Entities {
+		entity := &p.Entities[idx]
+
+		w.string(entity.ID)
+		w.fixed(entity.X)
+		w.fixed(entity.Y)
+		w.fixed(entity.DestX)
+		w.fixed(entity.DestY)
+		w.int(entity.Life)
+		w.int(entity.MaxLife)
+		w.int(entity.Mana)
+		w.int(entity.MaxMana)
+	}
+}
+
+func (p *StateDeltaPacket) readBinary(r *binaryReader) {
+	p.Tick = r.uvarint()
+	p.Entities = make([]EntityState, r.count())
+
+	for idx := range p.Entities {
+		entity := &p.Entities[idx]
+
+		entity.ID = r.string()
+		entity.X = r.fixed()
+		entity.Y = r.fixed()
+		entity.DestX = r.fixed()
+		entity.DestY = r.fixed()
+		entity.Life = r.int()
+		entity.MaxLife = r.int()
+		entity.Mana = r.int()
+		entity.MaxMana = r.int()
+	}
+}
+
+func writeHeroState(w *binaryWriter, state *d2hero.HeroState) {
+	w.bool(state != nil)
+
+	if state == nil {
+		return
+	}
+
+	w.string(state.HeroName)
+	w.int(int(state.HeroType))
+	w.int(state.Act)
+	writeEquipment(w, &state.Equipment)
+	writeStats(w, state.Stats)
+	writeSkills(w, state.Skills)
+	w.fixed(state.X)
+	w.fixed(state.Y)
+	w.int(state.LeftSkill)
+	w.int(state.RightSkill)
+	w.int(state.Gold)
+	w.int(int(state.Difficulty))
+	w.bool(state.Hireling != nil)
+
+	if state.Hireling != nil {
+		writeHireling(w, state.Hireling)
+	}
+
+	writeAutomap(w, state.Automap)
+	writeInts(w, state.Waypoints)
+	writeItems(w, state.Items)
+}
+
+func readHeroState(r *binaryReader) *d2hero.HeroState {
+	if !r.bool() {
+		return nil
+	}
+
+	state := &d2hero.HeroState{
+		HeroName:   r.string(),
+		HeroType:   d2enum.Hero(r.int()),
+		Act:        r.int(),
+		Equipment:  readEquipment(r),
+		Stats:      readStats(r),
+		Skills:     readSkills(r),
+		X:          r.fixed(),
+		Y:          r.fixed(),
+		LeftSkill:  r.int(),
+		RightSkill: r.int(),
+		Gold:       r.int(),
+		Difficulty: d2enum.DifficultyType(r.int()),
+	}
+
+	if r.bool() {
+		hireling := readHireling(r)
+		state.Hireling = &hireling
+	}
+
+	state.Automap = readAutomap(r)
+	state.Waypoints = readInts(r)
+	state.Items = readItems(r)
+
+	return state
+}
+
+// writeItems writes the items of a hero as JSON, the saved item format needs the records to be
+// written and read
+func writeItems(w *binaryWriter, items map[string]*d2s.Item) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		data = nil
+	}
+
+	w.string(string(data))
+}
+
+func readItems(r *binaryReader) map[string]*d2s.Item {
+	data := r.string()
+	if r.err != nil || data == "" || data == "null" {
+		return nil
+	}
+
+	var items map[string]*d2s.Item
+	if err := json.Unmarshal([]byte(data), &items); err != nil {
+		r.fail("malformed items")
+		return nil
+	}
+
+	return items
+}
+
+func writeAutomap(w *binaryWriter, automap map[int][]byte) {
+	levelIDs := make([]int, 0, len(automap))
+	for levelID := range automap {
+		levelIDs = append(levelIDs, levelID)
+	}
+
+	sort.Ints(levelIDs)
+	w.uvarint(uint64(len(levelIDs)))
+
+	for _, levelID := range levelIDs {
+		w.int(levelID)
+		w.string(string(automap[levelID]))
+	}
+}
+
+func readAutomap(r *binaryReader) map[int][]byte {
+	count := r.count()
+	if count == 0 || r.err != nil {
+		return nil
+	}
+
+	automap := make(map[int][]byte, count)
+
+	for idx := 0; idx < count && r.err == nil; idx++ {
+		levelID := r.int()
+		automap[levelID] = []byte(r.string())
+	}
+
+	return automap
+}
+
+func writeInts(w *binaryWriter, values []int) {
+	w.uvarint(uint64(len(values)))
+
+	for _, value := range values {
+		w.int(value)
+	}
+}
+
+func readInts(r *binaryReader) []int {
+	count := r.count()
+	if count == 0 || r.err != nil {
+		return nil
+	}
+
+	values := make([]int, 0, count)
+
+	for idx := 0; idx < count && r.err == nil; idx++ {
+		values = append(values, r.int())
+	}
+
+	return values
+}
+
+func writeHireling(w *binaryWriter, hireling *d2hero.HirelingState) {
+	w.uvarint(uint64(hireling.Seed))
+	w.int(hireling.ID)
+	w.int(hireling.NameID)
+	w.int(hireling.Level)
+	w.int(hireling.Experience)
+	w.int(hireling.Life)
+	w.bool(hireling.Dead)
+
+	slots := make([]int, 0, len(hireling.Equipment))
+	for slot := range hireling.Equipment {
+		slots = append(slots, int(slot))
+	}
+
+	sort.Ints(slots)
+	w.uvarint(uint64(len(slots)))
+
+	for _, slot := range slots {
+		w.int(slot)
+		w.string(string(hireling.Equipment[d2enum.EquippedSlot(slot)]))
+	}
+}
+
+func readHireling(r *binaryReader) d2hero.HirelingState {
+	hireling := d2hero.HirelingState{
+		Seed:       uint32(r.uvarint()),
+		ID:         r.int(),
+		NameID:     r.int(),
+		Level:      r.int(),
+		Experience: r.int(),
+		Life:       r.int(),
+		Dead:       r.bool(),
+	}
+
+	if count := r.count(); count > 0 {
+		hireling.Equipment = make(map[d2enum.EquippedSlot][]byte, count)
+
+		for idx := 0; idx < count; idx++ {
+			slot := d2enum.EquippedSlot(r.int())
+			hireling.Equipment[slot] = []byte(r.string())
+		}
+	}
+
+	return hireling
+}
+
+// writeStats writes the stats which are sent as JSON, the stamina and the experience of the
+// next level are computed by the receiver
+func writeStats(w *binaryWriter, stats *d2hero.HeroStatsState) {
+	w.bool(stats != nil)
+
+	if stats == nil {
+		return
+	}
+
+	for _, value := range []int{
+		stats.Level, stats.Experience,
+		stats.Strength, stats.Energy, stats.Dexterity, stats.Vitality,
+		stats.StatsPoints, stats.SkillPoints,
+		stats.Health, stats.MaxHealth, stats.Mana, stats.MaxMana, stats.MaxStamina,
+	} {
+		w.int(value)
+	}
+}
+
+func readStats(r *binaryReader) *d2hero.HeroStatsState {
+	if !r.bool() {
+		return nil
+	}
+
+	return &d2hero.HeroStatsState{
+		Level:       r.int(),
+		Experience:  r.int(),
+		Strength:    r.int(),
+		Energy:      r.int(),
+		Dexterity:   r.int(),
+		Vitality:    r.int(),
+		StatsPoints: r.int(),
+		SkillPoints: r.int(),
+		Health:      r.int(),
+		MaxHealth:   r.int(),
+		Mana:        r.int(),
+		MaxMana:     r.int(),
+		MaxStamina:  r.int(),
+	}
+}
+
+// writeSkills writes the skills sorted by ID, so the same skills always have the same encoding
+func writeSkills(w *binaryWriter, skills map[int]*d2hero.HeroSkill) {
+	ids := make([]int, 0, len(skills))
+
+	for id := range skills {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	w.uvarint(uint64(len(ids)))
+
+	for _, id := range ids {
+		w.int(id)
+		writeSkill(w, skills[id])
+	}
+}
+
+func readSkills(r *binaryReader) map[int]*d2hero.HeroSkill {
+	count := r.count()
+	if r.err != nil {
+		return nil
+	}
+
+	skills := make(map[int]*d2hero.HeroSkill, count)
+
+	for idx := 0; idx < count && r.err == nil; idx++ {
+		id := r.int()
+		skills[id] = readSkill(r)
+	}
+
+	return skills
+}
+
+// writeSkill writes the skill ID and points, the records are looked up by the receiver
+func writeSkill(w *binaryWriter, skill *d2hero.HeroSkill) {
+	w.bool(skill != nil && skill.Shallow != nil)
+
+	if skill == nil || skill.Shallow == nil {
+		return
+	}
+
+	w.int(skill.Shallow.SkillID)
+	w.int(skill.Shallow.SkillPoints)
+}
+
+func readSkill(r *binaryReader) *d2hero.HeroSkill {
+	if !r.bool() {
+		return nil
+	}
+
+	return d2hero.NewShallowHeroSkill(r.int(), r.int())
+}
+
+func writeEquipment(w *binaryWriter, equipment *d2inventory.CharacterEquipment) {
+	for _, armor := range []*d2inventory.InventoryItemArmor{
+		equipment.Head, equipment.Torso, equipment.Legs, equipment.RightArm, equipment.LeftArm, equipment.Shield,
+	} {
+		w.bool(armor != nil)
+
+		if armor != nil {
+			w.int(armor.InventorySizeX)
+			w.int(armor.InventorySizeY)
+			w.int(armor.InventorySlotX)
+			w.int(armor.InventorySlotY)
+			w.string(armor.ItemName)
+			w.string(armor.ItemCode)
+			w.string(armor.ArmorClass)
+		}
+	}
+
+	for _, weapon := range []*d2inventory.InventoryItemWeapon{equipment.LeftHand, equipment.RightHand} {
+		w.bool(weapon != nil)
+
+		if weapon != nil {
+			w.int(weapon.InventorySizeX)
+			w.int(weapon.InventorySizeY)
+			w.int(weapon.InventorySlotX)
+			w.int(weapon.InventorySlotY)
+			w.string(weapon.ItemName)
+			w.string(weapon.ItemCode)
+			w.string(weapon.WeaponClass)
+			w.string(weapon.WeaponClassOffHand)
+		}
+	}
+}
+
+func readEquipment(r *binaryReader) d2inventory.CharacterEquipment {
+	equipment := d2inventory.CharacterEquipment{}
+
+	for _, armor := range []**d2inventory.InventoryItemArmor{
+		&equipment.Head, &equipment.Torso, &equipment.Legs, &equipment.RightArm, &equipment.LeftArm, &equipment.Shield,
+	} {
+		if r.bool() {
+			*armor = &d2inventory.InventoryItemArmor{
+				InventorySizeX: r.int(),
+				InventorySizeY: r.int(),
+				InventorySlotX: r.int(),
+				InventorySlotY: r.int(),
+				ItemName:       r.string(),
+				ItemCode:       r.string(),
+				ArmorClass:     r.string(),
+			}
+		}
+	}
+
+	for _, weapon := range []**d2inventory.InventoryItemWeapon{&equipment.LeftHand, &equipment.RightHand} {
+		if r.bool() {
+			*weapon = &d2inventory.InventoryItemWeapon{
+				InventorySizeX:     r.int(),
+				InventorySizeY:     r.int(),
+				InventorySlotX:     r.int(),
+				InventorySlotY:     r.int(),
+				ItemName:           r.string(),
+				ItemCode:           r.string(),
+				WeaponClass:        r.string(),
+				WeaponClassOffHand: r.string(),
+			}
+		}
+	}
+
+	return equipment
+}
+
+func (p *WarpPlayerPacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.int(p.LevelID)
+	w.bool(p.Waypoint)
+}
+
+func (p *WarpPlayerPacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.LevelID = r.int()
+	p.Waypoint = r.bool()
+}
+
+func (p *PickUpItemPacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.string(p.ItemID)
+	w.int(p.Gold)
+}
+
+func (p *PickUpItemPacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.ItemID = r.string()
+	p.Gold = r.int()
+}
+
+func (p *OperateObjectPacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.fixed(p.X)
+	w.fixed(p.Y)
+}
+
+func (p *OperateObjectPacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.X = r.fixed()
+	p.Y = r.fixed()
+}
+
+func (p *SpawnMonsterPacket) writeBinary(w *binaryWriter) {
+	w.string(p.MonsterID)
+	w.string(p.Code)
+	w.int(p.X)
+	w.int(p.Y)
+}
+
+func (p *SpawnMonsterPacket) readBinary(r *binaryReader) {
+	p.MonsterID = r.string()
+	p.Code = r.string()
+	p.X = r.int()
+	p.Y = r.int()
+}
+
+func (p *MonsterAttackPacket) writeBinary(w *binaryWriter) {
+	w.string(p.MonsterID)
+	w.string(p.TargetID)
+	w.string(p.Missile)
+	w.fixed(p.X)
+	w.fixed(p.Y)
+}
+
+func (p *MonsterAttackPacket) readBinary(r *binaryReader) {
+	p.MonsterID = r.string()
+	p.TargetID = r.string()
+	p.Missile = r.string()
+	p.X = r.fixed()
+	p.Y = r.fixed()
+}
+
+func (p *CombatPacket) writeBinary(w *binaryWriter) {
+	w.uvarint(uint64(len(p.Events)))
+
+	for idx := range p.Events {
+		event := &p.Events[idx]
+
+		w.int(int(event.Type))
+		w.string(event.SourceID)
+		w.string(event.TargetID)
+		w.string(event.Element)
+		w.fixed(event.Amount)
+		w.fixed(event.Duration)
+	}
+}
+
+func (p *CombatPacket) readBinary(r *binaryReader) {
+	count := r.count()
+	p.Events = make([]d2combat.Event, count)
+
+	for idx := range p.Events {
+		event := &p.Events[idx]
+
+		event.Type = d2combat.EventType(r.int())
+		event.SourceID = r.string()
+		event.TargetID = r.string()
+		event.Element = r.string()
+		event.Amount = r.fixed()
+		event.Duration = r.fixed()
+	}
+}
+
+func (p *SetStatePacket) writeBinary(w *binaryWriter) {
+	w.string(p.UnitID)
+	w.string(p.State)
+	w.fixed(p.Duration)
+	w.bool(p.Removed)
+}
+
+func (p *SetStatePacket) readBinary(r *binaryReader) {
+	p.UnitID = r.string()
+	p.State = r.string()
+	p.Duration = r.fixed()
+	p.Removed = r.bool()
+}
+
+func (p *TradePacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.string(p.Vendor)
+	w.fixed(p.X)
+	w.fixed(p.Y)
+	w.int(int(p.Action))
+	w.string(p.ItemID)
+	w.string(string(p.Item))
+	w.int(p.Gold)
+}
+
+func (p *TradePacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.Vendor = r.string()
+	p.X = r.fixed()
+	p.Y = r.fixed()
+	p.Action = TradeAction(r.int())
+	p.ItemID = r.string()
+
+	if item := r.string(); item != "" {
+		p.Item = []byte(item)
+	}
+
+	p.Gold = r.int()
+}
+
+func (p *ShopInventoryPacket) writeBinary(w *binaryWriter) {
+	w.string(p.Vendor)
+	w.bool(p.Gambling)
+	w.uvarint(uint64(len(p.Items)))
+
+	for idx := range p.Items {
+		item := &p.Items[idx]
+
+		w.string(item.ID)
+		w.string(string(item.Item))
+		w.int(item.Price)
+	}
+}
+
+func (p *ShopInventoryPacket) readBinary(r *binaryReader) {
+	p.Vendor = r.string()
+	p.Gambling = r.bool()
+	p.Items = make([]ShopItem, r.count())
+
+	for idx := range p.Items {
+		item := &p.Items[idx]
+
+		item.ID = r.string()
+		item.Item = []byte(r.string())
+		item.Price = r.int()
+	}
+}
+
+func (p *HirePacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.string(p.Seller)
+	w.fixed(p.X)
+	w.fixed(p.Y)
+	w.int(int(p.Action))
+	w.string(p.ID)
+	w.int(int(p.Slot))
+	w.string(string(p.Item))
+	w.int(p.Gold)
+}
+
+func (p *HirePacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.Seller = r.string()
+	p.X = r.fixed()
+	p.Y = r.fixed()
+	p.Action = HireAction(r.int())
+	p.ID = r.string()
+	p.Slot = d2enum.EquippedSlot(r.int())
+
+	if item := r.string(); item != "" {
+		p.Item = []byte(item)
+	}
+
+	p.Gold = r.int()
+}
+
+func (p *HirelingOffersPacket) writeBinary(w *binaryWriter) {
+	w.string(p.Seller)
+	w.uvarint(uint64(len(p.Offers)))
+
+	for idx := range p.Offers {
+		offer := &p.Offers[idx]
+
+		w.string(offer.ID)
+		writeHireling(w, &offer.Hireling)
+		w.int(offer.Price)
+	}
+
+	w.int(p.ResurrectPrice)
+}
+
+func (p *HirelingOffersPacket) readBinary(r *binaryReader) {
+	p.Seller = r.string()
+	p.Offers = make([]HirelingOffer, r.count())
+
+	for idx := range p.Offers {
+		offer := &p.Offers[idx]
+
+		offer.ID = r.string()
+		offer.Hireling = readHireling(r)
+		offer.Price = r.int()
+	}
+
+	p.ResurrectPrice = r.int()
+}
+
+func (p *HirelingPacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.string(p.HirelingID)
+	w.string(p.Code)
+	w.int(p.X)
+	w.int(p.Y)
+	writeHireling(w, &p.Hireling)
+}
+
+func (p *HirelingPacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.HirelingID = r.string()
+	p.Code = r.string()
+	p.X = r.int()
+	p.Y = r.int()
+	p.Hireling = readHireling(r)
+}
+
+func (p *AutomapPacket) writeBinary(w *binaryWriter) {
+	w.int(p.LevelID)
+	w.string(string(p.Explored))
+}
+
+func (p *AutomapPacket) readBinary(r *binaryReader) {
+	p.LevelID = r.int()
+	p.Explored = []byte(r.string())
+}
+
+func (p *ObjectStatePacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.fixed(p.X)
+	w.fixed(p.Y)
+	w.int(int(p.Mode))
+}
+
+func (p *ObjectStatePacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.X = r.fixed()
+	p.Y = r.fixed()
+	p.Mode = d2enum.ObjectAnimationMode(r.int())
+}
+
+func (p *InventoryPacket) writeBinary(w *binaryWriter) {
+	w.string(p.PlayerID)
+	w.uvarint(uint64(len(p.Items)))
+
+	for idx := range p.Items {
+		w.string(p.Items[idx].ID)
+		w.string(string(p.Items[idx].Item))
+	}
+}
+
+func (p *InventoryPacket) readBinary(r *binaryReader) {
+	p.PlayerID = r.string()
+	p.Items = make([]OwnedItem, r.count())
+
+	for idx := range p.Items {
+		p.Items[idx].ID = r.string()
+		p.Items[idx].Item = []byte(r.string())
+	}
+}
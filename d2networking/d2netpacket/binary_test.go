@@ -0,0 +1,490 @@
+package d2netpacket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2inventory"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+const fuzzIterations = 2000
+
+func samplePackets(t *testing.T) []NetPacket {
+	stats := &d2hero.HeroStatsState{Level: 12, Experience: 43210, Strength: 30, Vitality: 25, Health: 120, MaxHealth: 140}
+	skills := map[int]*d2hero.HeroSkill{0: d2hero.NewShallowHeroSkill(0, 1), 36: d2hero.NewShallowHeroSkill(36, 5)}
+	equipment := d2inventory.CharacterEquipment{
+		Head:      &d2inventory.InventoryItemArmor{InventorySizeX: 2, InventorySizeY: 2, ItemName: "Cap", ItemCode: "cap"},
+		RightHand: &d2inventory.InventoryItemWeapon{ItemName: "Short Sword", ItemCode: "ssd", WeaponClass: "1hs"},
+	}
+	state := &d2hero.HeroState{
+		HeroName: "Tester", HeroType: d2enum.HeroSorceress, Act: 1, Equipment: equipment, Stats: stats,
+		Skills: skills, X: 12.5, Y: 80.25, LeftSkill: 0, RightSkill: 36, Gold: 999,
+		Hireling: &d2hero.HirelingState{
+			Seed: 0xdeadbeef, ID: 3, NameID: 12, Level: 10, Experience: 110000, Life: 130,
+			Equipment: map[d2enum.EquippedSlot][]byte{d2enum.EquippedSlotHead: {0x10, 0x00, 0xa0, 0xff}},
+		},
+		Automap:   map[int][]byte{1: {0x00, 0xf8, 0x01}, 8: {0xff}},
+		Waypoints: []int{1, 3, 40},
+		Items: map[string]*d2s.Item{
+			"item-1": {Identified: true, Location: d2s.LocationEquipped, BodyLocation: d2s.BodyHead, Code: "cap", Level: 12},
+		},
+	}
+	player := &d2mapentity.Player{
+		Equipment: &equipment, Stats: stats, Skills: skills, LeftSkill: skills[0], RightSkill: skills[36],
+		Class: d2enum.HeroSorceress, Gold: 999, Act: 1,
+	}
+
+	creators := []func() (NetPacket, error){
+		func() (NetPacket, error) {
+			return CreateUpdateServerInfoPacket(-1234567890123, "player-1", BinaryVersion)
+		},
+		func() (NetPacket, error) { return CreateGenerateMapPacket(1, d2enum.RegionAct1Town) },
+		func() (NetPacket, error) {
+			return CreateAddPlayerPacket("player-1", "Tester", 403, 328, d2enum.HeroSorceress, stats, skills, equipment, 0, 36, 999)
+		},
+		func() (NetPacket, error) { return CreateMovePlayerPacket("player-1", 80.5, 65.25, -3.125, 1000, true) },
+		func() (NetPacket, error) {
+			return CreatePlayerConnectionRequestPacket("player-1", state, BinaryVersion)
+		},
+		func() (NetPacket, error) { return CreatePlayerDisconnectRequestPacket("player-1") },
+		func() (NetPacket, error) { return CreatePingPacket() },
+		func() (NetPacket, error) { return CreatePongPacket("player-1") },
+		func() (NetPacket, error) { return CreateServerClosedPacket() },
+		func() (NetPacket, error) { return CreateCastPacket("player-1", 36, 81.5, 66.75) },
+		func() (NetPacket, error) { return CreateSpawnItemPacket(80, 65, "cap", "Strong") },
+		func() (NetPacket, error) {
+			return CreateDroppedItemPacket("item-1", "player-1", 80, 65, []byte{0x10, 0x00, 0xa0, 0xff}, 0)
+		},
+		func() (NetPacket, error) { return CreateSavePlayerPacket(player, d2enum.DifficultyNightmare) },
+		func() (NetPacket, error) { return CreateServerFullPacket() },
+		func() (NetPacket, error) {
+			return CreateStateDeltaPacket(99, []EntityState{
+				{ID: "player-1", X: 80.5, Y: 65.25, DestX: 82, DestY: 66, Life: 100, MaxLife: 140, Mana: 7, MaxMana: 35},
+				{ID: "player-2", X: 10, Y: 20, DestX: 10, DestY: 20},
+			})
+		},
+		func() (NetPacket, error) { return CreateWarpPlayerPacket("player-1", 40, true) },
+		func() (NetPacket, error) { return CreatePickUpItemPacket("player-1", "item-1", 150) },
+		func() (NetPacket, error) { return CreateOperateObjectPacket("player-1", 81.5, 66.25) },
+		func() (NetPacket, error) { return CreateSpawnMonsterPacket("monster-1", "fallen1", 402, 327) },
+		func() (NetPacket, error) {
+			return CreateMonsterAttackPacket("monster-1", "player-1", "arrow", 80.5, 65.25)
+		},
+		func() (NetPacket, error) {
+			return CreateCombatPacket([]d2combat.Event{
+				{Type: d2combat.EventDeadlyStrike, SourceID: "player-1", TargetID: "monster-1"},
+				{Type: d2combat.EventDamage, SourceID: "player-1", TargetID: "monster-1", Amount: 42.5},
+				{Type: d2combat.EventDamageOverTime, SourceID: "player-1", TargetID: "monster-1", Element: "pois", Amount: 60, Duration: 2.4},
+			})
+		},
+		func() (NetPacket, error) { return CreateSetStatePacket("monster-1", "poison", 2.4, false) },
+		func() (NetPacket, error) { return CreateSetStatePacket("player-1", "frozenarmor", 0, true) },
+		func() (NetPacket, error) {
+			return CreateTradePacket("player-1", "charsi", 80.5, 65.25, TradeBuy, "item-1")
+		},
+		func() (NetPacket, error) {
+			return CreateTradeDonePacket("player-1", "gheed", TradeGamble, "item-2", []byte{0x10, 0x00, 0xa0, 0xff}, 1250)
+		},
+		func() (NetPacket, error) {
+			return CreateShopInventoryPacket("gheed", true, []ShopItem{
+				{ID: "item-1", Item: []byte{0x10, 0x00, 0xa0, 0xff}, Price: 2200},
+				{ID: "item-2", Item: []byte{0x10, 0x00}, Price: 9},
+			})
+		},
+		func() (NetPacket, error) {
+			return CreateHirePacket("player-1", "kashya", 80.5, 65.25, HireHire, "hireling-2", d2enum.EquippedSlotNone)
+		},
+		func() (NetPacket, error) {
+			return CreateHireDonePacket("player-1", HireUnequip, "item-3", d2enum.EquippedSlotTorso, []byte{0x10, 0x00}, 420)
+		},
+		func() (NetPacket, error) {
+			return CreateHirelingOffersPacket("kashya", []HirelingOffer{
+				{ID: "hireling-1", Hireling: d2hero.HirelingState{Seed: 7, ID: 1, NameID: 3, Level: 9, Life: 110}, Price: 460},
+			}, 600)
+		},
+		func() (NetPacket, error) {
+			return CreateHirelingPacket("player-1", "hireling-1", "roguehire", 402, 327, state.Hireling)
+		},
+		func() (NetPacket, error) { return CreateAutomapPacket(1, []byte{0x00, 0xf8, 0x01}) },
+		func() (NetPacket, error) {
+			return CreateObjectStatePacket("player-1", 80.5, 65.25, d2enum.ObjectAnimationModeOpened)
+		},
+		func() (NetPacket, error) {
+			return CreateInventoryPacket("player-1", []OwnedItem{
+				{ID: "item-1", Item: []byte{0x10, 0x00, 0xa0, 0xff}},
+				{ID: "item-2", Item: []byte{0x10, 0x00}},
+			})
+		},
+	}
+
+	packets := make([]NetPacket, len(creators))
+
+	for idx, create := range creators {
+		packet, err := create()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		packets[idx] = packet
+	}
+
+	return packets
+}
+
+func TestEveryPacketTypeHasBinaryEncoding(t *testing.T) {
+	for packetType := d2netpackettype.UpdateServerInfo; packetType <= d2netpackettype.Inventory; packetType++ {
+		if _, found := newBinaryPacket(packetType); !found {
+			t.Errorf("packet %s has no binary encoding", packetType)
+		}
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _, packet := range samplePackets(t) {
+		frame, err := MarshalBinaryPacket(packet, BinaryVersion)
+		if err != nil {
+			t.Fatalf("%s: %v", packet.PacketType, err)
+		}
+
+		decoded, err := UnmarshalBinaryPacket(frame)
+		if err != nil {
+			t.Fatalf("%s: %v", packet.PacketType, err)
+		}
+
+		if decoded.PacketType != packet.PacketType {
+			t.Errorf("decoded %s as %s", packet.PacketType, decoded.PacketType)
+		}
+
+		again, err := MarshalBinaryPacket(decoded, BinaryVersion)
+		if err != nil || !bytes.Equal(again, frame) {
+			t.Errorf("%s: encoding the decoded packet changed it (%v)", packet.PacketType, err)
+		}
+	}
+}
+
+func TestBinaryValues(t *testing.T) {
+	packet, _ := CreateMovePlayerPacket("player-1", 80.5, 65.25, -3.125, 1000, true)
+
+	frame, err := MarshalBinaryPacket(packet, BinaryVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := UnmarshalBinaryPacket(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.PacketData != nil {
+		t.Error("the decoded packet has JSON data")
+	}
+
+	move, err := UnmarshalMovePlayer(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := MovePlayerPacket{PlayerID: "player-1", StartX: 80.5, StartY: 65.25, DestX: -3.125, DestY: 1000, Running: true}
+	if move != expected {
+		t.Errorf("decoded %+v, expected %+v", move, expected)
+	}
+
+	if len(frame) >= len(packet.PacketData) {
+		t.Errorf("binary frame of %d bytes is not smaller than %d bytes of JSON", len(frame), len(packet.PacketData))
+	}
+}
+
+func TestBinaryFramesAreEncodedFromTheBody(t *testing.T) {
+	packet, _ := CreateCastPacket("player-1", 36, 81.5, 66.75)
+	frame, err := MarshalBinaryPacket(packet, BinaryVersion)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet.PacketData = []byte("not JSON")
+
+	if again, err := MarshalBinaryPacket(packet, BinaryVersion); err != nil || !bytes.Equal(again, frame) {
+		t.Errorf("the frame depends on the JSON data of the packet (%v)", err)
+	}
+
+	table := []struct {
+		name string
+		body interface{}
+	}{
+		{"no body", nil},
+		{"body of another packet type", &MovePlayerPacket{}},
+		{"body which is not a pointer", CastPacket{}},
+	}
+
+	for _, row := range table {
+		packet.Body = row.body
+
+		if _, err := MarshalBinaryPacket(packet, BinaryVersion); err == nil {
+			t.Errorf("a cast packet with %s was encoded", row.name)
+		}
+	}
+}
+
+func TestNegotiateBinaryVersion(t *testing.T) {
+	table := []struct {
+		peer, expected int
+	}{
+		{0, 0},
+		{-1, 0},
+		{BinaryVersion, BinaryVersion},
+		{BinaryVersion + 1, BinaryVersion},
+	}
+
+	for _, row := range table {
+		if got := NegotiateBinaryVersion(row.peer); got != row.expected {
+			t.Errorf("peer version %d: got %d, expected %d", row.peer, got, row.expected)
+		}
+	}
+}
+
+func TestPacketStream(t *testing.T) {
+	packets := samplePackets(t)
+	stream := &bytes.Buffer{}
+	writer := NewPacketWriter(stream, 0)
+
+	for idx, packet := range packets {
+		if idx == len(packets)/2 {
+			writer.SetBinaryVersion(BinaryVersion)
+
+			// a frame with a valid length but a bad body, the reader skips it
+			stream.Write([]byte{BinaryVersion, 2, byte(d2netpackettype.MovePlayer), 0xff})
+		}
+
+		if err := writer.WritePacket(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reader := NewPacketReader(stream)
+	malformed := 0
+
+	for idx := 0; idx < len(packets); {
+		packet, err := reader.ReadPacket()
+
+		if errors.Is(err, ErrMalformedPacket) {
+			malformed++
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("packet %d: %v", idx, err)
+		}
+
+		if packet.PacketType != packets[idx].PacketType {
+			t.Errorf("packet %d: read %s, expected %s", idx, packet.PacketType, packets[idx].PacketType)
+		}
+
+		idx++
+	}
+
+	if malformed != 1 {
+		t.Errorf("skipped %d malformed packets, expected 1", malformed)
+	}
+
+	if _, err := reader.ReadPacket(); err != io.EOF {
+		t.Errorf("expected the end of the stream, got %v", err)
+	}
+}
+
+// mutate returns a randomly changed copy of the data
+func mutate(random *rand.Rand, data []byte) []byte {
+	result := append([]byte{}, data...)
+
+	for changes := 1 + random.Intn(4); changes > 0; changes-- {
+		switch random.Intn(4) {
+		case 0: // flip a byte
+			if len(result) > 0 {
+				result[random.Intn(len(result))] = byte(random.Intn(256))
+			}
+		case 1: // truncate
+			result = result[:random.Intn(len(result)+1)]
+		case 2: // insert a byte
+			at := random.Intn(len(result) + 1)
+			result = append(result[:at], append([]byte{byte(random.Intn(256))}, result[at:]...)...)
+		default: // remove a byte
+			if len(result) > 0 {
+				at := random.Intn(len(result))
+				result = append(result[:at], result[at+1:]...)
+			}
+		}
+	}
+
+	return result
+}
+
+// decodeSafely fails the test if decode panics on the given input
+func decodeSafely(t *testing.T, name string, data []byte, decode func([]byte)) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			t.Fatalf("%s panicked on %x: %v", name, data, recovered)
+		}
+	}()
+
+	decode(data)
+}
+
+func TestFuzzBinaryDecoders(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	for _, packet := range samplePackets(t) {
+		frame, err := MarshalBinaryPacket(packet, BinaryVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		payload := frame[1+binary.PutUvarint(make([]byte, binary.MaxVarintLen64), uint64(len(frame))):]
+		name := packet.PacketType.String()
+
+		for iteration := 0; iteration < fuzzIterations; iteration++ {
+			decodeSafely(t, name, mutate(random, frame), func(data []byte) {
+				decoded, err := UnmarshalBinaryPacket(data)
+				if err != nil {
+					return
+				}
+
+				if _, err := MarshalBinaryPacket(decoded, BinaryVersion); err != nil {
+					t.Errorf("%s: cannot encode decoded packet %x: %v", name, data, err)
+				}
+			})
+
+			// the mutated bodies keep their packet type, so every body decoder is reached
+			body := append([]byte{}, payload[:1]...)
+			body = append(body, mutate(random, payload[1:])...)
+
+			decodeSafely(t, name, body, func(data []byte) {
+				_, _ = decodeBinaryPayload(BinaryVersion, data)
+			})
+		}
+	}
+}
+
+func TestFuzzJSONDecoders(t *testing.T) {
+	random := rand.New(rand.NewSource(2))
+
+	decoders := map[d2netpackettype.NetPacketType]func([]byte) error{
+		d2netpackettype.UpdateServerInfo: func(b []byte) error { _, err := UnmarshalUpdateServerInfo(NetPacket{PacketData: b}); return err },
+		d2netpackettype.GenerateMap:      func(b []byte) error { _, err := UnmarshalGenerateMap(NetPacket{PacketData: b}); return err },
+		d2netpackettype.AddPlayer:        func(b []byte) error { _, err := UnmarshalAddPlayer(NetPacket{PacketData: b}); return err },
+		d2netpackettype.MovePlayer:       func(b []byte) error { _, err := UnmarshalMovePlayer(NetPacket{PacketData: b}); return err },
+		d2netpackettype.PlayerConnectionRequest: func(b []byte) error {
+			_, err := UnmarshalPlayerConnectionRequest(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.PlayerDisconnectionNotification: func(b []byte) error {
+			_, err := UnmarshalPlayerDisconnectionRequest(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.Ping:         func(b []byte) error { _, err := UnmarshalPing(NetPacket{PacketData: b}); return err },
+		d2netpackettype.Pong:         func(b []byte) error { _, err := UnmarshalPong(NetPacket{PacketData: b}); return err },
+		d2netpackettype.ServerClosed: func(b []byte) error { _, err := UnmarshalServerClosed(NetPacket{PacketData: b}); return err },
+		d2netpackettype.CastSkill:    func(b []byte) error { _, err := UnmarshalCast(NetPacket{PacketData: b}); return err },
+		d2netpackettype.SpawnItem:    func(b []byte) error { _, err := UnmarshalSpawnItem(NetPacket{PacketData: b}); return err },
+		d2netpackettype.SavePlayer:   func(b []byte) error { _, err := UnmarshalSavePlayer(NetPacket{PacketData: b}); return err },
+		d2netpackettype.ServerFull:   func(b []byte) error { _, err := UnmarshalServerFull(NetPacket{PacketData: b}); return err },
+		d2netpackettype.StateDelta:   func(b []byte) error { _, err := UnmarshalStateDelta(NetPacket{PacketData: b}); return err },
+		d2netpackettype.WarpPlayer:   func(b []byte) error { _, err := UnmarshalWarpPlayer(NetPacket{PacketData: b}); return err },
+		d2netpackettype.PickUpItem:   func(b []byte) error { _, err := UnmarshalPickUpItem(NetPacket{PacketData: b}); return err },
+		d2netpackettype.OperateObject: func(b []byte) error {
+			_, err := UnmarshalOperateObject(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.SpawnMonster: func(b []byte) error {
+			_, err := UnmarshalSpawnMonster(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.MonsterAttack: func(b []byte) error {
+			_, err := UnmarshalMonsterAttack(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.Combat: func(b []byte) error {
+			_, err := UnmarshalCombat(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.SetState: func(b []byte) error {
+			_, err := UnmarshalSetState(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.Trade: func(b []byte) error { _, err := UnmarshalTrade(NetPacket{PacketData: b}); return err },
+		d2netpackettype.ShopInventory: func(b []byte) error {
+			_, err := UnmarshalShopInventory(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.Hire: func(b []byte) error { _, err := UnmarshalHire(NetPacket{PacketData: b}); return err },
+		d2netpackettype.HirelingOffers: func(b []byte) error {
+			_, err := UnmarshalHirelingOffers(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.Hireling: func(b []byte) error { _, err := UnmarshalHireling(NetPacket{PacketData: b}); return err },
+		d2netpackettype.Automap:  func(b []byte) error { _, err := UnmarshalAutomap(NetPacket{PacketData: b}); return err },
+		d2netpackettype.ObjectState: func(b []byte) error {
+			_, err := UnmarshalObjectState(NetPacket{PacketData: b})
+			return err
+		},
+		d2netpackettype.Inventory: func(b []byte) error {
+			_, err := UnmarshalInventory(NetPacket{PacketData: b})
+			return err
+		},
+	}
+
+	for _, packet := range samplePackets(t) {
+		decode, found := decoders[packet.PacketType]
+		if !found {
+			t.Fatalf("no JSON decoder for %s", packet.PacketType)
+		}
+
+		if err := decode(packet.PacketData); err != nil {
+			t.Fatalf("%s: %v", packet.PacketType, err)
+		}
+
+		for iteration := 0; iteration < fuzzIterations; iteration++ {
+			decodeSafely(t, packet.PacketType.String(), mutate(random, packet.PacketData), func(data []byte) {
+				_ = decode(data)
+			})
+		}
+	}
+}
+
+func TestFuzzPacketReader(t *testing.T) {
+	random := rand.New(rand.NewSource(3))
+	stream := &bytes.Buffer{}
+	writer := NewPacketWriter(stream, BinaryVersion)
+
+	for _, packet := range samplePackets(t) {
+		if err := writer.WritePacket(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	valid := stream.Bytes()
+
+	for iteration := 0; iteration < fuzzIterations; iteration++ {
+		decodeSafely(t, "PacketReader", mutate(random, valid), func(data []byte) {
+			reader := NewPacketReader(bytes.NewReader(data))
+
+			// every packet takes at least one byte, so the stream ends after len(data) reads
+			for reads := 0; reads <= len(data); reads++ {
+				if _, err := reader.ReadPacket(); err != nil && !errors.Is(err, ErrMalformedPacket) {
+					return
+				}
+			}
+
+			t.Errorf("reader did not reach the end of %x", data)
+		})
+	}
+}
@@ -28,9 +28,25 @@ const (
 	Pong                                                 // Responds to a Ping packet
 	ServerClosed                                         // Sent by the local host when it has closed the server
 	CastSkill                                            // Sent by client or server, indicates entity casting skill
-	SpawnItem                                            // Sent by server
+	SpawnItem                                            // Sent by client or server, puts an item on the ground
 	SavePlayer                                           // Sent by the client, saves the player
 	ServerFull                                           // Sent by server when server has reached max connections
+	StateDelta                                           // Sent by server, updates the state of the entities which changed
+	WarpPlayer                                           // Sent by client or server, moves a player to another level
+	PickUpItem                                           // Sent by client or server, picks up an item
+	OperateObject                                        // Sent by client, operates an object like a chest, a door or a shrine
+	SpawnMonster                                         // Sent by client or server, puts a monster on the map
+	MonsterAttack                                        // Sent by server, a monster attacks
+	Combat                                               // Sent by server, tells the outcome of attacks
+	SetState                                             // Sent by server, puts a state on a unit or takes it off
+	Trade                                                // Sent by client or server, buys, sells, repairs or gambles at a vendor
+	ShopInventory                                        // Sent by server, shows the items of a vendor
+	Hire                                                 // Sent by client or server, hires, resurrects or equips a hireling
+	HirelingOffers                                       // Sent by server, shows the hirelings a seller offers
+	Hireling                                             // Sent by server, puts the hireling of a player on the map or updates it
+	Automap                                              // Sent by server, the tiles of the map the player has explored
+	ObjectState                                          // Sent by server, the animation mode of an operated object
+	Inventory                                            // Sent by server, the items of the hero of the player
 
 	UnknownPacketType = 666
 )
@@ -50,6 +66,22 @@ func (n NetPacketType) String() string {
 		SpawnItem:                       "SpawnItem",
 		SavePlayer:                      "SavePlayer",
 		ServerFull:                      "ServerFull",
+		StateDelta:                      "StateDelta",
+		WarpPlayer:                      "WarpPlayer",
+		PickUpItem:                      "PickUpItem",
+		OperateObject:                   "OperateObject",
+		SpawnMonster:                    "SpawnMonster",
+		MonsterAttack:                   "MonsterAttack",
+		Combat:                          "Combat",
+		SetState:                        "SetState",
+		Trade:                           "Trade",
+		ShopInventory:                   "ShopInventory",
+		Hire:                            "Hire",
+		HirelingOffers:                  "HirelingOffers",
+		Hireling:                        "Hireling",
+		Automap:                         "Automap",
+		ObjectState:                     "ObjectState",
+		Inventory:                       "Inventory",
 	}
 
 	return strings[n]
@@ -2,6 +2,8 @@ package d2netpacket
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
 )
@@ -9,10 +11,31 @@ import (
 // NetPacket is used to wrap and send all packet types under d2netpacket.
 // When decoding a packet: First the PacketType byte is read, then the
 // PacketData is unmarshalled to a struct of the type associated with
-// PacketType.
+// PacketType. The Body is the struct itself: the one the packet was created
+// from, which the binary encoding writes, or the one a binary frame was
+// decoded to, which has no PacketData.
 type NetPacket struct {
 	PacketType d2netpackettype.NetPacketType `json:"packetType"`
 	PacketData json.RawMessage               `json:"packetData"`
+	Body       interface{}                   `json:"-"`
+}
+
+// unmarshalBody sets the given packet struct to the decoded Body of the packet, or else unmarshals
+// the PacketData into it. The Body of a created packet is not used, it may share its maps and
+// pointers with the sender.
+func (p NetPacket) unmarshalBody(body interface{}) error {
+	if len(p.PacketData) > 0 || p.Body == nil {
+		return json.Unmarshal(p.PacketData, body)
+	}
+
+	decoded, target := reflect.ValueOf(p.Body), reflect.ValueOf(body)
+	if decoded.Type() != target.Type() {
+		return fmt.Errorf("%s packet has a body of type %T", p.PacketType, p.Body)
+	}
+
+	target.Elem().Set(decoded.Elem())
+
+	return nil
 }
 
 // InspectPacketType determines the packet type from the given data
@@ -58,13 +58,14 @@ func CreateAddPlayerPacket(
 	return NetPacket{
 		PacketType: d2netpackettype.AddPlayer,
 		PacketData: b,
+		Body:       &addPlayerPacket,
 	}, nil
 }
 
 // UnmarshalAddPlayer unmarshals the packet data into an AddPlayerPacket struct
-func UnmarshalAddPlayer(packet []byte) (AddPlayerPacket, error) {
+func UnmarshalAddPlayer(packet NetPacket) (AddPlayerPacket, error) {
 	var p AddPlayerPacket
-	if err := json.Unmarshal(packet, &p); err != nil {
+	if err := packet.unmarshalBody(&p); err != nil {
 		return p, err
 	}
 
@@ -0,0 +1,46 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// AutomapPacket is sent by the server when a player enters a level, it
+// holds the tiles of the map the player has explored before. The map is
+// known by its first level, the tiles are one bit each in rows.
+type AutomapPacket struct {
+	LevelID  int    `json:"levelId"`
+	Explored []byte `json:"explored"`
+}
+
+// CreateAutomapPacket returns a NetPacket which declares an AutomapPacket
+// with the given map and explored tiles.
+func CreateAutomapPacket(levelID int, explored []byte) (NetPacket, error) {
+	automapPacket := AutomapPacket{
+		LevelID:  levelID,
+		Explored: explored,
+	}
+
+	b, err := json.Marshal(automapPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.Automap}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.Automap,
+		PacketData: b,
+		Body:       &automapPacket,
+	}, nil
+}
+
+// UnmarshalAutomap unmarshals the given packet data into an AutomapPacket
+// struct
+func UnmarshalAutomap(packet NetPacket) (AutomapPacket, error) {
+	var p AutomapPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,43 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// CombatPacket is sent by the server with the outcome of the attacks of a
+// tick: hits, misses, damage, leech and deaths.
+type CombatPacket struct {
+	Events []d2combat.Event `json:"events"`
+}
+
+// CreateCombatPacket returns a NetPacket which declares a CombatPacket with
+// the given combat events.
+func CreateCombatPacket(events []d2combat.Event) (NetPacket, error) {
+	combatPacket := CombatPacket{
+		Events: events,
+	}
+
+	b, err := json.Marshal(combatPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.Combat}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.Combat,
+		PacketData: b,
+		Body:       &combatPacket,
+	}, nil
+}
+
+// UnmarshalCombat unmarshals the given packet data into a CombatPacket struct
+func UnmarshalCombat(packet NetPacket) (CombatPacket, error) {
+	var p CombatPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
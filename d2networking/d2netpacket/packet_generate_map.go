@@ -7,17 +7,19 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
 )
 
-// GenerateMapPacket contains an enumerable representing a region. It
-// is sent by the server to generate the map for the given region on
-// a client.
+// GenerateMapPacket contains the ID of a level from levels.txt and an
+// enumerable representing its region. It is sent by the server to
+// generate the map of the level a player enters on a client.
 type GenerateMapPacket struct {
+	LevelID    int                 `json:"levelId"`
 	RegionType d2enum.RegionIdType `json:"regionType"`
 }
 
 // CreateGenerateMapPacket returns a NetPacket which declares a
-// GenerateMapPacket with the given regionType.
-func CreateGenerateMapPacket(regionType d2enum.RegionIdType) (NetPacket, error) {
+// GenerateMapPacket with the given levelID and regionType.
+func CreateGenerateMapPacket(levelID int, regionType d2enum.RegionIdType) (NetPacket, error) {
 	generateMapPacket := GenerateMapPacket{
+		LevelID:    levelID,
 		RegionType: regionType,
 	}
 
@@ -29,13 +31,14 @@ func CreateGenerateMapPacket(regionType d2enum.RegionIdType) (NetPacket, error)
 	return NetPacket{
 		PacketType: d2netpackettype.GenerateMap,
 		PacketData: b,
+		Body:       &generateMapPacket,
 	}, nil
 }
 
 // UnmarshalGenerateMap unmarshals the given packet data into a GenerateMapPacket struct
-func UnmarshalGenerateMap(packet []byte) (GenerateMapPacket, error) {
+func UnmarshalGenerateMap(packet NetPacket) (GenerateMapPacket, error) {
 	var p GenerateMapPacket
-	if err := json.Unmarshal(packet, &p); err != nil {
+	if err := packet.unmarshalBody(&p); err != nil {
 		return p, err
 	}
 
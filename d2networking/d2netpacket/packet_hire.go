@@ -0,0 +1,90 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// HireAction is what a player does about its hireling
+type HireAction int
+
+// Hire actions
+const (
+	HireOffers    HireAction = iota // shows the hirelings the seller offers
+	HireHire                        // hires the hireling offered with the given ID
+	HireResurrect                   // resurrects the dead hireling of the player
+	HireEquip                       // gives the item of the player with the given ID to the hireling
+	HireUnequip                     // takes the item in the given slot off the hireling
+)
+
+// HirePacket is sent by a client to hire, resurrect or equip its hireling.
+// Hiring and resurrecting happen at the seller, by its row in monstats.txt,
+// at the given world position. The server checks the gold of the player and
+// sends the packet back to the player once it is done, with the item the
+// player got back and the gold the player has left.
+type HirePacket struct {
+	PlayerID string              `json:"playerId"`
+	Seller   string              `json:"seller,omitempty"`
+	X        float64             `json:"x"`
+	Y        float64             `json:"y"`
+	Action   HireAction          `json:"action"`
+	ID       string              `json:"id,omitempty"` // the offer hired or the item equipped
+	Slot     d2enum.EquippedSlot `json:"slot,omitempty"`
+	Item     []byte              `json:"item,omitempty"`
+	Gold     int                 `json:"gold"`
+}
+
+// CreateHirePacket returns a NetPacket which declares a HirePacket with the
+// given player, seller, action, offer or item ID and slot.
+func CreateHirePacket(playerID, seller string, x, y float64, action HireAction, id string,
+	slot d2enum.EquippedSlot) (NetPacket, error) {
+	return createHirePacket(HirePacket{
+		PlayerID: playerID,
+		Seller:   seller,
+		X:        x,
+		Y:        y,
+		Action:   action,
+		ID:       id,
+		Slot:     slot,
+	})
+}
+
+// CreateHireDonePacket returns a NetPacket which declares a HirePacket for
+// an action the server has done, with the item the player got back in the
+// saved item format and the gold of the player afterwards.
+func CreateHireDonePacket(playerID string, action HireAction, id string, slot d2enum.EquippedSlot, item []byte,
+	gold int) (NetPacket, error) {
+	return createHirePacket(HirePacket{
+		PlayerID: playerID,
+		Action:   action,
+		ID:       id,
+		Slot:     slot,
+		Item:     item,
+		Gold:     gold,
+	})
+}
+
+func createHirePacket(hirePacket HirePacket) (NetPacket, error) {
+	b, err := json.Marshal(hirePacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.Hire}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.Hire,
+		PacketData: b,
+		Body:       &hirePacket,
+	}, nil
+}
+
+// UnmarshalHire unmarshals the given packet data into a HirePacket struct
+func UnmarshalHire(packet NetPacket) (HirePacket, error) {
+	var p HirePacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,56 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// HirelingPacket is sent by the server to put the hireling of a player on
+// the map, by its row in monstats.txt at the given sub tile position, or to
+// update it when it levels, is equipped or dies. A hireling which is not on
+// the map has no unit ID.
+type HirelingPacket struct {
+	PlayerID   string               `json:"playerId"`
+	HirelingID string               `json:"hirelingId,omitempty"`
+	Code       string               `json:"code,omitempty"`
+	X          int                  `json:"x"`
+	Y          int                  `json:"y"`
+	Hireling   d2hero.HirelingState `json:"hireling"`
+}
+
+// CreateHirelingPacket returns a NetPacket which declares a HirelingPacket
+// with the given player, hireling unit, monster, position and state.
+func CreateHirelingPacket(playerID, hirelingID, code string, x, y int, state *d2hero.HirelingState) (NetPacket, error) {
+	hirelingPacket := HirelingPacket{
+		PlayerID:   playerID,
+		HirelingID: hirelingID,
+		Code:       code,
+		X:          x,
+		Y:          y,
+		Hireling:   *state,
+	}
+
+	b, err := json.Marshal(hirelingPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.Hireling}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.Hireling,
+		PacketData: b,
+		Body:       &hirelingPacket,
+	}, nil
+}
+
+// UnmarshalHireling unmarshals the given packet data into a HirelingPacket
+// struct
+func UnmarshalHireling(packet NetPacket) (HirelingPacket, error) {
+	var p HirelingPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
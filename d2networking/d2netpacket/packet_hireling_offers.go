@@ -0,0 +1,57 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// HirelingOffer is a hireling a seller offers, with what the player pays
+// for it
+type HirelingOffer struct {
+	ID       string               `json:"id"`
+	Hireling d2hero.HirelingState `json:"hireling"`
+	Price    int                  `json:"price"`
+}
+
+// HirelingOffersPacket is sent by the server when a player asks a seller
+// for the hirelings it offers, with what resurrecting the hireling of the
+// player costs if it is dead.
+type HirelingOffersPacket struct {
+	Seller         string          `json:"seller"`
+	Offers         []HirelingOffer `json:"offers"`
+	ResurrectPrice int             `json:"resurrectPrice,omitempty"`
+}
+
+// CreateHirelingOffersPacket returns a NetPacket which declares a
+// HirelingOffersPacket with the given seller, offers and resurrect price.
+func CreateHirelingOffersPacket(seller string, offers []HirelingOffer, resurrectPrice int) (NetPacket, error) {
+	hirelingOffersPacket := HirelingOffersPacket{
+		Seller:         seller,
+		Offers:         offers,
+		ResurrectPrice: resurrectPrice,
+	}
+
+	b, err := json.Marshal(hirelingOffersPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.HirelingOffers}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.HirelingOffers,
+		PacketData: b,
+		Body:       &hirelingOffersPacket,
+	}, nil
+}
+
+// UnmarshalHirelingOffers unmarshals the given packet data into a
+// HirelingOffersPacket struct
+func UnmarshalHirelingOffers(packet NetPacket) (HirelingOffersPacket, error) {
+	var p HirelingOffersPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,53 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// OwnedItem is an item of a hero, in the saved item format, by the ID the
+// server knows it by
+type OwnedItem struct {
+	ID   string `json:"id"`
+	Item []byte `json:"item"`
+}
+
+// InventoryPacket is sent by the server when a player joins the game, with
+// the items its hero wears and carries. The player trades these items by
+// their IDs.
+type InventoryPacket struct {
+	PlayerID string      `json:"playerId"`
+	Items    []OwnedItem `json:"items"`
+}
+
+// CreateInventoryPacket returns a NetPacket which declares an
+// InventoryPacket with the given player ID and items.
+func CreateInventoryPacket(playerID string, items []OwnedItem) (NetPacket, error) {
+	inventoryPacket := InventoryPacket{
+		PlayerID: playerID,
+		Items:    items,
+	}
+
+	b, err := json.Marshal(inventoryPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.Inventory}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.Inventory,
+		PacketData: b,
+		Body:       &inventoryPacket,
+	}, nil
+}
+
+// UnmarshalInventory unmarshals the given packet data into an
+// InventoryPacket struct
+func UnmarshalInventory(packet NetPacket) (InventoryPacket, error) {
+	var p InventoryPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
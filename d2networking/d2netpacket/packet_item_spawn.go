@@ -6,11 +6,17 @@ import (
 	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
 )
 
-// SpawnItemPacket contains the data required to create a Item entity
+// SpawnItemPacket contains the data required to create a Item entity. A client
+// sends the codes of an item to spawn, the server rolls the item and sends it
+// with its ID, the player it dropped for and the item in the saved item format.
 type SpawnItemPacket struct {
-	X     int      `json:"x"`
-	Y     int      `json:"y"`
-	Codes []string `json:"codes"`
+	X        int      `json:"x"`
+	Y        int      `json:"y"`
+	Codes    []string `json:"codes"`
+	ItemID   string   `json:"itemId,omitempty"`
+	OwnerID  string   `json:"ownerId,omitempty"`
+	Item     []byte   `json:"item,omitempty"`
+	Quantity int      `json:"quantity,omitempty"` // amount of gold of a gold item
 }
 
 // CreateSpawnItemPacket returns a NetPacket which declares a
@@ -30,13 +36,39 @@ func CreateSpawnItemPacket(x, y int, codes ...string) (NetPacket, error) {
 	return NetPacket{
 		PacketType: d2netpackettype.SpawnItem,
 		PacketData: b,
+		Body:       &spawnItemPacket,
+	}, nil
+}
+
+// CreateDroppedItemPacket returns a NetPacket which declares a SpawnItemPacket
+// for an item the server put on the ground at the given tile position.
+func CreateDroppedItemPacket(itemID, ownerID string, x, y int, item []byte, quantity int) (NetPacket, error) {
+	spawnItemPacket := SpawnItemPacket{
+		X:        x,
+		Y:        y,
+		Codes:    []string{},
+		ItemID:   itemID,
+		OwnerID:  ownerID,
+		Item:     item,
+		Quantity: quantity,
+	}
+
+	b, err := json.Marshal(spawnItemPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.SpawnItem}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.SpawnItem,
+		PacketData: b,
+		Body:       &spawnItemPacket,
 	}, nil
 }
 
 // UnmarshalSpawnItem unmarshals the given data to a SpawnItemPacket struct
-func UnmarshalSpawnItem(packet []byte) (SpawnItemPacket, error) {
+func UnmarshalSpawnItem(packet NetPacket) (SpawnItemPacket, error) {
 	var p SpawnItemPacket
-	if err := json.Unmarshal(packet, &p); err != nil {
+	if err := packet.unmarshalBody(&p); err != nil {
 		return p, err
 	}
 
@@ -0,0 +1,52 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// MonsterAttackPacket is sent by the server when a monster attacks the
+// target with the given ID at the given world position. The missile is the
+// missiles.txt name of the missile the monster shoots, it is empty for melee
+// attacks.
+type MonsterAttackPacket struct {
+	MonsterID string  `json:"monsterId"`
+	TargetID  string  `json:"targetId"`
+	Missile   string  `json:"missile,omitempty"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+}
+
+// CreateMonsterAttackPacket returns a NetPacket which declares a
+// MonsterAttackPacket with the given monster, target, missile and position.
+func CreateMonsterAttackPacket(monsterID, targetID, missile string, x, y float64) (NetPacket, error) {
+	monsterAttackPacket := MonsterAttackPacket{
+		MonsterID: monsterID,
+		TargetID:  targetID,
+		Missile:   missile,
+		X:         x,
+		Y:         y,
+	}
+
+	b, err := json.Marshal(monsterAttackPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.MonsterAttack}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.MonsterAttack,
+		PacketData: b,
+		Body:       &monsterAttackPacket,
+	}, nil
+}
+
+// UnmarshalMonsterAttack unmarshals the given packet data into a MonsterAttackPacket struct
+func UnmarshalMonsterAttack(packet NetPacket) (MonsterAttackPacket, error) {
+	var p MonsterAttackPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
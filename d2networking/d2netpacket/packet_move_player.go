@@ -15,17 +15,19 @@ type MovePlayerPacket struct {
 	StartY   float64 `json:"startY"`
 	DestX    float64 `json:"destX"`
 	DestY    float64 `json:"destY"`
+	Running  bool    `json:"running"`
 }
 
 // CreateMovePlayerPacket returns a NetPacket which declares a MovePlayerPacket
 // with the given ID and movement command.
-func CreateMovePlayerPacket(playerID string, startX, startY, destX, destY float64) (NetPacket, error) {
+func CreateMovePlayerPacket(playerID string, startX, startY, destX, destY float64, running bool) (NetPacket, error) {
 	movePlayerPacket := MovePlayerPacket{
 		PlayerID: playerID,
 		StartX:   startX,
 		StartY:   startY,
 		DestX:    destX,
 		DestY:    destY,
+		Running:  running,
 	}
 
 	b, err := json.Marshal(movePlayerPacket)
@@ -36,13 +38,14 @@ func CreateMovePlayerPacket(playerID string, startX, startY, destX, destY float6
 	return NetPacket{
 		PacketType: d2netpackettype.MovePlayer,
 		PacketData: b,
+		Body:       &movePlayerPacket,
 	}, nil
 }
 
 // UnmarshalMovePlayer unmarshals the given data to a MovePlayerPacket struct
-func UnmarshalMovePlayer(packet []byte) (MovePlayerPacket, error) {
+func UnmarshalMovePlayer(packet NetPacket) (MovePlayerPacket, error) {
 	var p MovePlayerPacket
-	if err := json.Unmarshal(packet, &p); err != nil {
+	if err := packet.unmarshalBody(&p); err != nil {
 		return p, err
 	}
 
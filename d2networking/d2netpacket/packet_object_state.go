@@ -0,0 +1,52 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// ObjectStatePacket is sent by the server when an object at the given
+// world position is operated or resets, like a door which opens or a
+// shrine which can be used again. The objects of a map are known by their
+// position, the player is the one who operated the object, if any.
+type ObjectStatePacket struct {
+	PlayerID string                     `json:"playerId"`
+	X        float64                    `json:"x"`
+	Y        float64                    `json:"y"`
+	Mode     d2enum.ObjectAnimationMode `json:"mode"`
+}
+
+// CreateObjectStatePacket returns a NetPacket which declares an
+// ObjectStatePacket with the given player ID, object position and mode.
+func CreateObjectStatePacket(playerID string, x, y float64, mode d2enum.ObjectAnimationMode) (NetPacket, error) {
+	objectStatePacket := ObjectStatePacket{
+		PlayerID: playerID,
+		X:        x,
+		Y:        y,
+		Mode:     mode,
+	}
+
+	b, err := json.Marshal(objectStatePacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.ObjectState}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.ObjectState,
+		PacketData: b,
+		Body:       &objectStatePacket,
+	}, nil
+}
+
+// UnmarshalObjectState unmarshals the given packet data into an
+// ObjectStatePacket struct
+func UnmarshalObjectState(packet NetPacket) (ObjectStatePacket, error) {
+	var p ObjectStatePacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,47 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// OperateObjectPacket is sent by a client to operate the object at the
+// given world position, like a chest, a door or a shrine. The server
+// answers with an ObjectStatePacket once the object is operated.
+type OperateObjectPacket struct {
+	PlayerID string  `json:"playerId"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+}
+
+// CreateOperateObjectPacket returns a NetPacket which declares an
+// OperateObjectPacket with the given player ID and object position.
+func CreateOperateObjectPacket(playerID string, x, y float64) (NetPacket, error) {
+	operateObjectPacket := OperateObjectPacket{
+		PlayerID: playerID,
+		X:        x,
+		Y:        y,
+	}
+
+	b, err := json.Marshal(operateObjectPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.OperateObject}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.OperateObject,
+		PacketData: b,
+		Body:       &operateObjectPacket,
+	}, nil
+}
+
+// UnmarshalOperateObject unmarshals the given packet data into an OperateObjectPacket struct
+func UnmarshalOperateObject(packet NetPacket) (OperateObjectPacket, error) {
+	var p OperateObjectPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,47 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// PickUpItemPacket is sent by a client to pick up the item with the given ID.
+// The server sends it to every player on the level once the item is picked up,
+// with the amount of gold the player got from it.
+type PickUpItemPacket struct {
+	PlayerID string `json:"playerId"`
+	ItemID   string `json:"itemId"`
+	Gold     int    `json:"gold"`
+}
+
+// CreatePickUpItemPacket returns a NetPacket which declares a
+// PickUpItemPacket with the given player and item IDs and gold amount.
+func CreatePickUpItemPacket(playerID, itemID string, gold int) (NetPacket, error) {
+	pickUpItemPacket := PickUpItemPacket{
+		PlayerID: playerID,
+		ItemID:   itemID,
+		Gold:     gold,
+	}
+
+	b, err := json.Marshal(pickUpItemPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.PickUpItem}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.PickUpItem,
+		PacketData: b,
+		Body:       &pickUpItemPacket,
+	}, nil
+}
+
+// UnmarshalPickUpItem unmarshals the given packet data into a PickUpItemPacket struct
+func UnmarshalPickUpItem(packet NetPacket) (PickUpItemPacket, error) {
+	var p PickUpItemPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
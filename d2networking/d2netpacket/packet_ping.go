@@ -28,13 +28,14 @@ func CreatePingPacket() (NetPacket, error) {
 	return NetPacket{
 		PacketType: d2netpackettype.Ping,
 		PacketData: b,
+		Body:       &ping,
 	}, nil
 }
 
 // UnmarshalPing unmarshals the given data to a PingPacket struct
-func UnmarshalPing(packet []byte) (PingPacket, error) {
+func UnmarshalPing(packet NetPacket) (PingPacket, error) {
 	var p PingPacket
-	if err := json.Unmarshal(packet, &p); err != nil {
+	if err := packet.unmarshalBody(&p); err != nil {
 		return p, err
 	}
 
@@ -36,13 +36,14 @@ func CreateCastPacket(entityID string, skillID int, targetX, targetY float64) (N
 	return NetPacket{
 		PacketType: d2netpackettype.CastSkill,
 		PacketData: b,
+		Body:       &castPacket,
 	}, nil
 }
 
 // UnmarshalCast unmarshals the given data to a CastPacket struct
-func UnmarshalCast(packet []byte) (CastPacket, error) {
+func UnmarshalCast(packet NetPacket) (CastPacket, error) {
 	var p CastPacket
-	if err := json.Unmarshal(packet, &p); err != nil {
+	if err := packet.unmarshalBody(&p); err != nil {
 		return p, err
 	}
 
@@ -9,18 +9,23 @@ import (
 )
 
 // PlayerConnectionRequestPacket contains a player ID and game state.
-// It is sent by a remote client to initiate a connection (join a game).
+// It is sent by a remote client to initiate a connection (join a game),
+// always as JSON. The binary version is the newest one the client supports,
+// 0 asks the server to keep sending JSON.
 type PlayerConnectionRequestPacket struct {
-	ID          string            `json:"id"`
-	PlayerState *d2hero.HeroState `json:"gameState"`
+	ID            string            `json:"id"`
+	PlayerState   *d2hero.HeroState `json:"gameState"`
+	BinaryVersion int               `json:"binaryVersion"`
 }
 
 // CreatePlayerConnectionRequestPacket returns a NetPacket which defines a
-// PlayerConnectionRequestPacket with the given ID and game state.
-func CreatePlayerConnectionRequestPacket(id string, playerState *d2hero.HeroState) (NetPacket, error) {
+// PlayerConnectionRequestPacket with the given ID, game state and binary
+// version.
+func CreatePlayerConnectionRequestPacket(id string, playerState *d2hero.HeroState, binaryVersion int) (NetPacket, error) {
 	playerConnectionRequest := PlayerConnectionRequestPacket{
-		ID:          id,
-		PlayerState: playerState,
+		ID:            id,
+		PlayerState:   playerState,
+		BinaryVersion: binaryVersion,
 	}
 
 	b, err := json.Marshal(playerConnectionRequest)
@@ -31,15 +36,16 @@ func CreatePlayerConnectionRequestPacket(id string, playerState *d2hero.HeroStat
 	return NetPacket{
 		PacketType: d2netpackettype.PlayerConnectionRequest,
 		PacketData: b,
+		Body:       &playerConnectionRequest,
 	}, nil
 }
 
 // UnmarshalPlayerConnectionRequest unmarshals the given data to a
 // PlayerConnectionRequestPacket struct
-func UnmarshalPlayerConnectionRequest(packet []byte) (PlayerConnectionRequestPacket, error) {
+func UnmarshalPlayerConnectionRequest(packet NetPacket) (PlayerConnectionRequestPacket, error) {
 	var resp PlayerConnectionRequestPacket
 
-	if err := json.Unmarshal(packet, &resp); err != nil {
+	if err := packet.unmarshalBody(&resp); err != nil {
 		return PlayerConnectionRequestPacket{}, err
 	}
 
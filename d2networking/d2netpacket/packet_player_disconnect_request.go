@@ -30,15 +30,16 @@ func CreatePlayerDisconnectRequestPacket(id string) (NetPacket, error) {
 	return NetPacket{
 		PacketType: d2netpackettype.PlayerDisconnectionNotification,
 		PacketData: b,
+		Body:       &playerDisconnectRequest,
 	}, nil
 }
 
 // UnmarshalPlayerDisconnectionRequest unmarshals the given data to a
 // PlayerDisconnectRequestPacket struct
-func UnmarshalPlayerDisconnectionRequest(packet []byte) (PlayerDisconnectRequestPacket, error) {
+func UnmarshalPlayerDisconnectionRequest(packet NetPacket) (PlayerDisconnectRequestPacket, error) {
 	var resp PlayerDisconnectRequestPacket
 
-	if err := json.Unmarshal(packet, &resp); err != nil {
+	if err := packet.unmarshalBody(&resp); err != nil {
 		return resp, err
 	}
 
@@ -30,14 +30,15 @@ func CreatePongPacket(id string) (NetPacket, error) {
 	return NetPacket{
 		PacketType: d2netpackettype.Pong,
 		PacketData: b,
+		Body:       &pong,
 	}, nil
 }
 
 // UnmarshalPong unmarshals the given data to a PongPacket struct
-func UnmarshalPong(packet []byte) (PongPacket, error) {
+func UnmarshalPong(packet NetPacket) (PongPacket, error) {
 	var resp PongPacket
 
-	if err := json.Unmarshal(packet, &resp); err != nil {
+	if err := packet.unmarshalBody(&resp); err != nil {
 		return resp, err
 	}
 
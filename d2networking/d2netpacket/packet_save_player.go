@@ -31,13 +31,14 @@ func CreateSavePlayerPacket(playerState *d2mapentity.Player, difficulty d2enum.D
 	return NetPacket{
 		PacketType: d2netpackettype.SavePlayer,
 		PacketData: b,
+		Body:       &savePlayerData,
 	}, nil
 }
 
 // UnmarshalSavePlayer unmarshalls the given data to a SavePlayerPacket struct
-func UnmarshalSavePlayer(packet []byte) (SavePlayerPacket, error) {
+func UnmarshalSavePlayer(packet NetPacket) (SavePlayerPacket, error) {
 	var p SavePlayerPacket
-	if err := json.Unmarshal(packet, &p); err != nil {
+	if err := packet.unmarshalBody(&p); err != nil {
 		return p, err
 	}
 
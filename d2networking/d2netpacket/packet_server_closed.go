@@ -28,14 +28,15 @@ func CreateServerClosedPacket() (NetPacket, error) {
 	return NetPacket{
 		PacketType: d2netpackettype.ServerClosed,
 		PacketData: b,
+		Body:       &serverClosed,
 	}, nil
 }
 
 // UnmarshalServerClosed unmarshals the given data to a ServerClosedPacket struct
-func UnmarshalServerClosed(packet []byte) (ServerClosedPacket, error) {
+func UnmarshalServerClosed(packet NetPacket) (ServerClosedPacket, error) {
 	var resp ServerClosedPacket
 
-	if err := json.Unmarshal(packet, &resp); err != nil {
+	if err := packet.unmarshalBody(&resp); err != nil {
 		return resp, err
 	}
 
@@ -22,14 +22,15 @@ func CreateServerFullPacket() (NetPacket, error) {
 	return NetPacket{
 		PacketType: d2netpackettype.ServerFull,
 		PacketData: b,
+		Body:       &serverClosed,
 	}, nil
 }
 
 // UnmarshalServerFull unmarshalls the given data to a ServerFullPacket struct
-func UnmarshalServerFull(packet []byte) (ServerFullPacket, error) {
+func UnmarshalServerFull(packet NetPacket) (ServerFullPacket, error) {
 	var resp ServerFullPacket
 
-	if err := json.Unmarshal(packet, &resp); err != nil {
+	if err := packet.unmarshalBody(&resp); err != nil {
 		return resp, err
 	}
 
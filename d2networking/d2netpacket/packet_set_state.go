@@ -0,0 +1,50 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// SetStatePacket is sent by the server when a state of states.txt is put on
+// or taken off the unit with the given ID. The duration is the number of
+// seconds the state lasts, it is negative for the states which last until
+// they are removed.
+type SetStatePacket struct {
+	UnitID   string  `json:"unitId"`
+	State    string  `json:"state"`
+	Duration float64 `json:"duration,omitempty"`
+	Removed  bool    `json:"removed,omitempty"`
+}
+
+// CreateSetStatePacket returns a NetPacket which declares a SetStatePacket
+// with the given unit, state and duration. A removed state has no duration.
+func CreateSetStatePacket(unitID, state string, duration float64, removed bool) (NetPacket, error) {
+	setStatePacket := SetStatePacket{
+		UnitID:   unitID,
+		State:    state,
+		Duration: duration,
+		Removed:  removed,
+	}
+
+	b, err := json.Marshal(setStatePacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.SetState}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.SetState,
+		PacketData: b,
+		Body:       &setStatePacket,
+	}, nil
+}
+
+// UnmarshalSetState unmarshals the given packet data into a SetStatePacket struct
+func UnmarshalSetState(packet NetPacket) (SetStatePacket, error) {
+	var p SetStatePacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
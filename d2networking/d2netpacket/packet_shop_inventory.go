@@ -0,0 +1,55 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// ShopItem is an item a vendor offers, in the saved item format, with what
+// the player pays for it
+type ShopItem struct {
+	ID    string `json:"id"`
+	Item  []byte `json:"item"`
+	Price int    `json:"price"`
+}
+
+// ShopInventoryPacket is sent by the server when a player opens the trade
+// screen of a vendor, with the items the vendor sells or gambles on.
+type ShopInventoryPacket struct {
+	Vendor   string     `json:"vendor"`
+	Gambling bool       `json:"gambling,omitempty"`
+	Items    []ShopItem `json:"items"`
+}
+
+// CreateShopInventoryPacket returns a NetPacket which declares a
+// ShopInventoryPacket with the given vendor and items.
+func CreateShopInventoryPacket(vendor string, gambling bool, items []ShopItem) (NetPacket, error) {
+	shopInventoryPacket := ShopInventoryPacket{
+		Vendor:   vendor,
+		Gambling: gambling,
+		Items:    items,
+	}
+
+	b, err := json.Marshal(shopInventoryPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.ShopInventory}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.ShopInventory,
+		PacketData: b,
+		Body:       &shopInventoryPacket,
+	}, nil
+}
+
+// UnmarshalShopInventory unmarshals the given packet data into a
+// ShopInventoryPacket struct
+func UnmarshalShopInventory(packet NetPacket) (ShopInventoryPacket, error) {
+	var p ShopInventoryPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
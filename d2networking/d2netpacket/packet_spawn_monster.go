@@ -0,0 +1,49 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// SpawnMonsterPacket is sent by the server to put a monster with the
+// monstats.txt ID of the code at the given sub tile position. A client sends
+// it without a monster ID to have the server spawn a monster.
+type SpawnMonsterPacket struct {
+	MonsterID string `json:"monsterId,omitempty"`
+	Code      string `json:"code"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+}
+
+// CreateSpawnMonsterPacket returns a NetPacket which declares a
+// SpawnMonsterPacket with the given monster ID, code and sub tile position.
+func CreateSpawnMonsterPacket(monsterID, code string, x, y int) (NetPacket, error) {
+	spawnMonsterPacket := SpawnMonsterPacket{
+		MonsterID: monsterID,
+		Code:      code,
+		X:         x,
+		Y:         y,
+	}
+
+	b, err := json.Marshal(spawnMonsterPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.SpawnMonster}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.SpawnMonster,
+		PacketData: b,
+		Body:       &spawnMonsterPacket,
+	}, nil
+}
+
+// UnmarshalSpawnMonster unmarshals the given packet data into a SpawnMonsterPacket struct
+func UnmarshalSpawnMonster(packet NetPacket) (SpawnMonsterPacket, error) {
+	var p SpawnMonsterPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
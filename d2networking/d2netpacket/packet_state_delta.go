@@ -0,0 +1,59 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// EntityState is the state of a single entity as simulated by the server.
+// Positions are in world tile units, like the positions of MovePlayerPacket.
+type EntityState struct {
+	ID      string  `json:"id"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	DestX   float64 `json:"destX"`
+	DestY   float64 `json:"destY"`
+	Life    int     `json:"life"`
+	MaxLife int     `json:"maxLife"`
+	Mana    int     `json:"mana"`
+	MaxMana int     `json:"maxMana"`
+}
+
+// StateDeltaPacket contains the state of the entities which changed during a
+// server tick. It is sent by the server, the clients correct their predicted
+// state with it.
+type StateDeltaPacket struct {
+	Tick     uint64        `json:"tick"`
+	Entities []EntityState `json:"entities"`
+}
+
+// CreateStateDeltaPacket returns a NetPacket which declares a StateDeltaPacket
+// with the given tick and entity states.
+func CreateStateDeltaPacket(tick uint64, entities []EntityState) (NetPacket, error) {
+	stateDeltaPacket := StateDeltaPacket{
+		Tick:     tick,
+		Entities: entities,
+	}
+
+	b, err := json.Marshal(stateDeltaPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.StateDelta}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.StateDelta,
+		PacketData: b,
+		Body:       &stateDeltaPacket,
+	}, nil
+}
+
+// UnmarshalStateDelta unmarshals the given data to a StateDeltaPacket struct
+func UnmarshalStateDelta(packet NetPacket) (StateDeltaPacket, error) {
+	var p StateDeltaPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
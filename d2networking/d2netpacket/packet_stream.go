@@ -0,0 +1,158 @@
+package d2netpacket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const jsonPacketStart = '{'
+
+// PacketReader reads packets from a connection. Each packet is either a line of JSON or a binary
+// frame, the first byte tells them apart, so a connection can switch encodings between packets.
+type PacketReader struct {
+	reader *bufio.Reader
+}
+
+// NewPacketReader creates a PacketReader which reads from the given reader
+func NewPacketReader(reader io.Reader) *PacketReader {
+	return &PacketReader{reader: bufio.NewReader(reader)}
+}
+
+// ReadPacket reads the next packet. An error which wraps ErrMalformedPacket means the packet could
+// not be decoded, but the next packet can be read. Any other error ends the connection.
+func (p *PacketReader) ReadPacket() (NetPacket, error) {
+	first, err := p.skipWhitespace()
+	if err != nil {
+		return NetPacket{}, err
+	}
+
+	if first == jsonPacketStart {
+		return p.readJSON()
+	}
+
+	return p.readBinary()
+}
+
+// skipWhitespace skips the whitespace between packets and returns the first byte of the next packet
+func (p *PacketReader) skipWhitespace() (byte, error) {
+	for {
+		next, err := p.reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+
+		switch next[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := p.reader.Discard(1); err != nil {
+				return 0, err
+			}
+		default:
+			return next[0], nil
+		}
+	}
+}
+
+// readJSON reads a JSON packet, json.Encoder ends each packet with a new line
+func (p *PacketReader) readJSON() (NetPacket, error) {
+	line := make([]byte, 0)
+
+	for {
+		chunk, err := p.reader.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		if len(line) > MaxPacketSize {
+			return NetPacket{}, fmt.Errorf("JSON packet larger than %d bytes", MaxPacketSize)
+		}
+
+		if err == nil || (err == io.EOF && len(line) > 0) {
+			break
+		}
+
+		if err != bufio.ErrBufferFull {
+			return NetPacket{}, err
+		}
+	}
+
+	packet, err := UnmarshalNetPacket(line)
+	if err != nil {
+		return NetPacket{}, fmt.Errorf("%w: %v", ErrMalformedPacket, err)
+	}
+
+	return packet, nil
+}
+
+func (p *PacketReader) readBinary() (NetPacket, error) {
+	version, err := p.reader.ReadByte()
+	if err != nil {
+		return NetPacket{}, err
+	}
+
+	length, err := binary.ReadUvarint(p.reader)
+	if err != nil {
+		return NetPacket{}, err
+	}
+
+	if length > MaxPacketSize {
+		return NetPacket{}, fmt.Errorf("binary packet larger than %d bytes", MaxPacketSize)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(p.reader, payload); err != nil {
+		return NetPacket{}, err
+	}
+
+	return decodeBinaryPayload(int(version), payload)
+}
+
+// PacketWriter writes packets to a connection, as JSON or as binary frames of the negotiated
+// version. It can be used by several goroutines.
+type PacketWriter struct {
+	mutex         sync.Mutex
+	writer        io.Writer
+	binaryVersion int
+}
+
+// NewPacketWriter creates a PacketWriter which writes to the given writer, a binary version of 0
+// writes JSON
+func NewPacketWriter(writer io.Writer, binaryVersion int) *PacketWriter {
+	return &PacketWriter{writer: writer, binaryVersion: binaryVersion}
+}
+
+// SetBinaryVersion sets the binary version of the packets written from now on, 0 writes JSON
+func (p *PacketWriter) SetBinaryVersion(binaryVersion int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.binaryVersion = binaryVersion
+}
+
+// WritePacket writes a packet with a single write, so the packets of several goroutines do not
+// interleave
+func (p *PacketWriter) WritePacket(packet NetPacket) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var (
+		data []byte
+		err  error
+	)
+
+	if p.binaryVersion > 0 {
+		data, err = MarshalBinaryPacket(packet, p.binaryVersion)
+	} else {
+		data, err = json.Marshal(packet)
+		data = append(data, '\n')
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = p.writer.Write(data)
+
+	return err
+}
@@ -0,0 +1,86 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// TradeAction is what a player does at a vendor
+type TradeAction int
+
+// Trade actions
+const (
+	TradeOpen       TradeAction = iota // shows the items the vendor sells
+	TradeOpenGamble                    // shows the items the vendor gambles on
+	TradeBuy                           // buys the item of the vendor with the given ID
+	TradeSell                          // sells the item of the player with the given ID
+	TradeRepair                        // repairs the item of the player with the given ID, or every item
+	TradeGamble                        // gambles on the item of the vendor with the given ID
+)
+
+// TradePacket is sent by a client to trade with the vendor, by its row in
+// monstats.txt, at the given world position. The server checks the gold of
+// the player and sends the packet back to the player once the trade is done,
+// with the item the player got and the gold the player has left.
+type TradePacket struct {
+	PlayerID string      `json:"playerId"`
+	Vendor   string      `json:"vendor"`
+	X        float64     `json:"x"`
+	Y        float64     `json:"y"`
+	Action   TradeAction `json:"action"`
+	ItemID   string      `json:"itemId,omitempty"`
+	Item     []byte      `json:"item,omitempty"`
+	Gold     int         `json:"gold"`
+}
+
+// CreateTradePacket returns a NetPacket which declares a TradePacket with the
+// given player, vendor, action and item ID.
+func CreateTradePacket(playerID, vendor string, x, y float64, action TradeAction, itemID string) (NetPacket, error) {
+	return createTradePacket(TradePacket{
+		PlayerID: playerID,
+		Vendor:   vendor,
+		X:        x,
+		Y:        y,
+		Action:   action,
+		ItemID:   itemID,
+	})
+}
+
+// CreateTradeDonePacket returns a NetPacket which declares a TradePacket for
+// a trade the server has done, with the item the player got in the saved item
+// format and the gold of the player after the trade.
+func CreateTradeDonePacket(playerID, vendor string, action TradeAction, itemID string, item []byte,
+	gold int) (NetPacket, error) {
+	return createTradePacket(TradePacket{
+		PlayerID: playerID,
+		Vendor:   vendor,
+		Action:   action,
+		ItemID:   itemID,
+		Item:     item,
+		Gold:     gold,
+	})
+}
+
+func createTradePacket(tradePacket TradePacket) (NetPacket, error) {
+	b, err := json.Marshal(tradePacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.Trade}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.Trade,
+		PacketData: b,
+		Body:       &tradePacket,
+	}, nil
+}
+
+// UnmarshalTrade unmarshals the given packet data into a TradePacket struct
+func UnmarshalTrade(packet NetPacket) (TradePacket, error) {
+	var p TradePacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
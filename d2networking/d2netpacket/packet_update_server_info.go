@@ -7,18 +7,23 @@ import (
 )
 
 // UpdateServerInfoPacket contains the ID for a player and the map seed.
-// It is sent by the server to synchronize these values on the client.
+// It is sent by the server to synchronize these values on the client. It
+// also tells the client the binary version the server selected, the client
+// sends its packets in that encoding from then on.
 type UpdateServerInfoPacket struct {
-	Seed     int64  `json:"seed"`
-	PlayerID string `json:"playerId"`
+	Seed          int64  `json:"seed"`
+	PlayerID      string `json:"playerId"`
+	BinaryVersion int    `json:"binaryVersion"`
 }
 
 // CreateUpdateServerInfoPacket returns a NetPacket which declares an
-// UpdateServerInfoPacket with the given player ID and map seed.
-func CreateUpdateServerInfoPacket(seed int64, playerID string) (NetPacket, error) {
+// UpdateServerInfoPacket with the given player ID, map seed and binary
+// version, a binary version of 0 selects JSON.
+func CreateUpdateServerInfoPacket(seed int64, playerID string, binaryVersion int) (NetPacket, error) {
 	updateServerInfo := UpdateServerInfoPacket{
-		Seed:     seed,
-		PlayerID: playerID,
+		Seed:          seed,
+		PlayerID:      playerID,
+		BinaryVersion: binaryVersion,
 	}
 
 	b, err := json.Marshal(updateServerInfo)
@@ -29,14 +34,15 @@ func CreateUpdateServerInfoPacket(seed int64, playerID string) (NetPacket, error
 	return NetPacket{
 		PacketType: d2netpackettype.UpdateServerInfo,
 		PacketData: b,
+		Body:       &updateServerInfo,
 	}, nil
 }
 
 // UnmarshalUpdateServerInfo unmarshals the data to a UpdateServerInfoPacket struct
-func UnmarshalUpdateServerInfo(packet []byte) (UpdateServerInfoPacket, error) {
+func UnmarshalUpdateServerInfo(packet NetPacket) (UpdateServerInfoPacket, error) {
 	var resp UpdateServerInfoPacket
 
-	if err := json.Unmarshal(packet, &resp); err != nil {
+	if err := packet.unmarshalBody(&resp); err != nil {
 		return resp, err
 	}
 
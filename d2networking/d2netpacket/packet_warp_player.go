@@ -0,0 +1,47 @@
+package d2netpacket
+
+import (
+	"encoding/json"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+// WarpPlayerPacket moves a player to another level. It is sent by a client
+// to take a warp or, when Waypoint is true, a waypoint to the given level.
+// The server sends it to the players on the level the player left.
+type WarpPlayerPacket struct {
+	PlayerID string `json:"playerId"`
+	LevelID  int    `json:"levelId"`
+	Waypoint bool   `json:"waypoint"`
+}
+
+// CreateWarpPlayerPacket returns a NetPacket which declares a
+// WarpPlayerPacket with the given player ID and destination level.
+func CreateWarpPlayerPacket(playerID string, levelID int, waypoint bool) (NetPacket, error) {
+	warpPlayerPacket := WarpPlayerPacket{
+		PlayerID: playerID,
+		LevelID:  levelID,
+		Waypoint: waypoint,
+	}
+
+	b, err := json.Marshal(warpPlayerPacket)
+	if err != nil {
+		return NetPacket{PacketType: d2netpackettype.WarpPlayer}, err
+	}
+
+	return NetPacket{
+		PacketType: d2netpackettype.WarpPlayer,
+		PacketData: b,
+		Body:       &warpPlayerPacket,
+	}, nil
+}
+
+// UnmarshalWarpPlayer unmarshals the given packet data into a WarpPlayerPacket struct
+func UnmarshalWarpPlayer(packet NetPacket) (WarpPlayerPacket, error) {
+	var p WarpPlayerPacket
+	if err := packet.unmarshalBody(&p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
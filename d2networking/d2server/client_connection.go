@@ -15,3 +15,9 @@ type ClientConnection interface {
 	GetPlayerState() *d2hero.HeroState
 	SetPlayerState(playerState *d2hero.HeroState)
 }
+
+// binaryConnection is implemented by the client connections which negotiated
+// the binary packet encoding, see d2netpacket.BinaryVersion.
+type binaryConnection interface {
+	BinaryVersion() int
+}
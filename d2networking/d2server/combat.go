@@ -0,0 +1,356 @@
+package d2server
+
+import (
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+const (
+	// the attack rating and defense of a player grow with the dexterity
+	attackRatingPerDexterity = 5
+	dexterityPerDefense      = 4
+
+	// a player without a weapon hits with its fists
+	unarmedMinDamage = 1
+	unarmedMaxDamage = 2
+
+	meleeReach = 2.0 // how far, in tiles, from the cast target a melee skill finds its target
+)
+
+// hit resolves an attack of the attacker on the defender, updates the life and mana of both and
+// queues the events for the next combat packet. Units killed by the attack are removed.
+func (w *world) hit(attackerID, defenderID string, attack *d2combat.Attack) {
+	attacker, defender := w.combatant(attackerID), w.combatant(defenderID)
+	if attacker == nil || defender == nil || !defender.Alive() {
+		return
+	}
+
+	events := w.combat.Resolve(attacker, defender, attack)
+
+	w.updateCombatant(attacker)
+	w.updateCombatant(defender)
+
+	for idx := range events {
+		w.afflictions.Add(&events[idx])
+	}
+
+	w.combatEvents = append(w.combatEvents, events...)
+	w.applyCombatStates(events)
+
+	if !defender.Alive() {
+		w.unitDied(defenderID, attackerID)
+	}
+}
+
+// advanceAfflictions deals the damage over time of the tick. Only the deaths are sent to the
+// clients, the life lost shows in the state deltas.
+func (w *world) advanceAfflictions(tickTime float64) {
+	for _, event := range w.afflictions.Advance(tickTime) {
+		defender := w.combatant(event.TargetID)
+		if defender == nil || !defender.Alive() {
+			continue
+		}
+
+		defender.Life -= event.Amount
+		if defender.Life < 0 {
+			defender.Life = 0
+		}
+
+		w.updateCombatant(defender)
+
+		if !defender.Alive() {
+			w.combatEvents = append(w.combatEvents, d2combat.Event{
+				Type:     d2combat.EventDeath,
+				SourceID: event.SourceID,
+				TargetID: event.TargetID,
+			})
+
+			w.unitDied(event.TargetID, event.SourceID)
+		}
+	}
+}
+
+// queueCombatEvents queues the combat events of the tick as a single packet
+func (w *world) queueCombatEvents() {
+	if len(w.combatEvents) == 0 {
+		return
+	}
+
+	packet, err := d2netpacket.CreateCombatPacket(w.combatEvents)
+	w.combatEvents = nil
+
+	if err != nil {
+		w.Errorf("CombatPacket: %v", err)
+		return
+	}
+
+	w.send(packet, "")
+}
+
+// unitDied removes a killed monster from the world and drops its treasure if a player or a
+// hireling killed it, the hireling of the player earns the experience of the monster. A killed
+// hireling stays dead until it is resurrected, dead players return to town after a while.
+func (w *world) unitDied(id, killerID string) {
+	w.afflictions.Remove(id)
+
+	if player, found := w.players[id]; found {
+		w.playerDied(player)
+		return
+	}
+
+	if unit, found := w.hirelings[id]; found {
+		w.hirelingDied(unit)
+		return
+	}
+
+	monster, found := w.monsters[id]
+	if !found {
+		return
+	}
+
+	w.ai.Kill(id)
+	w.mapEngine.RemoveEntity(monster)
+	delete(w.monsters, id)
+
+	if killer := w.killer(killerID); killer != nil {
+		position := monster.Position.World()
+		w.monsterDied(monster.record, position.X(), position.Y(), killer)
+		w.hirelingExperience(killer, monster.record)
+	}
+}
+
+// combatant returns the player, hireling or monster with the given ID as a combatant, or nil if
+// there is no such unit
+func (w *world) combatant(id string) *d2combat.Combatant {
+	if player, found := w.players[id]; found {
+		return w.playerCombatant(player)
+	}
+
+	if unit, found := w.hirelings[id]; found {
+		return w.hirelingCombatant(unit)
+	}
+
+	if monster, found := w.monsters[id]; found {
+		return w.monsterCombatant(monster)
+	}
+
+	return nil
+}
+
+// playerCombatant returns the combat stats of a player: the stats of its attributes, and the
+// damage, defense and stats of the items its hero wears
+func (w *world) playerCombatant(player *worldPlayer) *d2combat.Combatant {
+	combatant := &d2combat.Combatant{
+		ID:      player.ID(),
+		Level:   1,
+		Life:    player.life,
+		MaxLife: player.maxLife,
+		Mana:    player.mana,
+		MaxMana: player.maxMana,
+		Leech:   100, //nolint:gomnd // all of the leech works on players
+	}
+
+	values := make(map[string]int)
+
+	attributes := player.client.GetPlayerState().Stats
+	if attributes != nil {
+		combatant.Level = attributes.Level
+		values[d2combat.StatStrength] = attributes.Strength
+		values[d2combat.StatDexterity] = attributes.Dexterity
+		values[d2combat.StatAttackRating] = attributes.Dexterity * attackRatingPerDexterity
+		values[d2combat.StatDefense] = attributes.Dexterity / dexterityPerDefense
+	}
+
+	items := w.wornItems(player)
+
+	for _, item := range items {
+		values[d2combat.StatDefense] += item.saved.Defense
+
+		record := item.CommonRecord()
+
+		minDamage, maxDamage := weaponDamage(record)
+		if maxDamage == 0 {
+			continue
+		}
+
+		values[d2combat.StatMinDamage] += minDamage
+		values[d2combat.StatMaxDamage] += maxDamage
+
+		if attributes != nil {
+			values[d2combat.StatDamageBonus] += (attributes.Strength*record.StrengthBonus +
+				attributes.Dexterity*record.DexterityBonus) / percent
+		}
+	}
+
+	if values[d2combat.StatMaxDamage] == 0 {
+		values[d2combat.StatMinDamage], values[d2combat.StatMaxDamage] = unarmedMinDamage, unarmedMaxDamage
+	}
+
+	combatant.Stats = w.statList(values)
+
+	for _, item := range items {
+		combatant.Stats = combatant.Stats.AppendStatList(item.StatList())
+	}
+
+	combatant.Stats = player.states.StatList(combatant.Stats)
+
+	return combatant
+}
+
+// wornItem is an item a hero wears, with its saved state
+type wornItem struct {
+	*diablo2item.Item
+	saved *d2s.Item
+}
+
+// wornItems returns the items the hero of the player wears, the weapons of the other weapon set
+// are left out
+func (w *world) wornItems(player *worldPlayer) []wornItem {
+	items := make([]wornItem, 0)
+
+	for _, id := range ownedItemIDs(player) {
+		saved := player.client.GetPlayerState().Items[id]
+
+		switch {
+		case saved.Location != d2s.LocationEquipped:
+			continue
+		case saved.BodyLocation == d2s.BodyRightHandSwap, saved.BodyLocation == d2s.BodyLeftHandSwap:
+			continue
+		}
+
+		if item := w.ownedItem(player, id); item != nil {
+			items = append(items, wornItem{Item: item, saved: saved})
+		}
+	}
+
+	return items
+}
+
+// weaponDamage returns the damage range of a weapon, the two handed weapons which are not held in
+// one hand do their two handed damage. The items which are not weapons do no damage.
+func weaponDamage(record *d2records.ItemCommonRecord) (minDamage, maxDamage int) {
+	if record.UsesTwoHands && !record.BarbOneOrTwoHanded {
+		return record.Min2HandDamage, record.Max2HandDamage
+	}
+
+	return record.MinDamage, record.MaxDamage
+}
+
+// monsterCombatant returns the combat stats of a monster, the stat list of the monster is made
+// once
+func (w *world) monsterCombatant(monster *worldMonster) *d2combat.Combatant {
+	record := monster.record
+
+	if monster.stats == nil {
+		resists := record.Resistances(w.difficulty)
+
+		monster.stats = w.statList(map[string]int{
+			d2combat.StatAttackRating:   record.AttackRating(w.difficulty),
+			d2combat.StatDefense:        record.ArmorClass(w.difficulty),
+			d2combat.StatBlock:          record.BlockChance(w.difficulty),
+			d2combat.StatDeadlyStrike:   record.ChanceDeadlyStrike,
+			d2combat.StatPhysicalResist: resists.Physical,
+			"magicresist":               resists.Magic,
+			"fireresist":                resists.Fire,
+			"lightresist":               resists.Lightning,
+			"coldresist":                resists.Cold,
+			"poisonresist":              resists.Poison,
+		})
+	}
+
+	return &d2combat.Combatant{
+		ID:        monster.ID(),
+		Level:     w.monsterLevel(record),
+		Stats:     monster.states.StatList(monster.stats),
+		Life:      monster.ai.Life,
+		MaxLife:   monster.ai.MaxLife,
+		IsMonster: true,
+		IsBoss:    record.IsSpecialBoss,
+		CanBlock:  record.BlockChance(w.difficulty) > 0,
+		Leech:     record.LeechSensitivity(w.difficulty),
+	}
+}
+
+// monsterLevel returns the level of the monsters of the given record in the world
+func (w *world) monsterLevel(record *d2records.MonStatRecord) int {
+	areaLevel := 0
+	if details := w.records.Level.Details[w.levelID]; details != nil {
+		areaLevel = details.MonsterLevel(w.difficulty)
+	}
+
+	return record.Level(w.difficulty, areaLevel)
+}
+
+// updateCombatant writes the life and mana of a combatant back to its unit
+func (w *world) updateCombatant(combatant *d2combat.Combatant) {
+	if player, found := w.players[combatant.ID]; found {
+		player.changed = player.changed || int(player.life) != int(combatant.Life) || int(player.mana) != int(combatant.Mana)
+		player.life, player.mana = combatant.Life, combatant.Mana
+
+		return
+	}
+
+	if unit, found := w.hirelings[combatant.ID]; found {
+		unit.changed = unit.changed || int(unit.ai.Life) != int(combatant.Life)
+		unit.ai.Life = combatant.Life
+		unit.hireling.State.Life = int(combatant.Life)
+
+		return
+	}
+
+	if monster, found := w.monsters[combatant.ID]; found {
+		monster.changed = monster.changed || int(monster.ai.Life) != int(combatant.Life)
+		monster.ai.Life = combatant.Life
+	}
+}
+
+// statList returns a stat list with the given values, the stats are added in the order of their
+// names and the unknown stats are left out
+func (w *world) statList(values map[string]int) d2stats.StatList {
+	names := make([]string, 0, len(values))
+
+	for name, value := range values {
+		if value != 0 {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	stats := make([]d2stats.Stat, 0, len(names))
+
+	for _, name := range names {
+		if stat := w.stats.NewStat(name, float64(values[name])); stat != nil {
+			stats = append(stats, stat)
+		}
+	}
+
+	return w.stats.NewStatList(stats...)
+}
+
+// meleeTarget returns the ID of the living monster closest to the given world position within
+// the reach of a melee skill, or an empty string if there is none
+func (w *world) meleeTarget(player *worldPlayer, x, y float64) string {
+	position := player.Position.World()
+	targetID, closest := "", meleeReach
+
+	for _, monster := range w.sortedMonsters() {
+		monsterPosition := monster.Position.World()
+
+		if monsterPosition.Distance(position) > meleeReach {
+			continue
+		}
+
+		if distance := monster.ai.Distance(x, y); distance <= closest {
+			targetID, closest = monster.ID(), distance
+		}
+	}
+
+	return targetID
+}
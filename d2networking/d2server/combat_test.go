@@ -0,0 +1,189 @@
+package d2server
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+const (
+	testMonsterLife  = 25
+	testWeaponDamage = 10
+	testWeaponID     = "weapon"
+	testDexterity    = 25
+)
+
+// attackTestWorld returns the cast test world with the stats used in combat and a melee Attack
+// skill, the caster has the dexterity of a sorceress and wears a hand axe if armed
+func attackTestWorld(t *testing.T, armed bool) (*world, *testClient) {
+	t.Helper()
+
+	w, caster := castTestWorld(t, 1)
+
+	w.records.Item.Stats = d2records.ItemStatCosts{}
+	for _, name := range []string{
+		d2combat.StatStrength, d2combat.StatDexterity, d2combat.StatAttackRating, d2combat.StatDefense,
+		d2combat.StatMinDamage, d2combat.StatMaxDamage, d2combat.StatDamageBonus,
+	} {
+		w.records.Item.Stats[name] = &d2records.ItemStatCostRecord{Name: name}
+	}
+
+	attack := w.records.Skill.Details[testBasicSkillID]
+	attack.Range, attack.SrcDam = "m", 128
+
+	caster.state.Stats.Dexterity = testDexterity
+
+	axe := w.records.Item.Weapons["hax"]
+	axe.MinDamage, axe.MaxDamage = testWeaponDamage, testWeaponDamage
+
+	if !armed {
+		return w, caster
+	}
+
+	item, err := w.itemFactory.NewItem("hax")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.giveItem(w.players[caster.id], testWeaponID, item); err != nil {
+		t.Fatal(err)
+	}
+
+	worn := caster.state.Items[testWeaponID]
+	worn.Location, worn.BodyLocation = d2s.LocationEquipped, d2s.BodyRightHand
+
+	return w, caster
+}
+
+// testStatValue returns the sum of the values of the stats with the given name
+func testStatValue(stats d2stats.StatList, name string) int {
+	total := 0
+
+	for _, stat := range stats.Stats() {
+		if stat.Name() == name {
+			total += stat.Values()[0].Int()
+		}
+	}
+
+	return total
+}
+
+func TestPlayerCombatantHasTheDamageOfItsWeapon(t *testing.T) {
+	table := []struct {
+		name                 string
+		armed                bool
+		minDamage, maxDamage int
+	}{
+		{"unarmed", false, unarmedMinDamage, unarmedMaxDamage},
+		{"hand axe", true, testWeaponDamage, testWeaponDamage},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			w, caster := attackTestWorld(t, row.armed)
+			combatant := w.playerCombatant(w.players[caster.id])
+
+			if got := testStatValue(combatant.Stats, d2combat.StatMinDamage); got != row.minDamage {
+				t.Errorf("min damage %d, want %d", got, row.minDamage)
+			}
+
+			if got := testStatValue(combatant.Stats, d2combat.StatMaxDamage); got != row.maxDamage {
+				t.Errorf("max damage %d, want %d", got, row.maxDamage)
+			}
+
+			if got, want := testStatValue(combatant.Stats, d2combat.StatAttackRating), testDexterity*attackRatingPerDexterity; got != want {
+				t.Errorf("attack rating %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPlayerKillsAMonsterWithItsWeapon(t *testing.T) {
+	w, caster := attackTestWorld(t, true)
+
+	monster := w.addMonster(&d2records.MonStatRecord{Key: "test"}, 4, 4, "")
+	monster.ai.Life, monster.ai.MaxLife = testMonsterLife, testMonsterLife
+
+	target := monster.Position.World()
+
+	for attacks := 0; attacks < 20; attacks++ {
+		packet, err := d2netpacket.CreateCastPacket(caster.id, testBasicSkillID, target.X(), target.Y())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.queue(caster, packet)
+		w.advance(1)
+
+		if _, alive := w.monsters[monster.ID()]; !alive {
+			return
+		}
+
+		if lost := testMonsterLife - int(monster.ai.Life); lost%testWeaponDamage != 0 {
+			t.Fatalf("monster lost %d life, want hits of %d", lost, testWeaponDamage)
+		}
+	}
+
+	t.Errorf("monster has %.0f life left after 20 attacks", monster.ai.Life)
+}
+
+func TestDeadPlayerCannotActUntilItReturnsToTown(t *testing.T) {
+	table := []struct {
+		name      string
+		act       int
+		departsTo int
+	}{
+		{"died in town", 1, 0},
+		{"died out of town", 2, townLevelIDs[2]},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			w, caster := castTestWorld(t, 1)
+			caster.state.Act = row.act
+
+			player := w.players[caster.id]
+			w.unitDied(caster.id, "")
+
+			if !player.dead() {
+				t.Fatal("killed player is alive")
+			}
+
+			cast, err := d2netpacket.CreateCastPacket(caster.id, testSkillID, 2.5, 2.5)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := player.Position.World()
+
+			w.queue(caster, movePacket(t, caster, start.X(), start.Y(), 3, 3))
+			w.queue(caster, cast)
+			w.advance(1)
+
+			if player.IsMoving() || player.mana != float64(caster.state.Stats.MaxMana) {
+				t.Errorf("dead player moves or casts")
+			}
+
+			if caster.state.Stats.Health == 0 {
+				t.Errorf("dead player is saved without life")
+			}
+
+			departures := w.advance(respawnSeconds)
+
+			if player.dead() || player.life != player.maxLife {
+				t.Errorf("player has %.0f of %.0f life after the respawn delay", player.life, player.maxLife)
+			}
+
+			switch {
+			case row.departsTo == 0 && len(departures) != 0:
+				t.Errorf("player died in town and leaves the level")
+			case row.departsTo != 0 && (len(departures) != 1 || departures[0].levelID != row.departsTo):
+				t.Errorf("departures %v, want level %d", departures, row.departsTo)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@
 package d2tcpclientconnection
 
 import (
-	"encoding/json"
 	"net"
 
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
@@ -15,13 +14,18 @@ import (
 type TCPClientConnection struct {
 	id            string
 	tcpConnection net.Conn
+	writer        *d2netpacket.PacketWriter
+	binaryVersion int
 	playerState   *d2hero.HeroState
 }
 
-// CreateTCPClientConnection creates a new tcp client connection instance
-func CreateTCPClientConnection(tcpConnection net.Conn, id string) *TCPClientConnection {
+// CreateTCPClientConnection creates a new tcp client connection instance, which sends the packets
+// as binary frames of the given version, or as JSON for version 0
+func CreateTCPClientConnection(tcpConnection net.Conn, id string, binaryVersion int) *TCPClientConnection {
 	return &TCPClientConnection{
 		tcpConnection: tcpConnection,
+		writer:        d2netpacket.NewPacketWriter(tcpConnection, binaryVersion),
+		binaryVersion: binaryVersion,
 		id:            id,
 	}
 }
@@ -31,16 +35,14 @@ func (t TCPClientConnection) GetUniqueID() string {
 	return t.id
 }
 
+// BinaryVersion returns the binary version negotiated with the client, 0 if it receives JSON
+func (t *TCPClientConnection) BinaryVersion() int {
+	return t.binaryVersion
+}
+
 // SendPacketToClient marshals and sends (writes) NetPackets
 func (t *TCPClientConnection) SendPacketToClient(p d2netpacket.NetPacket) error {
-	encoder := json.NewEncoder(t.tcpConnection)
-
-	err := encoder.Encode(p)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return t.writer.WritePacket(p)
 }
 
 // SetPlayerState sets the game client player state
@@ -27,6 +27,7 @@ type UDPClientConnection struct {
 	address       *net.UDPAddr      // IP address of the associated RemoteClientConnection
 	udpConnection *net.UDPConn      // Server's UDP Connection
 	playerState   *d2hero.HeroState // Client's game state
+	binaryVersion int               // Binary version negotiated with the client, 0 for JSON
 
 	*d2util.Logger
 }
@@ -58,9 +59,30 @@ func (u UDPClientConnection) GetConnectionType() d2clientconnectiontype.ClientCo
 	return d2clientconnectiontype.LANClient
 }
 
-// SendPacketToClient compresses the JSON encoding of a NetPacket and
-// sends it to the client.
+// SetBinaryVersion sets the binary version negotiated with the client, 0 sends JSON.
+func (u *UDPClientConnection) SetBinaryVersion(binaryVersion int) {
+	u.binaryVersion = binaryVersion
+}
+
+// BinaryVersion returns the binary version negotiated with the client.
+func (u *UDPClientConnection) BinaryVersion() int {
+	return u.binaryVersion
+}
+
+// SendPacketToClient sends a NetPacket to the client, each datagram holds
+// a binary frame or the compressed JSON encoding of the packet.
 func (u *UDPClientConnection) SendPacketToClient(packet d2netpacket.NetPacket) error {
+	if u.binaryVersion > 0 {
+		frame, err := d2netpacket.MarshalBinaryPacket(packet, u.binaryVersion)
+		if err != nil {
+			return err
+		}
+
+		_, err = u.udpConnection.WriteToUDP(frame, u.address)
+
+		return err
+	}
+
 	data, err := json.Marshal(packet.PacketData)
 	if err != nil {
 		return err
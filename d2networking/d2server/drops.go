@@ -0,0 +1,304 @@
+package d2server
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2s"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+const (
+	itemOwnershipTime = 5.0 // seconds a dropped item can only be picked up by the player it dropped for
+
+	pickUpReach  = 2.0 // how far, in tiles, from an item a player can pick it up
+	operateReach = 2.0 // how far, in tiles, from an object a player can operate it
+
+	dropSpread = 2 // how far, in tiles, from a monster or a chest its items are scattered
+	dropTries  = 8 // random tiles tried for each item before it is put on the tile of the drop
+
+	// chestTreasureClassFmt is the treasure class of the chests of an act and a difficulty
+	chestTreasureClassFmt = "Act %d%s Chest A"
+
+	// statMagicFind is the itemstatcost.txt name of the magic find of the items
+	statMagicFind = "item_magicbonus"
+)
+
+// difficultySuffixes are appended to the act of the treasure classes of nightmare and hell
+var difficultySuffixes = map[d2enum.DifficultyType]string{ //nolint:gochecknoglobals // treasureclassex.txt names
+	d2enum.DifficultyNormal:    "",
+	d2enum.DifficultyNightmare: " (N)",
+	d2enum.DifficultyHell:      " (H)",
+}
+
+// monsterDied drops the treasure of a monster killed by the given player at the given world
+// position. The treasure class and the level of the items depend on the difficulty of the world.
+func (w *world) monsterDied(monster *d2records.MonStatRecord, x, y float64, killer *worldPlayer) {
+	areaLevel := 0
+	if details := w.records.Level.Details[w.levelID]; details != nil {
+		areaLevel = details.MonsterLevel(w.difficulty)
+	}
+
+	w.dropTreasure(monster.TreasureClass(w.difficulty), monster.Level(w.difficulty, areaLevel), x, y, killer)
+}
+
+// openContainer opens the chest or breaks the barrel for the player and drops its treasure, a
+// container opens once
+func (w *world) openContainer(player *worldPlayer, container *worldObject) error {
+	if container.operated || container.IsOpen() {
+		return nil
+	}
+
+	if err := container.Open(); err != nil {
+		return err
+	}
+
+	container.operated = true
+
+	packet, err := objectStatePacket(container.Object, player.ID(), container.Mode())
+	if err != nil {
+		return err
+	}
+
+	w.send(packet, "")
+
+	details := w.records.Level.Details[w.levelID]
+	if details == nil {
+		return nil
+	}
+
+	x, y := container.GetPositionF()
+	name := fmt.Sprintf(chestTreasureClassFmt, details.Act+1, difficultySuffixes[w.difficulty])
+	w.dropTreasure(name, details.MonsterLevel(w.difficulty), x, y, player)
+
+	return nil
+}
+
+// dropTreasure rolls the treasure class for the player the drop is for and scatters the items
+// around the given world position, the items are reserved for the player for a while
+func (w *world) dropTreasure(name string, level int, x, y float64, owner *worldPlayer) {
+	record := w.records.GetTreasureClassByName(name)
+	if record == nil {
+		w.Debugf("unknown treasure class %q", name)
+		return
+	}
+
+	ctx := &diablo2item.DropContext{
+		Level:     level,
+		Players:   len(w.players),
+		MagicFind: w.magicFind(owner),
+	}
+
+	for _, item := range w.itemFactory.ItemsFromDrop(record, ctx) {
+		tileX, tileY := w.dropTile(x, y)
+
+		if err := w.placeItem(item, tileX, tileY, owner.ID()); err != nil {
+			w.Errorf("failed to drop %s: %v", item.CommonCode, err)
+		}
+	}
+}
+
+// magicFind returns the magic find of the player, the magic find of the items its hero wears and
+// of the items in their sockets. The bonuses of the sets are left out.
+func (w *world) magicFind(player *worldPlayer) int {
+	record := w.records.Item.Stats[statMagicFind]
+	if record == nil {
+		return 0
+	}
+
+	total := 0
+
+	for _, item := range player.client.GetPlayerState().Items {
+		if item.Location == d2s.LocationEquipped {
+			total += savedStatValue(item, record.Index)
+		}
+	}
+
+	return total
+}
+
+// savedStatValue returns the sum of the values of the stat with the given ID on a saved item, its
+// runeword and the items in its sockets
+func savedStatValue(item *d2s.Item, statID int) int {
+	total := 0
+
+	for _, list := range [][]d2s.Property{item.Properties, item.RunewordProperties} {
+		for idx := range list {
+			if list[idx].ID == statID && len(list[idx].Values) > 0 {
+				total += list[idx].Values[0]
+			}
+		}
+	}
+
+	for _, socketed := range item.Sockets {
+		total += savedStatValue(socketed, statID)
+	}
+
+	return total
+}
+
+// dropTile returns a walkable tile near the given world position which has no item on it yet
+func (w *world) dropTile(x, y float64) (tileX, tileY int) {
+	for idx := 0; idx < dropTries; idx++ {
+		nearX := int(x) + w.rand.Intn(2*dropSpread+1) - dropSpread
+		nearY := int(y) + w.rand.Intn(2*dropSpread+1) - dropSpread
+
+		if !w.inBounds(float64(nearX), float64(nearY)) || w.itemAt(nearX, nearY) {
+			continue
+		}
+
+		subX, subY := nearX*subtilesPerTile+middleOfTileOffset, nearY*subtilesPerTile+middleOfTileOffset
+		if !w.mapEngine.SubTileAt(subX, subY).BlockWalk {
+			return nearX, nearY
+		}
+	}
+
+	return int(x), int(y)
+}
+
+// itemAt returns true if there is an item on the given tile
+func (w *world) itemAt(tileX, tileY int) bool {
+	for _, item := range w.items {
+		tile := item.Position.Tile()
+
+		if int(tile.X()) == tileX && int(tile.Y()) == tileY {
+			return true
+		}
+	}
+
+	return false
+}
+
+// placeItem puts the item on the given tile and tells the clients about it, an item with an
+// owner can only be picked up by its owner for a while
+func (w *world) placeItem(item *diablo2item.Item, tileX, tileY int, ownerID string) error {
+	entity, err := w.mapEngine.NewItemEntity(tileX*subtilesPerTile, tileY*subtilesPerTile, item)
+	if err != nil {
+		return err
+	}
+
+	if ownerID != "" {
+		entity.SetOwner(ownerID, itemOwnershipTime)
+	}
+
+	packet, err := itemPacket(entity, tileX, tileY)
+	if err != nil {
+		return err
+	}
+
+	w.items[entity.ID()] = entity
+	w.mapEngine.AddEntity(entity)
+	w.send(packet, "")
+
+	return nil
+}
+
+// itemPacket returns the packet which puts the item on the given tile on a client
+func itemPacket(entity *d2mapentity.Item, tileX, tileY int) (d2netpacket.NetPacket, error) {
+	data, err := entity.Item.Marshal()
+	if err != nil {
+		return d2netpacket.NetPacket{}, err
+	}
+
+	quantity := 0
+	if entity.Item.IsGold() {
+		quantity = entity.Item.Quantity()
+	}
+
+	return d2netpacket.CreateDroppedItemPacket(entity.ID(), entity.Owner(), tileX, tileY, data, quantity)
+}
+
+// spawnItem puts the item with the codes of the packet on the ground, it is a debug command of
+// the host
+func (w *world) spawnItem(spawn *d2netpacket.SpawnItemPacket) error {
+	if !w.inBounds(float64(spawn.X), float64(spawn.Y)) {
+		return nil
+	}
+
+	item, err := w.itemFactory.NewItem(spawn.Codes...)
+	if err != nil {
+		return err
+	}
+
+	return w.placeItem(item, spawn.X, spawn.Y, "")
+}
+
+// pickUpItem gives the item of the packet to the player if the player is close enough to it and
+// the item is not reserved for another player. Gold is added to the gold of the player, the other
+// items to the inventory of its hero.
+func (w *world) pickUpItem(player *worldPlayer, pickUp *d2netpacket.PickUpItemPacket) error {
+	item, found := w.items[pickUp.ItemID]
+	if !found {
+		return nil // someone else was faster
+	}
+
+	itemPosition := item.GetPosition()
+	if player.Position.World().Distance(itemPosition.World()) > pickUpReach {
+		w.Debugf("%s is too far away to pick up %s", player.ID(), pickUp.ItemID)
+		return nil
+	}
+
+	if !item.CanPickUp(player.ID()) {
+		w.Debugf("%s cannot pick up %s of %s yet", player.ID(), pickUp.ItemID, item.Owner())
+		return nil
+	}
+
+	gold := 0
+	if item.Item.IsGold() {
+		gold = item.Item.Quantity()
+	}
+
+	packet, err := d2netpacket.CreatePickUpItemPacket(player.ID(), pickUp.ItemID, gold)
+	if err != nil {
+		return err
+	}
+
+	if !item.Item.IsGold() {
+		if err := w.giveItem(player, pickUp.ItemID, item.Item); err != nil {
+			return err
+		}
+	}
+
+	player.client.GetPlayerState().Gold += gold
+
+	delete(w.items, pickUp.ItemID)
+	w.mapEngine.RemoveEntity(item)
+	w.send(packet, "")
+
+	return nil
+}
+
+// advanceItems counts down the time the items are reserved for their owners
+func (w *world) advanceItems(tickTime float64) {
+	for _, item := range w.items {
+		item.Advance(tickTime)
+	}
+}
+
+// groundPackets returns the packets which show a client entering the world the items on the
+// ground
+func (w *world) groundPackets() []d2netpacket.NetPacket {
+	w.Lock()
+	defer w.Unlock()
+
+	packets := make([]d2netpacket.NetPacket, 0)
+
+	for _, item := range w.items {
+		position := item.GetPosition()
+		tile := position.Tile()
+
+		packet, err := itemPacket(item, int(math.Floor(tile.X())), int(math.Floor(tile.Y())))
+		if err != nil {
+			w.Errorf("failed to send %s: %v", item.Item.CommonCode, err)
+			continue
+		}
+
+		packets = append(packets, packet)
+	}
+
+	return packets
+}
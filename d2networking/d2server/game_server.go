@@ -2,7 +2,6 @@ package d2server
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"io"
 	"net"
@@ -11,12 +10,9 @@ import (
 
 	"github.com/robertkrimen/otto"
 
-	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
 	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
 	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
-	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
-	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapgen"
 	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2client/d2clientconnectiontype"
 	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
 	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
@@ -36,6 +32,8 @@ const (
 var (
 	errPlayerAlreadyExists = errors.New("player already exists")
 	errServerFull          = errors.New("server full") // Server currently at maximum TCP connections
+
+	errInvalidConnectionRequest = errors.New("invalid player connection request")
 )
 
 // GameServer manages a copy of the map and entities as well as manages packet routing and connections.
@@ -48,7 +46,7 @@ type GameServer struct {
 	ctx               context.Context
 	cancel            context.CancelFunc
 	asset             *d2asset.AssetManager
-	mapEngines        []*d2mapengine.MapEngine
+	levels            *levelManager
 	scriptEngine      *d2script.ScriptEngine
 	seed              int64
 	maxConnections    int
@@ -94,7 +92,6 @@ func NewGameServer(asset *d2asset.AssetManager,
 		networkServer:     networkServer,
 		maxConnections:    maxConnections[0],
 		packetManagerChan: make(chan ReceivedPacket),
-		mapEngines:        make([]*d2mapengine.MapEngine, 0),
 		scriptEngine:      d2script.CreateScriptEngine(),
 		seed:              time.Now().UnixNano(),
 		heroStateFactory:  heroStateFactory,
@@ -104,21 +101,11 @@ func NewGameServer(asset *d2asset.AssetManager,
 	gameServer.Logger.SetPrefix(logPrefix)
 	gameServer.Logger.SetLevel(l)
 
-	mapEngine := d2mapengine.CreateMapEngine(l, asset)
-	mapEngine.SetSeed(gameServer.seed)
-	mapEngine.ResetMap(d2enum.RegionAct1Town, 100, 100)
-
-	mapGen, err := d2mapgen.NewMapGenerator(asset, l, mapEngine)
-	if err != nil {
-		return nil, err
-	}
-
-	mapGen.GenerateAct1Overworld()
-
-	gameServer.mapEngines = append(gameServer.mapEngines, mapEngine)
+	// the levels are loaded when the first player enters them
+	gameServer.levels = newLevelManager(asset, gameServer.seed, l, gameServer.Logger)
 
 	gameServer.scriptEngine.AddFunction("getMapEngines", func(call otto.FunctionCall) otto.Value {
-		val, err := gameServer.scriptEngine.ToValue(gameServer.mapEngines)
+		val, err := gameServer.scriptEngine.ToValue(gameServer.levels.mapEngines())
 		if err != nil {
 			gameServer.Error(err.Error())
 		}
@@ -146,6 +133,7 @@ func (g *GameServer) Start() error {
 	g.listener = l
 
 	go g.packetManager()
+	go g.runLevels()
 
 	go func() {
 		for {
@@ -221,15 +209,16 @@ func (g *GameServer) handleConnection(conn net.Conn) {
 		}
 	}()
 
-	decoder := json.NewDecoder(conn)
+	reader := d2netpacket.NewPacketReader(conn)
 
 	for {
-		var packet d2netpacket.NetPacket
-
-		err := decoder.Decode(&packet)
+		packet, err := reader.ReadPacket()
 		if err != nil {
-			switch err {
-			case io.EOF:
+			switch {
+			case errors.Is(err, d2netpacket.ErrMalformedPacket):
+				g.Warningf("Skipping packet from %s: %v", conn.RemoteAddr().String(), err)
+				continue
+			case err == io.EOF:
 				break // the other side closed the connection
 			default:
 				g.Error(err.Error())
@@ -244,9 +233,10 @@ func (g *GameServer) handleConnection(conn net.Conn) {
 		if connected == 0 {
 			if packet.PacketType != d2netpackettype.PlayerConnectionRequest {
 				g.Infof("Closing connection with %s: did not receive new player connection request...", conn.RemoteAddr().String())
+				return
 			}
 
-			if client, err = g.registerConnection(packet.PacketData, conn); err != nil {
+			if client, err = g.registerConnection(packet, conn); err != nil {
 				return
 			}
 
@@ -270,7 +260,7 @@ func (g *GameServer) handleConnection(conn net.Conn) {
 // Errors:
 // - errServerFull
 // - errPlayerAlreadyExists
-func (g *GameServer) registerConnection(b []byte, conn net.Conn) (ClientConnection, error) {
+func (g *GameServer) registerConnection(request d2netpacket.NetPacket, conn net.Conn) (ClientConnection, error) {
 	var client ClientConnection
 
 	g.Lock()
@@ -295,9 +285,10 @@ func (g *GameServer) registerConnection(b []byte, conn net.Conn) (ClientConnecti
 	}
 
 	// if it is not full, unmarshal the playerConnectionRequest
-	packet, err := d2netpacket.UnmarshalPlayerConnectionRequest(b)
-	if err != nil {
-		g.Errorf("Failed to unmarshal PlayerConnectionRequest: %s\n", err)
+	packet, err := d2netpacket.UnmarshalPlayerConnectionRequest(request)
+	if err != nil || packet.PlayerState == nil {
+		g.Errorf("Failed to unmarshal PlayerConnectionRequest: %v\n", err)
+		return client, errInvalidConnectionRequest
 	}
 
 	// check to see if the player is already registered
@@ -307,7 +298,8 @@ func (g *GameServer) registerConnection(b []byte, conn net.Conn) (ClientConnecti
 	}
 
 	// Client a new TCP Client Connection and add it to the connections map
-	client = d2tcpclientconnection.CreateTCPClientConnection(conn, packet.ID)
+	binaryVersion := d2netpacket.NegotiateBinaryVersion(packet.BinaryVersion)
+	client = d2tcpclientconnection.CreateTCPClientConnection(conn, packet.ID, binaryVersion)
 	client.SetPlayerState(packet.PlayerState)
 
 	g.OnClientConnected(client)
@@ -315,111 +307,44 @@ func (g *GameServer) registerConnection(b []byte, conn net.Conn) (ClientConnecti
 	return client, nil
 }
 
-// OnClientConnected initializes the given ClientConnection. It sends the
-// following packets to the newly connected client: UpdateServerInfoPacket,
-// GenerateMapPacket, AddPlayerPacket.
+// OnClientConnected initializes the given ClientConnection. It sends an
+// UpdateServerInfoPacket to the newly connected client, then its player
+// enters the town of its act on the next tick of the levels.
 //
-// It also sends AddPlayerPackets for each other player entity to the new
-// player and vice versa, so all player entities exist on all clients.
+// Entering a level sends a GenerateMapPacket for the level to the client and
+// AddPlayerPackets for each other player on the level to the new player and
+// vice versa, so all player entities of a level exist on its clients.
 //
 // For more information, see d2networking.d2netpacket.
 func (g *GameServer) OnClientConnected(client ClientConnection) {
-	// Temporary position hack --------------------------------------------
-	// https://github.com/OpenDiablo2/OpenDiablo2/issues/829
-	sx, sy := g.mapEngines[0].GetStartPosition()
-	clientPlayerState := client.GetPlayerState()
-	clientPlayerState.X = sx
-	clientPlayerState.Y = sy
-	// --------------------------------------------------------------------
-
 	g.Infof("Client connected with an id of %s", client.GetUniqueID())
 	g.connections[client.GetUniqueID()] = client
 
-	g.handleClientConnection(client, sx, sy)
-}
+	g.handleClientConnection(client)
 
-func (g *GameServer) handleClientConnection(client ClientConnection, x, y float64) {
-	usi, err := d2netpacket.CreateUpdateServerInfoPacket(g.seed, client.GetUniqueID())
-	if err != nil {
-		g.Errorf("UpdateServerInfoPacket: %v", err)
-	}
+	// the levels send their packets after the server info, which negotiates the packet encoding
+	g.levels.join(client)
+}
 
-	err = client.SendPacketToClient(usi)
-	if err != nil {
-		g.Errorf("GameServer: error sending UpdateServerInfoPacket to client %s: %s", client.GetUniqueID(), err)
-	}
+// toSubTile returns the sub tile in the middle of the tile at the given world position
+func toSubTile(tile float64) int {
+	return int(tile*subtilesPerTile) + middleOfTileOffset
+}
 
-	gmp, err := d2netpacket.CreateGenerateMapPacket(d2enum.RegionAct1Town)
-	if err != nil {
-		g.Errorf("GenerateMapPacket: %v", err)
+func (g *GameServer) handleClientConnection(client ClientConnection) {
+	binaryVersion := 0
+	if binaryClient, ok := client.(binaryConnection); ok {
+		binaryVersion = binaryClient.BinaryVersion()
 	}
 
-	err = client.SendPacketToClient(gmp)
+	usi, err := d2netpacket.CreateUpdateServerInfoPacket(g.seed, client.GetUniqueID(), binaryVersion)
 	if err != nil {
-		g.Errorf("GameServer: error sending GenerateMapPacket to client %s: %s", client.GetUniqueID(), err)
+		g.Errorf("UpdateServerInfoPacket: %v", err)
 	}
 
-	playerState := client.GetPlayerState()
-
-	// these are in subtiles
-	playerX := int(x*subtilesPerTile) + middleOfTileOffset
-	playerY := int(y*subtilesPerTile) + middleOfTileOffset
-
-	d2hero.HydrateSkills(playerState.Skills, g.asset)
-
-	createPlayerPacket, err := d2netpacket.CreateAddPlayerPacket(
-		client.GetUniqueID(),
-		playerState.HeroName,
-		playerX,
-		playerY,
-		playerState.HeroType,
-		playerState.Stats,
-		playerState.Skills,
-		playerState.Equipment,
-		playerState.LeftSkill,
-		playerState.RightSkill,
-		playerState.Gold,
-	)
+	err = client.SendPacketToClient(usi)
 	if err != nil {
-		g.Errorf("AddPlayerPacket: %v", err)
-	}
-
-	for _, connection := range g.connections {
-		err := connection.SendPacketToClient(createPlayerPacket)
-		if err != nil {
-			g.Errorf("GameServer: error sending %T to client %s: %s", createPlayerPacket, connection.GetUniqueID(), err)
-		}
-
-		if connection.GetUniqueID() == client.GetUniqueID() {
-			continue
-		}
-
-		conPlayerState := connection.GetPlayerState()
-		playerX := int(conPlayerState.X*subtilesPerTile) + middleOfTileOffset
-		playerY := int(conPlayerState.Y*subtilesPerTile) + middleOfTileOffset
-		app, err := d2netpacket.CreateAddPlayerPacket(
-			connection.GetUniqueID(),
-			conPlayerState.HeroName,
-			playerX,
-			playerY,
-			conPlayerState.HeroType,
-			conPlayerState.Stats,
-			conPlayerState.Skills,
-			conPlayerState.Equipment,
-			conPlayerState.LeftSkill,
-			conPlayerState.RightSkill,
-			conPlayerState.Gold,
-		)
-
-		if err != nil {
-			g.Errorf("AddPlayerPacket: %v", err)
-		}
-
-		err = client.SendPacketToClient(app)
-
-		if err != nil {
-			g.Errorf("GameServer: error sending CreateAddPlayerPacket to client %s: %s", connection.GetUniqueID(), err)
-		}
+		g.Errorf("GameServer: error sending UpdateServerInfoPacket to client %s: %s", client.GetUniqueID(), err)
 	}
 }
 
@@ -429,6 +354,15 @@ func (g *GameServer) handleClientConnection(client ClientConnection, x, y float6
 func (g *GameServer) OnClientDisconnected(client ClientConnection) {
 	g.Infof("Client disconnected with an id of %s", client.GetUniqueID())
 	delete(g.connections, client.GetUniqueID())
+	g.levels.leave(client.GetUniqueID())
+
+	// the heroes loaded on this machine are saved with what they traded, the remote players
+	// save their heroes themselves
+	if playerState := client.GetPlayerState(); playerState != nil && playerState.FilePath != "" {
+		if err := g.heroStateFactory.Save(playerState); err != nil {
+			g.Errorf("GameServer: error saving Player: %s", err)
+		}
+	}
 
 	if client.GetConnectionType() == d2clientconnectiontype.Local {
 		g.Info("Host disconnected, game server shuting down")
@@ -453,21 +387,13 @@ func (g *GameServer) OnPacketReceived(client ClientConnection, packet d2netpacke
 	}
 
 	switch packet.PacketType {
-	case d2netpackettype.MovePlayer:
-		movePacket, err := d2netpacket.UnmarshalMovePlayer(packet.PacketData)
-		if err != nil {
-			return err
-		}
-
-		playerState := g.connections[client.GetUniqueID()].GetPlayerState()
-		playerState.X = movePacket.DestX
-		playerState.Y = movePacket.DestY
-
-		g.sendPacketToClients(packet)
-	case d2netpackettype.CastSkill, d2netpackettype.SpawnItem:
-		g.sendPacketToClients(packet)
+	case d2netpackettype.MovePlayer, d2netpackettype.CastSkill, d2netpackettype.WarpPlayer,
+		d2netpackettype.SpawnItem, d2netpackettype.PickUpItem, d2netpackettype.OperateObject,
+		d2netpackettype.SpawnMonster, d2netpackettype.Trade, d2netpackettype.Hire:
+		// the commands of the players are validated by the level of the player on its next tick
+		g.levels.queue(client, packet)
 	case d2netpackettype.SavePlayer:
-		savePacket, err := d2netpacket.UnmarshalSavePlayer(packet.PacketData)
+		savePacket, err := d2netpacket.UnmarshalSavePlayer(packet)
 		if err != nil {
 			return err
 		}
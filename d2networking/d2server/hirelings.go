@@ -0,0 +1,596 @@
+package d2server
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2ai"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hireling"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2skill"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+const (
+	hirelingIDFmt = "hireling-%s"
+	hirelingAI    = "Hireling" // the behaviour of the hirelings in the AI controller
+
+	hireOfferCount = 4    // hirelings a seller offers at a time
+	hirelingRange  = 10.0 // tiles within which a hireling with a missile shoots at its target
+)
+
+// worldHireling is the hireling of a player on the map, its decisions are made by the hireling
+// controller of the world. Its state is the state saved with the hero of the player.
+type worldHireling struct {
+	*d2mapentity.HeadlessEntity
+	ai         *d2ai.Monster
+	hireling   *d2hireling.Hireling
+	ownerID    string
+	states     *d2states.States
+	attackTime float64 // seconds until the hireling can attack again
+	changed    bool    // true if the state has to be sent in the next state delta
+}
+
+// hirelingID returns the ID of the hireling of the player with the given ID
+func hirelingID(playerID string) string {
+	return fmt.Sprintf(hirelingIDFmt, playerID)
+}
+
+// spawnHireling puts the living hireling of the player next to it. The clients are told about the
+// hireling either way, so its player knows about a dead hireling too.
+func (w *world) spawnHireling(player *worldPlayer) {
+	state := player.client.GetPlayerState().Hireling
+	if state == nil {
+		return
+	}
+
+	hireling, err := w.hireFactory.Load(state)
+	if err != nil {
+		w.Warningf("hireling of %s: %v", player.ID(), err)
+		return
+	}
+
+	if !hireling.Alive() {
+		w.sendHireling(player.ID(), nil, state)
+		return
+	}
+
+	id := hirelingID(player.ID())
+	position := d2vector.NewPosition(player.Position.X(), player.Position.Y())
+	tile := position.World()
+	record := hireling.MonStat
+	maxLife := hireling.Stats().MaxLife
+
+	if state.Life <= 0 || state.Life > maxLife {
+		state.Life = maxLife
+	}
+
+	unit := &worldHireling{
+		HeadlessEntity: d2mapentity.NewHeadlessEntity(id, position),
+		ai:             d2ai.NewMonster(id, record, w.records.Monster.Stats2[record.ExtraDataKey], w.difficulty, tile.X(), tile.Y()),
+		hireling:       hireling,
+		ownerID:        player.ID(),
+		states:         d2states.NewStates(),
+	}
+
+	unit.ai.AI = hirelingAI
+	unit.ai.OwnerID = player.ID()
+	unit.ai.Life, unit.ai.MaxLife = float64(state.Life), float64(maxLife)
+	unit.ai.CanRun = true
+
+	if w.hirelingMissiles(hireling) {
+		unit.ai.Range = hirelingRange
+	}
+
+	unit.SetSpeed(float64(record.SpeedBase))
+
+	w.hirelings[id] = unit
+	w.hirelingAI.Add(unit.ai)
+	w.mapEngine.AddEntity(unit)
+	w.sendHireling(player.ID(), unit, state)
+}
+
+// removeHireling takes the hireling of the player with the given ID off the map, its state stays
+// with the player
+func (w *world) removeHireling(playerID string) {
+	id := hirelingID(playerID)
+
+	unit, found := w.hirelings[id]
+	if !found {
+		return
+	}
+
+	w.hirelingAI.Remove(id)
+	w.afflictions.Remove(id)
+	w.mapEngine.RemoveEntity(unit)
+	delete(w.hirelings, id)
+
+	w.sendHireling(playerID, nil, unit.hireling.State)
+}
+
+// hirelingMissiles returns true if the hireling shoots missiles, with its attack or its skills
+func (w *world) hirelingMissiles(hireling *d2hireling.Hireling) bool {
+	if hireling.MonStat.MissileA1 != "" {
+		return true
+	}
+
+	for _, skill := range hireling.Skills() {
+		if record := w.records.GetSkillByName(skill.Name); record != nil && record.Srvmissile != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sendHireling tells the clients about the hireling of a player, the hireling is taken off the
+// map of the clients if it is not on the map of the world
+func (w *world) sendHireling(playerID string, unit *worldHireling, state *d2hero.HirelingState) {
+	packet, err := hirelingPacket(playerID, unit, state)
+	if err != nil {
+		w.Errorf("HirelingPacket: %v", err)
+		return
+	}
+
+	w.send(packet, "")
+}
+
+// hirelingPacket returns the packet of the hireling of a player
+func hirelingPacket(playerID string, unit *worldHireling, state *d2hero.HirelingState) (d2netpacket.NetPacket, error) {
+	if unit == nil {
+		return d2netpacket.CreateHirelingPacket(playerID, "", "", 0, 0, state)
+	}
+
+	return d2netpacket.CreateHirelingPacket(playerID, unit.ID(), unit.hireling.MonsterKey(),
+		int(unit.Position.X()), int(unit.Position.Y()), state)
+}
+
+// hirelingPackets returns the packets which show a client entering the world the hirelings on
+// the map
+func (w *world) hirelingPackets() []d2netpacket.NetPacket {
+	w.Lock()
+	defer w.Unlock()
+
+	packets := make([]d2netpacket.NetPacket, 0, len(w.hirelings))
+
+	for _, unit := range w.sortedHirelings() {
+		packet, err := hirelingPacket(unit.ownerID, unit, unit.hireling.State)
+		if err != nil {
+			w.Errorf("HirelingPacket: %v", err)
+			continue
+		}
+
+		packets = append(packets, packet)
+	}
+
+	return packets
+}
+
+// sortedHirelings returns the hirelings on the map ordered by ID
+func (w *world) sortedHirelings() []*worldHireling {
+	hirelings := make([]*worldHireling, 0, len(w.hirelings))
+	for _, unit := range w.hirelings {
+		hirelings = append(hirelings, unit)
+	}
+
+	sort.Slice(hirelings, func(i, j int) bool {
+		return hirelings[i].ID() < hirelings[j].ID()
+	})
+
+	return hirelings
+}
+
+// advanceHirelings moves the hirelings and carries out the decisions of their AI, they fight the
+// monsters and follow their players
+func (w *world) advanceHirelings(tickTime float64) {
+	if len(w.hirelings) == 0 {
+		return
+	}
+
+	for _, unit := range w.hirelings {
+		unit.Advance(tickTime)
+
+		if unit.attackTime -= tickTime; unit.attackTime < 0 {
+			unit.attackTime = 0
+		}
+
+		position := unit.Position.World()
+		unit.ai.X, unit.ai.Y = position.X(), position.Y()
+	}
+
+	targets := make([]*d2ai.Unit, 0, len(w.monsters))
+
+	for _, monster := range w.sortedMonsters() {
+		targets = append(targets, &monster.ai.Unit)
+	}
+
+	for _, decision := range w.hirelingAI.AdvanceOwned(tickTime, targets, w.playerUnits()) {
+		unit := w.hirelings[decision.MonsterID]
+
+		switch decision.Type {
+		case d2ai.ActionMove:
+			if w.moveEntity(unit.HeadlessEntity, unit.hireling.MonStat, decision.X, decision.Y, decision.Running) {
+				unit.changed = true
+			}
+		case d2ai.ActionAttack, d2ai.ActionShoot:
+			if err := w.hirelingAttack(unit, &decision.Action); err != nil {
+				w.Errorf("attack of %s failed: %v", unit.ID(), err)
+			}
+		}
+	}
+}
+
+// hirelingAttack attacks the monster of the action with a skill the hireling picks, or with its
+// weapon
+func (w *world) hirelingAttack(unit *worldHireling, action *d2ai.Action) error {
+	monster, found := w.monsters[action.TargetID]
+	if !found || unit.attackTime > 0 {
+		return nil
+	}
+
+	unit.StopMoving()
+	unit.attackTime = monsterAttackTime
+	unit.changed = true
+
+	stats := unit.hireling.Stats()
+	attack := d2combat.WeaponAttack(stats.MinDamage, stats.MaxDamage)
+	missileName, missiles := "", 0
+
+	if skill := unit.hireling.PickSkill(w.rand.Intn); skill != nil {
+		if record := w.records.GetSkillByName(skill.Name); record != nil {
+			attack = d2combat.SkillAttack(d2skill.New(w.records, record, skill.Level, unit.hireling))
+			missileName, missiles = w.hirelingSkillMissiles(unit, record, skill.Level, monster, attack)
+		}
+	}
+
+	if missiles == 0 && action.Type == d2ai.ActionShoot {
+		missile := w.records.GetMissileByName(unit.hireling.MonStat.MissileA1)
+		if missile == nil {
+			return nil
+		}
+
+		attack.Melee = false
+		missileName = missile.Name
+		missiles++
+
+		w.spawnMissile(unit.ID(), unit.Position, missile, monster.Position, attack)
+	}
+
+	target := monster.Position.World()
+
+	packet, err := d2netpacket.CreateMonsterAttackPacket(unit.ID(), monster.ID(), missileName, target.X(), target.Y())
+	if err != nil {
+		return err
+	}
+
+	// the attack is sent before its outcome
+	w.send(packet, "")
+
+	if missiles == 0 {
+		w.hit(unit.ID(), monster.ID(), attack)
+	}
+
+	return nil
+}
+
+// hirelingSkillMissiles fires the server missiles of a skill of the hireling at the monster and
+// returns the name of the first missile and the number of missiles
+func (w *world) hirelingSkillMissiles(unit *worldHireling, record *d2records.SkillRecord, level int,
+	monster *worldMonster, attack *d2combat.Attack) (name string, count int) {
+	for _, missileName := range []string{record.Srvmissile, record.Srvmissilea, record.Srvmissileb, record.Srvmissilec} {
+		missile := w.records.GetMissileByName(missileName)
+		if missileName == "" || missile == nil {
+			continue
+		}
+
+		if count == 0 {
+			name = missile.Name
+		}
+
+		w.spawnMissile(unit.ID(), unit.Position, missile, monster.Position, missileAttack(attack, missile, level))
+		count++
+	}
+
+	return name, count
+}
+
+// hirelingCombatant returns the combat stats of a hireling
+func (w *world) hirelingCombatant(unit *worldHireling) *d2combat.Combatant {
+	stats := unit.hireling.Stats()
+
+	return &d2combat.Combatant{
+		ID:    unit.ID(),
+		Level: unit.hireling.State.Level,
+		Stats: unit.states.StatList(w.statList(map[string]int{
+			d2combat.StatDexterity:    stats.Dexterity,
+			d2combat.StatAttackRating: stats.AttackRating,
+			d2combat.StatDefense:      stats.Defense,
+			"fireresist":              stats.Resistance,
+			"lightresist":             stats.Resistance,
+			"coldresist":              stats.Resistance,
+			"poisonresist":            stats.Resistance,
+		})),
+		Life:    unit.ai.Life,
+		MaxLife: unit.ai.MaxLife,
+		Leech:   100, //nolint:gomnd // all of the leech works on hirelings
+	}
+}
+
+// hirelingDied takes a killed hireling off the map, it stays dead until its player resurrects it
+func (w *world) hirelingDied(unit *worldHireling) {
+	unit.hireling.Die()
+
+	w.hirelingAI.Kill(unit.ID())
+	w.mapEngine.RemoveEntity(unit)
+	delete(w.hirelings, unit.ID())
+
+	w.sendHireling(unit.ownerID, nil, unit.hireling.State)
+}
+
+// killer returns the player who killed a unit, the hirelings kill for their players
+func (w *world) killer(killerID string) *worldPlayer {
+	if unit, found := w.hirelings[killerID]; found {
+		killerID = unit.ownerID
+	}
+
+	return w.players[killerID]
+}
+
+// hirelingExperience gives the hireling of the player the experience of a monster the player or
+// the hireling killed
+func (w *world) hirelingExperience(player *worldPlayer, monster *d2records.MonStatRecord) {
+	unit, found := w.hirelings[hirelingID(player.ID())]
+	if !found {
+		return
+	}
+
+	playerLevel := 1
+	if stats := player.client.GetPlayerState().Stats; stats != nil {
+		playerLevel = stats.Level
+	}
+
+	if unit.hireling.AddExperience(monster.Experience(w.difficulty), playerLevel) == 0 {
+		return
+	}
+
+	unit.ai.MaxLife = float64(unit.hireling.Stats().MaxLife)
+	unit.ai.Life = float64(unit.hireling.State.Life)
+	unit.changed = true
+
+	w.sendHireling(player.ID(), unit, unit.hireling.State)
+}
+
+// hire shows the hirelings a seller offers, hires or resurrects a hireling for the player or
+// equips its hireling. The gold of the player is checked on the server.
+func (w *world) hire(player *worldPlayer, hire *d2netpacket.HirePacket) error {
+	switch hire.Action {
+	case d2netpacket.HireEquip:
+		return w.equipHireling(player, hire)
+	case d2netpacket.HireUnequip:
+		return w.unequipHireling(player, hire)
+	}
+
+	act := d2hireling.SellerAct(hire.Seller)
+	if act == 0 || !w.npcNear(player, hire.Seller, hire.X, hire.Y) {
+		w.Debugf("%s cannot hire from %s", player.ID(), hire.Seller)
+		return nil
+	}
+
+	switch hire.Action {
+	case d2netpacket.HireOffers:
+		return w.sendHireOffers(player, hire.Seller, act)
+	case d2netpacket.HireHire:
+		return w.hireHireling(player, hire)
+	case d2netpacket.HireResurrect:
+		return w.resurrectHireling(player, hire)
+	}
+
+	return nil
+}
+
+// sendHireOffers sends the hirelings the seller offers the player, with their prices. The offers
+// are rolled when the player first asks the seller in a level.
+func (w *world) sendHireOffers(player *worldPlayer, seller string, act int) error {
+	state := player.client.GetPlayerState()
+
+	if player.hireSeller != seller || len(player.hireOffers) == 0 {
+		level := 1
+		if state.Stats != nil {
+			level = state.Stats.Level
+		}
+
+		player.hireSeller = seller
+		player.hireOffers = make(map[string]*d2hireling.Hireling)
+
+		for _, offer := range w.hireFactory.Offers(act, state.Difficulty, level, hireOfferCount) {
+			player.hireOffers[uuid.New().String()] = offer
+		}
+	}
+
+	ids := make([]string, 0, len(player.hireOffers))
+	for id := range player.hireOffers {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	offers := make([]d2netpacket.HirelingOffer, 0, len(ids))
+
+	for _, id := range ids {
+		offer := player.hireOffers[id]
+		offers = append(offers, d2netpacket.HirelingOffer{ID: id, Hireling: *offer.State, Price: offer.HirePrice()})
+	}
+
+	resurrectPrice := 0
+
+	if state.Hireling != nil && state.Hireling.Dead {
+		if hireling, err := w.hireFactory.Load(state.Hireling); err == nil {
+			resurrectPrice = hireling.ResurrectPrice()
+		}
+	}
+
+	packet, err := d2netpacket.CreateHirelingOffersPacket(seller, offers, resurrectPrice)
+	if err != nil {
+		return err
+	}
+
+	w.sendTo(packet, player.ID())
+
+	return nil
+}
+
+// hireHireling replaces the hireling of the player with the offer of the packet, the items of
+// the dismissed hireling are lost
+func (w *world) hireHireling(player *worldPlayer, hire *d2netpacket.HirePacket) error {
+	offer, found := player.hireOffers[hire.ID]
+	if !found || !w.pay(player, offer.HirePrice()) {
+		return nil
+	}
+
+	delete(player.hireOffers, hire.ID)
+	w.removeHireling(player.ID())
+
+	player.client.GetPlayerState().Hireling = offer.State
+
+	if err := w.hireDone(player, hire.Action, hire.ID, d2enum.EquippedSlotNone, nil); err != nil {
+		return err
+	}
+
+	w.spawnHireling(player)
+
+	return nil
+}
+
+// resurrectHireling brings the dead hireling of the player back to life
+func (w *world) resurrectHireling(player *worldPlayer, hire *d2netpacket.HirePacket) error {
+	state := player.client.GetPlayerState().Hireling
+	if state == nil || !state.Dead {
+		return nil
+	}
+
+	hireling, err := w.hireFactory.Load(state)
+	if err != nil {
+		return err
+	}
+
+	if !w.pay(player, hireling.ResurrectPrice()) {
+		return nil
+	}
+
+	hireling.Resurrect()
+
+	if err := w.hireDone(player, hire.Action, "", d2enum.EquippedSlotNone, nil); err != nil {
+		return err
+	}
+
+	w.spawnHireling(player)
+
+	return nil
+}
+
+// equipHireling gives an item of the player to its hireling, the item the hireling wore in the
+// slot goes back to the player
+func (w *world) equipHireling(player *worldPlayer, hire *d2netpacket.HirePacket) error {
+	state := player.client.GetPlayerState().Hireling
+	if state == nil {
+		return nil
+	}
+
+	item := w.ownedItem(player, hire.ID)
+	if item == nil {
+		return nil
+	}
+
+	hireling, err := w.hireFactory.Load(state)
+	if err != nil {
+		return err
+	}
+
+	if item.CommonRecord() == nil ||
+		!hireling.CanEquip(hire.Slot, w.records.FindEquivalentTypesByItemCommonRecord(item.CommonRecord())) {
+		w.Debugf("hireling of %s cannot wear %s in slot %d", player.ID(), hire.ID, hire.Slot)
+		return nil
+	}
+
+	if _, worn := state.Equipment[hire.Slot]; worn {
+		if err := w.unequipHireling(player, &d2netpacket.HirePacket{Action: d2netpacket.HireUnequip, Slot: hire.Slot}); err != nil {
+			return err
+		}
+	}
+
+	data, err := item.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if state.Equipment == nil {
+		state.Equipment = make(map[d2enum.EquippedSlot][]byte)
+	}
+
+	state.Equipment[hire.Slot] = data
+	w.takeItem(player, hire.ID)
+
+	if err := w.hireDone(player, hire.Action, hire.ID, hire.Slot, nil); err != nil {
+		return err
+	}
+
+	w.sendHireling(player.ID(), w.hirelings[hirelingID(player.ID())], state)
+
+	return nil
+}
+
+// unequipHireling gives the item the hireling of the player wears in the slot of the packet back
+// to the player, with a new ID
+func (w *world) unequipHireling(player *worldPlayer, hire *d2netpacket.HirePacket) error {
+	state := player.client.GetPlayerState().Hireling
+	if state == nil {
+		return nil
+	}
+
+	data, worn := state.Equipment[hire.Slot]
+	if !worn {
+		return nil
+	}
+
+	item, err := w.itemFactory.Deserialize(data)
+	if err != nil {
+		return err
+	}
+
+	id := uuid.New().String()
+	if err := w.giveItem(player, id, item); err != nil {
+		return err
+	}
+
+	delete(state.Equipment, hire.Slot)
+
+	if err := w.hireDone(player, d2netpacket.HireUnequip, id, hire.Slot, data); err != nil {
+		return err
+	}
+
+	w.sendHireling(player.ID(), w.hirelings[hirelingID(player.ID())], state)
+
+	return nil
+}
+
+// hireDone tells the player the outcome of a hire action, with the item the player got back
+func (w *world) hireDone(player *worldPlayer, action d2netpacket.HireAction, id string, slot d2enum.EquippedSlot,
+	item []byte) error {
+	packet, err := d2netpacket.CreateHireDonePacket(player.ID(), action, id, slot, item,
+		player.client.GetPlayerState().Gold)
+	if err != nil {
+		return err
+	}
+
+	w.sendTo(packet, player.ID())
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package d2server
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+const (
+	testHirelingClass = 271
+	testItemID        = "helm"
+)
+
+// hireTestWorld returns a world on the open grid with a player whose hero has a hireling and a
+// cap, which the hireling can wear on its head
+func hireTestWorld(t *testing.T) (*world, *testClient) {
+	t.Helper()
+
+	asset := testAsset()
+	asset.Records.Monster.Stats = d2records.MonStats{
+		"roguehire": {Key: "roguehire", ID: testHirelingClass},
+	}
+	asset.Records.Hireling.Details = d2records.Hirelings{
+		{Hireling: "Rogue Scout", Class: testHirelingClass, Act: 1, Difficulty: 1, Level: 3, HP: 45, WType1: "bow", Head: 1,
+			Torso: 1, Weapon: 1},
+	}
+	asset.Records.Item.Equivalency = d2records.ItemEquivalenceMap{
+		"helm": {asset.Records.Item.Armors["cap"]},
+		"shie": {asset.Records.Item.Armors["buc"]},
+	}
+
+	w := testWorld(t, asset, openGrid)
+	client := newTestClient("player")
+	client.state.Hireling = &d2hero.HirelingState{Seed: 1, Level: 3}
+
+	w.addPlayer(client, 2, 2)
+
+	player := w.players[client.id]
+
+	for id, code := range map[string]string{testItemID: "cap", "shield": "buc"} {
+		item, err := w.itemFactory.NewItem(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.giveItem(player, id, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return w, client
+}
+
+func hirePacket(t *testing.T, client *testClient, action d2netpacket.HireAction, id string,
+	slot d2enum.EquippedSlot) d2netpacket.NetPacket {
+	t.Helper()
+
+	packet, err := d2netpacket.CreateHirePacket(client.id, "", 0, 0, action, id, slot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return packet
+}
+
+func TestEquipHirelingTakesTheItemOfTheHero(t *testing.T) {
+	table := []struct {
+		name  string
+		id    string
+		slot  d2enum.EquippedSlot
+		wears bool
+	}{
+		{"item of the hero", testItemID, d2enum.EquippedSlotHead, true},
+		{"item the hero does not have", "unknown", d2enum.EquippedSlotHead, false},
+		{"item the hireling cannot wear", "shield", d2enum.EquippedSlotLeftArm, false},
+		{"item in the wrong slot", testItemID, d2enum.EquippedSlotTorso, false},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			w, client := hireTestWorld(t)
+			items := len(client.state.Items)
+
+			w.queue(client, hirePacket(t, client, d2netpacket.HireEquip, row.id, row.slot))
+			w.advance(testTick)
+
+			_, wears := client.state.Hireling.Equipment[row.slot]
+			if wears != row.wears {
+				t.Fatalf("hireling wears an item in slot %d = %v, want %v", row.slot, wears, row.wears)
+			}
+
+			_, kept := client.state.Items[row.id]
+			if row.wears && (kept || len(client.state.Items) != items-1) {
+				t.Error("the hero keeps the item the hireling wears")
+			}
+
+			if !row.wears && len(client.state.Items) != items {
+				t.Errorf("the hero has %d items, want %d", len(client.state.Items), items)
+			}
+
+			if done := client.received(d2netpackettype.Hire); (len(done) == 1) != row.wears {
+				t.Errorf("%d hire outcomes were sent, want the outcome = %v", len(done), row.wears)
+			}
+		})
+	}
+}
+
+func TestUnequipHirelingGivesTheItemBack(t *testing.T) {
+	w, client := hireTestWorld(t)
+
+	w.queue(client, hirePacket(t, client, d2netpacket.HireEquip, testItemID, d2enum.EquippedSlotHead))
+	w.queue(client, hirePacket(t, client, d2netpacket.HireUnequip, "", d2enum.EquippedSlotHead))
+	w.advance(testTick)
+
+	if _, wears := client.state.Hireling.Equipment[d2enum.EquippedSlotHead]; wears {
+		t.Fatal("hireling still wears the item")
+	}
+
+	if len(client.state.Items) != 2 {
+		t.Fatalf("the hero has %d items, want 2", len(client.state.Items))
+	}
+
+	found := false
+
+	for _, item := range client.state.Items {
+		found = found || item.Code == "cap"
+	}
+
+	if !found {
+		t.Error("the hero did not get the cap back")
+	}
+}
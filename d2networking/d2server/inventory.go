@@ -0,0 +1,67 @@
+package d2server
+
+import (
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+)
+
+// The items of a player are the items of its hero, which are saved with the hero. They are
+// kept in the saved item format, so the worn items keep their body location, and made into
+// items when a trade needs their prices.
+
+// ownedItem returns the item of the player with the given ID, or nil if the hero of the player
+// has no such item
+func (w *world) ownedItem(player *worldPlayer, id string) *diablo2item.Item {
+	saved, found := player.client.GetPlayerState().Items[id]
+	if !found {
+		return nil
+	}
+
+	item, err := w.itemFactory.ItemFromSave(saved)
+	if err != nil {
+		w.Errorf("item %s of %s cannot be loaded: %v", id, player.ID(), err)
+		return nil
+	}
+
+	return item
+}
+
+// ownedItemIDs returns the IDs of the items of the player, sorted
+func ownedItemIDs(player *worldPlayer) []string {
+	items := player.client.GetPlayerState().Items
+	ids := make([]string, 0, len(items))
+
+	for id := range items {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// giveItem puts the item in the inventory of the hero of the player by the given ID
+func (w *world) giveItem(player *worldPlayer, id string, item *diablo2item.Item) error {
+	saved, err := item.SaveItem()
+	if err != nil {
+		return err
+	}
+
+	player.client.GetPlayerState().GiveItem(id, saved)
+
+	return nil
+}
+
+// takeItem takes the item with the given ID from the hero of the player, it returns false if the
+// hero has no such item
+func (w *world) takeItem(player *worldPlayer, id string) bool {
+	return player.client.GetPlayerState().TakeItem(id) != nil
+}
+
+// repairItem restores the durability of the item of the player with the given ID
+func (w *world) repairItem(player *worldPlayer, id string) {
+	if saved, found := player.client.GetPlayerState().Items[id]; found {
+		saved.Durability = saved.MaxDurability
+	}
+}
@@ -0,0 +1,548 @@
+package d2server
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2automap"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapgen"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats/diablo2stats"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+const (
+	// levelUnloadTime is how long, in seconds, a level stays loaded after its last player left
+	levelUnloadTime = 30
+
+	// arrivalDistance is how far, in tiles, from a warp or a waypoint the players arrive
+	arrivalDistance = 3.0
+	arrivalTries    = 8 // directions tried around a warp or a waypoint
+
+	// fallbackLevelID is the first town, the players join the game there when the town of their
+	// act cannot be generated
+	fallbackLevelID = 1
+)
+
+// townLevelIDs are the levels.txt IDs of the towns of the acts, the players join the game in the
+// town of their act
+var townLevelIDs = map[int]int{1: 1, 2: 40, 3: 75, 4: 103, 5: 109} //nolint:gochecknoglobals,gomnd // levels.txt IDs
+
+// arrival is a player entering a level
+type arrival struct {
+	client   ClientConnection
+	levelID  int
+	from     *world // the level the player leaves, nil when the player joins the game
+	waypoint bool
+}
+
+// clientPacket is a packet to send to a single client
+type clientPacket struct {
+	client ClientConnection
+	packet d2netpacket.NetPacket
+}
+
+// levelManager loads the level a player enters when it is not loaded yet and unloads the levels
+// which have been empty for a while. Each loaded level is simulated by a world of its own, the
+// players of different difficulties play in different worlds of the same level.
+type levelManager struct {
+	sync.Mutex
+	asset    *d2asset.AssetManager
+	seed     int64
+	logLevel d2util.LogLevel
+	worlds   []*world
+	players  map[string]*world // the level of each player
+	arrivals []arrival
+	generate func(levelID int) (*d2mapengine.MapEngine, []int, error) // generates the map of a level
+
+	*d2util.Logger
+}
+
+func newLevelManager(asset *d2asset.AssetManager, seed int64, logLevel d2util.LogLevel,
+	logger *d2util.Logger) *levelManager {
+	m := &levelManager{
+		asset:    asset,
+		seed:     seed,
+		logLevel: logLevel,
+		worlds:   make([]*world, 0),
+		players:  make(map[string]*world),
+		Logger:   logger,
+	}
+
+	m.generate = m.generateMap
+
+	return m
+}
+
+// runLevels is meant to be started as a goroutine, it advances the levels at a fixed tick rate.
+func (g *GameServer) runLevels() {
+	ticker := time.NewTicker(worldTickTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case <-ticker.C:
+			g.levels.advance(worldTickTime.Seconds())
+		}
+	}
+}
+
+// join makes the player of the client enter the town of its act on the next tick
+func (m *levelManager) join(client ClientConnection) {
+	levelID, found := townLevelIDs[client.GetPlayerState().Act]
+	if !found {
+		levelID = fallbackLevelID
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.arrivals = append(m.arrivals, arrival{client: client, levelID: levelID})
+}
+
+// leave removes the player with the given ID from its level
+func (m *levelManager) leave(id string) {
+	m.Lock()
+
+	level := m.players[id]
+	delete(m.players, id)
+
+	arrivals := m.arrivals[:0]
+
+	for idx := range m.arrivals {
+		if m.arrivals[idx].client.GetUniqueID() != id {
+			arrivals = append(arrivals, m.arrivals[idx])
+		}
+	}
+
+	m.arrivals = arrivals
+
+	m.Unlock()
+
+	if level != nil {
+		level.removePlayer(id)
+	}
+}
+
+// queue passes a command of a client to the level of its player
+func (m *levelManager) queue(client ClientConnection, packet d2netpacket.NetPacket) {
+	m.Lock()
+	level := m.players[client.GetUniqueID()]
+	m.Unlock()
+
+	if level != nil {
+		level.queue(client, packet)
+	}
+}
+
+// mapEngines returns the map engines of the loaded levels
+func (m *levelManager) mapEngines() []*d2mapengine.MapEngine {
+	m.Lock()
+	defer m.Unlock()
+
+	engines := make([]*d2mapengine.MapEngine, len(m.worlds))
+	for idx := range m.worlds {
+		engines[idx] = m.worlds[idx].mapEngine
+	}
+
+	return engines
+}
+
+// advance processes a single tick of every level, then moves the players which took a warp or a
+// waypoint to their new level and unloads the levels which have been empty for long enough
+func (m *levelManager) advance(tickTime float64) {
+	m.Lock()
+	worlds := append([]*world{}, m.worlds...)
+	m.Unlock()
+
+	departures := make([]arrival, 0)
+	for _, level := range worlds {
+		departures = append(departures, level.advance(tickTime)...)
+	}
+
+	m.Lock()
+
+	arrivals := append(m.arrivals, departures...)
+	m.arrivals = nil
+
+	outbox := make([]clientPacket, 0)
+	for idx := range arrivals {
+		outbox = m.enter(&arrivals[idx], outbox)
+	}
+
+	m.unloadIdleLevels()
+
+	m.Unlock()
+
+	for idx := range outbox {
+		out := &outbox[idx]
+
+		if err := out.client.SendPacketToClient(out.packet); err != nil {
+			m.Errorf("GameServer: error sending packet: %s to client %s: %s", out.packet.PacketType, out.client.GetUniqueID(), err)
+		}
+	}
+}
+
+// enter moves the player of the arrival to its new level and appends the packets which tell the
+// clients about it to the outbox. The player stays where it is if the level cannot be loaded.
+func (m *levelManager) enter(entering *arrival, outbox []clientPacket) []clientPacket {
+	id := entering.client.GetUniqueID()
+
+	if entering.from != nil && m.players[id] != entering.from {
+		return outbox // the player disconnected before it left its level
+	}
+
+	difficulty := entering.client.GetPlayerState().Difficulty
+
+	level, err := m.load(entering.levelID, difficulty)
+	if err != nil && entering.from == nil && entering.levelID != fallbackLevelID {
+		m.Warningf("Player %s cannot join in level %d: %v", id, entering.levelID, err)
+		level, err = m.load(fallbackLevelID, difficulty)
+	}
+
+	if err != nil {
+		m.Errorf("Player %s cannot enter level %d: %v", id, entering.levelID, err)
+		return outbox
+	}
+
+	if entering.from != nil {
+		entering.from.removePlayer(id)
+
+		left, err := d2netpacket.CreateWarpPlayerPacket(id, level.levelID, entering.waypoint)
+		if err != nil {
+			m.Errorf("WarpPlayerPacket: %v", err)
+		}
+
+		for _, client := range entering.from.clients() {
+			outbox = append(outbox, clientPacket{client: client, packet: left})
+		}
+	}
+
+	x, y := level.arrivalPosition(entering)
+
+	playerState := entering.client.GetPlayerState()
+	playerState.X, playerState.Y = x, y
+
+	if details := m.asset.Records.Level.Details[level.levelID]; details != nil {
+		playerState.Act = details.Act + 1
+	}
+
+	outbox = append(outbox, m.levelPackets(level, entering.client, x, y)...)
+
+	if entering.from == nil {
+		inventory, err := m.inventoryPacket(entering.client)
+		if err != nil {
+			m.Errorf("InventoryPacket: %v", err)
+		}
+
+		outbox = append(outbox, clientPacket{client: entering.client, packet: inventory})
+	}
+
+	level.addPlayer(entering.client, toSubTile(x), toSubTile(y))
+	m.players[id] = level
+
+	return outbox
+}
+
+// load returns the world of the given level and difficulty, the level is generated with monsters
+// of the difficulty if it is not loaded yet
+func (m *levelManager) load(levelID int, difficulty d2enum.DifficultyType) (*world, error) {
+	for _, level := range m.worlds {
+		if level.containsLevel(levelID) && level.difficulty == difficulty {
+			return level, nil
+		}
+	}
+
+	mapEngine, levelIDs, err := m.generate(levelID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := diablo2stats.NewStatFactory(m.asset)
+	if err != nil {
+		return nil, err
+	}
+
+	level := newWorld(levelIDs[0], mapEngine, m.asset.Records, stats, difficulty, m.seed+int64(levelIDs[0]), m.Logger)
+	m.worlds = append(m.worlds, level)
+
+	m.Infof("Loaded level %d of difficulty %d", levelIDs[0], difficulty)
+
+	return level, nil
+}
+
+// generateMap generates the map of the given level and returns it with the IDs of the levels on it
+func (m *levelManager) generateMap(levelID int) (*d2mapengine.MapEngine, []int, error) {
+	mapEngine := d2mapengine.CreateMapEngine(m.logLevel, m.asset)
+	mapEngine.SetSeed(m.seed)
+
+	mapGen, err := d2mapgen.NewMapGenerator(m.asset, m.logLevel, mapEngine)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	levelIDs, err := mapGen.GenerateLevel(levelID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mapEngine, levelIDs, nil
+}
+
+// unloadIdleLevels unloads the levels which have been empty for long enough
+func (m *levelManager) unloadIdleLevels() {
+	worlds := m.worlds[:0]
+
+	for _, level := range m.worlds {
+		if level.idle() {
+			m.Infof("Unloaded level %d", level.levelID)
+			continue
+		}
+
+		worlds = append(worlds, level)
+	}
+
+	m.worlds = worlds
+}
+
+// levelPackets returns the packets which make a client enter a level: the map of the level with
+// the tiles the player has explored, its items, operated objects and monsters, its player on every
+// client of the level and the other players of the level with their hirelings on the client
+func (m *levelManager) levelPackets(level *world, client ClientConnection, x, y float64) []clientPacket {
+	packets := make([]clientPacket, 0)
+
+	regionType := d2enum.RegionNone
+	if details := m.asset.Records.Level.Details[level.levelID]; details != nil {
+		regionType = d2enum.RegionIdType(details.LevelType)
+	}
+
+	gmp, err := d2netpacket.CreateGenerateMapPacket(level.levelID, regionType)
+	if err != nil {
+		m.Errorf("GenerateMapPacket: %v", err)
+	}
+
+	packets = append(packets, clientPacket{client: client, packet: gmp})
+
+	explored := d2automap.ForHero(client.GetPlayerState(), level.mapEngine)
+
+	automap, err := d2netpacket.CreateAutomapPacket(d2automap.LevelKey(level.mapEngine.LevelIDs()), explored.Bytes())
+	if err != nil {
+		m.Errorf("AutomapPacket: %v", err)
+	}
+
+	packets = append(packets, clientPacket{client: client, packet: automap})
+
+	for _, ground := range level.groundPackets() {
+		packets = append(packets, clientPacket{client: client, packet: ground})
+	}
+
+	for _, object := range level.objectPackets(client.GetPlayerState()) {
+		packets = append(packets, clientPacket{client: client, packet: object})
+	}
+
+	for _, monster := range level.monsterPackets() {
+		packets = append(packets, clientPacket{client: client, packet: monster})
+	}
+
+	addPlayer, err := m.addPlayerPacket(client, x, y)
+	if err != nil {
+		m.Errorf("AddPlayerPacket: %v", err)
+	}
+
+	packets = append(packets, clientPacket{client: client, packet: addPlayer})
+
+	for _, other := range level.clients() {
+		packets = append(packets, clientPacket{client: other, packet: addPlayer})
+
+		otherState := other.GetPlayerState()
+
+		addOther, err := m.addPlayerPacket(other, otherState.X, otherState.Y)
+		if err != nil {
+			m.Errorf("AddPlayerPacket: %v", err)
+		}
+
+		packets = append(packets, clientPacket{client: client, packet: addOther})
+	}
+
+	for _, hireling := range level.hirelingPackets() {
+		packets = append(packets, clientPacket{client: client, packet: hireling})
+	}
+
+	for _, state := range level.statePackets() {
+		packets = append(packets, clientPacket{client: client, packet: state})
+	}
+
+	return packets
+}
+
+// addPlayerPacket returns the AddPlayer packet of the player of the client at the given position
+func (m *levelManager) addPlayerPacket(client ClientConnection, x, y float64) (d2netpacket.NetPacket, error) {
+	playerState := client.GetPlayerState()
+
+	d2hero.HydrateSkills(playerState.Skills, m.asset)
+
+	return d2netpacket.CreateAddPlayerPacket(
+		client.GetUniqueID(),
+		playerState.HeroName,
+		toSubTile(x),
+		toSubTile(y),
+		playerState.HeroType,
+		playerState.Stats,
+		playerState.Skills,
+		playerState.Equipment,
+		playerState.LeftSkill,
+		playerState.RightSkill,
+		playerState.Gold,
+	)
+}
+
+// inventoryPacket returns the Inventory packet with the items of the hero of the client
+func (m *levelManager) inventoryPacket(client ClientConnection) (d2netpacket.NetPacket, error) {
+	items := client.GetPlayerState().Items
+	owned := make([]d2netpacket.OwnedItem, 0, len(items))
+
+	for id, item := range items {
+		data, err := item.Marshal(m.asset.Records.SaveRecords())
+		if err != nil {
+			return d2netpacket.NetPacket{}, err
+		}
+
+		owned = append(owned, d2netpacket.OwnedItem{ID: id, Item: data})
+	}
+
+	sort.Slice(owned, func(i, j int) bool { return owned[i].ID < owned[j].ID })
+
+	return d2netpacket.CreateInventoryPacket(client.GetUniqueID(), owned)
+}
+
+// idle returns true if the world has been empty for long enough to be unloaded
+func (w *world) idle() bool {
+	w.Lock()
+	defer w.Unlock()
+
+	return len(w.players) == 0 && w.emptyTime >= levelUnloadTime
+}
+
+// warpPlayer checks that the player can take the warp or the waypoint to the level of the packet,
+// the player leaves the world at the end of the tick
+func (w *world) warpPlayer(player *worldPlayer, warp *d2netpacket.WarpPlayerPacket) {
+	if w.containsLevel(warp.LevelID) {
+		return // the player is already there
+	}
+
+	position := player.Position.World()
+
+	var allowed bool
+
+	if warp.Waypoint {
+		// using a waypoint activates it
+		if w.nearWaypoint(position) {
+			w.activateWaypoint(player)
+		}
+
+		allowed = w.canUseWaypoint(player, warp.LevelID)
+	} else {
+		allowed = w.nearWarp(position, warp.LevelID)
+	}
+
+	if !allowed {
+		w.Debugf("%s cannot warp to level %d", player.ID(), warp.LevelID)
+		return
+	}
+
+	player.StopMoving()
+
+	w.departures = append(w.departures, arrival{
+		client:   player.client,
+		levelID:  warp.LevelID,
+		from:     w,
+		waypoint: warp.Waypoint,
+	})
+}
+
+// nearWarp returns true if the given world position is close to a warp to the given level
+func (w *world) nearWarp(position *d2vector.Vector, levelID int) bool {
+	for idx := range w.warps {
+		warp := &w.warps[idx]
+
+		if warp.LevelID == levelID && position.Distance(d2vector.NewVector(warp.X, warp.Y)) <= warpReach {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canUseWaypoint returns true if the player can use a waypoint to reach the given level: the
+// player stands at a waypoint and has activated the waypoint of the level
+func (w *world) canUseWaypoint(player *worldPlayer, levelID int) bool {
+	target := w.records.Level.Details[levelID]
+	if target == nil || !target.HasWaypoint() || w.waypointLevel() == 0 {
+		return false
+	}
+
+	return w.nearWaypoint(player.Position.World()) && player.client.GetPlayerState().HasWaypoint(levelID)
+}
+
+// nearWaypoint returns true if the given world position is close to a waypoint of the map, the
+// players cannot use the waypoint of a map without waypoint objects
+func (w *world) nearWaypoint(position *d2vector.Vector) bool {
+	for idx := range w.waypoints {
+		if position.Distance(w.waypoints[idx].World()) <= waypointReach {
+			return true
+		}
+	}
+
+	return false
+}
+
+// arrivalPosition returns the world position where the player of the arrival enters the world:
+// next to the warp back to the level it came from, next to a waypoint or at the start of the map
+func (w *world) arrivalPosition(entering *arrival) (x, y float64) {
+	w.Lock()
+	defer w.Unlock()
+
+	switch {
+	case entering.from == nil:
+		break
+	case entering.waypoint && len(w.waypoints) > 0:
+		position := w.waypoints[0].World()
+		return w.walkableNear(position.X(), position.Y())
+	case !entering.waypoint:
+		for idx := range w.warps {
+			if entering.from.containsLevel(w.warps[idx].LevelID) {
+				return w.walkableNear(w.warps[idx].X, w.warps[idx].Y)
+			}
+		}
+	}
+
+	return w.mapEngine.GetStartPosition()
+}
+
+// walkableNear returns a walkable world position a few tiles from the given one, so the players
+// do not arrive on top of a warp or a waypoint
+func (w *world) walkableNear(x, y float64) (nearX, nearY float64) {
+	for idx := 0; idx < arrivalTries; idx++ {
+		angle := 2 * math.Pi * float64(idx) / arrivalTries
+		nearX, nearY = x+arrivalDistance*math.Cos(angle), y+arrivalDistance*math.Sin(angle)
+
+		if !w.inBounds(nearX, nearY) {
+			continue
+		}
+
+		if !w.mapEngine.SubTileAt(int(nearX*subtilesPerTile), int(nearY*subtilesPerTile)).BlockWalk {
+			return nearX, nearY
+		}
+	}
+
+	return x, y
+}
@@ -0,0 +1,189 @@
+package d2server
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+const testNextLevelID = 2
+
+// testLevelManager returns a level manager which generates every level on the open grid, and
+// counts the levels it generates
+func testLevelManager(t *testing.T) (m *levelManager, generated *int) {
+	t.Helper()
+
+	logger := d2util.NewLogger()
+	logger.SetLevel(d2util.LogLevelNone)
+
+	asset := testAsset()
+	m = newLevelManager(asset, testSeed, d2util.LogLevelNone, logger)
+	generated = new(int)
+
+	m.generate = func(levelID int) (*d2mapengine.MapEngine, []int, error) {
+		*generated++
+
+		mapEngine := testMapEngine(t, asset, openGrid)
+		mapEngine.SetLevelIDs([]int{levelID})
+
+		return mapEngine, []int{levelID}, nil
+	}
+
+	return m, generated
+}
+
+func TestLevelsAreLoadedWhenAPlayerEntersThem(t *testing.T) {
+	m, generated := testLevelManager(t)
+	first, second := newTestClient("first"), newTestClient("second")
+
+	m.join(first)
+
+	if len(m.worlds) != 0 || *generated != 0 {
+		t.Fatal("a level was loaded before the player entered it")
+	}
+
+	m.advance(testTick)
+
+	level := m.players[first.id]
+	if level == nil || !level.containsLevel(townLevelIDs[1]) {
+		t.Fatalf("player did not enter the town of its act")
+	}
+
+	if maps := first.received(d2netpackettype.GenerateMap); len(maps) != 1 {
+		t.Errorf("player received %d maps, want 1", len(maps))
+	}
+
+	m.join(second)
+	m.advance(testTick)
+
+	if m.players[second.id] != level || *generated != 1 {
+		t.Errorf("the town was generated %d times, want the players to share it", *generated)
+	}
+}
+
+func TestLevelsAreLoadedForEachDifficulty(t *testing.T) {
+	m, generated := testLevelManager(t)
+	normal, nightmare := newTestClient("normal"), newTestClient("nightmare")
+	nightmare.state.Difficulty = d2enum.DifficultyNightmare
+
+	m.join(normal)
+	m.join(nightmare)
+	m.advance(testTick)
+
+	if *generated != 2 || m.players[normal.id] == m.players[nightmare.id] {
+		t.Fatal("players of different difficulties share a level")
+	}
+
+	if difficulty := m.players[nightmare.id].difficulty; difficulty != d2enum.DifficultyNightmare {
+		t.Errorf("level of the nightmare player has difficulty %d", difficulty)
+	}
+}
+
+func TestWarpMovesThePlayerToTheOtherLevel(t *testing.T) {
+	m, generated := testLevelManager(t)
+	client, watcher := newTestClient("warper"), newTestClient("watcher")
+
+	m.join(client)
+	m.join(watcher)
+	m.advance(testTick)
+
+	town := m.players[client.id]
+	state := client.GetPlayerState()
+	town.warps = []d2mapengine.Warp{{X: state.X, Y: state.Y, LevelID: testNextLevelID}}
+
+	warp, err := d2netpacket.CreateWarpPlayerPacket(client.id, testNextLevelID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.queue(client, warp)
+	m.advance(testTick)
+
+	level := m.players[client.id]
+	if level == town || !level.containsLevel(testNextLevelID) || *generated != 2 {
+		t.Fatal("player did not enter the level of the warp")
+	}
+
+	if _, found := town.players[client.id]; found {
+		t.Error("player is still in the town")
+	}
+
+	if warps := watcher.received(d2netpackettype.WarpPlayer); len(warps) != 1 {
+		t.Errorf("the players of the town received %d warps, want 1", len(warps))
+	}
+
+	if maps := client.received(d2netpackettype.GenerateMap); len(maps) != 2 {
+		t.Errorf("player received %d maps, want the map of the town and of the other level", len(maps))
+	}
+}
+
+func TestPlayerUsesAWaypointOnlyNextToIt(t *testing.T) {
+	table := []struct {
+		name      string
+		waypoints []d2vector.Position
+		near      bool
+	}{
+		{"map without waypoints", nil, false},
+		{"next to the waypoint", []d2vector.Position{d2vector.NewPositionTile(1, 1)}, true},
+		{"far from the waypoint", []d2vector.Position{d2vector.NewPositionTile(3.5, 3.5)}, false},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			asset := testAsset()
+			asset.Records.Level.Details[testLevelID] = &d2records.LevelDetailRecord{ID: testLevelID, WaypointID: 0}
+			asset.Records.Level.Details[testNextLevelID] = &d2records.LevelDetailRecord{ID: testNextLevelID, WaypointID: 1}
+
+			w := testWorld(t, asset, openGrid)
+			w.waypoints = row.waypoints
+
+			client := newTestClient("traveler")
+			client.state.Waypoints = []int{testLevelID, testNextLevelID}
+			w.addPlayer(client, 2, 2)
+
+			player := w.players[client.id]
+
+			if near := w.nearWaypoint(player.Position.World()); near != row.near {
+				t.Errorf("player is near a waypoint = %v, want %v", near, row.near)
+			}
+
+			if allowed := w.canUseWaypoint(player, testNextLevelID); allowed != row.near {
+				t.Errorf("player can use the waypoint = %v, want %v", allowed, row.near)
+			}
+		})
+	}
+}
+
+func TestIdleLevelsAreUnloaded(t *testing.T) {
+	m, generated := testLevelManager(t)
+	client := newTestClient("player")
+
+	m.join(client)
+	m.advance(testTick)
+	m.leave(client.id)
+
+	m.advance(levelUnloadTime / 2)
+
+	if len(m.worlds) != 1 {
+		t.Fatal("level was unloaded right after its last player left")
+	}
+
+	m.advance(levelUnloadTime / 2)
+
+	if len(m.worlds) != 0 {
+		t.Fatal("idle level was not unloaded")
+	}
+
+	m.join(client)
+	m.advance(testTick)
+
+	if len(m.worlds) != 1 || *generated != 2 {
+		t.Error("unloaded level was not loaded again")
+	}
+}
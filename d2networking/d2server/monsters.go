@@ -0,0 +1,416 @@
+package d2server
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2ai"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+const (
+	monsterIDFmt = "monster-%d"
+
+	monsterAttackTime = 1.0 // seconds between two attacks of a monster
+
+	// repathDistance is how far, in tiles, the destination of a monster has to move before the
+	// path of the monster is found again
+	repathDistance = 1.0
+
+	minionSpread = 2 // how far, in sub tiles, from their leader the minions are spawned
+	packSpread   = 4 // how far, in sub tiles, from the middle of their tile the monsters of a pack are spawned
+
+	// monsterDensityScale is what the monster densities of levels.txt are chances out of
+	monsterDensityScale = 100000
+)
+
+// worldMonster is a monster simulated by the server, its decisions are made by the AI controller
+// of the world
+type worldMonster struct {
+	*d2mapentity.HeadlessEntity
+	ai         *d2ai.Monster
+	record     *d2records.MonStatRecord
+	stats      d2stats.StatList // the combat stats, made when the monster first fights
+	states     *d2states.States
+	attackTime float64 // seconds until the monster can attack again
+	changed    bool    // true if the state has to be sent in the next state delta
+}
+
+// worldTerrain lets the AI see the map of a world
+type worldTerrain struct {
+	mapEngine *d2mapengine.MapEngine
+}
+
+// Walkable returns true if the sub tile at the given world position does not block walking
+func (t worldTerrain) Walkable(x, y float64) bool {
+	return t.mapEngine.Walkable(d2vector.NewPositionTile(x, y))
+}
+
+// LineOfSight returns true if no sub tile between the given world positions blocks the sight
+func (t worldTerrain) LineOfSight(fromX, fromY, toX, toY float64) bool {
+	return t.mapEngine.LineOfSight(d2vector.NewPositionTile(fromX, fromY), d2vector.NewPositionTile(toX, toY))
+}
+
+// adoptMonsters replaces the hostile monsters of the map stamps with monsters simulated by the
+// world. They are numbered in the order of their positions, so every server gives the monsters of
+// a map the same IDs.
+func (w *world) adoptMonsters() {
+	npcs := make([]*d2mapentity.NPC, 0)
+
+	for _, entity := range w.mapEngine.Entities() {
+		if npc, ok := entity.(*d2mapentity.NPC); ok && npc.MonStatRecord() != nil && npc.MonStatRecord().IsHostile() {
+			npcs = append(npcs, npc)
+		}
+	}
+
+	sort.Slice(npcs, func(i, j int) bool {
+		a, b := npcs[i].GetPosition(), npcs[j].GetPosition()
+
+		if a.Y() != b.Y() {
+			return a.Y() < b.Y()
+		}
+
+		return a.X() < b.X()
+	})
+
+	for _, npc := range npcs {
+		position := npc.GetPosition()
+
+		w.mapEngine.RemoveEntity(npc)
+		w.addMonster(npc.MonStatRecord(), position.X(), position.Y(), "")
+	}
+}
+
+// addMonster adds a monster with the given record at the given sub tile position, the monster
+// follows the given leader
+func (w *world) addMonster(record *d2records.MonStatRecord, subX, subY float64, leaderID string) *worldMonster {
+	w.nextMonsterID++
+
+	id := fmt.Sprintf(monsterIDFmt, w.nextMonsterID)
+	position := d2vector.NewPosition(subX, subY)
+	tile := position.World()
+
+	monster := &worldMonster{
+		HeadlessEntity: d2mapentity.NewHeadlessEntity(id, position),
+		ai:             d2ai.NewMonster(id, record, w.records.Monster.Stats2[record.ExtraDataKey], w.difficulty, tile.X(), tile.Y()),
+		record:         record,
+		states:         d2states.NewStates(),
+	}
+
+	if minLife, maxLife := record.LifeRange(w.difficulty); maxLife > minLife {
+		life := float64(minLife + w.rand.Intn(maxLife-minLife+1))
+		monster.ai.Life, monster.ai.MaxLife = life, life
+	}
+
+	monster.ai.LeaderID = leaderID
+	monster.SetSpeed(float64(record.SpeedBase))
+
+	w.monsters[id] = monster
+	w.ai.Add(monster.ai)
+	w.mapEngine.AddEntity(monster)
+
+	return monster
+}
+
+// addParty adds a monster with the given record at the given sub tile position together with its
+// minions around it
+func (w *world) addParty(record *d2records.MonStatRecord, subX, subY float64) []*worldMonster {
+	leader := w.addMonster(record, subX, subY, "")
+	party := []*worldMonster{leader}
+
+	for _, minionID := range []string{record.MinionId1, record.MinionId2} {
+		minion := w.records.Monster.Stats[minionID]
+		if minion == nil {
+			continue
+		}
+
+		count := record.MinionPartyMin
+		if record.MinionPartyMax > count {
+			count += w.rand.Intn(record.MinionPartyMax - count + 1)
+		}
+
+		for idx := 0; idx < count; idx++ {
+			x := subX + float64(w.rand.Intn(2*minionSpread+1)-minionSpread)
+			y := subY + float64(w.rand.Intn(2*minionSpread+1)-minionSpread)
+
+			party = append(party, w.addMonster(minion, x, y, leader.ID()))
+		}
+	}
+
+	return party
+}
+
+// populate spawns the random monsters of the levels in the spawn zones of the map. A pack of
+// monsters spawns on a tile of a zone with the monster density of levels.txt as its chance, the
+// monsters of a pack are one of the monsters of the level.
+func (w *world) populate() {
+	for _, zone := range w.mapEngine.SpawnZones() {
+		details := w.records.Level.Details[zone.LevelID]
+		if details == nil {
+			continue
+		}
+
+		monsters := details.Monsters(w.difficulty)
+		density := details.MonsterDensity(w.difficulty)
+
+		if len(monsters) == 0 || density <= 0 {
+			continue
+		}
+
+		for tileY := zone.Area.Top; tileY < zone.Area.Bottom(); tileY++ {
+			for tileX := zone.Area.Left; tileX < zone.Area.Right(); tileX++ {
+				if w.rand.Intn(monsterDensityScale) >= density {
+					continue
+				}
+
+				record := w.records.Monster.Stats[monsters[w.rand.Intn(len(monsters))]]
+				if record == nil || !record.IsHostile() {
+					continue
+				}
+
+				w.addPack(record, float64(tileX*subtilesPerTile+subtilesPerTile/2), float64(tileY*subtilesPerTile+subtilesPerTile/2))
+			}
+		}
+	}
+}
+
+// addPack adds a pack of the monsters with the given record around the given sub tile position,
+// the size of the pack is the MinGrp and MaxGrp of monstats.txt. The monsters only spawn where
+// they can walk.
+func (w *world) addPack(record *d2records.MonStatRecord, subX, subY float64) {
+	count := record.MinionGroupMin
+	if record.MinionGroupMax > count {
+		count += w.rand.Intn(record.MinionGroupMax - count + 1)
+	}
+
+	if count < 1 {
+		count = 1
+	}
+
+	for idx := 0; idx < count; idx++ {
+		x := subX + float64(w.rand.Intn(2*packSpread+1)-packSpread)
+		y := subY + float64(w.rand.Intn(2*packSpread+1)-packSpread)
+
+		if w.mapEngine.Walkable(d2vector.NewPosition(x, y)) {
+			w.addParty(record, x, y)
+		}
+	}
+}
+
+// spawnMonster puts the monster of the packet and its minions on the map, it is a debug command
+// of the host
+func (w *world) spawnMonster(spawn *d2netpacket.SpawnMonsterPacket) error {
+	record := w.records.Monster.Stats[spawn.Code]
+	if record == nil {
+		w.Debugf("unknown monster %q", spawn.Code)
+		return nil
+	}
+
+	if !w.inBounds(float64(spawn.X)/subtilesPerTile, float64(spawn.Y)/subtilesPerTile) {
+		return nil
+	}
+
+	spawned := w.addParty(record, float64(spawn.X), float64(spawn.Y))
+
+	for _, monster := range spawned {
+		packet, err := monsterPacket(monster)
+		if err != nil {
+			return err
+		}
+
+		w.send(packet, "")
+	}
+
+	return nil
+}
+
+// monsterPacket returns the packet which puts the monster on a client
+func monsterPacket(monster *worldMonster) (d2netpacket.NetPacket, error) {
+	return d2netpacket.CreateSpawnMonsterPacket(monster.ID(), monster.record.Key,
+		int(monster.Position.X()), int(monster.Position.Y()))
+}
+
+// monsterPackets returns the packets which show a client entering the world the monsters alive
+func (w *world) monsterPackets() []d2netpacket.NetPacket {
+	w.Lock()
+	defer w.Unlock()
+
+	packets := make([]d2netpacket.NetPacket, 0, len(w.monsters))
+
+	for _, monster := range w.sortedMonsters() {
+		packet, err := monsterPacket(monster)
+		if err != nil {
+			w.Errorf("SpawnMonsterPacket: %v", err)
+			continue
+		}
+
+		packets = append(packets, packet)
+	}
+
+	return packets
+}
+
+// advanceMonsters moves the monsters and carries out the decisions of their AI
+func (w *world) advanceMonsters(tickTime float64) {
+	for _, monster := range w.monsters {
+		monster.Advance(tickTime)
+		monster.attackTime = math.Max(monster.attackTime-tickTime, 0)
+
+		position := monster.Position.World()
+		monster.ai.X, monster.ai.Y = position.X(), position.Y()
+	}
+
+	// the monsters go after the players and their hirelings
+	targets := w.playerUnits()
+
+	for _, unit := range w.sortedHirelings() {
+		targets = append(targets, &unit.ai.Unit)
+	}
+
+	for _, decision := range w.ai.Advance(tickTime, targets) {
+		monster := w.monsters[decision.MonsterID]
+
+		switch decision.Type {
+		case d2ai.ActionMove:
+			if w.moveEntity(monster.HeadlessEntity, monster.record, decision.X, decision.Y, decision.Running) {
+				monster.changed = true
+			}
+		case d2ai.ActionAttack, d2ai.ActionShoot:
+			if err := w.monsterAttack(monster, &decision.Action); err != nil {
+				w.Errorf("attack of %s failed: %v", monster.ID(), err)
+			}
+		}
+	}
+}
+
+// playerUnits returns the players as units of the AI, ordered by ID
+func (w *world) playerUnits() []*d2ai.Unit {
+	units := make([]*d2ai.Unit, 0, len(w.players))
+
+	for _, player := range w.players {
+		position := player.Position.World()
+
+		units = append(units, &d2ai.Unit{
+			ID:      player.ID(),
+			X:       position.X(),
+			Y:       position.Y(),
+			Life:    player.life,
+			MaxLife: player.maxLife,
+		})
+	}
+
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].ID < units[j].ID
+	})
+
+	return units
+}
+
+// moveEntity moves a monster or a hireling with the speeds of the given record to the given world
+// position, it returns false if the entity keeps going where it goes
+func (w *world) moveEntity(entity *d2mapentity.HeadlessEntity, record *d2records.MonStatRecord, x, y float64,
+	running bool) bool {
+	dest := entity.Destination()
+	destWorld := dest.World()
+
+	if entity.IsMoving() && math.Hypot(destWorld.X()-x, destWorld.Y()-y) < repathDistance {
+		return false
+	}
+
+	path := w.mapEngine.PathFind(entity.Position, d2vector.NewPositionTile(x, y))
+	if len(path) == 0 {
+		return false
+	}
+
+	speed := record.SpeedBase
+	if running && record.SpeedRun > 0 {
+		speed = record.SpeedRun
+	}
+
+	entity.SetSpeed(float64(speed))
+	entity.SetPath(path, nil)
+
+	return true
+}
+
+// monsterAttack hits the target of the action in melee or shoots the missile of the monster at it
+func (w *world) monsterAttack(monster *worldMonster, action *d2ai.Action) error {
+	targetPosition, found := w.targetPosition(action.TargetID)
+	if !found || monster.attackTime > 0 {
+		return nil
+	}
+
+	monster.StopMoving()
+	monster.attackTime = monsterAttackTime
+	monster.changed = true
+
+	attack := d2combat.WeaponAttack(monster.record.AttackDamage(w.difficulty))
+	missileName := ""
+
+	if action.Type == d2ai.ActionShoot {
+		missile := w.records.GetMissileByName(monster.record.MissileA1)
+		if missile == nil {
+			return nil
+		}
+
+		if missile.Damage.MaxDamage > 0 {
+			attack = d2combat.MissileAttack(missile, w.monsterLevel(monster.record))
+		} else {
+			attack.Melee = false
+		}
+
+		missileName = missile.Name
+		w.spawnMissile(monster.ID(), monster.Position, missile, targetPosition, attack)
+	}
+
+	target := targetPosition.World()
+
+	packet, err := d2netpacket.CreateMonsterAttackPacket(monster.ID(), action.TargetID, missileName, target.X(), target.Y())
+	if err != nil {
+		return err
+	}
+
+	// the attack is sent before its outcome
+	w.send(packet, "")
+
+	if action.Type != d2ai.ActionShoot {
+		w.hit(monster.ID(), action.TargetID, attack)
+	}
+
+	return nil
+}
+
+// targetPosition returns the position of the player or hireling with the given ID
+func (w *world) targetPosition(id string) (d2vector.Position, bool) {
+	if player, found := w.players[id]; found {
+		return player.Position, true
+	}
+
+	if unit, found := w.hirelings[id]; found {
+		return unit.Position, true
+	}
+
+	return d2vector.Position{}, false
+}
+
+// sortedMonsters returns the monsters of the world ordered by ID
+func (w *world) sortedMonsters() []*worldMonster {
+	monsters := make([]*worldMonster, 0, len(w.monsters))
+	for _, monster := range w.monsters {
+		monsters = append(monsters, monster)
+	}
+
+	sort.Slice(monsters, func(i, j int) bool {
+		return monsters[i].ID() < monsters[j].ID()
+	})
+
+	return monsters
+}
@@ -0,0 +1,326 @@
+package d2server
+
+import (
+	"math"
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2object"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+const percent = 100.0
+
+// worldObject is an object of the map the players can operate
+type worldObject struct {
+	*d2mapentity.Object
+	operation d2object.Operation
+	shrine    *d2records.ShrineRecord // the shrine of a shrine or the shrine a well works like
+	operated  bool                    // true if the object is open or used
+	resetTime float64                 // seconds until a used shrine or well can be used again
+}
+
+// adoptObjects finds the objects of the map the players can operate, in the order of their
+// positions, so every server picks the same shrines for a map from the same seed
+func (w *world) adoptObjects() {
+	areaLevel := 0
+	if details := w.records.Level.Details[w.levelID]; details != nil {
+		areaLevel = details.MonsterLevel(w.difficulty)
+	}
+
+	for _, entity := range w.mapEngine.Entities() {
+		object, ok := entity.(*d2mapentity.Object)
+		if !ok {
+			continue
+		}
+
+		if object.IsWaypoint() {
+			w.waypoints = append(w.waypoints, object.GetPosition())
+		}
+
+		operation := d2object.OperationOf(object.Record())
+		if object.IsContainer() {
+			operation = d2object.OperationContainer
+		}
+
+		if operation != d2object.OperationNone {
+			w.objects = append(w.objects, &worldObject{Object: object, operation: operation})
+		}
+	}
+
+	sort.Slice(w.objects, func(i, j int) bool {
+		a, b := w.objects[i].GetPosition(), w.objects[j].GetPosition()
+		if a.Y() != b.Y() {
+			return a.Y() < b.Y()
+		}
+
+		return a.X() < b.X()
+	})
+
+	for _, object := range w.objects {
+		switch object.operation {
+		case d2object.OperationShrine:
+			object.shrine = d2object.PickShrine(w.rand, w.records.Object.Shrines, areaLevel)
+		case d2object.OperationWell:
+			object.shrine = d2object.WellShrine(w.records.Object.Shrines)
+		}
+	}
+}
+
+// objectNear returns the object closest to the given world position within the reach of a
+// player, or nil if there is none
+func (w *world) objectNear(position *d2vector.Vector) *worldObject {
+	var closest *worldObject
+
+	distance := operateReach
+
+	for _, object := range w.objects {
+		objectPosition := object.GetPosition()
+
+		if d := objectPosition.World().Distance(position); d <= distance {
+			closest, distance = object, d
+		}
+	}
+
+	return closest
+}
+
+// operateObject operates the object at the position of the packet for the player
+func (w *world) operateObject(player *worldPlayer, operate *d2netpacket.OperateObjectPacket) error {
+	target := d2vector.NewVector(operate.X, operate.Y)
+	position := player.Position.World()
+
+	if position.Distance(target) > operateReach {
+		w.Debugf("%s is too far away to operate the object at (%.1f, %.1f)", player.ID(), operate.X, operate.Y)
+		return nil
+	}
+
+	object := w.objectNear(target)
+	if object == nil {
+		return nil
+	}
+
+	switch object.operation {
+	case d2object.OperationContainer, d2object.OperationBarrel:
+		return w.openContainer(player, object)
+	case d2object.OperationDoor:
+		return w.toggleDoor(player, object)
+	case d2object.OperationShrine, d2object.OperationWell:
+		return w.useShrine(player, object)
+	case d2object.OperationWaypoint:
+		w.activateWaypoint(player)
+	case d2object.OperationPortal, d2object.OperationSwitch:
+		if !object.operated {
+			return w.setObjectMode(object, d2object.OperatedMode(object.Record()), player.ID(), true)
+		}
+	}
+
+	return nil
+}
+
+// setObjectMode plays the given mode of the object on the server and on the clients
+func (w *world) setObjectMode(object *worldObject, mode d2enum.ObjectAnimationMode, playerID string, operated bool) error {
+	if err := w.mapEngine.SetObjectMode(object.Object, mode); err != nil {
+		return err
+	}
+
+	object.operated = operated
+
+	packet, err := objectStatePacket(object.Object, playerID, mode)
+	if err != nil {
+		return err
+	}
+
+	w.send(packet, "")
+
+	return nil
+}
+
+// objectStatePacket returns the packet which plays the given mode of the object on a client
+func objectStatePacket(object *d2mapentity.Object, playerID string,
+	mode d2enum.ObjectAnimationMode) (d2netpacket.NetPacket, error) {
+	position := object.GetPosition()
+	world := position.World()
+
+	return d2netpacket.CreateObjectStatePacket(playerID, world.X(), world.Y(), mode)
+}
+
+// toggleDoor opens a closed door and closes an open door, unless someone stands in its way
+func (w *world) toggleDoor(player *worldPlayer, object *worldObject) error {
+	if !object.operated {
+		return w.setObjectMode(object, d2object.OperatedMode(object.Record()), player.ID(), true)
+	}
+
+	if w.unitWithin(object) {
+		return nil
+	}
+
+	return w.setObjectMode(object, d2enum.ObjectAnimationModeNeutral, player.ID(), false)
+}
+
+// unitWithin returns true if a player, a hireling or a monster stands on the sub tiles of the object
+func (w *world) unitWithin(object *worldObject) bool {
+	area := object.Footprint()
+
+	within := func(position d2vector.Position) bool {
+		x, y := int(math.Floor(position.X())), int(math.Floor(position.Y()))
+
+		return x >= area.Left && y >= area.Top && x < area.Left+area.Width && y < area.Top+area.Height
+	}
+
+	for _, player := range w.players {
+		if within(player.Position) {
+			return true
+		}
+	}
+
+	for _, unit := range w.hirelings {
+		if within(unit.Position) {
+			return true
+		}
+	}
+
+	for _, monster := range w.monsters {
+		if within(monster.Position) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// useShrine puts the effect of the shrine or the well on the player, the shrine can be used again
+// once its reset time is over
+func (w *world) useShrine(player *worldPlayer, object *worldObject) error {
+	if object.operated || object.shrine == nil {
+		return nil
+	}
+
+	effect, ok := d2object.NewShrineEffect(object.shrine)
+	if !ok {
+		return nil
+	}
+
+	if effect.Life > 0 {
+		player.life = math.Min(player.life+player.maxLife*float64(effect.Life)/percent, player.maxLife)
+		player.changed = true
+	}
+
+	if effect.Mana > 0 {
+		player.mana = math.Min(player.mana+player.maxMana*float64(effect.Mana)/percent, player.maxMana)
+		player.changed = true
+	}
+
+	if effect.State != "" {
+		w.applyState(player.ID(), effect.State, player.ID(), effect.Duration, w.statList(effect.Stats))
+	}
+
+	object.resetTime = d2object.ResetTime(object.shrine)
+
+	return w.setObjectMode(object, d2object.OperatedMode(object.Record()), player.ID(), true)
+}
+
+// advanceObjects counts down the reset time of the used shrines and wells
+func (w *world) advanceObjects(tickTime float64) {
+	for _, object := range w.objects {
+		if !object.operated || !object.operation.Resets() || object.resetTime <= 0 {
+			continue
+		}
+
+		object.resetTime -= tickTime
+		if object.resetTime > 0 {
+			continue
+		}
+
+		if err := w.setObjectMode(object, d2enum.ObjectAnimationModeNeutral, "", false); err != nil {
+			x, y := object.GetPositionF()
+			w.Errorf("failed to reset the object at (%.1f, %.1f): %v", x, y, err)
+		}
+	}
+}
+
+// waypointLevel returns the level whose waypoint is on the map, 0 if there is none
+func (w *world) waypointLevel() int {
+	if details := w.records.Level.Details[w.levelID]; details != nil && details.HasWaypoint() {
+		return w.levelID
+	}
+
+	for _, id := range w.mapEngine.LevelIDs() {
+		if details := w.records.Level.Details[id]; details != nil && details.HasWaypoint() {
+			return id
+		}
+	}
+
+	return 0
+}
+
+// activateWaypoint activates the waypoint of the map for the player, the waypoints light up for
+// the player only
+func (w *world) activateWaypoint(player *worldPlayer) {
+	levelID := w.waypointLevel()
+	if levelID == 0 {
+		return
+	}
+
+	if !player.client.GetPlayerState().ActivateWaypoint(levelID) {
+		return
+	}
+
+	for _, packet := range w.waypointPackets(player.ID()) {
+		w.sendTo(packet, player.ID())
+	}
+}
+
+// waypointPackets returns the packets which light up the waypoints of the map on a client
+func (w *world) waypointPackets(playerID string) []d2netpacket.NetPacket {
+	packets := make([]d2netpacket.NetPacket, 0)
+
+	for _, object := range w.objects {
+		if object.operation != d2object.OperationWaypoint {
+			continue
+		}
+
+		packet, err := objectStatePacket(object.Object, playerID, d2enum.ObjectAnimationModeOpened)
+		if err != nil {
+			w.Errorf("ObjectStatePacket: %v", err)
+			continue
+		}
+
+		packets = append(packets, packet)
+	}
+
+	return packets
+}
+
+// objectPackets returns the packets which show a client entering the world the operated objects
+// and the waypoints the hero of the client has activated
+func (w *world) objectPackets(hero *d2hero.HeroState) []d2netpacket.NetPacket {
+	w.Lock()
+	defer w.Unlock()
+
+	packets := make([]d2netpacket.NetPacket, 0)
+
+	for _, object := range w.objects {
+		if !object.operated {
+			continue
+		}
+
+		packet, err := objectStatePacket(object.Object, "", object.Mode())
+		if err != nil {
+			w.Errorf("ObjectStatePacket: %v", err)
+			continue
+		}
+
+		packets = append(packets, packet)
+	}
+
+	if levelID := w.waypointLevel(); levelID != 0 && hero != nil && hero.HasWaypoint(levelID) {
+		packets = append(packets, w.waypointPackets("")...)
+	}
+
+	return packets
+}
@@ -0,0 +1,286 @@
+package d2server
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+const (
+	tradeReach = 3.0 // how far, in tiles, from a vendor a player can trade with it
+
+	// vendorWander is how far, in tiles, a vendor may be from where it stands on the server. The
+	// vendors walk around the towns on the clients only.
+	vendorWander = 15.0
+)
+
+// shopItem is an item a vendor offers
+type shopItem struct {
+	id   string
+	item *diablo2item.Item
+}
+
+// worldShop is what a vendor offers a player. It is rolled when the player first trades with the
+// vendor in a level, so the vendors offer new items whenever the player changes the level.
+type worldShop struct {
+	items  []shopItem // items the vendor sells
+	gamble []shopItem // bases the vendor gambles on
+}
+
+// take removes the item with the given ID from the items and returns it, or nil if there is none
+func take(items *[]shopItem, id string) *diablo2item.Item {
+	for idx := range *items {
+		if (*items)[idx].id == id {
+			item := (*items)[idx].item
+			*items = append((*items)[:idx], (*items)[idx+1:]...)
+
+			return item
+		}
+	}
+
+	return nil
+}
+
+// find returns the item with the given ID, or nil if there is none
+func find(items []shopItem, id string) *diablo2item.Item {
+	for idx := range items {
+		if items[idx].id == id {
+			return items[idx].item
+		}
+	}
+
+	return nil
+}
+
+// trade opens the shop of a vendor for the player or trades with it. The gold of the player is
+// checked on the server, the trades the player cannot pay for are ignored.
+func (w *world) trade(player *worldPlayer, trade *d2netpacket.TradePacket) error {
+	if !diablo2item.IsVendor(trade.Vendor) || !w.npcNear(player, trade.Vendor, trade.X, trade.Y) {
+		w.Debugf("%s cannot trade with %s", player.ID(), trade.Vendor)
+		return nil
+	}
+
+	state := player.client.GetPlayerState()
+	ctx := &diablo2item.ShopContext{Vendor: trade.Vendor, Difficulty: state.Difficulty}
+
+	if state.Stats != nil {
+		ctx.Level = state.Stats.Level
+	}
+
+	shop := w.shop(player, ctx)
+
+	switch trade.Action {
+	case d2netpacket.TradeOpen:
+		return w.sendShop(player, ctx, false, shop.items, w.itemFactory.BuyPrice)
+	case d2netpacket.TradeOpenGamble:
+		if !diablo2item.CanGamble(trade.Vendor) {
+			return nil
+		}
+
+		return w.sendShop(player, ctx, true, shop.gamble, w.itemFactory.GamblePrice)
+	case d2netpacket.TradeBuy:
+		return w.buyItem(player, shop, trade, ctx)
+	case d2netpacket.TradeSell:
+		return w.sellItem(player, trade, ctx)
+	case d2netpacket.TradeRepair:
+		return w.repairItems(player, trade, ctx)
+	case d2netpacket.TradeGamble:
+		return w.gamble(player, shop, trade, ctx)
+	}
+
+	return nil
+}
+
+// npcNear returns true if the player stands at the given world position and a town folk of the
+// given monstats.txt row is close to it
+func (w *world) npcNear(player *worldPlayer, key string, x, y float64) bool {
+	target := d2vector.NewVector(x, y)
+	position := player.Position.World()
+
+	if position.Distance(target) > tradeReach {
+		return false
+	}
+
+	for _, entity := range w.mapEngine.Entities() {
+		npc, ok := entity.(*d2mapentity.NPC)
+		if !ok || npc.MonStatRecord() == nil || npc.MonStatRecord().Key != key {
+			continue
+		}
+
+		npcPosition := npc.GetPosition()
+
+		if npcPosition.World().Distance(target) <= vendorWander {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shop returns the shop of the vendor for the player, it is rolled on the first trade
+func (w *world) shop(player *worldPlayer, ctx *diablo2item.ShopContext) *worldShop {
+	if shop, found := player.shops[ctx.Vendor]; found {
+		return shop
+	}
+
+	shop := &worldShop{}
+
+	for _, item := range w.itemFactory.VendorItems(ctx) {
+		shop.items = append(shop.items, shopItem{id: uuid.New().String(), item: item})
+	}
+
+	for _, item := range w.itemFactory.GambleItems(ctx) {
+		shop.gamble = append(shop.gamble, shopItem{id: uuid.New().String(), item: item})
+	}
+
+	player.shops[ctx.Vendor] = shop
+
+	return shop
+}
+
+// sendShop sends the items of a shop with their prices to the player
+func (w *world) sendShop(player *worldPlayer, ctx *diablo2item.ShopContext, gambling bool, items []shopItem,
+	price func(*diablo2item.Item, *diablo2item.ShopContext) int) error {
+	offers := make([]d2netpacket.ShopItem, 0, len(items))
+
+	for idx := range items {
+		data, err := items[idx].item.Marshal()
+		if err != nil {
+			return err
+		}
+
+		offers = append(offers, d2netpacket.ShopItem{ID: items[idx].id, Item: data, Price: price(items[idx].item, ctx)})
+	}
+
+	packet, err := d2netpacket.CreateShopInventoryPacket(ctx.Vendor, gambling, offers)
+	if err != nil {
+		return err
+	}
+
+	w.sendTo(packet, player.ID())
+
+	return nil
+}
+
+// pay takes the price from the gold of the player, it returns false if the player cannot afford it
+func (w *world) pay(player *worldPlayer, price int) bool {
+	state := player.client.GetPlayerState()
+
+	if state.Gold < price {
+		w.Debugf("%s cannot pay %d gold", player.ID(), price)
+		return false
+	}
+
+	state.Gold -= price
+
+	return true
+}
+
+// tradeDone tells the player the outcome of a trade, with the item the player got
+func (w *world) tradeDone(player *worldPlayer, trade *d2netpacket.TradePacket, itemID string,
+	item *diablo2item.Item) error {
+	var data []byte
+
+	if item != nil {
+		var err error
+
+		if data, err = item.Marshal(); err != nil {
+			return err
+		}
+	}
+
+	packet, err := d2netpacket.CreateTradeDonePacket(player.ID(), trade.Vendor, trade.Action, itemID, data,
+		player.client.GetPlayerState().Gold)
+	if err != nil {
+		return err
+	}
+
+	w.sendTo(packet, player.ID())
+
+	return nil
+}
+
+// buyItem gives the item of the vendor to the player, the item keeps its ID
+func (w *world) buyItem(player *worldPlayer, shop *worldShop, trade *d2netpacket.TradePacket,
+	ctx *diablo2item.ShopContext) error {
+	item := find(shop.items, trade.ItemID)
+	if item == nil || !w.pay(player, w.itemFactory.BuyPrice(item, ctx)) {
+		return nil
+	}
+
+	take(&shop.items, trade.ItemID)
+
+	if err := w.giveItem(player, trade.ItemID, item); err != nil {
+		return err
+	}
+
+	return w.tradeDone(player, trade, trade.ItemID, item)
+}
+
+// sellItem gives an item of the player to the vendor for gold
+func (w *world) sellItem(player *worldPlayer, trade *d2netpacket.TradePacket, ctx *diablo2item.ShopContext) error {
+	item := w.ownedItem(player, trade.ItemID)
+	if item == nil {
+		w.Debugf("%s does not have %s to sell", player.ID(), trade.ItemID)
+		return nil
+	}
+
+	player.client.GetPlayerState().Gold += w.itemFactory.SellPrice(item, ctx)
+	w.takeItem(player, trade.ItemID)
+
+	return w.tradeDone(player, trade, trade.ItemID, nil)
+}
+
+// repairItems repairs the item of the trade, or every item of the player if the trade has no
+// item. Either everything is repaired or nothing.
+func (w *world) repairItems(player *worldPlayer, trade *d2netpacket.TradePacket,
+	ctx *diablo2item.ShopContext) error {
+	if !diablo2item.CanRepair(trade.Vendor) {
+		return nil
+	}
+
+	ids := make([]string, 0)
+	price := 0
+
+	for _, id := range ownedItemIDs(player) {
+		if trade.ItemID != "" && id != trade.ItemID {
+			continue
+		}
+
+		if item := w.ownedItem(player, id); item != nil {
+			ids = append(ids, id)
+			price += w.itemFactory.RepairPrice(item, ctx)
+		}
+	}
+
+	if price == 0 || !w.pay(player, price) {
+		return nil
+	}
+
+	for _, id := range ids {
+		w.repairItem(player, id)
+	}
+
+	return w.tradeDone(player, trade, trade.ItemID, nil)
+}
+
+// gamble gives the player a new item rolled from a base the vendor gambles on
+func (w *world) gamble(player *worldPlayer, shop *worldShop, trade *d2netpacket.TradePacket,
+	ctx *diablo2item.ShopContext) error {
+	base := find(shop.gamble, trade.ItemID)
+	if base == nil || !w.pay(player, w.itemFactory.GamblePrice(base, ctx)) {
+		return nil
+	}
+
+	id := uuid.New().String()
+	item := w.itemFactory.Gamble(base, ctx)
+
+	if err := w.giveItem(player, id, item); err != nil {
+		return err
+	}
+
+	return w.tradeDone(player, trade, id, item)
+}
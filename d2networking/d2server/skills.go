@@ -0,0 +1,143 @@
+package d2server
+
+import (
+	"math"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2skill"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
+)
+
+const (
+	// frames of the animations of the skills, the attack animations are slowed down or sped up by
+	// the speed of the weapon
+	attackFrames = 16
+	castFrames   = 14
+
+	// castTolerance is how much sooner than its delay, in seconds, a skill may be cast, so the
+	// casts of a client sent on the frame the delay ends are not rejected for a rounding error
+	castTolerance = 0.5 / d2skill.FramesPerSecond
+
+	statAllSkills = "item_allskills" // the stat of the levels added to every skill
+)
+
+// playerSkill returns the skill of the given record the player uses at the given level
+func (w *world) playerSkill(player *worldPlayer, record *d2records.SkillRecord, level int) *d2skill.Skill {
+	state := player.client.GetPlayerState()
+
+	return d2skill.New(w.records, record, level, &d2hero.SkillCaster{
+		Stats:  state.Stats,
+		Skills: state.Skills,
+		Bonus:  w.skillBonus(player),
+	})
+}
+
+// skillBonus returns the levels the items the hero of the player wears and the states of the
+// player add to all of its skills
+func (w *world) skillBonus(player *worldPlayer) int {
+	stats := w.playerCombatant(player).Stats
+	if stats == nil {
+		return 0
+	}
+
+	bonus := 0
+
+	for _, stat := range stats.Stats() {
+		if stat.Name() == statAllSkills {
+			bonus += stat.Values()[0].Int()
+		}
+	}
+
+	return bonus
+}
+
+// castStates puts the states of a skill on the caster and the monsters around the target: the
+// state of the skill on the caster, a curse and the target state on the monsters. The states of
+// the skills without a duration, like the auras, last until they are replaced.
+func (w *world) castStates(player *worldPlayer, skill *d2skill.Skill, target d2vector.Position) {
+	record := skill.Record
+	if record.Aurastate == "" && record.Auratargetstate == "" {
+		return
+	}
+
+	duration := skill.Duration()
+	if duration <= 0 {
+		duration = d2states.Forever
+	}
+
+	values := make(map[string]int)
+	for _, stat := range skill.AuraStats() {
+		values[stat.Name] += stat.Value
+	}
+
+	stats := w.statList(values)
+	targetStates := make([]string, 0)
+
+	if state := w.records.States[record.Aurastate]; state != nil && state.Curse {
+		targetStates = append(targetStates, record.Aurastate)
+	} else if record.Aurastate != "" {
+		w.applyState(player.ID(), record.Aurastate, player.ID(), duration, stats)
+	}
+
+	if record.Auratargetstate != "" {
+		targetStates = append(targetStates, record.Auratargetstate)
+	}
+
+	// a state cast at a monster reaches it even if the skill has no radius
+	radius := math.Max(skill.Radius(), subtilesPerTile)
+
+	for _, monster := range w.sortedMonsters() {
+		if monster.Position.Distance(&target.Vector) > radius {
+			continue
+		}
+
+		for _, name := range targetStates {
+			w.applyState(monster.ID(), name, player.ID(), duration, stats)
+		}
+	}
+}
+
+// castDelay returns how many seconds the player needs to cast the skill before it casts another
+// one: the frames of the attack or cast animation of the skill, or the delay of the skill if it
+// is longer. The auras have no animation.
+func (w *world) castDelay(player *worldPlayer, skill *d2skill.Skill) float64 {
+	frames := 0
+
+	switch skill.Record.Anim {
+	case d2enum.PlayerAnimationModeAttack1, d2enum.PlayerAnimationModeAttack2,
+		d2enum.PlayerAnimationModeThrow, d2enum.PlayerAnimationModeKick:
+		frames = attackFrames * (percent + w.weaponSpeed(player)) / percent
+	case d2enum.PlayerAnimationModeNone:
+	default:
+		frames = castFrames
+	}
+
+	if skill.Record.Delay > frames {
+		frames = skill.Record.Delay
+	}
+
+	return float64(frames) / d2skill.FramesPerSecond
+}
+
+// weaponSpeed returns the speed of the slowest weapon the hero of the player holds, in percent of
+// the frames of its attacks, 0 for the fists
+func (w *world) weaponSpeed(player *worldPlayer) int {
+	speed := 0
+	armed := false
+
+	for _, item := range w.wornItems(player) {
+		record := item.CommonRecord()
+		if _, maxDamage := weaponDamage(record); maxDamage == 0 {
+			continue
+		}
+
+		if !armed || record.Speed > speed {
+			speed, armed = record.Speed, true
+		}
+	}
+
+	return speed
+}
@@ -0,0 +1,160 @@
+package d2server
+
+import (
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+const (
+	testSkillID      = 36 // a sorceress skill with a mana cost of testSkillMana
+	testBasicSkillID = 0  // a skill of every class which costs no mana
+	testSkillMana    = 8
+	unknownSkillID   = 999
+)
+
+// castTestWorld returns a world on the open grid with the test skills and a caster at the
+// top left, which knows the class skill if it has points in it
+func castTestWorld(t *testing.T, skillPoints int) (*world, *testClient) {
+	t.Helper()
+
+	asset := testAsset()
+	skills := map[int]*d2records.SkillRecord{
+		testSkillID:      {ID: testSkillID, Skill: "Test Bolt", Charclass: "sor", Mana: testSkillMana, Manashift: 8},
+		testBasicSkillID: {ID: testBasicSkillID, Skill: "Attack"},
+	}
+
+	asset.Records.Skill.Details = skills
+
+	w := testWorld(t, asset, openGrid)
+	caster := newTestClient("caster")
+	caster.state.Skills = map[int]*d2hero.HeroSkill{
+		testSkillID:      {SkillPoints: skillPoints, SkillRecord: skills[testSkillID]},
+		testBasicSkillID: {SkillRecord: skills[testBasicSkillID]},
+	}
+
+	w.addPlayer(caster, 2, 2)
+
+	return w, caster
+}
+
+func TestCastSkill(t *testing.T) {
+	table := []struct {
+		name        string
+		skillID     int
+		skillPoints int
+		mana        float64
+		targetX     float64
+		casts       bool
+		manaLeft    float64
+	}{
+		{"class skill", testSkillID, 1, 20, 2.5, true, 20 - testSkillMana},
+		{"skill of every class", testBasicSkillID, 0, 20, 2.5, true, 20},
+		{"unknown skill", unknownSkillID, 1, 20, 2.5, false, 20},
+		{"class skill without points", testSkillID, 0, 20, 2.5, false, 20},
+		{"not enough mana", testSkillID, 1, testSkillMana - 1, 2.5, false, testSkillMana - 1},
+		{"target out of the map", testSkillID, 1, 20, -1, false, 20},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			w, caster := castTestWorld(t, row.skillPoints)
+			player := w.players[caster.id]
+			player.mana = row.mana
+
+			packet, err := d2netpacket.CreateCastPacket(caster.id, row.skillID, row.targetX, 2.5)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w.queue(caster, packet)
+			w.advance(testTick)
+
+			if casts := caster.received(d2netpackettype.CastSkill); (len(casts) == 1) != row.casts {
+				t.Errorf("%d casts were sent, want the cast = %v", len(casts), row.casts)
+			}
+
+			// the mana regenerates during the tick
+			if player.mana < row.manaLeft || player.mana > row.manaLeft+1 {
+				t.Errorf("caster has %.2f mana, want %v", player.mana, row.manaLeft)
+			}
+		})
+	}
+}
+
+func TestCastSkillStopsTheCaster(t *testing.T) {
+	w, caster := castTestWorld(t, 1)
+	player := w.players[caster.id]
+
+	w.queue(caster, movePacket(t, caster, 0.4, 0.4, 3.5, 3.5))
+	w.advance(testTick)
+
+	if !player.IsMoving() {
+		t.Fatal("caster does not walk")
+	}
+
+	packet, err := d2netpacket.CreateCastPacket(caster.id, testSkillID, 2.5, 2.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.queue(caster, packet)
+	w.advance(testTick)
+
+	if player.IsMoving() {
+		t.Error("caster keeps walking while it casts")
+	}
+}
+
+func TestCastSkillWaitsForTheLastCast(t *testing.T) {
+	const slowWeaponSpeed = 50
+
+	table := []struct {
+		name   string
+		anim   d2enum.PlayerAnimationMode
+		delay  int
+		armed  bool
+		frames int
+	}{
+		{"cast", d2enum.PlayerAnimationModeCast, 0, false, castFrames},
+		{"cast with a delay", d2enum.PlayerAnimationModeCast, 2 * castFrames, false, 2 * castFrames},
+		{"attack with the fists", d2enum.PlayerAnimationModeAttack1, 0, false, attackFrames},
+		{"attack with a slow weapon", d2enum.PlayerAnimationModeAttack1, 0, true, attackFrames * 3 / 2},
+		{"aura", d2enum.PlayerAnimationModeNone, 0, false, 1},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			w, caster := attackTestWorld(t, row.armed)
+			w.records.Item.Weapons["hax"].Speed = slowWeaponSpeed
+
+			record := w.records.Skill.Details[testBasicSkillID]
+			record.Anim, record.Delay = row.anim, row.delay
+
+			packet, err := d2netpacket.CreateCastPacket(caster.id, testBasicSkillID, 2.5, 2.5)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a cast is sent on every frame, the first one is cast right away
+			for frames := 0; frames <= 4*attackFrames; frames++ {
+				w.queue(caster, packet)
+				w.advance(testTick)
+
+				if casts := caster.received(d2netpackettype.CastSkill); len(casts) == 2 {
+					if frames != row.frames {
+						t.Errorf("second cast after %d frames, want %d", frames, row.frames)
+					}
+
+					return
+				}
+			}
+
+			t.Errorf("skill is not cast again after %d frames", 4*attackFrames)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package d2server
+
+import (
+	"sort"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+)
+
+// the states.txt states of the elements which keep affecting a unit after a hit
+const (
+	stateChilled  = "cold"
+	statePoisoned = "poison"
+)
+
+// unitStates returns the states of the player, hireling or monster with the given ID, or nil if
+// there is no such unit
+func (w *world) unitStates(id string) *d2states.States {
+	if player, found := w.players[id]; found {
+		return player.states
+	}
+
+	if unit, found := w.hirelings[id]; found {
+		return unit.states
+	}
+
+	if monster, found := w.monsters[id]; found {
+		return monster.states
+	}
+
+	return nil
+}
+
+// applyState puts the state with the given name on the unit, the state adds the given stats to
+// the stats of the unit. The clients are told about the state and the states it replaced.
+func (w *world) applyState(unitID, name, sourceID string, duration float64, stats d2stats.StatList) {
+	record := w.records.States[name]
+	states := w.unitStates(unitID)
+
+	if record == nil || states == nil {
+		return
+	}
+
+	state := d2states.NewState(record, sourceID, duration)
+	state.Stats = stats
+
+	for _, replaced := range states.Apply(state) {
+		if replaced.Name() != name {
+			w.sendState(unitID, replaced, true)
+		}
+	}
+
+	w.sendState(unitID, state, false)
+}
+
+// applyCombatStates puts the states of the chill and the poison of an attack on its target
+func (w *world) applyCombatStates(events []d2combat.Event) {
+	for idx := range events {
+		event := &events[idx]
+
+		switch {
+		case event.Type == d2combat.EventChill:
+			w.applyState(event.TargetID, stateChilled, event.SourceID, event.Duration, nil)
+		case event.Type == d2combat.EventDamageOverTime && event.Element == d2combat.ElementPoison:
+			w.applyState(event.TargetID, statePoisoned, event.SourceID, event.Duration, nil)
+		}
+	}
+}
+
+// advanceStates counts down the timed states of the players, hirelings and monsters and takes the
+// expired states off them
+func (w *world) advanceStates(tickTime float64) {
+	for _, player := range w.players {
+		for _, state := range player.states.Advance(tickTime) {
+			w.sendState(player.ID(), state, true)
+		}
+	}
+
+	for _, unit := range w.sortedHirelings() {
+		for _, state := range unit.states.Advance(tickTime) {
+			w.sendState(unit.ID(), state, true)
+		}
+	}
+
+	for _, monster := range w.sortedMonsters() {
+		for _, state := range monster.states.Advance(tickTime) {
+			w.sendState(monster.ID(), state, true)
+		}
+	}
+}
+
+// sendState tells the clients about a state which was put on or taken off the unit, the states
+// marked nosend in states.txt are not sent
+func (w *world) sendState(unitID string, state *d2states.State, removed bool) {
+	if state.Record.NoSend {
+		return
+	}
+
+	packet, err := statePacket(unitID, state, removed)
+	if err != nil {
+		w.Errorf("SetStatePacket: %v", err)
+		return
+	}
+
+	w.send(packet, "")
+}
+
+// statePacket returns the packet of a state which was put on or taken off the unit
+func statePacket(unitID string, state *d2states.State, removed bool) (d2netpacket.NetPacket, error) {
+	duration := state.Remaining
+	if removed {
+		duration = 0
+	}
+
+	return d2netpacket.CreateSetStatePacket(unitID, state.Name(), duration, removed)
+}
+
+// statePackets returns the packets which show a client entering the world the states of the
+// players, hirelings and monsters
+func (w *world) statePackets() []d2netpacket.NetPacket {
+	w.Lock()
+	defer w.Unlock()
+
+	packets := make([]d2netpacket.NetPacket, 0)
+	units := make(map[string]*d2states.States)
+
+	for _, player := range w.players {
+		units[player.ID()] = player.states
+	}
+
+	for _, monster := range w.monsters {
+		units[monster.ID()] = monster.states
+	}
+
+	for _, unit := range w.hirelings {
+		units[unit.ID()] = unit.states
+	}
+
+	for _, id := range sortedKeys(units) {
+		for _, state := range units[id].Active() {
+			if state.Record.NoSend {
+				continue
+			}
+
+			packet, err := statePacket(id, state, false)
+			if err != nil {
+				w.Errorf("SetStatePacket: %v", err)
+				continue
+			}
+
+			packets = append(packets, packet)
+		}
+	}
+
+	return packets
+}
+
+// sortedKeys returns the unit IDs of the given states in order
+func sortedKeys(units map[string]*d2states.States) []string {
+	ids := make([]string, 0, len(units))
+	for id := range units {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
@@ -0,0 +1,807 @@
+package d2server
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2math/d2vector"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2ai"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2automap"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2combat"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hireling"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2item/diablo2item"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapentity"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2states"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats/diablo2stats"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2client/d2clientconnectiontype"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+const (
+	worldTickRate = 25 // simulation ticks per second
+	worldTickTime = time.Second / worldTickRate
+
+	// positionSyncTicks is the number of ticks between the state deltas sent for moving entities
+	// which did not change otherwise
+	positionSyncTicks = 5
+
+	// maxMoveDrift is how far, in sub tiles, the start of a move sent by a client may be from the
+	// position simulated by the server before the move is rejected
+	maxMoveDrift = 2 * subtilesPerTile
+
+	manaRegenSeconds = 120 // seconds it takes to regenerate the whole mana pool
+	respawnSeconds   = 5   // seconds a dead player lies on the ground before it returns to town
+
+	playerRadius = 1.0 // sub tiles
+
+	warpReach     = 2.0 // how far, in tiles, from a warp tile a player can take the warp
+	waypointReach = 4.0 // how far, in tiles, from a waypoint a player can use it
+)
+
+// worldPlayer is the server side state of a connected player
+type worldPlayer struct {
+	*d2mapentity.HeadlessEntity
+	client  ClientConnection
+	life    float64
+	maxLife float64
+	mana    float64
+	maxMana float64
+	states  *d2states.States
+	shops   map[string]*worldShop // shops of the vendors the player traded with in the level
+	changed bool                  // true if the state has to be sent in the next state delta
+
+	deadTime float64 // seconds since the player died
+
+	lastCast  float64 // world time of the last skill the player cast
+	castDelay float64 // seconds the player needs after its last skill before it casts another one
+
+	explored *d2automap.Explored // the tiles of the map the player has explored, saved with the hero
+
+	hireSeller string                          // the seller of the hirelings offered to the player
+	hireOffers map[string]*d2hireling.Hireling // hirelings offered to the player, by their offer ID
+}
+
+// dead returns true if the player was killed and has not returned to town yet, dead players
+// cannot act
+func (p *worldPlayer) dead() bool {
+	return p.life <= 0
+}
+
+// isHost returns true if the player plays on the machine of the server, only the host may use the
+// debug commands which create items or monsters
+func (p *worldPlayer) isHost() bool {
+	return p.client.GetConnectionType() == d2clientconnectiontype.Local
+}
+
+// worldMissile is a missile simulated by the server
+type worldMissile struct {
+	*d2mapentity.HeadlessEntity
+	ownerID string
+	radius  float64
+	attack  *d2combat.Attack
+	done    bool
+}
+
+// worldPacket is a packet produced by a world tick, it is sent to every player except the given
+// one, or only to the given player
+type worldPacket struct {
+	packet d2netpacket.NetPacket
+	except string
+	only   string
+}
+
+// world runs the authoritative simulation of the entities on the map of a level. The clients
+// send commands, the world validates them, advances the entities at a fixed tick rate and sends
+// the state of the entities which changed back to the clients.
+type world struct {
+	sync.Mutex
+	levelID       int                   // the level the map was generated for
+	difficulty    d2enum.DifficultyType // the difficulty of the monsters
+	mapEngine     *d2mapengine.MapEngine
+	records       *d2records.RecordManager
+	warps         []d2mapengine.Warp
+	waypoints     []d2vector.Position
+	objects       []*worldObject // objects the players can operate, in the order of their positions
+	players       map[string]*worldPlayer
+	missiles      map[string]*worldMissile
+	items         map[string]*d2mapentity.Item // items on the ground
+	monsters      map[string]*worldMonster
+	ai            *d2ai.Controller
+	hirelings     map[string]*worldHireling // hirelings of the players on the map
+	hirelingAI    *d2ai.Controller
+	hireFactory   *d2hireling.Factory
+	combat        *d2combat.Engine
+	afflictions   *d2combat.Afflictions
+	combatEvents  []d2combat.Event // events of the tick, sent as a single packet
+	stats         *diablo2stats.StatFactory
+	itemFactory   *diablo2item.ItemFactory
+	commands      []ReceivedPacket
+	outbox        []worldPacket
+	departures    []arrival
+	tick          uint64
+	time          float64 // seconds the world has been simulated
+	nextMissileID int
+	nextMonsterID int
+	emptyTime     float64 // seconds since the last player left
+	rand          *rand.Rand
+
+	*d2util.Logger
+}
+
+func newWorld(levelID int, mapEngine *d2mapengine.MapEngine, records *d2records.RecordManager,
+	stats *diablo2stats.StatFactory, difficulty d2enum.DifficultyType, seed int64, logger *d2util.Logger) *world {
+	w := &world{
+		levelID:     levelID,
+		difficulty:  difficulty,
+		mapEngine:   mapEngine,
+		records:     records,
+		warps:       mapEngine.Warps(),
+		waypoints:   make([]d2vector.Position, 0),
+		objects:     make([]*worldObject, 0),
+		players:     make(map[string]*worldPlayer),
+		missiles:    make(map[string]*worldMissile),
+		items:       make(map[string]*d2mapentity.Item),
+		monsters:    make(map[string]*worldMonster),
+		ai:          d2ai.NewController(worldTerrain{mapEngine: mapEngine}, seed),
+		hirelings:   make(map[string]*worldHireling),
+		hirelingAI:  d2ai.NewController(worldTerrain{mapEngine: mapEngine}, seed),
+		hireFactory: d2hireling.NewFactory(records, seed),
+		combat:      d2combat.NewEngine(records.ElemTypes, seed),
+		afflictions: d2combat.NewAfflictions(),
+		stats:       stats,
+		itemFactory: mapEngine.ItemFactory(),
+		rand:        rand.New(rand.NewSource(seed)), //nolint:gosec // the simulation does not need a secure source
+		Logger:      logger,
+	}
+
+	w.itemFactory.SetSeed(seed)
+
+	w.adoptObjects()
+	w.adoptMonsters()
+	w.populate()
+
+	return w
+}
+
+// containsLevel returns true if the given level is a part of the map of the world
+func (w *world) containsLevel(levelID int) bool {
+	for _, id := range w.mapEngine.LevelIDs() {
+		if id == levelID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clients returns the clients of the players in the world
+func (w *world) clients() []ClientConnection {
+	w.Lock()
+	defer w.Unlock()
+
+	clients := make([]ClientConnection, 0, len(w.players))
+	for _, player := range w.players {
+		clients = append(clients, player.client)
+	}
+
+	return clients
+}
+
+// addPlayer adds the player of the given client at the given sub tile position
+func (w *world) addPlayer(client ClientConnection, x, y int) {
+	w.Lock()
+	defer w.Unlock()
+
+	player := &worldPlayer{
+		HeadlessEntity: d2mapentity.NewHeadlessEntity(client.GetUniqueID(), d2vector.NewPosition(float64(x), float64(y))),
+		client:         client,
+		states:         d2states.NewStates(),
+		shops:          make(map[string]*worldShop),
+	}
+
+	if stats := client.GetPlayerState().Stats; stats != nil {
+		player.life, player.maxLife = float64(stats.Health), float64(stats.MaxHealth)
+		player.mana, player.maxMana = float64(stats.Mana), float64(stats.MaxMana)
+	}
+
+	player.SetSpeed(d2mapentity.BaseWalkSpeed)
+
+	player.explored = d2automap.ForHero(client.GetPlayerState(), w.mapEngine)
+
+	w.players[player.ID()] = player
+	w.mapEngine.AddEntity(player)
+	w.spawnHireling(player)
+}
+
+// removePlayer removes the player with the given ID
+func (w *world) removePlayer(id string) {
+	w.Lock()
+	defer w.Unlock()
+
+	if player, found := w.players[id]; found {
+		w.mapEngine.RemoveEntity(player)
+		delete(w.players, id)
+		w.removeHireling(id)
+	}
+
+	w.emptyTime = 0
+}
+
+// queue stores a command of a client, it is handled at the start of the next tick
+func (w *world) queue(client ClientConnection, packet d2netpacket.NetPacket) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.commands = append(w.commands, ReceivedPacket{Client: client, Packet: packet})
+}
+
+// advance processes a single tick of the world, sends the resulting packets and returns the
+// players which take a warp or a waypoint to another level. The packets are sent after the world
+// is unlocked, as a local client handles them right away and may send commands of its own in
+// response.
+func (w *world) advance(tickTime float64) []arrival {
+	w.Lock()
+
+	w.tick++
+	w.time += tickTime
+
+	if len(w.players) == 0 {
+		w.emptyTime += tickTime
+	}
+
+	commands := w.commands
+	w.commands = nil
+
+	for idx := range commands {
+		if err := w.handleCommand(commands[idx].Client, commands[idx].Packet); err != nil {
+			w.Errorf("failed to handle %s of client %s: %v",
+				commands[idx].Packet.PacketType, commands[idx].Client.GetUniqueID(), err)
+		}
+	}
+
+	// only the entities of the world are advanced, the entities of the map stamps are animated
+	// by the clients
+	for _, player := range w.players {
+		player.Advance(tickTime)
+
+		position := player.Position.World()
+		player.explored.Explore(position.X(), position.Y(), nil)
+	}
+
+	for _, missile := range w.missiles {
+		missile.Advance(tickTime)
+	}
+
+	w.advanceMonsters(tickTime)
+	w.advanceHirelings(tickTime)
+	w.advanceMissiles()
+	w.advanceAfflictions(tickTime)
+	w.advanceStates(tickTime)
+	w.advanceItems(tickTime)
+	w.advanceObjects(tickTime)
+	w.advanceDeadPlayers(tickTime)
+	w.regenerate(tickTime)
+	w.queueCombatEvents()
+	w.queueStateDelta()
+
+	outbox, departures := w.outbox, w.departures
+	w.outbox, w.departures = nil, nil
+
+	clients := make([]ClientConnection, 0, len(w.players))
+	for _, player := range w.players {
+		clients = append(clients, player.client)
+	}
+
+	w.Unlock()
+
+	for _, out := range outbox {
+		for _, client := range clients {
+			if client.GetUniqueID() == out.except || out.only != "" && client.GetUniqueID() != out.only {
+				continue
+			}
+
+			if err := client.SendPacketToClient(out.packet); err != nil {
+				w.Errorf("GameServer: error sending packet: %s to client %s: %s", out.packet.PacketType, client.GetUniqueID(), err)
+			}
+		}
+	}
+
+	return departures
+}
+
+func (w *world) send(packet d2netpacket.NetPacket, except string) {
+	w.outbox = append(w.outbox, worldPacket{packet: packet, except: except})
+}
+
+// sendTo sends the packet to the given player only
+func (w *world) sendTo(packet d2netpacket.NetPacket, playerID string) {
+	w.outbox = append(w.outbox, worldPacket{packet: packet, only: playerID})
+}
+
+func (w *world) handleCommand(client ClientConnection, packet d2netpacket.NetPacket) error {
+	player, found := w.players[client.GetUniqueID()]
+	if !found {
+		return nil // the player disconnected before the command was handled
+	}
+
+	if player.dead() {
+		w.Debugf("%s is dead and cannot send %s", player.ID(), packet.PacketType)
+		return nil
+	}
+
+	switch packet.PacketType {
+	case d2netpackettype.MovePlayer:
+		move, err := d2netpacket.UnmarshalMovePlayer(packet)
+		if err != nil {
+			return err
+		}
+
+		return w.movePlayer(player, &move)
+	case d2netpackettype.CastSkill:
+		cast, err := d2netpacket.UnmarshalCast(packet)
+		if err != nil {
+			return err
+		}
+
+		return w.castSkill(player, &cast)
+	case d2netpackettype.WarpPlayer:
+		warp, err := d2netpacket.UnmarshalWarpPlayer(packet)
+		if err != nil {
+			return err
+		}
+
+		w.warpPlayer(player, &warp)
+	case d2netpackettype.SpawnItem:
+		spawn, err := d2netpacket.UnmarshalSpawnItem(packet)
+		if err != nil {
+			return err
+		}
+
+		if !player.isHost() {
+			w.Warningf("%s is not the host and cannot spawn items", player.ID())
+			return nil
+		}
+
+		return w.spawnItem(&spawn)
+	case d2netpackettype.PickUpItem:
+		pickUp, err := d2netpacket.UnmarshalPickUpItem(packet)
+		if err != nil {
+			return err
+		}
+
+		return w.pickUpItem(player, &pickUp)
+	case d2netpackettype.OperateObject:
+		operate, err := d2netpacket.UnmarshalOperateObject(packet)
+		if err != nil {
+			return err
+		}
+
+		return w.operateObject(player, &operate)
+	case d2netpackettype.SpawnMonster:
+		spawn, err := d2netpacket.UnmarshalSpawnMonster(packet)
+		if err != nil {
+			return err
+		}
+
+		if !player.isHost() {
+			w.Warningf("%s is not the host and cannot spawn monsters", player.ID())
+			return nil
+		}
+
+		return w.spawnMonster(&spawn)
+	case d2netpackettype.Trade:
+		trade, err := d2netpacket.UnmarshalTrade(packet)
+		if err != nil {
+			return err
+		}
+
+		return w.trade(player, &trade)
+	case d2netpackettype.Hire:
+		hire, err := d2netpacket.UnmarshalHire(packet)
+		if err != nil {
+			return err
+		}
+
+		return w.hire(player, &hire)
+	}
+
+	return nil
+}
+
+// inBounds returns true if the given world tile position lies within the map
+func (w *world) inBounds(x, y float64) bool {
+	size := w.mapEngine.Size()
+
+	return x >= 0 && y >= 0 && x < float64(size.Width) && y < float64(size.Height)
+}
+
+// movePlayer moves the player from its server position to the destination of the move. The path
+// is found on the server, so unreachable destinations are clamped to the closest reachable sub
+// tile, and the player moves at the speed of the server. A move which starts too far from the
+// server position is rejected, the player stops and its client is corrected by the next state
+// delta.
+func (w *world) movePlayer(player *worldPlayer, move *d2netpacket.MovePlayerPacket) error {
+	// the mover gets its corrected state in the next state delta
+	player.changed = true
+
+	clientStart := d2vector.NewPositionTile(move.StartX, move.StartY)
+
+	if drift := clientStart.Distance(&player.Position.Vector); drift > maxMoveDrift {
+		w.Debugf("move of %s starts %.1f sub tiles from its server position", player.ID(), drift)
+		player.StopMoving()
+
+		return nil
+	}
+
+	if !w.inBounds(move.DestX, move.DestY) {
+		player.StopMoving()
+		return nil
+	}
+
+	path := w.mapEngine.PathFind(player.Position, d2vector.NewPositionTile(move.DestX, move.DestY))
+	if len(path) == 0 {
+		player.StopMoving()
+		return nil
+	}
+
+	if move.Running {
+		player.SetSpeed(d2mapentity.BaseRunSpeed)
+	} else {
+		player.SetSpeed(d2mapentity.BaseWalkSpeed)
+	}
+
+	player.SetPath(path, nil)
+
+	start, dest := player.Position.World(), player.Destination()
+	destWorld := dest.World()
+
+	packet, err := d2netpacket.CreateMovePlayerPacket(player.ID(), start.X(), start.Y(),
+		destWorld.X(), destWorld.Y(), move.Running)
+	if err != nil {
+		return err
+	}
+
+	// the mover has already predicted its move
+	w.send(packet, player.ID())
+
+	return nil
+}
+
+// castSkill validates a skill cast, takes its mana cost, spawns its server missiles and puts its
+// states on the units. A melee skill without missiles hits the monster at the target.
+func (w *world) castSkill(player *worldPlayer, cast *d2netpacket.CastPacket) error {
+	record := w.records.Skill.Details[cast.SkillID]
+	heroSkill, found := player.client.GetPlayerState().Skills[cast.SkillID]
+
+	if record == nil || !found || heroSkill == nil || (heroSkill.SkillPoints == 0 && record.Charclass != "") {
+		w.Debugf("%s cannot cast skill %d", player.ID(), cast.SkillID)
+		return nil
+	}
+
+	if !w.inBounds(cast.TargetX, cast.TargetY) {
+		return nil
+	}
+
+	level := heroSkill.SkillPoints
+	if level < 1 {
+		level = 1
+	}
+
+	skill := w.playerSkill(player, record, level)
+
+	if since := w.time - player.lastCast; since < player.castDelay-castTolerance {
+		w.Debugf("%s casts skill %d %.2f seconds after its last skill", player.ID(), cast.SkillID, since)
+		return nil
+	}
+
+	cost := skill.ManaCost()
+	if player.mana < cost {
+		return nil
+	}
+
+	player.mana -= cost
+	player.changed = true
+	player.lastCast, player.castDelay = w.time, w.castDelay(player, skill)
+
+	player.StopMoving()
+
+	target := d2vector.NewPositionTile(cast.TargetX, cast.TargetY)
+	attack := d2combat.SkillAttack(skill)
+	missiles := 0
+
+	for _, name := range []string{record.Srvmissile, record.Srvmissilea, record.Srvmissileb, record.Srvmissilec} {
+		if name == "" {
+			continue
+		}
+
+		if missile := w.records.GetMissileByName(name); missile != nil {
+			w.spawnMissile(player.ID(), player.Position, missile, target, missileAttack(attack, missile, level))
+			missiles++
+		}
+	}
+
+	if missiles == 0 && attack.Melee {
+		if targetID := w.meleeTarget(player, cast.TargetX, cast.TargetY); targetID != "" {
+			w.hit(player.ID(), targetID, attack)
+		}
+	}
+
+	w.castStates(player, skill, target)
+
+	packet, err := d2netpacket.CreateCastPacket(player.ID(), cast.SkillID, cast.TargetX, cast.TargetY)
+	if err != nil {
+		return err
+	}
+
+	w.send(packet, "")
+
+	return nil
+}
+
+// missileAttack returns the attack of a missile of a skill, the missiles which refer to their
+// skill or have no damage of their own make the attack of the skill
+func missileAttack(skill *d2combat.Attack, missile *d2records.MissileRecord, level int) *d2combat.Attack {
+	if missile.SkillName == "" && missile.Damage.MaxDamage > 0 {
+		return d2combat.MissileAttack(missile, level)
+	}
+
+	return skill
+}
+
+// spawnMissile fires a missile of the given owner from the start towards the target, it flies as
+// far as the range of the missile
+func (w *world) spawnMissile(ownerID string, start d2vector.Position, record *d2records.MissileRecord,
+	target d2vector.Position, attack *d2combat.Attack) {
+	direction := target.Vector.Clone()
+	direction.Subtract(&start.Vector)
+
+	if record.Velocity <= 0 || direction.IsZero() {
+		return
+	}
+
+	direction.SetLength(float64(record.Range))
+
+	w.nextMissileID++
+
+	missile := &worldMissile{
+		HeadlessEntity: d2mapentity.NewHeadlessEntity(fmt.Sprintf("missile-%d", w.nextMissileID), start),
+		ownerID:        ownerID,
+		radius:         float64(record.Size)/2 + playerRadius, //nolint:gomnd // size is a diameter
+		attack:         attack,
+	}
+
+	end := d2vector.NewPosition(start.X()+direction.X(), start.Y()+direction.Y())
+
+	missile.SetSpeed(float64(record.Velocity))
+	missile.SetPath([]d2vector.Position{end}, func() {
+		missile.done = true
+	})
+
+	w.missiles[missile.ID()] = missile
+	w.mapEngine.AddEntity(missile)
+}
+
+// blocksMissiles returns true if a missile cannot fly through the given sub tile
+func (w *world) blocksMissiles(subX, subY int) bool {
+	size := w.mapEngine.Size()
+
+	if subX < 0 || subY < 0 || subX >= size.Width*subtilesPerTile || subY >= size.Height*subtilesPerTile {
+		return true
+	}
+
+	return w.mapEngine.SubTileAt(subX, subY).BlockLOS
+}
+
+// advanceMissiles stops the missiles which hit a wall or a unit. The missiles of the monsters
+// hit the players and the hirelings, the missiles of the players hit the monsters and the other
+// players and the missiles of the hirelings hit the monsters.
+func (w *world) advanceMissiles() {
+	for id, missile := range w.missiles {
+		if !missile.done && w.blocksMissiles(int(missile.Position.X()), int(missile.Position.Y())) {
+			missile.done = true
+		}
+
+		if !missile.done {
+			if targetID := w.missileTarget(missile); targetID != "" {
+				w.hit(missile.ownerID, targetID, missile.attack)
+				missile.done = true
+			}
+		}
+
+		if missile.done {
+			w.mapEngine.RemoveEntity(missile)
+			delete(w.missiles, id)
+		}
+	}
+}
+
+// missileTarget returns the ID of a living unit the missile hits, or an empty string if it hits
+// none
+func (w *world) missileTarget(missile *worldMissile) string {
+	hits := func(position *d2vector.Position) bool {
+		return missile.Position.Distance(&position.Vector) <= missile.radius
+	}
+
+	_, fromMonster := w.monsters[missile.ownerID]
+	_, fromHireling := w.hirelings[missile.ownerID]
+
+	if !fromHireling {
+		for _, player := range w.players {
+			if player.ID() != missile.ownerID && player.life > 0 && hits(&player.Position) {
+				return player.ID()
+			}
+		}
+	}
+
+	if fromMonster {
+		for _, unit := range w.sortedHirelings() {
+			if unit.ai.Alive() && hits(&unit.Position) {
+				return unit.ID()
+			}
+		}
+
+		return ""
+	}
+
+	for _, monster := range w.sortedMonsters() {
+		if monster.ai.Alive() && hits(&monster.Position) {
+			return monster.ID()
+		}
+	}
+
+	return ""
+}
+
+// playerDied stops a killed player where it is, it lies dead until it returns to town
+func (w *world) playerDied(player *worldPlayer) {
+	player.StopMoving()
+	player.life, player.deadTime = 0, 0
+	player.changed = true
+}
+
+// advanceDeadPlayers returns the players which have been dead for long enough to the town of
+// their act with their whole life and mana. A player which died in town is revived at the start
+// of the map, the other ones leave the world at the end of the tick.
+func (w *world) advanceDeadPlayers(tickTime float64) {
+	for _, player := range w.players {
+		if !player.dead() {
+			continue
+		}
+
+		player.deadTime += tickTime
+		if player.deadTime < respawnSeconds {
+			continue
+		}
+
+		player.life, player.mana, player.deadTime = player.maxLife, player.maxMana, 0
+		player.changed = true
+
+		town, found := townLevelIDs[player.client.GetPlayerState().Act]
+		if !found {
+			town = fallbackLevelID
+		}
+
+		if !w.containsLevel(town) {
+			w.departures = append(w.departures, arrival{client: player.client, levelID: town, from: w})
+			continue
+		}
+
+		x, y := w.mapEngine.GetStartPosition()
+		player.Position = d2vector.NewPositionTile(x, y)
+	}
+}
+
+// regenerate refills the mana of the players
+func (w *world) regenerate(tickTime float64) {
+	for _, player := range w.players {
+		if player.dead() || player.mana >= player.maxMana {
+			continue
+		}
+
+		before := int(player.mana)
+		player.mana = math.Min(player.mana+player.maxMana*tickTime/manaRegenSeconds, player.maxMana)
+		player.changed = player.changed || int(player.mana) != before
+	}
+}
+
+// queueStateDelta queues the state of the players, monsters and hirelings which changed during the
+// tick. The positions of the moving players, monsters and hirelings are sent every few ticks.
+func (w *world) queueStateDelta() {
+	syncPositions := w.tick%positionSyncTicks == 0
+	entities := make([]d2netpacket.EntityState, 0)
+
+	for _, player := range w.players {
+		position := player.Position.World()
+
+		if state := player.client.GetPlayerState(); state != nil {
+			state.X, state.Y = position.X(), position.Y()
+
+			// a hero saved while it is dead joins the next game alive in town
+			if state.Stats != nil && player.dead() {
+				state.Stats.Health, state.Stats.Mana = int(player.maxLife), int(player.maxMana)
+			} else if state.Stats != nil {
+				state.Stats.Health, state.Stats.Mana = int(player.life), int(player.mana)
+			}
+		}
+
+		if !player.changed && !(syncPositions && player.IsMoving()) {
+			continue
+		}
+
+		player.changed = false
+		dest := player.Destination()
+		destWorld := dest.World()
+
+		entities = append(entities, d2netpacket.EntityState{
+			ID:      player.ID(),
+			X:       position.X(),
+			Y:       position.Y(),
+			DestX:   destWorld.X(),
+			DestY:   destWorld.Y(),
+			Life:    int(player.life),
+			MaxLife: int(player.maxLife),
+			Mana:    int(player.mana),
+			MaxMana: int(player.maxMana),
+		})
+	}
+
+	for _, monster := range w.monsters {
+		if !monster.changed && !(syncPositions && monster.IsMoving()) {
+			continue
+		}
+
+		monster.changed = false
+		entities = append(entities, unitState(monster.HeadlessEntity, monster.ai))
+	}
+
+	for _, unit := range w.hirelings {
+		if !unit.changed && !(syncPositions && unit.IsMoving()) {
+			continue
+		}
+
+		unit.changed = false
+		entities = append(entities, unitState(unit.HeadlessEntity, unit.ai))
+	}
+
+	if len(entities) == 0 {
+		return
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].ID < entities[j].ID
+	})
+
+	packet, err := d2netpacket.CreateStateDeltaPacket(w.tick, entities)
+	if err != nil {
+		w.Errorf("StateDeltaPacket: %v", err)
+		return
+	}
+
+	w.send(packet, "")
+}
+
+// unitState returns the state of a monster or a hireling for a state delta
+func unitState(entity *d2mapentity.HeadlessEntity, ai *d2ai.Monster) d2netpacket.EntityState {
+	position := entity.Position.World()
+	dest := entity.Destination()
+	destWorld := dest.World()
+
+	return d2netpacket.EntityState{
+		ID:      entity.ID(),
+		X:       position.X(),
+		Y:       position.Y(),
+		DestX:   destWorld.X(),
+		DestY:   destWorld.Y(),
+		Life:    int(ai.Life),
+		MaxLife: int(ai.MaxLife),
+	}
+}
@@ -0,0 +1,328 @@
+package d2server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2enum"
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2util"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2asset"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2hero"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2map/d2mapengine"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2records"
+	"github.com/OpenDiablo2/OpenDiablo2/d2core/d2stats/diablo2stats"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2client/d2clientconnectiontype"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket"
+	"github.com/OpenDiablo2/OpenDiablo2/d2networking/d2netpacket/d2netpackettype"
+)
+
+const (
+	testLevelID = 1
+	testSeed    = 1234
+	testTick    = 0.04
+)
+
+// testClient is a client connection which keeps the packets sent to it
+type testClient struct {
+	id             string
+	connectionType d2clientconnectiontype.ClientConnectionType
+	state          *d2hero.HeroState
+	packets        []d2netpacket.NetPacket
+}
+
+func newTestClient(id string) *testClient {
+	return &testClient{
+		id:             id,
+		connectionType: d2clientconnectiontype.LANClient,
+		state: &d2hero.HeroState{
+			HeroName: id,
+			HeroType: d2enum.HeroSorceress,
+			Act:      1,
+			Stats:    &d2hero.HeroStatsState{Level: 1, Health: 50, MaxHealth: 50, Mana: 20, MaxMana: 20},
+			Skills:   map[int]*d2hero.HeroSkill{},
+		},
+	}
+}
+
+func (c *testClient) GetUniqueID() string { return c.id }
+
+func (c *testClient) GetConnectionType() d2clientconnectiontype.ClientConnectionType {
+	return c.connectionType
+}
+
+func (c *testClient) SendPacketToClient(packet d2netpacket.NetPacket) error {
+	c.packets = append(c.packets, packet)
+	return nil
+}
+
+func (c *testClient) GetPlayerState() *d2hero.HeroState { return c.state }
+
+func (c *testClient) SetPlayerState(state *d2hero.HeroState) { c.state = state }
+
+// received returns the packets of the given type the client received
+func (c *testClient) received(packetType d2netpackettype.NetPacketType) []d2netpacket.NetPacket {
+	result := make([]d2netpacket.NetPacket, 0)
+
+	for _, packet := range c.packets {
+		if packet.PacketType == packetType {
+			result = append(result, packet)
+		}
+	}
+
+	return result
+}
+
+// testAsset returns an asset manager with a level type without tiles and the items the heroes
+// start with, the other records are empty
+func testAsset() *d2asset.AssetManager {
+	asset := &d2asset.AssetManager{Records: &d2records.RecordManager{}}
+	asset.Records.Level.Types = d2records.LevelTypes{{Name: "Test"}}
+	asset.Records.Level.Details = d2records.LevelDetails{}
+	asset.Records.Skill.Details = map[int]*d2records.SkillRecord{}
+
+	items := &asset.Records.Item
+	items.Armors = d2records.CommonItems{
+		"buc": {Code: "buc", Type: "shie", MinAC: 4, MaxAC: 6, Durability: 12},
+		"cap": {Code: "cap", Type: "helm", MinAC: 3, MaxAC: 5, Durability: 12},
+	}
+	items.Weapons = d2records.CommonItems{}
+	items.All = d2records.CommonItems{}
+
+	for _, code := range []string{"hax", "wnd", "ssd", "ktr", "sst", "jav", "clb"} {
+		items.Weapons[code] = &d2records.ItemCommonRecord{Code: code, Type: "weap", Durability: 20}
+	}
+
+	for _, list := range []d2records.CommonItems{items.Armors, items.Weapons} {
+		for code, record := range list {
+			items.All[code] = record
+		}
+	}
+
+	return asset
+}
+
+// testMapEngine creates a map engine from a grid of sub tiles, where '#' blocks walking. The grid
+// is padded with walkable sub tiles to a whole number of tiles.
+func testMapEngine(t *testing.T, asset *d2asset.AssetManager, grid string) *d2mapengine.MapEngine {
+	t.Helper()
+
+	rows := strings.Split(strings.TrimSpace(grid), "\n")
+	width := (len(strings.TrimSpace(rows[0])) + subtilesPerTile - 1) / subtilesPerTile
+	height := (len(rows) + subtilesPerTile - 1) / subtilesPerTile
+
+	mapEngine := d2mapengine.CreateMapEngine(d2util.LogLevelNone, asset)
+	mapEngine.ResetMap(0, width, height)
+	mapEngine.SetLevelIDs([]int{testLevelID})
+
+	for y, row := range rows {
+		for x, cell := range strings.TrimSpace(row) {
+			if cell == '#' {
+				mapEngine.SubTileAt(x, y).BlockWalk = true
+			}
+		}
+	}
+
+	return mapEngine
+}
+
+// testWorld creates a world of the level testLevelID on the given grid, see testMapEngine
+func testWorld(t *testing.T, asset *d2asset.AssetManager, grid string) *world {
+	t.Helper()
+
+	stats, err := diablo2stats.NewStatFactory(asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := d2util.NewLogger()
+	logger.SetLevel(d2util.LogLevelNone)
+
+	return newWorld(testLevelID, testMapEngine(t, asset, grid), asset.Records, stats, d2enum.DifficultyNormal,
+		testSeed, logger)
+}
+
+// openGrid is a walkable grid of 4 by 4 tiles
+const openGrid = `
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................
+	....................`
+
+func movePacket(t *testing.T, client *testClient, startX, startY, destX, destY float64) d2netpacket.NetPacket {
+	t.Helper()
+
+	packet, err := d2netpacket.CreateMovePlayerPacket(client.id, startX, startY, destX, destY, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return packet
+}
+
+func TestAdvanceHandlesCommandsBeforeEntities(t *testing.T) {
+	w := testWorld(t, testAsset(), openGrid)
+	mover, watcher := newTestClient("mover"), newTestClient("watcher")
+
+	w.addPlayer(mover, 2, 2)
+	w.addPlayer(watcher, 12, 12)
+	w.queue(mover, movePacket(t, mover, 0.4, 0.4, 3.5, 0.5))
+
+	player := w.players[mover.id]
+	if start := player.Position.World(); start.X() != 0.4 || start.Y() != 0.4 {
+		t.Fatalf("queued move was handled before the tick, player is at %v", start)
+	}
+
+	w.advance(testTick)
+
+	// the command is handled first, so the player already walked during the tick
+	if position := player.Position.World(); position.X() <= 0.4 {
+		t.Errorf("player did not walk in the tick of its command, it is at %v", position)
+	}
+
+	if moves := watcher.received(d2netpackettype.MovePlayer); len(moves) != 1 {
+		t.Errorf("watcher received %d moves, want 1", len(moves))
+	}
+
+	if moves := mover.received(d2netpackettype.MovePlayer); len(moves) != 0 {
+		t.Errorf("mover received %d moves of its own, want 0", len(moves))
+	}
+
+	// the packets of the tick are sent with the tick, the state delta of the mover included
+	if deltas := mover.received(d2netpackettype.StateDelta); len(deltas) == 0 {
+		t.Error("mover did not receive the state delta of its move")
+	}
+}
+
+func TestAdvanceHandlesCommandsInOrder(t *testing.T) {
+	w := testWorld(t, testAsset(), openGrid)
+	mover := newTestClient("mover")
+
+	w.addPlayer(mover, 2, 2)
+	w.queue(mover, movePacket(t, mover, 0.4, 0.4, 3.5, 0.5))
+	w.queue(mover, movePacket(t, mover, 0.4, 0.4, 0.5, 3.5))
+	w.advance(testTick)
+
+	dest := w.players[mover.id].Destination()
+	if world := dest.World(); world.X() != 0.5 || world.Y() != 3.5 {
+		t.Errorf("player walks to %v, want the destination of its last command", world)
+	}
+}
+
+func TestMovePlayer(t *testing.T) {
+	grid := `
+		..........
+		..........
+		#########.
+		..........
+		..........`
+
+	table := []struct {
+		name         string
+		startX       float64
+		startY       float64
+		destX        float64
+		destY        float64
+		moves        bool
+		destinationX float64
+		destinationY float64
+	}{
+		{"reachable destination", 0.1, 0.1, 1.5, 0.1, true, 1.5, 0.1},
+		{"destination out of the map", 0.1, 0.1, 12, 0.1, false, 0, 0},
+		{"destination on a wall", 0.1, 0.1, 0.3, 0.5, true, 0.3, 0.3},
+		{"start too far from the server position", 1.9, 0.9, 1.5, 0.1, false, 0, 0},
+	}
+
+	for _, row := range table {
+		t.Run(row.name, func(t *testing.T) {
+			w := testWorld(t, testAsset(), grid)
+			client, watcher := newTestClient("mover"), newTestClient("watcher")
+
+			w.addPlayer(client, 0, 0)
+			w.addPlayer(watcher, 9, 0)
+
+			player := w.players[client.id]
+
+			move, err := d2netpacket.UnmarshalMovePlayer(movePacket(t, client, row.startX, row.startY, row.destX,
+				row.destY))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := w.movePlayer(player, &move); err != nil {
+				t.Fatal(err)
+			}
+
+			if !player.changed {
+				t.Error("the mover is not corrected by the next state delta")
+			}
+
+			if moving := player.IsMoving(); moving != row.moves {
+				t.Fatalf("player moves = %v, want %v", moving, row.moves)
+			}
+
+			if moves := len(w.outbox); (moves == 1) != row.moves {
+				t.Errorf("%d moves were sent to the other players", moves)
+			}
+
+			if !row.moves {
+				return
+			}
+
+			dest := player.Destination()
+			if world := dest.World(); !near(world.X(), row.destinationX) || !near(world.Y(), row.destinationY) {
+				t.Errorf("player walks to %v, want (%v, %v)", world, row.destinationX, row.destinationY)
+			}
+		})
+	}
+}
+
+func TestMovePlayerDoesNotWalkThroughWalls(t *testing.T) {
+	grid := `
+		..........
+		..........
+		#########.
+		..........
+		..........`
+
+	w := testWorld(t, testAsset(), grid)
+	client := newTestClient("mover")
+
+	w.addPlayer(client, 0, 0)
+	w.queue(client, movePacket(t, client, 0, 0, 0.1, 0.9))
+
+	player := w.players[client.id]
+
+	for idx := 0; idx < 200 && (idx == 0 || player.IsMoving()); idx++ {
+		w.advance(testTick)
+
+		if w.mapEngine.SubTileAt(int(player.Position.X()), int(player.Position.Y())).BlockWalk {
+			t.Fatalf("player walked into a wall at sub tile (%v, %v)", player.Position.X(), player.Position.Y())
+		}
+	}
+
+	if position := player.Position.World(); !near(position.X(), 0.1) || !near(position.Y(), 0.9) {
+		t.Errorf("player walked around the wall to %v, want (0.1, 0.9)", position)
+	}
+}
+
+func near(a, b float64) bool {
+	const epsilon = 0.21 // a sub tile
+
+	return a-b < epsilon && b-a < epsilon
+}
@@ -2,6 +2,7 @@
 //
 // Flags:
 // -o [directory] Output directory
+// -l [filename] External listfile, naming the files of archives without a (listfile)
 // -v Enable verbose output
 //
 // Usage:
@@ -19,11 +19,13 @@ const (
 
 func main() {
 	var (
-		outPath string
-		verbose bool
+		outPath  string
+		listPath string
+		verbose  bool
 	)
 
 	flag.StringVar(&outPath, "o", "./output/", "output directory")
+	flag.StringVar(&listPath, "l", "", "external listfile, for archives without a (listfile)")
 	flag.BoolVar(&verbose, "v", false, "verbose output")
 	flag.Parse()
 
@@ -39,6 +41,15 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if listPath != "" {
+		names, err := d2mpq.ReadListFile(listPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		mpq.(*d2mpq.MPQ).AddListFile(names)
+	}
+
 	list, err := mpq.GetFileList()
 	if err != nil {
 		log.Fatal(err)
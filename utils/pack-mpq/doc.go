@@ -0,0 +1,20 @@
+// This command line utility provides a way to pack a directory into an mpq file.
+//
+// Flags:
+// -o [filename] Output mpq file
+// -a [filename] Existing mpq file to patch, its files are kept unless they are replaced
+// -c [method] Compression: none, zlib, pkware, bzip2 or implode (default zlib)
+// -s Store files as a single unit
+// -e Encrypt files
+// -k Encrypt files with a key adjusted by their position (implies -e)
+// -h [size] Hash table size, a power of two (default: large enough for the files)
+// -v Enable verbose output
+//
+// Usage:
+// First run `go install pack-mpq.go` in this directory.
+// Then run pack-mpq(.exe) with the directory to be packed. The files in the
+// directory are named relative to it, so a directory extracted by extract-mpq
+// packs back to an mpq with the same file names.
+//
+// pack-mpq -o patch_d2.mpq ./output/patch_d2.mpq
+package main
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenDiablo2/OpenDiablo2/d2common/d2fileformats/d2mpq"
+)
+
+const (
+	minHashTableSize = 16
+	reservedFiles    = 2 // (listfile) and (attributes)
+)
+
+func main() {
+	var (
+		outPath       string
+		patchPath     string
+		compression   string
+		singleUnit    bool
+		encrypted     bool
+		fixKey        bool
+		hashTableSize uint
+		verbose       bool
+	)
+
+	flag.StringVar(&outPath, "o", "./output.mpq", "output mpq file")
+	flag.StringVar(&patchPath, "a", "", "existing mpq file to patch")
+	flag.StringVar(&compression, "c", "zlib", "compression: none, zlib, pkware, bzip2 or implode")
+	flag.BoolVar(&singleUnit, "s", false, "store files as a single unit")
+	flag.BoolVar(&encrypted, "e", false, "encrypt files")
+	flag.BoolVar(&fixKey, "k", false, "encrypt files with a key adjusted by their position")
+	flag.UintVar(&hashTableSize, "h", 0, "hash table size")
+	flag.BoolVar(&verbose, "v", false, "verbose output")
+	flag.Parse()
+
+	if len(flag.Args()) != 1 {
+		fmt.Printf("Usage: %s [flags] directory\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	options, err := fileOptions(compression)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options.SingleUnit = singleUnit
+	options.Encrypted = encrypted
+	options.FixKey = fixKey
+
+	files, err := listFiles(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w *d2mpq.Writer
+
+	if patchPath != "" {
+		w, err = d2mpq.OpenWriter(patchPath, uint32(hashTableSize))
+	} else {
+		w, err = d2mpq.NewWriter(uint32(defaultHashTableSize(hashTableSize, len(files))))
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := ioutil.ReadFile(files[name]) //nolint:gosec // the files are chosen by the user
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if w.Contains(name) {
+			err = w.ReplaceFile(name, data, options)
+		} else {
+			err = w.AddFile(name, data, options)
+		}
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if verbose {
+			fmt.Printf("Adding: %s\n", name)
+		}
+	}
+
+	if err := w.Save(outPath); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func fileOptions(compression string) (d2mpq.FileOptions, error) {
+	switch strings.ToLower(compression) {
+	case "none":
+		return d2mpq.FileOptions{}, nil
+	case "zlib":
+		return d2mpq.FileOptions{Compression: d2mpq.CompressionZLib}, nil
+	case "pkware":
+		return d2mpq.FileOptions{Compression: d2mpq.CompressionPKWare}, nil
+	case "bzip2":
+		return d2mpq.FileOptions{Compression: d2mpq.CompressionBZip2}, nil
+	case "implode":
+		return d2mpq.FileOptions{Implode: true}, nil
+	}
+
+	return d2mpq.FileOptions{}, fmt.Errorf("unknown compression: %s", compression)
+}
+
+// listFiles returns the files in the directory, keyed by their name in the archive
+func listFiles(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files[strings.ReplaceAll(filepath.ToSlash(name), "/", "\\")] = path
+
+		return nil
+	})
+
+	return files, err
+}
+
+// defaultHashTableSize returns the given size, or the smallest power of two which leaves
+// room for the files and a quarter of the table empty, so lookups stay short
+func defaultHashTableSize(size uint, fileCount int) uint {
+	if size != 0 {
+		return size
+	}
+
+	size = minHashTableSize
+
+	for size*3/4 < uint(fileCount+reservedFiles) { //nolint:gomnd // keep a quarter of the table empty
+		size *= 2
+	}
+
+	return size
+}